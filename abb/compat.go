@@ -0,0 +1,122 @@
+package abb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compat describes how a RAPID instruction, function or predefined data
+// differs between RobotWare 5, 6 (IRC5) and 7 (OmniCore). Versions are
+// major.minor strings such as "7.0".
+type Compat struct {
+	Name        string         `json:"-"`
+	Option      string         `json:"option,omitempty"` // RobotWare option the instruction needs
+	Deprecated  string         `json:"deprecated,omitempty"`
+	Removed     string         `json:"removed,omitempty"`
+	Replacement string         `json:"replacement,omitempty"`
+	Changes     []CompatChange `json:"changes,omitempty"`
+	Note        string         `json:"note,omitempty"`
+}
+
+// CompatChange is a behavior change introduced with a RobotWare version
+type CompatChange struct {
+	Version string `json:"version"`
+	Note    string `json:"note"`
+}
+
+// Version is a RobotWare version as major and minor number
+type Version struct {
+	Major, Minor int
+}
+
+// ParseVersion reads a RobotWare version such as 7, 6.14, rw7 or omnicore.
+// IRC5 means RobotWare 6.
+func ParseVersion(s string) (Version, error) {
+	v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "rw")
+	switch v {
+	case "omnicore":
+		return Version{Major: 7}, nil
+	case "irc5":
+		return Version{Major: 6}, nil
+	}
+	major, minor, _ := strings.Cut(v, ".")
+	var ver Version
+	var err error
+	if ver.Major, err = strconv.Atoi(major); err != nil || ver.Major < 5 || ver.Major > 7 {
+		return Version{}, fmt.Errorf("unknown RobotWare version %q (use rw5, rw6, rw7 or e.g. 6.14)", s)
+	}
+	if minor != "" {
+		if ver.Minor, err = strconv.Atoi(minor); err != nil {
+			return Version{}, fmt.Errorf("unknown RobotWare version %q", s)
+		}
+	}
+	return ver, nil
+}
+
+// Before reports whether v is an older version than other
+func (v Version) Before(other Version) bool {
+	return v.Major < other.Major || v.Major == other.Major && v.Minor < other.Minor
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// reached reports whether the version in the data is at or before v; an
+// empty version is never reached
+func reached(version string, v Version) bool {
+	if version == "" {
+		return false
+	}
+	at, err := ParseVersion(version)
+	return err == nil && !v.Before(at)
+}
+
+// Compatibility returns the compatibility data of an instruction, matched
+// without regard to case as RAPID does
+func Compatibility(name string) (Compat, bool) {
+	d := load()
+	for _, key := range sortedKeys(d.compat) {
+		if strings.EqualFold(key, name) {
+			c := d.compat[key]
+			c.Name = key
+			return c, true
+		}
+	}
+	return Compat{}, false
+}
+
+// CompatIndex returns the compatibility data keyed by upper-case name, for
+// scanning source code
+func CompatIndex() map[string]Compat {
+	d := load()
+	index := make(map[string]Compat, len(d.compat))
+	for key, c := range d.compat {
+		c.Name = key
+		index[strings.ToUpper(key)] = c
+	}
+	return index
+}
+
+// RemovedIn reports whether the instruction no longer exists in v
+func (c Compat) RemovedIn(v Version) bool {
+	return reached(c.Removed, v)
+}
+
+// DeprecatedIn reports whether the instruction is deprecated in v
+func (c Compat) DeprecatedIn(v Version) bool {
+	return reached(c.Deprecated, v)
+}
+
+// ChangesBetween returns the behavior changes a program moving from one
+// version to another meets
+func (c Compat) ChangesBetween(from, to Version) []CompatChange {
+	var changes []CompatChange
+	for _, ch := range c.Changes {
+		if reached(ch.Version, to) && !reached(ch.Version, from) {
+			changes = append(changes, ch)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,151 @@
+package abb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LocalPack is the data pack holding entries added with data add
+const LocalPack = "local"
+
+// CommandEntry is a command authored by a user, with the line its YAML
+// document starts on
+type CommandEntry struct {
+	Key     string
+	Line    int
+	Command ABBCommand
+}
+
+var commandKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// commandFields are the keys a command entry may set
+var commandFields = []string{"key", "name", "category", "syntax", "example", "description"}
+
+// ParseCommandEntries reads command entries in a YAML subset: documents
+// separated by ---, each a flat map of the command fields. Values are plain
+// or quoted on one line, or literal blocks introduced by |.
+//
+//	key: search_c
+//	name: SearchC
+//	category: Motion
+//	syntax: SearchC [\Stop] | [\PStop], Signal, SearchPoint, CirPoint, ToPoint, Speed, Tool;
+//	example: |
+//	  SearchC \Stop:=di_Contact, sp, cirpoint, p10, v100, tool1;
+//	description: |
+//	  Circular search motion that records the position where the signal changes.
+func ParseCommandEntries(src string) ([]CommandEntry, error) {
+	var entries []CommandEntry
+	var entry *CommandEntry
+	var block *string
+	blockIndent := -1
+	finish := func() {
+		if entry != nil {
+			entries = append(entries, *entry)
+			entry = nil
+		}
+	}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for n, raw := range lines {
+		line := strings.TrimRight(raw, " \t")
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if block != nil {
+			if strings.TrimSpace(line) == "" {
+				*block += "\n"
+				continue
+			}
+			if blockIndent < 0 && indent > 0 {
+				blockIndent = indent
+			}
+			if blockIndent > 0 && indent >= blockIndent {
+				*block += line[blockIndent:] + "\n"
+				continue
+			}
+			*block = strings.TrimRight(*block, "\n")
+			block = nil
+		}
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if text == "---" {
+			finish()
+			continue
+		}
+		if indent > 0 {
+			return nil, fmt.Errorf("line %d: unexpected indentation", n+1)
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		if entry == nil {
+			entry = &CommandEntry{Line: n + 1}
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		var field *string
+		switch key {
+		case "key":
+			field = &entry.Key
+		case "name":
+			field = &entry.Command.Name
+		case "category":
+			field = &entry.Command.Category
+		case "syntax":
+			field = &entry.Command.Syntax
+		case "example":
+			field = &entry.Command.Example
+		case "description":
+			field = &entry.Command.Description
+		default:
+			return nil, fmt.Errorf("line %d: unknown field %q (fields: %s)", n+1, key, strings.Join(commandFields, ", "))
+		}
+		if value == "|" || value == "|-" {
+			*field = ""
+			block, blockIndent = field, -1
+			continue
+		}
+		*field = unquoteYAML(value)
+	}
+	if block != nil {
+		*block = strings.TrimRight(*block, "\n")
+	}
+	finish()
+	return entries, nil
+}
+
+// unquoteYAML removes the quotes of a single or double quoted scalar
+func unquoteYAML(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n").Replace(s[1 : len(s)-1])
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// Validate checks an entry against the command schema: all fields are
+// required and the key is a lower case identifier. Categories outside the
+// known ones are reported as notes, since packs may add categories.
+func (e CommandEntry) Validate() (problems, notes []string) {
+	c := e.Command
+	for _, f := range []struct{ name, value string }{
+		{"key", e.Key}, {"name", c.Name}, {"category", c.Category},
+		{"syntax", c.Syntax}, {"example", c.Example}, {"description", c.Description},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			problems = append(problems, "missing "+f.name)
+		}
+	}
+	if e.Key != "" && !commandKeyRe.MatchString(e.Key) {
+		problems = append(problems, fmt.Sprintf("key %q must be lower case letters, digits and _", e.Key))
+	}
+	if c.Category != "" && !containsFold(CommandCategories(), c.Category) {
+		notes = append(notes, fmt.Sprintf("new category %q (known: %s)", c.Category, strings.Join(CommandCategories(), ", ")))
+	}
+	if _, ok := load().commands[e.Key]; ok {
+		notes = append(notes, "replaces the existing entry "+e.Key)
+	}
+	return problems, notes
+}
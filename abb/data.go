@@ -0,0 +1,286 @@
+// Package abb provides functionality for working with ABB robots and RAPID programming
+package abb
+
+import (
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/polyfant/automation-helper-cli/datapack"
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+// Reference data is kept as JSON next to the code and only decoded on first use,
+// so startup stays fast as coverage grows
+//
+//go:embed data/*.json data/i18n/*.json
+var builtin embed.FS
+
+// Pack is the JSON layout of an ABB reference data pack. Every section is
+// optional; entries in installed packs extend or override the built-in data.
+// A pack with a language code holds translations: its entries only replace the
+// text fields they set, and only while that language is selected.
+type Pack struct {
+	Vendor         string                `json:"vendor"`
+	Language       string                `json:"language,omitempty"`
+	Commands       map[string]ABBCommand `json:"commands"`
+	QuickReference map[string]string     `json:"quickref"`
+	ErrorCodes     map[string]ErrorCode  `json:"errors"`
+	Glossary       map[string]Term       `json:"glossary"`
+	Robots         map[string]Robot      `json:"robots"`
+	// Maintenance maps a maintenance profile to its tasks
+	Maintenance map[string][]MaintenanceTask `json:"maintenance"`
+	Parts       map[string]Part              `json:"parts"`
+	// Compat holds RobotWare version differences keyed by instruction name
+	Compat map[string]Compat `json:"compat"`
+	// Ports maps a device type or fieldbus to the network ports it needs
+	Ports map[string]PortDevice `json:"ports"`
+	// Options maps RobotWare option names used in Compat to their numbers
+	Options map[string]Option `json:"options"`
+	// Stopping holds stopping times and distances keyed by robot key
+	Stopping map[string]Stopping `json:"stopping"`
+	// FAQ holds questions and answers for the ai command when offline
+	FAQ map[string]FAQEntry `json:"faq"`
+}
+
+type dataset struct {
+	commands map[string]ABBCommand
+	quickref map[string]string
+	errors   map[string]ErrorCode
+	glossary map[string]Term
+	robots   map[string]Robot
+	// maintenance holds the tasks of each maintenance profile
+	maintenance map[string][]MaintenanceTask
+	parts       map[string]Part
+	compat      map[string]Compat
+	ports       map[string]PortDevice
+	options     map[string]Option
+	stopping    map[string]Stopping
+	faq         map[string]FAQEntry
+	// translations holds the merged translation packs per language code
+	translations map[string]*Pack
+	warnings     []string
+}
+
+var (
+	loadOnce sync.Once
+	loaded   *dataset
+)
+
+// load decodes the built-in data and installed packs the first time it is called
+func load() *dataset {
+	loadOnce.Do(func() {
+		d := &dataset{
+			commands: make(map[string]ABBCommand),
+			quickref: make(map[string]string),
+			errors:   make(map[string]ErrorCode),
+			glossary: make(map[string]Term),
+			robots:   make(map[string]Robot),
+
+			maintenance:  make(map[string][]MaintenanceTask),
+			parts:        make(map[string]Part),
+			compat:       make(map[string]Compat),
+			ports:        make(map[string]PortDevice),
+			options:      make(map[string]Option),
+			stopping:     make(map[string]Stopping),
+			faq:          make(map[string]FAQEntry),
+			translations: make(map[string]*Pack),
+		}
+		mustDecode("data/commands.json", &d.commands)
+		mustDecode("data/quickref.json", &d.quickref)
+		mustDecode("data/errors.json", &d.errors)
+		mustDecode("data/glossary.json", &d.glossary)
+		mustDecode("data/robots.json", &d.robots)
+		mustDecode("data/maintenance.json", &d.maintenance)
+		mustDecode("data/parts.json", &d.parts)
+		mustDecode("data/compat.json", &d.compat)
+		mustDecode("data/ports.json", &d.ports)
+		mustDecode("data/options.json", &d.options)
+		mustDecode("data/stopping.json", &d.stopping)
+		mustDecode("data/faq.json", &d.faq)
+		d.loadTranslations()
+		d.mergePacks()
+		loaded = d
+	})
+	return loaded
+}
+
+// mustDecode reads an embedded file; a failure is a build defect
+func mustDecode(name string, v interface{}) {
+	data, err := builtin.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("abb: missing embedded %s: %v", name, err))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		panic(fmt.Sprintf("abb: invalid embedded %s: %v", name, err))
+	}
+}
+
+// mergePacks adds entries from installed ABB data packs (.json or .json.gz)
+func (d *dataset) mergePacks() {
+	packs, err := datapack.Installed()
+	if err != nil {
+		d.warnings = append(d.warnings, err.Error())
+		return
+	}
+	for _, p := range packs {
+		if !strings.HasSuffix(p.File, ".json") && !strings.HasSuffix(p.File, ".json.gz") {
+			continue
+		}
+		pack, err := ReadPack(p)
+		if err != nil {
+			d.warnings = append(d.warnings, fmt.Sprintf("pack %s: %v", p.Name, err))
+			continue
+		}
+		if pack.Vendor != "" && pack.Vendor != "abb" {
+			continue
+		}
+		if pack.Language != "" {
+			d.addTranslation(pack)
+			continue
+		}
+		for k, v := range pack.Commands {
+			d.commands[k] = v
+		}
+		for k, v := range pack.QuickReference {
+			d.quickref[k] = v
+		}
+		for k, v := range pack.ErrorCodes {
+			d.errors[k] = v
+		}
+		for k, v := range pack.Glossary {
+			d.glossary[k] = v
+		}
+		for k, v := range pack.Robots {
+			d.robots[k] = v
+		}
+		for k, v := range pack.Maintenance {
+			d.maintenance[k] = v
+		}
+		for k, v := range pack.Parts {
+			d.parts[k] = v
+		}
+		for k, v := range pack.Compat {
+			d.compat[k] = v
+		}
+		for k, v := range pack.Ports {
+			d.ports[k] = v
+		}
+		for k, v := range pack.Options {
+			d.options[k] = v
+		}
+		for k, v := range pack.Stopping {
+			d.stopping[k] = v
+		}
+		for k, v := range pack.FAQ {
+			d.faq[k] = v
+		}
+	}
+}
+
+// loadTranslations decodes the translation packs shipped with the binary
+func (d *dataset) loadTranslations() {
+	files, err := builtin.ReadDir("data/i18n")
+	if err != nil {
+		panic(fmt.Sprintf("abb: missing embedded translations: %v", err))
+	}
+	for _, f := range files {
+		var pack Pack
+		mustDecode("data/i18n/"+f.Name(), &pack)
+		d.addTranslation(&pack)
+	}
+}
+
+// addTranslation merges a translation pack into the one kept for its language
+func (d *dataset) addTranslation(pack *Pack) {
+	lang := i18n.Normalize(pack.Language)
+	t, ok := d.translations[lang]
+	if !ok {
+		t = &Pack{
+			Language:       lang,
+			Commands:       make(map[string]ABBCommand),
+			QuickReference: make(map[string]string),
+			ErrorCodes:     make(map[string]ErrorCode),
+			Glossary:       make(map[string]Term),
+		}
+		d.translations[lang] = t
+	}
+	for k, v := range pack.Commands {
+		t.Commands[k] = v
+	}
+	for k, v := range pack.QuickReference {
+		t.QuickReference[k] = v
+	}
+	for k, v := range pack.ErrorCodes {
+		t.ErrorCodes[k] = v
+	}
+	for k, v := range pack.Glossary {
+		t.Glossary[k] = v
+	}
+}
+
+// translation returns the translations for the selected language, if any
+func (d *dataset) translation() *Pack {
+	return d.translations[i18n.Language()]
+}
+
+// Languages returns the language codes that have reference data translations
+func Languages() []string {
+	return sortedKeys(load().translations)
+}
+
+// override returns value unless the translation replaces it
+func override(value, translated string) string {
+	if translated != "" {
+		return translated
+	}
+	return value
+}
+
+// ReadPack decodes an installed ABB data pack
+func ReadPack(p datapack.Pack) (*Pack, error) {
+	path, err := datapack.Path(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(p.File, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var pack Pack
+	if err := json.NewDecoder(r).Decode(&pack); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// LoadWarnings reports data packs that could not be read
+func LoadWarnings() []string {
+	return load().warnings
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
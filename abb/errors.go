@@ -0,0 +1,28 @@
+package abb
+
+// ErrorCode describes a predefined RAPID error number (ERRNO constant)
+// that can be caught in an ERROR handler
+type ErrorCode struct {
+	Name     string `json:"name"`
+	Cause    string `json:"cause"`
+	Recovery string `json:"recovery"`
+}
+
+// Error returns the error code with the given ERRNO constant name, with cause
+// and recovery hints translated if available
+func Error(name string) (ErrorCode, bool) {
+	d := load()
+	code, ok := d.errors[name]
+	if t := d.translation(); ok && t != nil {
+		if tr, found := t.ErrorCodes[name]; found {
+			code.Cause = override(code.Cause, tr.Cause)
+			code.Recovery = override(code.Recovery, tr.Recovery)
+		}
+	}
+	return code, ok
+}
+
+// ErrorNames returns all known ERRNO constant names in sorted order
+func ErrorNames() []string {
+	return sortedKeys(load().errors)
+}
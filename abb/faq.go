@@ -0,0 +1,20 @@
+package abb
+
+// FAQEntry is a frequently asked question about RAPID with its answer,
+// which the ai command shows when no language model can be reached
+type FAQEntry struct {
+	Question string   `json:"question"`
+	Answer   string   `json:"answer"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// FAQ returns the question and answer stored under a key
+func FAQ(key string) (FAQEntry, bool) {
+	entry, ok := load().faq[key]
+	return entry, ok
+}
+
+// FAQKeys returns the keys of all questions in sorted order
+func FAQKeys() []string {
+	return sortedKeys(load().faq)
+}
@@ -0,0 +1,60 @@
+package abb
+
+import "strings"
+
+// Term is a glossary entry explaining automation terminology
+type Term struct {
+	Term       string   `json:"term"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Definition string   `json:"definition"`
+	SeeAlso    []string `json:"see_also,omitempty"`
+}
+
+// GlossaryTerm looks up a term by key, name, alias or translated name, ignoring
+// case, spaces and hyphens. The definition is translated if available.
+func GlossaryTerm(name string) (Term, bool) {
+	d := load()
+	key, ok := glossaryKey(d, name)
+	if !ok {
+		return Term{}, false
+	}
+	term := d.glossary[key]
+	if t := d.translation(); t != nil {
+		if tr, found := t.Glossary[key]; found {
+			term.Term = override(term.Term, tr.Term)
+			term.Definition = override(term.Definition, tr.Definition)
+		}
+	}
+	return term, true
+}
+
+// GlossaryKeys returns the keys of all glossary terms in sorted order
+func GlossaryKeys() []string {
+	return sortedKeys(load().glossary)
+}
+
+func glossaryKey(d *dataset, name string) (string, bool) {
+	want := normalizeTerm(name)
+	if _, ok := d.glossary[want]; ok {
+		return want, true
+	}
+	for _, key := range sortedKeys(d.glossary) {
+		term := d.glossary[key]
+		if normalizeTerm(term.Term) == want {
+			return key, true
+		}
+		for _, alias := range term.Aliases {
+			if normalizeTerm(alias) == want {
+				return key, true
+			}
+		}
+		if t := d.translation(); t != nil && normalizeTerm(t.Glossary[key].Term) == want {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func normalizeTerm(s string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(s)))
+}
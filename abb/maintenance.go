@@ -0,0 +1,69 @@
+package abb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaintenanceTask is a preventive maintenance item. A task is due after
+// Months calendar months or Hours operating hours, whichever comes first;
+// zero means the limit does not apply. Intervals are nominal and the
+// product manual of the actual variant takes precedence.
+type MaintenanceTask struct {
+	Task     string  `json:"task"`
+	Category string  `json:"category"` // lubrication, battery, brakes, inspection, cleaning, safety or controller
+	Months   float64 `json:"months,omitempty"`
+	Hours    float64 `json:"hours,omitempty"`
+	Details  string  `json:"details,omitempty"`
+}
+
+// Interval describes when the task is due, like "12 months or 20000 h"
+func (t MaintenanceTask) Interval() string {
+	switch {
+	case t.Months > 0 && t.Hours > 0:
+		return fmt.Sprintf("%g months or %g h", t.Months, t.Hours)
+	case t.Hours > 0:
+		return fmt.Sprintf("%g h", t.Hours)
+	default:
+		return fmt.Sprintf("%g months", t.Months)
+	}
+}
+
+// MaintenanceCategories lists the task categories in checklist order
+var MaintenanceCategories = []string{"safety", "brakes", "lubrication", "battery", "inspection", "cleaning", "controller"}
+
+// MaintenanceDue returns the tasks of the robot's profiles, plus the
+// controller tasks every robot shares, that fall due within a service
+// interval of the given months. Hour-based intervals are converted with
+// the operating hours per year. Tasks are sorted by category and interval.
+func MaintenanceDue(r Robot, months, hoursPerYear float64) []MaintenanceTask {
+	d := load()
+	var due []MaintenanceTask
+	for _, profile := range append([]string{"controller"}, r.Maintenance...) {
+		for _, t := range d.maintenance[profile] {
+			byTime := t.Months > 0 && t.Months <= months
+			byHours := t.Hours > 0 && hoursPerYear > 0 && t.Hours/hoursPerYear*12 <= months
+			if byTime || byHours {
+				due = append(due, t)
+			}
+		}
+	}
+	order := make(map[string]int, len(MaintenanceCategories))
+	for i, c := range MaintenanceCategories {
+		order[c] = i + 1
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		a, b := order[due[i].Category], order[due[j].Category]
+		if a == 0 {
+			a = len(order) + 1
+		}
+		if b == 0 {
+			b = len(order) + 1
+		}
+		if a != b {
+			return a < b
+		}
+		return due[i].Months < due[j].Months
+	})
+	return due
+}
@@ -0,0 +1,50 @@
+package abb
+
+import (
+	"regexp"
+)
+
+// Option identifies a RobotWare option in the option list of a system, by
+// its number (608-1) or by the names RobotWare versions list it under
+type Option struct {
+	Name    string   `json:"-"`
+	IDs     []string `json:"ids"`
+	Aliases []string `json:"aliases"`
+	Note    string   `json:"note,omitempty"`
+}
+
+// RobotWareOption returns how an option named in the compatibility data
+// appears in system option lists
+func RobotWareOption(name string) (Option, bool) {
+	d := load()
+	o, ok := d.options[name]
+	o.Name = name
+	return o, ok
+}
+
+// optionLineRe matches an option line such as "608-1 World Zones"
+var optionLineRe = regexp.MustCompile(`^\s*(\d{3,4}-\d+)\s+(.*\S)`)
+
+// Installed reports whether an option list contains the option, by number
+// or by one of its names as whole words
+func (o Option) Installed(options []string) bool {
+	var names []*regexp.Regexp
+	for _, alias := range append([]string{o.Name}, o.Aliases...) {
+		names = append(names, regexp.MustCompile(`(?i)(^|\W)`+regexp.QuoteMeta(alias)+`($|\W)`))
+	}
+	for _, line := range options {
+		if m := optionLineRe.FindStringSubmatch(line); m != nil {
+			for _, id := range o.IDs {
+				if m[1] == id {
+					return true
+				}
+			}
+		}
+		for _, re := range names {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package abb
+
+import (
+	"sort"
+	"strings"
+)
+
+// Part is a spare part or consumable. Models lists the robot families
+// (irb6700) and controllers (IRC5, OmniCore) it fits. Part numbers change
+// with manipulator revisions, so the spare parts list for the serial number
+// has the final word.
+type Part struct {
+	Number      string   `json:"-"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"` // battery, pendant, seal, lubricant, filter, cable
+	Models      []string `json:"models"`
+	Keywords    []string `json:"keywords"`
+	Note        string   `json:"note,omitempty"`
+}
+
+// PartNumbers returns all known part numbers in sorted order
+func PartNumbers() []string {
+	return sortedKeys(load().parts)
+}
+
+// SparePart returns the part with the given number
+func SparePart(number string) (Part, bool) {
+	d := load()
+	for _, key := range sortedKeys(d.parts) {
+		if strings.EqualFold(key, strings.TrimSpace(number)) {
+			p := d.parts[key]
+			p.Number = key
+			return p, true
+		}
+	}
+	return Part{}, false
+}
+
+// PartsFor returns the parts, grouped by category, fitting a robot or controller whose description,
+// category, number or keywords contain every word of the query. A robot is
+// given as key, model or family (irb6700) and also gets the parts of its
+// controllers. The result is false when the name is neither a known robot
+// nor a controller.
+func PartsFor(name, query string) ([]Part, bool) {
+	d := load()
+	var targets []string
+	for _, key := range robotKeys(d, name) {
+		targets = append(targets, strings.ReplaceAll(key, "-", ""))
+		for _, c := range d.robots[key].Controllers {
+			targets = append(targets, normalizeRobotName(c))
+		}
+	}
+	if len(targets) == 0 {
+		want := normalizeRobotName(name)
+		for _, key := range sortedKeys(d.robots) {
+			for _, c := range d.robots[key].Controllers {
+				if strings.HasPrefix(normalizeRobotName(c), want) {
+					targets = append(targets, want)
+					break
+				}
+			}
+			if len(targets) > 0 {
+				break
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, false
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	var result []Part
+	for _, key := range sortedKeys(d.parts) {
+		p := d.parts[key]
+		p.Number = key
+		if !partFits(p, targets) {
+			continue
+		}
+		text := strings.ToLower(strings.Join(append([]string{key, p.Description, p.Category}, p.Keywords...), " "))
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			result = append(result, p)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result, true
+}
+
+// partFits reports whether one of the part's models is a prefix of a
+// normalized robot key or controller name, so irb6700 fits irb6700-200 and
+// IRC5 fits IRC5 Compact
+func partFits(p Part, targets []string) bool {
+	for _, m := range p.Models {
+		m = normalizeRobotName(m)
+		for _, t := range targets {
+			if strings.HasPrefix(t, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// robotKeys returns the key of the robot with the given key or model name,
+// or the keys of all variants of a family
+func robotKeys(d *dataset, name string) []string {
+	want := normalizeRobotName(name)
+	for _, key := range sortedKeys(d.robots) {
+		if normalizeRobotName(d.robots[key].Model) == want {
+			return []string{key}
+		}
+	}
+	return RobotVariants(name)
+}
@@ -0,0 +1,56 @@
+package abb
+
+import (
+	"strings"
+)
+
+// Port is a network port a device needs open. Protocol is tcp, udp or
+// ethernet for layer 2 traffic that no router or firewall forwards; Port is
+// a number, a range (49152-65535) or an EtherType (0x8892).
+type Port struct {
+	Protocol  string `json:"protocol"`
+	Port      string `json:"port"`
+	Direction string `json:"direction"` // in: to the device, out: from the device, both
+	Service   string `json:"service"`
+	Required  bool   `json:"required"`
+	Note      string `json:"note,omitempty"`
+}
+
+// PortDevice is a device type or fieldbus with the ports it uses
+type PortDevice struct {
+	Name        string   `json:"-"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Ports       []Port   `json:"ports"`
+	Note        string   `json:"note,omitempty"`
+}
+
+// PortDevices returns the keys of all device types with port data
+func PortDevices() []string {
+	return sortedKeys(load().ports)
+}
+
+// PortsFor returns the port requirements of a device type, given by key or
+// alias
+func PortsFor(name string) (PortDevice, bool) {
+	d := load()
+	want := normalizePortName(name)
+	for _, key := range sortedKeys(d.ports) {
+		dev := d.ports[key]
+		dev.Name = key
+		if normalizePortName(key) == want {
+			return dev, true
+		}
+		for _, alias := range dev.Aliases {
+			if normalizePortName(alias) == want {
+				return dev, true
+			}
+		}
+	}
+	return PortDevice{}, false
+}
+
+// normalizePortName makes EtherNet/IP, ethernet-ip and ethernetip equal
+func normalizePortName(s string) string {
+	return strings.NewReplacer("-", "", "_", "", "/", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+}
@@ -0,0 +1,162 @@
+package abb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Robot is a manipulator variant from the robot model database. Figures are
+// nominal datasheet values.
+type Robot struct {
+	Model         string   `json:"model"`
+	Payload       float64  `json:"payload"` // kg
+	Reach         float64  `json:"reach"`   // m
+	Axes          int      `json:"axes"`
+	Repeatability float64  `json:"repeatability"` // mm
+	Weight        float64  `json:"weight"`        // kg
+	Footprint     string   `json:"footprint"`     // Base size in mm
+	Controllers   []string `json:"controllers"`
+	Applications  []string `json:"applications"`
+	Mounting      []string `json:"mounting"`
+	Protection    string   `json:"protection"`
+	// Shoulder is the height of axis 2 above the base in m, used for the
+	// working envelope; zero for SCARA and delta robots
+	Shoulder float64 `json:"shoulder,omitempty"`
+	// Maintenance names the maintenance profiles that apply to the robot
+	Maintenance []string `json:"maintenance"`
+}
+
+// RobotKeys returns the keys of all robot models in sorted order
+func RobotKeys() []string {
+	return sortedKeys(load().robots)
+}
+
+// RobotModel looks up a robot by key (irb6700-200) or model name, ignoring
+// case, spaces and hyphens. A key prefix such as irb6700 matches when it
+// selects a single variant.
+func RobotModel(name string) (Robot, bool) {
+	d := load()
+	want := normalizeRobotName(name)
+	for _, key := range sortedKeys(d.robots) {
+		r := d.robots[key]
+		if strings.ReplaceAll(key, "-", "") == want || normalizeRobotName(r.Model) == want {
+			return r, true
+		}
+	}
+	if matches := RobotVariants(name); len(matches) == 1 {
+		return d.robots[matches[0]], true
+	}
+	return Robot{}, false
+}
+
+// RobotVariants returns the keys of the variants in a robot family, such as
+// irb6700-150 and irb6700-200 for irb6700
+func RobotVariants(family string) []string {
+	want := normalizeRobotName(family)
+	var keys []string
+	for _, key := range RobotKeys() {
+		if strings.HasPrefix(strings.ReplaceAll(key, "-", ""), want) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func normalizeRobotName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// RobotRequirements describe the application a robot is selected for
+type RobotRequirements struct {
+	Payload     float64 // kg, including gripper and part
+	Reach       float64 // m
+	Application string
+	Mounting    string
+}
+
+// RobotCandidate is a robot meeting the requirements with its ranking
+type RobotCandidate struct {
+	Robot
+	Key         string
+	Score       int
+	PayloadUse  float64 // Share of the rated payload used
+	ReachMargin float64 // m
+	Notes       []string
+}
+
+// SelectRobots returns the robots meeting the payload and reach, best first.
+// Candidates are ranked by application fit, payload utilization (60-85 % of
+// the rating is ideal), reach margin and robot weight.
+func SelectRobots(req RobotRequirements) []RobotCandidate {
+	var result []RobotCandidate
+	d := load()
+	for _, key := range sortedKeys(d.robots) {
+		r := d.robots[key]
+		if r.Payload < req.Payload || r.Reach < req.Reach {
+			continue
+		}
+		if req.Mounting != "" && !containsFold(r.Mounting, req.Mounting) {
+			continue
+		}
+		c := RobotCandidate{Robot: r, Key: key, Score: 100}
+		c.PayloadUse = req.Payload / r.Payload
+		c.ReachMargin = r.Reach - req.Reach
+
+		if req.Application != "" && !hasApplication(r, req.Application) {
+			c.Score -= 30
+			c.Notes = append(c.Notes, fmt.Sprintf("not a typical %s robot", req.Application))
+		}
+		switch {
+		case c.PayloadUse > 0.85:
+			c.Score -= 15
+			c.Notes = append(c.Notes, "payload above 85 %: check the load diagram with the real inertia")
+		case c.PayloadUse < 0.3:
+			c.Score -= int(math.Min(30, (0.6-c.PayloadUse)*50))
+			c.Notes = append(c.Notes, "oversized for the payload")
+		case c.PayloadUse < 0.6:
+			c.Score -= 5
+		}
+		if req.Reach > 0 {
+			if c.ReachMargin < 0.1*req.Reach {
+				c.Score -= 10
+				c.Notes = append(c.Notes, "little reach margin: check the work envelope at full extension")
+			} else if c.ReachMargin > req.Reach {
+				c.Score -= 10
+				c.Notes = append(c.Notes, "reach much larger than needed")
+			}
+		}
+		// Lighter robots are cheaper to mount and take less floor space
+		c.Score -= int(math.Min(10, r.Weight/500))
+		result = append(result, c)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Weight < result[j].Weight
+	})
+	return result
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasApplication matches a whole or partial application name, so "welding"
+// finds arc and spot welding robots
+func hasApplication(r Robot, app string) bool {
+	app = strings.ToLower(app)
+	for _, a := range r.Applications {
+		if strings.Contains(strings.ToLower(a), app) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package abb
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Match kinds returned by Search
+const (
+	KindCommand = "command"
+	KindTopic   = "quickref"
+	KindError   = "error"
+	KindTerm    = "glossary"
+	KindPart    = "part"
+	KindFAQ     = "faq"
+)
+
+// Match is a search hit in the reference data
+type Match struct {
+	Kind  string
+	Key   string
+	Score int
+}
+
+type entryRef struct {
+	kind string
+	key  string
+}
+
+var (
+	indexOnce sync.Once
+	index     map[string][]entryRef
+)
+
+// buildIndex maps every word in the reference data to the entries containing it
+func buildIndex() {
+	d := load()
+	index = make(map[string][]entryRef)
+	add := func(ref entryRef, texts ...string) {
+		seen := make(map[string]bool)
+		for _, text := range texts {
+			for _, w := range words(text) {
+				if !seen[w] {
+					seen[w] = true
+					index[w] = append(index[w], ref)
+				}
+			}
+		}
+	}
+	for key, cmd := range d.commands {
+		add(entryRef{KindCommand, key}, key, cmd.Name, cmd.Syntax, cmd.Description)
+	}
+	for topic, content := range d.quickref {
+		add(entryRef{KindTopic, topic}, topic, content)
+	}
+	for name, code := range d.errors {
+		add(entryRef{KindError, name}, name, code.Cause, code.Recovery)
+	}
+	for key, term := range d.glossary {
+		add(entryRef{KindTerm, key}, append([]string{key, term.Term, term.Definition}, term.Aliases...)...)
+	}
+	for number, part := range d.parts {
+		add(entryRef{KindPart, number}, append([]string{number, part.Description, part.Category}, part.Keywords...)...)
+	}
+	for key, entry := range d.faq {
+		add(entryRef{KindFAQ, key}, append([]string{entry.Question, entry.Answer}, entry.Keywords...)...)
+	}
+}
+
+// stopWords are left out of queries, so questions typed as sentences match
+// on the words that matter
+var stopWords = map[string]bool{
+	"an": true, "and": true, "are": true, "at": true, "be": true, "by": true, "can": true,
+	"do": true, "does": true, "for": true, "from": true, "how": true, "in": true, "is": true,
+	"it": true, "me": true, "my": true, "of": true, "on": true, "or": true, "should": true,
+	"the": true, "this": true, "that": true, "to": true, "what": true, "when": true,
+	"where": true, "which": true, "why": true, "with": true, "you": true, "your": true,
+}
+
+// Search finds commands, topics, error codes, terms, spare parts and
+// questions matching the query words. A word that is not in the reference
+// data matches similar words, such as misspellings and other forms of the
+// word, at a lower score. The index is built on the first search.
+func Search(query string) []Match {
+	indexOnce.Do(buildIndex)
+
+	scores := make(map[entryRef]int)
+	for _, w := range words(query) {
+		if stopWords[w] {
+			continue
+		}
+		refs, points := index[w], 2
+		if len(refs) == 0 {
+			refs, points = similar(w), 1
+		}
+		for _, ref := range refs {
+			scores[ref] += points
+			// Reward hits on the entry's own key
+			if strings.Contains(strings.ToLower(ref.key), w) {
+				scores[ref]++
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(scores))
+	for ref, score := range scores {
+		matches = append(matches, Match{Kind: ref.kind, Key: ref.key, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Kind != matches[j].Kind {
+			return matches[i].Kind < matches[j].Kind
+		}
+		return matches[i].Key < matches[j].Key
+	})
+	return matches
+}
+
+// similar returns the entries containing words close to w: words it starts
+// or that start it, and words one edit away (two for long words)
+func similar(w string) []entryRef {
+	if len(w) < 4 {
+		return nil
+	}
+	maxEdits := 1
+	if len(w) >= 8 {
+		maxEdits = 2
+	}
+	seen := make(map[entryRef]bool)
+	var refs []entryRef
+	for word, entries := range index {
+		if len(word) < 4 {
+			continue
+		}
+		if !strings.HasPrefix(word, w) && !strings.HasPrefix(w, word) && editDistance(w, word) > maxEdits {
+			continue
+		}
+		for _, ref := range entries {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// editDistance is the Levenshtein distance between two words
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// words splits text into lower-case search terms, including the parts of
+// snake_case identifiers
+func words(text string) []string {
+	var out []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(f) < 2 {
+			continue
+		}
+		out = append(out, f)
+		if strings.Contains(f, "_") {
+			for _, part := range strings.Split(f, "_") {
+				if len(part) >= 2 {
+					out = append(out, part)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package abb
+
+// SpeedData mirrors the four components of a RAPID speeddata record
+type SpeedData struct {
+	TCP    float64 // v_tcp - TCP linear speed (mm/s)
+	Ori    float64 // v_ori - TCP reorientation speed (deg/s)
+	LinExt float64 // v_leax - linear external axis speed (mm/s)
+	RotExt float64 // v_reax - rotating external axis speed (deg/s)
+}
+
+// StandardSpeeds holds the predefined speeddata values from the BASE module.
+// Every standard speed shares the same 500 deg/s reorientation limit, which is
+// why fast MoveL instructions with large orientation changes run slower than programmed.
+var StandardSpeeds = map[string]SpeedData{
+	"v5":    {5, 500, 5000, 1000},
+	"v10":   {10, 500, 5000, 1000},
+	"v20":   {20, 500, 5000, 1000},
+	"v30":   {30, 500, 5000, 1000},
+	"v40":   {40, 500, 5000, 1000},
+	"v50":   {50, 500, 5000, 1000},
+	"v60":   {60, 500, 5000, 1000},
+	"v80":   {80, 500, 5000, 1000},
+	"v100":  {100, 500, 5000, 1000},
+	"v150":  {150, 500, 5000, 1000},
+	"v200":  {200, 500, 5000, 1000},
+	"v300":  {300, 500, 5000, 1000},
+	"v400":  {400, 500, 5000, 1000},
+	"v500":  {500, 500, 5000, 1000},
+	"v600":  {600, 500, 5000, 1000},
+	"v800":  {800, 500, 5000, 1000},
+	"v1000": {1000, 500, 5000, 1000},
+	"v1500": {1500, 500, 5000, 1000},
+	"v2000": {2000, 500, 5000, 1000},
+	"v2500": {2500, 500, 5000, 1000},
+	"v3000": {3000, 500, 5000, 1000},
+	"v4000": {4000, 500, 5000, 1000},
+	"v5000": {5000, 500, 5000, 1000},
+	"v6000": {6000, 500, 5000, 1000},
+	"v7000": {7000, 500, 5000, 1000},
+}
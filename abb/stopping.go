@@ -0,0 +1,80 @@
+package abb
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StopPoint is one measured stop: the robot running at Speed, arm Extension
+// and Load (all in percent of the maximum) stops within Time seconds after
+// the stop signal, moving Distance degrees on the axis
+type StopPoint struct {
+	Speed     float64 `json:"speed"`
+	Extension float64 `json:"extension"`
+	Load      float64 `json:"load"`
+	Time      float64 `json:"time"`
+	Distance  float64 `json:"distance"`
+}
+
+// StopAxis holds the stopping data of one axis for one stop category
+type StopAxis struct {
+	Axis     int         `json:"axis"`
+	Category int         `json:"category"` // stop category 0 or 1 of IEC 60204-1
+	Points   []StopPoint `json:"points"`
+}
+
+// Stopping is the stopping data of a robot model, keyed like the robot data
+type Stopping struct {
+	Source string     `json:"source"` // document the values were taken from
+	Axes   []StopAxis `json:"axes"`
+}
+
+// StoppingRobots returns the robot keys with stopping data
+func StoppingRobots() []string {
+	return sortedKeys(load().stopping)
+}
+
+// StoppingData returns the stopping data of a robot key
+func StoppingData(robot string) (Stopping, bool) {
+	s, ok := load().stopping[robot]
+	return s, ok
+}
+
+// StopEstimate is the data point used for one axis
+type StopEstimate struct {
+	Axis  int
+	Point StopPoint
+}
+
+// Estimate picks for every axis of the stop category the data point that
+// covers the requested speed, extension and load (all values at least as
+// high) and lies closest to them, so the estimate never undercuts the
+// measured data. Estimates are sorted by stopping time, longest first.
+func (s Stopping) Estimate(category int, speed, extension, load float64) ([]StopEstimate, error) {
+	var estimates []StopEstimate
+	for _, axis := range s.Axes {
+		if axis.Category != category {
+			continue
+		}
+		found := false
+		var best StopPoint
+		for _, p := range axis.Points {
+			if p.Speed < speed || p.Extension < extension || p.Load < load {
+				continue
+			}
+			if !found || p.Speed+p.Extension+p.Load < best.Speed+best.Extension+best.Load ||
+				p.Speed+p.Extension+p.Load == best.Speed+best.Extension+best.Load && p.Time > best.Time {
+				best, found = p, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("axis %d has no data for speed %g%%, extension %g%%, load %g%%", axis.Axis, speed, extension, load)
+		}
+		estimates = append(estimates, StopEstimate{Axis: axis.Axis, Point: best})
+	}
+	if len(estimates) == 0 {
+		return nil, fmt.Errorf("no data for stop category %d", category)
+	}
+	sort.SliceStable(estimates, func(i, j int) bool { return estimates[i].Point.Time > estimates[j].Point.Time })
+	return estimates, nil
+}
@@ -0,0 +1,230 @@
+package abb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// UASUser is a controller user account from the User Authorization System
+type UASUser struct {
+	Name   string
+	Active bool
+	Groups []string
+}
+
+// UASGroup is a UAS group with the grants its members get
+type UASGroup struct {
+	Name   string
+	Grants []string
+}
+
+// UASConfig holds the users and groups of a controller
+type UASConfig struct {
+	Users  []UASUser
+	Groups []UASGroup
+}
+
+// ParseUAS reads the UAS settings saved in a controller backup. The layout
+// differs between RobotWare versions, so elements are matched by name: User
+// and Group elements with a Name attribute, group references inside users
+// and grant elements inside groups. Passwords are never read.
+func ParseUAS(r io.Reader) (*UASConfig, error) {
+	dec := xml.NewDecoder(r)
+	cfg := &UASConfig{}
+	var user *UASUser
+	var group *UASGroup
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid UAS file: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			text.Reset()
+			switch {
+			case name == "user" && user == nil && group == nil:
+				user = &UASUser{Name: attr(t, "name"), Active: !strings.EqualFold(attr(t, "status"), "deactivated")}
+			case name == "group" && user != nil:
+				if n := attr(t, "name"); n != "" {
+					user.Groups = append(user.Groups, n)
+				}
+			case name == "group" && group == nil:
+				group = &UASGroup{Name: attr(t, "name")}
+			case strings.Contains(name, "grant") && group != nil:
+				if n := attr(t, "name"); n != "" {
+					group.Grants = append(group.Grants, n)
+				}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "user" && user != nil:
+				if user.Name != "" {
+					cfg.Users = append(cfg.Users, *user)
+				}
+				user = nil
+			case name == "group" && user == nil && group != nil:
+				if group.Name != "" {
+					cfg.Groups = append(cfg.Groups, *group)
+				}
+				group = nil
+			case strings.Contains(name, "grant") && group != nil:
+				// Grants written as <Grant>name</Grant>
+				if s := strings.TrimSpace(text.String()); s != "" && !containsString(group.Grants, s) {
+					group.Grants = append(group.Grants, s)
+				}
+			}
+			text.Reset()
+		}
+	}
+	if len(cfg.Users) == 0 && len(cfg.Groups) == 0 {
+		return nil, fmt.Errorf("no UAS users or groups found")
+	}
+	sort.Slice(cfg.Users, func(i, j int) bool { return cfg.Users[i].Name < cfg.Users[j].Name })
+	sort.Slice(cfg.Groups, func(i, j int) bool { return cfg.Groups[i].Name < cfg.Groups[j].Name })
+	return cfg, nil
+}
+
+// Group returns the group with the given name
+func (c *UASConfig) Group(name string) (UASGroup, bool) {
+	for _, g := range c.Groups {
+		if strings.EqualFold(g.Name, name) {
+			return g, true
+		}
+	}
+	return UASGroup{}, false
+}
+
+// Grants returns the grants a user gets through all of their groups
+func (c *UASConfig) Grants(u UASUser) []string {
+	var grants []string
+	for _, name := range u.Groups {
+		g, _ := c.Group(name)
+		for _, grant := range g.Grants {
+			if !containsString(grants, grant) {
+				grants = append(grants, grant)
+			}
+		}
+	}
+	sort.Strings(grants)
+	return grants
+}
+
+// Check reports common UAS mistakes: an unrestricted Default User, users
+// without groups, references to missing groups and too many administrators
+func (c *UASConfig) Check() []string {
+	var findings []string
+	admins := 0
+	for _, u := range c.Users {
+		grants := c.Grants(u)
+		full := hasGrant(grants, "full access") || hasGrant(grants, "admin")
+		if full && u.Active {
+			admins++
+		}
+		if strings.EqualFold(u.Name, "Default User") && u.Active {
+			if full || hasGrant(grants, "configuration") || hasGrant(grants, "write access") {
+				findings = append(findings, "Default User is active with configuration or write grants; anyone at the FlexPendant gets them without logging in")
+			}
+		}
+		if len(u.Groups) == 0 && u.Active {
+			findings = append(findings, fmt.Sprintf("user %q is in no group and has no grants", u.Name))
+		}
+		for _, g := range u.Groups {
+			if _, ok := c.Group(g); !ok {
+				findings = append(findings, fmt.Sprintf("user %q refers to missing group %q", u.Name, g))
+			}
+		}
+	}
+	if admins > 3 {
+		findings = append(findings, fmt.Sprintf("%d active users have full access; keep it to named integrators", admins))
+	}
+	if admins == 0 && len(c.Users) > 0 {
+		findings = append(findings, "no active user has full access; nobody can change the UAS settings")
+	}
+	return findings
+}
+
+// hasGrant reports whether a grant name contains the given words, matching
+// both display names (Full access) and identifiers (UAS_ADMIN)
+func hasGrant(grants []string, words string) bool {
+	want := strings.ReplaceAll(words, " ", "")
+	for _, g := range grants {
+		if strings.Contains(strings.ToLower(strings.NewReplacer("_", "", " ", "").Replace(g)), want) {
+			return true
+		}
+	}
+	return false
+}
+
+// UASRole is a recommended group with the controller grants it needs.
+// Grants are named as in the RobotStudio UAS tool.
+type UASRole struct {
+	Name        string
+	Description string
+	Grants      []string
+}
+
+// UASRoles is the recommended group setup for production cells
+var UASRoles = []UASRole{
+	{
+		Name:        "Operator",
+		Description: "Runs production from the FlexPendant. Also the grants to leave on Default User.",
+		Grants:      []string{"Execute program"},
+	},
+	{
+		Name:        "Maintenance",
+		Description: "Recovers from stops, replaces batteries and motors, takes backups",
+		Grants: []string{"Execute program", "Backup and save", "Calibration", "I/O write access",
+			"Delete log", "Read access to controller disks"},
+	},
+	{
+		Name:        "Programmer",
+		Description: "Touches up and edits programs; no system configuration",
+		Grants: []string{"Execute program", "Edit RAPID code", "Load program", "Program debug",
+			"Perform ModPos and HotEdit", "Modify current value", "I/O write access", "Backup and save",
+			"Read access to controller disks", "Write access to controller disks"},
+	},
+	{
+		Name:        "Integrator",
+		Description: "Named engineers of the integrator and the plant; one account each",
+		Grants:      []string{"Full access"},
+	},
+	{
+		Name:        "Safety",
+		Description: "Person responsible for the safety configuration; in addition to Integrator",
+		Grants:      []string{"Safety Controller configuration"},
+	},
+	{
+		Name:        "Service account",
+		Description: "Accounts for Robot Web Services, PC SDK and MES integrations; one per application",
+		Grants:      []string{"Read access to controller disks", "I/O write access"},
+	},
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
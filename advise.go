@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+func init() {
+	commandRegistry["advise"] = Command{
+		Group:       groupCalc,
+		Description: "Advisors for robot selection and energy-efficient RAPID",
+		Usage:       adviseUsage,
+		Execute:     text(runAdvise),
+	}
+}
+
+const adviseUsage = `Usage: advise <robot|energy> ...
+  robot  - Rank robot models for a payload and reach
+  energy - Find RAPID patterns that waste energy or cause wear
+
+Examples:
+  advise robot --payload 12kg --reach 1.6m --application palletizing
+  advise energy MainModule.mod`
+
+const adviseRobotUsage = `Usage: advise robot --payload <kg> --reach <m> [--application name] [--mounting floor|wall|ceiling|...] [--top 5]
+Filters the robot model database for robots carrying the payload (gripper
+plus part) at the reach, and ranks them by application fit, payload
+utilization, reach margin and size. Figures are nominal datasheet values;
+confirm the final choice with the load diagram and a reach study.
+
+Example:
+  advise robot --payload 12kg --reach 1.6m --application palletizing`
+
+func runAdvise(args []string) (string, error) {
+	if len(args) < 1 {
+		return adviseUsage, nil
+	}
+	switch args[0] {
+	case "robot":
+		return adviseRobot(args[1:])
+	case "energy":
+		return adviseEnergy(args[1:]), nil
+	default:
+		return adviseUsage, nil
+	}
+}
+
+func adviseRobot(args []string) (string, error) {
+	var req abb.RobotRequirements
+	top := 5
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return adviseRobotUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--payload":
+			req.Payload, err = parseUnit(value, map[string]float64{"kg": 1, "g": 0.001})
+		case "--reach":
+			req.Reach, err = parseUnit(value, map[string]float64{"m": 1, "mm": 0.001, "cm": 0.01})
+		case "--application":
+			req.Application = value
+		case "--mounting":
+			req.Mounting = value
+		case "--top":
+			top, err = strconv.Atoi(value)
+		default:
+			return adviseRobotUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if req.Payload <= 0 || req.Reach <= 0 {
+		return adviseRobotUsage, nil
+	}
+
+	candidates := abb.SelectRobots(req)
+	if len(candidates) == 0 {
+		return fmt.Sprintf("No robot in the database carries %g kg at %g m%s.", req.Payload, req.Reach, mountingText(req.Mounting)), nil
+	}
+	if top > 0 && len(candidates) > top {
+		candidates = candidates[:top]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Robots for %g kg at %g m", req.Payload, req.Reach)
+	if req.Application != "" {
+		fmt.Fprintf(&b, ", %s", req.Application)
+	}
+	b.WriteString(mountingText(req.Mounting) + ":\n\n")
+	fmt.Fprintf(&b, "%-5s %-26s %8s %7s %9s %8s %-12s %s\n", "Score", "Model", "Payload", "Reach", "Use", "Weight", "Footprint", "Controller")
+	for _, c := range candidates {
+		fmt.Fprintf(&b, "%-5d %-26s %6gkg %6gm %8.0f%% %6gkg %-12s %s\n",
+			c.Score, c.Model, c.Payload, c.Reach, c.PayloadUse*100, c.Weight, c.Footprint, strings.Join(c.Controllers, ", "))
+		for _, n := range c.Notes {
+			fmt.Fprintf(&b, "      - %s\n", n)
+		}
+	}
+	b.WriteString("\nNominal datasheet values; verify with the load diagram and a reach study.")
+	return b.String(), nil
+}
+
+// adviseEnergy reports energy and wear findings for RAPID modules
+func adviseEnergy(args []string) string {
+	if len(args) < 1 {
+		return "Usage: advise energy <file.mod>...\nExample: advise energy MainModule.mod"
+	}
+	var b strings.Builder
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			b.WriteString(fmt.Sprintf("Error: %v\n", err))
+			continue
+		}
+		advice := rapid.EnergyAdvice(string(data))
+		if len(advice) == 0 {
+			b.WriteString(fmt.Sprintf("%s: no energy findings\n", path))
+			continue
+		}
+		for _, a := range advice {
+			b.WriteString(fmt.Sprintf("%s: %s\n", path, a))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func mountingText(mounting string) string {
+	if mounting == "" {
+		return ""
+	}
+	return ", " + mounting + " mounted"
+}
+
+// parseUnit reads a number with an optional unit suffix, converting it to the
+// base unit (the unit with factor 1)
+func parseUnit(s string, units map[string]float64) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	factor := 1.0
+	longest := ""
+	for unit, f := range units {
+		if strings.HasSuffix(s, unit) && len(unit) > len(longest) {
+			longest, factor = unit, f
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, longest)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * factor, nil
+}
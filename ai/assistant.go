@@ -7,39 +7,33 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// systemPrompt starts every request
+const systemPrompt = `You are an expert in ABB RAPID robotics programming language. 
+					Help users understand and modify their RAPID code. Provide clear, 
+					practical explanations and examples.`
+
+// Assistant answers questions about RAPID code with a language model
 type Assistant struct {
-	client *openai.Client
+	provider Provider
 }
 
-func NewAssistant(apiKey string) *Assistant {
-	return &Assistant{
-		client: openai.NewClient(apiKey),
-	}
+// NewAssistant returns an assistant that asks the provider
+func NewAssistant(p Provider) *Assistant {
+	return &Assistant{provider: p}
 }
 
+// GetHelp answers a single question without a conversation
 func (a *Assistant) GetHelp(question string) (string, error) {
-	resp, err := a.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role: openai.ChatMessageRoleSystem,
-					Content: `You are an expert in ABB RAPID robotics programming language. 
-					Help users understand and modify their RAPID code. Provide clear, 
-					practical explanations and examples.`,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: question,
-				},
-			},
-		},
-	)
+	return a.complete([]Message{
+		{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: question},
+	})
+}
 
+func (a *Assistant) complete(messages []Message) (string, error) {
+	answer, err := a.provider.Complete(context.Background(), messages)
 	if err != nil {
-		return "", fmt.Errorf("AI request failed: %v", err)
+		return "", fmt.Errorf("AI request failed: %w", err)
 	}
-
-	return resp.Choices[0].Message.Content, nil
-}
\ No newline at end of file
+	return answer, nil
+}
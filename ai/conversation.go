@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/db"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// MaxHistory is the number of earlier messages sent with a question; older
+// ones stay in the conversation but no longer take up the context
+const MaxHistory = 20
+
+var sessionNameRe = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// Message is one question or answer of a conversation
+type Message struct {
+	Role    string    `json:"role"` // "user" or "assistant"
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Conversation is a troubleshooting session whose earlier questions and
+// answers are sent along with each follow-up question
+type Conversation struct {
+	Name     string
+	Created  time.Time
+	Updated  time.Time
+	Messages []Message
+}
+
+// NewConversation starts an empty conversation
+func NewConversation(name string) (*Conversation, error) {
+	if !sessionNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid session name %q (use letters, digits, ., - and _)", name)
+	}
+	now := time.Now()
+	return &Conversation{Name: name, Created: now, Updated: now}, nil
+}
+
+// Reset forgets the messages, so the next question starts afresh
+func (c *Conversation) Reset() {
+	c.Messages = nil
+	c.Updated = time.Now()
+}
+
+// Ask sends a question with the recent history of the conversation and
+// adds both to it. A failed request leaves the conversation unchanged.
+func (a *Assistant) Ask(c *Conversation, question string) (string, error) {
+	messages := []Message{{Role: openai.ChatMessageRoleSystem, Content: systemPrompt}}
+	history := c.Messages
+	if len(history) > MaxHistory {
+		history = history[len(history)-MaxHistory:]
+	}
+	messages = append(messages, history...)
+	messages = append(messages, Message{Role: openai.ChatMessageRoleUser, Content: question})
+	answer, err := a.complete(messages)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	c.Messages = append(c.Messages,
+		Message{Role: openai.ChatMessageRoleUser, Content: question, Time: now},
+		Message{Role: openai.ChatMessageRoleAssistant, Content: answer, Time: now})
+	c.Updated = now
+	return answer, nil
+}
+
+// LoadConversation reads a saved conversation
+func LoadConversation(name string) (*Conversation, error) {
+	d, err := db.Open()
+	if err != nil {
+		return nil, err
+	}
+	c := &Conversation{Name: name}
+	var created, updated, messages string
+	err = d.QueryRow(`SELECT created, updated, messages FROM conversations WHERE name = ?`, name).
+		Scan(&created, &updated, &messages)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no AI session %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Created, _ = time.Parse(time.RFC3339Nano, created)
+	c.Updated, _ = time.Parse(time.RFC3339Nano, updated)
+	if err := json.Unmarshal([]byte(messages), &c.Messages); err != nil {
+		return nil, fmt.Errorf("invalid AI session %q: %v", name, err)
+	}
+	return c, nil
+}
+
+// Save stores the conversation, replacing an earlier save
+func (c *Conversation) Save() error {
+	d, err := db.Open()
+	if err != nil {
+		return err
+	}
+	messages, err := json.Marshal(c.Messages)
+	if err != nil {
+		return err
+	}
+	if c.Messages == nil {
+		messages = []byte("[]")
+	}
+	_, err = d.Exec(`INSERT INTO conversations (name, created, updated, messages) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET updated = excluded.updated, messages = excluded.messages`,
+		c.Name, c.Created.UTC().Format(time.RFC3339Nano), c.Updated.UTC().Format(time.RFC3339Nano), string(messages))
+	return err
+}
+
+// Summary describes a saved conversation without its messages
+type Summary struct {
+	Name     string
+	Created  time.Time
+	Updated  time.Time
+	Messages int
+}
+
+// Conversations lists the saved conversations, most recently used first
+func Conversations() ([]Summary, error) {
+	d, err := db.Open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.Query(`SELECT name, created, updated, json_array_length(messages) FROM conversations ORDER BY updated DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Summary
+	for rows.Next() {
+		var c Summary
+		var created, updated string
+		if err := rows.Scan(&c.Name, &created, &updated, &c.Messages); err != nil {
+			return nil, err
+		}
+		c.Created, _ = time.Parse(time.RFC3339Nano, created)
+		c.Updated, _ = time.Parse(time.RFC3339Nano, updated)
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// DeleteConversation removes a saved conversation
+func DeleteConversation(name string) error {
+	d, err := db.Open()
+	if err != nil {
+		return err
+	}
+	res, err := d.Exec(`DELETE FROM conversations WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no AI session %q", name)
+	}
+	return nil
+}
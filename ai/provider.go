@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Provider sends the messages of a chat to a language model and returns
+// its answer. The first message may have the role "system".
+type Provider interface {
+	Complete(ctx context.Context, messages []Message) (string, error)
+}
+
+// Providers are the provider names NewProvider accepts
+var Providers = []string{"openai", "azure", "anthropic", "ollama"}
+
+// ProviderConfig selects and configures a provider
+type ProviderConfig struct {
+	Provider string // one of Providers; openai when empty
+	Model    string // the provider's default when empty; the deployment for azure
+	Endpoint string // base URL; the provider's public API when empty, required for azure
+	APIKey   string // not used by ollama
+}
+
+// Default models and endpoints
+const (
+	defaultOpenAIModel       = openai.GPT4
+	defaultAnthropicModel    = "claude-3-5-sonnet-latest"
+	defaultAnthropicEndpoint = "https://api.anthropic.com"
+	defaultOllamaModel       = "llama3.1"
+	defaultOllamaEndpoint    = "http://localhost:11434"
+)
+
+// requestTimeout bounds one request to a provider; local models on a
+// laptop can take minutes for a long answer
+const requestTimeout = 5 * time.Minute
+
+// NewProvider returns the provider a configuration names
+func NewProvider(c ProviderConfig) (Provider, error) {
+	switch c.Provider {
+	case "", "openai":
+		config := openai.DefaultConfig(c.APIKey)
+		if c.Endpoint != "" {
+			// An OpenAI compatible server, such as a proxy or vLLM
+			config.BaseURL = strings.TrimRight(c.Endpoint, "/")
+		}
+		return &openaiProvider{client: openai.NewClientWithConfig(config), model: orDefault(c.Model, defaultOpenAIModel)}, nil
+	case "azure":
+		if c.Endpoint == "" || c.Model == "" {
+			return nil, fmt.Errorf("azure needs the endpoint of the resource and the deployment as the model")
+		}
+		config := openai.DefaultAzureConfig(c.APIKey, strings.TrimRight(c.Endpoint, "/"))
+		deployment := c.Model
+		config.AzureModelMapperFunc = func(string) string { return deployment }
+		return &openaiProvider{client: openai.NewClientWithConfig(config), model: deployment}, nil
+	case "anthropic":
+		return &anthropicProvider{
+			endpoint: strings.TrimRight(orDefault(c.Endpoint, defaultAnthropicEndpoint), "/"),
+			model:    orDefault(c.Model, defaultAnthropicModel),
+			apiKey:   c.APIKey,
+		}, nil
+	case "ollama":
+		return &ollamaProvider{
+			endpoint: strings.TrimRight(orDefault(c.Endpoint, defaultOllamaEndpoint), "/"),
+			model:    orDefault(c.Model, defaultOllamaModel),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q (use %s)", c.Provider, strings.Join(Providers, ", "))
+	}
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// openaiProvider is the OpenAI API, Azure OpenAI or a compatible server
+type openaiProvider struct {
+	client *openai.Client
+	model  string
+}
+
+func (p *openaiProvider) Complete(ctx context.Context, messages []Message) (string, error) {
+	req := openai.ChatCompletionRequest{Model: p.model}
+	for _, m := range messages {
+		req.Messages = append(req.Messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	resp, err := p.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("the response has no answer")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// anthropicProvider is the Anthropic Messages API
+type anthropicProvider struct {
+	endpoint, model, apiKey string
+}
+
+// anthropicMaxTokens bounds the length of an answer, which the API requires
+const anthropicMaxTokens = 4096
+
+func (p *anthropicProvider) Complete(ctx context.Context, messages []Message) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	req := struct {
+		Model     string    `json:"model"`
+		MaxTokens int       `json:"max_tokens"`
+		System    string    `json:"system,omitempty"`
+		Messages  []message `json:"messages"`
+	}{Model: p.model, MaxTokens: anthropicMaxTokens}
+	for _, m := range messages {
+		if m.Role == openai.ChatMessageRoleSystem {
+			req.System = m.Content
+			continue
+		}
+		req.Messages = append(req.Messages, message{m.Role, m.Content})
+	}
+	var resp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	headers := map[string]string{"x-api-key": p.apiKey, "anthropic-version": "2023-06-01"}
+	status, err := postJSON(ctx, p.endpoint+"/v1/messages", headers, req, &resp)
+	if resp.Error != nil {
+		return "", fmt.Errorf("%s (HTTP %d)", resp.Error.Message, status)
+	}
+	if err != nil {
+		return "", err
+	}
+	var answer strings.Builder
+	for _, c := range resp.Content {
+		if c.Type == "text" {
+			answer.WriteString(c.Text)
+		}
+	}
+	if answer.Len() == 0 {
+		return "", fmt.Errorf("the response has no answer")
+	}
+	return answer.String(), nil
+}
+
+// ollamaProvider is a local model served by Ollama
+type ollamaProvider struct {
+	endpoint, model string
+}
+
+func (p *ollamaProvider) Complete(ctx context.Context, messages []Message) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	req := struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+		Stream   bool      `json:"stream"`
+	}{Model: p.model}
+	for _, m := range messages {
+		req.Messages = append(req.Messages, message{m.Role, m.Content})
+	}
+	var resp struct {
+		Message message `json:"message"`
+		Error   string  `json:"error"`
+	}
+	status, err := postJSON(ctx, p.endpoint+"/api/chat", nil, req, &resp)
+	if resp.Error != "" {
+		return "", fmt.Errorf("%s (HTTP %d)", resp.Error, status)
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.Message.Content == "" {
+		return "", fmt.Errorf("the response has no answer")
+	}
+	return resp.Message.Content, nil
+}
+
+// postJSON posts a JSON request and decodes the JSON response, also of a
+// failed request so its error message can be shown. It returns the HTTP
+// status.
+func postJSON(ctx context.Context, url string, headers map[string]string, req, resp any) (int, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+	decodeErr := json.Unmarshal(data, resp)
+	if res.StatusCode != http.StatusOK {
+		return res.StatusCode, fmt.Errorf("%s: HTTP %s", url, res.Status)
+	}
+	if decodeErr != nil {
+		return res.StatusCode, fmt.Errorf("%s: invalid response: %v", url, decodeErr)
+	}
+	return res.StatusCode, nil
+}
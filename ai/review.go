@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// MaxChunk is the number of characters of a module sent in one request,
+// leaving room in the context for the instructions and the answer
+const MaxChunk = 12000
+
+// ReviewModes are the instructions for each kind of review
+var ReviewModes = map[string]string{
+	"review": "Review this RAPID code as an experienced robot programmer. List concrete problems: " +
+		"bugs, unsafe motion (zones, speeds, missing WaitRob or error handlers), hard-coded values, " +
+		"unclear names and dead code. Refer to line numbers and keep each point short.",
+	"refactor": "Suggest how to refactor this RAPID code: routines to extract, duplicated code, " +
+		"data to name or move into CONST and PERS declarations, simpler control flow. " +
+		"Show the changed code for each suggestion and refer to line numbers.",
+	"explain": "Explain what this RAPID code does for a technician who maintains the cell: " +
+		"the purpose of each routine, the motion sequence, the signals it waits for and sets, " +
+		"and anything surprising. Refer to line numbers.",
+}
+
+// Chunk is a part of a module sent in one request
+type Chunk struct {
+	From, To int // first and last line
+	Text     string
+}
+
+// Chunks splits a module into parts of at most max characters. Parts end
+// after a routine where possible; only a routine longer than max is cut
+// between lines.
+func Chunks(src string, max int) []Chunk {
+	lines := strings.Split(strings.TrimRight(src, "\r\n"), "\n")
+	// ends[n] marks line n (1-based) as the end of a routine
+	ends := make(map[int]bool)
+	for _, r := range rapid.Routines(src) {
+		ends[r.EndLine] = true
+	}
+	var chunks []Chunk
+	var b strings.Builder
+	from, cut, cutLen := 1, 0, 0
+	flush := func(to int) {
+		text := b.String()
+		chunks = append(chunks, Chunk{From: from, To: to, Text: text[:cutLen]})
+		rest := text[cutLen:]
+		b.Reset()
+		b.WriteString(rest)
+		from, cut, cutLen = to+1, 0, 0
+	}
+	for i, line := range lines {
+		n := i + 1
+		numbered := fmt.Sprintf("%4d: %s\n", n, strings.TrimRight(line, "\r"))
+		for b.Len()+len(numbered) > max && b.Len() > 0 {
+			if cut == 0 {
+				// No routine ends in this part: cut before this line
+				cut, cutLen = n-1, b.Len()
+			}
+			flush(cut)
+		}
+		b.WriteString(numbered)
+		if ends[n] {
+			cut, cutLen = n, b.Len()
+		}
+	}
+	if b.Len() > 0 {
+		cutLen = b.Len()
+		flush(len(lines))
+	}
+	return chunks
+}
+
+// Review sends a module in chunks with the instructions of a review mode
+// and returns one answer per chunk
+func (a *Assistant) Review(name, src, mode string) ([]Chunk, []string, error) {
+	instructions, ok := ReviewModes[mode]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown review mode %q", mode)
+	}
+	chunks := Chunks(src, MaxChunk)
+	var answers []string
+	for i, c := range chunks {
+		part := fmt.Sprintf("This is the module %s, lines numbered.", name)
+		if len(chunks) > 1 {
+			part = fmt.Sprintf("This is part %d of %d of the module %s (lines %d-%d, numbered); the other parts are sent separately.",
+				i+1, len(chunks), name, c.From, c.To)
+		}
+		answer, err := a.complete([]Message{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: instructions + "\n\n" + part + "\n\n" + c.Text},
+		})
+		if err != nil {
+			return chunks[:i], answers, err
+		}
+		answers = append(answers, answer)
+	}
+	return chunks, answers, nil
+}
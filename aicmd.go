@@ -0,0 +1,442 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/ai"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/secrets"
+)
+
+// aiUsage includes the number of messages a follow-up is sent with
+var aiUsage = `Usage:
+  ai help [--session <name>] [--offline] "your question about the code"
+                         Start a new conversation with a question
+  ai continue "follow-up question"
+                         Ask in the current conversation, with its history
+  ai reset               Forget the history of the current conversation
+  ai sessions            List the saved conversations
+  ai resume <name>       Make a saved conversation the current one
+  ai show [name]         Show a conversation, the current one by default
+  ai delete <name>       Delete a saved conversation
+  ai review <file.mod> [--mode review|refactor|explain] [--output <file>]
+                         Send a RAPID module for review (the default), refactoring
+                         suggestions or an explanation
+
+Conversations are saved in the database in the data directory (see db), so
+a troubleshooting session can be picked up the next day with ai resume and
+ai continue. A follow-up is sent with the last ` + strconv.Itoa(ai.MaxHistory) + ` questions and
+answers.
+ai review sends the whole module with line numbers, so the answer can refer
+to them. A module longer than ` + strconv.Itoa(ai.MaxChunk) + ` characters is sent in parts that end
+after a routine, with one answer per part. The code leaves the computer:
+check that the customer allows it.
+
+The assistant is OpenAI's GPT-4 unless the ai_provider setting names
+another (see config): azure (Azure OpenAI, with ai_endpoint set to the
+resource URL and ai_model to the deployment), anthropic, or ollama for a
+model served on the local network (ai_endpoint, default
+http://localhost:11434, and ai_model, default llama3.1). ai_model and
+ai_endpoint also pick another OpenAI model or compatible server. The API key
+is stored with 'secrets set openai_api_key', azure_openai_api_key or
+anthropic_api_key, or set in OPENAI_API_KEY, AZURE_OPENAI_API_KEY or
+ANTHROPIC_API_KEY; Ollama needs none.
+
+Without an API key, or when the provider cannot be reached, ai help and ai
+continue answer from the built-in reference instead: the RAPID commands,
+quick reference topics, error codes, glossary and a set of common
+questions, searched for the words of the question (misspellings included).
+--offline answers that way without trying the provider.
+
+Examples:
+  ai help How do I wait for a digital input with a timeout?
+  ai continue And how do I raise an error when it times out?
+  ai help --session cell3-collision Why does MoveL stop with 50204 motion supervision?
+  ai sessions
+  ai resume cell3-collision
+  ai review RAPID/T_ROB1/MainModule.mod
+  ai review Palletizing.mod --mode explain --output Palletizing-explained.md`
+
+func runAI(args []string) (string, error) {
+	if len(args) < 1 {
+		return aiUsage, nil
+	}
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+	switch args[0] {
+	case "help":
+		name := ""
+		offline := false
+		for len(args) > 1 {
+			if args[1] == "--offline" {
+				offline, args = true, args[1:]
+			} else if len(args) > 2 && args[1] == "--session" {
+				name, args = args[2], args[2:]
+			} else {
+				break
+			}
+		}
+		if len(args) < 2 {
+			return aiUsage, nil
+		}
+		if offline {
+			return offlineAnswer(strings.Join(args[1:], " "), nil), nil
+		}
+		if name != "" && (confirmDisabled || readOnlyFlag) {
+			return "", errors.New("--session is not available here; ask without it for a new conversation")
+		}
+		if name == "" {
+			if name, err = newSessionName(); err != nil {
+				return "", err
+			}
+		} else if _, err := ai.LoadConversation(name); err == nil {
+			return "", fmt.Errorf("there is already a conversation %s; pick it up with 'ai resume %s' and 'ai continue'", name, name)
+		}
+		c, err := ai.NewConversation(name)
+		if err != nil {
+			return "", err
+		}
+		// A read-only run asks without keeping the conversation
+		if !readOnlyFlag {
+			if err := c.Save(); err != nil {
+				return "", err
+			}
+			s.AISession = c.Name
+			if err := config.SaveSettings(s); err != nil {
+				return "", err
+			}
+		}
+		return askAI(c, strings.Join(args[1:], " "))
+	case "continue":
+		if len(args) < 2 {
+			return aiUsage, nil
+		}
+		c, err := currentConversation(s)
+		if err != nil {
+			return "", err
+		}
+		return askAI(c, strings.Join(args[1:], " "))
+	case "reset":
+		c, err := currentConversation(s)
+		if err != nil {
+			return "", err
+		}
+		c.Reset()
+		if err := c.Save(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Cleared the history of %s; the next question starts afresh.", c.Name), nil
+	case "sessions":
+		list, err := ai.Conversations()
+		if err != nil {
+			return "", err
+		}
+		if len(list) == 0 {
+			return "No saved conversations. Start one with 'ai help <question>'.", nil
+		}
+		var b strings.Builder
+		b.WriteString("AI conversations (newest first):\n")
+		for _, c := range list {
+			marker := " "
+			if c.Name == s.AISession {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, "%s %-28s %3d messages  last used %s\n", marker, c.Name, c.Messages, c.Updated.Local().Format("2006-01-02 15:04"))
+		}
+		return strings.TrimRight(b.String(), "\n"), nil
+	case "resume":
+		if len(args) != 2 {
+			return aiUsage, nil
+		}
+		c, err := ai.LoadConversation(args[1])
+		if err != nil {
+			return "", err
+		}
+		s.AISession = c.Name
+		if err := config.SaveSettings(s); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Resumed %s (%d messages); ask with 'ai continue <question>'.", c.Name, len(c.Messages)), nil
+	case "show":
+		var c *ai.Conversation
+		switch len(args) {
+		case 1:
+			c, err = currentConversation(s)
+		case 2:
+			c, err = ai.LoadConversation(args[1])
+		default:
+			return aiUsage, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return describeConversation(c), nil
+	case "delete":
+		if len(args) != 2 {
+			return aiUsage, nil
+		}
+		if err := ai.DeleteConversation(args[1]); err != nil {
+			return "", err
+		}
+		if s.AISession == args[1] {
+			s.AISession = ""
+			if err := config.SaveSettings(s); err != nil {
+				return "", err
+			}
+		}
+		return "Deleted the conversation " + args[1], nil
+	case "review":
+		return aiReview(args[1:])
+	default:
+		return aiUsage, nil
+	}
+}
+
+// currentConversation loads the conversation ai continue adds to
+func currentConversation(s config.Settings) (*ai.Conversation, error) {
+	if s.AISession == "" {
+		return nil, fmt.Errorf("no current conversation; start one with 'ai help <question>' or pick one with 'ai resume <name>'")
+	}
+	return ai.LoadConversation(s.AISession)
+}
+
+// aiKeys are the secrets holding the API key of each provider; ollama
+// needs none
+var aiKeys = map[string]string{
+	"openai":    "openai_api_key",
+	"azure":     "azure_openai_api_key",
+	"anthropic": "anthropic_api_key",
+}
+
+// aiAssistant returns the assistant of the provider in the settings
+func aiAssistant() (*ai.Assistant, error) {
+	s, err := config.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	c := ai.ProviderConfig{Provider: orDefault(s.AIProvider, "openai"), Model: s.AIModel, Endpoint: s.AIEndpoint}
+	if name, ok := aiKeys[c.Provider]; ok {
+		if c.APIKey, err = secrets.Get(name); err != nil {
+			return nil, err
+		}
+		if c.APIKey == "" {
+			return nil, fmt.Errorf("no API key for %s; store one with 'secrets set %s' or set %s", c.Provider, name, secrets.Known[name])
+		}
+	}
+	p, err := ai.NewProvider(c)
+	if err != nil {
+		return nil, err
+	}
+	return ai.NewAssistant(p), nil
+}
+
+// askAI sends a question in a conversation and saves the answer with it
+func askAI(c *ai.Conversation, question string) (string, error) {
+	assistant, err := aiAssistant()
+	if err != nil {
+		return offlineAnswer(question, err), nil
+	}
+	answer, err := assistant.Ask(c, question)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return offlineAnswer(question, err), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting AI help: %v", err)
+	}
+	if readOnlyFlag {
+		return answer, nil
+	}
+	if err := c.Save(); err != nil {
+		return answer + "\n\nWarning: the conversation was not saved: " + err.Error(), nil
+	}
+	return answer, nil
+}
+
+// newSessionName names a new conversation by the time it starts, with a
+// random suffix so questions asked in the same second stay apart
+func newSessionName() (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return time.Now().Format("2006-01-02-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// offlineAnswers is the number of reference entries an offline answer shows
+// in full; the next ones are listed by name
+const offlineAnswers = 3
+
+// offlineAnswer answers a question from the RAPID reference and the bundled
+// questions and answers, for when no language model can be reached. why is
+// the reason, nil when --offline was asked for.
+func offlineAnswer(question string, why error) string {
+	var b strings.Builder
+	if why != nil {
+		fmt.Fprintf(&b, "The AI assistant is not available (%v).\n", why)
+	}
+	b.WriteString("Offline answer from the built-in reference; it searches, it does not understand the question:\n")
+	shown := 0
+	var more []string
+	for _, m := range abb.Search(question) {
+		if shown == offlineAnswers {
+			if len(more) < 5 && m.Kind != abb.KindPart {
+				more = append(more, referenceCommand(m))
+			}
+			continue
+		}
+		text := referenceEntry(m)
+		if text == "" {
+			continue
+		}
+		b.WriteString("\n" + text + "\n")
+		shown++
+	}
+	if shown == 0 {
+		b.WriteString("\nNothing in the reference matches. Try other words, or 'abb search' and 'glossary'.")
+	}
+	if len(more) > 0 {
+		b.WriteString("\nSee also: " + strings.Join(more, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// offlineQuickrefLines bounds a quick reference topic in an offline answer
+const offlineQuickrefLines = 15
+
+// referenceEntry shows a search match in an offline answer; spare parts
+// are left out
+func referenceEntry(m abb.Match) string {
+	switch m.Kind {
+	case abb.KindFAQ:
+		if e, ok := abb.FAQ(m.Key); ok {
+			return fmt.Sprintf("Q: %s\n%s", e.Question, e.Answer)
+		}
+	case abb.KindCommand:
+		if c, ok := abb.Command(m.Key); ok {
+			return fmt.Sprintf("%s (%s)\n%s\nSyntax:\n%s\nExample:\n%s", c.Name, referenceCommand(m), c.Description, c.Syntax, c.Example)
+		}
+	case abb.KindTopic:
+		if content, ok := abb.Topic(m.Key); ok {
+			lines := strings.Split(content, "\n")
+			if len(lines) > offlineQuickrefLines {
+				lines = append(lines[:offlineQuickrefLines], "...")
+			}
+			return fmt.Sprintf("%s:\n%s", referenceCommand(m), strings.Join(lines, "\n"))
+		}
+	case abb.KindError:
+		if e, ok := abb.Error(m.Key); ok {
+			return fmt.Sprintf("%s (%s)\nCause: %s\nRecovery: %s", e.Name, referenceCommand(m), e.Cause, e.Recovery)
+		}
+	case abb.KindTerm:
+		if t, ok := abb.GlossaryTerm(m.Key); ok {
+			return fmt.Sprintf("%s (%s)\n%s", t.Term, referenceCommand(m), t.Definition)
+		}
+	}
+	return ""
+}
+
+// referenceCommand is the command that shows a search match
+func referenceCommand(m abb.Match) string {
+	switch m.Kind {
+	case abb.KindCommand:
+		return "abb command " + m.Key
+	case abb.KindTopic:
+		return "abb quickref " + m.Key
+	case abb.KindError:
+		return "error " + m.Key
+	case abb.KindTerm:
+		return "glossary " + m.Key
+	case abb.KindFAQ:
+		return "ai help --offline " + m.Key
+	}
+	return m.Kind + " " + m.Key
+}
+
+// aiReview sends a RAPID module to the assistant, in parts if it is long
+func aiReview(args []string) (string, error) {
+	if len(args) < 1 {
+		return aiUsage, nil
+	}
+	path := args[0]
+	mode, output := "review", ""
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return aiUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--mode":
+			if _, ok := ai.ReviewModes[value]; !ok {
+				return "", fmt.Errorf("unknown mode %q (use review, refactor or explain)", value)
+			}
+			mode = value
+		case "--output":
+			output = value
+		default:
+			return aiUsage, nil
+		}
+		i++
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	assistant, err := aiAssistant()
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(path)
+	chunks, answers, err := assistant.Review(name, string(data), mode)
+	var b strings.Builder
+	for i, answer := range answers {
+		if len(chunks) > 1 || err != nil {
+			fmt.Fprintf(&b, "## Lines %d-%d\n\n", chunks[i].From, chunks[i].To)
+		}
+		b.WriteString(strings.TrimSpace(answer) + "\n\n")
+	}
+	text := strings.TrimRight(b.String(), "\n")
+	if err != nil {
+		return text, fmt.Errorf("getting AI help: %v", err)
+	}
+	if output == "" {
+		return text, nil
+	}
+	title := strings.ToUpper(mode[:1]) + mode[1:]
+	doc := fmt.Sprintf("# %s of %s\n\n%s\n", title, name, text)
+	if err := writeFile(output, []byte(doc), 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote the %s of %s to %s", mode, name, output), nil
+}
+
+func describeConversation(c *ai.Conversation) string {
+	if len(c.Messages) == 0 {
+		return fmt.Sprintf("%s has no messages yet.", c.Name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Conversation %s, started %s\n", c.Name, c.Created.Local().Format("2006-01-02 15:04"))
+	for _, m := range c.Messages {
+		who := "You"
+		if m.Role != "user" {
+			who = "AI"
+		}
+		fmt.Fprintf(&b, "\n[%s] %s:\n%s\n", m.Time.Local().Format("15:04"), who, m.Content)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/modbustcp"
+	"github.com/polyfant/automation-helper-cli/notify"
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+func init() {
+	commandRegistry["alarms"] = Command{
+		Group:       groupIntegration,
+		Description: "Annunciate controller event log and PLC alarms, with notifications",
+		Usage:       alarmsUsage,
+		Execute:     text(runAlarms),
+	}
+}
+
+const alarmsUsage = `Usage: alarms watch [--host localhost] [--plc host:502 --plc-tags tags.csv]
+       [--interval 1s] [--dedup 60s] [--min-severity warning]
+       [--notify <target>]... [--no-color]
+Follows the controller event log over Robot Web Services and, optionally,
+alarm coils of a PLC over Modbus TCP, and prints new alarms colored by
+severity (red critical, yellow warning). An alarm repeated within the
+--dedup window is counted instead of printed again. Critical alarms are
+pushed to every --notify target (see 'help notify' for the targets), for
+unattended test runs; the rules set up with notify get the alarms at their
+own minimum severity.
+
+Event log errors are critical, warnings are warnings. The PLC tag file is
+a CSV with the columns coil (zero-based address), text and severity
+(critical, warning or info); a coil going to 1 raises the alarm.
+
+Examples:
+  alarms watch --host 192.168.125.1 --notify ntfy:https://ntfy.sh/cell3
+  alarms watch --plc 192.168.0.10 --plc-tags alarms.csv --min-severity info`
+
+// Alarm severities, lowest first
+var alarmSeverities = []string{"info", "warning", "critical"}
+
+func severityRank(s string) int {
+	for i, name := range alarmSeverities {
+		if name == s {
+			return i
+		}
+	}
+	return -1
+}
+
+type plcTag struct {
+	coil     uint16
+	text     string
+	severity string
+}
+
+type alarm struct {
+	source   string // robot or plc
+	code     string
+	text     string
+	severity string
+	time     time.Time
+}
+
+func (a alarm) key() string {
+	return a.source + "|" + a.code + "|" + a.text
+}
+
+func runAlarms(args []string) (string, error) {
+	if len(args) < 1 || args[0] != "watch" {
+		return alarmsUsage, nil
+	}
+	host, plcAddr, tagFile := "localhost", "", ""
+	var user, password string
+	interval, dedup := time.Second, time.Minute
+	minSeverity, color := "warning", true
+	var notifiers []notify.Notifier
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--no-color" {
+			color = false
+			continue
+		}
+		if i+1 >= len(args) {
+			return alarmsUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		case "--plc":
+			plcAddr = value
+		case "--plc-tags":
+			tagFile = value
+		case "--interval", "--dedup":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+			}
+			if args[i] == "--interval" {
+				interval = d
+			} else {
+				dedup = d
+			}
+		case "--min-severity":
+			if severityRank(value) < 0 {
+				return "", fmt.Errorf("invalid value %q for --min-severity", value)
+			}
+			minSeverity = value
+		case "--notify":
+			n, err := notifier(value)
+			if err != nil {
+				return "", err
+			}
+			notifiers = append(notifiers, n)
+		default:
+			return alarmsUsage, nil
+		}
+		i++
+	}
+	if (plcAddr == "") != (tagFile == "") {
+		return "", errors.New("--plc and --plc-tags go together")
+	}
+
+	var tags []plcTag
+	var plc *modbustcp.Client
+	if tagFile != "" {
+		var err error
+		if tags, err = loadPLCTags(tagFile); err != nil {
+			return "", fmt.Errorf("%s: %v", tagFile, err)
+		}
+		plc = modbustcp.NewClient(plcAddr)
+		defer plc.Close()
+	}
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	last, err := c.LastEvent()
+	if err != nil {
+		return "", fmt.Errorf("cannot read the event log at %s: %v", c.Base, err)
+	}
+
+	a := &annunciator{
+		host:      host,
+		dedup:     dedup,
+		min:       severityRank(minSeverity),
+		color:     color,
+		notifiers: notifiers,
+		seen:      map[string]*alarmSeen{},
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Watching alarms of %s", c.Base)
+	if plc != nil {
+		fmt.Printf(" and %d PLC tags at %s", len(tags), plc.Addr)
+	}
+	fmt.Println(" (Ctrl+C to stop)")
+	active := make([]bool, len(tags))
+	for {
+		events, err := c.Events(last)
+		if err != nil {
+			a.warn("event log: " + err.Error())
+		}
+		for _, e := range events {
+			last = e.Seq
+			a.raise(alarm{source: "robot", code: e.Code, text: e.Title, severity: eventSeverity(e.Type), time: time.Now()})
+		}
+		if plc != nil {
+			if err := pollPLCTags(plc, tags, active, a); err != nil {
+				a.warn("PLC: " + err.Error())
+			}
+		}
+		select {
+		case <-interrupt:
+			return fmt.Sprintf("\nStopped watching alarms: %d raised", a.raised), nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func eventSeverity(msgType int) string {
+	switch msgType {
+	case 3:
+		return "critical"
+	case 2:
+		return "warning"
+	}
+	return "info"
+}
+
+// pollPLCTags raises the alarm of every coil that went to 1 since the last
+// poll; active holds the previous coil states
+func pollPLCTags(plc *modbustcp.Client, tags []plcTag, active []bool, a *annunciator) error {
+	lo, hi := tags[0].coil, tags[0].coil
+	for _, t := range tags {
+		lo, hi = min(lo, t.coil), max(hi, t.coil)
+	}
+	coils, err := plc.ReadCoils(lo, hi-lo+1)
+	if err != nil {
+		return err
+	}
+	for i, t := range tags {
+		on := coils[t.coil-lo]
+		if on && !active[i] {
+			a.raise(alarm{source: "plc", code: strconv.Itoa(int(t.coil)), text: t.text, severity: t.severity, time: time.Now()})
+		}
+		active[i] = on
+	}
+	return nil
+}
+
+func loadPLCTags(path string) ([]plcTag, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var tags []plcTag
+	for n, rec := range records {
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: expected coil,text,severity", n+1)
+		}
+		coil, err := strconv.ParseUint(strings.TrimSpace(rec[0]), 10, 16)
+		if err != nil {
+			if n == 0 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("line %d: invalid coil %q", n+1, rec[0])
+		}
+		t := plcTag{coil: uint16(coil), text: strings.TrimSpace(rec[1]), severity: "critical"}
+		if len(rec) > 2 && strings.TrimSpace(rec[2]) != "" {
+			t.severity = strings.ToLower(strings.TrimSpace(rec[2]))
+			if severityRank(t.severity) < 0 {
+				return nil, fmt.Errorf("line %d: invalid severity %q", n+1, rec[2])
+			}
+		}
+		tags = append(tags, t)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags")
+	}
+	lo, hi := tags[0].coil, tags[0].coil
+	for _, t := range tags {
+		lo, hi = min(lo, t.coil), max(hi, t.coil)
+	}
+	if hi-lo >= 2000 {
+		return nil, fmt.Errorf("tags span more than 2000 coils")
+	}
+	return tags, nil
+}
+
+type alarmSeen struct {
+	last    time.Time
+	repeats int
+}
+
+// annunciator prints and forwards alarms, suppressing repeats within the
+// dedup window
+type annunciator struct {
+	host      string
+	dedup     time.Duration
+	min       int
+	color     bool
+	notifiers []notify.Notifier
+	seen      map[string]*alarmSeen
+	raised    int
+}
+
+func (a *annunciator) raise(al alarm) {
+	if severityRank(al.severity) < a.min {
+		return
+	}
+	s := a.seen[al.key()]
+	if s != nil && al.time.Sub(s.last) < a.dedup {
+		s.repeats++
+		return
+	}
+	repeats := 0
+	if s != nil {
+		repeats = s.repeats
+	}
+	a.seen[al.key()] = &alarmSeen{last: al.time}
+	a.raised++
+
+	line := fmt.Sprintf("%s  %-8s %-5s %-6s %s", al.time.Format("15:04:05"), strings.ToUpper(al.severity), al.source, al.code, al.text)
+	if repeats > 0 {
+		line += fmt.Sprintf("  (%d repeats suppressed)", repeats)
+	}
+	if a.color {
+		switch al.severity {
+		case "critical":
+			line = "\033[31m" + line + "\033[0m"
+		case "warning":
+			line = "\033[33m" + line + "\033[0m"
+		}
+	}
+	fmt.Println(line)
+
+	title := fmt.Sprintf("%s alarm %s", al.source, al.code)
+	emitNotification(notification{Source: "alarms", Severity: al.severity, Title: title, Text: al.text, Host: a.host, Time: al.time})
+	if al.severity != "critical" {
+		return
+	}
+	msg := notify.Message{
+		Title:    title,
+		Text:     al.text,
+		Priority: "urgent",
+	}
+	for _, n := range a.notifiers {
+		if err := n.Notify(msg); err != nil {
+			a.warn("notification: " + err.Error())
+		}
+	}
+}
+
+func (a *annunciator) warn(msg string) {
+	fmt.Printf("%s  Warning: %s\n", time.Now().Format("15:04:05"), msg)
+}
@@ -0,0 +1,229 @@
+// Package analysis turns recorded signal logs into production figures:
+// cycle times, stoppages and OEE
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/datalog"
+)
+
+// CycleOptions name the columns of the log to analyze
+type CycleOptions struct {
+	Start  string        // Signal whose rising edge starts a cycle
+	Alarm  string        // Optional alarm code signal, 0 when no alarm
+	Reject string        // Optional signal whose rising edge marks a bad part
+	Ideal  time.Duration // Ideal cycle time; zero takes the 10th percentile
+	// A cycle longer than StopFactor times the median contains a stoppage
+	StopFactor float64
+}
+
+// Cycle is the time from one rising edge of the start signal to the next
+type Cycle struct {
+	Start    time.Time
+	Duration time.Duration
+	Alarm    string // Most frequent alarm code during the cycle
+	Stopped  bool
+	Rejected bool
+}
+
+// Stoppage sums the cycles stopped by one alarm code
+type Stoppage struct {
+	Alarm string
+	Count int
+	Lost  time.Duration // Time beyond the median cycle
+}
+
+// CycleReport is the result of Cycles
+type CycleReport struct {
+	Cycles                           []Cycle
+	Planned                          time.Duration // First to last sample
+	Min, Max, Mean, Median, P90, Std time.Duration
+	Ideal                            time.Duration
+	Stoppages                        []Stoppage // Most lost time first
+	Rejects                          int
+
+	Availability, Performance, Quality, OEE float64
+}
+
+// Cycles detects cycle boundaries in a log and computes the statistics.
+// Availability counts the time lost in stopped cycles as downtime,
+// performance compares the ideal cycle time with the remaining run time
+// and quality is the share of cycles without a reject.
+func Cycles(t *datalog.Table, o CycleOptions) (*CycleReport, error) {
+	start := t.Column(o.Start)
+	if start < 0 {
+		return nil, fmt.Errorf("no column %s", o.Start)
+	}
+	alarm, reject := -1, -1
+	if o.Alarm != "" {
+		if alarm = t.Column(o.Alarm); alarm < 0 {
+			return nil, fmt.Errorf("no column %s", o.Alarm)
+		}
+	}
+	if o.Reject != "" {
+		if reject = t.Column(o.Reject); reject < 0 {
+			return nil, fmt.Errorf("no column %s", o.Reject)
+		}
+	}
+	if o.StopFactor <= 1 {
+		o.StopFactor = 1.5
+	}
+
+	edges := risingEdges(t, start)
+	if len(edges) < 2 {
+		return nil, fmt.Errorf("%s rises %d times; at least two cycle starts are needed", o.Start, len(edges))
+	}
+	r := &CycleReport{Planned: t.Times[len(t.Times)-1].Sub(t.Times[0])}
+	rejects := map[int]bool{}
+	if reject >= 0 {
+		for _, row := range risingEdges(t, reject) {
+			// A reject belongs to the cycle it happens in
+			i := sort.SearchInts(edges, row+1) - 1
+			if i >= 0 && i < len(edges)-1 {
+				rejects[i] = true
+			}
+		}
+	}
+	for i := 0; i+1 < len(edges); i++ {
+		c := Cycle{Start: t.Times[edges[i]], Duration: t.Times[edges[i+1]].Sub(t.Times[edges[i]]), Rejected: rejects[i]}
+		if alarm >= 0 {
+			c.Alarm = dominantCode(t, alarm, edges[i], edges[i+1])
+		}
+		r.Cycles = append(r.Cycles, c)
+		if c.Rejected {
+			r.Rejects++
+		}
+	}
+
+	durations := make([]time.Duration, len(r.Cycles))
+	var sum float64
+	for i, c := range r.Cycles {
+		durations[i] = c.Duration
+		sum += c.Duration.Seconds()
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mean := sum / float64(len(durations))
+	var variance float64
+	for _, d := range durations {
+		variance += (d.Seconds() - mean) * (d.Seconds() - mean)
+	}
+	r.Min, r.Max = durations[0], durations[len(durations)-1]
+	r.Mean = seconds(mean)
+	r.Std = seconds(math.Sqrt(variance / float64(len(durations))))
+	r.Median, r.P90 = percentile(durations, 50), percentile(durations, 90)
+	r.Ideal = o.Ideal
+	if r.Ideal <= 0 {
+		r.Ideal = percentile(durations, 10)
+	}
+
+	byAlarm := map[string]*Stoppage{}
+	var lost time.Duration
+	limit := time.Duration(o.StopFactor * float64(r.Median))
+	for i, c := range r.Cycles {
+		if c.Duration <= limit {
+			continue
+		}
+		r.Cycles[i].Stopped = true
+		code := c.Alarm
+		if code == "" {
+			code = "no alarm"
+		}
+		s := byAlarm[code]
+		if s == nil {
+			s = &Stoppage{Alarm: code}
+			byAlarm[code] = s
+		}
+		s.Count++
+		s.Lost += c.Duration - r.Median
+		lost += c.Duration - r.Median
+	}
+	for _, s := range byAlarm {
+		r.Stoppages = append(r.Stoppages, *s)
+	}
+	sort.Slice(r.Stoppages, func(i, j int) bool {
+		if r.Stoppages[i].Lost != r.Stoppages[j].Lost {
+			return r.Stoppages[i].Lost > r.Stoppages[j].Lost
+		}
+		return r.Stoppages[i].Alarm < r.Stoppages[j].Alarm
+	})
+
+	run := r.Planned - lost
+	if r.Planned > 0 {
+		r.Availability = run.Seconds() / r.Planned.Seconds()
+	}
+	if run > 0 {
+		r.Performance = math.Min(1, r.Ideal.Seconds()*float64(len(r.Cycles))/run.Seconds())
+	}
+	r.Quality = float64(len(r.Cycles)-r.Rejects) / float64(len(r.Cycles))
+	r.OEE = r.Availability * r.Performance * r.Quality
+	return r, nil
+}
+
+// Histogram counts the cycle times in equal bins between Min and Max
+func (r *CycleReport) Histogram(bins int) []int {
+	counts := make([]int, bins)
+	width := float64(r.Max-r.Min) / float64(bins)
+	for _, c := range r.Cycles {
+		i := 0
+		if width > 0 {
+			i = min(bins-1, int(float64(c.Duration-r.Min)/width))
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// risingEdges returns the rows where a signal goes from below 0.5 to 0.5 or
+// above; missing samples keep the previous state
+func risingEdges(t *datalog.Table, column int) []int {
+	var edges []int
+	high, known := false, false
+	for i, row := range t.Rows {
+		v := row[column]
+		if math.IsNaN(v) {
+			continue
+		}
+		on := v >= 0.5
+		if on && !high && known {
+			edges = append(edges, i)
+		}
+		high, known = on, true
+	}
+	return edges
+}
+
+// dominantCode returns the most frequent non-zero value of a column in the
+// rows [from, to)
+func dominantCode(t *datalog.Table, column, from, to int) string {
+	counts := map[float64]int{}
+	best, bestCount := 0.0, 0
+	for _, row := range t.Rows[from:to] {
+		v := row[column]
+		if math.IsNaN(v) || v == 0 {
+			continue
+		}
+		counts[v]++
+		if counts[v] > bestCount || counts[v] == bestCount && v < best {
+			best, bestCount = v, counts[v]
+		}
+	}
+	if bestCount == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(best, 'g', -1, 64)
+}
+
+// percentile uses the nearest rank of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(0, min(len(sorted)-1, rank-1))]
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
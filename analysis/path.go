@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/datalog"
+)
+
+// pathWindow is how many segments ahead of the last match a point of the
+// compared run is searched on the reference path. Both runs follow the same
+// program, so the match moves forward along the path.
+const pathWindow = 400
+
+// PathPoint is a TCP position of a recorded run
+type PathPoint struct {
+	Time time.Time
+	Pos  [3]float64
+}
+
+// PathComparison is the deviation of a run from a reference run
+type PathComparison struct {
+	Reference, Compared int // Points used of each run
+	Durations           [2]time.Duration
+	Length              [2]float64 // Path length in mm
+	Mean, RMS, P95, Max float64    // Deviation in mm
+	MaxAt               PathPoint  // Point of the compared run with the largest deviation
+	Offset              [3]float64 // Mean deviation per axis, a systematic shift
+	Exceeded            int        // Points beyond the tolerance
+}
+
+// TCPPath reads the positions of a log; the columns are the names of x, y
+// and z. Samples with missing values and standstill repeats are dropped.
+func TCPPath(t *datalog.Table, columns [3]string) ([]PathPoint, error) {
+	var idx [3]int
+	for i, name := range columns {
+		if idx[i] = t.Column(name); idx[i] < 0 {
+			return nil, fmt.Errorf("no column %s", name)
+		}
+	}
+	var path []PathPoint
+	for r, row := range t.Rows {
+		p := PathPoint{Time: t.Times[r]}
+		missing := false
+		for i := range idx {
+			p.Pos[i] = row[idx[i]]
+			missing = missing || math.IsNaN(p.Pos[i])
+		}
+		if missing || len(path) > 0 && path[len(path)-1].Pos == p.Pos {
+			continue
+		}
+		path = append(path, p)
+	}
+	if len(path) < 2 {
+		return nil, fmt.Errorf("fewer than two TCP positions")
+	}
+	return path, nil
+}
+
+// ComparePaths measures for every point of run the distance to the
+// polyline of reference. tolerance (mm) counts the points beyond it.
+func ComparePaths(reference, run []PathPoint, tolerance float64) *PathComparison {
+	c := &PathComparison{Reference: len(reference), Compared: len(run)}
+	for i, p := range [][]PathPoint{reference, run} {
+		c.Durations[i] = p[len(p)-1].Time.Sub(p[0].Time)
+		for j := 1; j < len(p); j++ {
+			c.Length[i] += distance(p[j-1].Pos, p[j].Pos)
+		}
+	}
+
+	deviations := make([]float64, len(run))
+	var sum, squares float64
+	last := 0
+	for i, p := range run {
+		best, bestSeg, bestFoot := math.Inf(1), last, p.Pos
+		search := func(from, to int) {
+			for s := max(0, from); s < min(len(reference)-1, to); s++ {
+				foot := closestOnSegment(reference[s].Pos, reference[s+1].Pos, p.Pos)
+				if d := distance(foot, p.Pos); d < best {
+					best, bestSeg, bestFoot = d, s, foot
+				}
+			}
+		}
+		search(last-pathWindow/8, last+pathWindow)
+		// A far match may mean the window lost track, e.g. after a stop
+		if best > 10*max(tolerance, 1) {
+			search(0, len(reference)-1)
+		}
+		last = bestSeg
+		deviations[i] = best
+		sum += best
+		squares += best * best
+		for k := range c.Offset {
+			c.Offset[k] += p.Pos[k] - bestFoot[k]
+		}
+		if best > c.Max {
+			c.Max, c.MaxAt = best, p
+		}
+		if tolerance > 0 && best > tolerance {
+			c.Exceeded++
+		}
+	}
+	n := float64(len(run))
+	c.Mean, c.RMS = sum/n, math.Sqrt(squares/n)
+	for k := range c.Offset {
+		c.Offset[k] /= n
+	}
+	sort.Float64s(deviations)
+	c.P95 = deviations[max(0, int(math.Ceil(0.95*n))-1)]
+	return c
+}
+
+func closestOnSegment(a, b, p [3]float64) [3]float64 {
+	var ab, ap [3]float64
+	var lengthSq, dot float64
+	for i := range a {
+		ab[i], ap[i] = b[i]-a[i], p[i]-a[i]
+		lengthSq += ab[i] * ab[i]
+		dot += ab[i] * ap[i]
+	}
+	t := 0.0
+	if lengthSq > 0 {
+		t = math.Max(0, math.Min(1, dot/lengthSq))
+	}
+	var foot [3]float64
+	for i := range a {
+		foot[i] = a[i] + t*ab[i]
+	}
+	return foot
+}
+
+func distance(a, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/analysis"
+	"github.com/polyfant/automation-helper-cli/datalog"
+)
+
+func init() {
+	commandRegistry["analyze"] = Command{
+		Group:       groupIntegration,
+		Description: "Cycle statistics, OEE and path deviation from logged data",
+		Usage:       analyzeUsage,
+		Execute:     text(runAnalyze),
+	}
+}
+
+const analyzeUsage = `Usage: analyze cycles <log.csv> --start-signal <name> [--alarm-signal <name>]
+       [--reject-signal <name>] [--ideal <seconds>] [--stop-factor 1.5]
+Detects cycles in a log from log record: each rising edge of the start
+signal begins a cycle. Reports the cycle time distribution, a Pareto of
+stoppages by alarm code and an OEE estimate.
+
+A cycle longer than --stop-factor times the median contains a stoppage;
+the time beyond the median is lost and charged to the alarm code seen
+most during the cycle (--alarm-signal, e.g. a group output with the
+active alarm number). Rising edges of --reject-signal count bad parts.
+The ideal cycle time defaults to the fastest 10% of the cycles.
+
+Usage: analyze path <reference.csv> <run.csv> [--columns x,y,z] [--tolerance mm]
+Compares the TCP path of a run with a reference run, e.g. before and after
+maintenance or a collision: the distance of every point of the run to the
+reference path, with mean, RMS, 95th percentile, maximum and the mean
+offset per axis. Timing differences between the runs do not matter. The
+position columns default to tcp_x, tcp_y, tcp_z (log record --tcp) or x,
+y, z. With --tolerance the result is an error when a point deviates more.
+
+Examples:
+  analyze cycles run1.csv --start-signal do_CycleStart --alarm-signal go_AlarmCode
+  analyze path path_before.csv path_after.csv --tolerance 0.5`
+
+func runAnalyze(args []string) (string, error) {
+	if len(args) >= 3 && args[0] == "path" {
+		return analyzePath(args[1:])
+	}
+	if len(args) < 2 || args[0] != "cycles" {
+		return analyzeUsage, nil
+	}
+	path := args[1]
+	var o analysis.CycleOptions
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return analyzeUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--start-signal":
+			o.Start = value
+		case "--alarm-signal":
+			o.Alarm = value
+		case "--reject-signal":
+			o.Reject = value
+		case "--ideal", "--stop-factor":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f <= 0 || args[i] == "--stop-factor" && f <= 1 {
+				return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+			}
+			if args[i] == "--ideal" {
+				o.Ideal = time.Duration(f * float64(time.Second))
+			} else {
+				o.StopFactor = f
+			}
+		default:
+			return analyzeUsage, nil
+		}
+		i++
+	}
+	if o.Start == "" {
+		return analyzeUsage, nil
+	}
+
+	t, err := datalog.ReadCSV(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	r, err := analysis.Cycles(t, o)
+	if err != nil {
+		return "", err
+	}
+	return formatCycleReport(path, r), nil
+}
+
+func formatCycleReport(path string, r *analysis.CycleReport) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	secs := func(d time.Duration) string { return fmt.Sprintf("%.2f s", d.Seconds()) }
+
+	w("Cycle analysis of %s: %d cycles in %s", path, len(r.Cycles), r.Planned.Round(time.Second))
+	w("")
+	w("Cycle time")
+	w("  min %s   median %s   mean %s   p90 %s   max %s   std dev %s",
+		secs(r.Min), secs(r.Median), secs(r.Mean), secs(r.P90), secs(r.Max), secs(r.Std))
+	counts := r.Histogram(10)
+	most := 1
+	for _, n := range counts {
+		most = max(most, n)
+	}
+	width := (r.Max - r.Min).Seconds() / float64(len(counts))
+	for i, n := range counts {
+		lo := r.Min.Seconds() + float64(i)*width
+		w("  %7.2f-%-7.2f %-30s %d", lo, lo+width, strings.Repeat("#", (n*30+most-1)/most), n)
+		if width == 0 {
+			break
+		}
+	}
+
+	w("")
+	if len(r.Stoppages) == 0 {
+		w("Stoppages: none (no cycle above the stop limit)")
+	} else {
+		var total time.Duration
+		for _, s := range r.Stoppages {
+			total += s.Lost
+		}
+		w("Stoppages by alarm (Pareto)")
+		w("  %-12s %6s %10s %7s %7s", "Alarm", "Count", "Lost", "Share", "Cum.")
+		var cumulative time.Duration
+		for _, s := range r.Stoppages {
+			cumulative += s.Lost
+			w("  %-12s %6d %10s %6.1f%% %6.1f%%", s.Alarm, s.Count, secs(s.Lost),
+				100*s.Lost.Seconds()/total.Seconds(), 100*cumulative.Seconds()/total.Seconds())
+		}
+	}
+
+	w("")
+	w("OEE estimate (ideal cycle %s)", secs(r.Ideal))
+	w("  Availability %5.1f%%", 100*r.Availability)
+	w("  Performance  %5.1f%%", 100*r.Performance)
+	w("  Quality      %5.1f%%  (rejects: %d)", 100*r.Quality, r.Rejects)
+	w("  OEE          %5.1f%%", 100*r.OEE)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func analyzePath(args []string) (string, error) {
+	refPath, runPath := args[0], args[1]
+	var columns []string
+	tolerance := 0.0
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return analyzeUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--columns":
+			columns = strings.Split(value, ",")
+			if len(columns) != 3 {
+				return "", fmt.Errorf("invalid value %q for --columns; give x,y,z", value)
+			}
+		case "--tolerance":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f <= 0 {
+				return "", fmt.Errorf("invalid value %q for --tolerance", value)
+			}
+			tolerance = f
+		default:
+			return analyzeUsage, nil
+		}
+		i++
+	}
+
+	var paths [2][]analysis.PathPoint
+	for i, path := range []string{refPath, runPath} {
+		t, err := datalog.ReadCSV(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+		names := [3]string{"tcp_x", "tcp_y", "tcp_z"}
+		if len(columns) == 3 {
+			names = [3]string{strings.TrimSpace(columns[0]), strings.TrimSpace(columns[1]), strings.TrimSpace(columns[2])}
+		} else if t.Column("tcp_x") < 0 {
+			names = [3]string{"x", "y", "z"}
+		}
+		if paths[i], err = analysis.TCPPath(t, names); err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	c := analysis.ComparePaths(paths[0], paths[1], tolerance)
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("Path deviation of %s from %s", runPath, refPath)
+	w("")
+	w("  %-10s %8s %10s %10s", "", "Points", "Duration", "Length")
+	w("  %-10s %8d %8.1f s %7.0f mm", "Reference", c.Reference, c.Durations[0].Seconds(), c.Length[0])
+	w("  %-10s %8d %8.1f s %7.0f mm", "Run", c.Compared, c.Durations[1].Seconds(), c.Length[1])
+	w("")
+	w("Deviation: mean %.3f mm, RMS %.3f mm, p95 %.3f mm, max %.3f mm", c.Mean, c.RMS, c.P95, c.Max)
+	w("Largest at [%.1f,%.1f,%.1f], %.1f s into the run", c.MaxAt.Pos[0], c.MaxAt.Pos[1], c.MaxAt.Pos[2],
+		c.MaxAt.Time.Sub(paths[1][0].Time).Seconds())
+	w("Mean offset: x %+.3f, y %+.3f, z %+.3f mm", c.Offset[0], c.Offset[1], c.Offset[2])
+	if tolerance > 0 && c.Exceeded > 0 {
+		return "", fmt.Errorf("%d of %d points deviate more than %g mm\n%s", c.Exceeded, c.Compared, tolerance, strings.TrimRight(b.String(), "\n"))
+	}
+	if tolerance > 0 {
+		w("All points within %g mm", tolerance)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
@@ -0,0 +1,213 @@
+// Package approval signs and checks the approvals a second person gives
+// for deploying modules to a production controller. An approval names the
+// targets and the SHA-256 of each file, so a token cannot be reused for
+// other code or another controller, or a change ticket reference that
+// allows deploying any files to the targets, and is signed with the
+// approver's Ed25519 key. The deployer's computer accepts approvals from the approvers
+// it trusts, whose public keys are kept in the data directory.
+package approval
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+// File holds the trusted approvers in the data directory
+const File = "approvers.json"
+
+// tokenPrefix marks a token and the version of its format
+const tokenPrefix = "ahc1."
+
+// Approval allows deploying files to targets until it expires
+type Approval struct {
+	Approver string            `json:"approver"`
+	Targets  []string          `json:"targets"`          // fleet target names
+	Files    map[string]string `json:"files"`            // file name to SHA-256
+	Ticket   string            `json:"ticket,omitempty"` // a change ticket reference instead of Files
+	Note     string            `json:"note,omitempty"`
+	Expires  time.Time         `json:"expires"`
+}
+
+// GenerateKey returns a new key pair, encoded for secrets and trust
+func GenerateKey() (public, private string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv.Seed()), nil
+}
+
+// PublicKey returns the public key of an encoded private key
+func PublicKey(private string) (string, error) {
+	priv, err := privateKey(private)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)), nil
+}
+
+func privateKey(private string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(private)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid approval key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Hash returns the SHA-256 of a file's content as an approval names it
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns the token of an approval
+func Sign(a Approval, private string) (string, error) {
+	priv, err := privateKey(private)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(priv, payload)
+	enc := base64.RawURLEncoding
+	return tokenPrefix + enc.EncodeToString(payload) + "." + enc.EncodeToString(sig), nil
+}
+
+// Parse checks the signature of a token against the key trusted for its
+// approver and returns the approval
+func Parse(token string, trusted map[string]string) (Approval, error) {
+	var a Approval
+	body, ok := strings.CutPrefix(strings.TrimSpace(token), tokenPrefix)
+	if !ok {
+		return a, errors.New("not an approval token")
+	}
+	enc := base64.RawURLEncoding
+	p, s, ok := strings.Cut(body, ".")
+	payload, err1 := enc.DecodeString(p)
+	sig, err2 := enc.DecodeString(s)
+	if !ok || err1 != nil || err2 != nil {
+		return a, errors.New("the approval token is damaged")
+	}
+	if err := json.Unmarshal(payload, &a); err != nil {
+		return a, errors.New("the approval token is damaged")
+	}
+	key, ok := trusted[a.Approver]
+	if !ok {
+		return a, fmt.Errorf("%s is not a trusted approver on this computer; see 'approve trust'", a.Approver)
+	}
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return a, fmt.Errorf("invalid public key of %s in %s", a.Approver, File)
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return a, fmt.Errorf("the signature of %s does not match: the token was changed or signed with another key", a.Approver)
+	}
+	return a, nil
+}
+
+// Check reports whether the approval covers deploying files, by name and
+// content, to a target at a time. A change ticket covers any files.
+func (a Approval) Check(target string, files map[string][]byte, now time.Time) error {
+	if now.After(a.Expires) {
+		return fmt.Errorf("the approval of %s expired at %s", a.Approver, a.Expires.Local().Format("2006-01-02 15:04"))
+	}
+	found := false
+	for _, t := range a.Targets {
+		found = found || strings.EqualFold(t, target)
+	}
+	if !found {
+		return fmt.Errorf("the approval of %s is for %s, not %s", a.Approver, strings.Join(a.Targets, ", "), target)
+	}
+	if a.Ticket != "" {
+		return nil
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum, ok := a.Files[name]
+		if !ok {
+			return fmt.Errorf("the approval of %s does not include %s", a.Approver, name)
+		}
+		if sum != Hash(files[name]) {
+			return fmt.Errorf("%s was changed after %s approved it", name, a.Approver)
+		}
+	}
+	return nil
+}
+
+// Trusted returns the public keys of the trusted approvers by name
+func Trusted() (map[string]string, error) {
+	path, err := config.Path(File)
+	if err != nil {
+		return nil, err
+	}
+	trusted := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return trusted, nil
+}
+
+// Trust accepts approvals signed with a public key under a name, replacing
+// an earlier key of the name
+func Trust(name, public string) error {
+	pub, err := base64.StdEncoding.DecodeString(public)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key; copy it as 'approve key' prints it")
+	}
+	trusted, err := Trusted()
+	if err != nil {
+		return err
+	}
+	trusted[name] = public
+	return saveTrusted(trusted)
+}
+
+// Distrust removes a trusted approver
+func Distrust(name string) error {
+	trusted, err := Trusted()
+	if err != nil {
+		return err
+	}
+	if _, ok := trusted[name]; !ok {
+		return fmt.Errorf("%s is not a trusted approver", name)
+	}
+	delete(trusted, name)
+	return saveTrusted(trusted)
+}
+
+func saveTrusted(trusted map[string]string) error {
+	path, err := config.Path(File)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
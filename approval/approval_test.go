@@ -0,0 +1,157 @@
+package approval
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+func newKey(t *testing.T) (public, private string) {
+	t.Helper()
+	public, private, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+func TestSignAndParse(t *testing.T) {
+	public, private := newKey(t)
+	otherPublic, otherPrivate := newKey(t)
+	if got, err := PublicKey(private); err != nil || got != public {
+		t.Fatalf("PublicKey = %s, %v; want %s", got, err, public)
+	}
+	a := Approval{
+		Approver: "anna",
+		Targets:  []string{"cell3"},
+		Files:    map[string]string{"Main.mod": Hash([]byte("MODULE Main\nENDMODULE\n"))},
+		Note:     "Cell 3 gripper timing",
+		Expires:  time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC),
+	}
+	token, err := Sign(a, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := Sign(a, otherPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing the payload, here the target, keeps the old signature
+	body, sig, _ := strings.Cut(strings.TrimPrefix(token, tokenPrefix), ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(body)
+	changed := tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), "cell3", "cell4", 1))) + "." + sig
+
+	tests := []struct {
+		name    string
+		token   string
+		trusted map[string]string
+		want    string // error text, or empty
+	}{
+		{"valid", token, map[string]string{"anna": public}, ""},
+		{"surrounding space", " " + token + "\n", map[string]string{"anna": public}, ""},
+		{"untrusted approver", token, map[string]string{"ben": public}, "anna is not a trusted approver"},
+		{"signed with another key", forged, map[string]string{"anna": public}, "does not match"},
+		{"another key trusted", token, map[string]string{"anna": otherPublic}, "does not match"},
+		{"changed payload", changed, map[string]string{"anna": public}, "does not match"},
+		{"no prefix", strings.TrimPrefix(token, tokenPrefix), map[string]string{"anna": public}, "not an approval token"},
+		{"truncated", token[:len(token)-len(sig)-1], map[string]string{"anna": public}, "damaged"},
+		{"invalid trusted key", token, map[string]string{"anna": "c2hvcnQ="}, "invalid public key of anna"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.token, tt.trusted)
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("got %v, want an error containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Approver != a.Approver || got.Note != a.Note || !got.Expires.Equal(a.Expires) ||
+				strings.Join(got.Targets, ",") != "cell3" || got.Files["Main.mod"] != a.Files["Main.mod"] {
+				t.Errorf("got %+v, want %+v", got, a)
+			}
+		})
+	}
+
+	if _, err := Sign(a, "not a key"); err == nil {
+		t.Error("signed with an invalid key")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	main, tool := []byte("MODULE Main\nENDMODULE\n"), []byte("MODULE Tool\nENDMODULE\n")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := Approval{
+		Approver: "anna",
+		Targets:  []string{"Cell3", "cell4"},
+		Files:    map[string]string{"Main.mod": Hash(main), "Tool.mod": Hash(tool)},
+		Expires:  now.Add(time.Hour),
+	}
+	ticket := Approval{Approver: "anna", Targets: []string{"cell3", "cell4"}, Ticket: "CHG0012345", Expires: now.Add(time.Hour)}
+	tests := []struct {
+		name     string
+		approval Approval
+		target   string
+		files    map[string][]byte
+		at       time.Time
+		want     string
+	}{
+		{"covered", a, "cell3", map[string][]byte{"Main.mod": main, "Tool.mod": tool}, now, ""},
+		{"a part of the files", a, "CELL4", map[string][]byte{"Tool.mod": tool}, now, ""},
+		{"expired", a, "cell3", map[string][]byte{"Main.mod": main}, now.Add(2 * time.Hour), "expired"},
+		{"another target", a, "cell5", map[string][]byte{"Main.mod": main}, now, "is for Cell3, cell4, not cell5"},
+		{"a file not approved", a, "cell3", map[string][]byte{"Main.mod": main, "Other.mod": tool}, now, "does not include Other.mod"},
+		{"a changed file", a, "cell3", map[string][]byte{"Main.mod": append(main, '\n')}, now, "Main.mod was changed after anna approved it"},
+		{"ticket covers any files", ticket, "cell4", map[string][]byte{"Other.mod": tool}, now, ""},
+		{"ticket for another target", ticket, "cell5", map[string][]byte{"Main.mod": main}, now, "not cell5"},
+		{"expired ticket", ticket, "cell3", map[string][]byte{"Main.mod": main}, now.Add(2 * time.Hour), "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.approval.Check(tt.target, tt.files, tt.at)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrust(t *testing.T) {
+	t.Setenv(config.HomeEnv, t.TempDir())
+	public, _ := newKey(t)
+	second, _ := newKey(t)
+	if err := Trust("anna", "not a key"); err == nil {
+		t.Error("trusted an invalid key")
+	}
+	if err := Trust("anna", public); err != nil {
+		t.Fatal(err)
+	}
+	if err := Trust("anna", second); err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := Trusted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trusted) != 1 || trusted["anna"] != second {
+		t.Errorf("trusted %v, want the second key of anna", trusted)
+	}
+	if err := Distrust("anna"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Distrust("anna"); err == nil || !strings.Contains(err.Error(), "not a trusted approver") {
+		t.Errorf("second distrust: %v", err)
+	}
+	if trusted, _ := Trusted(); len(trusted) != 0 {
+		t.Errorf("trusted %v after distrust", trusted)
+	}
+}
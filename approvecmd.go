@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/approval"
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/secrets"
+)
+
+func init() {
+	commandRegistry["approve"] = Command{
+		Group:       groupIntegration,
+		Description: "Approve deployments to production controllers as a second person",
+		Usage:       approveUsage,
+		Execute:     text(runApprove),
+	}
+}
+
+const approveUsage = `Usage:
+  approve key                   Show your public key, creating your approval
+                                key on first use
+  approve deploy <target,...> <file.mod>... [--hours 24] [--note <text>]
+                                Print a token approving these files for the
+                                targets
+  approve ticket <reference> <target,...> [--hours 24] [--note <text>]
+                                Print a signed change ticket allowing
+                                deployments to the targets
+  approve trust <name> <public-key>
+                                Accept approvals signed by a colleague
+  approve distrust <name>       No longer accept approvals of a colleague
+  approve trusted               List the trusted approvers
+
+Fleet targets added with --production need an approval before modules are
+deployed to them with 'abb rws module put' or 'abb vc run': a token from a
+second person (--approval) or a signed change ticket (--ticket), whichever
+the target asks for (see fleet). The reviewer checks the files and runs
+approve deploy; the token names the targets and the SHA-256 of each file,
+so it is void once a file changes, and expires after --hours. For a change
+ticket, whoever approves the change runs approve ticket with its reference,
+which must match the ticket pattern of the targets; the signed ticket
+allows deploying any files to them until it expires. Tokens are signed with
+the signer's key, kept in the secrets as approval_key, and are only
+accepted on computers that trust the signer's public key and from a
+deployer whose own approval key is not the one that signed.
+Approved deployments are recorded in the audit log with the approver or
+ticket.
+
+Examples:
+  approve key
+  approve trust jdoe sLEFlSNVHas0EMzi1D/BdRgYE0eYY8ksEBHCehMgBgI=
+  approve deploy cell3 RAPID/T_ROB1/MainModule.mod --note "CR-1042 new pallet pattern"
+  abb rws module put RAPID/T_ROB1/MainModule.mod --target cell3 --approval ahc1.eyJh...
+  approve ticket CHG0012345 cell3,cell4 --hours 8
+  abb rws module put RAPID/T_ROB1 --target cell3 --ticket ahc1.eyJh...`
+
+// defaultTicketPattern matches change ticket references such as CHG0012345
+// and CR-1042
+const defaultTicketPattern = `^[A-Z][A-Z0-9]*-?[0-9]+$`
+
+func runApprove(args []string) (string, error) {
+	if len(args) == 0 {
+		return approveUsage, nil
+	}
+	switch args[0] {
+	case "key":
+		if len(args) != 1 {
+			return approveUsage, nil
+		}
+		public, created, err := approvalKey()
+		if err != nil {
+			return "", err
+		}
+		msg := fmt.Sprintf("Your public key, for colleagues to run:\n  approve trust %s %s", auditUser(), public)
+		if created {
+			msg = "Created your approval key.\n" + msg
+		}
+		return msg, nil
+	case "deploy":
+		return approveDeploy(args[1:])
+	case "ticket":
+		return approveTicket(args[1:])
+	case "trust":
+		if len(args) != 3 {
+			return approveUsage, nil
+		}
+		if err := approval.Trust(args[1], args[2]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Approvals signed by %s are accepted on this computer", args[1]), nil
+	case "distrust":
+		if len(args) != 2 {
+			return approveUsage, nil
+		}
+		if err := approval.Distrust(args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Approvals signed by %s are no longer accepted", args[1]), nil
+	case "trusted":
+		trusted, err := approval.Trusted()
+		if err != nil {
+			return "", err
+		}
+		if len(trusted) == 0 {
+			return "No trusted approvers. Add one with 'approve trust <name> <public-key>'.", nil
+		}
+		names := make([]string, 0, len(trusted))
+		for name := range trusted {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		b.WriteString("Trusted approvers:\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "  %-16s %s\n", name, trusted[name])
+		}
+		return strings.TrimRight(b.String(), "\n"), nil
+	default:
+		return approveUsage, nil
+	}
+}
+
+// approvalKey returns the public key of the user's approval key, creating
+// the key when there is none
+func approvalKey() (public string, created bool, err error) {
+	private, err := secrets.Get("approval_key")
+	if err != nil {
+		return "", false, err
+	}
+	if private != "" {
+		public, err = approval.PublicKey(private)
+		return public, false, err
+	}
+	public, private, err = approval.GenerateKey()
+	if err != nil {
+		return "", false, err
+	}
+	if err := secrets.Set("approval_key", private); err != nil {
+		return "", false, err
+	}
+	return public, true, nil
+}
+
+func approveDeploy(args []string) (string, error) {
+	if len(args) < 2 || strings.HasPrefix(args[0], "--") {
+		return approveUsage, nil
+	}
+	a := approval.Approval{Approver: auditUser(), Targets: strings.Split(args[0], ","), Files: make(map[string]string)}
+	paths, ok, err := approvalOptions(args[1:], &a)
+	if !ok || err != nil {
+		return approveUsage, err
+	}
+	if len(paths) == 0 {
+		return approveUsage, nil
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		a.Files[filepath.Base(path)] = approval.Hash(data)
+	}
+	token, err := signApproval(a)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Approved %d files for %s until %s as %s. Give the deployer this token:\n%s",
+		len(a.Files), strings.Join(a.Targets, ", "), a.Expires.Format("2006-01-02 15:04"), a.Approver, token), nil
+}
+
+// approveTicket signs a change ticket reference for targets
+func approveTicket(args []string) (string, error) {
+	if len(args) < 2 || strings.HasPrefix(args[0], "--") || strings.HasPrefix(args[1], "--") {
+		return approveUsage, nil
+	}
+	a := approval.Approval{Approver: auditUser(), Ticket: args[0], Targets: strings.Split(args[1], ",")}
+	rest, ok, err := approvalOptions(args[2:], &a)
+	if !ok || err != nil || len(rest) > 0 {
+		return approveUsage, err
+	}
+	token, err := signApproval(a)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Signed change ticket %s for %s until %s as %s. Give the deployer this token for --ticket:\n%s",
+		a.Ticket, strings.Join(a.Targets, ", "), a.Expires.Format("2006-01-02 15:04"), a.Approver, token), nil
+}
+
+// approvalOptions reads --hours and --note into an approval and returns
+// the other arguments; ok is false for a usage error
+func approvalOptions(args []string, a *approval.Approval) (rest []string, ok bool, err error) {
+	hours := 24.0
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, false, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--hours":
+			h, err := strconv.ParseFloat(value, 64)
+			if err != nil || h <= 0 {
+				return nil, false, fmt.Errorf("invalid value %q for --hours", value)
+			}
+			hours = h
+		case "--note":
+			a.Note = value
+		default:
+			return nil, false, nil
+		}
+		i++
+	}
+	a.Expires = time.Now().Add(time.Duration(hours * float64(time.Hour))).Truncate(time.Second)
+	return rest, true, nil
+}
+
+// signApproval signs an approval with the user's approval key
+func signApproval(a approval.Approval) (string, error) {
+	private, err := secrets.Get("approval_key")
+	if err != nil {
+		return "", err
+	}
+	if private == "" {
+		return "", errors.New("you have no approval key yet; create it with 'approve key' and have the deployers trust it")
+	}
+	return approval.Sign(a, private)
+}
+
+// sameController reports whether two host arguments may reach the same
+// controller: the same port and the same host name or address. Hosts that
+// cannot be told apart count as the same, so a production target is not
+// reached without approval.
+func sameController(a, b string) bool {
+	hostA, portA, errA := rws.HostPort(a)
+	hostB, portB, errB := rws.HostPort(b)
+	if errA != nil || errB != nil {
+		return true
+	}
+	if portA != portB {
+		return false
+	}
+	if hostA == hostB {
+		return true
+	}
+	addrsA, addrsB := resolveHost(hostA), resolveHost(hostB)
+	for _, x := range addrsA {
+		if slices.Contains(addrsB, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHost returns the addresses of a host name in canonical form, or
+// the address itself
+func resolveHost(host string) []string {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []string{ip.String()}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for i, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil {
+			addrs[i] = ip.String()
+		}
+	}
+	return addrs
+}
+
+// deployApproval checks that deploying files to a host is approved when it
+// is a production fleet target. It returns what to record in the audit
+// log, or "" when the host needs no approval.
+func deployApproval(host, token, ticket string, files map[string][]byte) (string, error) {
+	fleet, err := loadFleet()
+	if err != nil {
+		return "", err
+	}
+	var t *fleetTarget
+	for i := range fleet {
+		if fleet[i].Production && sameController(fleet[i].Host, host) {
+			t = &fleet[i]
+		}
+	}
+	if t == nil {
+		return "", nil
+	}
+	if token != "" && t.Approval != "ticket" {
+		a, err := parseApproval(token, t.Name)
+		if err != nil {
+			return "", err
+		}
+		if a.Ticket != "" {
+			return "", fmt.Errorf("the token is the signed change ticket %s; pass it with --ticket", a.Ticket)
+		}
+		if err := a.Check(t.Name, files, time.Now()); err != nil {
+			return "", err
+		}
+		record := "approved by " + a.Approver
+		if a.Note != "" {
+			record += ": " + a.Note
+		}
+		return record, nil
+	}
+	if ticket != "" && t.Approval != "token" {
+		pattern := t.TicketPattern
+		if pattern == "" {
+			pattern = defaultTicketPattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid ticket pattern of %s: %v", t.Name, err)
+		}
+		a, err := parseApproval(ticket, t.Name)
+		if err != nil {
+			return "", err
+		}
+		if a.Ticket == "" {
+			return "", errors.New("--ticket needs a change ticket signed with 'approve ticket'; pass an approval token with --approval")
+		}
+		if !re.MatchString(a.Ticket) {
+			return "", fmt.Errorf("%q is not a change ticket reference for %s (%s)", a.Ticket, t.Name, pattern)
+		}
+		if err := a.Check(t.Name, files, time.Now()); err != nil {
+			return "", err
+		}
+		return "change ticket " + a.Ticket + " signed by " + a.Approver, nil
+	}
+	need := "an approval token from a second person (--approval, see approve) or a signed change ticket (--ticket)"
+	switch t.Approval {
+	case "token":
+		need = "an approval token from a second person (--approval, see approve)"
+	case "ticket":
+		need = "a signed change ticket (--ticket, see approve ticket)"
+	}
+	return "", fmt.Errorf("%s is a production controller: deploying to it needs %s", t.Name, need)
+}
+
+// parseApproval checks the signature of a token presented for deploying
+// to a target. A token signed with the deployer's own approval key is
+// refused, whatever approver name it was trusted under.
+func parseApproval(token, target string) (approval.Approval, error) {
+	trusted, err := approval.Trusted()
+	if err != nil {
+		return approval.Approval{}, err
+	}
+	a, err := approval.Parse(token, trusted)
+	if err != nil {
+		return a, err
+	}
+	private, err := secrets.Get("approval_key")
+	if err != nil {
+		return a, err
+	}
+	if private != "" {
+		own, err := approval.PublicKey(private)
+		if err != nil {
+			return a, err
+		}
+		if own == trusted[a.Approver] {
+			return a, fmt.Errorf("you cannot approve your own deployment to %s; a second person must sign it", target)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,179 @@
+// Package audit keeps an append-only log of the actions the CLI takes on
+// live equipment: writes, I/O changes, module loads and program execution.
+// Each entry carries the hash of the one before it, so an edited or deleted
+// line breaks the chain and Verify finds it.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+// File is the audit log in the data directory, one JSON entry per line
+const File = "audit.jsonl"
+
+// headFile holds the hash of the last entry, so changing the last line is
+// found too
+const headFile = "audit.head"
+
+// Entry is one action on a machine
+type Entry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Machine string    `json:"machine"` // the computer the CLI ran on
+	Command string    `json:"command"` // the command line, passwords removed
+	Target  string    `json:"target"`  // the controller or device
+	Action  string    `json:"action"`  // e.g. POST /rw/rapid/execution?action=start
+	Values  string    `json:"values,omitempty"`
+	Result  string    `json:"result"` // ok or the error
+	// Prev is the hash of the previous line, empty for the first entry
+	Prev string `json:"prev"`
+}
+
+// Record appends an entry, chained to the last one
+func Record(e Entry) error {
+	path, err := config.Path(File)
+	if err != nil {
+		return err
+	}
+	last, err := lastLine(path)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		e.Prev = hash(last)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	head, err := config.Path(headFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(head, []byte(hash(line)+"\n"), 0o600)
+}
+
+// Read returns the entries of the log, oldest first
+func Read() ([]Entry, error) {
+	path, err := config.Path(File)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	err = scan(path, func(n int, line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("%s line %d: %v", File, n, err)
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the hash chain and returns the number of entries. The
+// error names the first line that was changed, or that follows a removed
+// one, or the last entry when it no longer matches the hash kept beside
+// the log.
+func Verify() (int, error) {
+	path, err := config.Path(File)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	var prev []byte
+	err = scan(path, func(n int, line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("line %d is not an audit entry: %v", n, err)
+		}
+		want := ""
+		if prev != nil {
+			want = hash(prev)
+		}
+		if e.Prev != want {
+			return fmt.Errorf("line %d does not follow line %d: an entry was changed or removed", n, n-1)
+		}
+		prev = append(prev[:0], line...)
+		count++
+		return nil
+	})
+	if err != nil || count == 0 {
+		return count, err
+	}
+	head, err := config.Path(headFile)
+	if err != nil {
+		return count, err
+	}
+	want, err := os.ReadFile(head)
+	if err != nil {
+		return count, fmt.Errorf("%s: %v", headFile, err)
+	}
+	if strings.TrimSpace(string(want)) != hash(prev) {
+		return count, fmt.Errorf("the last entry was changed or removed")
+	}
+	return count, nil
+}
+
+func hash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// scan calls fn with the number and content of each non-empty line
+func scan(path string, fn func(n int, line []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// lastLine returns the last non-empty line of the log, nil for an empty log
+func lastLine(path string) ([]byte, error) {
+	var last []byte
+	err := scan(path, func(_ int, line []byte) error {
+		last = append(last[:0], line...)
+		return nil
+	})
+	return last, err
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/audit"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/serial"
+)
+
+func init() {
+	commandRegistry["audit"] = Command{
+		Group:       groupSystem,
+		Description: "Show, verify and export the log of actions on live equipment",
+		Usage:       auditUsage,
+		Execute:     text(runAudit),
+	}
+	rws.OnWrite = auditRWS
+}
+
+const auditUsage = `Usage:
+  audit [--last <n>]          Show the latest entries (default 20)
+  audit verify                Check that no entry was changed or removed
+  audit export [--since <date>] [--until <date>] [--target <host>]
+               [--format csv|markdown|json] [--output <file>]
+                              Export entries for customer sign-off
+
+Every request that changes a controller over Robot Web Services (program
+pointer, start and stop, motors, I/O writes, module loads, file uploads and
+backups), every socket message and every line sent on a serial port is
+appended to ` + audit.File + ` in the data directory with the time, user,
+computer, command line (passwords removed), target, action, values and
+outcome. File uploads are recorded by size and SHA-256, not content. Each
+entry holds the hash of the one before it, so audit verify detects edited
+or deleted lines. Dates are YYYY-MM-DD; --until includes the whole day.
+The user is the author setting (see config), or the login name.
+
+Examples:
+  audit --last 50
+  audit verify
+  audit export --since 2026-03-01 --target 192.168.125.1 --format csv --output fat-cell3.csv`
+
+func runAudit(args []string) (string, error) {
+	if len(args) > 0 && args[0] == "verify" {
+		if len(args) != 1 {
+			return auditUsage, nil
+		}
+		n, err := audit.Verify()
+		if err != nil {
+			return "", fmt.Errorf("the audit log was tampered with: %v", err)
+		}
+		return fmt.Sprintf("The audit log is intact: %d entries", n), nil
+	}
+	if len(args) > 0 && args[0] == "export" {
+		return auditExport(args[1:])
+	}
+	last := 20
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) || args[i] != "--last" {
+			return auditUsage, nil
+		}
+		if _, err := fmt.Sscan(args[i+1], &last); err != nil || last < 1 {
+			return "", errors.New("--last must be a positive whole number")
+		}
+		i++
+	}
+	entries, err := audit.Read()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "No actions on equipment recorded yet.", nil
+	}
+	if len(entries) > last {
+		entries = entries[len(entries)-last:]
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s  %-10s %-18s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.Target, e.Action)
+		if e.Values != "" {
+			fmt.Fprintf(&b, "  [%s]", e.Values)
+		}
+		if e.Result != "ok" {
+			fmt.Fprintf(&b, "  FAILED: %s", e.Result)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func auditExport(args []string) (string, error) {
+	var since, until time.Time
+	var target, output string
+	format := "csv"
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return auditUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--since", "--until":
+			t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return "", fmt.Errorf("invalid date %q for %s; use YYYY-MM-DD", value, args[i])
+			}
+			if args[i] == "--since" {
+				since = t
+			} else {
+				until = t.AddDate(0, 0, 1)
+			}
+		case "--target":
+			target = value
+		case "--format":
+			format = value
+		case "--output":
+			output = value
+		default:
+			return auditUsage, nil
+		}
+		i++
+	}
+	entries, err := audit.Read()
+	if err != nil {
+		return "", err
+	}
+	var selected []audit.Entry
+	for _, e := range entries {
+		if !since.IsZero() && e.Time.Before(since) || !until.IsZero() && !e.Time.Before(until) ||
+			target != "" && !strings.EqualFold(e.Target, target) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	var text string
+	switch format {
+	case "csv":
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		w.Write([]string{"time", "user", "machine", "target", "action", "values", "result", "command"})
+		for _, e := range selected {
+			w.Write([]string{e.Time.Format(time.RFC3339), e.User, e.Machine, e.Target, e.Action, e.Values, e.Result, e.Command})
+		}
+		w.Flush()
+		text = b.String()
+	case "markdown":
+		var b strings.Builder
+		fmt.Fprintf(&b, "# Actions on equipment\n\n%d entries", len(selected))
+		if target != "" {
+			fmt.Fprintf(&b, " on %s", target)
+		}
+		fmt.Fprintf(&b, ", exported %s.\n\n", time.Now().Format("2006-01-02 15:04"))
+		b.WriteString("| Time | User | Target | Action | Values | Result |\n|---|---|---|---|---|---|\n")
+		cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+		for _, e := range selected {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", e.Time.Local().Format("2006-01-02 15:04:05"),
+				cell(e.User), cell(e.Target), cell(e.Action), cell(e.Values), cell(e.Result))
+		}
+		b.WriteString("\nCustomer sign-off: ______________________  Date: __________\n")
+		text = b.String()
+	case "json":
+		if selected == nil {
+			selected = []audit.Entry{}
+		}
+		data, err := json.MarshalIndent(selected, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		text = string(data) + "\n"
+	default:
+		return "", fmt.Errorf("unknown format %q (use csv, markdown or json)", format)
+	}
+	if output == "" {
+		return strings.TrimRight(text, "\n"), nil
+	}
+	if err := writeFile(output, []byte(text), 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Exported %d audit entries to %s", len(selected), output), nil
+}
+
+// auditRWS records a request that changed a controller. Mastership is
+// requested and released around every action, so it is left out.
+func auditRWS(c *rws.Client, method, path string, body []byte, err error) {
+	if strings.HasPrefix(path, "/rw/mastership") {
+		return
+	}
+	values := ""
+	if method == http.MethodPut {
+		sum := sha256.Sum256(body)
+		values = fmt.Sprintf("%d bytes, sha256 %s", len(body), hex.EncodeToString(sum[:]))
+	} else if form, perr := url.ParseQuery(string(body)); perr == nil {
+		var pairs []string
+		for key, v := range form {
+			pairs = append(pairs, key+"="+strings.Join(v, ","))
+		}
+		sort.Strings(pairs)
+		values = strings.Join(pairs, " ")
+	}
+	target := strings.TrimPrefix(strings.TrimPrefix(c.Base, "http://"), "https://")
+	recordAction(target, method+" "+path, values, err)
+}
+
+// auditSent records bytes sent to a device over a socket or serial port
+func auditSent(target, action string, data []byte, err error) {
+	recordAction(target, action, serial.ASCII(data), err)
+}
+
+// recordAction appends an action on equipment to the audit log. A failure
+// to record is reported but does not undo or stop the action.
+func recordAction(target, action, values string, err error) {
+	e := audit.Entry{Time: time.Now(), User: auditUser(), Target: target, Action: action, Values: values, Result: "ok"}
+	e.Machine, _ = os.Hostname()
+	if changes != nil {
+		e.Command = redactCommand(changes.Command)
+	}
+	if err != nil {
+		e.Result = err.Error()
+	}
+	if dryRun() {
+		return
+	}
+	if err := audit.Record(e); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the action was not recorded in the audit log: %v\n", err)
+	}
+}
+
+// auditUser is the author setting or the login name
+func auditUser() string {
+	if s, err := config.LoadSettings(); err == nil && s.Author != "" {
+		return s.Author
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// redactCommand replaces the values of password options in a command line
+func redactCommand(command string) string {
+	fields := strings.Fields(command)
+	for i := 0; i+1 < len(fields); i++ {
+		if strings.Contains(strings.ToLower(fields[i]), "password") {
+			fields[i+1] = "***"
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const backupUsage = `Usage: abb backup sync <backupdir> <projectdir> [--check]
+       abb backup drift <old-backup> <new-backup> [options]
+Copies the RAPID modules of every task in a controller backup
+(RAPID/<task>/PROGMOD and SYSMOD) to <projectdir>/<task>/, normalizing line
+endings, tabs and trailing whitespace, and reports where the project differs.
+With --check nothing is written; use it to verify the robot matches git.
+
+drift lists the taught points that moved between two backups; see
+'abb backup drift' for its options.
+
+Examples:
+  abb backup sync ./Backups/IRB6700_2024-05-02 ./rapid
+  abb backup drift ./Backups/IRB6700_2024-05-01 ./Backups/IRB6700_2024-05-02`
+
+// abbBackup dispatches the backup subcommands
+func abbBackup(args []string) (string, error) {
+	if len(args) > 0 && args[0] == "drift" {
+		if len(args) == 1 {
+			return driftUsage, nil
+		}
+		return backupDrift(args[1:])
+	}
+	if len(args) < 3 || args[0] != "sync" {
+		return backupUsage, nil
+	}
+	check := false
+	var dirs []string
+	for _, a := range args[1:] {
+		if a == "--check" {
+			check = true
+			continue
+		}
+		dirs = append(dirs, a)
+	}
+	if len(dirs) != 2 {
+		return backupUsage, nil
+	}
+	return backupSync(dirs[0], dirs[1], check)
+}
+
+// backupModules maps "<task>/<file>" to the module path inside a backup
+func backupModules(backup string) (map[string]string, error) {
+	root := filepath.Join(backup, "RAPID")
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("%s does not look like a controller backup (no RAPID folder)", backup)
+	}
+	modules := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !rapid.IsSourceFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		// RAPID/<task>/PROGMOD/<file> or RAPID/<task>/SYSMOD/<file>
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 2 {
+			return nil
+		}
+		key := parts[0] + "/" + parts[len(parts)-1]
+		if other, exists := modules[key]; exists {
+			return fmt.Errorf("module %s found twice: %s and %s", key, other, path)
+		}
+		modules[key] = path
+		return nil
+	})
+	return modules, err
+}
+
+// backupSync copies normalized modules from a backup into the project and
+// reports drift between the two
+func backupSync(backup, project string, check bool) (string, error) {
+	modules, err := backupModules(backup)
+	if err != nil {
+		return "", err
+	}
+	if len(modules) == 0 {
+		return "", fmt.Errorf("no RAPID modules found in %s", backup)
+	}
+
+	keys := make([]string, 0, len(modules))
+	for key := range modules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("\nBackup %s -> project %s\n", backup, project))
+	added, changed, same := 0, 0, 0
+	tasks := make(map[string]bool)
+	for _, key := range keys {
+		data, err := os.ReadFile(modules[key])
+		if err != nil {
+			return "", err
+		}
+		fromRobot := rapid.Normalize(string(data))
+		target := filepath.Join(project, filepath.FromSlash(key))
+		tasks[filepath.Dir(target)] = true
+
+		existing, err := os.ReadFile(target)
+		switch {
+		case os.IsNotExist(err):
+			added++
+			result.WriteString(fmt.Sprintf("\n%s: only on the robot\n", key))
+		case err != nil:
+			return "", err
+		case rapid.Normalize(string(existing)) == fromRobot:
+			same++
+			if string(existing) != fromRobot && !check {
+				result.WriteString(fmt.Sprintf("\n%s: formatting normalized\n", key))
+			}
+		default:
+			changed++
+			result.WriteString(moduleChangelog(key, 'M', string(existing), fromRobot))
+		}
+
+		if !check && string(existing) != fromRobot {
+			if err := makeDir(filepath.Dir(target)); err != nil {
+				return "", err
+			}
+			if err := writeFile(target, []byte(fromRobot), 0o644); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// Modules kept in the project that the robot no longer has
+	onlyProject := 0
+	for dir := range tasks {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			key := filepath.Base(dir) + "/" + e.Name()
+			if _, onRobot := modules[key]; !onRobot && rapid.IsSourceFile(e.Name()) {
+				onlyProject++
+				result.WriteString(fmt.Sprintf("\n%s: only in the project\n", key))
+			}
+		}
+	}
+
+	result.WriteString(fmt.Sprintf("\nSummary: %d unchanged, %d changed, %d new on robot, %d only in project\n",
+		same, changed, added, onlyProject))
+	if check {
+		if changed+added+onlyProject > 0 {
+			return "", fmt.Errorf("robot and project differ%s", result.String())
+		}
+		result.WriteString("Robot and project are in sync.")
+	} else if changed+added > 0 {
+		result.WriteString("Project updated from backup - review with git diff before committing.")
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/bot"
+	"github.com/polyfant/automation-helper-cli/secrets"
+)
+
+// botCommands are the registry commands answered in chat channels
+var botCommands = []string{"abb", "error", "ai"}
+
+// botSubcommands limits the chat commands to lookups, by their first
+// argument; "*" allows any. Chat users are not authenticated as cell
+// staff, so nothing that touches a controller or a file is offered.
+var botSubcommands = map[string][]string{
+	"abb":   {"command", "quickref", "list", "search"},
+	"error": {"*"},
+	"ai":    {"help"},
+}
+
+// botMu serializes the chat commands, which share the state of execute
+var botMu sync.Mutex
+
+func init() {
+	commandRegistry["bot"] = Command{
+		Group:       groupIntegration,
+		Description: "Answer abb, error and ai queries from Slack or Microsoft Teams",
+		Usage:       botUsage,
+		Execute:     text(runBot),
+	}
+}
+
+const botUsage = `Usage: bot [--port 3000] [--host 0.0.0.0]
+Environment:
+  SLACK_SIGNING_SECRET  - enables the Slack slash command endpoint POST /slack
+  TEAMS_WEBHOOK_SECRET  - enables the Teams outgoing webhook endpoint POST /teams
+Point the slash command or outgoing webhook at this server (typically via a reverse proxy with TLS).
+Chat users can look up abb command, quickref, list and search, error codes
+and ask ai help questions; the commands run in read-only mode.`
+
+func runBot(args []string) (string, error) {
+	host, port := "0.0.0.0", 3000
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return botUsage, nil
+		}
+		switch args[i] {
+		case "--port":
+			p, err := strconv.Atoi(args[i+1])
+			if err != nil || p < 1 || p > 65535 {
+				return "", fmt.Errorf("invalid port: %s", args[i+1])
+			}
+			port = p
+		case "--host":
+			host = args[i+1]
+		default:
+			return botUsage, nil
+		}
+		i++
+	}
+
+	slackSecret, err := secrets.Get("slack_signing_secret")
+	if err != nil {
+		return "", err
+	}
+	teamsSecret, err := secrets.Get("teams_webhook_secret")
+	if err != nil {
+		return "", err
+	}
+	cfg := bot.Config{
+		SlackSigningSecret: slackSecret,
+		TeamsSecret:        teamsSecret,
+		Allowed:            botCommands,
+		Slow:               []string{"ai"},
+		Run: func(name string, args []string) (string, bool) {
+			cmd, exists := commandRegistry[name]
+			if !exists {
+				return "", false
+			}
+			if !botAllowed(name, args) {
+				return fmt.Sprintf("Only these lookups are available in chat: %s", botLookups()), true
+			}
+			botMu.Lock()
+			defer botMu.Unlock()
+			return execute(name, cmd, args).String(), true
+		},
+	}
+	if cfg.SlackSigningSecret == "" && cfg.TeamsSecret == "" {
+		return "", fmt.Errorf("set SLACK_SIGNING_SECRET and/or TEAMS_WEBHOOK_SECRET, or store them with secrets set\n\n%s", botUsage)
+	}
+
+	// Chat commands never ask for confirmation and never write
+	prevReadOnly, prevConfirm := readOnlyFlag, confirmDisabled
+	readOnlyFlag, confirmDisabled = true, true
+	defer func() { readOnlyFlag, confirmDisabled = prevReadOnly, prevConfirm }()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           bot.NewHandler(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Printf("Chat bot listening on %s (Ctrl+C to stop)\n", addr)
+
+	select {
+	case err := <-errc:
+		return "", err
+	case <-interrupt:
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		return "Chat bot stopped", nil
+	}
+}
+
+// botAllowed reports whether a chat user may run a command line
+func botAllowed(name string, args []string) bool {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	for _, s := range botSubcommands[name] {
+		if s == "*" || s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// botLookups lists the command lines allowed in chat
+func botLookups() string {
+	var lines []string
+	for _, name := range botCommands {
+		for _, sub := range botSubcommands[name] {
+			if sub == "*" {
+				lines = append(lines, name)
+			} else {
+				lines = append(lines, name+" "+sub)
+			}
+		}
+	}
+	return strings.Join(lines, ", ")
+}
@@ -0,0 +1,190 @@
+// Package bot bridges chat platforms (Slack slash commands and Microsoft
+// Teams outgoing webhooks) to the helper's command registry
+package bot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRequestSize bounds incoming chat payloads
+const maxRequestSize = 1 << 20
+
+// slackMaxAge rejects signed Slack requests older than this to prevent replays
+const slackMaxAge = 5 * time.Minute
+
+// Runner executes a command by name and reports whether it exists
+type Runner func(name string, args []string) (string, bool)
+
+// Config holds the secrets and command runner used by the bridge
+type Config struct {
+	SlackSigningSecret string   // Slack app signing secret; empty disables /slack
+	TeamsSecret        string   // Base64 security token of the Teams outgoing webhook; empty disables /teams
+	Allowed            []string // Commands that may be run from chat
+	Run                Runner
+	// Slow lists commands answered asynchronously via Slack's response_url,
+	// since Slack requires a reply within three seconds
+	Slow []string
+}
+
+type handler struct {
+	cfg    Config
+	client *http.Client
+}
+
+// NewHandler returns an HTTP handler serving /slack and /teams endpoints
+func NewHandler(cfg Config) http.Handler {
+	h := &handler{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+	mux := http.NewServeMux()
+	if cfg.SlackSigningSecret != "" {
+		mux.HandleFunc("POST /slack", h.slack)
+	}
+	if cfg.TeamsSecret != "" {
+		mux.HandleFunc("POST /teams", h.teams)
+	}
+	return mux
+}
+
+// answer runs an allowed command from a chat message
+func (h *handler) answer(text string) string {
+	args := strings.Fields(text)
+	if len(args) == 0 || !contains(h.cfg.Allowed, strings.ToLower(args[0])) {
+		return "Available commands: " + strings.Join(h.cfg.Allowed, ", ") + "\nExample: abb command move_j"
+	}
+	out, ok := h.cfg.Run(strings.ToLower(args[0]), args[1:])
+	if !ok {
+		return "Unknown command: " + args[0]
+	}
+	return strings.TrimSpace(out)
+}
+
+func (h *handler) slack(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !verifySlack(h.cfg.SlackSigningSecret, r.Header, body, time.Now()) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	text := form.Get("text")
+	fields := strings.Fields(text)
+	responseURL := form.Get("response_url")
+	if len(fields) > 0 && contains(h.cfg.Slow, strings.ToLower(fields[0])) && responseURL != "" {
+		go h.slackDeferred(responseURL, text)
+		writeJSON(w, map[string]string{"response_type": "ephemeral", "text": "Working on it..."})
+		return
+	}
+	writeJSON(w, slackMessage(h.answer(text)))
+}
+
+// slackDeferred posts a slow command's answer to the slash command's response_url
+func (h *handler) slackDeferred(responseURL, text string) {
+	payload, _ := json.Marshal(slackMessage(h.answer(text)))
+	resp, err := h.client.Post(responseURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("bot: slack response failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func slackMessage(answer string) map[string]string {
+	return map[string]string{"response_type": "in_channel", "text": "```" + answer + "```"}
+}
+
+// verifySlack checks the v0 request signature described in Slack's signing secret docs
+func verifySlack(secret string, header http.Header, body []byte, now time.Time) bool {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > slackMaxAge || age < -slackMaxAge {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+// mentionTag matches the <at>Bot</at> mention Teams prepends to messages
+var mentionTag = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// htmlTag strips simple formatting Teams adds around message text
+var htmlTag = regexp.MustCompile(`<[^>]+>`)
+
+func (h *handler) teams(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !verifyTeams(h.cfg.TeamsSecret, r.Header.Get("Authorization"), body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var activity struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	text := htmlTag.ReplaceAllString(mentionTag.ReplaceAllString(activity.Text, ""), "")
+	text = strings.ReplaceAll(text, "&nbsp;", " ")
+	writeJSON(w, map[string]string{"type": "message", "text": "<pre>" + html.EscapeString(h.answer(text)) + "</pre>"})
+}
+
+// verifyTeams checks the HMAC header of a Teams outgoing webhook request
+func verifyTeams(secret, authorization string, body []byte) bool {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return false
+	}
+	got, ok := strings.CutPrefix(authorization, "HMAC ")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(got))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
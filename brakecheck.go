@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const brakecheckUsage = `Usage: generate brakecheck [--interval 24] [--prewarning 2] [--position 0,0,0,0,30,0]
+       [--warning diCBCPreWarn] [--required diCBCRequired] [--sync 0,-20,20,0,40,0]
+       [--module BrakeCheck] [--output dir]
+Generates the RAPID side of the SafeMove cyclic brake check: a routine that
+runs the check as soon as SafeMove warns, a service routine dispatcher the
+PLC or HMI can call through giServiceReq, and scheduling guidance. Intervals
+are in hours and must match the safety configuration. --sync adds the
+synchronization check at the given joint position (degrees).
+
+Example:
+  generate brakecheck --interval 24 --prewarning 4 --position 0,0,0,0,45,0`
+
+// generateBrakeCheck writes the brake check module and its guidance
+func generateBrakeCheck(args []string) (string, error) {
+	opts := generate.BrakeCheckOptions{
+		Module:     "BrakeCheck",
+		Interval:   24,
+		PreWarning: 2,
+		Position:   [6]float64{0, 0, 0, 0, 30, 0},
+		Warning:    "diCBCPreWarn",
+		Required:   "diCBCRequired",
+	}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return brakecheckUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--interval":
+			opts.Interval, err = strconv.ParseFloat(strings.TrimSuffix(value, "h"), 64)
+		case "--prewarning":
+			opts.PreWarning, err = strconv.ParseFloat(strings.TrimSuffix(value, "h"), 64)
+		case "--position":
+			opts.Position, err = parseJoints(value)
+		case "--sync":
+			opts.SyncPos, err = parseJoints(value)
+			opts.Sync = true
+		case "--warning":
+			opts.Warning = value
+		case "--required":
+			opts.Required = value
+		case "--module":
+			opts.Module = value
+		case "--output":
+			output = value
+		default:
+			return brakecheckUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if !identifierRe.MatchString(opts.Module) {
+		return "", fmt.Errorf("%q is not a valid RAPID module name", opts.Module)
+	}
+
+	module, guidance, err := generate.BrakeCheckCode(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return guidance + "\n--- " + opts.Module + ".mod ---\n" + strings.TrimRight(module, "\n"), nil
+	}
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	path := filepath.Join(output, opts.Module+".mod")
+	if err := writeFile(path, []byte(module), 0644); err != nil {
+		return "", err
+	}
+	return guidance + "\nWrote " + path, nil
+}
+
+// parseJoints reads six comma-separated joint angles
+func parseJoints(s string) ([6]float64, error) {
+	var joints [6]float64
+	fields := strings.Split(s, ",")
+	if len(fields) != 6 {
+		return joints, fmt.Errorf("need 6 joint angles")
+	}
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return joints, err
+		}
+		joints[i] = v
+	}
+	return joints, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+func init() {
+	commandRegistry["calc"] = Command{
+		Group:       groupCalc,
+		Description: "Engineering calculators for robot programming",
+		Usage:       calcUsage,
+		Execute:     text(runCalc),
+	}
+}
+
+const calcUsage = `Usage: calc <calculator> [arguments]
+Available calculators:
+  reorient      - Check if reorientation speed limits the programmed TCP speed
+  workspace     - Working envelope on a pedestal and mounting checks
+  track         - Track positions for robtargets and reach checks on a linear axis
+  checksum      - CRC, LRC, XOR and sum checks of serial/socket messages
+  frame         - Build STX/ETX frames with check values and RAPID string literals
+  collaborative - Allowed speeds per body region for power and force limiting
+  stopping      - Robot stopping time and light curtain distance (ISO 13855)
+  pneumatic     - Air consumption, forces and valve duty of grippers and cylinders
+
+Examples:
+  calc reorient 100 90 v1000
+  calc reorient 100 1,0,0,0 0,0,1,0 v500
+  calc workspace --robot irb2600 --pedestal 400
+  calc track Station1.mod --robot irb6700-150 --travel 0:12000
+  calc checksum --algo crc16-modbus --hex "01 03 00 00 00 02"
+  calc frame --text "JOB,12" --stx --etx --check xor --check-hex
+  calc collaborative --payload 5kg --speed 250mm/s
+  calc stopping --robot irb6700-150 --speed 66
+  calc pneumatic --bore 32 --stroke 20 --rod 12 --cycle 12s --grip-time 8s`
+
+func runCalc(args []string) (string, error) {
+	if len(args) < 1 {
+		return calcUsage, nil
+	}
+
+	switch args[0] {
+	case "reorient":
+		return calcReorient(args[1:])
+	case "workspace":
+		return calcWorkspace(args[1:])
+	case "track":
+		return calcTrack(args[1:])
+	case "checksum":
+		return calcChecksum(args[1:])
+	case "frame":
+		return calcFrame(args[1:])
+	case "collaborative":
+		return calcCollaborative(args[1:])
+	case "stopping":
+		return calcStopping(args[1:])
+	case "pneumatic":
+		return calcPneumatic(args[1:])
+	default:
+		return "Unknown calculator. Available: reorient, workspace, track, checksum, frame, collaborative, stopping, pneumatic", nil
+	}
+}
+
+func calcReorient(args []string) (string, error) {
+	usage := "Usage: calc reorient <length_mm> <angle_deg | from_quat to_quat> <speeddata>\n" +
+		"Example: calc reorient 100 90 v1000"
+	if len(args) != 3 && len(args) != 4 {
+		return usage, nil
+	}
+
+	length, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid segment length: %s", args[0])
+	}
+
+	var angle float64
+	if len(args) == 4 {
+		from, err := calc.ParseQuaternion(args[1])
+		if err != nil {
+			return "", err
+		}
+		to, err := calc.ParseQuaternion(args[2])
+		if err != nil {
+			return "", err
+		}
+		angle = calc.QuaternionAngle(from, to)
+	} else {
+		angle, err = strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid angle: %s", args[1])
+		}
+	}
+
+	speed, err := calc.ParseSpeed(args[len(args)-1])
+	if err != nil {
+		return "", err
+	}
+
+	r, err := calc.Reorient(length, angle, speed)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString("\nReorientation Check:\n")
+	result.WriteString("====================\n")
+	result.WriteString(fmt.Sprintf("Segment length:     %.1f mm\n", r.Length))
+	result.WriteString(fmt.Sprintf("Orientation change: %.1f deg\n", r.Angle))
+	result.WriteString(fmt.Sprintf("Speeddata:          [%g,%g,%g,%g]\n", speed.TCP, speed.Ori, speed.LinExt, speed.RotExt))
+	result.WriteString(fmt.Sprintf("TCP time:           %.3f s (at %g mm/s)\n", r.TCPTime, speed.TCP))
+	result.WriteString(fmt.Sprintf("Reorientation time: %.3f s (at %g deg/s)\n\n", r.OriTime, speed.Ori))
+
+	if r.Pure {
+		result.WriteString(fmt.Sprintf("Result: Pure reorientation - the TCP speed does not apply.\n"+
+			"The segment takes %.3f s at v_ori %g deg/s; change v_ori to change it.", r.OriTime, speed.Ori))
+		return result.String(), nil
+	}
+	if !r.Limited {
+		result.WriteString("Result: TCP speed is the limiting factor - programmed speed will be reached.")
+		return result.String(), nil
+	}
+
+	result.WriteString(fmt.Sprintf("Result: Reorientation LIMITS this segment.\n"+
+		"- Effective TCP speed: %.0f mm/s instead of %g mm/s\n"+
+		"- Required v_ori:      %.0f deg/s\n\n", r.EffectiveTCP, speed.TCP, r.RequiredOri))
+	result.WriteString("Suggested speeddata:\n")
+	result.WriteString(r.SuggestSpeed() + "\n\n")
+	result.WriteString("Note: The robot's own axis limits still apply. If the path stays slow,\n" +
+		"reduce the orientation change or split it over a longer segment.")
+	return result.String(), nil
+}
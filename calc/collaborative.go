@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"math"
+	"strings"
+)
+
+// BodyRegion holds the ISO/TS 15066 Annex A values of one body region
+type BodyRegion struct {
+	Name   string
+	Title  string
+	Force  float64 // N, maximum quasi-static contact force (Table A.2)
+	Spring float64 // N/mm, effective spring constant (Table A.3)
+	Mass   float64 // kg, effective mass of the body region (Table A.3)
+	// Transient is false where the standard allows no transient contact,
+	// which applies to the head
+	Transient bool
+}
+
+// BodyRegions are the body regions of ISO/TS 15066 Annex A, head to feet
+var BodyRegions = []BodyRegion{
+	{"skull", "Skull and forehead", 130, 150, 4.4, false},
+	{"face", "Face", 65, 75, 4.4, false},
+	{"neck", "Neck", 150, 50, 1.2, false},
+	{"shoulder", "Back and shoulders", 210, 35, 40, true},
+	{"chest", "Chest", 140, 25, 40, true},
+	{"abdomen", "Abdomen", 110, 10, 40, true},
+	{"pelvis", "Pelvis", 180, 25, 40, true},
+	{"upper_arm", "Upper arm and elbow", 150, 30, 3, true},
+	{"lower_arm", "Lower arm and wrist", 160, 40, 2, true},
+	{"hand", "Hand and fingers", 140, 75, 0.6, true},
+	{"thigh", "Thigh and knee", 220, 50, 75, true},
+	{"lower_leg", "Lower leg", 130, 60, 75, true},
+}
+
+// FindBodyRegion looks up a body region by name
+func FindBodyRegion(name string) (BodyRegion, bool) {
+	for _, r := range BodyRegions {
+		if strings.EqualFold(r.Name, name) {
+			return r, true
+		}
+	}
+	return BodyRegion{}, false
+}
+
+// TransientForce is the force permitted in transient contact, twice the
+// quasi-static limit (ISO/TS 15066 A.3.2)
+func (r BodyRegion) TransientForce() float64 {
+	return 2 * r.Force
+}
+
+// Energy is the transferable energy in J at the transient force limit,
+// E = F²/2k
+func (r BodyRegion) Energy() float64 {
+	return r.TransientForce() * r.TransientForce() / (2 * r.Spring * 1000)
+}
+
+// Contact is the power and force limiting estimate for one body region
+type Contact struct {
+	Region      BodyRegion
+	ReducedMass float64 // kg, two-body reduced mass of robot and body region
+	MaxSpeed    float64 // mm/s, highest relative speed for transient contact; 0 when none is allowed
+}
+
+// RobotEffectiveMass is the effective robot mass of ISO/TS 15066 A.3.3,
+// m = M/2 + mL with the moving mass M of the robot and the payload mL
+func RobotEffectiveMass(moving, payload float64) float64 {
+	return moving/2 + payload
+}
+
+// Collaborative estimates the highest relative speed for transient
+// contact with each body region following ISO/TS 15066 A.3.3,
+// v = F / sqrt(μ k) with the reduced mass μ = (1/mH + 1/mR)⁻¹. Masses in kg.
+func Collaborative(robotMass float64) []Contact {
+	var contacts []Contact
+	for _, r := range BodyRegions {
+		c := Contact{Region: r, ReducedMass: 1 / (1/r.Mass + 1/robotMass)}
+		if r.Transient {
+			c.MaxSpeed = r.TransientForce() / math.Sqrt(c.ReducedMass*r.Spring*1000) * 1000
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts
+}
@@ -0,0 +1,61 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// atmosphere is the ambient pressure in bar used to convert compressed
+// air to free air (normal litres)
+const atmosphere = 1.013
+
+// Cylinder is a double-acting pneumatic cylinder, dimensions in mm
+type Cylinder struct {
+	Bore   float64
+	Rod    float64
+	Stroke float64
+	// Tube is the inner diameter and length in mm of the tubing between
+	// valve and cylinder, filled and exhausted on every stroke
+	TubeID     float64
+	TubeLength float64
+}
+
+// Pneumatics is the air consumption and force of a cylinder at a gauge
+// pressure
+type Pneumatics struct {
+	Pressure     float64 // bar gauge
+	ExtendForce  float64 // N, theoretical at the piston
+	RetractForce float64 // N
+	ExtendAir    float64 // normal litres per extend stroke, tubing included
+	RetractAir   float64 // normal litres per retract stroke
+}
+
+// CycleAir is the free air of one extend and retract cycle in normal litres
+func (p Pneumatics) CycleAir() float64 {
+	return p.ExtendAir + p.RetractAir
+}
+
+// Pneumatic computes forces and air consumption of a cylinder at the gauge
+// pressure in bar
+func Pneumatic(c Cylinder, pressure float64) (Pneumatics, error) {
+	if c.Bore <= 0 || c.Stroke <= 0 {
+		return Pneumatics{}, fmt.Errorf("bore and stroke must be positive")
+	}
+	if c.Rod < 0 || c.Rod >= c.Bore {
+		return Pneumatics{}, fmt.Errorf("rod diameter must be smaller than the bore")
+	}
+	if pressure <= 0 {
+		return Pneumatics{}, fmt.Errorf("pressure must be positive")
+	}
+	area := math.Pi / 4 * c.Bore * c.Bore                    // mm²
+	annulus := math.Pi / 4 * (c.Bore*c.Bore - c.Rod*c.Rod)   // mm²
+	tube := math.Pi / 4 * c.TubeID * c.TubeID * c.TubeLength // mm³
+	ratio := (pressure + atmosphere) / atmosphere
+	return Pneumatics{
+		Pressure:     pressure,
+		ExtendForce:  area * pressure / 10, // 1 bar = 0.1 N/mm²
+		RetractForce: annulus * pressure / 10,
+		ExtendAir:    (area*c.Stroke + tube) * ratio / 1e6,
+		RetractAir:   (annulus*c.Stroke + tube) * ratio / 1e6,
+	}, nil
+}
@@ -0,0 +1,128 @@
+// Package calc provides engineering calculators for robot cell programming
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+)
+
+// ReorientResult describes how a path segment is limited by the speeddata components
+type ReorientResult struct {
+	Length       float64 // Segment length (mm)
+	Angle        float64 // Orientation change (deg)
+	Speed        abb.SpeedData
+	TCPTime      float64 // Time needed at the programmed TCP speed (s)
+	OriTime      float64 // Time needed at the programmed reorientation speed (s)
+	Limited      bool    // True when reorientation dominates the segment time
+	EffectiveTCP float64 // Actual TCP speed achieved over the segment (mm/s)
+	RequiredOri  float64 // v_ori needed to keep the programmed TCP speed (deg/s)
+	Pure         bool    // Zero length: only v_ori applies, the TCP speed does not
+}
+
+// Reorient calculates whether the reorientation speed limits the TCP speed
+// over a segment of the given length and orientation change
+func Reorient(length, angle float64, speed abb.SpeedData) (ReorientResult, error) {
+	if !finite(length) || !finite(angle) || length < 0 || angle < 0 {
+		return ReorientResult{}, fmt.Errorf("length and angle must be positive numbers")
+	}
+	for _, v := range []float64{speed.TCP, speed.Ori, speed.LinExt, speed.RotExt} {
+		if !finite(v) || v < 0 {
+			return ReorientResult{}, fmt.Errorf("speeddata components must be positive numbers")
+		}
+	}
+	if speed.TCP == 0 || speed.Ori == 0 {
+		return ReorientResult{}, fmt.Errorf("speeddata must have positive v_tcp and v_ori")
+	}
+
+	r := ReorientResult{Length: length, Angle: angle, Speed: speed}
+	r.TCPTime = length / speed.TCP
+	r.OriTime = angle / speed.Ori
+	if length == 0 {
+		// A pure reorientation takes the time v_ori gives it; there is no
+		// TCP speed to keep
+		r.Pure = true
+		return r, nil
+	}
+	r.Limited = r.OriTime > r.TCPTime
+	r.EffectiveTCP = speed.TCP
+	if r.Limited && r.OriTime > 0 {
+		r.EffectiveTCP = length / r.OriTime
+	}
+	r.RequiredOri = angle / r.TCPTime
+	return r, nil
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// ParseSpeed accepts a standard speeddata name (v1000) or a custom
+// speeddata record written as [v_tcp,v_ori,v_leax,v_reax]
+func ParseSpeed(s string) (abb.SpeedData, error) {
+	if sd, ok := abb.StandardSpeeds[strings.ToLower(s)]; ok {
+		return sd, nil
+	}
+
+	fields := strings.Split(strings.Trim(s, "[]"), ",")
+	if len(fields) != 4 {
+		return abb.SpeedData{}, fmt.Errorf("unknown speeddata %q (use v1000 or [v_tcp,v_ori,v_leax,v_reax])", s)
+	}
+	var values [4]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return abb.SpeedData{}, fmt.Errorf("invalid speeddata component %q", f)
+		}
+		values[i] = v
+	}
+	return abb.SpeedData{TCP: values[0], Ori: values[1], LinExt: values[2], RotExt: values[3]}, nil
+}
+
+// ParseQuaternion reads a quaternion written as q1,q2,q3,q4 (brackets optional)
+func ParseQuaternion(s string) ([4]float64, error) {
+	var q [4]float64
+	fields := strings.Split(strings.Trim(s, "[]"), ",")
+	if len(fields) != 4 {
+		return q, fmt.Errorf("quaternion needs 4 components: %q", s)
+	}
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return q, fmt.Errorf("invalid quaternion component %q", f)
+		}
+		q[i] = v
+	}
+	norm := math.Sqrt(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])
+	if norm == 0 {
+		return q, fmt.Errorf("quaternion must not be zero")
+	}
+	for i := range q {
+		q[i] /= norm
+	}
+	return q, nil
+}
+
+// QuaternionAngle returns the rotation angle in degrees between two orientations
+func QuaternionAngle(a, b [4]float64) float64 {
+	dot := math.Abs(a[0]*b[0] + a[1]*b[1] + a[2]*b[2] + a[3]*b[3])
+	if dot > 1 {
+		dot = 1
+	}
+	return 2 * math.Acos(dot) * 180 / math.Pi
+}
+
+// SuggestSpeed returns a speeddata declaration that lets the TCP speed
+// be reached over the segment, rounded up to the next 10 deg/s. It applies
+// to segments with a length; a pure reorientation keeps its speeddata.
+func (r ReorientResult) SuggestSpeed() string {
+	ori := r.Speed.Ori
+	if !r.Pure {
+		ori = math.Ceil(r.RequiredOri/10) * 10
+	}
+	return fmt.Sprintf("VAR speeddata vReorient := [%g,%g,%g,%g];",
+		r.Speed.TCP, ori, r.Speed.LinExt, r.Speed.RotExt)
+}
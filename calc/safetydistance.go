@@ -0,0 +1,58 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// SafetyDistance is the minimum distance of an electro-sensitive
+// protective device from the hazard zone by ISO 13855, with the steps of
+// the calculation
+type SafetyDistance struct {
+	Time     float64 // s, overall stopping performance T
+	K        float64 // mm/s, approach speed
+	C        float64 // mm, intrusion distance
+	Distance float64 // mm
+	Steps    []string
+}
+
+// LightCurtainDistance computes the minimum distance of a light curtain or
+// grid with the detection capability d in mm approached at right angles
+// (ISO 13855 6.2.3), for the overall stopping performance t in s
+func LightCurtainDistance(t, d float64) (SafetyDistance, error) {
+	if t <= 0 {
+		return SafetyDistance{}, fmt.Errorf("stopping performance must be positive")
+	}
+	if d <= 0 || d > 70 {
+		return SafetyDistance{}, fmt.Errorf("detection capability %g mm is outside 14-70 mm; use the multi-beam distances of ISO 13855 6.2.4", d)
+	}
+	s := SafetyDistance{Time: t}
+	if d > 40 {
+		s.K, s.C = 1600, 850
+		s.Distance = s.K*t + s.C
+		s.Steps = append(s.Steps,
+			fmt.Sprintf("d = %g mm > 40 mm: S = K*T + C with K = 1600 mm/s, C = 850 mm", d),
+			fmt.Sprintf("S = 1600 * %.3f + 850 = %.0f mm", t, s.Distance))
+		return s, nil
+	}
+	s.C = math.Max(0, 8*(d-14))
+	s.K = 2000
+	s.Distance = s.K*t + s.C
+	s.Steps = append(s.Steps,
+		fmt.Sprintf("d = %g mm <= 40 mm: C = 8 * (d - 14) = %.0f mm", d, s.C),
+		fmt.Sprintf("S = 2000 * %.3f + %.0f = %.0f mm", t, s.C, s.Distance))
+	switch {
+	case s.Distance < 100:
+		s.Distance = 100
+		s.Steps = append(s.Steps, "S < 100 mm: S = 100 mm (minimum)")
+	case s.Distance > 500:
+		s.K = 1600
+		s.Distance = s.K*t + s.C
+		s.Steps = append(s.Steps, fmt.Sprintf("S > 500 mm: recalculated with K = 1600 mm/s: S = 1600 * %.3f + %.0f = %.0f mm", t, s.C, s.Distance))
+		if s.Distance < 500 {
+			s.Distance = 500
+			s.Steps = append(s.Steps, "S < 500 mm with K = 1600 mm/s: S = 500 mm (minimum)")
+		}
+	}
+	return s, nil
+}
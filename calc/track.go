@@ -0,0 +1,92 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Track is a linear axis carrying the robot along world X. At track
+// position 0 the robot base is at the world origin, as with a base frame
+// coordinated to the track in MOC.cfg. Positions are in mm.
+type Track struct {
+	Min, Max float64
+}
+
+// TrackPlacement is the track position chosen for one target and how the
+// arm reaches it from there
+type TrackPlacement struct {
+	Position  float64
+	Distance  float64 // Horizontal distance from axis 2 to the target
+	Available float64 // Horizontal reach at the target height
+	Reachable bool
+	Warning   string
+}
+
+// Share of the reach used for comfortable working: beyond it the wrist has
+// little room for reorientation
+const trackComfort = 0.85
+
+// minRadius is the distance from axis 2 below which the arm folds back over
+// its own base
+func (e Envelope) minRadius() float64 {
+	return 0.3 * e.Reach
+}
+
+// PlaceOnTrack returns the track position for a target in world
+// coordinates. The target is placed in front of the arm at a comfortable
+// distance; of two equal choices the one nearer to from is taken, to keep
+// track travel between consecutive targets short.
+func (e Envelope) PlaceOnTrack(t Track, target [3]float64, from float64) TrackPlacement {
+	x, y, z := target[0], target[1], target[2]
+	p := TrackPlacement{Available: e.ReachAt(z)}
+
+	// Along the track the robot can stand right beside the target, unless
+	// the target is so close to the track line that the arm would fold back
+	dx := 0.0
+	if r := e.minRadius(); math.Abs(y) < r {
+		dx = math.Sqrt(r*r - y*y)
+	}
+	before, after := clamp(x-dx, t.Min, t.Max), clamp(x+dx, t.Min, t.Max)
+	p.Position = before
+	if math.Abs(after-from) < math.Abs(before-from) {
+		p.Position = after
+	}
+	p.Distance = math.Hypot(x-p.Position, y)
+	p.Reachable, p.Warning = e.judgeReach(p.Distance, p.Available, z)
+	if !p.Reachable && p.Available > 0 && math.Abs(y) > p.Available {
+		p.Warning = fmt.Sprintf("%.0f mm beside the track, the arm reaches %.0f mm at z=%.0f from any track position", math.Abs(y), p.Available, z)
+	} else if !p.Reachable && (x < t.Min-p.Available || x > t.Max+p.Available) {
+		p.Warning = fmt.Sprintf("beyond the track travel %.0f to %.0f mm", t.Min, t.Max)
+	}
+	return p
+}
+
+// CheckOnTrack judges a target programmed at a given track position
+func (e Envelope) CheckOnTrack(t Track, target [3]float64, position float64) TrackPlacement {
+	p := TrackPlacement{Position: position, Available: e.ReachAt(target[2])}
+	p.Distance = math.Hypot(target[0]-position, target[1])
+	p.Reachable, p.Warning = e.judgeReach(p.Distance, p.Available, target[2])
+	if position < t.Min || position > t.Max {
+		p.Reachable = false
+		p.Warning = fmt.Sprintf("track position %.0f mm outside the travel %.0f to %.0f mm", position, t.Min, t.Max)
+	}
+	return p
+}
+
+func (e Envelope) judgeReach(distance, available, z float64) (bool, string) {
+	switch {
+	case available == 0:
+		return false, fmt.Sprintf("z=%.0f mm is outside the envelope (%.0f to %.0f mm)", z, math.Max(e.Bottom, 0), e.Top)
+	case distance > available:
+		return false, fmt.Sprintf("%.0f mm beyond the reach of the arm", distance-available)
+	case distance > trackComfort*available:
+		return true, "near full extension, little room for reorientation"
+	case distance < e.minRadius():
+		return true, "close to the base, check for collision with the arm and the track carriage"
+	}
+	return true, ""
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
@@ -0,0 +1,154 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Transform is a rigid transform: a rotation about Origin followed by a
+// translation. Rotation is a unit quaternion in RAPID order (q1 = w).
+type Transform struct {
+	Origin    [3]float64
+	Rotation  [4]float64
+	Translate [3]float64
+}
+
+// Identity returns a transform that leaves positions unchanged
+func Identity() Transform {
+	return Transform{Rotation: [4]float64{1, 0, 0, 0}}
+}
+
+// AxisRotation returns the quaternion for a rotation in degrees about the
+// x, y or z axis
+func AxisRotation(axis byte, degrees float64) ([4]float64, error) {
+	half := degrees * math.Pi / 360
+	s, c := math.Sin(half), math.Cos(half)
+	switch axis {
+	case 'x':
+		return [4]float64{c, s, 0, 0}, nil
+	case 'y':
+		return [4]float64{c, 0, s, 0}, nil
+	case 'z':
+		return [4]float64{c, 0, 0, s}, nil
+	default:
+		return [4]float64{}, fmt.Errorf("unknown rotation axis %q", axis)
+	}
+}
+
+// Rotate adds a rotation applied after the existing one
+func (t *Transform) Rotate(q [4]float64) {
+	t.Rotation = QuaternionMultiply(q, t.Rotation)
+}
+
+// Apply transforms a position and orientation
+func (t Transform) Apply(pos [3]float64, rot [4]float64) ([3]float64, [4]float64) {
+	rel := [3]float64{pos[0] - t.Origin[0], pos[1] - t.Origin[1], pos[2] - t.Origin[2]}
+	r := RotateVector(t.Rotation, rel)
+	var out [3]float64
+	for i := range out {
+		out[i] = r[i] + t.Origin[i] + t.Translate[i]
+	}
+	return out, NormalizeQuaternion(QuaternionMultiply(t.Rotation, rot))
+}
+
+// RotationAngle returns the size of the transform's rotation in degrees
+func (t Transform) RotationAngle() float64 {
+	return QuaternionAngle([4]float64{1, 0, 0, 0}, t.Rotation)
+}
+
+// QuaternionMultiply returns the Hamilton product a*b (apply b, then a)
+func QuaternionMultiply(a, b [4]float64) [4]float64 {
+	return [4]float64{
+		a[0]*b[0] - a[1]*b[1] - a[2]*b[2] - a[3]*b[3],
+		a[0]*b[1] + a[1]*b[0] + a[2]*b[3] - a[3]*b[2],
+		a[0]*b[2] - a[1]*b[3] + a[2]*b[0] + a[3]*b[1],
+		a[0]*b[3] + a[1]*b[2] - a[2]*b[1] + a[3]*b[0],
+	}
+}
+
+// RotateVector rotates v by the unit quaternion q
+func RotateVector(q [4]float64, v [3]float64) [3]float64 {
+	p := QuaternionMultiply(QuaternionMultiply(q, [4]float64{0, v[0], v[1], v[2]}),
+		[4]float64{q[0], -q[1], -q[2], -q[3]})
+	return [3]float64{p[1], p[2], p[3]}
+}
+
+// NormalizeQuaternion scales q to unit length with a non-negative q1, the
+// form RAPID programs usually store
+func NormalizeQuaternion(q [4]float64) [4]float64 {
+	norm := math.Sqrt(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])
+	if norm == 0 {
+		return [4]float64{1, 0, 0, 0}
+	}
+	sign := 1.0
+	if q[0] < 0 {
+		sign = -1
+	}
+	for i := range q {
+		q[i] = sign * q[i] / norm
+		if math.Abs(q[i]) < 1e-12 {
+			q[i] = 0
+		}
+	}
+	return q
+}
+
+// ParseVector reads a vector written as x,y,z (brackets optional)
+func ParseVector(s string) ([3]float64, error) {
+	var v [3]float64
+	fields := strings.Split(strings.Trim(s, "[]"), ",")
+	if len(fields) != 3 {
+		return v, fmt.Errorf("vector needs 3 components: %q", s)
+	}
+	for i, f := range fields {
+		n, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid vector component %q", f)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// Mirror reflects positions and orientations in a plane. The orientation
+// becomes that of a mirrored tool, so the result is again a valid rotation.
+type Mirror struct {
+	Axis   int     // Index of the plane normal: 0 for the yz plane, 1 for xz, 2 for xy
+	Offset float64 // Position of the plane along its normal in mm
+}
+
+// ParseMirrorPlane reads a plane name such as "yz"
+func ParseMirrorPlane(plane string) (int, error) {
+	switch strings.ToLower(plane) {
+	case "yz", "zy":
+		return 0, nil
+	case "xz", "zx":
+		return 1, nil
+	case "xy", "yx":
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("unknown mirror plane %q (available: yz, xz, xy)", plane)
+	}
+}
+
+// Apply mirrors a position and orientation
+func (m Mirror) Apply(pos [3]float64, rot [4]float64) ([3]float64, [4]float64) {
+	pos[m.Axis] = 2*m.Offset - pos[m.Axis]
+	// Reflecting the rotation matrix on both sides keeps the component along
+	// the plane normal and negates the other two vector components
+	for i := 0; i < 3; i++ {
+		if i != m.Axis {
+			rot[i+1] = -rot[i+1]
+		}
+	}
+	return pos, NormalizeQuaternion(rot)
+}
+
+// AxisOneQuadrant estimates the RAPID cf1 value of a position for a robot
+// whose base is at the origin of the target frame
+func AxisOneQuadrant(pos [3]float64) int {
+	angle := math.Atan2(pos[1], pos[0]) * 180 / math.Pi
+	return int(math.Floor(angle / 90))
+}
@@ -0,0 +1,101 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+)
+
+// Envelope is the working envelope of an articulated robot mounted at a
+// given height, approximated as a sphere of the rated reach around axis 2.
+// Heights are in mm above the floor.
+type Envelope struct {
+	Robot    abb.Robot
+	Mounting string  // floor, ceiling, wall, tilted or shelf
+	Pedestal float64 // Base height above the floor; for ceiling mounting the height of the base under the ceiling structure
+	Center   float64 // Height of axis 2
+	Reach    float64
+	Top      float64
+	Bottom   float64
+	Warnings []string
+}
+
+// Mountings lists the mounting positions Workspace understands
+var Mountings = []string{"floor", "ceiling", "wall", "tilted", "shelf"}
+
+// Workspace computes the envelope of a robot on a pedestal of the given
+// height in mm and checks the mounting against the robot's specification
+func Workspace(r abb.Robot, mounting string, pedestal float64) (Envelope, error) {
+	if r.Shoulder == 0 {
+		return Envelope{}, fmt.Errorf("no envelope data for %s; SCARA and delta robots have a cylindrical envelope, see the product manual", r.Model)
+	}
+	if pedestal < 0 {
+		return Envelope{}, fmt.Errorf("pedestal height must not be negative")
+	}
+	e := Envelope{Robot: r, Mounting: mounting, Pedestal: pedestal, Reach: r.Reach * 1000}
+	shoulder := r.Shoulder * 1000
+	switch mounting {
+	case "floor", "shelf", "tilted":
+		e.Center = pedestal + shoulder
+	case "ceiling":
+		e.Center = pedestal - shoulder
+	case "wall":
+		// Axis 2 sits about the shoulder height out from the wall at the mounting height
+		e.Center = pedestal
+	default:
+		return Envelope{}, fmt.Errorf("unknown mounting %q (available: %s)", mounting, strings.Join(Mountings, ", "))
+	}
+	e.Top = e.Center + e.Reach
+	e.Bottom = e.Center - e.Reach
+	if mounting == "ceiling" {
+		// The ceiling structure takes the space above the base
+		e.Top = math.Min(e.Top, pedestal)
+	}
+
+	if !containsFold(r.Mounting, mounting) {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("%s is not specified for %s mounting (allowed: %s)", r.Model, mounting, strings.Join(r.Mounting, ", ")))
+	}
+	switch mounting {
+	case "floor", "shelf":
+		if e.Bottom < 0 {
+			e.Warnings = append(e.Warnings, fmt.Sprintf("the envelope reaches %.0f mm below floor level: limit axis 2/3 or use SafeMove zones so the arm cannot hit the floor", -e.Bottom))
+		}
+	case "ceiling":
+		if e.Center < 0 {
+			e.Warnings = append(e.Warnings, "axis 2 would be below the floor: the pedestal is the height of the base under the ceiling structure")
+		}
+		e.Warnings = append(e.Warnings, "ceiling mounting: set the base frame and gravity (MOC.cfg gravity_beta) for the inverted robot and check the ceiling structure for the dynamic loads")
+	case "wall":
+		e.Warnings = append(e.Warnings, "wall mounting: set gravity_beta in MOC.cfg and reduce the axis 1 range so the arm cannot hit the wall")
+	case "tilted":
+		if e.Bottom < 0 {
+			e.Warnings = append(e.Warnings, fmt.Sprintf("the envelope reaches %.0f mm below floor level: limit axis 2/3 or use SafeMove zones so the arm cannot hit the floor", -e.Bottom))
+		}
+		e.Warnings = append(e.Warnings, "tilted mounting: the envelope is shown upright; set the tilt in gravity_beta and check the load diagram for the angle")
+	}
+	if pedestal > 1000 && mounting != "ceiling" {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("a %.0f mm riser must carry the base tilting moment of the product manual; its stiffness also limits path accuracy", pedestal))
+	}
+	return e, nil
+}
+
+// ReachAt returns the horizontal reach in mm at a height above the floor,
+// or zero when the height is outside the envelope
+func (e Envelope) ReachAt(height float64) float64 {
+	dz := height - e.Center
+	if math.Abs(dz) >= e.Reach || height > e.Top {
+		return 0
+	}
+	return math.Sqrt(e.Reach*e.Reach - dz*dz)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+// Package calib keeps track of robot calibration data: the motor
+// calibration offsets used when revolution counters are updated after a lost
+// SMB battery, axis ranges and a per-serial calibration log
+package calib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/cfg"
+)
+
+// Axis is the calibration data of one robot axis from MOC.cfg
+type Axis struct {
+	Name   string  // Instance name, e.g. rob1_1
+	Offset float64 // Calibration offset in motor radians
+	Valid  bool    // The offset is marked valid
+	Ratio  float64 // Gear ratio motor to arm, 0 when unknown
+	Upper  float64 // Axis range in radians (linear axes in meters)
+	Lower  float64
+	Range  bool // Upper and Lower are set
+}
+
+// Window returns the angle in arm degrees the axis may be off its sync
+// mark when the revolution counter is updated. Within half a motor
+// revolution the controller finds the right revolution; beyond it the axis
+// ends up a full motor revolution, 360/ratio degrees, away.
+func (a Axis) Window() (half, revolution float64) {
+	if a.Ratio == 0 {
+		return 0, 0
+	}
+	return 180 / a.Ratio, 360 / a.Ratio
+}
+
+// FromMOC collects the motor calibration, transmission and axis range of
+// every calibrated axis in a MOC configuration, ordered by name
+func FromMOC(f *cfg.File) []Axis {
+	axes := make(map[string]*Axis)
+	var names []string
+	if t := f.Type("MOTOR_CALIB"); t != nil {
+		for _, inst := range t.Instances {
+			value, ok := inst.Get("cal_offset")
+			if !ok {
+				continue
+			}
+			offset, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			_, valid := inst.Get("valid_cal_offset")
+			name := inst.Name()
+			axes[strings.ToLower(name)] = &Axis{Name: name, Offset: offset, Valid: valid}
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	if t := f.Type("TRANSMISSION"); t != nil {
+		for _, inst := range t.Instances {
+			a := axes[strings.ToLower(inst.Name())]
+			if a == nil {
+				continue
+			}
+			if v, ok := inst.Get("transm_joint"); ok {
+				if ratio, err := strconv.ParseFloat(v, 64); err == nil {
+					a.Ratio = math.Abs(ratio)
+				}
+			}
+		}
+	}
+	if t := f.Type("ARM"); t != nil {
+		for _, inst := range t.Instances {
+			a := axes[strings.ToLower(inst.Name())]
+			if a == nil {
+				continue
+			}
+			upper, errU := strconv.ParseFloat(getOr(inst, "upper_joint_bound"), 64)
+			lower, errL := strconv.ParseFloat(getOr(inst, "lower_joint_bound"), 64)
+			if errU == nil && errL == nil {
+				a.Upper, a.Lower, a.Range = upper, lower, true
+			}
+		}
+	}
+	sort.Strings(names)
+	result := make([]Axis, 0, len(names))
+	for _, n := range names {
+		result = append(result, *axes[n])
+	}
+	return result
+}
+
+func getOr(inst cfg.Instance, name string) string {
+	v, _ := inst.Get(name)
+	return v
+}
+
+// Offsets returns the calibration offsets by axis name
+func Offsets(axes []Axis) map[string]float64 {
+	offsets := make(map[string]float64, len(axes))
+	for _, a := range axes {
+		offsets[strings.ToLower(a.Name)] = a.Offset
+	}
+	return offsets
+}
+
+// ParseOffsets reads offsets typed from the calibration label on the robot,
+// comma separated in axis order, for the robot's axes rob1_1, rob1_2, ...
+func ParseOffsets(list, robot string) (map[string]float64, error) {
+	offsets := make(map[string]float64)
+	for i, field := range strings.Split(list, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %q for axis %d", field, i+1)
+		}
+		offsets[fmt.Sprintf("%s_%d", strings.ToLower(robot), i+1)] = v
+	}
+	return offsets, nil
+}
+
+// Tolerance is the largest offset difference in motor radians treated as
+// equal; labels and MOC.cfg print four decimals
+const Tolerance = 0.0001
+
+// Finding is a problem with the calibration offsets of an axis
+type Finding struct {
+	Axis    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Axis + ": " + f.Message
+}
+
+// Validate checks current offsets against reference values, normally the
+// calibration label or the first log entry, and against the valid range of
+// one motor revolution
+func Validate(reference, current map[string]float64) []Finding {
+	var findings []Finding
+	for _, name := range sortedNames(current) {
+		v := current[name]
+		if v < 0 || v >= 2*math.Pi {
+			findings = append(findings, Finding{name, fmt.Sprintf("offset %.4f is outside 0-6.2832 motor radians", v)})
+		}
+		ref, ok := reference[name]
+		if !ok {
+			if reference != nil {
+				findings = append(findings, Finding{name, "no reference value recorded"})
+			}
+			continue
+		}
+		if math.Abs(ref-v) > Tolerance {
+			findings = append(findings, Finding{name, fmt.Sprintf("offset %.4f differs from the reference %.4f", v, ref)})
+		}
+	}
+	for _, name := range sortedNames(reference) {
+		if _, ok := current[name]; !ok {
+			findings = append(findings, Finding{name, "axis missing from the current configuration"})
+		}
+	}
+	return findings
+}
+
+func sortedNames(m map[string]float64) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,57 @@
+package calib
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Guide returns the revolution counter update procedure. With axes from
+// MOC.cfg it adds the offsets, axis ranges and sync mark windows to check
+// against.
+func Guide(axes []Axis) string {
+	var b strings.Builder
+	b.WriteString(`Updating revolution counters (after 38213 Battery charge low or
+50296 SMB memory data differs, or after replacing motors or the SMB):
+
+ 1. Note the calibration offsets on the label on the robot base and compare
+    them with MOC.cfg (abb revcounter check). Never update with wrong offsets.
+ 2. Jog each axis in manual reduced speed to its sync mark, starting at the
+    axis that is easiest to reach. Use the axis order of the product manual
+    when the wrist would collide.
+ 3. Align the marks within the window below: half a motor revolution. A
+    larger error puts the axis a full motor revolution off, which the
+    controller cannot detect.
+ 4. Calibrate > Update revolution counters, select the aligned axes, update.
+ 5. Check the calibration position: move all axes to zero (MoveAbsJ with all
+    joints at 0) and verify every sync mark lines up.
+ 6. Record the event: abb revcounter record <serial> --from <backup>.
+`)
+	if len(axes) == 0 {
+		return b.String()
+	}
+	b.WriteString("\nAxes from MOC.cfg:\n")
+	fmt.Fprintf(&b, "  %-10s %10s  %-20s %s\n", "Axis", "Offset", "Range (deg)", "Sync window")
+	for _, a := range axes {
+		offset := fmt.Sprintf("%.4f", a.Offset)
+		if !a.Valid {
+			offset += "*"
+		}
+		rng := "-"
+		if a.Range {
+			rng = fmt.Sprintf("%.1f to %.1f", a.Lower*180/math.Pi, a.Upper*180/math.Pi)
+		}
+		window := "gear ratio unknown"
+		if half, rev := a.Window(); half > 0 {
+			window = fmt.Sprintf("±%.2f°, one revolution = %.2f°", half, rev)
+		}
+		fmt.Fprintf(&b, "  %-10s %10s  %-20s %s\n", a.Name, offset, rng, window)
+	}
+	for _, a := range axes {
+		if !a.Valid {
+			b.WriteString("\n* offset not marked valid; the axis needs fine calibration\n")
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package calib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Entry is one recorded calibration event
+type Entry struct {
+	Date    time.Time          `json:"date"`
+	Event   string             `json:"event"`  // label, backup, battery, revcounter, calibration
+	Source  string             `json:"source"` // File or "label" the offsets came from
+	Note    string             `json:"note,omitempty"`
+	Offsets map[string]float64 `json:"offsets,omitempty"`
+}
+
+// Log is the calibration history of one robot, kept in the project so it
+// survives controller replacements
+type Log struct {
+	Serial  string  `json:"serial"`
+	Entries []Entry `json:"entries"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// LogPath returns where the log for a robot serial number is stored in a
+// project: calibration/<serial>.json
+func LogPath(project, serial string) string {
+	return filepath.Join(project, "calibration", unsafeFileChars.ReplaceAllString(serial, "_")+".json")
+}
+
+// LoadLog reads the log at path; a missing file gives an empty log
+func LoadLog(path, serial string) (*Log, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Log{Serial: serial}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l Log
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &l, nil
+}
+
+// Save writes the log, creating the calibration folder when needed
+func (l *Log) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Reference returns the offsets of the first entry that has any: the
+// calibration label or the first recorded backup
+func (l *Log) Reference() (Entry, bool) {
+	for _, e := range l.Entries {
+		if len(e.Offsets) > 0 {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,189 @@
+// Package cfg parses ABB controller configuration files (SYS.cfg, MOC.cfg,
+// EIO.cfg and the other SYSPAR domains) and compares them
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// File is one configuration domain such as SYS or MOC
+type File struct {
+	Domain  string // SYS, MOC, EIO, ...
+	Version string // Format version from the first line, e.g. CFG_1.0:6:0
+	Types   []*Type
+}
+
+// Type is a configuration type (e.g. CAB_TASKS) with its instances
+type Type struct {
+	Name      string
+	Instances []Instance
+}
+
+// Instance is one record of a type, made of "-Attribute value" pairs
+type Instance []Attr
+
+// Attr is a single attribute. Flags without a value have an empty Value.
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Parse reads a configuration file. Lines ending in a backslash continue on
+// the next line and instances are separated by blank lines.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var current *Type
+	var record strings.Builder
+	flush := func() error {
+		text := strings.TrimSpace(record.String())
+		record.Reset()
+		if text == "" {
+			return nil
+		}
+		if current == nil {
+			return fmt.Errorf("instance outside of a type: %.40s", text)
+		}
+		inst, err := parseInstance(text)
+		if err != nil {
+			return err
+		}
+		current.Instances = append(current.Instances, inst)
+		return nil
+	}
+
+	first := true
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		trimmed := strings.TrimSpace(strings.TrimPrefix(line, "\uFEFF"))
+		if first {
+			first = false
+			domain, version, ok := strings.Cut(trimmed, ":")
+			if !ok || !strings.HasPrefix(version, "CFG_") {
+				return nil, fmt.Errorf("not a controller configuration file (missing DOMAIN:CFG_ header)")
+			}
+			f.Domain, f.Version = domain, strings.TrimRight(version, ":")
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(trimmed, "\\"):
+			record.WriteString(strings.TrimSuffix(trimmed, "\\") + " ")
+		case trimmed == "" || trimmed == "#":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case record.Len() == 0 && !strings.HasPrefix(trimmed, "-") && strings.HasSuffix(trimmed, ":"):
+			current = &Type{Name: strings.TrimSuffix(trimmed, ":")}
+			f.Types = append(f.Types, current)
+		default:
+			record.WriteString(trimmed + " ")
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseInstance splits "-Name "T_ROB1" -Type "NORMAL" -MotionTask" into attributes
+func parseInstance(text string) (Instance, error) {
+	var inst Instance
+	for len(text) > 0 {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			break
+		}
+		if text[0] != '-' {
+			return nil, fmt.Errorf("expected attribute at %.40q", text)
+		}
+		end := strings.IndexAny(text, " \t")
+		if end < 0 {
+			inst = append(inst, Attr{Name: text[1:]})
+			break
+		}
+		attr := Attr{Name: text[1:end]}
+		text = strings.TrimSpace(text[end:])
+		switch {
+		case strings.HasPrefix(text, `"`):
+			closing := strings.Index(text[1:], `"`)
+			if closing < 0 {
+				return nil, fmt.Errorf("unterminated string in attribute -%s", attr.Name)
+			}
+			attr.Value = text[1 : closing+1]
+			text = text[closing+2:]
+		case text == "" || (text[0] == '-' && !isNumber(text[1:])):
+			// Flag attribute without a value
+		default:
+			end = strings.IndexAny(text, " \t")
+			if end < 0 {
+				end = len(text)
+			}
+			attr.Value = text[:end]
+			text = text[end:]
+		}
+		inst = append(inst, attr)
+	}
+	return inst, nil
+}
+
+// Type returns the type with the given name, or nil
+func (f *File) Type(name string) *Type {
+	for _, t := range f.Types {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Get returns an attribute value, matching the name case-insensitively
+func (inst Instance) Get(name string) (string, bool) {
+	for _, a := range inst {
+		if strings.EqualFold(a.Name, name) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Name returns the attribute identifying the instance within its type: -Name,
+// or -ModName and -File for task modules that have no name
+func (inst Instance) Name() string {
+	for _, attr := range []string{"Name", "ModName", "File"} {
+		if name, ok := inst.Get(attr); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// isNumber reports whether text starts with a digit or decimal point, which
+// tells a negative value such as "-2.9" from the next attribute
+func isNumber(text string) bool {
+	return text != "" && (text[0] >= '0' && text[0] <= '9' || text[0] == '.')
+}
+
+// String formats the instance as "-Name value ..." on one line
+func (inst Instance) String() string {
+	parts := make([]string, len(inst))
+	for i, a := range inst {
+		parts[i] = formatAttr(a)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatAttr(a Attr) string {
+	if a.Value == "" {
+		return "-" + a.Name
+	}
+	return fmt.Sprintf("-%s %s", a.Name, a.Value)
+}
@@ -0,0 +1,107 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change describes one difference between two configurations
+type Change struct {
+	Type     string
+	Instance string // Instance name, or "#n" for unnamed instances
+	Kind     string // "added", "removed" or "changed"
+	Detail   string // For changes, the attributes that differ
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Type, c.Instance, c.Kind)
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+// Diff compares two versions of the same domain. Instances are matched by
+// their name (see Instance.Name), or by position when they have none.
+func Diff(before, after *File) []Change {
+	var changes []Change
+	seen := make(map[string]bool)
+	for _, t := range after.Types {
+		seen[strings.ToUpper(t.Name)] = true
+		changes = append(changes, diffType(t.Name, before.Type(t.Name), t)...)
+	}
+	for _, t := range before.Types {
+		if !seen[strings.ToUpper(t.Name)] {
+			changes = append(changes, diffType(t.Name, t, nil)...)
+		}
+	}
+	return changes
+}
+
+func instanceKeys(t *Type) ([]string, map[string]Instance) {
+	byKey := make(map[string]Instance)
+	var keys []string
+	if t == nil {
+		return keys, byKey
+	}
+	for i, inst := range t.Instances {
+		key := inst.Name()
+		if key == "" {
+			key = fmt.Sprintf("#%d", i+1)
+		}
+		keys = append(keys, key)
+		byKey[key] = inst
+	}
+	return keys, byKey
+}
+
+func diffType(name string, before, after *Type) []Change {
+	oldKeys, oldByKey := instanceKeys(before)
+	newKeys, newByKey := instanceKeys(after)
+
+	var changes []Change
+	for _, key := range newKeys {
+		inst := newByKey[key]
+		old, existed := oldByKey[key]
+		if !existed {
+			changes = append(changes, Change{Type: name, Instance: key, Kind: "added", Detail: inst.String()})
+			continue
+		}
+		if detail := diffAttrs(old, inst); detail != "" {
+			changes = append(changes, Change{Type: name, Instance: key, Kind: "changed", Detail: detail})
+		}
+	}
+	for _, key := range oldKeys {
+		if _, kept := newByKey[key]; !kept {
+			changes = append(changes, Change{Type: name, Instance: key, Kind: "removed"})
+		}
+	}
+	return changes
+}
+
+// diffAttrs lists attribute changes as "-attr old -> new"
+func diffAttrs(before, after Instance) string {
+	var parts []string
+	for _, a := range after {
+		old, ok := before.Get(a.Name)
+		switch {
+		case !ok:
+			parts = append(parts, "+"+formatAttr(a))
+		case old != a.Value:
+			parts = append(parts, fmt.Sprintf("-%s %s -> %s", a.Name, valueOrFlag(old), valueOrFlag(a.Value)))
+		}
+	}
+	for _, a := range before {
+		if _, ok := after.Get(a.Name); !ok {
+			parts = append(parts, "removed "+formatAttr(a))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func valueOrFlag(v string) string {
+	if v == "" {
+		return `""`
+	}
+	return v
+}
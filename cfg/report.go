@@ -0,0 +1,86 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// view selects the attributes worth showing for a well-known type
+type view struct {
+	title string
+	attrs []string
+}
+
+// views covers the types service engineers ask about most; other types are
+// only counted in reports
+var views = map[string]view{
+	"CAB_TASKS":        {"Tasks", []string{"Type", "MotionTask", "Entry", "TrustLevel", "UseMechanicalUnitGroup"}},
+	"CAB_TASK_MODULES": {"Task modules", []string{"File", "Task", "Shared", "AllTask", "Hidden"}},
+	"MECHANICAL_UNIT":  {"Mechanical units", []string{"use_robot", "use_single_0", "use_single_1", "activate_at_start_up", "deactivation_forbidden", "standby_state"}},
+	"ROBOT":            {"Robots", []string{"use_robot_type", "use_joint_0", "base_frame_pos_x", "base_frame_pos_y", "base_frame_pos_z", "base_frame_coordinated"}},
+	"SINGLE":           {"Single axes", []string{"use_single_type", "use_joint", "base_frame_pos_x", "base_frame_pos_y", "base_frame_pos_z"}},
+	"ARM":              {"Axis limits", []string{"upper_joint_bound", "lower_joint_bound", "independent_joint_on"}},
+	"MOTION_PLANNER":   {"Motion planners", []string{"speed_control_warning", "path_resolution", "std_servo_queue_time", "dynamic_resolution", "process_update_time"}},
+	"MOTION_SYSTEM":    {"Motion system", []string{"min_temp_cabinet", "max_temp_cabinet"}},
+	"EIO_SIGNAL":       {"I/O signals", []string{"SignalType", "Device", "DeviceMap", "Access"}},
+	"EIO_DEVICE":       {"I/O devices", []string{"Network", "VendorName", "ProductName", "Address"}},
+}
+
+// Report formats the domain in readable form: well-known types as tables
+// of their important attributes, the rest as instance counts
+func Report(f *File) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%s configuration (%s)\n", f.Domain, f.Version))
+	var others []string
+	for _, t := range f.Types {
+		v, known := views[strings.ToUpper(t.Name)]
+		if !known {
+			others = append(others, fmt.Sprintf("%s (%d)", t.Name, len(t.Instances)))
+			continue
+		}
+		result.WriteString(fmt.Sprintf("\n%s:\n", v.title))
+		for i, inst := range t.Instances {
+			name := inst.Name()
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			var fields []string
+			for _, attr := range v.attrs {
+				value, ok := inst.Get(attr)
+				switch {
+				case !ok:
+				case value == "":
+					fields = append(fields, attr)
+				default:
+					fields = append(fields, attr+"="+value)
+				}
+			}
+			result.WriteString(fmt.Sprintf("  %-16s %s\n", name, strings.Join(fields, "  ")))
+		}
+	}
+	if len(others) > 0 {
+		result.WriteString("\nOther types: " + strings.Join(others, ", ") + "\n")
+	}
+	return result.String()
+}
+
+// ParseBackInfo reads the BACKINFO/backinfo.txt of a controller backup into
+// its ">>SECTION:" blocks
+func ParseBackInfo(r io.Reader) (map[string][]string, error) {
+	sections := make(map[string][]string)
+	current := ""
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, ">>") {
+			current = strings.TrimSuffix(strings.TrimPrefix(line, ">>"), ":")
+			continue
+		}
+		if line != "" && current != "" {
+			sections[current] = append(sections[current], line)
+		}
+	}
+	return sections, sc.Err()
+}
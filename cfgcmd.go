@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/cfg"
+)
+
+func init() {
+	commandRegistry["cfg"] = Command{
+		Group:       groupReference,
+		Description: "Readable reports and diffs of controller configuration (SYS.cfg, MOC.cfg, ...)",
+		Usage:       cfgUsage,
+		Execute:     text(runCfg),
+	}
+}
+
+const cfgUsage = `Usage: cfg <show|diff> <file.cfg|backupdir> [other]
+  show <source>          - Report tasks, mechanical units, motion parameters and options
+  diff <before> <after>  - List configuration changes between two files or backups
+
+A backup directory is read from its SYSPAR folder, and installed products and
+options from BACKINFO/backinfo.txt.
+
+Examples:
+  cfg show ./Backups/2024-05-02/SYSPAR/MOC.cfg
+  cfg diff ./Backups/2024-01-10 ./Backups/2024-05-02`
+
+func runCfg(args []string) (string, error) {
+	if len(args) < 2 {
+		return cfgUsage, nil
+	}
+	switch args[0] {
+	case "show":
+		domains, info, err := loadConfig(args[1])
+		if err != nil {
+			return "", err
+		}
+		var result strings.Builder
+		for _, section := range optionSections(info) {
+			result.WriteString(fmt.Sprintf("\n%s:\n", section))
+			for _, line := range info[section] {
+				result.WriteString("  " + line + "\n")
+			}
+		}
+		for _, name := range sortedDomains(domains) {
+			result.WriteString("\n" + cfg.Report(domains[name]))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	case "diff":
+		if len(args) < 3 {
+			return "Usage: cfg diff <before> <after>", nil
+		}
+		before, _, err := loadConfig(args[1])
+		if err != nil {
+			return "", err
+		}
+		after, _, err := loadConfig(args[2])
+		if err != nil {
+			return "", err
+		}
+		return cfgDiff(before, after), nil
+
+	default:
+		return cfgUsage, nil
+	}
+}
+
+// loadConfig reads a single .cfg file or every .cfg file of a backup
+func loadConfig(source string) (map[string]*cfg.File, map[string][]string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []string{source}
+	var backInfo map[string][]string
+	if info.IsDir() {
+		dir := source
+		if _, err := os.Stat(filepath.Join(source, "SYSPAR")); err == nil {
+			dir = filepath.Join(source, "SYSPAR")
+		}
+		files, err = filepath.Glob(filepath.Join(dir, "*.cfg"))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no .cfg files in %s", dir)
+		}
+		if f, err := os.Open(filepath.Join(source, "BACKINFO", "backinfo.txt")); err == nil {
+			backInfo, err = cfg.ParseBackInfo(f)
+			f.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	domains := make(map[string]*cfg.File)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed, err := cfg.Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		domains[parsed.Domain] = parsed
+	}
+	return domains, backInfo, nil
+}
+
+// optionSections picks the backinfo sections listing products and options
+func optionSections(info map[string][]string) []string {
+	var sections []string
+	for name := range info {
+		upper := strings.ToUpper(name)
+		if strings.Contains(upper, "PRODUCT") || strings.Contains(upper, "OPTION") {
+			sections = append(sections, name)
+		}
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+func sortedDomains(domains map[string]*cfg.File) []string {
+	names := make([]string, 0, len(domains))
+	for name := range domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func cfgDiff(before, after map[string]*cfg.File) string {
+	var result strings.Builder
+	total := 0
+	names := sortedDomains(after)
+	for _, name := range sortedDomains(before) {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		a, b := before[name], after[name]
+		switch {
+		case a == nil:
+			result.WriteString(fmt.Sprintf("\n%s: domain added\n", name))
+			total++
+			continue
+		case b == nil:
+			result.WriteString(fmt.Sprintf("\n%s: domain removed\n", name))
+			total++
+			continue
+		}
+		changes := cfg.Diff(a, b)
+		if len(changes) == 0 {
+			continue
+		}
+		total += len(changes)
+		result.WriteString(fmt.Sprintf("\n%s:\n", name))
+		for _, c := range changes {
+			result.WriteString("  " + c.String() + "\n")
+		}
+	}
+	if total == 0 {
+		return "No configuration changes."
+	}
+	return fmt.Sprintf("%d configuration changes:\n%s", total, strings.TrimRight(result.String(), "\n"))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/polyfant/automation-helper-cli/history"
+	"github.com/polyfant/automation-helper-cli/result"
+)
+
+// changes collects the files the running command writes; execute records
+// them for undo, or shows them as a diff for --dry-run
+var changes *history.Changeset
+
+// noDryRun lists commands whose effects a dry run cannot preview, such as
+// settings, installs, servers and live connections; they refuse --dry-run
+// instead of running
+var noDryRun = map[string]bool{
+	"alarms": true, "approve": true, "bot": true, "config": true, "data": true, "db": true, "edit": true,
+	"grpc": true, "log": true, "pack": true, "redo": true, "secrets": true,
+	"serial": true, "serve": true, "snippet": true, "socket": true, "tour": true,
+	"undo": true, "update": true, "use": true, "vault": true, "vcs": true, "watch": true,
+}
+
+// dryRunFlag removes --dry-run from the arguments and reports whether it
+// was given
+func dryRunFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	dry := false
+	for _, a := range args {
+		if a == "--dry-run" {
+			dry = true
+			continue
+		}
+		rest = append(rest, a)
+	}
+	return rest, dry
+}
+
+// dryRun reports whether the running command should only show what it
+// would change
+func dryRun() bool {
+	return changes != nil && changes.DryRun
+}
+
+// writeFile writes a file like os.WriteFile, so undo can restore it and a
+// dry run can show it as a diff instead. Generated code takes the code style
+// and is stamped with its provenance.
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	data = stampProvenance(path, styleGenerated(path, data))
+	if changes == nil {
+		return os.WriteFile(path, data, perm)
+	}
+	return changes.WriteFile(path, data, perm)
+}
+
+// makeDir creates an output directory, except in a dry run
+func makeDir(dir string) error {
+	if dryRun() {
+		return nil
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// dryRunReport describes what a dry run would have written
+func dryRunReport(cs history.Changeset) []result.Block {
+	if len(cs.Changes) == 0 {
+		return []result.Block{result.Text{Body: "Dry run: no files would change."}}
+	}
+	files := "files"
+	if len(cs.Changes) == 1 {
+		files = "file"
+	}
+	return []result.Block{
+		result.Text{Body: fmt.Sprintf("Dry run: %d %s would change, nothing was written.", len(cs.Changes), files)},
+		result.Code{Lang: "diff", Source: cs.Diff()},
+	}
+}
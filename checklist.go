@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/pdf"
+)
+
+const checklistUsage = `Usage: generate checklist --robot <model> --interval <6m|1y|5000h>
+       [--hours-per-year 4000] [--output checklist.md|checklist.pdf]
+Builds a maintenance checklist with the lubrication, battery, brake test,
+inspection and controller tasks due at a service visit, from the robot model
+database. Hour-based tasks are converted with the operating hours per year.
+--output writes markdown, or PDF when the file name ends in .pdf.
+
+Examples:
+  generate checklist --robot irb6700 --interval 6m
+  generate checklist --robot irb6700-200 --interval 1y --output IRB6700_1y.pdf`
+
+// generateChecklist writes the maintenance checklist for a robot model
+func generateChecklist(args []string) (string, error) {
+	var robot, interval, output string
+	opts := generate.ChecklistOptions{HoursPerYear: 4000}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return checklistUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--robot":
+			robot = value
+		case "--interval":
+			interval = value
+		case "--hours-per-year":
+			hours, err := strconv.ParseFloat(value, 64)
+			if err != nil || hours <= 0 || hours > 8760 {
+				return "", fmt.Errorf("invalid operating hours per year %q", value)
+			}
+			opts.HoursPerYear = hours
+		case "--output":
+			output = value
+		default:
+			return checklistUsage, nil
+		}
+		i++
+	}
+	if robot == "" || interval == "" {
+		return checklistUsage, nil
+	}
+
+	r, ok := abb.RobotModel(robot)
+	if !ok {
+		// Variants of a family share their maintenance, so irb6700 is enough
+		variants := abb.RobotVariants(robot)
+		if len(variants) == 0 {
+			return "", fmt.Errorf("unknown robot %q. Available: %s", robot, strings.Join(abb.RobotKeys(), ", "))
+		}
+		r, _ = abb.RobotModel(variants[0])
+		for _, key := range variants[1:] {
+			v, _ := abb.RobotModel(key)
+			if strings.Join(v.Maintenance, ",") != strings.Join(r.Maintenance, ",") {
+				return "", fmt.Errorf("%q matches %s; choose a variant", robot, strings.Join(variants, ", "))
+			}
+		}
+		family, _, _ := strings.Cut(r.Model, "-")
+		r.Model = family + " (" + strings.Join(variants, ", ") + ")"
+	}
+	months, err := parseUnit(interval, map[string]float64{
+		"m": 1, "mo": 1, "months": 1, "y": 12, "years": 12, "h": 12 / opts.HoursPerYear,
+	})
+	if err != nil || months <= 0 {
+		return "", fmt.Errorf("invalid interval %q (use e.g. 6m, 1y or 5000h)", interval)
+	}
+	opts.Robot = r
+	opts.Interval = interval
+	opts.Months = months
+
+	checklist, err := generate.Checklist(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return strings.TrimRight(checklist, "\n"), nil
+	}
+	data := []byte(checklist)
+	if strings.EqualFold(filepath.Ext(output), ".pdf") {
+		data = pdf.Render(checklist)
+	}
+	if err := writeFile(output, data, 0644); err != nil {
+		return "", err
+	}
+	return "Wrote " + output, nil
+}
@@ -0,0 +1,181 @@
+// Package checksum computes the check values of common field protocols
+package checksum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// Algorithm is a checksum that calc checksum and framing offer
+type Algorithm struct {
+	Name        string
+	Description string
+	Width       int // Bytes
+	Compute     func([]byte) uint32
+	LowFirst    bool // Sent low byte first on the wire
+}
+
+// Algorithms lists the supported checksums
+var Algorithms = []Algorithm{
+	{"xor", "XOR of all bytes (BCC)", 1, func(d []byte) uint32 { return uint32(XOR(d)) }, false},
+	{"sum8", "Byte sum modulo 256", 1, func(d []byte) uint32 { return uint32(Sum8(d)) }, false},
+	{"lrc", "Two's complement of the byte sum (Modbus ASCII)", 1, func(d []byte) uint32 { return uint32(LRC(d)) }, false},
+	{"crc8", "CRC-8, polynomial 0x07", 1, func(d []byte) uint32 { return uint32(CRC8(d)) }, false},
+	{"crc16-modbus", "CRC-16/MODBUS, polynomial 0x8005 reflected, init 0xFFFF", 2, func(d []byte) uint32 { return uint32(CRC16Modbus(d)) }, true},
+	{"crc16-ccitt", "CRC-16/CCITT-FALSE, polynomial 0x1021, init 0xFFFF", 2, func(d []byte) uint32 { return uint32(CRC16CCITT(d, 0xffff)) }, false},
+	{"crc16-xmodem", "CRC-16/XMODEM, polynomial 0x1021, init 0", 2, func(d []byte) uint32 { return uint32(CRC16CCITT(d, 0)) }, false},
+	{"crc32", "CRC-32 (Ethernet, zip)", 4, func(d []byte) uint32 { return crc32.ChecksumIEEE(d) }, true},
+}
+
+// Find returns an algorithm by name
+func Find(name string) (Algorithm, bool) {
+	for _, a := range Algorithms {
+		if strings.EqualFold(a.Name, name) {
+			return a, true
+		}
+	}
+	return Algorithm{}, false
+}
+
+// Bytes returns a checksum value in wire order
+func (a Algorithm) Bytes(v uint32) []byte {
+	out := make([]byte, a.Width)
+	for i := range out {
+		shift := 8 * (a.Width - 1 - i)
+		if a.LowFirst {
+			shift = 8 * i
+		}
+		out[i] = byte(v >> shift)
+	}
+	return out
+}
+
+// CRC8 uses polynomial 0x07 with initial value 0
+func CRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRC16CCITT uses polynomial 0x1021, unreflected, with the given initial
+// value: 0xFFFF for CCITT-FALSE, 0 for XMODEM
+func CRC16CCITT(data []byte, init uint16) uint16 {
+	crc := init
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRC16Modbus is the CRC of Modbus RTU frames (polynomial 0xA001
+// reflected, initial value 0xFFFF), sent low byte first
+func CRC16Modbus(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// XOR is the exclusive or of all bytes (BCC)
+func XOR(data []byte) byte {
+	var x byte
+	for _, b := range data {
+		x ^= b
+	}
+	return x
+}
+
+// Sum8 is the sum of all bytes modulo 256
+func Sum8(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// LRC is the longitudinal redundancy check of Modbus ASCII: the two's
+// complement of the byte sum
+func LRC(data []byte) byte {
+	return -Sum8(data)
+}
+
+// ParseHex reads bytes written as "01 03 00 0A", "01,03", "0x01 0x03" or
+// "01030A"
+func ParseHex(s string) ([]byte, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == ':' || r == '\t' })
+	var out []byte
+	for _, f := range fields {
+		f = strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X")
+		if len(f)%2 != 0 {
+			f = "0" + f
+		}
+		b, err := hex.DecodeString(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %q", f)
+		}
+		out = append(out, b...)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no bytes")
+	}
+	return out, nil
+}
+
+// Frame describes the framing of a message: start and end bytes around the
+// data, a check value after the end byte and a terminator
+type Frame struct {
+	Start      []byte // e.g. STX
+	End        []byte // e.g. ETX
+	Check      *Algorithm
+	CheckEnd   bool // The check covers the end byte too
+	CheckStart bool // The check covers the start byte too
+	CheckHex   bool // The check is sent as ASCII hex digits
+	Terminator []byte
+}
+
+// Build frames data and returns the frame and the check bytes
+func (f Frame) Build(data []byte) (frame, check []byte) {
+	frame = append(append(append([]byte{}, f.Start...), data...), f.End...)
+	if f.Check != nil {
+		covered := frame
+		if !f.CheckStart {
+			covered = covered[len(f.Start):]
+		}
+		if !f.CheckEnd {
+			covered = covered[:len(covered)-len(f.End)]
+		}
+		check = f.Check.Bytes(f.Check.Compute(covered))
+		if f.CheckHex {
+			check = []byte(strings.ToUpper(hex.EncodeToString(check)))
+		}
+		frame = append(frame, check...)
+	}
+	return append(frame, f.Terminator...), check
+}
@@ -0,0 +1,103 @@
+package checksum
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The check values are those of the CRC catalogue for "123456789"
+func TestAlgorithms(t *testing.T) {
+	check := []byte("123456789")
+	tests := []struct {
+		name string
+		data []byte
+		want uint32
+		wire []byte
+	}{
+		{"xor", check, 0x31, []byte{0x31}},
+		{"sum8", check, 0xdd, []byte{0xdd}},
+		{"lrc", check, 0x23, []byte{0x23}},
+		{"crc8", check, 0xf4, []byte{0xf4}},
+		{"crc16-modbus", check, 0x4b37, []byte{0x37, 0x4b}},
+		{"crc16-ccitt", check, 0x29b1, []byte{0x29, 0xb1}},
+		{"crc16-xmodem", check, 0x31c3, []byte{0x31, 0xc3}},
+		{"crc32", check, 0xcbf43926, []byte{0x26, 0x39, 0xf4, 0xcb}},
+		// Read holding registers 0-9 of unit 1, as sent on Modbus RTU
+		{"crc16-modbus", []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0a}, 0xcdc5, []byte{0xc5, 0xcd}},
+		// Modbus ASCII :010300000001FB
+		{"lrc", []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x01}, 0xfb, []byte{0xfb}},
+	}
+	for _, tt := range tests {
+		a, ok := Find(tt.name)
+		if !ok {
+			t.Fatalf("no algorithm %s", tt.name)
+		}
+		got := a.Compute(tt.data)
+		if got != tt.want {
+			t.Errorf("%s(% x) = %#x, want %#x", tt.name, tt.data, got, tt.want)
+		}
+		if wire := a.Bytes(got); !bytes.Equal(wire, tt.wire) {
+			t.Errorf("%s wire order % x, want % x", tt.name, wire, tt.wire)
+		}
+	}
+	if _, ok := Find("CRC16-Modbus"); !ok {
+		t.Error("Find is case sensitive")
+	}
+	if _, ok := Find("md5"); ok {
+		t.Error("found md5")
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []byte
+		err  bool
+	}{
+		{"01 03 00 0A", []byte{1, 3, 0, 10}, false},
+		{"01,03", []byte{1, 3}, false},
+		{"0x01 0X03", []byte{1, 3}, false},
+		{"01030A", []byte{1, 3, 10}, false},
+		{"1 2:f", []byte{1, 2, 15}, false},
+		{"", nil, true},
+		{"0G", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHex(tt.in)
+		if (err != nil) != tt.err || !bytes.Equal(got, tt.want) {
+			t.Errorf("ParseHex(%q) = % x, %v; want % x", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFrameBuild(t *testing.T) {
+	xor, _ := Find("xor")
+	modbus, _ := Find("crc16-modbus")
+	stx, etx := []byte{0x02}, []byte{0x03}
+	tests := []struct {
+		name  string
+		frame Frame
+		data  string
+		want  []byte
+		check []byte
+	}{
+		{"plain", Frame{Start: stx, End: etx, Terminator: []byte("\r\n")}, "AB",
+			[]byte{0x02, 'A', 'B', 0x03, '\r', '\n'}, nil},
+		{"check over the data", Frame{Start: stx, End: etx, Check: &xor}, "AB",
+			[]byte{0x02, 'A', 'B', 0x03, 0x03}, []byte{0x03}},
+		{"check over the end byte", Frame{Start: stx, End: etx, Check: &xor, CheckEnd: true}, "AB",
+			[]byte{0x02, 'A', 'B', 0x03, 0x00}, []byte{0x00}},
+		{"check over the whole frame", Frame{Start: stx, End: etx, Check: &xor, CheckStart: true, CheckEnd: true}, "AB",
+			[]byte{0x02, 'A', 'B', 0x03, 0x02}, []byte{0x02}},
+		{"check as hex", Frame{Check: &modbus, CheckHex: true}, "\x01\x03\x00\x00\x00\x0a",
+			[]byte("\x01\x03\x00\x00\x00\x0aC5CD"), []byte("C5CD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, check := tt.frame.Build([]byte(tt.data))
+			if !bytes.Equal(frame, tt.want) || !bytes.Equal(check, tt.check) {
+				t.Errorf("got % x check % x, want % x check % x", frame, check, tt.want, tt.check)
+			}
+		})
+	}
+}
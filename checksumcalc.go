@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/checksum"
+	"github.com/polyfant/automation-helper-cli/message"
+	"github.com/polyfant/automation-helper-cli/serial"
+)
+
+const checksumUsage = `Usage: calc checksum (--hex "01 03 00 00 00 02" | --text "HELLO\r") [--algo all]
+Computes checksums of a message for debugging serial and socket protocols.
+Algorithms: %s.
+--text accepts the escapes \r, \n, \t and \xHH.
+
+Examples:
+  calc checksum --algo crc16-modbus --hex "01 03 00 00 00 02"
+  calc checksum --text ":010300000002" --algo lrc`
+
+const frameUsage = `Usage: calc frame (--hex ... | --text ...) [--stx] [--etx] [--start hex] [--end hex]
+       [--check <algo>] [--check-hex] [--check-end] [--check-start]
+       [--terminator cr|lf|crlf|hex]
+Builds a framed message: start byte(s), data, end byte(s), check value and
+terminator, and shows it as hex, readable text and a RAPID string literal
+for SocketSend or WriteStrBin. The check covers the data, plus the end and
+start bytes with --check-end and --check-start; --check-hex sends it as
+ASCII hex digits.
+
+Example:
+  calc frame --text "JOB,12" --stx --etx --check xor --check-end --check-hex --terminator cr`
+
+// checksumInput decodes the value of --hex or --text
+func checksumInput(flag, value string) ([]byte, error) {
+	if flag == "--hex" {
+		return checksum.ParseHex(value)
+	}
+	return message.Unescape(value)
+}
+
+func algorithmNames() string {
+	var names []string
+	for _, a := range checksum.Algorithms {
+		names = append(names, a.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func calcChecksum(args []string) (string, error) {
+	usage := fmt.Sprintf(checksumUsage, algorithmNames())
+	var data []byte
+	algo := "all"
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return usage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--hex", "--text":
+			var err error
+			if data, err = checksumInput(args[i], value); err != nil {
+				return "", err
+			}
+		case "--algo":
+			algo = value
+		default:
+			return usage, nil
+		}
+		i++
+	}
+	if data == nil {
+		return usage, nil
+	}
+	algorithms := checksum.Algorithms
+	if algo != "all" {
+		a, ok := checksum.Find(algo)
+		if !ok {
+			return "", fmt.Errorf("unknown algorithm %q. Available: %s", algo, algorithmNames())
+		}
+		algorithms = []checksum.Algorithm{a}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Data: %d bytes  %s\n", len(data), serial.Hex(data))
+	fmt.Fprintf(&b, "      %s\n\n", serial.ASCII(data))
+	fmt.Fprintf(&b, "%-13s %-10s %-12s %s\n", "Algorithm", "Value", "Wire bytes", "Frame")
+	for _, a := range algorithms {
+		v := a.Compute(data)
+		wire := a.Bytes(v)
+		value := fmt.Sprintf("0x%0*X", 2*a.Width, v)
+		fmt.Fprintf(&b, "%-13s %-10s %-12s %s\n", a.Name, value, serial.Hex(wire), serial.Hex(append(append([]byte{}, data...), wire...)))
+	}
+	if len(algorithms) == 1 {
+		a := algorithms[0]
+		order := "high byte first"
+		if a.LowFirst {
+			order = "low byte first"
+		}
+		if a.Width > 1 {
+			fmt.Fprintf(&b, "\n%s: %s. Sent %s.", a.Name, a.Description, order)
+		} else {
+			fmt.Fprintf(&b, "\n%s: %s.", a.Name, a.Description)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func calcFrame(args []string) (string, error) {
+	var data []byte
+	var f checksum.Frame
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--stx":
+			f.Start = append(f.Start, 0x02)
+			continue
+		case "--etx":
+			f.End = append(f.End, 0x03)
+			continue
+		case "--check-hex":
+			f.CheckHex = true
+			continue
+		case "--check-end":
+			f.CheckEnd = true
+			continue
+		case "--check-start":
+			f.CheckStart = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return frameUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--hex", "--text":
+			data, err = checksumInput(args[i], value)
+		case "--start":
+			f.Start, err = checksum.ParseHex(value)
+		case "--end":
+			f.End, err = checksum.ParseHex(value)
+		case "--check":
+			a, ok := checksum.Find(value)
+			if !ok {
+				return "", fmt.Errorf("unknown algorithm %q. Available: %s", value, algorithmNames())
+			}
+			f.Check = &a
+		case "--terminator":
+			switch strings.ToLower(value) {
+			case "cr":
+				f.Terminator = []byte{'\r'}
+			case "lf":
+				f.Terminator = []byte{'\n'}
+			case "crlf":
+				f.Terminator = []byte{'\r', '\n'}
+			default:
+				f.Terminator, err = checksum.ParseHex(value)
+			}
+		default:
+			return frameUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if data == nil {
+		return frameUsage, nil
+	}
+
+	frame, check := f.Build(data)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Frame: %d bytes\n", len(frame))
+	fmt.Fprintf(&b, "  Hex:   %s\n", serial.Hex(frame))
+	fmt.Fprintf(&b, "  Text:  %s\n", serial.ASCII(frame))
+	fmt.Fprintf(&b, "  RAPID: %s\n", rapidStringLiteral(frame))
+	if f.Check != nil {
+		fmt.Fprintf(&b, "  Check: %s = %s", f.Check.Name, serial.Hex(check))
+	}
+	if len(frame) > 80 {
+		b.WriteString("\nWarning: RAPID strings hold at most 80 characters; send it as rawbytes")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// rapidStringLiteral writes bytes as a RAPID string with \hh escapes for
+// non-printable characters
+func rapidStringLiteral(data []byte) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range data {
+		switch {
+		case c == '"':
+			b.WriteString(`""`)
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\%02X`, c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+const collaborativeUsage = `Usage: calc collaborative --payload <kg> [--speed <mm/s>] [--robot <model> | --robot-mass <kg>] [--regions hand,lower_arm]
+
+Estimates the highest relative speed for transient contact with each body
+region in power and force limiting (ISO/TS 15066 Annex A), from the force
+limits, spring constants and effective masses of Tables A.2 and A.3.
+--speed marks the regions the programmed speed exceeds. The moving robot
+mass comes from --robot-mass or, conservatively, the robot weight of
+--robot; without either 20 kg is assumed.
+
+Examples:
+  calc collaborative --payload 5kg --speed 250mm/s
+  calc collaborative --payload 3kg --robot crb15000-5 --regions hand,lower_arm,upper_arm`
+
+// defaultMovingMass is the moving robot mass assumed without --robot or
+// --robot-mass, about a 10 kg class collaborative robot
+const defaultMovingMass = 20.0
+
+func calcCollaborative(args []string) (string, error) {
+	payload, speed, moving := -1.0, 0.0, 0.0
+	var robot, regionList string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return collaborativeUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--payload":
+			payload, err = parseUnit(value, map[string]float64{"kg": 1, "g": 0.001})
+		case "--speed":
+			speed, err = parseUnit(value, map[string]float64{"mm/s": 1, "m/s": 1000})
+		case "--robot-mass":
+			moving, err = parseUnit(value, map[string]float64{"kg": 1})
+		case "--robot":
+			robot = value
+		case "--regions":
+			regionList = value
+		default:
+			return collaborativeUsage, nil
+		}
+		if err != nil || strings.HasPrefix(strings.TrimSpace(value), "-") {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if payload < 0 {
+		return collaborativeUsage, nil
+	}
+
+	if moving == 0 && robot == "" {
+		robot = projectRobot()
+	}
+	var assumptions []string
+	switch {
+	case moving > 0:
+	case robot != "":
+		r, ok := abb.RobotModel(robot)
+		if !ok {
+			return "", fmt.Errorf("unknown robot %q. Available: %s", robot, strings.Join(abb.RobotKeys(), ", "))
+		}
+		moving = r.Weight
+		assumptions = append(assumptions, fmt.Sprintf("Robot weight of the %s (%g kg) used as moving mass; the real moving mass is lower, so the speeds are conservative", r.Model, r.Weight))
+	default:
+		moving = defaultMovingMass
+		assumptions = append(assumptions, fmt.Sprintf("Moving robot mass of %g kg assumed; pass --robot or --robot-mass", defaultMovingMass))
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range strings.Split(regionList, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := calc.FindBodyRegion(name); !ok {
+			var names []string
+			for _, r := range calc.BodyRegions {
+				names = append(names, r.Name)
+			}
+			return "", fmt.Errorf("unknown body region %q. Available: %s", name, strings.Join(names, ", "))
+		}
+		wanted[strings.ToLower(name)] = true
+	}
+
+	mass := calc.RobotEffectiveMass(moving, payload)
+	var result strings.Builder
+	fmt.Fprintf(&result, "Power and force limiting (ISO/TS 15066 Annex A)\n")
+	fmt.Fprintf(&result, "Effective robot mass: %.1f kg (moving mass %g kg / 2 + payload %g kg)\n", mass, moving, payload)
+	if speed > 0 {
+		fmt.Fprintf(&result, "Programmed speed: %g mm/s\n", speed)
+	}
+	fmt.Fprintf(&result, "\n%-11s %8s %9s %7s %7s %8s %9s", "Region", "F static", "F transnt", "k N/mm", "mH kg", "E J", "max mm/s")
+	if speed > 0 {
+		result.WriteString("  Check")
+	}
+	result.WriteString("\n")
+	exceeded := 0
+	for _, c := range calc.Collaborative(mass) {
+		r := c.Region
+		if len(wanted) > 0 && !wanted[r.Name] {
+			continue
+		}
+		if !r.Transient {
+			fmt.Fprintf(&result, "%-11s %7.0fN %9s %7g %7g %8s %9s", r.Name, r.Force, "-", r.Spring, r.Mass, "-", "-")
+			if speed > 0 {
+				result.WriteString("  avoid contact")
+			}
+			result.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&result, "%-11s %7.0fN %8.0fN %7g %7g %8.2f %9.0f", r.Name, r.Force, r.TransientForce(), r.Spring, r.Mass, r.Energy(), c.MaxSpeed)
+		if speed > 0 {
+			if speed > c.MaxSpeed {
+				exceeded++
+				result.WriteString("  EXCEEDED")
+			} else {
+				result.WriteString("  ok")
+			}
+		}
+		result.WriteString("\n")
+	}
+	if speed > 0 && exceeded > 0 {
+		fmt.Fprintf(&result, "\n%g mm/s exceeds the transient limit for %d region(s); reduce the speed where those regions can be reached.\n", speed, exceeded)
+	}
+
+	result.WriteString("\nNotes:\n")
+	for _, a := range assumptions {
+		result.WriteString("- " + a + "\n")
+	}
+	result.WriteString("- Head regions (skull, face, neck): no transient contact permitted; design it out\n")
+	result.WriteString("- Quasi-static contact (clamping) must stay below F static; pressure limits (Table A.2) are not checked\n")
+	result.WriteString("- Estimates for the risk assessment; measure forces and pressures with the actual tool")
+	return result.String(), nil
+}
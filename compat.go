@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const compatUsage = `Usage: abb compat <dir|file.mod>... --target <rw5|rw6|rw7> [--from rw6]
+       abb compat <instruction>
+Checks RAPID modules before moving them to another RobotWare version or
+from IRC5 to OmniCore (rw7): instructions that no longer exist, deprecated
+ones, behavior changes between --from and --target, and the RobotWare
+options the program needs on the new controller. With an instruction name
+it shows the known differences of that instruction.
+
+The data covers the common migration pitfalls, not every release note;
+install a data pack with a "compat" section to add entries.
+
+Examples:
+  abb compat ./RAPID/TASK1 --target rw7
+  abb compat Main.mod --from 5.15 --target 6.14
+  abb compat CamReqImage`
+
+// abbCompat checks modules against a RobotWare version or shows one instruction
+func abbCompat(args []string) (string, error) {
+	var target, from string
+	var sources []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--target", "--from":
+			if i+1 >= len(args) {
+				return compatUsage, nil
+			}
+			if args[i] == "--target" {
+				target = args[i+1]
+			} else {
+				from = args[i+1]
+			}
+			i++
+		default:
+			sources = append(sources, args[i])
+		}
+	}
+	p := activeProject()
+	if len(sources) == 0 && p != nil {
+		sources = p.SourceDirs()
+	}
+	if len(sources) == 0 {
+		return compatUsage, nil
+	}
+	if target == "" {
+		if len(sources) == 1 {
+			if _, err := os.Stat(sources[0]); err != nil {
+				if c, ok := abb.Compatibility(sources[0]); ok {
+					return formatCompat(c), nil
+				}
+				return fmt.Sprintf("No compatibility notes for %s; it behaves the same in RobotWare 5, 6 and 7 as far as known.", sources[0]), nil
+			}
+		}
+		if p == nil || p.Controller == "" {
+			return compatUsage, nil
+		}
+		target = p.Controller
+	}
+
+	to, err := abb.ParseVersion(target)
+	if err != nil {
+		return "", err
+	}
+	origin := abb.Version{Major: 6}
+	if from != "" {
+		if origin, err = abb.ParseVersion(from); err != nil {
+			return "", err
+		}
+	}
+	if !origin.Before(to) {
+		return "", fmt.Errorf("--target %s must be newer than --from %s", to, origin)
+	}
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+
+	index := abb.CompatIndex()
+	var result strings.Builder
+	blocking, warnings := 0, 0
+	options := make(map[string][]string)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		reported := make(map[string]bool)
+		for _, occ := range rapid.Occurrences(string(data)) {
+			c, ok := index[strings.ToUpper(occ.Name)]
+			if !ok {
+				continue
+			}
+			if c.Option != "" && !c.RemovedIn(to) && !containsFold(options[c.Option], c.Name) {
+				options[c.Option] = append(options[c.Option], c.Name)
+			}
+			switch {
+			case c.RemovedIn(to):
+				blocking++
+				fmt.Fprintf(&result, "%s:%d: %s is not available in RobotWare %s%s\n", path, occ.Line, c.Name, to, compatHint(c))
+				continue
+			case c.DeprecatedIn(to) && !reported[c.Name]:
+				warnings++
+				fmt.Fprintf(&result, "%s:%d: %s is deprecated since RobotWare %s%s\n", path, occ.Line, c.Name, c.Deprecated, compatHint(c))
+			}
+			// Behavior changes are reported at the first use in each file
+			if reported[c.Name] {
+				continue
+			}
+			reported[c.Name] = true
+			for _, ch := range c.ChangesBetween(origin, to) {
+				warnings++
+				fmt.Fprintf(&result, "%s:%d: %s changed in RobotWare %s: %s\n", path, occ.Line, c.Name, ch.Version, ch.Note)
+			}
+		}
+	}
+
+	if len(options) > 0 {
+		result.WriteString("\nRobotWare options used (order them for the new controller):\n")
+		names := make([]string, 0, len(options))
+		for option := range options {
+			names = append(names, option)
+		}
+		sort.Strings(names)
+		for _, option := range names {
+			fmt.Fprintf(&result, "  %-40s %s\n", option, strings.Join(options[option], ", "))
+		}
+	}
+	summary := fmt.Sprintf("%d files checked for RobotWare %s to %s: %d not available, %d to review", len(files), origin, to, blocking, warnings)
+	if blocking > 0 {
+		return "", fmt.Errorf("%s\n%s", summary, strings.TrimRight(result.String(), "\n"))
+	}
+	if result.Len() == 0 {
+		return summary + "; no known differences", nil
+	}
+	return summary + "\n" + strings.TrimRight(result.String(), "\n"), nil
+}
+
+// compatHint adds the replacement and note of an instruction to a finding
+func compatHint(c abb.Compat) string {
+	hint := ""
+	if c.Replacement != "" {
+		hint += "; use " + c.Replacement
+	}
+	if c.Note != "" {
+		hint += ". " + c.Note
+	}
+	return hint
+}
+
+// formatCompat shows the known differences of one instruction
+func formatCompat(c abb.Compat) string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s:\n", c.Name)
+	if c.Option != "" {
+		fmt.Fprintf(&result, "  Option:      %s\n", c.Option)
+	}
+	if c.Deprecated != "" {
+		fmt.Fprintf(&result, "  Deprecated:  RobotWare %s\n", c.Deprecated)
+	}
+	if c.Removed != "" {
+		fmt.Fprintf(&result, "  Removed:     RobotWare %s\n", c.Removed)
+	}
+	if c.Replacement != "" {
+		fmt.Fprintf(&result, "  Replacement: %s\n", c.Replacement)
+	}
+	for _, ch := range c.Changes {
+		fmt.Fprintf(&result, "  Changed in %s: %s\n", ch.Version, ch.Note)
+	}
+	if c.Note != "" {
+		fmt.Fprintf(&result, "  %s\n", c.Note)
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
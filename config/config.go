@@ -0,0 +1,48 @@
+// Package config locates the user data directory and stores user settings
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// HomeEnv overrides the data directory location when set
+const HomeEnv = "AUTOMATION_HELPER_HOME"
+
+// DataDir returns the directory where snippets, settings and data packs are stored
+func DataDir() (string, error) {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "automation-helper"), nil
+}
+
+// Path returns a path inside the data directory, creating the parent directories
+func Path(elem ...string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Dir returns a directory inside the data directory, creating it if needed
+func Dir(elem ...string) (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
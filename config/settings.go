@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Settings holds user preferences stored in config.json in the data directory
+type Settings struct {
+	Language string `json:"language,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Cell     string `json:"cell,omitempty"`
+	// Profile tailors help and confirmations to a role such as maintenance
+	Profile string `json:"profile,omitempty"`
+	// Project is the directory of the active project selected with use
+	Project string `json:"project,omitempty"`
+	// Storage is where the projects keep snapshots, backups and job logs;
+	// the project directory when empty
+	Storage string `json:"storage,omitempty"`
+	// AIProvider, AIModel and AIEndpoint select the language model the ai
+	// command asks; OpenAI's GPT-4 when empty
+	AIProvider string `json:"ai_provider,omitempty"`
+	AIModel    string `json:"ai_model,omitempty"`
+	AIEndpoint string `json:"ai_endpoint,omitempty"`
+	// AISession names the AI conversation ai continue adds to
+	AISession string `json:"ai_session,omitempty"`
+	// Tour lists the tour steps the user has completed
+	Tour []string `json:"tour,omitempty"`
+}
+
+// settingsFile is the name of the settings file inside the data directory
+const settingsFile = "config.json"
+
+// LoadSettings reads the settings file; a missing file yields the defaults
+func LoadSettings() (Settings, error) {
+	var s Settings
+	path, err := Path(settingsFile)
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// SaveSettings writes the settings file
+func SaveSettings(s Settings) error {
+	path, err := Path(settingsFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// settingFields maps setting keys to accessors on Settings
+var settingFields = map[string]func(*Settings) *string{
+	"language":    func(s *Settings) *string { return &s.Language },
+	"author":      func(s *Settings) *string { return &s.Author },
+	"cell":        func(s *Settings) *string { return &s.Cell },
+	"profile":     func(s *Settings) *string { return &s.Profile },
+	"storage":     func(s *Settings) *string { return &s.Storage },
+	"ai_provider": func(s *Settings) *string { return &s.AIProvider },
+	"ai_model":    func(s *Settings) *string { return &s.AIModel },
+	"ai_endpoint": func(s *Settings) *string { return &s.AIEndpoint },
+}
+
+// Keys returns the names of all settings in sorted order
+func Keys() []string {
+	keys := make([]string, 0, len(settingFields))
+	for k := range settingFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of a setting by key
+func (s *Settings) Get(key string) (string, error) {
+	field, ok := settingFields[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting %q", key)
+	}
+	return *field(s), nil
+}
+
+// Set changes a setting by key
+func (s *Settings) Set(key, value string) error {
+	field, ok := settingFields[key]
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	*field(s) = value
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+func init() {
+	commandRegistry["dashboard"] = Command{
+		Group:       groupIntegration,
+		Description: "Show live I/O and controller state in the terminal",
+		Usage:       dashboardUsage,
+		Execute:     text(runDashboard),
+	}
+}
+
+const dashboardUsage = `Usage: dashboard --signals a,b,... [--host localhost] [--rate 2hz] [--history 40]
+       [--user "Default User"] [--password robotics]
+Shows the operating mode, motor and execution state of the controller and
+the selected signals as a table that updates in place, with min/max and a
+sparkline of the last --history samples per signal. Reads over Robot Web
+Services (RobotWare 6). Press Ctrl+C to stop.
+
+Example:
+  dashboard --host 192.168.125.1 --signals diPartPresent,doGripClosed,aiPressure`
+
+// sparkBlocks are the bar heights of a sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func runDashboard(args []string) (string, error) {
+	host := "localhost"
+	var user, password string
+	interval, history := 500*time.Millisecond, 40
+	var signals []string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return dashboardUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--signals":
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					signals = append(signals, s)
+				}
+			}
+		case "--rate":
+			d, err := parseRate(value)
+			if err != nil || d < 50*time.Millisecond {
+				return "", fmt.Errorf("invalid value %q for --rate; at most 20hz", value)
+			}
+			interval = d
+		case "--history":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 2 || n > 200 {
+				return "", fmt.Errorf("invalid value %q for --history (2 to 200)", value)
+			}
+			history = n
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		default:
+			return dashboardUsage, nil
+		}
+		i++
+	}
+	if len(signals) == 0 {
+		return dashboardUsage, nil
+	}
+
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	if _, err := c.OperationMode(); err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", c.Base, err)
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	rows := make([]dashboardRow, len(signals))
+	for i, s := range signals {
+		rows[i] = dashboardRow{signal: s, min: math.Inf(1), max: math.Inf(-1)}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Hide the cursor while redrawing, show it again on exit
+	fmt.Print("\033[?25l")
+	defer fmt.Print("\033[?25h")
+	for {
+		state := dashboardState(c)
+		for i := range rows {
+			rows[i].sample(c, history)
+		}
+		fmt.Print("\033[H\033[2J" + renderDashboard(c.Base, state, rows))
+		select {
+		case <-interrupt:
+			return "\nStopped the dashboard", nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type dashboardRow struct {
+	signal   string
+	value    string
+	err      string
+	min, max float64
+	history  []float64
+}
+
+func (r *dashboardRow) sample(c *rws.Client, history int) {
+	text, err := c.ReadSignal(r.signal)
+	if err != nil {
+		r.err = err.Error()
+		return
+	}
+	r.value, r.err = text, ""
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return
+	}
+	r.min, r.max = math.Min(r.min, v), math.Max(r.max, v)
+	r.history = append(r.history, v)
+	if len(r.history) > history {
+		r.history = r.history[len(r.history)-history:]
+	}
+}
+
+// dashboardState is the header line with mode, motors and execution
+func dashboardState(c *rws.Client) string {
+	var parts []string
+	for _, read := range []struct {
+		label string
+		get   func() (string, error)
+	}{
+		{"Mode", c.OperationMode},
+		{"Motors", c.ControllerState},
+		{"Program", c.ExecutionState},
+	} {
+		v, err := read.get()
+		if err != nil {
+			v = "?"
+		}
+		parts = append(parts, read.label+": "+v)
+	}
+	return strings.Join(parts, "   ")
+}
+
+func renderDashboard(base, state string, rows []dashboardRow) string {
+	width := len("Signal")
+	for _, r := range rows {
+		width = max(width, len(r.signal))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s   %s   %s\n%s\n\n", base, time.Now().Format("15:04:05"), "Ctrl+C to stop", state)
+	fmt.Fprintf(&b, "%-*s  %10s  %10s  %10s  %s\n", width, "Signal", "Value", "Min", "Max", "History")
+	for _, r := range rows {
+		if r.err != "" {
+			fmt.Fprintf(&b, "%-*s  %10s  %s\n", width, r.signal, "-", r.err)
+			continue
+		}
+		lo, hi := "-", "-"
+		if len(r.history) > 0 {
+			lo, hi = strconv.FormatFloat(r.min, 'g', 6, 64), strconv.FormatFloat(r.max, 'g', 6, 64)
+		}
+		fmt.Fprintf(&b, "%-*s  %10s  %10s  %10s  %s\n", width, r.signal, r.value, lo, hi, sparkline(r.history))
+	}
+	return b.String()
+}
+
+// sparkline scales values between their own min and max
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	out := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1)))
+		}
+		out[i] = sparkBlocks[level]
+	}
+	return string(out)
+}
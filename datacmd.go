@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/datapack"
+)
+
+func init() {
+	commandRegistry["data"] = Command{
+		Group:       groupSystem,
+		Description: "Validate reference data entries and add them to the local pack",
+		Usage:       dataUsage,
+		Execute:     text(runData),
+	}
+}
+
+const dataUsage = `Usage:
+  data validate <entries.yaml>...
+  data add command <entries.yaml>...
+
+Entries are YAML documents separated by ---, each with the fields key,
+name, category, syntax, example and description. validate checks the
+required fields and wraps every example into a module to check its syntax.
+add validates the entries and installs them into the local data pack, where
+they extend or replace the built-in commands from the next start.
+
+Example entry:
+  key: search_c
+  name: SearchC
+  category: Motion
+  syntax: SearchC [\Stop], Signal, SearchPoint, CirPoint, ToPoint, Speed, Tool;
+  example: |
+    SearchC \Stop:=di_Contact, sp, cirPoint, p10, v100, tool1;
+  description: |
+    Circular search that stores the position where the signal changes.
+
+Examples:
+  data validate company_commands.yaml
+  data add command company_commands.yaml`
+
+func runData(args []string) (string, error) {
+	if len(args) < 1 {
+		return dataUsage, nil
+	}
+	switch args[0] {
+	case "validate":
+		if len(args) < 2 {
+			return dataUsage, nil
+		}
+		report, _, invalid := validateEntries(args[1:])
+		if invalid > 0 {
+			return report, fmt.Errorf("%d invalid entries", invalid)
+		}
+		return report, nil
+	case "add":
+		if len(args) < 3 || args[1] != "command" {
+			return dataUsage, nil
+		}
+		return dataAddCommands(args[2:])
+	default:
+		return dataUsage, nil
+	}
+}
+
+// validateEntries reads and checks the command entries of the files and
+// returns a report, the valid entries and the number of invalid ones
+func validateEntries(files []string) (string, []abb.CommandEntry, int) {
+	var result strings.Builder
+	var valid []abb.CommandEntry
+	invalid := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(&result, "Error: %v\n", err)
+			invalid++
+			continue
+		}
+		entries, err := abb.ParseCommandEntries(string(data))
+		if err != nil {
+			fmt.Fprintf(&result, "Error: %s: %v\n", file, err)
+			invalid++
+			continue
+		}
+		for _, e := range entries {
+			problems, notes := e.Validate()
+			if e.Command.Example != "" {
+				_, _, errs := verifyExample(e.Key, e.Command)
+				for _, msg := range errs {
+					problems = append(problems, "example: "+msg)
+				}
+			}
+			status := "OK  "
+			if len(problems) > 0 {
+				status = "FAIL"
+				invalid++
+			} else {
+				valid = append(valid, e)
+			}
+			fmt.Fprintf(&result, "%s %s (%s:%d)\n", status, e.Key, file, e.Line)
+			for _, p := range problems {
+				fmt.Fprintf(&result, "     %s\n", p)
+			}
+			for _, n := range notes {
+				fmt.Fprintf(&result, "     note: %s\n", n)
+			}
+		}
+	}
+	fmt.Fprintf(&result, "%d valid, %d invalid", len(valid), invalid)
+	return result.String(), valid, invalid
+}
+
+func dataAddCommands(files []string) (string, error) {
+	report, entries, invalid := validateEntries(files)
+	if invalid > 0 {
+		return report, errors.New("nothing installed; fix the invalid entries first")
+	}
+	if len(entries) == 0 {
+		return report, errors.New("no entries found")
+	}
+
+	pack := &abb.Pack{Vendor: "abb"}
+	installed, ok, err := datapack.Find(abb.LocalPack)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		if pack, err = abb.ReadPack(installed); err != nil {
+			return "", fmt.Errorf("reading the %s pack: %v", abb.LocalPack, err)
+		}
+	}
+	if pack.Commands == nil {
+		pack.Commands = make(map[string]abb.ABBCommand)
+	}
+	for _, e := range entries {
+		pack.Commands[e.Key] = e.Command
+	}
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	p, err := datapack.Install(abb.LocalPack, time.Now().Format("2006.01.02-150405"), ".json", hex.EncodeToString(sum[:]), data)
+	if err != nil {
+		return "", err
+	}
+	path, _ := datapack.Path(p)
+	return fmt.Sprintf("%s\nAdded %d command(s) to the %s pack (%s, %d commands). They are available from the next start.",
+		report, len(entries), abb.LocalPack, path, len(pack.Commands)), nil
+}
@@ -0,0 +1,178 @@
+// Package datalog writes recorded signal values to CSV or Parquet files,
+// rotating to a new file by time or size for long captures
+package datalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writer stores samples: a timestamp and one value per column. NaN marks a
+// value that could not be read.
+type Writer interface {
+	Write(t time.Time, values []float64) error
+	// Size is the number of bytes written so far, used for rotation
+	Size() int64
+	Close() error
+}
+
+// Create opens a writer for the format given by the extension of path:
+// .csv or .parquet
+func Create(path string, columns []string) (Writer, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return newCSV(path, columns)
+	case ".parquet":
+		return newParquet(path, columns)
+	}
+	return nil, fmt.Errorf("unsupported log format %q; use .csv or .parquet", filepath.Ext(path))
+}
+
+type csvWriter struct {
+	f    *os.File
+	w    *csv.Writer
+	size int64
+}
+
+func newCSV(path string, columns []string) (*csvWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &csvWriter{f: f, w: csv.NewWriter(f)}
+	if err := c.record(append([]string{"time"}, columns...)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(t time.Time, values []float64) error {
+	rec := []string{t.Format("2006-01-02T15:04:05.000Z07:00")}
+	for _, v := range values {
+		if math.IsNaN(v) {
+			rec = append(rec, "")
+			continue
+		}
+		rec = append(rec, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return c.record(rec)
+}
+
+func (c *csvWriter) record(rec []string) error {
+	for _, field := range rec {
+		c.size += int64(len(field)) + 1
+	}
+	if err := c.w.Write(rec); err != nil {
+		return err
+	}
+	// Flush every row, so a capture stopped by a crash or Ctrl+C is complete
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Size() int64 { return c.size }
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
+
+// Rotation starts a new file when the current one is older than Every or
+// larger than MaxBytes; zero disables either limit
+type Rotation struct {
+	Every    time.Duration
+	MaxBytes int64
+}
+
+// Rotating writes to numbered files run1_001.csv, run1_002.csv and so on
+type Rotating struct {
+	Path     string
+	Columns  []string
+	Rotation Rotation
+	Files    []string // Files written so far
+
+	current Writer
+	opened  time.Time
+}
+
+func (r *Rotating) Write(t time.Time, values []float64) error {
+	if r.current != nil && r.due(t) {
+		if err := r.current.Close(); err != nil {
+			return err
+		}
+		r.current = nil
+	}
+	if r.current == nil {
+		path := r.Path
+		if r.Rotation != (Rotation{}) {
+			ext := filepath.Ext(path)
+			path = fmt.Sprintf("%s_%03d%s", strings.TrimSuffix(path, ext), len(r.Files)+1, ext)
+		}
+		w, err := Create(path, r.Columns)
+		if err != nil {
+			return err
+		}
+		r.current, r.opened = w, t
+		r.Files = append(r.Files, path)
+	}
+	return r.current.Write(t, values)
+}
+
+func (r *Rotating) due(t time.Time) bool {
+	if r.Rotation.Every > 0 && t.Sub(r.opened) >= r.Rotation.Every {
+		return true
+	}
+	return r.Rotation.MaxBytes > 0 && r.current.Size() >= r.Rotation.MaxBytes
+}
+
+// Size is the size of the current file
+func (r *Rotating) Size() int64 {
+	if r.current == nil {
+		return 0
+	}
+	return r.current.Size()
+}
+
+// Close closes the current file
+func (r *Rotating) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// ParseRotation reads a rotation limit: a duration such as 30m or 1h, or a
+// size such as 50MB
+func ParseRotation(s string) (Rotation, error) {
+	upper := strings.ToUpper(s)
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(upper[:len(upper)-2]), 64)
+			if err != nil || n <= 0 {
+				return Rotation{}, fmt.Errorf("invalid size %q", s)
+			}
+			return Rotation{MaxBytes: int64(n * float64(unit.bytes))}, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return Rotation{}, fmt.Errorf("invalid rotation %q; use a duration such as 1h or a size such as 50MB", s)
+	}
+	return Rotation{Every: d}, nil
+}
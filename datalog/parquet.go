@@ -0,0 +1,282 @@
+package datalog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"time"
+)
+
+// rowGroupSize is the number of samples buffered per Parquet row group
+const rowGroupSize = 10000
+
+// Parquet enum values used by the writer
+const (
+	parquetInt64           = 2
+	parquetDouble          = 5
+	parquetRequired        = 0
+	parquetPlain           = 0
+	parquetRLE             = 3
+	parquetUncompressed    = 0
+	parquetDataPage        = 0
+	parquetTimestampMillis = 9
+)
+
+// parquetWriter writes an uncompressed Parquet file: a time column as
+// TIMESTAMP_MILLIS and one DOUBLE column per signal, PLAIN encoded, in row
+// groups of rowGroupSize samples. The footer is written on Close, so a file
+// of an interrupted capture without Close is unreadable.
+type parquetWriter struct {
+	f       *os.File
+	columns []string
+	offset  int64
+	groups  []rowGroup
+	times   []int64
+	values  [][]float64
+	rows    int64
+}
+
+type rowGroup struct {
+	rows   int64
+	chunks []columnChunk
+}
+
+type columnChunk struct {
+	offset int64 // Of the data page header
+	size   int64 // Page header and data
+	values int64
+}
+
+func newParquet(path string, columns []string) (*parquetWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString("PAR1"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &parquetWriter{f: f, columns: columns, offset: 4, values: make([][]float64, len(columns))}, nil
+}
+
+func (p *parquetWriter) Write(t time.Time, values []float64) error {
+	p.times = append(p.times, t.UnixMilli())
+	for i := range p.columns {
+		v := math.NaN()
+		if i < len(values) {
+			v = values[i]
+		}
+		p.values[i] = append(p.values[i], v)
+	}
+	p.rows++
+	if len(p.times) >= rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// Size counts buffered samples too, so rotation by size works before the
+// first row group is flushed
+func (p *parquetWriter) Size() int64 {
+	return p.offset + int64(len(p.times))*int64(8*(len(p.columns)+1))
+}
+
+func (p *parquetWriter) flush() error {
+	if len(p.times) == 0 {
+		return nil
+	}
+	g := rowGroup{rows: int64(len(p.times))}
+	data := make([]byte, 0, 8*len(p.times))
+	for _, t := range p.times {
+		data = binary.LittleEndian.AppendUint64(data, uint64(t))
+	}
+	pages := [][]byte{data}
+	for _, column := range p.values {
+		data := make([]byte, 0, 8*len(column))
+		for _, v := range column {
+			data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+		}
+		pages = append(pages, data)
+	}
+	for _, data := range pages {
+		var header thrift
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(p.times)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+		chunk := columnChunk{offset: p.offset, size: int64(header.Len() + len(data)), values: int64(len(p.times))}
+		if _, err := p.f.Write(append(header.Bytes(), data...)); err != nil {
+			return err
+		}
+		p.offset += chunk.size
+		g.chunks = append(g.chunks, chunk)
+	}
+	p.groups = append(p.groups, g)
+	p.times = p.times[:0]
+	for i := range p.values {
+		p.values[i] = p.values[i][:0]
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		p.f.Close()
+		return err
+	}
+	names := append([]string{"time"}, p.columns...)
+	types := func(i int) int32 {
+		if i == 0 {
+			return parquetInt64
+		}
+		return parquetDouble
+	}
+
+	var meta thrift
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(names)+1)
+	meta.element()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(names)))
+	meta.endStruct()
+	for i, name := range names {
+		meta.element()
+		meta.i32(1, types(i))
+		meta.i32(3, parquetRequired)
+		meta.binary(4, name)
+		if i == 0 {
+			meta.i32(6, parquetTimestampMillis)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, p.rows)
+	meta.list(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		meta.element()
+		var total int64
+		meta.list(1, thriftStruct, len(g.chunks))
+		for i, c := range g.chunks {
+			total += c.size
+			meta.element()
+			meta.i64(2, c.offset)
+			meta.beginStruct(3)
+			meta.i32(1, types(i))
+			meta.list(2, thriftI32, 1)
+			meta.varint(zigzag(parquetPlain))
+			meta.list(3, thriftBinary, 1)
+			meta.varint(uint64(len(names[i])))
+			meta.WriteString(names[i])
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, c.values)
+			meta.i64(6, c.size)
+			meta.i64(7, c.size)
+			meta.i64(9, c.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, total)
+		meta.i64(3, g.rows)
+		meta.endStruct()
+	}
+	meta.binary(6, "automation-helper-cli")
+	meta.stop()
+
+	footer := meta.Bytes()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, "PAR1"...)
+	if _, err := p.f.Write(footer); err != nil {
+		p.f.Close()
+		return err
+	}
+	return p.f.Close()
+}
+
+// thrift encodes the Thrift compact protocol, which Parquet uses for page
+// headers and the file footer
+type thrift struct {
+	bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+// Compact protocol type codes
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+func (t *thrift) field(id int16, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.WriteByte(kind)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thrift) varint(v uint64) {
+	t.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thrift) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// list writes a list header; the elements follow as plain values or, for
+// structs, each opened with element and closed with endStruct
+func (t *thrift) list(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.WriteByte(byte(size)<<4 | kind)
+		return
+	}
+	t.WriteByte(0xf0 | kind)
+	t.varint(uint64(size))
+}
+
+func (t *thrift) element() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thrift) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.element()
+}
+
+func (t *thrift) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thrift) stop() {
+	t.WriteByte(0)
+}
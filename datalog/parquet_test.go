@@ -0,0 +1,113 @@
+package datalog
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// TestParquetRoundTrip reads a written log back with an independent
+// Parquet reader, across more than one row group
+func TestParquetRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    int
+	}{
+		{"one row", []string{"speed"}, 1},
+		{"one group", []string{"speed", "torque", "DO_Gripper"}, 250},
+		{"several groups", []string{"speed", "torque"}, 2*rowGroupSize + 7},
+	}
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	value := func(row, col int) float64 {
+		if row%11 == 5 && col == 0 {
+			return math.NaN()
+		}
+		return float64(row)*0.5 - float64(col)*100
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run.parquet")
+			w, err := Create(path, tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for row := 0; row < tt.rows; row++ {
+				values := make([]float64, len(tt.columns))
+				for col := range values {
+					values[col] = value(row, col)
+				}
+				if err := w.Write(start.Add(time.Duration(row)*10*time.Millisecond), values); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := local.NewLocalFileReader(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			r, err := reader.NewParquetColumnReader(f, 1)
+			if err != nil {
+				t.Fatalf("reading the footer: %v", err)
+			}
+			if got := r.GetNumRows(); got != int64(tt.rows) {
+				t.Fatalf("rows: got %d, want %d", got, tt.rows)
+			}
+			if got, want := len(r.Footer.RowGroups), (tt.rows+rowGroupSize-1)/rowGroupSize; got != want {
+				t.Errorf("row groups: got %d, want %d", got, want)
+			}
+			// The reader renames the schema elements; the names as written
+			// are its external names
+			schema, names := r.Footer.Schema, r.SchemaHandler
+			if len(schema) != len(tt.columns)+2 {
+				t.Fatalf("schema has %d elements, want %d", len(schema), len(tt.columns)+2)
+			}
+			if names.GetExName(1) != "time" || schema[1].GetType() != parquet.Type_INT64 ||
+				schema[1].GetConvertedType() != parquet.ConvertedType_TIMESTAMP_MILLIS {
+				t.Errorf("time column: %v", schema[1])
+			}
+			for i, name := range tt.columns {
+				if e := schema[i+2]; names.GetExName(i+2) != name || e.GetType() != parquet.Type_DOUBLE {
+					t.Errorf("column %d: %v", i, e)
+				}
+			}
+
+			times, _, _, err := r.ReadColumnByIndex(0, int64(tt.rows))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(times) != tt.rows {
+				t.Fatalf("time column has %d values, want %d", len(times), tt.rows)
+			}
+			for row, v := range times {
+				if want := start.Add(time.Duration(row) * 10 * time.Millisecond).UnixMilli(); v != want {
+					t.Fatalf("time of row %d: got %v, want %d", row, v, want)
+				}
+			}
+			for col := range tt.columns {
+				values, _, _, err := r.ReadColumnByIndex(int64(col+1), int64(tt.rows))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(values) != tt.rows {
+					t.Fatalf("column %s has %d values, want %d", tt.columns[col], len(values), tt.rows)
+				}
+				for row, v := range values {
+					got, want := v.(float64), value(row, col)
+					if got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+						t.Fatalf("%s of row %d: got %v, want %v", tt.columns[col], row, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package datalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table is a recorded log read back, one row per sample
+type Table struct {
+	Columns []string
+	Times   []time.Time
+	Rows    [][]float64
+}
+
+// Column returns the index of a column by name, ignoring case, or -1
+func (t *Table) Column(name string) int {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReadCSV reads a log written by log record. The first column is the time,
+// either a timestamp or seconds; empty cells become NaN.
+func ReadCSV(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("no header: %v", err)
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("expected a time column and at least one signal")
+	}
+	t := &Table{Columns: header[1:]}
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		at, err := parseTime(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		row := make([]float64, len(t.Columns))
+		for i := range row {
+			row[i] = math.NaN()
+			if i+1 < len(rec) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(rec[i+1]), 64); err == nil {
+					row[i] = v
+				}
+			}
+		}
+		t.Times = append(t.Times, at)
+		t.Rows = append(t.Rows, row)
+	}
+	if len(t.Rows) == 0 {
+		return nil, fmt.Errorf("no samples")
+	}
+	return t, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, 0).Add(time.Duration(secs * float64(time.Second))), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.000", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,148 @@
+// Package datapack manages reference data packs installed in the user data directory
+package datapack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+// indexFile records the installed packs inside the packs directory
+const indexFile = "installed.json"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]*$`)
+
+// Pack describes an installed data pack
+type Pack struct {
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	File      string    `json:"file"`
+	SHA256    string    `json:"sha256"`
+	Installed time.Time `json:"installed"`
+}
+
+// Dir returns the directory holding installed packs
+func Dir() (string, error) {
+	return config.Dir("packs")
+}
+
+// Installed returns the installed packs sorted by name
+func Installed() ([]Pack, error) {
+	index, err := readIndex()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Pack, 0, len(index))
+	for _, p := range index {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Find returns the installed pack with the given name
+func Find(name string) (Pack, bool, error) {
+	index, err := readIndex()
+	if err != nil {
+		return Pack{}, false, err
+	}
+	p, ok := index[name]
+	return p, ok, nil
+}
+
+// Path returns the location of an installed pack's data file
+func Path(p Pack) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, p.File), nil
+}
+
+// Install stores pack data and records it in the index, replacing older versions
+func Install(name, version, ext, sha string, data []byte) (Pack, error) {
+	if !validName.MatchString(name) {
+		return Pack{}, fmt.Errorf("invalid pack name %q", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return Pack{}, err
+	}
+
+	p := Pack{Name: name, Version: version, File: name + ext, SHA256: sha, Installed: time.Now()}
+	tmp := filepath.Join(dir, p.File+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return Pack{}, err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, p.File)); err != nil {
+		return Pack{}, err
+	}
+
+	index, err := readIndex()
+	if err != nil {
+		return Pack{}, err
+	}
+	if old, ok := index[name]; ok && old.File != p.File {
+		os.Remove(filepath.Join(dir, old.File))
+	}
+	index[name] = p
+	return p, writeIndex(index)
+}
+
+// Remove deletes an installed pack
+func Remove(name string) error {
+	index, err := readIndex()
+	if err != nil {
+		return err
+	}
+	p, ok := index[name]
+	if !ok {
+		return fmt.Errorf("pack %q is not installed", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, p.File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(index, name)
+	return writeIndex(index)
+}
+
+func readIndex() (map[string]Pack, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]Pack)
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("corrupt pack index: %v", err)
+	}
+	return index, nil
+}
+
+func writeIndex(index map[string]Pack) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, indexFile), data, 0o644)
+}
@@ -0,0 +1,106 @@
+// Package db opens the SQLite database in the data directory that keeps the
+// snippet library, the undo history, the AI conversations and the record of
+// the commands run
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// File is the name of the database inside the data directory
+const File = "automation-helper.db"
+
+// schema creates the tables; imports records which files of earlier
+// versions were read into the database
+const schema = `
+CREATE TABLE IF NOT EXISTS snippets (
+	name     TEXT PRIMARY KEY,
+	ext      TEXT NOT NULL,
+	code     TEXT NOT NULL,
+	modified TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS changesets (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	stack   TEXT NOT NULL,
+	command TEXT NOT NULL,
+	time    TEXT NOT NULL,
+	changes TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS usage (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	command  TEXT NOT NULL,
+	time     TEXT NOT NULL,
+	failed   INTEGER NOT NULL,
+	duration INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS conversations (
+	name     TEXT PRIMARY KEY,
+	created  TEXT NOT NULL,
+	updated  TEXT NOT NULL,
+	messages TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS imports (
+	name TEXT PRIMARY KEY,
+	time TEXT NOT NULL
+);`
+
+var (
+	once   sync.Once
+	handle *sql.DB
+	err    error
+)
+
+// Open returns the database, creating it and its tables on first use
+func Open() (*sql.DB, error) {
+	once.Do(func() {
+		var path string
+		if path, err = config.Path(File); err != nil {
+			return
+		}
+		if handle, err = sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"); err != nil {
+			return
+		}
+		if _, err = handle.Exec(schema); err != nil {
+			err = fmt.Errorf("%s: %v", path, err)
+		}
+	})
+	return handle, err
+}
+
+// Path returns the location of the database file
+func Path() (string, error) {
+	return config.Path(File)
+}
+
+// ImportOnce runs fn in a transaction unless an import of that name ran
+// before, so the files of earlier versions are read in only once
+func ImportOnce(name string, fn func(tx *sql.Tx) error) error {
+	d, err := Open()
+	if err != nil {
+		return err
+	}
+	var done int
+	if err := d.QueryRow(`SELECT count(*) FROM imports WHERE name = ?`, name).Scan(&done); err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return fmt.Errorf("importing %s: %v", name, err)
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO imports (name, time) VALUES (?, datetime('now'))`, name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Export is the content of the database as db export writes it
+type Export struct {
+	Exported   time.Time   `json:"exported"`
+	Snippets   []Snippet   `json:"snippets"`
+	Changesets []Changeset `json:"changesets"`
+	// Conversations are the saved AI conversations
+	Conversations []Conversation `json:"conversations"`
+	Usage         []Use          `json:"usage"`
+}
+
+// Snippet is a row of the snippets table
+type Snippet struct {
+	Name     string    `json:"name"`
+	Ext      string    `json:"ext"`
+	Code     string    `json:"code"`
+	Modified time.Time `json:"modified"`
+}
+
+// Changeset is a row of the changesets table; Changes is the JSON the
+// history package stores
+type Changeset struct {
+	Stack   string          `json:"stack"`
+	Command string          `json:"command"`
+	Time    time.Time       `json:"time"`
+	Changes json.RawMessage `json:"changes"`
+}
+
+// Conversation is a row of the conversations table; Messages is the JSON
+// the ai package stores
+type Conversation struct {
+	Name     string          `json:"name"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+	Messages json.RawMessage `json:"messages"`
+}
+
+// Dump reads all tables
+func Dump() (*Export, error) {
+	d, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	e := &Export{Exported: time.Now().UTC(), Snippets: []Snippet{}, Changesets: []Changeset{},
+		Conversations: []Conversation{}, Usage: []Use{}}
+	rows, err := d.Query(`SELECT name, ext, code, modified FROM snippets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var s Snippet
+		var modified string
+		if err := rows.Scan(&s.Name, &s.Ext, &s.Code, &modified); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.Modified, _ = time.Parse(time.RFC3339Nano, modified)
+		e.Snippets = append(e.Snippets, s)
+	}
+	rows.Close()
+
+	rows, err = d.Query(`SELECT stack, command, time, changes FROM changesets ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c Changeset
+		var t, changes string
+		if err := rows.Scan(&c.Stack, &c.Command, &t, &changes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Time, _ = time.Parse(time.RFC3339Nano, t)
+		c.Changes = json.RawMessage(changes)
+		e.Changesets = append(e.Changesets, c)
+	}
+	rows.Close()
+
+	rows, err = d.Query(`SELECT name, created, updated, messages FROM conversations ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c Conversation
+		var created, updated, messages string
+		if err := rows.Scan(&c.Name, &created, &updated, &messages); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.Created, _ = time.Parse(time.RFC3339Nano, created)
+		c.Updated, _ = time.Parse(time.RFC3339Nano, updated)
+		c.Messages = json.RawMessage(messages)
+		e.Conversations = append(e.Conversations, c)
+	}
+	rows.Close()
+
+	rows, err = d.Query(`SELECT command, time, failed, duration FROM usage ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u Use
+		var t string
+		var ms int64
+		if err := rows.Scan(&u.Command, &t, &u.Failed, &ms); err != nil {
+			return nil, err
+		}
+		u.Time, _ = time.Parse(time.RFC3339Nano, t)
+		u.Duration = time.Duration(ms) * time.Millisecond
+		e.Usage = append(e.Usage, u)
+	}
+	return e, rows.Err()
+}
+
+// Merged counts the rows Merge added or updated
+type Merged struct {
+	Snippets      int
+	Conversations int
+	Uses          int
+}
+
+// Merge reads an export of another installation into the database: its
+// snippets and AI conversations replace older ones of the same name and
+// its command runs are added unless already present. The undo history is
+// left out, as it restores files of the machine it was recorded on.
+func Merge(e *Export) (Merged, error) {
+	var m Merged
+	d, err := Open()
+	if err != nil {
+		return m, err
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		return m, err
+	}
+	defer tx.Rollback()
+	for _, s := range e.Snippets {
+		res, err := tx.Exec(`INSERT INTO snippets (name, ext, code, modified) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET ext = excluded.ext, code = excluded.code, modified = excluded.modified
+			WHERE excluded.modified > snippets.modified`,
+			s.Name, s.Ext, s.Code, s.Modified.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return m, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			m.Snippets++
+		}
+	}
+	for _, c := range e.Conversations {
+		res, err := tx.Exec(`INSERT INTO conversations (name, created, updated, messages) VALUES (?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET updated = excluded.updated, messages = excluded.messages
+			WHERE excluded.updated > conversations.updated`,
+			c.Name, c.Created.UTC().Format(time.RFC3339Nano), c.Updated.UTC().Format(time.RFC3339Nano), string(c.Messages))
+		if err != nil {
+			return m, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			m.Conversations++
+		}
+	}
+	for _, u := range e.Usage {
+		res, err := tx.Exec(`INSERT INTO usage (command, time, failed, duration)
+			SELECT ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM usage WHERE command = ? AND time = ?)`,
+			u.Command, u.Time.UTC().Format(time.RFC3339Nano), u.Failed, u.Duration.Milliseconds(),
+			u.Command, u.Time.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return m, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			m.Uses++
+		}
+	}
+	return m, tx.Commit()
+}
@@ -0,0 +1,57 @@
+package db
+
+import "time"
+
+// Use is one command run, without its arguments, which may hold hosts or
+// file names
+type Use struct {
+	Command  string        `json:"command"`
+	Time     time.Time     `json:"time"`
+	Failed   bool          `json:"failed"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// CommandCount is how often a command was run
+type CommandCount struct {
+	Command string
+	Runs    int
+	Failed  int
+	Last    time.Time
+}
+
+// RecordUse adds a command run to the usage table
+func RecordUse(u Use) error {
+	d, err := Open()
+	if err != nil {
+		return err
+	}
+	_, err = d.Exec(`INSERT INTO usage (command, time, failed, duration) VALUES (?, ?, ?, ?)`,
+		u.Command, u.Time.UTC().Format(time.RFC3339Nano), u.Failed, u.Duration.Milliseconds())
+	return err
+}
+
+// Usage counts the runs of each command since a time, most used first
+func Usage(since time.Time) ([]CommandCount, error) {
+	d, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.Query(`SELECT command, count(*), sum(failed), max(time) FROM usage
+		WHERE time >= ? GROUP BY command ORDER BY count(*) DESC, command`,
+		since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var counts []CommandCount
+	for rows.Next() {
+		var c CommandCount
+		var last string
+		if err := rows.Scan(&c.Command, &c.Runs, &c.Failed, &last); err != nil {
+			return nil, err
+		}
+		c.Last, _ = time.Parse(time.RFC3339Nano, last)
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/db"
+)
+
+func init() {
+	commandRegistry["db"] = Command{
+		Group:       groupSystem,
+		Description: "Show, export and merge the local database of snippets, history and usage",
+		Usage:       dbUsage,
+		Execute:     text(runDB),
+	}
+}
+
+const dbUsage = `Usage:
+  db                          Show where the database is and what it holds
+  db usage [--days <n>]       Count the commands run, most used first
+                              (default: the last 30 days)
+  db export [--output <file>] Write the database as JSON, to the terminal
+                              unless --output
+  db import <file>            Merge an export of another installation
+
+The snippet library, the undo history, the AI conversations and a record
+of the commands run (name, time, outcome and duration; never their
+arguments) are kept in automation-helper.db in the data directory.
+Snippets and the undo history of earlier versions are read in on first
+use and their files left in place. db import adds the snippets, AI
+conversations and command runs of an export, keeping the newer of two
+with the same name; the undo history is not merged, as it restores files
+of the machine it was recorded on.
+
+Examples:
+  db
+  db usage --days 7
+  db export --output laptop.json
+  db import laptop.json`
+
+func runDB(args []string) (string, error) {
+	if len(args) == 0 {
+		return showDB()
+	}
+	switch args[0] {
+	case "usage":
+		days := 30
+		for i := 1; i < len(args); i++ {
+			if i+1 >= len(args) || args[i] != "--days" {
+				return dbUsage, nil
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return "", errors.New("--days must be a positive whole number")
+			}
+			days = n
+			i++
+		}
+		return dbCommandUsage(days)
+	case "export":
+		output := ""
+		for i := 1; i < len(args); i++ {
+			if i+1 >= len(args) || args[i] != "--output" {
+				return dbUsage, nil
+			}
+			output = args[i+1]
+			i++
+		}
+		return exportDB(output)
+	case "import":
+		if len(args) != 2 {
+			return dbUsage, nil
+		}
+		return importDB(args[1])
+	default:
+		return dbUsage, nil
+	}
+}
+
+func showDB() (string, error) {
+	path, err := db.Path()
+	if err != nil {
+		return "", err
+	}
+	e, err := db.Dump()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Database: %s\n", path)
+	fmt.Fprintf(&b, "  %-14s %d\n", "snippets:", len(e.Snippets))
+	fmt.Fprintf(&b, "  %-14s %d\n", "changesets:", len(e.Changesets))
+	fmt.Fprintf(&b, "  %-14s %d\n", "conversations:", len(e.Conversations))
+	fmt.Fprintf(&b, "  %-14s %d", "commands run:", len(e.Usage))
+	if len(e.Usage) > 0 {
+		fmt.Fprintf(&b, " since %s", e.Usage[0].Time.Local().Format("2006-01-02"))
+	}
+	return b.String(), nil
+}
+
+func dbCommandUsage(days int) (string, error) {
+	counts, err := db.Usage(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return "", err
+	}
+	if len(counts) == 0 {
+		return fmt.Sprintf("No commands recorded in the last %d days.", days), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Commands run in the last %d days:\n", days)
+	fmt.Fprintf(&b, "  %-20s %6s %7s  %s\n", "COMMAND", "RUNS", "FAILED", "LAST")
+	for _, c := range counts {
+		fmt.Fprintf(&b, "  %-20s %6d %7d  %s\n", c.Command, c.Runs, c.Failed, c.Last.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func exportDB(output string) (string, error) {
+	e, err := db.Dump()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return string(data), nil
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Exported %d snippets, %d changesets, %d AI conversations and %d command runs to %s",
+		len(e.Snippets), len(e.Changesets), len(e.Conversations), len(e.Usage), output), nil
+}
+
+func importDB(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	var e db.Export
+	if err := json.Unmarshal(data, &e); err != nil {
+		return "", fmt.Errorf("%s is not a db export: %v", file, err)
+	}
+	m, err := db.Merge(&e)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Merged %d snippets, %d AI conversations and %d command runs from %s", m.Snippets, m.Conversations, m.Uses, file), nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const depsUsage = `Usage: abb deps <dir|file.mod>... [--dot]
+Finds which modules use symbols declared in others, reports names no module
+declares, duplicate declarations and circular dependencies, and recommends a
+module load order for the task. --dot prints the graph for Graphviz.
+
+Example:
+  abb deps ./T_ROB1`
+
+// abbDeps analyzes the module dependencies of one task
+func abbDeps(args []string) (string, error) {
+	dot := false
+	var sources []string
+	for _, a := range args {
+		if a == "--dot" {
+			dot = true
+			continue
+		}
+		sources = append(sources, a)
+	}
+	if len(sources) == 0 {
+		sources = projectSources()
+	}
+	if len(sources) == 0 {
+		return depsUsage, nil
+	}
+
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", errors.New("no RAPID modules found")
+	}
+	var modules []rapid.ModuleInfo
+	paths := make(map[string]string)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		info := rapid.AnalyzeModule(string(data))
+		if info.Name == "" {
+			info.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if other, exists := paths[info.Name]; exists {
+			return "", fmt.Errorf("module %s is declared in both %s and %s", info.Name, other, path)
+		}
+		paths[info.Name] = path
+		modules = append(modules, info)
+	}
+
+	g := rapid.BuildGraph(modules)
+	if dot {
+		return depsDot(g), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("\nModule dependencies (%d modules):\n", len(modules)))
+	for _, name := range g.LoadOrder() {
+		uses := g.Uses[name]
+		if len(uses) == 0 {
+			continue
+		}
+		deps := make([]string, 0, len(uses))
+		for d := range uses {
+			deps = append(deps, d)
+		}
+		sort.Strings(deps)
+		for _, d := range deps {
+			result.WriteString(fmt.Sprintf("  %s -> %s (%s)\n", name, d, summarizeNames(uses[d], 4)))
+		}
+	}
+
+	problems := 0
+	if len(g.Missing) > 0 {
+		result.WriteString("\nNot declared in any module (reference errors on load unless defined in the\nI/O configuration or by a RobotWare option):\n")
+		for _, m := range modules {
+			for _, ref := range g.Missing[m.Name] {
+				problems++
+				result.WriteString(fmt.Sprintf("  %s:%d: %s\n", paths[m.Name], m.Refs[ref], ref))
+			}
+		}
+	}
+	if len(g.Duplicates) > 0 {
+		result.WriteString("\nDeclared in more than one module (ambiguous):\n")
+		names := make([]string, 0, len(g.Duplicates))
+		for name := range g.Duplicates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			problems++
+			result.WriteString(fmt.Sprintf("  %s: %s\n", name, strings.Join(g.Duplicates[name], ", ")))
+		}
+	}
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		result.WriteString("\nCircular dependencies (modules must be loaded together):\n")
+		for _, c := range cycles {
+			problems++
+			result.WriteString("  " + strings.Join(c, " <-> ") + "\n")
+		}
+	}
+
+	result.WriteString("\nRecommended load order:\n")
+	for i, name := range g.LoadOrder() {
+		result.WriteString(fmt.Sprintf("  %2d. %s (%s)\n", i+1, name, filepath.Base(paths[name])))
+	}
+	if problems == 0 {
+		result.WriteString("\nNo dependency problems found.")
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+// rapidFiles expands directories into the RAPID modules they contain
+func rapidFiles(sources []string) ([]string, error) {
+	var files []string
+	for _, src := range sources {
+		info, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, src)
+			continue
+		}
+		err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && rapid.IsSourceFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// summarizeNames lists up to max names followed by a count of the rest
+func summarizeNames(names []string, max int) string {
+	if len(names) <= max {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:max], ", "), len(names)-max)
+}
+
+// depsDot renders the graph in Graphviz format
+func depsDot(g rapid.Graph) string {
+	var result strings.Builder
+	result.WriteString("digraph modules {\n")
+	for _, name := range g.LoadOrder() {
+		result.WriteString(fmt.Sprintf("  %q;\n", name))
+		deps := make([]string, 0, len(g.Uses[name]))
+		for d := range g.Uses[name] {
+			deps = append(deps, d)
+		}
+		sort.Strings(deps)
+		for _, d := range deps {
+			result.WriteString(fmt.Sprintf("  %q -> %q [label=%q];\n", name, d, summarizeNames(g.Uses[name][d], 2)))
+		}
+	}
+	result.WriteString("}")
+	return result.String()
+}
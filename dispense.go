@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const dispenseUsage = `Usage: generate dispense <datasheet.csv> [--temp 25] [--signal aoDispPress]
+       [--module DispenseData] [--volts 0-10] [--max-pressure 6] [--output dir]
+Reads a dispensing datasheet (columns flow, pressure and optionally
+temperature; units in brackets, e.g. "Flow (ml/min)") and generates the
+analog output scaling for EIO.cfg and a RAPID module that converts a
+requested flow into the regulator pressure. --max-pressure is the regulator
+pressure at the top of the voltage range; by default the highest datasheet
+pressure is used. --output writes <module>.mod and EIO_<signal>.cfg.
+
+Example:
+  generate dispense PU-8590.csv --temp 30 --max-pressure 6`
+
+// generateDispense turns a dispensing datasheet into signal scaling and RAPID code
+func generateDispense(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return dispenseUsage, nil
+	}
+	path := args[0]
+	opts := generate.DispenseOptions{
+		Source:   filepath.Base(path),
+		Module:   "DispenseData",
+		Signal:   "aoDispPress",
+		MaxVolts: 10,
+	}
+	tempSet := false
+	output := ""
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return dispenseUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--temp":
+			opts.Temperature, err = strconv.ParseFloat(value, 64)
+			tempSet = true
+		case "--signal":
+			opts.Signal = value
+		case "--module":
+			opts.Module = value
+		case "--volts":
+			lo, hi, ok := strings.Cut(value, "-")
+			if !ok {
+				return "", errors.New("--volts needs a range such as 0-10")
+			}
+			if opts.MinVolts, err = strconv.ParseFloat(lo, 64); err == nil {
+				opts.MaxVolts, err = strconv.ParseFloat(hi, 64)
+			}
+		case "--max-pressure":
+			opts.MaxPressure, err = strconv.ParseFloat(value, 64)
+		case "--output":
+			output = value
+		default:
+			return dispenseUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if !identifierRe.MatchString(opts.Module) || !identifierRe.MatchString(opts.Signal) {
+		return "", errors.New("module and signal names must be valid RAPID identifiers")
+	}
+	if opts.MaxVolts <= opts.MinVolts {
+		return "", errors.New("--volts range must be increasing")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sheet, err := generate.ParseDatasheet(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	if temps := sheet.Temperatures(); len(temps) > 1 && !tempSet {
+		var names []string
+		for _, t := range temps {
+			names = append(names, strconv.FormatFloat(t, 'f', -1, 64))
+		}
+		return "", fmt.Errorf("the datasheet has curves for %s °C; choose one with --temp", strings.Join(names, ", "))
+	}
+
+	eio, module, warnings, err := generate.DispenseCode(sheet, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, w := range warnings {
+		result.WriteString("Warning: " + w + "\n")
+	}
+	if output != "" {
+		if err := makeDir(output); err != nil {
+			return "", err
+		}
+		modPath := filepath.Join(output, opts.Module+".mod")
+		eioPath := filepath.Join(output, "EIO_"+opts.Signal+".cfg")
+		if err := writeFile(modPath, []byte(module), 0644); err != nil {
+			return "", err
+		}
+		if err := writeFile(eioPath, []byte(eio), 0644); err != nil {
+			return "", err
+		}
+		result.WriteString(fmt.Sprintf("Wrote %s and %s", modPath, eioPath))
+		return result.String(), nil
+	}
+	result.WriteString("--- EIO.cfg ---\n")
+	result.WriteString(eio)
+	result.WriteString("\n--- " + opts.Module + ".mod ---\n")
+	result.WriteString(module)
+	return strings.TrimRight(result.String(), "\n"), nil
+}
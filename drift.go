@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const driftUsage = `Usage: abb backup drift <old-backup> <new-backup> [--mm 0.1] [--deg 0.1] [--output report.csv]
+Matches robtargets by task, module and name across two controller backups and
+lists the points that moved more than --mm or turned more than --deg, largest
+move first, with the offset and its main direction in the coordinates the
+point is taught in. Points only in one backup are listed as added or removed.
+
+Example:
+  abb backup drift ./Backups/Cell4_monday ./Backups/Cell4_tuesday`
+
+// targetDrift is a robtarget that differs between two backups
+type targetDrift struct {
+	Key        string // task/module/name
+	Old, New   rapid.Target
+	Move, Turn float64
+}
+
+// backupDrift reports the taught points that changed between two backups
+func backupDrift(args []string) (string, error) {
+	var dirs []string
+	mm, deg, output := 0.1, 0.1, ""
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			dirs = append(dirs, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return driftUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--mm":
+			mm, err = strconv.ParseFloat(value, 64)
+		case "--deg":
+			deg, err = strconv.ParseFloat(value, 64)
+		case "--output":
+			output = value
+		default:
+			return driftUsage, nil
+		}
+		if err != nil || mm < 0 || deg < 0 {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if len(dirs) != 2 {
+		return driftUsage, nil
+	}
+
+	before, err := backupTargets(dirs[0])
+	if err != nil {
+		return "", err
+	}
+	after, err := backupTargets(dirs[1])
+	if err != nil {
+		return "", err
+	}
+
+	var moved []targetDrift
+	var added, removed []string
+	for key, old := range before {
+		t, ok := after[key]
+		if !ok {
+			removed = append(removed, key)
+			continue
+		}
+		d := targetDrift{Key: key, Old: old, New: t, Move: old.Distance(t),
+			Turn: calc.QuaternionAngle(calc.NormalizeQuaternion(old.Rot), calc.NormalizeQuaternion(t.Rot))}
+		if d.Move > mm || d.Turn > deg {
+			moved = append(moved, d)
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Slice(moved, func(i, j int) bool {
+		if moved[i].Move != moved[j].Move {
+			return moved[i].Move > moved[j].Move
+		}
+		if moved[i].Turn != moved[j].Turn {
+			return moved[i].Turn > moved[j].Turn
+		}
+		return moved[i].Key < moved[j].Key
+	})
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	if output != "" {
+		if err := writeFile(output, []byte(driftCSV(moved)), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Taught points %s -> %s: %d of %d moved more than %g mm or %g deg\n",
+		dirs[0], dirs[1], len(moved), len(before), mm, deg)
+	if len(moved) > 0 {
+		width := len("Point")
+		for _, d := range moved {
+			width = max(width, len(d.Key))
+		}
+		fmt.Fprintf(&b, "\n  %-*s  %9s  %8s  %8s  %8s  %8s  %s\n", width, "Point", "Moved mm", "dx", "dy", "dz", "Turn deg", "Direction")
+		for _, d := range moved {
+			dx, dy, dz := d.New.Trans[0]-d.Old.Trans[0], d.New.Trans[1]-d.Old.Trans[1], d.New.Trans[2]-d.Old.Trans[2]
+			fmt.Fprintf(&b, "  %-*s  %9.2f  %8.2f  %8.2f  %8.2f  %8.2f  %s\n", width, d.Key, d.Move, dx, dy, dz, d.Turn, driftDirection(dx, dy, dz, d.Move))
+		}
+	}
+	for _, list := range []struct {
+		title string
+		keys  []string
+	}{{"Added", added}, {"Removed", removed}} {
+		if len(list.keys) > 0 {
+			fmt.Fprintf(&b, "\n%s (%d):\n  %s\n", list.title, len(list.keys), strings.Join(list.keys, "\n  "))
+		}
+	}
+	if output != "" && !dryRun() {
+		fmt.Fprintf(&b, "\nWrote %s\n", output)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// backupTargets reads the robtargets of every module in a backup, keyed by
+// task/module/name
+func backupTargets(backup string) (map[string]rapid.Target, error) {
+	modules, err := backupModules(backup)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]rapid.Target)
+	for key, path := range modules {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		module := strings.TrimSuffix(key, filepath.Ext(key))
+		for _, t := range rapid.ParseTargets(string(data)) {
+			targets[module+"/"+t.Name] = t
+		}
+	}
+	return targets, nil
+}
+
+// driftDirection names the axes that make up most of a move, such as -Z or
+// +X-Y
+func driftDirection(dx, dy, dz, move float64) string {
+	if move == 0 {
+		return "-"
+	}
+	var dir strings.Builder
+	for i, d := range []float64{dx, dy, dz} {
+		if math.Abs(d) < move/3 {
+			continue
+		}
+		if d > 0 {
+			dir.WriteString("+")
+		} else {
+			dir.WriteString("-")
+		}
+		dir.WriteByte("XYZ"[i])
+	}
+	return dir.String()
+}
+
+func driftCSV(moved []targetDrift) string {
+	var b strings.Builder
+	b.WriteString("point,moved_mm,dx,dy,dz,turn_deg,old_x,old_y,old_z,new_x,new_y,new_z\n")
+	for _, d := range moved {
+		fmt.Fprintf(&b, "%s,%.3f,%.3f,%.3f,%.3f,%.3f,%g,%g,%g,%g,%g,%g\n", d.Key, d.Move,
+			d.New.Trans[0]-d.Old.Trans[0], d.New.Trans[1]-d.Old.Trans[1], d.New.Trans[2]-d.Old.Trans[2], d.Turn,
+			d.Old.Trans[0], d.Old.Trans[1], d.Old.Trans[2], d.New.Trans[0], d.New.Trans[1], d.New.Trans[2])
+	}
+	return b.String()
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/snippets"
+)
+
+func init() {
+	commandRegistry["edit"] = Command{
+		Group:       groupCode,
+		Description: "Open snippets, files or generated code in $EDITOR and lint on save",
+		Usage:       editUsage,
+		Execute:     text(runEdit),
+	}
+
+	commandRegistry["snippet"] = Command{
+		Group:       groupCode,
+		Description: "Manage the stored snippet library",
+		Usage:       snippetUsage,
+		Execute:     text(runSnippet),
+	}
+}
+
+const editUsage = `Usage: edit <target> [arguments]
+Targets:
+  snippet <name> [.mod|.st]             - Edit a stored snippet (created if missing)
+  file <path>                           - Edit a RAPID/ST file
+  gen <snippet:name|path> <command...>  - Edit the output of a command and save it
+
+Examples:
+  edit snippet gripper_open
+  edit file MainModule.mod
+  edit gen snippet:when_on sensor digital when_on
+  edit gen Pick.mod abb command move_l`
+
+func runEdit(args []string) (string, error) {
+	if len(args) < 2 {
+		return editUsage, nil
+	}
+
+	switch args[0] {
+	case "snippet":
+		ext := ".mod"
+		if len(args) > 2 {
+			ext = args[2]
+		}
+		initial := ""
+		if s, err := snippets.Load(args[1]); err == nil {
+			initial, ext = s.Code, s.Ext
+		}
+		code, ok, err := editAndLint(initial, ext)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "Discarded changes.", nil
+		}
+		if err := snippets.Save(snippets.Snippet{Name: args[1], Ext: ext, Code: code}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Saved snippet %s", args[1]), nil
+
+	case "file":
+		data, err := os.ReadFile(args[1])
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		code, ok, err := editAndLint(string(data), filepath.Ext(args[1]))
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "Discarded changes.", nil
+		}
+		if err := writeFile(args[1], []byte(code), 0o644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Wrote %s", args[1]), nil
+
+	case "gen":
+		if len(args) < 3 {
+			return editUsage, nil
+		}
+		name := strings.ToLower(args[2])
+		cmd, exists := commandRegistry[name]
+		if !exists {
+			return "", fmt.Errorf("unknown command: %s", args[2])
+		}
+		// Through execute, so the command is checked and confirmed as if
+		// it was typed at the prompt
+		r := execute(name, cmd, args[3:])
+		if r.Failed() {
+			return "", errors.New(r.Err)
+		}
+		generated := strings.TrimLeft(r.String(), "\n")
+		return saveEdited(args[1], generated)
+
+	default:
+		return editUsage, nil
+	}
+}
+
+// saveEdited edits generated code and stores it as a snippet or file
+func saveEdited(target, generated string) (string, error) {
+	name, isSnippet := strings.CutPrefix(target, "snippet:")
+	ext := filepath.Ext(target)
+	if isSnippet || ext == "" {
+		ext = ".mod"
+	}
+
+	code, ok, err := editAndLint(generated, ext)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "Discarded changes.", nil
+	}
+
+	if isSnippet {
+		if err := snippets.Save(snippets.Snippet{Name: name, Ext: ext, Code: code}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Saved snippet %s", name), nil
+	}
+	if err := writeFile(target, []byte(code), 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %s", target), nil
+}
+
+// editAndLint opens code in the user's editor and lints the result, offering
+// to re-edit until it is clean. It reports false if the user discards the edit.
+func editAndLint(code, ext string) (string, bool, error) {
+	tmp, err := os.CreateTemp("", "automation-helper-*"+ext)
+	if err != nil {
+		return "", false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(code); err != nil {
+		tmp.Close()
+		return "", false, err
+	}
+	tmp.Close()
+
+	for {
+		if err := openEditor(tmp.Name()); err != nil {
+			return "", false, err
+		}
+		data, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return "", false, err
+		}
+
+		findings, err := lintSource(tmp.Name(), string(data))
+		if err != nil || len(findings) == 0 {
+			// Files the linter does not understand are saved as-is
+			return string(data), true, nil
+		}
+
+		fmt.Println("\nLint found problems:")
+		for _, f := range findings {
+			fmt.Println("  " + f)
+		}
+		answer, ok := readLine("[e]dit again, [s]ave anyway, [d]iscard? ")
+		if !ok {
+			return "", false, nil
+		}
+		switch strings.ToLower(answer) {
+		case "s", "save":
+			return string(data), true, nil
+		case "d", "discard":
+			return "", false, nil
+		}
+	}
+}
+
+// openEditor runs $VISUAL or $EDITOR on the file, attached to the terminal
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// Allow editors with arguments such as "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+	return nil
+}
+
+const snippetUsage = `Usage: snippet <list|show|delete> [name]
+Use 'edit snippet <name>' to create or change a snippet.
+
+Examples:
+  snippet list
+  snippet show gripper_open`
+
+func runSnippet(args []string) (string, error) {
+	if len(args) < 1 {
+		return snippetUsage, nil
+	}
+
+	switch args[0] {
+	case "list":
+		list, err := snippets.List()
+		if err != nil {
+			return "", err
+		}
+		if len(list) == 0 {
+			return "No snippets stored yet. Create one with 'edit snippet <name>'.", nil
+		}
+		var result strings.Builder
+		result.WriteString("Stored snippets:\n")
+		for _, s := range list {
+			result.WriteString(fmt.Sprintf("  %s%s\n", s.Name, s.Ext))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	case "show":
+		if len(args) < 2 {
+			return "Usage: snippet show <name>", nil
+		}
+		s, err := snippets.Load(args[1])
+		if err != nil {
+			return "", err
+		}
+		return s.Code, nil
+
+	case "delete":
+		if len(args) < 2 {
+			return "Usage: snippet delete <name>", nil
+		}
+		if err := snippets.Delete(args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted snippet %s", args[1]), nil
+
+	default:
+		return "Unknown snippet subcommand. Available: list, show, delete", nil
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/i18n"
+	"github.com/polyfant/automation-helper-cli/result"
+)
+
+const errorUsage = `Usage: error <ERRNO name>
+The ERR_ prefix is optional. Type error on its own to list the known codes.
+
+Examples:
+  error ERR_DIVZERO
+  error sock_timeout`
+
+func init() {
+	commandRegistry["error"] = Command{
+		Group:       groupReference,
+		Description: "Look up RAPID error codes (ERRNO) and recovery hints",
+		Usage:       errorUsage,
+		Execute: func(args []string) result.Result {
+			if len(args) < 1 {
+				return result.New(result.Text{Body: errorUsage + "\n\nKnown error codes:\n" + strings.Join(abb.ErrorNames(), ", ")})
+			}
+
+			name := strings.ToUpper(args[0])
+			if !strings.HasPrefix(name, "ERR_") {
+				name = "ERR_" + name
+			}
+			code, exists := abb.Error(name)
+			if !exists {
+				return result.Errorf("unknown error code %s. Type 'error' to see known codes.", name)
+			}
+			return result.New(
+				result.Text{Body: "Error code: " + code.Name},
+				result.Text{Title: i18n.T("label.cause"), Body: code.Cause},
+				result.Text{Title: i18n.T("label.recovery"), Body: code.Recovery},
+			)
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const examplesUsage = `Usage: abb examples build [--output <dir>] [--keep-failed]
+
+Wraps the example of every command in the reference database into a
+complete module (data declarations at module level, statements in a PROC,
+TRAP routines after it), checks each with the RAPID syntax checks and
+writes the verified modules to the output directory (default examples).
+--keep-failed also writes modules that fail, for fixing the data.
+
+Example:
+  abb examples build --output ./examples`
+
+func abbExamples(args []string) (string, error) {
+	if len(args) < 1 || args[0] != "build" {
+		return examplesUsage, nil
+	}
+	dir, keepFailed := "examples", false
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--keep-failed" {
+			keepFailed = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return examplesUsage, nil
+		}
+		switch args[i] {
+		case "--output":
+			dir = args[i+1]
+		default:
+			return examplesUsage, nil
+		}
+		i++
+	}
+	if err := makeDir(dir); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	verified, failed, written := 0, 0, 0
+	for _, key := range abb.CommandKeys() {
+		cmd, _ := abb.Command(key)
+		if strings.TrimSpace(cmd.Example) == "" {
+			continue
+		}
+		module, src, errs := verifyExample(key, cmd)
+		if len(errs) == 0 {
+			verified++
+		} else {
+			failed++
+			for _, e := range errs {
+				fmt.Fprintf(&result, "FAIL %s: %s: %s\n", key, module, e)
+			}
+			if !keepFailed {
+				continue
+			}
+		}
+		if err := writeFile(filepath.Join(dir, module+".mod"), []byte(src), 0644); err != nil {
+			return "", err
+		}
+		written++
+	}
+	fmt.Fprintf(&result, "%d examples verified, %d failed; %d modules written to %s", verified, failed, written, dir)
+	if failed > 0 {
+		result.WriteString("\nFix the failing examples in the command data or data pack.")
+	}
+	return result.String(), nil
+}
+
+// verifyExample wraps the example of a command into a module and returns
+// the module name, its source and the syntax errors, each with the
+// offending line
+func verifyExample(key string, cmd abb.ABBCommand) (string, string, []string) {
+	module := rapid.ExampleModuleName(key)
+	src := rapid.WrapExample(module, "Example", fmt.Sprintf("Example of %s (%s) from the command reference", cmd.Name, key), cmd.Example)
+	lines := strings.Split(src, "\n")
+	var errs []string
+	for _, d := range rapid.CheckSyntax(src) {
+		if d.Severity != rapid.SeverityError {
+			continue
+		}
+		text := ""
+		if d.Line > 0 && d.Line <= len(lines) {
+			text = strings.TrimSpace(lines[d.Line-1])
+		}
+		errs = append(errs, fmt.Sprintf("%s\n       %s", d.Message, text))
+	}
+	return module, src, errs
+}
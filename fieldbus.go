@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/polyfant/automation-helper-cli/fieldbus"
+)
+
+func init() {
+	commandRegistry["fieldbus"] = Command{
+		Group:       groupIntegration,
+		Description: "Summarize EtherNet/IP EDS and Profinet GSDML device files",
+		Usage:       fieldbusUsage,
+		Execute:     text(runFieldbus),
+	}
+}
+
+const fieldbusUsage = `Usage: fieldbus show <file.eds|GSDML.xml>...
+Prints the identity, I/O connection sizes, assemblies or modules and the
+configurable parameters of a device description, for setting up the
+robot's fieldbus adapter without reading the raw file.
+
+Examples:
+  fieldbus show Gripper_v1_2.eds
+  fieldbus show GSDML-V2.35-Vendor-Device-20240101.xml`
+
+func runFieldbus(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "show" {
+		return fieldbusUsage, nil
+	}
+	var result string
+	for i, path := range args[1:] {
+		if i > 0 {
+			result += "\n\n"
+		}
+		d, err := fieldbus.Load(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+		result += d.Report()
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+// Package fieldbus summarizes fieldbus device description files: EtherNet/IP
+// EDS and Profinet GSDML
+package fieldbus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Device is the readable summary of a device description
+type Device struct {
+	Format      string // EDS or GSDML
+	Vendor      string
+	Product     string
+	Identity    string // Vendor, product and device codes
+	Revision    string
+	Connections []Connection
+	Modules     []Module
+	Params      []Param
+}
+
+// Connection is an EtherNet/IP I/O connection. Sizes are in bytes; -1 when
+// the size is set by a parameter.
+type Connection struct {
+	Name   string
+	OT     int    // Originator (scanner) to target size
+	TO     int    // Target to originator size
+	OTPath string // Assembly used for O->T data
+	TOPath string
+	RPI    string
+}
+
+// Module is an EDS assembly or a GSDML module/submodule. Sizes are in bytes.
+type Module struct {
+	Name   string
+	ID     string
+	Input  int
+	Output int
+	Detail string
+}
+
+// Param is a configurable parameter
+type Param struct {
+	Module  string
+	Name    string
+	Type    string
+	Default string
+	Range   string
+	Unit    string
+}
+
+// Load reads an EDS (.eds) or GSDML (.xml) file
+func Load(path string) (*Device, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".eds":
+		return ParseEDS(string(data))
+	case ".xml":
+		return ParseGSDML(data)
+	default:
+		return nil, fmt.Errorf("%s: expected an .eds or GSDML .xml file", path)
+	}
+}
+
+// Report formats the summary
+func (d *Device) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", d.Format, d.Product)
+	if d.Vendor != "" {
+		fmt.Fprintf(&b, "  Vendor:   %s\n", d.Vendor)
+	}
+	if d.Identity != "" {
+		fmt.Fprintf(&b, "  Identity: %s\n", d.Identity)
+	}
+	if d.Revision != "" {
+		fmt.Fprintf(&b, "  Revision: %s\n", d.Revision)
+	}
+
+	if len(d.Connections) > 0 {
+		b.WriteString("\nConnections (bytes):\n")
+		for _, c := range d.Connections {
+			fmt.Fprintf(&b, "  %-32s O->T %-6s T->O %-6s", c.Name, size(c.OT), size(c.TO))
+			if c.OTPath != "" || c.TOPath != "" {
+				fmt.Fprintf(&b, " assemblies %s/%s", orDash(c.OTPath), orDash(c.TOPath))
+			}
+			if c.RPI != "" {
+				fmt.Fprintf(&b, " RPI %s", c.RPI)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(d.Modules) > 0 {
+		title := "Modules (bytes)"
+		if d.Format == "EDS" {
+			title = "Assemblies"
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, m := range d.Modules {
+			fmt.Fprintf(&b, "  %-32s %-10s", m.Name, m.ID)
+			if m.Input >= 0 || m.Output >= 0 {
+				fmt.Fprintf(&b, " in %-4s out %-4s", size(m.Input), size(m.Output))
+			}
+			if m.Detail != "" {
+				b.WriteString(" " + m.Detail)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(d.Params) > 0 {
+		b.WriteString("\nParameters:\n")
+		for _, p := range d.Params {
+			name := p.Name
+			if p.Module != "" {
+				name = p.Module + ": " + p.Name
+			}
+			fmt.Fprintf(&b, "  %-40s %-10s", name, p.Type)
+			if p.Default != "" {
+				fmt.Fprintf(&b, " default %s", p.Default)
+			}
+			if p.Range != "" {
+				fmt.Fprintf(&b, " range %s", p.Range)
+			}
+			if p.Unit != "" {
+				fmt.Fprintf(&b, " [%s]", p.Unit)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func size(n int) string {
+	if n < 0 {
+		return "param"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,249 @@
+package fieldbus
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// edsSection maps entry keys to their comma-separated fields
+type edsSection map[string][]string
+
+// ParseEDS reads an EtherNet/IP Electronic Data Sheet
+func ParseEDS(src string) (*Device, error) {
+	sections := parseEDSSections(src)
+	dev, ok := sections["device"]
+	if !ok {
+		return nil, fmt.Errorf("no [Device] section; not an EDS file")
+	}
+
+	d := &Device{Format: "EDS"}
+	d.Vendor = edsValue(dev, "VendName")
+	d.Product = edsValue(dev, "ProdName")
+	d.Identity = fmt.Sprintf("vendor %s, product type %s, product code %s",
+		edsValue(dev, "VendCode"), edsValue(dev, "ProdType"), edsValue(dev, "ProdCode"))
+	if maj := edsValue(dev, "MajRev"); maj != "" {
+		d.Revision = maj + "." + edsValue(dev, "MinRev")
+	}
+
+	params := sections["params"]
+	for _, key := range edsKeys(params, "Param") {
+		f := params[key]
+		if len(f) < 12 {
+			continue
+		}
+		p := Param{Name: unquote(f[6]), Type: cipType(f[4]), Unit: unquote(f[7]), Default: f[11]}
+		if f[9] != "" || f[10] != "" {
+			p.Range = f[9] + ".." + f[10]
+		}
+		d.Params = append(d.Params, p)
+	}
+
+	assemblies := sections["assembly"]
+	paths := make(map[string]string)
+	for _, key := range edsKeys(assemblies, "Assem") {
+		f := assemblies[key]
+		if len(f) < 3 {
+			continue
+		}
+		// Assemblies have no direction of their own; the connections using
+		// them tell which is input and output
+		m := Module{Name: unquote(f[0]), ID: key, Input: -1, Output: -1}
+		var detail []string
+		if n, err := strconv.Atoi(f[2]); err == nil {
+			detail = append(detail, fmt.Sprintf("%d bytes", n))
+		}
+		if inst := edsInstance(f[1]); inst != "" {
+			detail = append(detail, "instance "+inst)
+			paths[key] = inst
+		}
+		m.Detail = strings.Join(detail, ", ")
+		if m.Name == "" {
+			m.Name = key
+		}
+		d.Modules = append(d.Modules, m)
+	}
+
+	conns := sections["connection manager"]
+	for _, key := range edsKeys(conns, "Connection") {
+		f := conns[key]
+		if len(f) < 13 {
+			continue
+		}
+		c := Connection{
+			Name:   unquote(f[12]),
+			OT:     edsSize(f[3]),
+			TO:     edsSize(f[6]),
+			OTPath: edsAssemblyRef(f[4], paths),
+			TOPath: edsAssemblyRef(f[7], paths),
+		}
+		if rpi := f[2]; rpi != "" {
+			if us, err := strconv.Atoi(rpi); err == nil {
+				c.RPI = fmt.Sprintf("%g ms", float64(us)/1000)
+			}
+		}
+		if c.Name == "" {
+			c.Name = key
+		}
+		d.Connections = append(d.Connections, c)
+	}
+	return d, nil
+}
+
+// parseEDSSections splits the file into sections of "Key = a, b, c;" entries.
+// Entries may span several lines and $ starts a comment.
+func parseEDSSections(src string) map[string]edsSection {
+	sections := make(map[string]edsSection)
+	var current edsSection
+	var entry strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(entry.String())
+		entry.Reset()
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || current == nil {
+			return
+		}
+		current[strings.TrimSpace(key)] = splitEDSFields(strings.TrimSuffix(strings.TrimSpace(value), ";"))
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		line = stripEDSComment(line)
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") && entry.Len() == 0 {
+			name := strings.ToLower(strings.Trim(trimmed, "[]"))
+			current = make(edsSection)
+			sections[name] = current
+			continue
+		}
+		entry.WriteString(trimmed + " ")
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return sections
+}
+
+// stripEDSComment removes a $ comment that is not inside a string
+func stripEDSComment(line string) string {
+	inString := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			inString = !inString
+		case '$':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// splitEDSFields splits on commas outside strings; adjacent strings are joined
+func splitEDSFields(value string) []string {
+	var fields []string
+	var field strings.Builder
+	inString := false
+	for _, c := range value {
+		switch {
+		case c == '"':
+			inString = !inString
+			field.WriteRune(c)
+		case c == ',' && !inString:
+			fields = append(fields, joinEDSStrings(field.String()))
+			field.Reset()
+		default:
+			field.WriteRune(c)
+		}
+	}
+	return append(fields, joinEDSStrings(field.String()))
+}
+
+var adjacentStrings = regexp.MustCompile(`"\s+"`)
+
+func joinEDSStrings(s string) string {
+	return adjacentStrings.ReplaceAllString(strings.TrimSpace(s), "")
+}
+
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"`)
+}
+
+func edsValue(sec edsSection, key string) string {
+	for k, v := range sec {
+		if strings.EqualFold(k, key) && len(v) > 0 {
+			return unquote(v[0])
+		}
+	}
+	return ""
+}
+
+// edsKeys returns the numbered keys with the prefix (Param1, Param2, ...) in order
+func edsKeys(sec edsSection, prefix string) []string {
+	var keys []string
+	for k := range sec {
+		if n, err := strconv.Atoi(strings.TrimPrefix(k, prefix)); err == nil && n > 0 && strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(keys[i], prefix))
+		b, _ := strconv.Atoi(strings.TrimPrefix(keys[j], prefix))
+		return a < b
+	})
+	return keys
+}
+
+// edsInstance returns the instance number from a CIP path such as "20 04 24 64"
+func edsInstance(path string) string {
+	bytes := strings.Fields(unquote(path))
+	for i := 0; i+1 < len(bytes); i++ {
+		if strings.EqualFold(bytes[i], "24") {
+			if n, err := strconv.ParseUint(bytes[i+1], 16, 8); err == nil {
+				return strconv.FormatUint(n, 10)
+			}
+		}
+	}
+	return ""
+}
+
+// edsSize reads a connection size; sizes set by a parameter return -1
+func edsSize(s string) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+		return n
+	}
+	return -1
+}
+
+// edsAssemblyRef resolves a format field that names an assembly
+func edsAssemblyRef(s string, paths map[string]string) string {
+	s = strings.TrimSpace(s)
+	if inst, ok := paths[s]; ok {
+		return inst
+	}
+	return ""
+}
+
+// cipTypes names the CIP elementary data type codes used in [Params]
+var cipTypes = map[string]string{
+	"0xC1": "BOOL", "0xC2": "SINT", "0xC3": "INT", "0xC4": "DINT", "0xC5": "LINT",
+	"0xC6": "USINT", "0xC7": "UINT", "0xC8": "UDINT", "0xC9": "ULINT",
+	"0xCA": "REAL", "0xCB": "LREAL", "0xD0": "STRING", "0xD1": "BYTE",
+	"0xD2": "WORD", "0xD3": "DWORD", "0xDA": "SHORT_STRING",
+}
+
+func cipType(code string) string {
+	code = strings.TrimSpace(code)
+	for k, t := range cipTypes {
+		if strings.EqualFold(k, code) {
+			return t
+		}
+	}
+	return code
+}
@@ -0,0 +1,215 @@
+package fieldbus
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GSDML element layout; struct tags without a namespace match any namespace
+type gsdDocument struct {
+	Identity struct {
+		VendorID string `xml:"VendorID,attr"`
+		DeviceID string `xml:"DeviceID,attr"`
+		InfoText struct {
+			TextID string `xml:"TextId,attr"`
+		} `xml:"InfoText"`
+		VendorName struct {
+			Value string `xml:"Value,attr"`
+		} `xml:"VendorName"`
+	} `xml:"ProfileBody>DeviceIdentity"`
+	DAPs       []gsdItem `xml:"ProfileBody>ApplicationProcess>DeviceAccessPointList>DeviceAccessPointItem"`
+	Modules    []gsdItem `xml:"ProfileBody>ApplicationProcess>ModuleList>ModuleItem"`
+	Submodules []gsdItem `xml:"ProfileBody>ApplicationProcess>SubmoduleList>SubmoduleItem"`
+	Texts      []struct {
+		ID    string `xml:"TextId,attr"`
+		Value string `xml:"Value,attr"`
+	} `xml:"ProfileBody>ApplicationProcess>ExternalTextList>PrimaryLanguage>Text"`
+}
+
+// gsdItem covers access points, modules and submodules
+type gsdItem struct {
+	ID             string         `xml:"ID,attr"`
+	ModuleIdent    string         `xml:"ModuleIdentNumber,attr"`
+	SubmoduleIdent string         `xml:"SubmoduleIdentNumber,attr"`
+	MinInterval    string         `xml:"MinDeviceInterval,attr"`
+	Name           gsdText        `xml:"ModuleInfo>Name"`
+	Inputs         []gsdDataItem  `xml:"IOData>Input>DataItem"`
+	Outputs        []gsdDataItem  `xml:"IOData>Output>DataItem"`
+	Records        []gsdRecord    `xml:"RecordDataList>ParameterRecordDataItem"`
+	Virtual        []gsdItem      `xml:"VirtualSubmoduleList>VirtualSubmoduleItem"`
+	UseableModules []gsdModuleRef `xml:"UseableModules>ModuleItemRef"`
+}
+
+type gsdText struct {
+	TextID string `xml:"TextId,attr"`
+}
+
+type gsdDataItem struct {
+	DataType string `xml:"DataType,attr"`
+	Length   string `xml:"Length,attr"`
+	TextID   string `xml:"TextId,attr"`
+}
+
+type gsdRecord struct {
+	Index  string   `xml:"Index,attr"`
+	Length string   `xml:"Length,attr"`
+	Name   gsdText  `xml:"Name"`
+	Refs   []gsdRef `xml:"Ref"`
+}
+
+type gsdRef struct {
+	DataType      string `xml:"DataType,attr"`
+	ByteOffset    string `xml:"ByteOffset,attr"`
+	DefaultValue  string `xml:"DefaultValue,attr"`
+	AllowedValues string `xml:"AllowedValues,attr"`
+	TextID        string `xml:"TextId,attr"`
+	ValueItem     string `xml:"ValueItemTarget,attr"`
+}
+
+type gsdModuleRef struct {
+	Target         string `xml:"ModuleItemTarget,attr"`
+	AllowedInSlots string `xml:"AllowedInSlots,attr"`
+	FixedInSlots   string `xml:"FixedInSlots,attr"`
+}
+
+// gsdSizes are the byte sizes of the GSDML data types with a fixed length
+var gsdSizes = map[string]int{
+	"Integer8": 1, "Unsigned8": 1, "Integer16": 2, "Unsigned16": 2,
+	"Integer32": 4, "Unsigned32": 4, "Integer64": 8, "Unsigned64": 8,
+	"Float32": 4, "Float64": 8, "Boolean": 1,
+	"Float32+Status8": 5, "Unsigned8+Unsigned8": 2, "Unsigned16_S": 2,
+	"Integer16_S": 2, "Unsigned8_S": 1, "Integer8_S": 1,
+}
+
+// ParseGSDML reads a Profinet General Station Description
+func ParseGSDML(data []byte) (*Device, error) {
+	var doc gsdDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid GSDML: %v", err)
+	}
+	if doc.Identity.VendorID == "" && len(doc.DAPs) == 0 {
+		return nil, fmt.Errorf("no DeviceIdentity found; not a GSDML file")
+	}
+
+	texts := make(map[string]string)
+	for _, t := range doc.Texts {
+		texts[t.ID] = t.Value
+	}
+	text := func(id string) string {
+		if v, ok := texts[id]; ok {
+			return v
+		}
+		return id
+	}
+
+	d := &Device{
+		Format:   "GSDML",
+		Vendor:   doc.Identity.VendorName.Value,
+		Product:  text(doc.Identity.InfoText.TextID),
+		Identity: fmt.Sprintf("vendor ID %s, device ID %s", doc.Identity.VendorID, doc.Identity.DeviceID),
+	}
+
+	submodules := make(map[string]gsdItem)
+	for _, s := range doc.Submodules {
+		submodules[s.ID] = s
+	}
+	moduleNames := make(map[string]string)
+	for _, m := range doc.Modules {
+		moduleNames[m.ID] = text(m.Name.TextID)
+	}
+
+	addItem := func(item gsdItem, kind string, detail string) {
+		in, out := 0, 0
+		params := d.gsdParams(item, text)
+		for _, v := range item.Virtual {
+			in += gsdBytes(v.Inputs)
+			out += gsdBytes(v.Outputs)
+			params += d.gsdParams(v, text)
+		}
+		in += gsdBytes(item.Inputs)
+		out += gsdBytes(item.Outputs)
+		ident := item.ModuleIdent
+		if ident == "" {
+			ident = item.SubmoduleIdent
+		}
+		if params > 0 {
+			detail = strings.TrimSpace(fmt.Sprintf("%s %d parameter(s)", detail, params))
+		}
+		d.Modules = append(d.Modules, Module{
+			Name:   fmt.Sprintf("%s %s", kind, text(item.Name.TextID)),
+			ID:     ident,
+			Input:  in,
+			Output: out,
+			Detail: detail,
+		})
+	}
+
+	for _, dap := range doc.DAPs {
+		var allowed []string
+		for _, ref := range dap.UseableModules {
+			slots := ref.AllowedInSlots
+			if slots == "" {
+				slots = ref.FixedInSlots
+			}
+			name := moduleNames[ref.Target]
+			if name == "" {
+				name = ref.Target
+			}
+			allowed = append(allowed, fmt.Sprintf("%s (slots %s)", name, slots))
+		}
+		detail := ""
+		if dap.MinInterval != "" {
+			if n, err := strconv.Atoi(dap.MinInterval); err == nil {
+				detail = fmt.Sprintf("min. cycle %g ms", float64(n)*31.25/1000)
+			}
+		}
+		addItem(dap, "DAP", detail)
+		if len(allowed) > 0 {
+			d.Modules[len(d.Modules)-1].Detail += "\n" + strings.Repeat(" ", 4) + "modules: " + strings.Join(allowed, ", ")
+		}
+	}
+	for _, m := range doc.Modules {
+		addItem(m, "Module", "")
+	}
+	for _, s := range doc.Submodules {
+		addItem(s, "Submodule", "")
+	}
+	return d, nil
+}
+
+// gsdParams adds the parameters of an item's records and returns their count
+func (d *Device) gsdParams(item gsdItem, text func(string) string) int {
+	count := 0
+	for _, rec := range item.Records {
+		for _, ref := range rec.Refs {
+			p := Param{
+				Module:  text(item.Name.TextID),
+				Name:    text(ref.TextID),
+				Type:    ref.DataType,
+				Default: ref.DefaultValue,
+				Range:   ref.AllowedValues,
+			}
+			if p.Module == "" {
+				p.Module = fmt.Sprintf("record %s", rec.Index)
+			}
+			d.Params = append(d.Params, p)
+			count++
+		}
+	}
+	return count
+}
+
+// gsdBytes sums the sizes of the data items
+func gsdBytes(items []gsdDataItem) int {
+	total := 0
+	for _, it := range items {
+		if n, ok := gsdSizes[it.DataType]; ok {
+			total += n
+		} else if n, err := strconv.Atoi(it.Length); err == nil {
+			total += n
+		}
+	}
+	return total
+}
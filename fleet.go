@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/result"
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+const fleetUsage = `Usage:
+  fleet                        List the registered controllers and PLCs
+  fleet add <name> --host <address> [--kind abb|plc] [--note text] [--credentials]
+            [--production] [--approval token|ticket|either] [--ticket-pattern <regexp>]
+  fleet remove <name>
+  fleet status [--target <name,...|all>]
+
+Registers the controllers and PLCs of a plant by name. Any command that
+takes --host runs against them with --target instead: a name, a comma
+separated list or all, which for commands means all ABB controllers. The
+results are shown per target. A --target that
+names no fleet target is passed on, for commands such as abb compat that
+have their own.
+
+ABB controllers are reached over Robot Web Services; --credentials prompts
+for the user and password and stores them encrypted like 'secrets set rws
+<host>'. PLCs are checked for a TCP connection, on port 502 (Modbus TCP)
+unless the address has a port. The fleet is kept in ` + fleetFile + ` in the
+data directory.
+
+Deploying modules to a --production controller needs an approval token from
+a second person, a signed change ticket whose reference matches
+--ticket-pattern (default ` + defaultTicketPattern + `), or either (the
+default); see approve.
+Updating a production controller keeps --production; remove it to drop it.
+
+Examples:
+  fleet add cell3 --host 192.168.125.1 --credentials
+  fleet add plc-line1 --host 10.0.1.20:502 --kind plc
+  fleet add cell3 --host 192.168.125.1 --production --approval token
+  fleet status
+  abb rws info --target all`
+
+// fleetFile holds the registered targets in the data directory
+const fleetFile = "fleet.json"
+
+// fleetTarget is a controller or PLC registered by name
+type fleetTarget struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // abb or plc
+	Host string `json:"host"`
+	Note string `json:"note,omitempty"`
+	// Production controllers need an approval to deploy modules to:
+	// token, ticket or either
+	Production    bool   `json:"production,omitempty"`
+	Approval      string `json:"approval,omitempty"`
+	TicketPattern string `json:"ticket_pattern,omitempty"`
+}
+
+// fleetTimeout bounds how long fleet status waits for one target
+const fleetTimeout = 5 * time.Second
+
+func init() {
+	commandRegistry["fleet"] = Command{
+		Group:       groupIntegration,
+		Description: "Register controllers and PLCs and run commands on them by name",
+		Usage:       fleetUsage,
+		Execute: func(args []string) result.Result {
+			if len(args) > 0 && args[0] == "status" {
+				return fleetStatus(args[1:])
+			}
+			return result.FromString(runFleet(args))
+		},
+	}
+}
+
+func runFleet(args []string) (string, error) {
+	if len(args) == 0 {
+		return listFleet()
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || strings.HasPrefix(args[1], "--") {
+			return fleetUsage, nil
+		}
+		return addFleetTarget(args[1], args[2:])
+	case "remove":
+		if len(args) != 2 {
+			return fleetUsage, nil
+		}
+		fleet, err := loadFleet()
+		if err != nil {
+			return "", err
+		}
+		for i, t := range fleet {
+			if t.Name == args[1] {
+				if err := saveFleet(append(fleet[:i], fleet[i+1:]...)); err != nil {
+					return "", err
+				}
+				return "Removed " + args[1] + " from the fleet", nil
+			}
+		}
+		return "", fmt.Errorf("no fleet target %q", args[1])
+	default:
+		return fleetUsage, nil
+	}
+}
+
+func listFleet() (string, error) {
+	fleet, err := loadFleet()
+	if err != nil {
+		return "", err
+	}
+	if len(fleet) == 0 {
+		return "No controllers registered. Add one with 'fleet add <name> --host <address>'.", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Fleet (%d):\n", len(fleet))
+	for _, t := range fleet {
+		note := t.Note
+		if t.Production {
+			note = strings.TrimSpace("[production] " + note)
+		}
+		fmt.Fprintf(&b, "  %-14s %-4s %-22s %s\n", t.Name, t.Kind, t.Host, note)
+	}
+	return strings.TrimRight(b.String(), " \n"), nil
+}
+
+func addFleetTarget(name string, args []string) (string, error) {
+	if name == "all" || !identifierRe.MatchString(strings.ReplaceAll(name, "-", "_")) {
+		return "", fmt.Errorf("invalid target name %q", name)
+	}
+	t := fleetTarget{Name: name, Kind: "abb"}
+	credentials := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--credentials" {
+			credentials = true
+			continue
+		}
+		if args[i] == "--production" {
+			t.Production = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return fleetUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			t.Host = value
+		case "--kind":
+			t.Kind = strings.ToLower(value)
+			if t.Kind != "abb" && t.Kind != "plc" {
+				return "", fmt.Errorf("invalid value %q for --kind (use abb or plc)", value)
+			}
+		case "--note":
+			t.Note = value
+		case "--approval":
+			if value != "token" && value != "ticket" && value != "either" {
+				return "", fmt.Errorf("invalid value %q for --approval (use token, ticket or either)", value)
+			}
+			t.Production, t.Approval = true, value
+		case "--ticket-pattern":
+			if _, err := regexp.Compile(value); err != nil {
+				return "", fmt.Errorf("invalid --ticket-pattern: %v", err)
+			}
+			t.Production, t.TicketPattern = true, value
+		default:
+			return fleetUsage, nil
+		}
+		i++
+	}
+	if t.Host == "" {
+		return "", errors.New("--host is required")
+	}
+	if credentials && t.Kind != "abb" {
+		return "", errors.New("--credentials is for ABB controllers")
+	}
+	if t.Production && t.Kind != "abb" {
+		return "", errors.New("--production is for ABB controllers")
+	}
+	if t.Approval == "either" {
+		t.Approval = ""
+	}
+
+	fleet, err := loadFleet()
+	if err != nil {
+		return "", err
+	}
+	verb := "Added"
+	replaced := false
+	for i := range fleet {
+		if fleet[i].Name == name {
+			if fleet[i].Production && !t.Production {
+				return "", fmt.Errorf("%s is a production controller; add --production to keep its approval, or 'fleet remove %s' first", name, name)
+			}
+			fleet[i], replaced, verb = t, true, "Updated"
+		}
+	}
+	if !replaced {
+		fleet = append(fleet, t)
+	}
+	if err := saveFleet(fleet); err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("%s %s (%s at %s)", verb, name, t.Kind, t.Host)
+	if t.Production {
+		msg += ", production: deployments need approval"
+	}
+	if credentials && dryRun() {
+		msg += "\nThe credentials are not stored in a dry run."
+	} else if credentials {
+		stored, err := secretsSetRWS(t.Host)
+		if err != nil {
+			return msg, err
+		}
+		msg += "\n" + stored
+	}
+	return msg, nil
+}
+
+// fleetStatus checks the targets in parallel and shows them as a table
+func fleetStatus(args []string) result.Result {
+	targets, rest, err := fleetTargets(args, true)
+	if err != nil {
+		return result.Errorf("%v", err)
+	}
+	if len(rest) > 0 {
+		return result.New(result.Text{Body: fleetUsage})
+	}
+	if targets == nil {
+		if targets, err = loadFleet(); err != nil {
+			return result.Errorf("%v", err)
+		}
+	}
+	if len(targets) == 0 {
+		return result.New(result.Text{Body: "No controllers registered. Add one with 'fleet add <name> --host <address>'."})
+	}
+
+	rows := make([][]string, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows[i] = targetStatus(t)
+		}()
+	}
+	wg.Wait()
+	return result.New(result.Table{
+		Title:   fmt.Sprintf("Fleet status at %s", time.Now().Format("2006-01-02 15:04:05")),
+		Columns: []string{"Target", "Kind", "Host", "Status", "Mode", "Controller", "Execution", "RobotWare"},
+		Rows:    rows,
+	})
+}
+
+// targetStatus is the status row of one target
+func targetStatus(t fleetTarget) []string {
+	row := []string{t.Name, t.Kind, t.Host, "", "-", "-", "-", "-"}
+	if t.Kind == "plc" {
+		addr := t.Host
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "502")
+		}
+		conn, err := net.DialTimeout("tcp", addr, fleetTimeout)
+		if err != nil {
+			row[3] = "unreachable"
+			return row
+		}
+		conn.Close()
+		row[3] = "reachable"
+		return row
+	}
+
+	c := rws.NewClient(t.Host)
+	c.HTTP.Timeout = fleetTimeout
+	c.User, c.Password = rwsCredentials(t.Host, "", "")
+	mode, err := c.OperationMode()
+	if err != nil {
+		row[3] = "unreachable"
+		if e, ok := err.(*rws.Error); ok {
+			row[3] = strings.ToLower(http.StatusText(e.Status))
+		}
+		return row
+	}
+	row[3], row[4] = "ok", mode
+	if s, err := c.ControllerState(); err == nil {
+		row[5] = s
+	}
+	if s, err := c.ExecutionState(); err == nil {
+		row[6] = s
+	}
+	if s, err := c.System(); err == nil && s.RobotWare != "" {
+		row[7] = s.RobotWare
+	}
+	return row
+}
+
+// fleetTargets takes --target from the arguments and returns the targets it
+// names, nil without the option. Unless strict, as for commands run on the
+// targets, all means the ABB controllers, and a --target that names no
+// fleet target is left in the arguments for commands with their own
+// --target, such as abb compat.
+func fleetTargets(args []string, strict bool) ([]fleetTarget, []string, error) {
+	var names []string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--target" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			if !strict {
+				return nil, args, nil
+			}
+			return nil, nil, fmt.Errorf("--target needs a fleet target name or all")
+		}
+		names = append(names, strings.Split(args[i+1], ",")...)
+		i++
+	}
+	if names == nil {
+		return nil, args, nil
+	}
+	fleet, err := loadFleet()
+	if err != nil {
+		return nil, nil, err
+	}
+	var targets []fleetTarget
+	for _, name := range names {
+		if name == "all" {
+			targets = nil
+			for _, t := range fleet {
+				if strict || t.Kind == "abb" {
+					targets = append(targets, t)
+				}
+			}
+			if len(targets) == 0 {
+				return nil, nil, fmt.Errorf("no controllers registered; add them with 'fleet add'")
+			}
+			return targets, rest, nil
+		}
+		found := false
+		for _, t := range fleet {
+			if t.Name == name {
+				targets = append(targets, t)
+				found = true
+			}
+		}
+		if !found && !strict {
+			return nil, args, nil
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown fleet target %q; 'fleet' lists them", name)
+		}
+	}
+	return targets, rest, nil
+}
+
+// onTargets runs a command once per fleet target with --host set to the
+// target's address, showing the results under the target names
+func onTargets(name string, cmd Command, targets []fleetTarget, args []string) result.Result {
+	for _, a := range args {
+		if a == "--host" {
+			return result.Errorf("use either --target or --host")
+		}
+	}
+	var r result.Result
+	var failed []string
+	for _, t := range targets {
+		tr := execute(name, cmd, append(append([]string{}, args...), "--host", t.Host))
+		blocks := tr.Blocks
+		if tr.Failed() {
+			blocks = append(blocks, result.Text{Body: "Error: " + tr.Err})
+			failed = append(failed, t.Name)
+		}
+		// The target names the first block of its output
+		title := fmt.Sprintf("%s (%s)", t.Name, t.Host)
+		if first, ok := firstText(blocks); ok && first.Title == "" {
+			first.Title = title
+			blocks[0] = first
+		} else {
+			blocks = append([]result.Block{result.Text{Title: title}}, blocks...)
+		}
+		r.Add(blocks...)
+	}
+	if len(failed) > 0 {
+		r.Err = fmt.Sprintf("%d of %d targets failed: %s", len(failed), len(targets), strings.Join(failed, ", "))
+	}
+	return r
+}
+
+func loadFleet() ([]fleetTarget, error) {
+	path, err := config.Path(fleetFile)
+	if err != nil {
+		return nil, err
+	}
+	var fleet []fleetTarget
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fleet); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return fleet, nil
+}
+
+func saveFleet(fleet []fleetTarget) error {
+	path, err := config.Path(fleetFile)
+	if err != nil {
+		return err
+	}
+	sort.Slice(fleet, func(i, j int) bool { return fleet[i].Name < fleet[j].Name })
+	data, err := json.MarshalIndent(fleet, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'), 0o644)
+}
+
+// firstText returns the first block when it is text
+func firstText(blocks []result.Block) (result.Text, bool) {
+	if len(blocks) == 0 {
+		return result.Text{}, false
+	}
+	t, ok := blocks[0].(result.Text)
+	return t, ok
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const formatUsage = `Usage: abb format [<file|dir>...] [--style <profile>] [--check]
+Re-indents RAPID modules by their block structure and sets the keyword case
+of the project's style profile (see style); --style picks another profile.
+Comments, strings and names are kept. Without files, the RAPID files of the
+active project are formatted. --check lists the files that need formatting
+without writing them and fails when there are some, for CI.
+
+Examples:
+  abb format Main.mod
+  abb format ./RAPID --style compact --dry-run
+  abb format --check`
+
+// abbFormat lays out modules in a style profile
+func abbFormat(args []string) (string, error) {
+	var sources []string
+	check := false
+	_, style := activeStyle()
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--check":
+			check = true
+		case "--style":
+			if i+1 >= len(args) {
+				return formatUsage, nil
+			}
+			s, err := styleProfile(args[i+1])
+			if err != nil {
+				return "", err
+			}
+			style = s
+			i++
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				return formatUsage, nil
+			}
+			sources = append(sources, args[i])
+		}
+	}
+	var files []string
+	var err error
+	if len(sources) == 0 {
+		files, err = projectFiles()
+		if err == nil && files == nil {
+			return formatUsage, nil
+		}
+	} else {
+		files, err = rapidFiles(sources)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var changed []string
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		out := style.Format(string(data))
+		if out == string(data) {
+			continue
+		}
+		changed = append(changed, path)
+		if check {
+			continue
+		}
+		if err := writeFile(path, []byte(out), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case len(changed) == 0:
+		return fmt.Sprintf("%d files already formatted", len(files)), nil
+	case check:
+		return "", fmt.Errorf("%d of %d files need formatting:\n  %s", len(changed), len(files), strings.Join(changed, "\n  "))
+	case dryRun():
+		return fmt.Sprintf("%d of %d files would be formatted", len(changed), len(files)), nil
+	}
+	return fmt.Sprintf("Formatted %d of %d files:\n  %s", len(changed), len(files), strings.Join(changed, "\n  ")), nil
+}
@@ -0,0 +1,160 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BrakeCheckOptions describe the cyclic brake check and the service
+// routines a SafeMove cell runs on request
+type BrakeCheckOptions struct {
+	Module     string
+	Interval   float64    // Hours between brake checks, as configured in SafeMove
+	PreWarning float64    // Hours before expiry that SafeMove warns
+	Position   [6]float64 // Joint angles of the brake check position in degrees
+	Warning    string     // Input set by the SafeMove pre-warning
+	Required   string     // Input set when the check has expired
+	Sync       bool       // Also generate the SafeMove synchronization check
+	SyncPos    [6]float64
+}
+
+// BrakeCheckCode returns a RAPID module with the cyclic brake check, a
+// service routine dispatcher for PLC requests and scheduling guidance
+func BrakeCheckCode(opts BrakeCheckOptions) (module, guidance string, err error) {
+	if opts.Interval <= 0 || opts.PreWarning <= 0 || opts.PreWarning >= opts.Interval {
+		return "", "", fmt.Errorf("the pre-warning must be shorter than the brake check interval")
+	}
+	for _, s := range []string{opts.Warning, opts.Required} {
+		if !stIdentifier(s) {
+			return "", "", fmt.Errorf("%q is not a valid signal name", s)
+		}
+	}
+	return brakeCheckModule(opts), brakeCheckGuidance(opts), nil
+}
+
+func jointTarget(j [6]float64) string {
+	var parts []string
+	for _, v := range j {
+		parts = append(parts, formatNum(v))
+	}
+	return "[[" + strings.Join(parts, ",") + "],[9E9,9E9,9E9,9E9,9E9,9E9]]"
+}
+
+func brakeCheckModule(opts BrakeCheckOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	w("MODULE %s", opts.Module)
+	w("    ! Cyclic brake check for SafeMove, interval %s h with %s h pre-warning.", formatNum(opts.Interval), formatNum(opts.PreWarning))
+	w("    ! The interval itself is set in the safety configuration; this module")
+	w("    ! only runs the check in time, at a point where the cell allows it.")
+	w("    ! %s: SafeMove brake check pre-warning, %s: check expired", opts.Warning, opts.Required)
+	w("")
+	w("    ! Position clear of fixtures where every axis can hold its own weight")
+	w("    CONST jointtarget jBrakeCheck := %s;", jointTarget(opts.Position))
+	if opts.Sync {
+		w("    ! Position where the sync switch is activated")
+		w("    CONST jointtarget jSyncCheck := %s;", jointTarget(opts.SyncPos))
+	}
+	w("    CONST speeddata vService := v500;")
+	w("")
+	w("    ! Last successful check, shown on the FlexPendant and kept for the safety audit")
+	w("    PERS string stLastBrakeCheck := \"\";")
+	w("    PERS num nBrakeChecks := 0;")
+	w("")
+	w("    ! Service routine numbers requested by the PLC over giServiceReq")
+	w("    CONST num SERVICE_BRAKECHECK := 1;")
+	if opts.Sync {
+		w("    CONST num SERVICE_SYNCCHECK := 2;")
+	}
+	w("    CONST num SERVICE_POSITION := 3;")
+	w("")
+	w("    ! Call between parts: runs the brake check once SafeMove warns, so the")
+	w("    ! check never expires during production")
+	w("    PROC BrakeCheckIfDue()")
+	w("        IF %s = 1 OR %s = 1 THEN", opts.Warning, opts.Required)
+	w("            RunBrakeCheck;")
+	w("        ENDIF")
+	w("    ENDPROC")
+	w("")
+	w("    PROC RunBrakeCheck()")
+	w("        MoveAbsJ jBrakeCheck, vService, fine, tool0;")
+	w("        TPWrite \"Cyclic brake check running\";")
+	w("        ! RobotWare SafeMove brake check of all axes of the motion task")
+	w("        BrakeCheck;")
+	w("        nBrakeChecks := nBrakeChecks + 1;")
+	w("        stLastBrakeCheck := CDate() + \" \" + CTime();")
+	w("        ErrWrite \\I, \"Brake check passed\", \"Cyclic brake check OK at \" + stLastBrakeCheck;")
+	w("    ERROR")
+	w("        ! A failed check leaves the robot in reduced speed until it passes;")
+	w("        ! never retry automatically")
+	w("        ErrWrite \"Brake check failed\", \"Call service. Robot limited until the brakes pass the check.\";")
+	w("        Stop;")
+	w("    ENDPROC")
+	if opts.Sync {
+		w("")
+		w("    ! Synchronization check: move to the sync position so SafeMove can")
+		w("    ! compare the measured position with the sync switch")
+		w("    PROC RunSyncCheck()")
+		w("        MoveAbsJ jSyncCheck, v100, fine, tool0;")
+		w("        WaitTime \\InPos, 0.5;")
+		w("        TPWrite \"Synchronization position reached\";")
+		w("        MoveAbsJ jBrakeCheck, vService, fine, tool0;")
+		w("    ENDPROC")
+	}
+	w("")
+	w("    ! Runs the service routine the PLC requests with giServiceReq, for")
+	w("    ! calls from the cell HMI instead of the FlexPendant")
+	w("    PROC ServiceRequest()")
+	w("        VAR num nService;")
+	w("        nService := giServiceReq;")
+	w("        TEST nService")
+	w("        CASE 0:")
+	w("            RETURN;")
+	w("        CASE SERVICE_BRAKECHECK:")
+	w("            RunBrakeCheck;")
+	if opts.Sync {
+		w("        CASE SERVICE_SYNCCHECK:")
+		w("            RunSyncCheck;")
+	}
+	w("        CASE SERVICE_POSITION:")
+	w("            MoveAbsJ jBrakeCheck, vService, fine, tool0;")
+	w("        DEFAULT:")
+	w("            ErrWrite \\W, \"Unknown service request\", \"giServiceReq = \" + NumToStr(nService, 0);")
+	w("        ENDTEST")
+	w("        ! Acknowledge so the PLC can clear its request")
+	w("        SetGO goServiceAck, nService;")
+	w("        WaitGI giServiceReq, 0 \\MaxTime:=10;")
+	w("        SetGO goServiceAck, 0;")
+	w("    ERROR")
+	w("        IF ERRNO = ERR_WAIT_MAXTIME THEN")
+	w("            SetGO goServiceAck, 0;")
+	w("            TRYNEXT;")
+	w("        ENDIF")
+	w("        RAISE;")
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String()
+}
+
+func brakeCheckGuidance(opts BrakeCheckOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("Scheduling:")
+	w("- SafeMove requires a brake check every %s h and warns %s h before expiry.", formatNum(opts.Interval), formatNum(opts.PreWarning))
+	w("  Choose the pre-warning longer than the longest stretch without a safe")
+	w("  point (batch, shift, unattended night run), or the check expires and")
+	w("  the robot drops to reduced speed mid-production.")
+	w("- Call BrakeCheckIfDue from main between parts, and ServiceRequest when")
+	w("  giServiceReq <> 0, only with the cell in a state where the robot may move")
+	w("  to jBrakeCheck.")
+	w("- Map %s and %s to the SafeMove brake check status outputs and", opts.Warning, opts.Required)
+	w("  configure giServiceReq/goServiceAck in EIO.cfg.")
+	w("- Record the interval, the brake check position and a passed check in the")
+	w("  safety validation report; auditors ask for all three.")
+	if opts.Sync {
+		w("- The synchronization check needs the sync switch mounted so the robot")
+		w("  activates it at jSyncCheck; verify the position on site before use.")
+	}
+	return b.String()
+}
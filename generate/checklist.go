@@ -0,0 +1,78 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+)
+
+// ChecklistOptions select the robot and the service visit a maintenance
+// checklist is made for
+type ChecklistOptions struct {
+	Robot        abb.Robot
+	Interval     string  // As given by the user, like 6m
+	Months       float64 // Service interval in months
+	HoursPerYear float64 // Operating hours per year for hour-based tasks
+}
+
+var checklistSections = map[string]string{
+	"safety":      "Safety functions",
+	"brakes":      "Brake test",
+	"lubrication": "Lubrication",
+	"battery":     "Battery",
+	"inspection":  "Inspection",
+	"cleaning":    "Cleaning",
+	"controller":  "Controller",
+}
+
+// Checklist returns a markdown maintenance checklist with the tasks due at
+// the service interval, grouped by category, with fields for sign-off
+func Checklist(opts ChecklistOptions) (string, error) {
+	tasks := abb.MaintenanceDue(opts.Robot, opts.Months, opts.HoursPerYear)
+	if len(tasks) == 0 {
+		return "", fmt.Errorf("no maintenance tasks are due within %s", opts.Interval)
+	}
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("# Maintenance checklist: %s", opts.Robot.Model)
+	w("")
+	w("Service interval: %s months. Hour-based tasks are counted at %s operating hours per year.", formatNum(math.Round(opts.Months*10)/10), formatNum(opts.HoursPerYear))
+	w("")
+	w("Serial number: ____________  Operating hours: ____________")
+	w("")
+	w("Date: ____________  Technician: ____________")
+
+	section := ""
+	for _, t := range tasks {
+		if t.Category != section {
+			section = t.Category
+			title, ok := checklistSections[section]
+			if !ok {
+				title = section
+			}
+			w("")
+			w("## %s", title)
+			w("")
+		}
+		line := fmt.Sprintf("- [ ] %s (every %s)", t.Task, t.Interval())
+		if t.Details != "" {
+			line += ". " + t.Details
+		}
+		w("%s", line)
+	}
+
+	w("")
+	w("## Sign-off")
+	w("")
+	w("Remarks: ________________________________________________")
+	w("")
+	w("Signature: ____________")
+	w("")
+	w("---")
+	w("")
+	w("Nominal intervals from the robot model database. The product manual of the installed variant and its operating conditions take precedence.")
+	return b.String(), nil
+}
@@ -0,0 +1,248 @@
+package generate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DispensePoint is one row of a dispensing datasheet
+type DispensePoint struct {
+	Flow        float64
+	Pressure    float64
+	Temperature float64
+}
+
+// Datasheet holds the measured flow curve of an adhesive or sealant
+type Datasheet struct {
+	Points       []DispensePoint
+	FlowUnit     string
+	PressureUnit string
+	HasTemp      bool
+}
+
+// DispenseOptions control the generated signal and module
+type DispenseOptions struct {
+	Source      string  // Datasheet file name, for the header comment
+	Module      string  // RAPID module name
+	Signal      string  // Analog output driving the pressure regulator
+	Temperature float64 // Used when the datasheet has several temperatures
+	MinVolts    float64 // Regulator input range
+	MaxVolts    float64
+	MaxPressure float64 // Regulator output at MaxVolts, 0 = highest datasheet pressure
+}
+
+var unitRe = regexp.MustCompile(`[(\[]([^)\]]+)[)\]]`)
+
+// ParseDatasheet reads a CSV datasheet with flow and pressure columns and an
+// optional temperature column. Columns are found by their header names;
+// semicolon-separated files with decimal commas are accepted.
+func ParseDatasheet(r io.Reader) (*Datasheet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	firstLine, _, _ := strings.Cut(text, "\n")
+	reader := csv.NewReader(strings.NewReader(text))
+	decimalComma := strings.Count(firstLine, ";") > strings.Count(firstLine, ",")
+	if decimalComma {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("datasheet needs a header row and at least two data rows")
+	}
+
+	sheet := &Datasheet{}
+	flowCol, pressureCol, tempCol := -1, -1, -1
+	for i, name := range rows[0] {
+		lower := strings.ToLower(name)
+		unit := ""
+		if m := unitRe.FindStringSubmatch(name); m != nil {
+			unit = strings.TrimSpace(m[1])
+		}
+		switch {
+		case strings.Contains(lower, "flow") || strings.Contains(lower, "rate"):
+			flowCol, sheet.FlowUnit = i, unit
+		case strings.Contains(lower, "press"):
+			pressureCol, sheet.PressureUnit = i, unit
+		case strings.Contains(lower, "temp"):
+			tempCol = i
+		}
+	}
+	if flowCol < 0 || pressureCol < 0 {
+		return nil, fmt.Errorf("datasheet needs columns named flow and pressure (found %s)", strings.Join(rows[0], ", "))
+	}
+	sheet.HasTemp = tempCol >= 0
+	if sheet.FlowUnit == "" {
+		sheet.FlowUnit = "ml/min"
+	}
+	if sheet.PressureUnit == "" {
+		sheet.PressureUnit = "bar"
+	}
+
+	value := func(row []string, col, line int) (float64, error) {
+		if col >= len(row) {
+			return 0, fmt.Errorf("line %d: missing column %d", line, col+1)
+		}
+		s := strings.TrimSpace(row[col])
+		if decimalComma {
+			s = strings.ReplaceAll(s, ",", ".")
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: invalid number %q", line, row[col])
+		}
+		return v, nil
+	}
+	for i, row := range rows[1:] {
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		var p DispensePoint
+		if p.Flow, err = value(row, flowCol, i+2); err != nil {
+			return nil, err
+		}
+		if p.Pressure, err = value(row, pressureCol, i+2); err != nil {
+			return nil, err
+		}
+		if tempCol >= 0 {
+			if p.Temperature, err = value(row, tempCol, i+2); err != nil {
+				return nil, err
+			}
+		}
+		sheet.Points = append(sheet.Points, p)
+	}
+	return sheet, nil
+}
+
+// Temperatures returns the distinct temperatures in the datasheet
+func (d *Datasheet) Temperatures() []float64 {
+	seen := make(map[float64]bool)
+	var temps []float64
+	for _, p := range d.Points {
+		if !seen[p.Temperature] {
+			seen[p.Temperature] = true
+			temps = append(temps, p.Temperature)
+		}
+	}
+	sort.Float64s(temps)
+	return temps
+}
+
+// Curve returns the flow/pressure points measured nearest to the temperature,
+// sorted by flow, and the temperature actually used
+func (d *Datasheet) Curve(temp float64) ([]DispensePoint, float64) {
+	temps := d.Temperatures()
+	used := temps[0]
+	for _, t := range temps {
+		if math.Abs(t-temp) < math.Abs(used-temp) {
+			used = t
+		}
+	}
+	var curve []DispensePoint
+	for _, p := range d.Points {
+		if p.Temperature == used {
+			curve = append(curve, p)
+		}
+	}
+	sort.Slice(curve, func(i, j int) bool { return curve[i].Flow < curve[j].Flow })
+	return curve, used
+}
+
+// DispenseCode returns the EIO.cfg signal definition and the RAPID parameter
+// module for the datasheet curve, plus warnings about the data
+func DispenseCode(sheet *Datasheet, opts DispenseOptions) (eio, module string, warnings []string, err error) {
+	curve, temp := sheet.Curve(opts.Temperature)
+	if len(curve) < 2 {
+		return "", "", nil, fmt.Errorf("need at least two datasheet points at %g °C", temp)
+	}
+	if sheet.HasTemp && len(sheet.Temperatures()) > 1 && temp != opts.Temperature {
+		warnings = append(warnings, fmt.Sprintf("no data at %g °C, using the %g °C curve", opts.Temperature, temp))
+	}
+	for i := 1; i < len(curve); i++ {
+		if curve[i].Flow == curve[i-1].Flow {
+			return "", "", nil, fmt.Errorf("duplicate flow value %g at %g °C", curve[i].Flow, temp)
+		}
+		if curve[i].Pressure < curve[i-1].Pressure {
+			warnings = append(warnings, fmt.Sprintf("pressure falls between %g and %g %s; check the datasheet", curve[i-1].Flow, curve[i].Flow, sheet.FlowUnit))
+		}
+	}
+
+	maxPressure := opts.MaxPressure
+	if maxPressure == 0 {
+		for _, p := range curve {
+			maxPressure = math.Max(maxPressure, p.Pressure)
+		}
+	}
+	for _, p := range curve {
+		if p.Pressure > maxPressure {
+			warnings = append(warnings, fmt.Sprintf("%g %s exceeds the regulator range of %g %s", p.Pressure, sheet.PressureUnit, maxPressure, sheet.PressureUnit))
+			break
+		}
+	}
+
+	// The logical value of the signal is the pressure, so RAPID code can
+	// write SetAO with the value from the lookup table directly
+	var e strings.Builder
+	fmt.Fprintf(&e, "# Dispensing pressure setpoint, %g-%g V = 0-%g %s\n", opts.MinVolts, opts.MaxVolts, maxPressure, sheet.PressureUnit)
+	e.WriteString("# Set -Device, -DeviceMap and the bit values to match the analog module\n")
+	e.WriteString("EIO_SIGNAL:\n\n")
+	fmt.Fprintf(&e, "      -Name \"%s\" -SignalType \"AO\" -Device \"\" -DeviceMap \"0-15\"\\\n", opts.Signal)
+	fmt.Fprintf(&e, "      -EncType \"UNSIGNED\" -MaxLog %s -MaxPhys %s -MaxPhysLimit %s\\\n",
+		formatNum(maxPressure), formatNum(opts.MaxVolts), formatNum(opts.MaxVolts))
+	fmt.Fprintf(&e, "      -MaxBitVal 65535 -MinLog 0 -MinPhys %s -MinPhysLimit %s -MinBitVal 0\n",
+		formatNum(opts.MinVolts), formatNum(opts.MinVolts))
+
+	flows := make([]string, len(curve))
+	pressures := make([]string, len(curve))
+	for i, p := range curve {
+		flows[i] = formatNum(p.Flow)
+		pressures[i] = formatNum(p.Pressure)
+	}
+
+	var m strings.Builder
+	fmt.Fprintf(&m, "MODULE %s\n", opts.Module)
+	fmt.Fprintf(&m, "    ! Dispensing parameters generated from %s\n", opts.Source)
+	if sheet.HasTemp {
+		fmt.Fprintf(&m, "    ! Material curve at %g °C\n", temp)
+	}
+	fmt.Fprintf(&m, "    ! Flow in %s, pressure in %s\n\n", sheet.FlowUnit, sheet.PressureUnit)
+	fmt.Fprintf(&m, "    CONST num nFlow{%d} := [%s];\n", len(curve), strings.Join(flows, ","))
+	fmt.Fprintf(&m, "    CONST num nPressure{%d} := [%s];\n\n", len(curve), strings.Join(pressures, ","))
+	m.WriteString("    ! Pressure needed for a flow, interpolated and clamped to the datasheet range\n")
+	m.WriteString("    FUNC num FlowToPressure(num flow)\n")
+	m.WriteString("        IF flow <= nFlow{1} RETURN nPressure{1};\n")
+	m.WriteString("        FOR i FROM 2 TO Dim(nFlow,1) DO\n")
+	m.WriteString("            IF flow <= nFlow{i} THEN\n")
+	m.WriteString("                RETURN nPressure{i-1} + (flow - nFlow{i-1}) * (nPressure{i} - nPressure{i-1}) / (nFlow{i} - nFlow{i-1});\n")
+	m.WriteString("            ENDIF\n")
+	m.WriteString("        ENDFOR\n")
+	m.WriteString("        RETURN nPressure{Dim(nPressure,1)};\n")
+	m.WriteString("    ENDFUNC\n\n")
+	m.WriteString("    ! Set the regulator for the requested flow\n")
+	m.WriteString("    PROC SetFlow(num flow)\n")
+	fmt.Fprintf(&m, "        SetAO %s, FlowToPressure(flow);\n", opts.Signal)
+	m.WriteString("    ENDPROC\n")
+	m.WriteString("ENDMODULE\n")
+
+	return e.String(), m.String(), warnings, nil
+}
+
+// formatNum writes a number without trailing zeros
+func formatNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
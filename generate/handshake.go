@@ -0,0 +1,277 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/timing"
+)
+
+// Handshake styles
+const (
+	StylePulse = "pulse"
+	StyleLevel = "level"
+)
+
+// HandshakeOptions describe a PLC-to-robot job handshake. Signals are the
+// request from the PLC, the completion from the robot and an optional fault
+// from the robot, in that order.
+type HandshakeOptions struct {
+	Signals []string
+	Style   string
+	Routine string  // RAPID routine doing the job
+	Timeout float64 // Seconds the robot waits for the PLC
+	Pulse   float64 // Pulse length in seconds
+}
+
+// Handshake is the matched code for both sides of the handshake
+type Handshake struct {
+	Rapid   string
+	ST      string
+	Diagram *timing.Diagram
+}
+
+// HandshakeCode generates the RAPID and PLC side of a handshake together
+// with its timing diagram
+func HandshakeCode(opts HandshakeOptions) (*Handshake, error) {
+	if len(opts.Signals) < 2 || len(opts.Signals) > 3 {
+		return nil, fmt.Errorf("give 2 or 3 signals: request, done and optionally fault")
+	}
+	for _, s := range opts.Signals {
+		if !stIdentifier(s) {
+			return nil, fmt.Errorf("%q is not a valid signal name", s)
+		}
+	}
+	if opts.Style != StylePulse && opts.Style != StyleLevel {
+		return nil, fmt.Errorf("unknown style %q (use pulse or level)", opts.Style)
+	}
+
+	h := &Handshake{Diagram: handshakeDiagram(opts)}
+	h.Rapid = handshakeRapid(opts)
+	h.ST = handshakeST(opts)
+	return h, nil
+}
+
+// stIdentifier reports whether s is usable as a variable name on both sides
+func stIdentifier(s string) bool {
+	if s == "" || len(s) > 28 {
+		return false
+	}
+	for i, c := range s {
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c != '_' && (c < '0' || c > '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// styleName capitalizes the style for headings
+func styleName(style string) string {
+	return strings.ToUpper(style[:1]) + style[1:]
+}
+
+// rapidSignal derives the robot I/O name, e.g. job_start -> diJobStart
+func rapidSignal(prefix, name string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	upper := true
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(c)))
+			upper = false
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func handshakeDiagram(opts HandshakeOptions) *timing.Diagram {
+	req, done := opts.Signals[0], opts.Signals[1]
+	d := &timing.Diagram{Title: fmt.Sprintf("%s handshake", styleName(opts.Style))}
+	d.AddSignal(req, "PLC")
+	d.AddSignal(done, "Robot")
+	if len(opts.Signals) == 3 {
+		d.AddSignal(opts.Signals[2], "Robot")
+	}
+	if opts.Style == StyleLevel {
+		d.Set(1, req, true, "PLC requests the job")
+		d.Set(3, done, true, "robot finished the job")
+		d.Set(4, req, false, "PLC acknowledges")
+		d.Set(5, done, false, "robot ready for the next request")
+	} else {
+		d.Set(1, req, true, fmt.Sprintf("PLC pulses the request (%gs)", opts.Pulse))
+		d.Set(2, req, false, "")
+		d.Set(4, done, true, fmt.Sprintf("robot pulses done (%gs)", opts.Pulse))
+		d.Set(5, done, false, "")
+	}
+	return d
+}
+
+func handshakeRapid(opts HandshakeOptions) string {
+	req := rapidSignal("di", opts.Signals[0])
+	done := rapidSignal("do", opts.Signals[1])
+	fault := ""
+	if len(opts.Signals) == 3 {
+		fault = rapidSignal("do", opts.Signals[2])
+	}
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("MODULE Handshake")
+	w("    ! %s handshake with the PLC, matched by FB_JobHandshake", styleName(opts.Style))
+	w("    ! %s: PLC -> robot, %s: robot -> PLC", req, done)
+	if fault != "" {
+		w("    ! %s: robot -> PLC, set when the job fails", fault)
+	}
+	w("    CONST num nHandshakeTimeout := %s;", formatNum(opts.Timeout))
+	w("")
+	w("    PROC JobHandshake()")
+	if opts.Style == StyleLevel {
+		w("        ! 1. Wait for the request")
+		w("        WaitDI %s, 1;", req)
+		if fault != "" {
+			w("        SetDO %s, 0;", fault)
+		}
+		w("        ! 2. Do the job")
+		w("        %s;", opts.Routine)
+		w("        ! 3. Report completion and hold it until the PLC removes the request")
+		w("        SetDO %s, 1;", done)
+		w("        WaitDI %s, 0 \\MaxTime:=nHandshakeTimeout;", req)
+		w("        ! 4. Ready for the next request")
+		w("        SetDO %s, 0;", done)
+	} else {
+		w("        ! 1. Wait for the request pulse")
+		w("        WaitDI %s, 1;", req)
+		if fault != "" {
+			w("        SetDO %s, 0;", fault)
+		}
+		w("        ! 2. Do the job")
+		w("        %s;", opts.Routine)
+		w("        ! 3. Make sure the request pulse has ended, then pulse done")
+		w("        WaitDI %s, 0 \\MaxTime:=nHandshakeTimeout;", req)
+		w("        PulseDO \\PLength:=%s, %s;", formatNum(opts.Pulse), done)
+	}
+	w("    ERROR")
+	w("        IF ERRNO = ERR_WAIT_MAXTIME THEN")
+	w("            ErrWrite \"Handshake\", \"%s not removed by the PLC within \" + NumToStr(nHandshakeTimeout, 0) + \" s\";", req)
+	if fault != "" {
+		w("            SetDO %s, 1;", fault)
+	}
+	w("            SetDO %s, 0;", done)
+	w("            RETURN;")
+	w("        ENDIF")
+	if fault != "" {
+		w("        SetDO %s, 1;", fault)
+	}
+	w("        RAISE;")
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String()
+}
+
+func handshakeST(opts HandshakeOptions) string {
+	req, done := opts.Signals[0], opts.Signals[1]
+	fault := ""
+	if len(opts.Signals) == 3 {
+		fault = opts.Signals[2]
+	}
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("FUNCTION_BLOCK FB_JobHandshake")
+	w("// %s handshake with the robot, matched by RAPID JobHandshake", styleName(opts.Style))
+	w("VAR_INPUT")
+	w("    Execute : BOOL;    // Rising edge starts a robot job")
+	w("    %s : BOOL;  // From robot", done)
+	if fault != "" {
+		w("    %s : BOOL;  // From robot", fault)
+	}
+	w("    Timeout : TIME := T#30S; // Longest expected job time")
+	w("END_VAR")
+	w("VAR_OUTPUT")
+	w("    %s : BOOL;  // To robot", req)
+	w("    Busy : BOOL;")
+	w("    Done : BOOL;")
+	w("    Error : BOOL;")
+	w("END_VAR")
+	w("VAR")
+	w("    Step : INT;")
+	w("    StartTrig : R_TRIG;")
+	if opts.Style == StylePulse {
+		w("    DoneTrig : R_TRIG;")
+		w("    RequestPulse : TP;")
+	}
+	w("    Watchdog : TON;")
+	w("END_VAR")
+	w("")
+	w("StartTrig(CLK := Execute);")
+	if opts.Style == StylePulse {
+		w("DoneTrig(CLK := %s);", done)
+		w("RequestPulse(IN := StartTrig.Q AND Step = 0, PT := T#%dMS);", int(opts.Pulse*1000))
+		w("%s := RequestPulse.Q;", req)
+	}
+	w("")
+	w("CASE Step OF")
+	w("    0: // Idle, keep Done/Error until Execute is removed")
+	w("        IF NOT Execute THEN")
+	w("            Done := FALSE;")
+	w("            Error := FALSE;")
+	w("        END_IF;")
+	w("        IF StartTrig.Q THEN")
+	w("            Done := FALSE;")
+	w("            Error := FALSE;")
+	if opts.Style == StyleLevel {
+		w("            %s := TRUE;", req)
+	}
+	w("            Step := 10;")
+	w("        END_IF;")
+	w("")
+	w("    10: // Wait for the robot to finish")
+	if fault != "" {
+		w("        IF %s THEN", fault)
+		if opts.Style == StyleLevel {
+			w("            %s := FALSE;", req)
+		}
+		w("            Error := TRUE;")
+		w("            Step := 0;")
+		if opts.Style == StyleLevel {
+			w("        ELSIF %s THEN", done)
+		} else {
+			w("        ELSIF DoneTrig.Q THEN")
+		}
+	} else if opts.Style == StyleLevel {
+		w("        IF %s THEN", done)
+	} else {
+		w("        IF DoneTrig.Q THEN")
+	}
+	if opts.Style == StyleLevel {
+		w("            %s := FALSE;", req)
+		w("            Step := 20;")
+		w("        END_IF;")
+		w("")
+		w("    20: // Wait for the robot to remove done")
+		w("        IF NOT %s THEN", done)
+	}
+	w("            Done := TRUE;")
+	w("            Step := 0;")
+	w("        END_IF;")
+	w("END_CASE;")
+	w("")
+	w("Watchdog(IN := Step <> 0, PT := Timeout);")
+	w("IF Watchdog.Q THEN")
+	if opts.Style == StyleLevel {
+		w("    %s := FALSE;", req)
+	}
+	w("    Error := TRUE;")
+	w("    Step := 0;")
+	w("END_IF;")
+	w("Busy := Step <> 0;")
+	w("END_FUNCTION_BLOCK")
+	return b.String()
+}
@@ -0,0 +1,228 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PositionerOptions describe a custom workpiece positioner with one or two
+// rotating axes, coordinated with the robot through a moving work object
+type PositionerOptions struct {
+	Unit   string  // Mechanical unit name, e.g. STN1
+	Axes   int     // 1 = turntable (eax_a), 2 = tilt (eax_a) and rotation (eax_b)
+	Ratio  float64 // Transmission from motor to table, sign gives the direction
+	Wobj   string
+	Module string
+}
+
+// Positioner is the RAPID module and the MOC.cfg entries for one unit
+type Positioner struct {
+	Rapid    string
+	MOC      string
+	Guidance string
+}
+
+// positionerAxes are the external axis components used by the unit, in order
+var positionerAxes = []string{"eax_a", "eax_b"}
+
+// PositionerCode generates coordinated motion code and the motion
+// configuration for a positioner
+func PositionerCode(opts PositionerOptions) (*Positioner, error) {
+	if opts.Axes < 1 || opts.Axes > 2 {
+		return nil, fmt.Errorf("positioners have 1 or 2 axes")
+	}
+	if opts.Ratio == 0 {
+		return nil, fmt.Errorf("the gear ratio must not be zero")
+	}
+	for _, s := range []string{opts.Unit, opts.Wobj, opts.Module} {
+		if !stIdentifier(s) {
+			return nil, fmt.Errorf("%q is not a valid RAPID name", s)
+		}
+	}
+	return &Positioner{
+		Rapid:    positionerRapid(opts),
+		MOC:      positionerMOC(opts),
+		Guidance: positionerGuidance(opts),
+	}, nil
+}
+
+// positionerExtax returns the extax part of a target with the given angles
+// on the unit's axes and 9E9 on the unused ones
+func positionerExtax(angles ...float64) string {
+	extax := []string{"9E9", "9E9", "9E9", "9E9", "9E9", "9E9"}
+	for i, a := range angles {
+		extax[i] = formatNum(a)
+	}
+	return "[" + strings.Join(extax, ",") + "]"
+}
+
+// positionerJoint names the single of an axis: the unit name for a
+// turntable, STN1_1 and STN1_2 for a two-axis positioner
+func positionerJoint(opts PositionerOptions, axis int) string {
+	if opts.Axes == 1 {
+		return opts.Unit
+	}
+	return fmt.Sprintf("%s_%d", opts.Unit, axis)
+}
+
+func positionerRapid(opts PositionerOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	home := make([]float64, opts.Axes)
+	sideB := make([]float64, opts.Axes)
+	sideB[opts.Axes-1] = 180
+	seam := make([]float64, opts.Axes)
+	seam[opts.Axes-1] = 45
+
+	w("MODULE %s", opts.Module)
+	if opts.Axes == 1 {
+		w("    ! Coordinated motion with turntable %s (eax_a)", opts.Unit)
+	} else {
+		w("    ! Coordinated motion with positioner %s: tilt eax_a, rotation eax_b", opts.Unit)
+	}
+	w("    ! The work object follows the unit, so targets taught in %s stay on", opts.Wobj)
+	w("    ! the part whatever the table angle. Calibrate the base frame of %s first.", opts.Unit)
+	w("")
+	w("    ! ufprog FALSE and ufmec make the user frame move with the unit")
+	w("    PERS wobjdata %s := [FALSE,FALSE,\"%s\",[[0,0,0],[1,0,0,0]],[[0,0,0],[1,0,0,0]]];", opts.Wobj, opts.Unit)
+	w("")
+	w("    ! Positioner only targets; the robot axes are ignored by MoveExtJ")
+	w("    CONST jointtarget jStnHome := [[9E9,9E9,9E9,9E9,9E9,9E9],%s];", positionerExtax(home...))
+	w("    CONST jointtarget jStnSideB := [[9E9,9E9,9E9,9E9,9E9,9E9],%s];", positionerExtax(sideB...))
+	w("")
+	w("    ! Example target on the part, taught with the unit at %s degrees", formatNum(seam[opts.Axes-1]))
+	w("    CONST robtarget pSeamStart := [[0,0,100],[0,0,1,0],[0,0,0,0],%s];", positionerExtax(seam...))
+	w("    CONST robtarget pSeamEnd := [[100,0,100],[0,0,1,0],[0,0,0,0],%s];", positionerExtax(seam...))
+	w("")
+	w("    ! ActUnit and DeactUnit stop the robot in a fine point")
+	w("    PROC StationOn()")
+	w("        ActUnit %s;", opts.Unit)
+	w("    ENDPROC")
+	w("")
+	w("    PROC StationOff()")
+	w("        DeactUnit %s;", opts.Unit)
+	w("    ENDPROC")
+	w("")
+	if opts.Axes == 1 {
+		w("    ! Turn the table without moving the robot")
+		w("    PROC IndexStation(num rotation)")
+	} else {
+		w("    ! Tilt and turn the positioner without moving the robot")
+		w("    PROC IndexStation(num tilt, num rotation)")
+	}
+	w("        VAR jointtarget jTarget;")
+	w("        jTarget := CJointT();")
+	if opts.Axes == 2 {
+		w("        jTarget.extax.eax_a := tilt;")
+		w("        jTarget.extax.eax_b := rotation;")
+	} else {
+		w("        jTarget.extax.eax_a := rotation;")
+	}
+	w("        MoveExtJ jTarget, vrot50, fine;")
+	w("    ENDPROC")
+	w("")
+	w("    ! Coordinated example: the positioner moves to the target angle while")
+	w("    ! the TCP follows the seam. Replace tool0 with the process tool.")
+	w("    PROC SeamExample()")
+	w("        StationOn;")
+	w("        MoveExtJ jStnHome, vrot50, fine;")
+	w("        MoveJ Offs(pSeamStart,0,0,50), v500, z10, tool0 \\WObj:=%s;", opts.Wobj)
+	w("        MoveL pSeamStart, v100, fine, tool0 \\WObj:=%s;", opts.Wobj)
+	w("        MoveL pSeamEnd, v20, fine, tool0 \\WObj:=%s;", opts.Wobj)
+	w("        MoveL Offs(pSeamEnd,0,0,50), v500, z10, tool0 \\WObj:=%s;", opts.Wobj)
+	w("        MoveExtJ jStnSideB, vrot50, fine;")
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String()
+}
+
+func positionerMOC(opts PositionerOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("MOC:CFG_1.0:6:0::")
+	w("# Custom positioner %s. Standard IRBP positioners are installed from the", opts.Unit)
+	w("# RobotWare media pool instead; merge these instances with the existing")
+	w("# MOC.cfg (Load parameters > Add new parameters) and restart.")
+	w("")
+	w("#")
+	w("MECHANICAL_UNIT:")
+	w("")
+	singles := ""
+	for axis := 1; axis <= opts.Axes; axis++ {
+		singles += fmt.Sprintf(" -use_single_%d \"%s\"", axis-1, positionerJoint(opts, axis))
+	}
+	w("      -name \"%s\" -use_activation_relay \"\"%s\\", opts.Unit, singles)
+	w("      -allow_move_of_user_frame -activate_at_start_up")
+	w("")
+	w("#")
+	w("SINGLE:")
+	for axis := 1; axis <= opts.Axes; axis++ {
+		name := positionerJoint(opts, axis)
+		w("")
+		if axis == 2 {
+			// The rotation axis is carried by the tilt axis
+			w("      -name \"%s\" -use_single_type \"%s\" -use_joint \"%s\"\\", name, name, name)
+			w("      -base_frame_coordinated \"%s\"", positionerJoint(opts, 1))
+		} else {
+			w("      -name \"%s\" -use_single_type \"%s\" -use_joint \"%s\"", name, name, name)
+		}
+	}
+	w("")
+	w("#")
+	w("SINGLE_TYPE:")
+	for axis := 1; axis <= opts.Axes; axis++ {
+		w("")
+		w("      -name \"%s\" -mechanics \"EXT_ROT\"", positionerJoint(opts, axis))
+	}
+	w("")
+	w("#")
+	w("JOINT:")
+	for axis := 1; axis <= opts.Axes; axis++ {
+		name := positionerJoint(opts, axis)
+		w("")
+		w("      -name \"%s\" -logical_axis %d -use_arm \"%s\" -use_transmission \"%s\"", name, axis+6, name, name)
+	}
+	w("")
+	w("#")
+	w("ARM:")
+	for axis := 1; axis <= opts.Axes; axis++ {
+		w("")
+		if opts.Axes == 2 && axis == 1 {
+			// Tilt axis, typically limited by the mechanics to about ±90°
+			w("      -name \"%s\" -upper_joint_bound 1.5708 -lower_joint_bound -1.5708", positionerJoint(opts, axis))
+		} else {
+			w("      -name \"%s\" -upper_joint_bound 6.28319 -lower_joint_bound -6.28319", positionerJoint(opts, axis))
+		}
+	}
+	w("")
+	w("#")
+	w("TRANSMISSION:")
+	for axis := 1; axis <= opts.Axes; axis++ {
+		w("")
+		w("      -name \"%s\" -rotating_move  -transm_joint %s", positionerJoint(opts, axis), formatNum(opts.Ratio))
+	}
+	return b.String()
+}
+
+func positionerGuidance(opts PositionerOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("Commissioning:")
+	w("- Load the MOC.cfg entries; motor, drive and measurement link data come")
+	w("  from the motor supplier and are not part of this template.")
+	var logical []string
+	for axis := 1; axis <= opts.Axes; axis++ {
+		logical = append(logical, fmt.Sprintf("%s = logical axis %d (%s)", positionerJoint(opts, axis), axis+6, positionerAxes[axis-1]))
+	}
+	w("- %s; no other unit of the task may use these.", strings.Join(logical, ", "))
+	w("- Check the direction with a small jog: a wrong sign of -transm_joint")
+	w("  turns the table the other way and breaks coordination.")
+	w("- Fine calibrate the axes, then calibrate the base frame of %s", opts.Unit)
+	w("  (Calibration > %s > Base Frame, 4 points on a reference tip on the table).", opts.Unit)
+	w("- Teach targets in %s; they stay valid when the part is turned.", opts.Wobj)
+	if opts.Axes == 2 {
+		w("- Two-axis positioners: calibrate the base frame of %s too, so the", positionerJoint(opts, 2))
+		w("  offset between tilt and rotation axis is known.")
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+// Package generate produces PLC and robot code from templates
+package generate
+
+import (
+	"fmt"
+
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+// SensorTypes lists the sensor types supported by SensorCode
+var SensorTypes = []string{"digital", "analog"}
+
+// SensorCode returns ladder, RAPID and S7 snippets reacting to a sensor.
+// Headings and comments follow the selected display language.
+func SensorCode(sensorType, action string) (string, error) {
+	switch sensorType {
+	case "digital":
+		return digitalSensorCode(action)
+	case "analog":
+		return analogSensorCode(action)
+	default:
+		return "", fmt.Errorf("unknown sensor type %q (available: digital, analog)", sensorType)
+	}
+}
+
+func digitalSensorCode(action string) (string, error) {
+	switch action {
+	case "when_on":
+		return fmt.Sprintf(`
+%s:
+|--[INPUT]--|--[OUTPUT]--|
+
+%s:
+IF DI_01 = 1 THEN
+    ! %s
+ENDIF
+
+%s:
+IF "Input_Bit" THEN
+    // %[3]s
+END_IF`, i18n.T("generate.ladder"), i18n.T("generate.robot"), i18n.T("generate.action"), i18n.T("generate.s7")), nil
+	default:
+		return "", fmt.Errorf("unknown action for digital sensor")
+	}
+}
+
+func analogSensorCode(action string) (string, error) {
+	return fmt.Sprintf(`
+%s:
+|--[ANALOG_IN]--|--[SCALE]--|--[COMPARE]--|--[OUTPUT]--|
+
+%s:
+IF AI_01 > SET_POINT THEN
+    ! %s
+ENDIF
+
+%s:
+IF "Analog_Input" > "Set_Point" THEN
+    // %[3]s
+END_IF`, i18n.T("generate.ladder"), i18n.T("generate.robot"), i18n.T("generate.action"), i18n.T("generate.s7")), nil
+}
@@ -0,0 +1,266 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxSweepTrials bounds the number of trials of a sweep
+const MaxSweepTrials = 1000
+
+// SweepParam is a process parameter and the values a sweep tries
+type SweepParam struct {
+	Name   string
+	Values []float64
+}
+
+var sweepRangeRe = regexp.MustCompile(`^([-+]?[0-9.]+)\.\.([-+]?[0-9.]+)(?:step([0-9.]+))?$`)
+
+// ParseSweepParam reads name=from..to[stepS] (step 1 by default) or
+// name=v1,v2,v3
+func ParseSweepParam(spec string) (SweepParam, error) {
+	name, values, ok := strings.Cut(spec, "=")
+	p := SweepParam{Name: strings.TrimSpace(name)}
+	if !ok || !stIdentifier(p.Name) {
+		return p, fmt.Errorf("invalid parameter %q; use name=5..15step2 or name=5,8,12", spec)
+	}
+	values = strings.ReplaceAll(values, " ", "")
+	if m := sweepRangeRe.FindStringSubmatch(values); m != nil {
+		from, err1 := strconv.ParseFloat(m[1], 64)
+		to, err2 := strconv.ParseFloat(m[2], 64)
+		step := 1.0
+		var err3 error
+		if m[3] != "" {
+			step, err3 = strconv.ParseFloat(m[3], 64)
+		}
+		if err1 != nil || err2 != nil || err3 != nil || step <= 0 || to < from {
+			return p, fmt.Errorf("invalid range %q for %s", values, p.Name)
+		}
+		if (to-from)/step >= MaxSweepTrials {
+			return p, fmt.Errorf("%s has more than %d values", p.Name, MaxSweepTrials)
+		}
+		// Count the steps instead of adding them up, so 0.1 steps end exactly
+		for i := 0; from+float64(i)*step <= to+step*1e-9; i++ {
+			p.Values = append(p.Values, math.Round((from+float64(i)*step)*1e6)/1e6)
+		}
+		return p, nil
+	}
+	for _, v := range strings.Split(values, ",") {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid value %q for %s", v, p.Name)
+		}
+		p.Values = append(p.Values, f)
+	}
+	return p, nil
+}
+
+// SweepOptions describe a trial series
+type SweepOptions struct {
+	Module  string
+	Routine string // Process routine run for every trial
+	Params  []SweepParam
+	Grid    bool // Every combination; otherwise one parameter at a time around the first values
+	Repeat  int  // Runs per combination
+	Pause   bool // Ask the operator after every trial
+}
+
+// SweepTrials lists the parameter values of every trial, in run order
+func SweepTrials(opts SweepOptions) [][]float64 {
+	var trials [][]float64
+	if opts.Grid {
+		trials = [][]float64{{}}
+		for _, p := range opts.Params {
+			var next [][]float64
+			for _, t := range trials {
+				for _, v := range p.Values {
+					next = append(next, append(append([]float64{}, t...), v))
+				}
+			}
+			trials = next
+		}
+	} else {
+		// One factor at a time: the others stay at their first value
+		base := make([]float64, len(opts.Params))
+		for i, p := range opts.Params {
+			base[i] = p.Values[0]
+		}
+		trials = append(trials, base)
+		for i, p := range opts.Params {
+			for _, v := range p.Values[1:] {
+				t := append([]float64{}, base...)
+				t[i] = v
+				trials = append(trials, t)
+			}
+		}
+	}
+	var repeated [][]float64
+	for _, t := range trials {
+		for r := 0; r < max(1, opts.Repeat); r++ {
+			repeated = append(repeated, t)
+		}
+	}
+	return repeated
+}
+
+// SweepCode returns a RAPID module that runs the process routine once per
+// trial with the parameters set, labels every trial on the FlexPendant and
+// logs it to a CSV file on the controller
+func SweepCode(opts SweepOptions) (string, error) {
+	if len(opts.Params) == 0 {
+		return "", fmt.Errorf("no parameters to sweep")
+	}
+	seen := map[string]bool{}
+	for _, p := range opts.Params {
+		if seen[strings.ToLower(p.Name)] {
+			return "", fmt.Errorf("parameter %s given twice", p.Name)
+		}
+		seen[strings.ToLower(p.Name)] = true
+	}
+	trials := SweepTrials(opts)
+	if len(trials) > MaxSweepTrials {
+		return "", fmt.Errorf("%d trials; at most %d", len(trials), MaxSweepTrials)
+	}
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	mode := "one parameter at a time"
+	if opts.Grid {
+		mode = "every combination"
+	}
+	w("MODULE %s", opts.Module)
+	w("    ! Parameter sweep for process trials, %s, %d trials:", mode, len(trials))
+	for _, p := range opts.Params {
+		w("    !   %s: %s", p.Name, joinNums(p.Values, ", "))
+	}
+	w("    ! %s reads the parameters below; every trial is logged to", opts.Routine)
+	w("    ! HOME:/%s.csv. Set nStartTrial to resume an interrupted series.", opts.Module)
+	w("")
+	for i, p := range opts.Params {
+		w("    PERS num %s := %s;", p.Name, formatNum(trials[0][i]))
+	}
+	w("    PERS string stTrialLabel := \"\";")
+	w("    PERS num nStartTrial := 1;")
+	w("    CONST string stTrialRoutine := \"%s\";", opts.Routine)
+	w("")
+	w("    ! Trial table, one row per trial: %s", joinNames(opts.Params))
+	rows := make([]string, len(trials))
+	for i, t := range trials {
+		rows[i] = "[" + joinNums(t, ",") + "]"
+	}
+	w("    CONST num nTrials := %d;", len(trials))
+	w("    CONST num nTable{%d,%d} := [\n        %s];", len(trials), len(opts.Params), strings.Join(rows, ",\n        "))
+	w("")
+	w("    PROC RunSweep()")
+	w("        VAR iodev ioLog;")
+	if opts.Pause {
+		w("        VAR num nAnswer;")
+	}
+	w("        VAR num nTrial;")
+	w("")
+	w("        Open \"HOME:\" \\File:=\"%s.csv\", ioLog \\Append;", opts.Module)
+	w("        IF nStartTrial = 1 THEN")
+	// Written in parts, RAPID strings hold at most 80 characters
+	w("            Write ioLog, \"trial,date,time\" \\NoNewLine;")
+	for _, p := range opts.Params {
+		w("            Write ioLog, \",%s\" \\NoNewLine;", p.Name)
+	}
+	w("            Write ioLog, \"\";")
+	w("        ENDIF")
+	w("        nTrial := nStartTrial;")
+	w("        WHILE nTrial <= nTrials DO")
+	w("            SetTrial nTrial;")
+	w("            TPWrite stTrialLabel;")
+	w("            Write ioLog, NumToStr(nTrial,0) + \",\" + CDate() + \",\" + CTime() \\NoNewLine;")
+	for i, p := range opts.Params {
+		w("            Write ioLog, \",\" + NumToStr(%s,%d) \\NoNewLine;", p.Name, decimals(opts.Params[i].Values))
+	}
+	w("            Write ioLog, \"\";")
+	w("            %%stTrialRoutine%%;")
+	if opts.Pause {
+		w("            TPReadFK nAnswer, \"Trial done. Inspect the part.\", \"Next\", \"Repeat\", stEmpty, stEmpty, \"Stop\";")
+		w("            TEST nAnswer")
+		w("            CASE 1:")
+		w("                Incr nTrial;")
+		w("            CASE 5:")
+		w("                nStartTrial := nTrial + 1;")
+		w("                Close ioLog;")
+		w("                RETURN;")
+		w("            ENDTEST")
+	} else {
+		w("            Incr nTrial;")
+	}
+	w("            nStartTrial := nTrial;")
+	w("        ENDWHILE")
+	w("        nStartTrial := 1;")
+	w("        Close ioLog;")
+	w("        TPWrite \"Sweep complete: \" \\Num:=nTrials;")
+	w("    ERROR")
+	w("        Close ioLog;")
+	w("        RAISE;")
+	w("    ENDPROC")
+	w("")
+	w("    ! Sets the parameters of a trial and its label, e.g. for marking the part")
+	w("    PROC SetTrial(num nTrial)")
+	for i, p := range opts.Params {
+		w("        %s := nTable{nTrial,%d};", p.Name, i+1)
+	}
+	label := fmt.Sprintf("\"T\" + NumToStr(nTrial,0) + \"/%d\"", len(trials))
+	length := len(fmt.Sprintf("T%d/%d", len(trials), len(trials)))
+	for _, p := range opts.Params {
+		length += len(p.Name) + 2 + valueWidth(p.Values)
+	}
+	if length <= 80 {
+		for _, p := range opts.Params {
+			label += fmt.Sprintf(" + \" %s=\" + NumToStr(%s,%d)", p.Name, p.Name, decimals(p.Values))
+		}
+	}
+	w("        stTrialLabel := %s;", label)
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String(), nil
+}
+
+// decimals is the number of decimals needed to print all values
+func decimals(values []float64) int {
+	n := 0
+	for _, v := range values {
+		s := formatNum(v)
+		if _, frac, ok := strings.Cut(s, "."); ok {
+			n = max(n, len(frac))
+		}
+	}
+	return n
+}
+
+// valueWidth is the longest printed value
+func valueWidth(values []float64) int {
+	n := 0
+	for _, v := range values {
+		n = max(n, len(strconv.FormatFloat(v, 'f', decimals(values), 64)))
+	}
+	return n
+}
+
+func joinNums(values []float64, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = formatNum(v)
+	}
+	return strings.Join(parts, sep)
+}
+
+func paramNames(params []SweepParam) []string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func joinNames(params []SweepParam) string {
+	return strings.Join(paramNames(params), ", ")
+}
@@ -0,0 +1,380 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestParam is a required parameter of a routine under test
+type TestParam struct {
+	Mode  string // "" (IN), INOUT, VAR or PERS
+	Type  string
+	Name  string
+	Array bool
+}
+
+// TestRoutine is a routine the test module calls. Inputs and Outputs are
+// the signals it uses.
+type TestRoutine struct {
+	Name    string
+	Returns string // Data type for a FUNC, empty for a PROC
+	Params  []TestParam
+	Moves   bool
+	Inputs  []string
+	Outputs []TestSignal
+}
+
+// TestSignal is an I/O signal with its RAPID type such as signaldo
+type TestSignal struct {
+	Name string
+	Type string
+}
+
+// TestOptions describe the module under test
+type TestOptions struct {
+	Module   string
+	Routines []TestRoutine
+	Inputs   []TestSignal // Inputs the tests drive through stub outputs
+	MaxCases int          // Per routine
+}
+
+// TestSuite is the generated test module and the I/O configuration for
+// the stubs
+type TestSuite struct {
+	Name    string
+	Rapid   string
+	EIO     string
+	Skipped []string // Routines left out, with the reason
+}
+
+// boundaries are the values each parameter type is tested with; the first
+// is the baseline the other parameters keep while one is varied
+var boundaries = map[string][]string{
+	"num":    {"0", "1", "-1", "0.001", "1000000"},
+	"dnum":   {"0", "1", "-1", "4294967295"},
+	"bool":   {"FALSE", "TRUE"},
+	"string": {`""`, `"A"`, `"` + strings.Repeat("X", 80) + `"`},
+	"byte":   {"0", "1", "255"},
+}
+
+// stubPrefix names the RAPID variable driving an input through AliasIO
+const stubPrefix = "stub_"
+
+// TestCode generates a RAPID test module calling every testable routine
+// with boundary inputs
+func TestCode(opts TestOptions) (*TestSuite, error) {
+	if !stIdentifier(opts.Module) {
+		return nil, fmt.Errorf("%q is not a valid module name", opts.Module)
+	}
+	if opts.MaxCases <= 0 {
+		opts.MaxCases = 12
+	}
+	name := "Test" + opts.Module
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	suite := &TestSuite{Name: name}
+	var testable []TestRoutine
+	for _, r := range opts.Routines {
+		if reason := untestable(r); reason != "" {
+			suite.Skipped = append(suite.Skipped, r.Name+": "+reason)
+			continue
+		}
+		testable = append(testable, r)
+	}
+	if len(testable) == 0 {
+		return nil, fmt.Errorf("%s has no routine the tests can call", opts.Module)
+	}
+	var stubs []TestSignal
+	for _, s := range opts.Inputs {
+		if s.Type == "signaldi" {
+			stubs = append(stubs, s)
+		} else {
+			suite.Skipped = append(suite.Skipped, s.Name+": only digital inputs can be stubbed; set it from the I/O simulator")
+		}
+	}
+	suite.Rapid = testModule(name, opts, testable, stubs)
+	suite.EIO = stubEIO(stubs)
+	return suite, nil
+}
+
+func untestable(r TestRoutine) string {
+	if strings.EqualFold(r.Name, "main") {
+		return "main runs the whole program"
+	}
+	if r.Returns != "" && boundaries[r.Returns] == nil {
+		return "returns " + r.Returns + ", which has no boundary values"
+	}
+	for _, p := range r.Params {
+		if p.Array {
+			return "array parameter " + p.Name
+		}
+		if boundaries[p.Type] == nil {
+			return "parameter " + p.Name + " of type " + p.Type + " needs hand-made test data"
+		}
+	}
+	return ""
+}
+
+// testCases returns the argument lists for a routine: the baseline, then
+// each parameter through its other boundaries
+func testCases(r TestRoutine, max int) [][]string {
+	base := make([]string, len(r.Params))
+	for i, p := range r.Params {
+		base[i] = boundaries[p.Type][0]
+	}
+	cases := [][]string{base}
+	for i, p := range r.Params {
+		for _, v := range boundaries[p.Type][1:] {
+			if len(cases) == max {
+				return cases
+			}
+			args := append([]string(nil), base...)
+			args[i] = v
+			cases = append(cases, args)
+		}
+	}
+	return cases
+}
+
+// testArgName is the variable passed for INOUT, VAR and PERS parameters
+func testArgName(r TestRoutine, p TestParam) string {
+	name := "t" + r.Name + "_" + p.Name
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// caseLabel names a case after its call, shortening long string literals
+// so report lines stay readable
+func caseLabel(routine string, args []string) string {
+	shown := make([]string, len(args))
+	for i, a := range args {
+		if len(a) > 12 {
+			a = fmt.Sprintf(`"%d chars"`, len(a)-2)
+		}
+		shown[i] = strings.ReplaceAll(a, `"`, `'`)
+	}
+	label := routine + "(" + strings.Join(shown, ", ") + ")"
+	if len(label) > 60 {
+		// Leaves room for the check name appended to the case
+		label = label[:57] + "..."
+	}
+	return label
+}
+
+func signalValue(s TestSignal) string {
+	switch s.Type {
+	case "signalgo":
+		return fmt.Sprintf("NumToStr(GOutput(%s), 0)", s.Name)
+	case "signalao":
+		return fmt.Sprintf("NumToStr(AOutput(%s), 3)", s.Name)
+	default:
+		return fmt.Sprintf("NumToStr(DOutput(%s), 0)", s.Name)
+	}
+}
+
+func testModule(name string, opts TestOptions, routines []TestRoutine, stubs []TestSignal) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	w("MODULE %s", name)
+	w("    ! Regression tests for %s. Load both modules on a virtual controller", opts.Module)
+	w("    ! and run RunTests. Results go to the FlexPendant and %s.", testReportFile(opts.Module))
+	w("    ! Expected values start as stTODO: the first run reports what the")
+	w("    ! routines return; paste the values you have verified into the Check")
+	w("    ! calls to turn them into assertions.")
+	w("    CONST string stTODO := \"?\";")
+	w("    VAR num nPass;")
+	w("    VAR num nFail;")
+	w("    VAR num nTodo;")
+	w("    VAR string stCase;")
+	w("    VAR num nErrno;")
+	w("    VAR iodev ioReport;")
+	if len(stubs) > 0 {
+		w("")
+		w("    ! Stub outputs cross-connected to the inputs the module reads (see the")
+		w("    ! EIO stub configuration). Aliased at run time, so this module also")
+		w("    ! loads on a controller without the stubs.")
+		for _, s := range stubs {
+			w("    VAR signaldo %s;", stubName(s.Name))
+		}
+	}
+	for _, r := range routines {
+		for _, p := range r.Params {
+			if p.Mode == "PERS" {
+				w("    PERS %s %s := %s;", p.Type, testArgName(r, p), boundaries[p.Type][0])
+			}
+		}
+	}
+
+	w("")
+	w("    PROC RunTests()")
+	w("        TestSetup;")
+	for _, r := range routines {
+		w("        Test_%s;", r.Name)
+	}
+	w("        TestReport;")
+	w("    ENDPROC")
+	w("")
+	w("    PROC TestSetup()")
+	w("        nPass := 0;")
+	w("        nFail := 0;")
+	w("        nTodo := 0;")
+	w("        Open \"%s\", ioReport \\Write;", testReportFile(opts.Module))
+	w("        Write ioReport, \"TestResult %s \" + CDate() + \" \" + CTime();", opts.Module)
+	for _, s := range stubs {
+		w("        AliasIO \"%s_Stub\", %s;", s.Name, stubName(s.Name))
+		w("        SetDO %s, 0;", stubName(s.Name))
+	}
+	w("    ENDPROC")
+	w("")
+	w("    ! Compares the value a case produced with the expected one")
+	w("    PROC Check(string name, string actual, string expected)")
+	w("        ! Written in parts: a RAPID string holds at most 80 characters")
+	w("        IF expected = stTODO THEN")
+	w("            nTodo := nTodo + 1;")
+	w("            Write ioReport, \"TODO \" + name \\NoNewLine;")
+	w("            Write ioReport, \" = \" + actual;")
+	w("        ELSEIF actual = expected THEN")
+	w("            nPass := nPass + 1;")
+	w("            Write ioReport, \"PASS \" + name;")
+	w("        ELSE")
+	w("            nFail := nFail + 1;")
+	w("            Write ioReport, \"FAIL \" + name \\NoNewLine;")
+	w("            Write ioReport, \": expected \" + expected \\NoNewLine;")
+	w("            Write ioReport, \", got \" + actual;")
+	w("            TPWrite \"FAIL \" + name;")
+	w("        ENDIF")
+	w("    ENDPROC")
+	w("")
+	w("    ! Passes when the case returned without raising an error")
+	w("    PROC CheckCompleted()")
+	w("        IF nErrno = 0 THEN")
+	w("            nPass := nPass + 1;")
+	w("            Write ioReport, \"PASS \" + stCase;")
+	w("        ELSE")
+	w("            nFail := nFail + 1;")
+	w("            Write ioReport, \"FAIL \" + stCase + \": error \" + NumToStr(nErrno, 0);")
+	w("            TPWrite \"FAIL \" + stCase + \": error \" + NumToStr(nErrno, 0);")
+	w("        ENDIF")
+	w("    ENDPROC")
+	w("")
+	w("    PROC TestReport()")
+	w("        Write ioReport, \"Passed \" + NumToStr(nPass, 0) + \", failed \" + NumToStr(nFail, 0) + \", todo \" + NumToStr(nTodo, 0);")
+	w("        Close ioReport;")
+	w("        TPWrite \"TestResult %s: \" + NumToStr(nPass, 0) + \" passed, \" + NumToStr(nFail, 0) + \" failed, \" + NumToStr(nTodo, 0) + \" todo\";", opts.Module)
+	w("    ENDPROC")
+
+	for _, r := range routines {
+		w("")
+		if r.Moves {
+			w("    ! %s moves the robot: run on a virtual controller only", r.Name)
+		}
+		w("    PROC Test_%s()", r.Name)
+		var locals []TestParam
+		for _, p := range r.Params {
+			if p.Mode == "INOUT" || p.Mode == "VAR" {
+				locals = append(locals, p)
+				w("        VAR %s %s;", p.Type, testArgName(r, p))
+			}
+		}
+		if r.Returns != "" {
+			w("        VAR %s result;", r.Returns)
+		}
+		for _, s := range r.Inputs {
+			w("        ! Set %s to the state this test needs", s)
+			w("        SetDO %s, 0;", stubName(s))
+		}
+		for i, args := range testCases(r, opts.MaxCases) {
+			label := caseLabel(r.Name, args)
+			if i > 0 || len(locals) > 0 || r.Returns != "" || len(r.Inputs) > 0 {
+				w("")
+			}
+			w("        stCase := \"%s\";", label)
+			w("        nErrno := 0;")
+			call := make([]string, len(args))
+			for i, p := range r.Params {
+				call[i] = args[i]
+				if p.Mode != "" {
+					w("        %s := %s;", testArgName(r, p), args[i])
+					call[i] = testArgName(r, p)
+				}
+			}
+			switch {
+			case r.Returns != "":
+				w("        result := %s(%s);", r.Name, strings.Join(call, ", "))
+			case len(call) > 0:
+				w("        %s %s;", r.Name, strings.Join(call, ", "))
+			default:
+				w("        %s;", r.Name)
+			}
+			w("        CheckCompleted;")
+			var checks []string
+			if r.Returns != "" {
+				checks = append(checks, "Check stCase + \" result\", ValToStr(result), stTODO;")
+			}
+			for _, p := range locals {
+				checks = append(checks, fmt.Sprintf("Check stCase + \" %s\", ValToStr(%s), stTODO;", p.Name, testArgName(r, p)))
+			}
+			for _, s := range r.Outputs {
+				checks = append(checks, fmt.Sprintf("Check stCase + \" %s\", %s, stTODO;", s.Name, signalValue(s)))
+			}
+			if len(checks) > 0 {
+				w("        IF nErrno = 0 THEN")
+				for _, c := range checks {
+					w("            %s", c)
+				}
+				w("        ENDIF")
+			}
+		}
+		w("    ERROR")
+		w("        nErrno := ERRNO;")
+		w("        TRYNEXT;")
+		w("    ENDPROC")
+	}
+	w("ENDMODULE")
+	return b.String()
+}
+
+func stubName(signal string) string {
+	name := stubPrefix + signal
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+func testReportFile(module string) string {
+	return "HOME:/TestResult_" + module + ".txt"
+}
+
+// stubEIO configures a stub output per input, cross-connected so that
+// setting the stub drives the input. For the virtual controller only.
+func stubEIO(stubs []TestSignal) string {
+	if len(stubs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("EIO:CFG_1.0:6:1::")
+	w("# Test stubs for the virtual controller. The inputs must not be assigned")
+	w("# to a device there, or the device overwrites the cross connection.")
+	w("#")
+	w("EIO_SIGNAL:")
+	for _, s := range stubs {
+		w("")
+		w("      -Name \"%s\" -SignalType \"DI\"", s.Name)
+		w("")
+		w("      -Name \"%s_Stub\" -SignalType \"DO\"", s.Name)
+	}
+	w("")
+	w("#")
+	w("EIO_CROSS:")
+	for _, s := range stubs {
+		w("")
+		w("      -Name \"Stub_%s\" -Res \"%s\" -Act1 \"%s_Stub\"", s.Name, s.Name, s.Name)
+	}
+	return b.String()
+}
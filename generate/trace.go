@@ -0,0 +1,348 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceOptions describe the scanner connection, code format and record target
+type TraceOptions struct {
+	Module   string
+	Station  string
+	Reader   string // socket or serial
+	Scanner  string // IP address for socket readers, channel (COM1:) for serial
+	Port     int
+	Trigger  string // Sent to socket scanners before reading; empty for push mode
+	Pattern  string // Code format mask, see PatternHelp
+	Record   string // file or mes
+	File     string
+	MES      string
+	MESPort  int
+	MaxTries int
+}
+
+// PatternHelp explains the code format mask
+const PatternHelp = "9 = digit, A = upper-case letter, X = digit or letter, ? = any character, anything else must match literally"
+
+// checkPattern rejects masks that cannot be turned into code
+func checkPattern(pattern string) error {
+	for _, c := range pattern {
+		if c < 32 || c > 126 || c == '"' || c == '\\' || c == '\'' {
+			return fmt.Errorf("pattern character %q is not supported", c)
+		}
+	}
+	if len(pattern) > 80 {
+		return fmt.Errorf("pattern is longer than 80 characters")
+	}
+	return nil
+}
+
+// TraceCode returns a RAPID module that reads, validates and records part
+// codes, and S7 SCL blocks doing the same on the PLC side
+func TraceCode(opts TraceOptions) (rapidCode, scl string, err error) {
+	if opts.Reader != "socket" && opts.Reader != "serial" {
+		return "", "", fmt.Errorf("unknown reader %q (use socket or serial)", opts.Reader)
+	}
+	if opts.Record != "file" && opts.Record != "mes" {
+		return "", "", fmt.Errorf("unknown record target %q (use file or mes)", opts.Record)
+	}
+	if err := checkPattern(opts.Pattern); err != nil {
+		return "", "", err
+	}
+	return traceRapid(opts), traceSCL(opts), nil
+}
+
+func traceRapid(opts TraceOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	socket := opts.Reader == "socket"
+	mes := opts.Record == "mes"
+
+	w("MODULE %s", opts.Module)
+	w("    ! Part traceability: read the code, check its format and record the result")
+	if opts.Pattern != "" {
+		w("    ! Code format %q (%s)", opts.Pattern, PatternHelp)
+	}
+	w("    ! Record: date;time;station;code;result")
+	w("    CONST string strStation := \"%s\";", opts.Station)
+	if socket {
+		w("    CONST string strScannerIP := \"%s\";", opts.Scanner)
+		w("    CONST num nScannerPort := %d;", opts.Port)
+		w("    VAR socketdev sockScanner;")
+	} else {
+		w("    CONST string strScannerPort := \"%s\";", opts.Scanner)
+		w("    VAR iodev ioScanner;")
+	}
+	if mes {
+		w("    CONST string strMesIP := \"%s\";", opts.MES)
+		w("    CONST num nMesPort := %d;", opts.MESPort)
+		w("    VAR socketdev sockMes;")
+	}
+	if mes {
+		w("    ! Local record file, used when the MES does not answer")
+	}
+	w("    CONST string strTraceFile := \"%s\";", opts.File)
+	w("    CONST num nMaxTries := %d;", opts.MaxTries)
+	w("    VAR iodev ioTrace;")
+	w("")
+
+	w("    ! Read a code and validate it; every attempt is recorded")
+	w("    FUNC bool ScanPart(INOUT string strCode)")
+	w("        VAR num nTries := 0;")
+	w("")
+	w("        WHILE nTries < nMaxTries DO")
+	w("            Incr nTries;")
+	w("            strCode := ReadCode();")
+	w("            IF strCode = \"\" THEN")
+	w("                WriteTrace \"\", \"NOREAD\";")
+	w("            ELSEIF NOT CodeValid(strCode) THEN")
+	w("                WriteTrace strCode, \"INVALID\";")
+	w("            ELSE")
+	w("                WriteTrace strCode, \"OK\";")
+	w("                RETURN TRUE;")
+	w("            ENDIF")
+	w("        ENDWHILE")
+	w("        RETURN FALSE;")
+	w("    ENDFUNC")
+	w("")
+
+	w("    ! Returns the code without line terminators, or \"\" on timeout")
+	w("    FUNC string ReadCode()")
+	w("        VAR string strRaw;")
+	w("        VAR num nEnd;")
+	w("")
+	if socket {
+		w("        SocketClose sockScanner;")
+		w("        SocketCreate sockScanner;")
+		w("        SocketConnect sockScanner, strScannerIP, nScannerPort \\Time:=5;")
+		if opts.Trigger != "" {
+			w("        SocketSend sockScanner \\Str:=\"%s\\0D\\0A\";", opts.Trigger)
+		}
+		w("        SocketReceive sockScanner \\Str:=strRaw \\Time:=5;")
+		w("        SocketClose sockScanner;")
+	} else {
+		w("        Close ioScanner;")
+		w("        Open strScannerPort, ioScanner \\Read;")
+		w("        strRaw := ReadStr(ioScanner \\RemoveCR \\Time:=5);")
+		w("        Close ioScanner;")
+	}
+	w("        nEnd := StrFind(strRaw, 1, \"\\0D\\0A\");")
+	w("        RETURN StrPart(strRaw, 1, nEnd - 1);")
+	w("    ERROR")
+	if socket {
+		w("        IF ERRNO = ERR_SOCK_TIMEOUT OR ERRNO = ERR_SOCK_CLOSED THEN")
+		w("            SocketClose sockScanner;")
+	} else {
+		w("        IF ERRNO = ERR_DEV_MAXTIME OR ERRNO = ERR_FILEACC THEN")
+		w("            Close ioScanner;")
+	}
+	w("            RETURN \"\";")
+	w("        ENDIF")
+	w("        RAISE;")
+	w("    ENDFUNC")
+	w("")
+
+	w("    ! Check the code against the expected format")
+	w("    FUNC bool CodeValid(string strCode)")
+	if opts.Pattern == "" {
+		w("        IF StrLen(strCode) = 0 RETURN FALSE;")
+		w("        RETURN StrFind(strCode, 1, \";\") > StrLen(strCode);")
+	} else {
+		w("        IF StrLen(strCode) <> %d RETURN FALSE;", len(opts.Pattern))
+		for i, c := range opts.Pattern {
+			pos := i + 1
+			switch c {
+			case '9':
+				w("        IF NOT StrMemb(strCode, %d, STR_DIGIT) RETURN FALSE;", pos)
+			case 'A':
+				w("        IF NOT StrMemb(strCode, %d, STR_UPPER) RETURN FALSE;", pos)
+			case 'X':
+				w("        IF NOT (StrMemb(strCode, %d, STR_DIGIT) OR StrMemb(strCode, %[1]d, STR_UPPER)) RETURN FALSE;", pos)
+			case '?':
+			default:
+				w("        IF StrPart(strCode, %d, 1) <> \"%c\" RETURN FALSE;", pos, c)
+			}
+		}
+		w("        RETURN TRUE;")
+	}
+	w("    ENDFUNC")
+	w("")
+
+	w("    ! Append a traceability record")
+	w("    PROC WriteTrace(string strCode, string strResult)")
+	w("        VAR string strLine;")
+	if mes {
+		w("        VAR string strAck;")
+	}
+	w("")
+	w("        strLine := CDate() + \";\" + CTime() + \";\" + strStation + \";\" + strCode + \";\" + strResult;")
+	if mes {
+		w("        SocketClose sockMes;")
+		w("        SocketCreate sockMes;")
+		w("        SocketConnect sockMes, strMesIP, nMesPort \\Time:=3;")
+		w("        SocketSend sockMes \\Str:=strLine + \"\\0D\\0A\";")
+		w("        SocketReceive sockMes \\Str:=strAck \\Time:=3;")
+		w("        SocketClose sockMes;")
+		w("        IF StrPart(strAck, 1, 3) = \"ACK\" RETURN;")
+		w("        ErrWrite \\W, \"Traceability\", \"MES did not acknowledge, record kept in \" + strTraceFile;")
+		w("        WriteTraceFile strLine;")
+		w("    ERROR")
+		w("        IF ERRNO = ERR_SOCK_TIMEOUT OR ERRNO = ERR_SOCK_CLOSED THEN")
+		w("            SocketClose sockMes;")
+		w("            ErrWrite \\W, \"Traceability\", \"MES not reachable, record kept in \" + strTraceFile;")
+		w("            WriteTraceFile strLine;")
+		w("            RETURN;")
+		w("        ENDIF")
+		w("        RAISE;")
+	} else {
+		w("        WriteTraceFile strLine;")
+	}
+	w("    ENDPROC")
+	w("")
+	w("    PROC WriteTraceFile(string strLine)")
+	w("        Open strTraceFile, ioTrace \\Append;")
+	w("        Write ioTrace, strLine;")
+	w("        Close ioTrace;")
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String()
+}
+
+func traceSCL(opts TraceOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	maxLen := 80
+	if opts.Pattern != "" {
+		maxLen = len(opts.Pattern)
+	}
+
+	w("FUNCTION \"CheckTraceCode\" : Bool")
+	w("{ S7_Optimized_Access := 'TRUE' }")
+	if opts.Pattern != "" {
+		w("// Code format '%s' (%s)", opts.Pattern, PatternHelp)
+	}
+	w("VAR_INPUT")
+	w("    Code : String;")
+	w("END_VAR")
+	w("BEGIN")
+	w("    #CheckTraceCode := FALSE;")
+	if opts.Pattern == "" {
+		w("    IF LEN(#Code) = 0 OR FIND(IN1 := #Code, IN2 := ';') > 0 THEN")
+		w("        RETURN;")
+		w("    END_IF;")
+	} else {
+		w("    IF LEN(#Code) <> %d THEN", len(opts.Pattern))
+		w("        RETURN;")
+		w("    END_IF;")
+		for i, c := range opts.Pattern {
+			pos := i + 1
+			var cond string
+			switch c {
+			case '9':
+				cond = fmt.Sprintf("#Code[%d] < '0' OR #Code[%[1]d] > '9'", pos)
+			case 'A':
+				cond = fmt.Sprintf("#Code[%d] < 'A' OR #Code[%[1]d] > 'Z'", pos)
+			case 'X':
+				cond = fmt.Sprintf("(#Code[%d] < '0' OR #Code[%[1]d] > '9') AND (#Code[%[1]d] < 'A' OR #Code[%[1]d] > 'Z')", pos)
+			case '?':
+				continue
+			default:
+				cond = fmt.Sprintf("#Code[%d] <> '%c'", pos, c)
+			}
+			w("    IF %s THEN", cond)
+			w("        RETURN;")
+			w("    END_IF;")
+		}
+	}
+	w("    #CheckTraceCode := TRUE;")
+	w("END_FUNCTION")
+	w("")
+
+	w("FUNCTION_BLOCK \"TraceScanner\"")
+	w("{ S7_Optimized_Access := 'TRUE' }")
+	if opts.Reader == "socket" {
+		w("// Receives codes from a TCP scanner at %s:%d", opts.Scanner, opts.Port)
+		w("// Set Connection.RemoteAddress and RemotePort in the instance DB")
+	} else {
+		w("// Receives codes from the serial scanner through a CM PtP module")
+	}
+	w("VAR_INPUT")
+	w("    Enable : Bool;")
+	if opts.Reader == "socket" {
+		w("    Connection : TCON_IP_v4;")
+	} else {
+		w("    Port : PORT;")
+	}
+	w("END_VAR")
+	w("VAR_OUTPUT")
+	w("    NewCode : Bool;")
+	w("    Code : String[%d];", maxLen)
+	w("    Valid : Bool;")
+	w("    Error : Bool;")
+	w("    Status : Word;")
+	w("END_VAR")
+	w("VAR")
+	if opts.Reader == "socket" {
+		w("    Receiver : TRCV_C;")
+	} else {
+		w("    Receiver : Receive_P2P;")
+	}
+	w("    Buffer : Array[0..%d] of Byte;", maxLen+1)
+	w("    Received : UInt;")
+	w("END_VAR")
+	w("BEGIN")
+	if opts.Reader == "socket" {
+		w("    #Receiver(EN_R := #Enable, CONT := #Enable, LEN := 0, ADHOC := TRUE,")
+		w("              CONNECT := #Connection, DATA := #Buffer,")
+		w("              ERROR => #Error, STATUS => #Status);")
+		w("    #NewCode := #Receiver.NDR;")
+		w("    #Received := UDINT_TO_UINT(#Receiver.RCVD_LEN);")
+	} else {
+		w("    #Receiver(PORT := #Port, BUFFER := #Buffer,")
+		w("              ERROR => #Error, STATUS => #Status);")
+		w("    #NewCode := #Receiver.NDR AND #Enable;")
+		w("    #Received := #Receiver.LENGTH;")
+	}
+	w("    IF #NewCode THEN")
+	w("        // Drop CR/LF terminators")
+	w("        WHILE #Received > 0 AND (#Buffer[#Received - 1] = 16#0D OR #Buffer[#Received - 1] = 16#0A) DO")
+	w("            #Received := #Received - 1;")
+	w("        END_WHILE;")
+	w("        Chars_TO_Strg(Chars := #Buffer, pChars := 0, Cnt := #Received, Strg => #Code);")
+	w("        #Valid := \"CheckTraceCode\"(#Code);")
+	w("    END_IF;")
+	w("END_FUNCTION_BLOCK")
+	w("")
+
+	w("DATA_BLOCK \"TraceLog\"")
+	w("{ S7_Optimized_Access := 'TRUE' }")
+	w("// Ring buffer of the last records for the MES or HMI to collect")
+	w("VAR")
+	w("    Next : Int;")
+	w("    Entry : Array[0..99] of Struct")
+	w("        Stamp : DTL;")
+	w("        Code : String[%d];", maxLen)
+	w("        Valid : Bool;")
+	w("    END_STRUCT;")
+	w("END_VAR")
+	w("BEGIN")
+	w("END_DATA_BLOCK")
+	w("")
+
+	w("FUNCTION \"TraceRecord\" : Void")
+	w("{ S7_Optimized_Access := 'TRUE' }")
+	w("VAR_INPUT")
+	w("    Code : String;")
+	w("    Valid : Bool;")
+	w("END_VAR")
+	w("VAR_TEMP")
+	w("    Result : Int;")
+	w("END_VAR")
+	w("BEGIN")
+	w("    #Result := RD_LOC_T(OUT => \"TraceLog\".Entry[\"TraceLog\".Next].Stamp);")
+	w("    \"TraceLog\".Entry[\"TraceLog\".Next].Code := #Code;")
+	w("    \"TraceLog\".Entry[\"TraceLog\".Next].Valid := #Valid;")
+	w("    \"TraceLog\".Next := (\"TraceLog\".Next + 1) MOD 100;")
+	w("END_FUNCTION")
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VisionFields are the result fields a camera can report; other names in the
+// field list are read and ignored
+var VisionFields = []string{"ok", "x", "y", "angle", "score"}
+
+// VisionOptions describe the camera connection and result format
+type VisionOptions struct {
+	Module    string
+	Protocol  string   // tcp or udp
+	Address   string   // Camera IP address
+	Port      int      // Camera port
+	RobotIP   string   // Local address to bind for UDP
+	Trigger   string   // Command sent to trigger an image
+	Fields    []string // Order of the comma-separated result fields
+	MinScore  float64
+	MaxOffset float64 // Largest accepted X/Y correction in mm
+	MaxTries  int
+}
+
+// visionVars maps result fields to the module variables that hold them
+var visionVars = map[string]string{
+	"ok":    "nVisOK",
+	"x":     "nVisX",
+	"y":     "nVisY",
+	"angle": "nVisAngle",
+	"score": "nVisScore",
+}
+
+// VisionModule returns a RAPID module that triggers a camera over a socket,
+// parses and validates its result with retries and corrects a work object
+// with the found part position
+func VisionModule(opts VisionOptions) (string, error) {
+	udp := false
+	switch strings.ToLower(opts.Protocol) {
+	case "", "tcp":
+	case "udp":
+		udp = true
+	default:
+		return "", fmt.Errorf("unknown protocol %q (use tcp or udp)", opts.Protocol)
+	}
+	has := make(map[string]bool)
+	for _, f := range opts.Fields {
+		has[f] = true
+	}
+	if !has["x"] || !has["y"] {
+		return "", fmt.Errorf("the result fields must include x and y")
+	}
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	w("MODULE %s", opts.Module)
+	w("    ! Camera-guided picking: trigger, parse, validate and correct wobjPart")
+	w("    ! Camera reply: \"%s\" terminated by CR/LF", strings.Join(opts.Fields, ","))
+	w("    ! Teach wobjCam.uframe to the camera calibration frame and pick targets in wobjPart")
+	w("    CONST string strCamIP := \"%s\";", opts.Address)
+	w("    CONST num nCamPort := %d;", opts.Port)
+	if udp {
+		w("    CONST string strRobotIP := \"%s\";", opts.RobotIP)
+	}
+	w("    CONST string strTrigger := \"%s\\0D\\0A\";", opts.Trigger)
+	w("    CONST num nMinScore := %s;", formatNum(opts.MinScore))
+	w("    CONST num nMaxOffset := %s;", formatNum(opts.MaxOffset))
+	w("    CONST num nMaxTries := %d;", opts.MaxTries)
+	w("    VAR socketdev sockCam;")
+	w("    VAR bool bCamConnected := FALSE;")
+	w("    VAR num nVisOK := 1;")
+	w("    VAR num nVisX;")
+	w("    VAR num nVisY;")
+	w("    VAR num nVisAngle;")
+	w("    VAR num nVisScore := 1;")
+	w("    PERS wobjdata wobjCam := [FALSE,TRUE,\"\",[[0,0,0],[1,0,0,0]],[[0,0,0],[1,0,0,0]]];")
+	w("    PERS wobjdata wobjPart := [FALSE,TRUE,\"\",[[0,0,0],[1,0,0,0]],[[0,0,0],[1,0,0,0]]];")
+	w("")
+
+	w("    ! Open the camera socket if it is not connected")
+	w("    PROC CamConnect()")
+	w("        IF bCamConnected RETURN;")
+	w("        SocketClose sockCam;")
+	if udp {
+		w("        SocketCreate sockCam \\UDP;")
+		w("        SocketBind sockCam, strRobotIP, nCamPort;")
+	} else {
+		w("        SocketCreate sockCam;")
+		w("        SocketConnect sockCam, strCamIP, nCamPort \\Time:=5;")
+	}
+	w("        bCamConnected := TRUE;")
+	w("    ENDPROC")
+	w("")
+
+	w("    ! Trigger the camera until a valid result arrives; corrects wobjPart.oframe")
+	w("    FUNC bool CamLocate()")
+	w("        VAR string strResult;")
+	if udp {
+		w("        VAR string strFromIP;")
+		w("        VAR num nFromPort;")
+	}
+	w("        VAR num nTries := 0;")
+	w("        VAR bool bValid := FALSE;")
+	w("")
+	w("        WHILE NOT bValid AND nTries < nMaxTries DO")
+	w("            Incr nTries;")
+	w("            strResult := \"\";")
+	w("            CamConnect;")
+	if udp {
+		w("            SocketSendTo sockCam, strCamIP, nCamPort \\Str:=strTrigger;")
+		w("            SocketReceiveFrom sockCam \\Str:=strResult, strFromIP, nFromPort \\Time:=3;")
+	} else {
+		w("            SocketSend sockCam \\Str:=strTrigger;")
+		w("            SocketReceive sockCam \\Str:=strResult \\Time:=3;")
+	}
+	w("            IF CamParse(strResult) bValid := CamValid();")
+	w("            IF NOT bValid WaitTime 0.2;")
+	w("        ENDWHILE")
+	w("")
+	w("        IF bValid THEN")
+	w("            wobjPart.uframe := wobjCam.uframe;")
+	w("            wobjPart.oframe.trans := [nVisX, nVisY, 0];")
+	w("            wobjPart.oframe.rot := OrientZYX(nVisAngle, 0, 0);")
+	w("        ELSE")
+	w("            ErrWrite \\W, \"Vision\", \"No valid part after \" + NumToStr(nTries, 0) + \" tries\";")
+	w("        ENDIF")
+	w("        RETURN bValid;")
+	w("    ERROR")
+	w("        IF ERRNO = ERR_SOCK_TIMEOUT OR ERRNO = ERR_SOCK_CLOSED THEN")
+	w("            bCamConnected := FALSE;")
+	w("            IF nTries < nMaxTries TRYNEXT;")
+	w("        ENDIF")
+	w("        RAISE;")
+	w("    ENDFUNC")
+	w("")
+
+	w("    ! Split the result into its fields; FALSE if a field is missing or not a number")
+	w("    FUNC bool CamParse(string strResult)")
+	w("        VAR num nField{%d};", len(opts.Fields))
+	w("        VAR num nStart := 1;")
+	w("        VAR num nEnd;")
+	w("")
+	w("        FOR i FROM 1 TO %d DO", len(opts.Fields))
+	w("            IF nStart > StrLen(strResult) RETURN FALSE;")
+	w("            nEnd := StrFind(strResult, nStart, \",;\\0D\\0A\");")
+	w("            IF NOT StrToVal(StrPart(strResult, nStart, nEnd - nStart), nField{i}) RETURN FALSE;")
+	w("            nStart := nEnd + 1;")
+	w("        ENDFOR")
+	for i, f := range opts.Fields {
+		if v, ok := visionVars[f]; ok {
+			w("        %s := nField{%d};", v, i+1)
+		}
+	}
+	w("        RETURN TRUE;")
+	w("    ENDFUNC")
+	w("")
+
+	w("    ! Reject results that are not found, uncertain or out of range")
+	w("    FUNC bool CamValid()")
+	w("        IF nVisOK <> 1 RETURN FALSE;")
+	w("        IF nVisScore < nMinScore RETURN FALSE;")
+	w("        IF Abs(nVisX) > nMaxOffset OR Abs(nVisY) > nMaxOffset RETURN FALSE;")
+	w("        RETURN TRUE;")
+	w("    ENDFUNC")
+	w("ENDMODULE")
+	return b.String(), nil
+}
@@ -0,0 +1,151 @@
+package generate
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+// The weld parameter table is stored as JSON so welding engineers can review
+// it without reading Go
+//
+//go:embed data/weld.json
+var weldFS embed.FS
+
+// WeldParams are starting values for one process, material and plate thickness
+type WeldParams struct {
+	Thickness  float64 `json:"thickness"` // Plate thickness in mm
+	Wire       float64 `json:"wire"`      // Wire diameter in mm
+	Current    float64 `json:"current"`   // A
+	Voltage    float64 `json:"voltage"`   // V
+	WireFeed   float64 `json:"wirefeed"`  // m/min
+	Speed      float64 `json:"speed"`     // Travel speed in mm/s
+	WeaveWidth float64 `json:"weave_width"`
+}
+
+type weldMaterial struct {
+	Gas   string       `json:"gas"`
+	Table []WeldParams `json:"table"`
+}
+
+// defaultWeldMaterial is used when no material is given for a process
+var defaultWeldMaterial = map[string]string{
+	"MAG": "steel",
+	"MIG": "aluminium",
+}
+
+var (
+	weldOnce  sync.Once
+	weldTable map[string]map[string]weldMaterial
+)
+
+func loadWeldTable() map[string]map[string]weldMaterial {
+	weldOnce.Do(func() {
+		data, err := weldFS.ReadFile("data/weld.json")
+		if err != nil {
+			panic(fmt.Sprintf("generate: missing embedded weld table: %v", err))
+		}
+		if err := json.Unmarshal(data, &weldTable); err != nil {
+			panic(fmt.Sprintf("generate: invalid embedded weld table: %v", err))
+		}
+	})
+	return weldTable
+}
+
+// WeldProcesses returns the processes and their materials in the table
+func WeldProcesses() map[string][]string {
+	result := make(map[string][]string)
+	for process, materials := range loadWeldTable() {
+		for material := range materials {
+			result[process] = append(result[process], material)
+		}
+		sort.Strings(result[process])
+	}
+	return result
+}
+
+// WeldLookup returns parameters for a plate thickness, interpolating between
+// table rows. The wire diameter is taken from the nearest row. An empty
+// material selects the usual material for the process.
+func WeldLookup(process, material string, thickness float64) (WeldParams, string, error) {
+	process = strings.ToUpper(process)
+	materials, ok := loadWeldTable()[process]
+	if !ok {
+		return WeldParams{}, "", fmt.Errorf("unknown process %q", process)
+	}
+	if material == "" {
+		material = defaultWeldMaterial[process]
+	}
+	m, ok := materials[strings.ToLower(material)]
+	if !ok {
+		return WeldParams{}, "", fmt.Errorf("no %s data for material %q", process, material)
+	}
+	table := m.Table
+	first, last := table[0], table[len(table)-1]
+	if thickness < first.Thickness || thickness > last.Thickness {
+		return WeldParams{}, "", fmt.Errorf("%s %s data covers %g-%g mm plate", process, material, first.Thickness, last.Thickness)
+	}
+	for i := 1; i < len(table); i++ {
+		lo, hi := table[i-1], table[i]
+		if thickness > hi.Thickness {
+			continue
+		}
+		f := (thickness - lo.Thickness) / (hi.Thickness - lo.Thickness)
+		lerp := func(a, b float64) float64 { return a + (b-a)*f }
+		p := WeldParams{
+			Thickness:  thickness,
+			Wire:       lo.Wire,
+			Current:    lerp(lo.Current, hi.Current),
+			Voltage:    lerp(lo.Voltage, hi.Voltage),
+			WireFeed:   lerp(lo.WireFeed, hi.WireFeed),
+			Speed:      lerp(lo.Speed, hi.Speed),
+			WeaveWidth: lerp(lo.WeaveWidth, hi.WeaveWidth),
+		}
+		if f > 0.5 {
+			p.Wire = hi.Wire
+		}
+		return p, m.Gas, nil
+	}
+	return first, m.Gas, nil
+}
+
+// WeldData returns seamdata, welddata and weavedata declarations for the
+// process. The component layout is that of RobotWare 6 Arc with a standard
+// I/O welder; other equipment configurations order arcdata differently.
+func WeldData(process, material string, thickness float64) (string, error) {
+	p, gas, err := WeldLookup(process, material, thickness)
+	if err != nil {
+		return "", err
+	}
+	if material == "" {
+		material = defaultWeldMaterial[strings.ToUpper(process)]
+	}
+	suffix := fmt.Sprintf("%s_%gmm", strings.ToUpper(process), thickness)
+	suffix = strings.ReplaceAll(suffix, ".", "_")
+
+	arc := fmt.Sprintf("[0,0,%.1f,%.1f,0,%.0f,0,0,0]", p.Voltage, p.WireFeed, p.Current)
+	shape := 0
+	if p.WeaveWidth > 0 {
+		shape = 1
+	}
+	weaveLength := 0.0
+	if shape > 0 {
+		weaveLength = p.WeaveWidth * 0.8
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "! %s\n", i18n.T("generate.weld_starting_values"))
+	fmt.Fprintf(&b, "! %s %s, %g mm plate, fillet weld, %.1f mm wire, gas %s\n", strings.ToUpper(process), strings.ToLower(material), thickness, p.Wire, gas)
+	fmt.Fprintf(&b, "! Approx. %.0f A, %.1f V, wire feed %.1f m/min, travel speed %.1f mm/s\n", p.Current, p.Voltage, p.WireFeed, p.Speed)
+	b.WriteString("! Layout: RobotWare 6 Arc, standard I/O welder. Check the component order\n")
+	b.WriteString("! against the seamdata/welddata defaults of your arc equipment.\n")
+	fmt.Fprintf(&b, "TASK PERS seamdata sm%s := [0,0.3,[0,0,0,0,0,0,0,0,0],0,0,0,0,0,[0,0,0,0,0,0,0,0,0],0,0,[0,0,0,0,0,0,0,0,0],0,0,[0,0,0,0,0,0,0,0,0],0.5];\n", suffix)
+	fmt.Fprintf(&b, "TASK PERS welddata wd%s := [%.1f,0,%s,[0,0,0,0,0,0,0,0,0]];\n", suffix, p.Speed, arc)
+	fmt.Fprintf(&b, "TASK PERS weavedata wv%s := [%d,0,%.1f,%.1f,0,0,0,0,0,0,0,0,0,0,0];\n", suffix, shape, weaveLength, p.WeaveWidth)
+	return b.String(), nil
+}
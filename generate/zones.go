@@ -0,0 +1,218 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Zone interlock methods
+const (
+	ZoneSignals    = "signals"
+	ZoneWorldZones = "worldzones"
+)
+
+// ZoneOptions describe shared zones in a multi-robot cell. With world zones
+// the in-zone outputs are set by the controller from the TCP position
+// instead of by the program.
+type ZoneOptions struct {
+	Robots  int
+	Zones   int
+	Method  string
+	Timeout float64 // Seconds before a waiting robot reports the zone as blocked
+}
+
+// ZoneInterlock is the matched code for all robots and the PLC
+type ZoneInterlock struct {
+	Rapid   []string // One module per robot
+	ST      string
+	Signals string // I/O list
+}
+
+// ZoneInterlockCode generates the zone request/permit semaphore for every
+// robot and the PLC function block arbitrating it
+func ZoneInterlockCode(opts ZoneOptions) (*ZoneInterlock, error) {
+	if opts.Robots < 2 || opts.Robots > 8 {
+		return nil, fmt.Errorf("zone interlocks need 2 to 8 robots")
+	}
+	if opts.Zones < 1 || opts.Zones > 16 {
+		return nil, fmt.Errorf("give 1 to 16 zones")
+	}
+	if opts.Method != ZoneSignals && opts.Method != ZoneWorldZones {
+		return nil, fmt.Errorf("unknown method %q (use signals or worldzones)", opts.Method)
+	}
+	z := &ZoneInterlock{ST: zoneArbiterST(opts), Signals: zoneSignalList(opts)}
+	for r := 1; r <= opts.Robots; r++ {
+		z.Rapid = append(z.Rapid, zoneRapid(opts, r))
+	}
+	return z, nil
+}
+
+// zoneSignal names a robot I/O signal of a zone: Req and In are robot
+// outputs, Perm is the permit input from the PLC, e.g. doR1ReqZ2
+func zoneSignal(kind string, robot, zone int) string {
+	prefix := "do"
+	if kind == "Perm" {
+		prefix = "di"
+	}
+	return fmt.Sprintf("%sR%d%sZ%d", prefix, robot, kind, zone)
+}
+
+func zoneRapid(opts ZoneOptions, robot int) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	world := opts.Method == ZoneWorldZones
+
+	w("MODULE ZoneInterlock")
+	w("    ! Shared zone interlock for robot %d of %d, arbitrated by FB_ZoneArbiter in the PLC", robot, opts.Robots)
+	w("    ! Enter zones in ascending number and leave in reverse order: the fixed")
+	w("    ! order keeps two robots from each holding a zone the other waits for.")
+	w("    ! The move before EnterZone must end in a fine point or a zone small")
+	w("    ! enough that the robot cannot cut into the zone while waiting.")
+	w("    CONST num nZones := %d;", opts.Zones)
+	w("    CONST num nZoneTimeout := %s;", formatNum(opts.Timeout))
+	w("    VAR bool bHeld{%d};", opts.Zones)
+	if world {
+		w("")
+		w("    ! Zone boxes in world coordinates, shared by all robots of the cell")
+		for zone := 1; zone <= opts.Zones; zone++ {
+			w("    CONST pos posZone%dLow := [0,0,0];", zone)
+			w("    CONST pos posZone%dHigh := [0,0,0];", zone)
+		}
+		for zone := 1; zone <= opts.Zones; zone++ {
+			w("    VAR wzstationary wzZone%d;", zone)
+		}
+		w("")
+		w("    ! POWER ON event routine: the controller sets the in-zone outputs from")
+		w("    ! the TCP position, independent of program execution")
+		w("    PROC ZoneSetup()")
+		w("        VAR shapedata shape;")
+		for zone := 1; zone <= opts.Zones; zone++ {
+			w("        WZBoxDef \\Inside, shape, posZone%dLow, posZone%dHigh;", zone, zone)
+			w("        WZDOSet \\Stat, wzZone%d \\Inside, shape, %s, 1;", zone, zoneSignal("In", robot, zone))
+		}
+		w("    ENDPROC")
+	}
+
+	w("")
+	w("    PROC EnterZone(num zone)")
+	w("        VAR bool bTimeout;")
+	w("        FOR i FROM zone + 1 TO nZones DO")
+	w("            IF bHeld{i} THEN")
+	w("                ErrWrite \"Zone order\", \"Zone \" + NumToStr(zone, 0) + \" requested while holding zone \" + NumToStr(i, 0);")
+	w("                Stop;")
+	w("            ENDIF")
+	w("        ENDFOR")
+	w("        TEST zone")
+	for zone := 1; zone <= opts.Zones; zone++ {
+		w("        CASE %d:", zone)
+		w("            SetDO %s, 1;", zoneSignal("Req", robot, zone))
+		w("            WaitDI %s, 1 \\MaxTime:=nZoneTimeout \\TimeFlag:=bTimeout;", zoneSignal("Perm", robot, zone))
+		w("            IF bTimeout THEN")
+		w("                ErrWrite \\W, \"Zone blocked\", \"Waiting for zone %d, held by another robot\";", zone)
+		w("                WaitDI %s, 1;", zoneSignal("Perm", robot, zone))
+		w("            ENDIF")
+		if !world {
+			w("            SetDO %s, 1;", zoneSignal("In", robot, zone))
+		}
+	}
+	w("        DEFAULT:")
+	w("            ErrWrite \"Zone number\", \"No zone \" + NumToStr(zone, 0);")
+	w("            Stop;")
+	w("        ENDTEST")
+	w("        bHeld{zone} := TRUE;")
+	w("    ENDPROC")
+	w("")
+	w("    ! Call after a fine point outside the zone")
+	w("    PROC ExitZone(num zone)")
+	w("        TEST zone")
+	for zone := 1; zone <= opts.Zones; zone++ {
+		w("        CASE %d:", zone)
+		if world {
+			w("            ! The world zone output confirms the TCP has left")
+			w("            WaitDO %s, 0;", zoneSignal("In", robot, zone))
+		} else {
+			w("            SetDO %s, 0;", zoneSignal("In", robot, zone))
+		}
+		w("            SetDO %s, 0;", zoneSignal("Req", robot, zone))
+	}
+	w("        ENDTEST")
+	w("        bHeld{zone} := FALSE;")
+	w("    ENDPROC")
+	w("ENDMODULE")
+	return b.String()
+}
+
+func zoneArbiterST(opts ZoneOptions) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+	w("FUNCTION_BLOCK FB_ZoneArbiter")
+	w("// Grants every shared zone to one robot at a time, matched by RAPID EnterZone/ExitZone.")
+	w("// Simultaneous requests are served round robin, starting after the last owner.")
+	w("VAR CONSTANT")
+	w("    Robots : INT := %d;", opts.Robots)
+	w("    Zones : INT := %d;", opts.Zones)
+	w("END_VAR")
+	w("VAR_INPUT")
+	w("    Request : ARRAY[1..%d, 1..%d] OF BOOL; // doR<n>ReqZ<k>", opts.Robots, opts.Zones)
+	w("    InZone : ARRAY[1..%d, 1..%d] OF BOOL;  // doR<n>InZ<k>", opts.Robots, opts.Zones)
+	w("END_VAR")
+	w("VAR_OUTPUT")
+	w("    Permit : ARRAY[1..%d, 1..%d] OF BOOL;  // diR<n>PermZ<k>", opts.Robots, opts.Zones)
+	w("    Fault : ARRAY[1..%d] OF BOOL;         // A robot is in a zone it does not own", opts.Zones)
+	w("END_VAR")
+	w("VAR")
+	w("    Owner : ARRAY[1..%d] OF INT;     // Robot holding the zone, 0 = free", opts.Zones)
+	w("    LastOwner : ARRAY[1..%d] OF INT;", opts.Zones)
+	w("    r : INT;")
+	w("    z : INT;")
+	w("    n : INT;")
+	w("    Candidate : INT;")
+	w("END_VAR")
+	w("")
+	w("FOR z := 1 TO Zones DO")
+	w("    // Release once the owner has dropped its request and left the zone")
+	w("    IF Owner[z] <> 0 THEN")
+	w("        IF NOT Request[Owner[z], z] AND NOT InZone[Owner[z], z] THEN")
+	w("            LastOwner[z] := Owner[z];")
+	w("            Owner[z] := 0;")
+	w("        END_IF;")
+	w("    END_IF;")
+	w("")
+	w("    // Grant to the next requesting robot after the last owner")
+	w("    IF Owner[z] = 0 THEN")
+	w("        FOR n := 1 TO Robots DO")
+	w("            Candidate := (LastOwner[z] + n - 1) MOD Robots + 1;")
+	w("            IF Owner[z] = 0 AND Request[Candidate, z] THEN")
+	w("                Owner[z] := Candidate;")
+	w("            END_IF;")
+	w("        END_FOR;")
+	w("    END_IF;")
+	w("")
+	w("    Fault[z] := FALSE;")
+	w("    FOR r := 1 TO Robots DO")
+	w("        Permit[r, z] := Owner[z] = r;")
+	w("        IF InZone[r, z] AND Owner[z] <> r THEN")
+	w("            Fault[z] := TRUE;")
+	w("        END_IF;")
+	w("    END_FOR;")
+	w("END_FOR;")
+	w("END_FUNCTION_BLOCK")
+	return b.String()
+}
+
+func zoneSignalList(opts ZoneOptions) string {
+	var b strings.Builder
+	source := "program"
+	if opts.Method == ZoneWorldZones {
+		source = "world zone"
+	}
+	fmt.Fprintf(&b, "%-14s %-10s %s\n", "Signal", "Direction", "Meaning")
+	for r := 1; r <= opts.Robots; r++ {
+		for zone := 1; zone <= opts.Zones; zone++ {
+			fmt.Fprintf(&b, "%-14s %-10s Robot %d requests zone %d -> Request[%d, %d]\n", zoneSignal("Req", r, zone), "robot>PLC", r, zone, r, zone)
+			fmt.Fprintf(&b, "%-14s %-10s Robot %d in zone %d (%s) -> InZone[%d, %d]\n", zoneSignal("In", r, zone), "robot>PLC", r, zone, source, r, zone)
+			fmt.Fprintf(&b, "%-14s %-10s Permit[%d, %d]: robot %d may enter zone %d\n", zoneSignal("Perm", r, zone), "PLC>robot", r, zone, r, zone)
+		}
+	}
+	return b.String()
+}
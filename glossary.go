@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+const glossaryUsage = `Usage: glossary <term>
+Type glossary on its own to list the known terms.
+
+Examples:
+  glossary tcp
+  glossary work object`
+
+func init() {
+	commandRegistry["glossary"] = Command{
+		Group:       groupReference,
+		Description: "Explain automation terms such as TCP, work object or interlock",
+		Usage:       glossaryUsage,
+		Execute:     text(runGlossary),
+	}
+}
+
+func runGlossary(args []string) (string, error) {
+	if len(args) < 1 {
+		var result strings.Builder
+		result.WriteString("Usage: glossary <term>\nKnown terms:\n")
+		for _, key := range abb.GlossaryKeys() {
+			term, _ := abb.GlossaryTerm(key)
+			result.WriteString(fmt.Sprintf("  %-14s %s\n", key, term.Term))
+		}
+		result.WriteString("\nExamples:\n  glossary tcp\n  glossary work object")
+		return result.String(), nil
+	}
+
+	term, exists := abb.GlossaryTerm(strings.Join(args, " "))
+	if !exists {
+		return "Unknown term. Type 'glossary' to see known terms.", nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("\n%s\n%s\n%s\n", term.Term, strings.Repeat("=", len([]rune(term.Term))), term.Definition))
+	if len(term.Aliases) > 0 {
+		result.WriteString(fmt.Sprintf("\n%s: %s\n", i18n.T("label.aliases"), strings.Join(term.Aliases, ", ")))
+	}
+	if len(term.SeeAlso) > 0 {
+		result.WriteString(fmt.Sprintf("\n%s:\n", i18n.T("label.see_also")))
+		for _, topic := range term.SeeAlso {
+			result.WriteString(fmt.Sprintf("  abb quickref %s\n", topic))
+		}
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/polyfant/automation-helper-cli/grpcserver"
+	"github.com/polyfant/automation-helper-cli/secrets"
+)
+
+// grpcTokenEnv supplies the bearer token for the gRPC server
+const grpcTokenEnv = "AUTOMATION_HELPER_GRPC_TOKEN"
+
+func init() {
+	commandRegistry["grpc"] = Command{
+		Group:       groupIntegration,
+		Description: "Run a gRPC server for programmatic integration",
+		Usage:       grpcUsage,
+		Execute:     text(runGRPC),
+	}
+}
+
+const grpcUsage = `Usage: grpc [--port 50051] [--host 127.0.0.1] [--token <secret>]
+Serves the AutomationHelper service defined in proto/automation_helper.proto.
+Clients authenticate with the metadata header "authorization: Bearer <secret>".
+The token can also be set with the ` + grpcTokenEnv + ` environment variable
+or stored with 'secrets set grpc_token', and is required when listening on a
+non-loopback address.`
+
+func runGRPC(args []string) (string, error) {
+	host, port := "127.0.0.1", 50051
+	token, err := secrets.Get("grpc_token")
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return grpcUsage, nil
+		}
+		switch args[i] {
+		case "--port":
+			p, err := strconv.Atoi(args[i+1])
+			if err != nil || p < 1 || p > 65535 {
+				return "", fmt.Errorf("invalid port: %s", args[i+1])
+			}
+			port = p
+		case "--host":
+			host = args[i+1]
+		case "--token":
+			token = args[i+1]
+		default:
+			return grpcUsage, nil
+		}
+		i++
+	}
+
+	if token == "" {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "", fmt.Errorf("a token is required when listening on %s", host)
+		}
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	srv := grpcserver.New(token)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(lis) }()
+	fmt.Printf("Serving gRPC on %s (Ctrl+C to stop)\n", addr)
+
+	select {
+	case err := <-errc:
+		return "", err
+	case <-interrupt:
+		srv.GracefulStop()
+		return "gRPC server stopped", nil
+	}
+}
@@ -0,0 +1,330 @@
+// Protocol definition for the automation helper gRPC service.
+// The Go code in grpcserver is generated from this file with protoc-gen-go
+// and protoc-gen-go-grpc: run go generate ./grpcserver after changing it.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: automation_helper.proto
+
+package grpcserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AutomationHelper_GetCommand_FullMethodName     = "/automationhelper.v1.AutomationHelper/GetCommand"
+	AutomationHelper_ListCommands_FullMethodName   = "/automationhelper.v1.AutomationHelper/ListCommands"
+	AutomationHelper_GetTopic_FullMethodName       = "/automationhelper.v1.AutomationHelper/GetTopic"
+	AutomationHelper_GenerateSensor_FullMethodName = "/automationhelper.v1.AutomationHelper/GenerateSensor"
+	AutomationHelper_Lint_FullMethodName           = "/automationhelper.v1.AutomationHelper/Lint"
+	AutomationHelper_CalcReorient_FullMethodName   = "/automationhelper.v1.AutomationHelper/CalcReorient"
+)
+
+// AutomationHelperClient is the client API for AutomationHelper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AutomationHelperClient interface {
+	// Reference lookups
+	GetCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*Command, error)
+	ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Command], error)
+	GetTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*Topic, error)
+	// Generators
+	GenerateSensor(ctx context.Context, in *SensorRequest, opts ...grpc.CallOption) (*GeneratedCode, error)
+	// Analysis - the diagnostics of the file, one message each
+	Lint(ctx context.Context, in *SourceFile, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Diagnostic], error)
+	// Calculators
+	CalcReorient(ctx context.Context, in *ReorientRequest, opts ...grpc.CallOption) (*ReorientReply, error)
+}
+
+type automationHelperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAutomationHelperClient(cc grpc.ClientConnInterface) AutomationHelperClient {
+	return &automationHelperClient{cc}
+}
+
+func (c *automationHelperClient) GetCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*Command, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Command)
+	err := c.cc.Invoke(ctx, AutomationHelper_GetCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *automationHelperClient) ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Command], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AutomationHelper_ServiceDesc.Streams[0], AutomationHelper_ListCommands_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListCommandsRequest, Command]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AutomationHelper_ListCommandsClient = grpc.ServerStreamingClient[Command]
+
+func (c *automationHelperClient) GetTopic(ctx context.Context, in *TopicRequest, opts ...grpc.CallOption) (*Topic, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Topic)
+	err := c.cc.Invoke(ctx, AutomationHelper_GetTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *automationHelperClient) GenerateSensor(ctx context.Context, in *SensorRequest, opts ...grpc.CallOption) (*GeneratedCode, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GeneratedCode)
+	err := c.cc.Invoke(ctx, AutomationHelper_GenerateSensor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *automationHelperClient) Lint(ctx context.Context, in *SourceFile, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Diagnostic], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AutomationHelper_ServiceDesc.Streams[1], AutomationHelper_Lint_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SourceFile, Diagnostic]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AutomationHelper_LintClient = grpc.ServerStreamingClient[Diagnostic]
+
+func (c *automationHelperClient) CalcReorient(ctx context.Context, in *ReorientRequest, opts ...grpc.CallOption) (*ReorientReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReorientReply)
+	err := c.cc.Invoke(ctx, AutomationHelper_CalcReorient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AutomationHelperServer is the server API for AutomationHelper service.
+// All implementations must embed UnimplementedAutomationHelperServer
+// for forward compatibility.
+type AutomationHelperServer interface {
+	// Reference lookups
+	GetCommand(context.Context, *CommandRequest) (*Command, error)
+	ListCommands(*ListCommandsRequest, grpc.ServerStreamingServer[Command]) error
+	GetTopic(context.Context, *TopicRequest) (*Topic, error)
+	// Generators
+	GenerateSensor(context.Context, *SensorRequest) (*GeneratedCode, error)
+	// Analysis - the diagnostics of the file, one message each
+	Lint(*SourceFile, grpc.ServerStreamingServer[Diagnostic]) error
+	// Calculators
+	CalcReorient(context.Context, *ReorientRequest) (*ReorientReply, error)
+	mustEmbedUnimplementedAutomationHelperServer()
+}
+
+// UnimplementedAutomationHelperServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAutomationHelperServer struct{}
+
+func (UnimplementedAutomationHelperServer) GetCommand(context.Context, *CommandRequest) (*Command, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommand not implemented")
+}
+func (UnimplementedAutomationHelperServer) ListCommands(*ListCommandsRequest, grpc.ServerStreamingServer[Command]) error {
+	return status.Errorf(codes.Unimplemented, "method ListCommands not implemented")
+}
+func (UnimplementedAutomationHelperServer) GetTopic(context.Context, *TopicRequest) (*Topic, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTopic not implemented")
+}
+func (UnimplementedAutomationHelperServer) GenerateSensor(context.Context, *SensorRequest) (*GeneratedCode, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateSensor not implemented")
+}
+func (UnimplementedAutomationHelperServer) Lint(*SourceFile, grpc.ServerStreamingServer[Diagnostic]) error {
+	return status.Errorf(codes.Unimplemented, "method Lint not implemented")
+}
+func (UnimplementedAutomationHelperServer) CalcReorient(context.Context, *ReorientRequest) (*ReorientReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalcReorient not implemented")
+}
+func (UnimplementedAutomationHelperServer) mustEmbedUnimplementedAutomationHelperServer() {}
+func (UnimplementedAutomationHelperServer) testEmbeddedByValue()                          {}
+
+// UnsafeAutomationHelperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AutomationHelperServer will
+// result in compilation errors.
+type UnsafeAutomationHelperServer interface {
+	mustEmbedUnimplementedAutomationHelperServer()
+}
+
+func RegisterAutomationHelperServer(s grpc.ServiceRegistrar, srv AutomationHelperServer) {
+	// If the following call pancis, it indicates UnimplementedAutomationHelperServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AutomationHelper_ServiceDesc, srv)
+}
+
+func _AutomationHelper_GetCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AutomationHelperServer).GetCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AutomationHelper_GetCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AutomationHelperServer).GetCommand(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AutomationHelper_ListCommands_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCommandsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AutomationHelperServer).ListCommands(m, &grpc.GenericServerStream[ListCommandsRequest, Command]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AutomationHelper_ListCommandsServer = grpc.ServerStreamingServer[Command]
+
+func _AutomationHelper_GetTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AutomationHelperServer).GetTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AutomationHelper_GetTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AutomationHelperServer).GetTopic(ctx, req.(*TopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AutomationHelper_GenerateSensor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SensorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AutomationHelperServer).GenerateSensor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AutomationHelper_GenerateSensor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AutomationHelperServer).GenerateSensor(ctx, req.(*SensorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AutomationHelper_Lint_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SourceFile)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AutomationHelperServer).Lint(m, &grpc.GenericServerStream[SourceFile, Diagnostic]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AutomationHelper_LintServer = grpc.ServerStreamingServer[Diagnostic]
+
+func _AutomationHelper_CalcReorient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AutomationHelperServer).CalcReorient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AutomationHelper_CalcReorient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AutomationHelperServer).CalcReorient(ctx, req.(*ReorientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AutomationHelper_ServiceDesc is the grpc.ServiceDesc for AutomationHelper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AutomationHelper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "automationhelper.v1.AutomationHelper",
+	HandlerType: (*AutomationHelperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCommand",
+			Handler:    _AutomationHelper_GetCommand_Handler,
+		},
+		{
+			MethodName: "GetTopic",
+			Handler:    _AutomationHelper_GetTopic_Handler,
+		},
+		{
+			MethodName: "GenerateSensor",
+			Handler:    _AutomationHelper_GenerateSensor_Handler,
+		},
+		{
+			MethodName: "CalcReorient",
+			Handler:    _AutomationHelper_CalcReorient_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListCommands",
+			Handler:       _AutomationHelper_ListCommands_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Lint",
+			Handler:       _AutomationHelper_Lint_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "automation_helper.proto",
+}
@@ -0,0 +1,150 @@
+// Package grpcserver exposes the automation helper services over gRPC
+// using the protocol defined in proto/automation_helper.proto
+package grpcserver
+
+//go:generate protoc -I ../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative automation_helper.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+// service implements the AutomationHelper RPCs
+type service struct {
+	UnimplementedAutomationHelperServer
+}
+
+// New creates a gRPC server with the AutomationHelper service registered.
+// When token is non-empty every call must carry "authorization: Bearer <token>".
+func New(token string) *grpc.Server {
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := authorize(ctx, token); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorize(ss.Context(), token); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+
+	s := grpc.NewServer(opts...)
+	RegisterAutomationHelperServer(s, service{})
+	return s
+}
+
+// authorize checks the bearer token in the incoming metadata
+func authorize(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		got, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+func (service) GetCommand(_ context.Context, req *CommandRequest) (*Command, error) {
+	cmd, exists := abb.Command(req.Key)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "unknown ABB command: %s", req.Key)
+	}
+	return newCommand(req.Key, cmd), nil
+}
+
+func (service) ListCommands(_ *ListCommandsRequest, stream grpc.ServerStreamingServer[Command]) error {
+	for _, key := range abb.CommandKeys() {
+		cmd, _ := abb.Command(key)
+		if err := stream.Send(newCommand(key, cmd)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newCommand(key string, cmd abb.ABBCommand) *Command {
+	return &Command{
+		Key:         key,
+		Name:        cmd.Name,
+		Syntax:      cmd.Syntax,
+		Example:     cmd.Example,
+		Description: cmd.Description,
+	}
+}
+
+func (service) GetTopic(_ context.Context, req *TopicRequest) (*Topic, error) {
+	content, exists := abb.Topic(req.Topic)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "unknown topic: %s", req.Topic)
+	}
+	return &Topic{Topic: req.Topic, Content: content}, nil
+}
+
+func (service) GenerateSensor(_ context.Context, req *SensorRequest) (*GeneratedCode, error) {
+	code, err := generate.SensorCode(req.Type, req.Action)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &GeneratedCode{Code: code}, nil
+}
+
+func (service) Lint(req *SourceFile, stream grpc.ServerStreamingServer[Diagnostic]) error {
+	var diags []*Diagnostic
+	switch {
+	case rapid.IsSourceFile(req.Filename):
+		for _, d := range rapid.Lint(req.Source) {
+			diags = append(diags, &Diagnostic{Line: int32(d.Line), Severity: d.Severity, Message: d.Message})
+		}
+	case st.IsSourceFile(req.Filename):
+		for _, d := range st.Lint(req.Source) {
+			diags = append(diags, &Diagnostic{Line: int32(d.Line), Severity: rapid.SeverityError, Message: d.Message})
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "unsupported file type: %s", req.Filename)
+	}
+	for _, d := range diags {
+		if err := stream.Send(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (service) CalcReorient(_ context.Context, req *ReorientRequest) (*ReorientReply, error) {
+	speed, err := calc.ParseSpeed(req.Speed)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	r, err := calc.Reorient(req.LengthMm, req.AngleDeg, speed)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ReorientReply{
+		Limited:            r.Limited,
+		TcpTime:            r.TCPTime,
+		OriTime:            r.OriTime,
+		EffectiveTcp:       r.EffectiveTCP,
+		RequiredOri:        r.RequiredOri,
+		SuggestedSpeeddata: r.SuggestSpeed(),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a server on an in-memory listener and returns a client
+func dial(t *testing.T, token string) AutomationHelperClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := New(token)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewAutomationHelperClient(conn)
+}
+
+func TestUnary(t *testing.T) {
+	c := dial(t, "")
+	ctx := context.Background()
+
+	cmd, err := c.GetCommand(ctx, &CommandRequest{Key: "move_j"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Name != "MoveJ" || cmd.Syntax == "" {
+		t.Errorf("GetCommand(move_j) = %v", cmd)
+	}
+	if _, err := c.GetCommand(ctx, &CommandRequest{Key: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetCommand(nope) error = %v, want NotFound", err)
+	}
+
+	r, err := c.CalcReorient(ctx, &ReorientRequest{LengthMm: 100, AngleDeg: 90, Speed: "v1000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Limited || r.TcpTime != 0.1 || r.RequiredOri != 900 {
+		t.Errorf("CalcReorient = %v", r)
+	}
+	if _, err := c.CalcReorient(ctx, &ReorientRequest{LengthMm: -1, Speed: "v1000"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CalcReorient(-1) error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	c := dial(t, "")
+	ctx := context.Background()
+
+	list, err := c.ListCommands(ctx, &ListCommandsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for {
+		_, err := list.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n == 0 {
+		t.Error("ListCommands sent no commands")
+	}
+
+	lint, err := c.Lint(ctx, &SourceFile{Filename: "T.mod", Source: "MODULE T\n  PROC main()\n    MoveL p10, v1000, fine, tool0;\nENDMODULE\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diags []*Diagnostic
+	for {
+		d, err := lint.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		diags = append(diags, d)
+	}
+	if len(diags) == 0 || diags[0].Line == 0 || diags[0].Message == "" {
+		t.Errorf("Lint = %v, want the unclosed PROC reported", diags)
+	}
+}
+
+func TestToken(t *testing.T) {
+	c := dial(t, "secret")
+	req := &TopicRequest{Topic: "io_handling"}
+	if _, err := c.GetTopic(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetTopic without token error = %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	topic, err := c.GetTopic(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic.Content == "" {
+		t.Error("GetTopic returned no content")
+	}
+}
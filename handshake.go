@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/wizard"
+)
+
+const handshakeUsage = `Usage: generate handshake --signals <request,done[,fault]> [--style pulse|level]
+       [--routine DoJob] [--timeout 10] [--pulse 0.5] [--output dir]
+       generate handshake --wizard [options]
+Generates matched code for both sides of a PLC-robot job handshake: a RAPID
+module for the robot and a Structured Text function block for the PLC, plus
+the timing diagram as ASCII and Mermaid. The request comes from the PLC; done
+and fault come from the robot.
+
+level: the request is held until done is seen, done is held until the request
+       is removed (4-phase, cannot miss an edge)
+pulse: both sides pulse their signal; simpler but a missed pulse stalls the cell
+
+--wizard asks for the options not given on the command line. Test the
+function block against a scenario with 'scenario sim' before the PLC exists.
+
+Examples:
+  generate handshake --signals job_start,job_done,fault --style level
+  generate handshake --wizard --output ./handshake`
+
+// handshakeQuestions are asked by generate handshake --wizard
+var handshakeQuestions = []wizard.Question{
+	{ID: "signals", Text: "Signals: request, done and optionally fault, comma separated", Kind: wizard.Text, Default: "job_start,job_done,fault",
+		Help: "The request comes from the PLC; done and fault come from the robot", Validate: handshakeSignals},
+	{ID: "style", Text: "Handshake style", Kind: wizard.Choice, Choices: []string{generate.StyleLevel, generate.StylePulse}, Default: generate.StyleLevel,
+		Help: "level: 4-phase, each side holds its signal until the other answers; pulse: simpler, but a missed pulse stalls the cell"},
+	{ID: "routine", Text: "RAPID routine that runs the job", Kind: wizard.Text, Default: "DoJob", Validate: identifier},
+	{ID: "timeout", Text: "Timeout waiting for the other side (s)", Kind: wizard.Number, Default: "10"},
+	{ID: "pulse", Text: "Pulse length (s)", Kind: wizard.Number, Default: "0.5", When: "style=" + generate.StylePulse},
+}
+
+// handshakeSignals validates the signal list of the handshake wizard
+func handshakeSignals(answer string) error {
+	signals := strings.Split(answer, ",")
+	if len(signals) < 2 || len(signals) > 3 {
+		return fmt.Errorf("give 2 or 3 signals: request, done and optionally fault")
+	}
+	for _, s := range signals {
+		if err := identifier(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateHandshake writes both sides of a job handshake
+func generateHandshake(args []string) (string, error) {
+	if args, wiz := wizardFlag(args); wiz {
+		options, err := wizardOptions("Job handshake wizard", handshakeQuestions, args)
+		if err != nil {
+			return "", err
+		}
+		generatedWith(options)
+		return generateHandshake(options)
+	}
+	opts := generate.HandshakeOptions{Style: generate.StyleLevel, Routine: "DoJob", Timeout: 10, Pulse: 0.5}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return handshakeUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--signals":
+			opts.Signals = strings.Split(value, ",")
+		case "--style":
+			opts.Style = strings.ToLower(value)
+		case "--routine":
+			opts.Routine = value
+		case "--timeout":
+			opts.Timeout, err = strconv.ParseFloat(value, 64)
+		case "--pulse":
+			opts.Pulse, err = strconv.ParseFloat(value, 64)
+		case "--output":
+			output = value
+		default:
+			return handshakeUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if len(opts.Signals) == 0 {
+		return handshakeUsage, nil
+	}
+	if !identifierRe.MatchString(opts.Routine) {
+		return "", fmt.Errorf("%q is not a valid RAPID routine name", opts.Routine)
+	}
+
+	h, err := generate.HandshakeCode(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return fmt.Sprintf("--- Handshake.mod ---\n%s\n--- FB_JobHandshake.st ---\n%s\n--- Timing ---\n%s\n--- Mermaid ---\n%s",
+			h.Rapid, h.ST, h.Diagram.ASCII(), strings.TrimRight(h.Diagram.Mermaid(), "\n")), nil
+	}
+
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	doc := fmt.Sprintf("# Job handshake: %s\n\n```\n%s```\n\n```mermaid\n%s```\n",
+		strings.Join(opts.Signals, ", "), h.Diagram.ASCII(), h.Diagram.Mermaid())
+	files := []struct{ name, content string }{
+		{"Handshake.mod", h.Rapid},
+		{"FB_JobHandshake.st", h.ST},
+		{"handshake.md", doc},
+		{"handshake.timing", h.Diagram.Source()},
+	}
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(output, f.name)
+		if err := writeFile(path, []byte(f.content), 0644); err != nil {
+			return "", err
+		}
+		written = append(written, path)
+	}
+	return "Wrote " + strings.Join(written, ", "), nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+// headerTemplateFile overrides rapid.DefaultHeaderTemplate when present in
+// the data directory
+const headerTemplateFile = "header.tmpl"
+
+// headerOptions parses --author and --cell, falling back to the settings file
+// and the login name. The remaining arguments are returned.
+func headerOptions(args []string) (author, cell string, rest []string, err error) {
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", "", nil, err
+	}
+	author, cell = s.Author, s.Cell
+	if p := activeProject(); p != nil && p.Cell != "" {
+		cell = p.Cell
+	}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--author", "--cell":
+			if i+1 >= len(args) {
+				return "", "", nil, fmt.Errorf("%s needs a value", args[i])
+			}
+			if args[i] == "--author" {
+				author = args[i+1]
+			} else {
+				cell = args[i+1]
+			}
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	if author == "" {
+		author = os.Getenv("USER")
+	}
+	if author == "" {
+		author = os.Getenv("USERNAME")
+	}
+	if author == "" {
+		author = "unknown"
+	}
+	// Revision lines are space separated, so the author must be one word
+	author = strings.Join(strings.Fields(author), "_")
+	return author, cell, rest, nil
+}
+
+// headerTemplate loads the user's header template or the built-in default
+func headerTemplate() (*template.Template, error) {
+	path, err := config.Path("templates", headerTemplateFile)
+	if err != nil {
+		return nil, err
+	}
+	text := rapid.DefaultHeaderTemplate
+	if data, err := os.ReadFile(path); err == nil {
+		text = string(data)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return template.New(headerTemplateFile).Parse(text)
+}
+
+// abbHeader inserts or refreshes the header of a RAPID module
+func abbHeader(args []string) (string, error) {
+	usage := `Usage: abb header <file.mod> [--author <name>] [--cell <name>]
+Inserts a standard header after the MODULE line, or refreshes an existing one.
+Defaults come from 'config set author' and 'config set cell'. A custom layout
+can be placed in templates/` + headerTemplateFile + ` in the data directory.`
+	author, cell, rest, err := headerOptions(args)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) != 1 {
+		return usage, nil
+	}
+	path := rest[0]
+
+	return rewriteHeader(path, func(h *rapid.Header, existed bool) string {
+		today := time.Now().Format("2006-01-02")
+		if !existed {
+			h.Author, h.Cell, h.Created = author, cell, today
+			h.BumpRevision(today, author, "Initial version")
+			return fmt.Sprintf("Added header to %s", path)
+		}
+		if h.Cell == "" {
+			h.Cell = cell
+		}
+		return fmt.Sprintf("Refreshed header of %s", path)
+	})
+}
+
+// abbBumpRev appends a revision entry to a module header
+func abbBumpRev(args []string) (string, error) {
+	author, cell, rest, err := headerOptions(args)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) < 2 {
+		return `Usage: abb bump-rev <file.mod> <description> [--author <name>]
+Example: abb bump-rev MainModule.mod "Reduced approach speed at station 2"`, nil
+	}
+	path, text := rest[0], strings.Join(rest[1:], " ")
+
+	return rewriteHeader(path, func(h *rapid.Header, existed bool) string {
+		today := time.Now().Format("2006-01-02")
+		if !existed {
+			h.Author, h.Cell, h.Created = author, cell, today
+		}
+		rev := h.BumpRevision(today, author, text)
+		return fmt.Sprintf("%s: revision %d - %s", path, rev.Number, text)
+	})
+}
+
+// rewriteHeader reads a module, lets update change its header and writes it back
+func rewriteHeader(path string, update func(h *rapid.Header, existed bool) string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := headerTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	src := string(data)
+	h, existed := rapid.ParseHeader(src)
+	message := update(&h, existed)
+
+	out, err := rapid.ApplyHeader(src, h, tmpl)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(path, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+	return message, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// printCommandHelp prints the usage of one command with its examples
+// numbered; help <command> <n> runs example n once confirmed
+func printCommandHelp(args []string) {
+	name := strings.ToLower(args[0])
+	cmd, exists := commandRegistry[name]
+	if !exists {
+		fmt.Printf("Unknown command: %s\nType 'help' for available commands\n", name)
+		return
+	}
+	examples := usageExamples(cmd.Usage)
+
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > len(examples) {
+			fmt.Printf("Error: %s has %d examples; give a number from the list\n", name, len(examples))
+			return
+		}
+		line, err := splitArgs(examples[n-1])
+		if err != nil {
+			fmt.Printf("Error: example %d: %v\n", n, err)
+			return
+		}
+		if run, ok := commandRegistry[line[0]]; ok {
+			name, cmd = line[0], run
+		}
+		// Examples may change files, controllers or send messages, so they
+		// run only when confirmed
+		fmt.Printf("> %s\n", examples[n-1])
+		answer, ok := readLine("Run this example? [y/N]: ")
+		if !ok || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			fmt.Println("Not run; type it yourself to change it first.")
+			return
+		}
+		fmt.Println(render(execute(name, cmd, line[1:]), os.Stdout))
+		return
+	}
+
+	fmt.Printf("\n%s - %s (%s)\n\n", name, cmd.Description, cmd.Group)
+	if cmd.Usage == "" {
+		fmt.Printf("Type '%s' on its own to see its usage.\n", name)
+		return
+	}
+	fmt.Println(cmd.Usage)
+	if len(examples) > 0 {
+		fmt.Printf("\nRun an example with 'help %s <n>', after confirming:\n", name)
+		for i, e := range examples {
+			fmt.Printf("  %2d  %s\n", i+1, e)
+		}
+	}
+	var notes []string
+	for key, what := range confirmations {
+		if key == name || strings.HasPrefix(key, name+" ") {
+			notes = append(notes, fmt.Sprintf("Note: '%s' %s.", key, what))
+		}
+	}
+	sort.Strings(notes)
+	for _, note := range notes {
+		fmt.Println("\n" + note)
+	}
+}
+
+// usageExamples returns the command lines under the Example or Examples
+// heading of a usage text, without their trailing explanations
+func usageExamples(usage string) []string {
+	var examples []string
+	in := false
+	for _, line := range strings.Split(usage, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Example:") || strings.HasPrefix(trimmed, "Examples:"):
+			in = true
+			_, rest, _ := strings.Cut(trimmed, ":")
+			if rest = strings.TrimSpace(rest); rest != "" {
+				examples = append(examples, rest)
+			}
+			continue
+		case !in:
+			continue
+		case trimmed == "" || !strings.HasPrefix(line, " "):
+			in = false
+			continue
+		}
+		if cmd, _, ok := strings.Cut(trimmed, " - "); ok {
+			trimmed = strings.TrimSpace(cmd)
+		}
+		examples = append(examples, trimmed)
+	}
+	return examples
+}
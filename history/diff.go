@@ -0,0 +1,189 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around a change
+const contextLines = 3
+
+// Diff returns the changes as a unified diff
+func (cs Changeset) Diff() string {
+	var b strings.Builder
+	for _, c := range cs.Changes {
+		b.WriteString(Unified(c.Path, c.Existed, string(c.Before), string(c.After)))
+	}
+	return b.String()
+}
+
+// Unified returns a unified diff of a file; existed is false for a file the
+// change creates
+func Unified(path string, existed bool, before, after string) string {
+	if existed && before == after {
+		return ""
+	}
+	a, b := splitLines(before), splitLines(after)
+	edits := diffLines(a, b)
+
+	var out strings.Builder
+	from := path
+	if !existed {
+		from = "/dev/null"
+	}
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, path)
+	for start := 0; start < len(edits); {
+		// Find the next change and the end of its hunk: changes closer
+		// than twice the context share a hunk
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				last = i
+			} else if i-last > 2*contextLines {
+				break
+			}
+		}
+		lo := first - contextLines
+		if lo < start {
+			lo = start
+		}
+		if lo < 0 {
+			lo = 0
+		}
+		hi := last + contextLines + 1
+		if hi > len(edits) {
+			hi = len(edits)
+		}
+		writeHunk(&out, edits[lo:hi])
+		start = hi
+	}
+	return out.String()
+}
+
+// edit is one line of a diff: ' ' unchanged, '-' removed or '+' added, with
+// the line numbers before and after (1-based, 0 where the line is absent)
+type edit struct {
+	op      byte
+	line    string
+	aLine   int
+	bLine   int
+	aBefore int // lines of the old file before this edit
+	bBefore int
+}
+
+func writeHunk(out *strings.Builder, edits []edit) {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, e := range edits {
+		if e.op != '+' {
+			if aCount == 0 {
+				aStart = e.aLine
+			}
+			aCount++
+		}
+		if e.op != '-' {
+			if bCount == 0 {
+				bStart = e.bLine
+			}
+			bCount++
+		}
+	}
+	// An empty range names the line before it
+	if aCount == 0 {
+		aStart = edits[0].aBefore
+	}
+	if bCount == 0 {
+		bStart = edits[0].bBefore
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, e := range edits {
+		fmt.Fprintf(out, "%c%s\n", e.op, e.line)
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines finds a shortest edit script with Myers' algorithm
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds v[-d-1..d+1] as it was at the start of round d
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back from the end, collecting edits in reverse
+	var reversed []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		at := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, edit{op: ' ', line: a[x-1], aLine: x, bLine: y})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, edit{op: '+', line: b[y-1], bLine: y})
+		} else {
+			reversed = append(reversed, edit{op: '-', line: a[x-1], aLine: x})
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]edit, len(reversed))
+	aSeen, bSeen := 0, 0
+	for i := range reversed {
+		e := reversed[len(reversed)-1-i]
+		e.aBefore, e.bBefore = aSeen, bSeen
+		if e.op != '+' {
+			aSeen++
+		}
+		if e.op != '-' {
+			bSeen++
+		}
+		edits[i] = e
+	}
+	return edits
+}
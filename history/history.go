@@ -0,0 +1,299 @@
+// Package history records the files a command rewrites as a changeset, so
+// undo can restore them and redo can apply them again. Changesets keep the
+// full file contents and are stored in the database in the data directory.
+package history
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/db"
+)
+
+// historyFile is the undo and redo log earlier versions kept in the data
+// directory; the log is now kept in the database
+const historyFile = "history.json"
+
+// The stacks of the changesets table
+const (
+	stackUndo = "undo"
+	stackRedo = "redo"
+)
+
+// Limit is the number of changesets undo can go back
+const Limit = 20
+
+// Change is one file written by a command
+type Change struct {
+	Path    string      `json:"path"`
+	Mode    os.FileMode `json:"mode"`
+	Existed bool        `json:"existed"`
+	Before  []byte      `json:"before,omitempty"`
+	After   []byte      `json:"after"`
+}
+
+// Changeset is the files one command line changed
+type Changeset struct {
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+	Changes []Change  `json:"changes"`
+	// DryRun records the changes without writing any file
+	DryRun bool `json:"-"`
+}
+
+// log is the undo and redo stacks, newest changesets last
+type log struct {
+	Undo []Changeset `json:"undo"`
+	Redo []Changeset `json:"redo"`
+}
+
+// WriteFile writes a file like os.WriteFile and records its previous
+// content in the changeset. Writing a file twice keeps the first content
+// as the one undo restores. In a dry run nothing is written.
+func (cs *Changeset) WriteFile(path string, data []byte, perm os.FileMode) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c := Change{Path: abs, Mode: perm}
+	if before, err := os.ReadFile(abs); err == nil {
+		c.Existed, c.Before = true, before
+		if info, err := os.Stat(abs); err == nil {
+			c.Mode = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if !cs.DryRun {
+		if err := os.WriteFile(abs, data, perm); err != nil {
+			return err
+		}
+	}
+	c.After = append([]byte(nil), data...)
+	for i := range cs.Changes {
+		if cs.Changes[i].Path == abs {
+			cs.Changes[i].After = c.After
+			return nil
+		}
+	}
+	cs.Changes = append(cs.Changes, c)
+	return nil
+}
+
+// Record adds a changeset to the undo log. A new change makes the undone
+// changesets unreachable, so the redo log is cleared.
+func Record(cs Changeset) error {
+	if len(cs.Changes) == 0 || cs.DryRun {
+		return nil
+	}
+	l, err := load()
+	if err != nil {
+		return err
+	}
+	if cs.Time.IsZero() {
+		cs.Time = time.Now()
+	}
+	l.Undo = append(l.Undo, cs)
+	if len(l.Undo) > Limit {
+		l.Undo = l.Undo[len(l.Undo)-Limit:]
+	}
+	l.Redo = nil
+	return l.save()
+}
+
+// List returns the changesets undo and redo would apply, newest first
+func List() (undo, redo []Changeset, err error) {
+	l, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := len(l.Undo) - 1; i >= 0; i-- {
+		undo = append(undo, l.Undo[i])
+	}
+	for i := len(l.Redo) - 1; i >= 0; i-- {
+		redo = append(redo, l.Redo[i])
+	}
+	return undo, redo, nil
+}
+
+// Undo restores the files of the newest changeset. Files edited since the
+// command ran are left alone and reported unless force is set.
+func Undo(force bool) (Changeset, error) {
+	l, err := load()
+	if err != nil {
+		return Changeset{}, err
+	}
+	if len(l.Undo) == 0 {
+		return Changeset{}, fmt.Errorf("nothing to undo")
+	}
+	cs := l.Undo[len(l.Undo)-1]
+	if err := apply(cs, false, force); err != nil {
+		return cs, err
+	}
+	l.Undo = l.Undo[:len(l.Undo)-1]
+	l.Redo = append(l.Redo, cs)
+	return cs, l.save()
+}
+
+// Redo applies the newest undone changeset again
+func Redo(force bool) (Changeset, error) {
+	l, err := load()
+	if err != nil {
+		return Changeset{}, err
+	}
+	if len(l.Redo) == 0 {
+		return Changeset{}, fmt.Errorf("nothing to redo")
+	}
+	cs := l.Redo[len(l.Redo)-1]
+	if err := apply(cs, true, force); err != nil {
+		return cs, err
+	}
+	l.Redo = l.Redo[:len(l.Redo)-1]
+	l.Undo = append(l.Undo, cs)
+	return cs, l.save()
+}
+
+// apply writes the contents after (redo) or before (undo) the changeset.
+// All files are checked before any is written, so a conflict changes
+// nothing.
+func apply(cs Changeset, forward, force bool) error {
+	if !force {
+		for _, c := range cs.Changes {
+			expected, exists := c.After, true
+			if forward {
+				expected, exists = c.Before, c.Existed
+			}
+			current, err := os.ReadFile(c.Path)
+			switch {
+			case os.IsNotExist(err) && !exists:
+				continue
+			case os.IsNotExist(err):
+				return fmt.Errorf("%s was deleted since; use --force to write it anyway", c.Path)
+			case err != nil:
+				return err
+			case !exists || !bytes.Equal(current, expected):
+				return fmt.Errorf("%s was changed since; use --force to overwrite it", c.Path)
+			}
+		}
+	}
+	for _, c := range cs.Changes {
+		if !forward && !c.Existed {
+			if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		data := c.Before
+		if forward {
+			data = c.After
+		}
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.Path, data, c.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the undo and redo stacks from the database, after reading in
+// the history file of earlier versions
+func load() (*log, error) {
+	err := db.ImportOnce(historyFile, func(tx *sql.Tx) error {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, historyFile))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l := &log{}
+		if err := json.Unmarshal(data, l); err != nil {
+			return err
+		}
+		return l.insert(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	d, err := db.Open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.Query(`SELECT stack, command, time, changes FROM changesets ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	l := &log{}
+	for rows.Next() {
+		var stack, t, changes string
+		var cs Changeset
+		if err := rows.Scan(&stack, &cs.Command, &t, &changes); err != nil {
+			return nil, err
+		}
+		if cs.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &cs.Changes); err != nil {
+			return nil, fmt.Errorf("invalid changeset %q: %v", cs.Command, err)
+		}
+		if stack == stackRedo {
+			l.Redo = append(l.Redo, cs)
+		} else {
+			l.Undo = append(l.Undo, cs)
+		}
+	}
+	return l, rows.Err()
+}
+
+// save replaces the stacks in the database
+func (l *log) save() error {
+	d, err := db.Open()
+	if err != nil {
+		return err
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM changesets`); err != nil {
+		return err
+	}
+	if err := l.insert(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insert adds the changesets of both stacks, oldest first
+func (l *log) insert(tx *sql.Tx) error {
+	for _, stack := range []struct {
+		name string
+		sets []Changeset
+	}{{stackUndo, l.Undo}, {stackRedo, l.Redo}} {
+		for _, cs := range stack.sets {
+			changes, err := json.Marshal(cs.Changes)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO changesets (stack, command, time, changes) VALUES (?, ?, ?, ?)`,
+				stack.name, cs.Command, cs.Time.UTC().Format(time.RFC3339Nano), string(changes)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package i18n selects the display language and translates interface text.
+// Reference data is translated separately by the data packs themselves.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Default is the language used when nothing else is selected. All built-in
+// text is written in it and used as the fallback for missing translations.
+const Default = "en"
+
+// Languages lists the supported language codes
+var Languages = []string{"en", "sv", "de", "es"}
+
+var (
+	mu      sync.RWMutex
+	current = Default
+)
+
+// SetLanguage selects the display language. Region suffixes such as "sv_SE"
+// or "de-AT" are accepted and reduced to the language code.
+func SetLanguage(lang string) error {
+	code := Normalize(lang)
+	if code == "" {
+		code = Default
+	}
+	for _, l := range Languages {
+		if l == code {
+			mu.Lock()
+			current = code
+			mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(Languages, ", "))
+}
+
+// Language returns the selected language code
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Normalize reduces a locale such as "de_DE.UTF-8" to its language code
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-."); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// T returns the interface text for key in the selected language, falling back
+// to English and finally to the key itself
+func T(key string) string {
+	if text, ok := messages[Language()][key]; ok {
+		return text
+	}
+	if text, ok := messages[Default][key]; ok {
+		return text
+	}
+	return key
+}
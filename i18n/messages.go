@@ -0,0 +1,65 @@
+package i18n
+
+// messages holds the interface text per language
+var messages = map[string]map[string]string{
+	"en": {
+		"label.command":                 "Command",
+		"label.syntax":                  "Syntax",
+		"label.example":                 "Example",
+		"label.description":             "Description",
+		"label.cause":                   "Cause",
+		"label.recovery":                "Recovery",
+		"label.aliases":                 "Also known as",
+		"label.see_also":                "See also",
+		"generate.action":               "Your action here",
+		"generate.ladder":               "PLC Ladder Logic",
+		"generate.robot":                "ABB Robot",
+		"generate.s7":                   "Siemens S7",
+		"generate.weld_starting_values": "STARTING VALUES - verify on test plates before production",
+	},
+	"sv": {
+		"label.command":                 "Instruktion",
+		"label.syntax":                  "Syntax",
+		"label.example":                 "Exempel",
+		"label.description":             "Beskrivning",
+		"label.cause":                   "Orsak",
+		"label.recovery":                "Åtgärd",
+		"label.aliases":                 "Även kallat",
+		"label.see_also":                "Se även",
+		"generate.action":               "Din åtgärd här",
+		"generate.ladder":               "PLC-stegdiagram",
+		"generate.robot":                "ABB-robot",
+		"generate.s7":                   "Siemens S7",
+		"generate.weld_starting_values": "STARTVÄRDEN - verifiera på provplåtar före produktion",
+	},
+	"de": {
+		"label.command":                 "Befehl",
+		"label.syntax":                  "Syntax",
+		"label.example":                 "Beispiel",
+		"label.description":             "Beschreibung",
+		"label.cause":                   "Ursache",
+		"label.recovery":                "Abhilfe",
+		"label.aliases":                 "Auch bekannt als",
+		"label.see_also":                "Siehe auch",
+		"generate.action":               "Ihre Aktion hier",
+		"generate.ladder":               "SPS-Kontaktplan",
+		"generate.robot":                "ABB-Roboter",
+		"generate.s7":                   "Siemens S7",
+		"generate.weld_starting_values": "STARTWERTE - vor der Produktion an Probeblechen prüfen",
+	},
+	"es": {
+		"label.command":                 "Instrucción",
+		"label.syntax":                  "Sintaxis",
+		"label.example":                 "Ejemplo",
+		"label.description":             "Descripción",
+		"label.cause":                   "Causa",
+		"label.recovery":                "Solución",
+		"label.aliases":                 "También conocido como",
+		"label.see_also":                "Véase también",
+		"generate.action":               "Su acción aquí",
+		"generate.ladder":               "Lógica ladder del PLC",
+		"generate.robot":                "Robot ABB",
+		"generate.s7":                   "Siemens S7",
+		"generate.weld_starting_values": "VALORES INICIALES - verificar en probetas antes de producir",
+	},
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/project"
+)
+
+const jobsUsage = `Usage:
+  jobs                          List the jobs with their last and next run
+  jobs add "<task> <schedule>"  Schedule a task
+  jobs remove <id>
+  jobs history [<id>] [--last 20]
+  jobs daemon [--once]          Run the jobs when they are due
+
+Tasks, on a fleet target or a controller address:
+  backup <target> [keep <n>]               abb rws backup create into backups/<target>,
+                                           keeping the newest n
+  elog <target>                            abb rws elog into jobs/elog/<target>.csv
+  info <target>                            abb rws info into the project inventory
+  log <target> <signals> for <duration>    log record into jobs/log/<target>/<date>.csv
+  run <command line>                       any command, e.g. run abb lint ./RAPID
+
+Schedules:
+  every <duration>          e.g. every 15m or every 2h
+  hourly [at :MM]
+  daily|nightly at HH:MM
+  weekly on <day> at HH:MM
+
+Jobs belong to the active project: they are kept in jobs/jobs.json and each
+run is appended to jobs/history.jsonl with its output. The daemon runs due
+jobs one at a time and checks every 30 seconds until Ctrl+C; --once runs
+the due jobs and exits, for cron or the Windows Task Scheduler. Jobs skip
+the confirmations of the profile; abb rws actions in them need --yes. Job
+results are sent to the notification rules set up with notify. With a
+shared store (see storage) the logs and history are uploaded after each run.
+
+Examples:
+  jobs add "backup cell3 nightly at 02:00"
+  jobs add "backup cell4 keep 14 daily at 03:00"
+  jobs add "elog cell3 every 15m"
+  jobs add "log cell3 ai_Flow,ai_Pressure for 10m daily at 14:00"
+  jobs daemon`
+
+// jobsDir holds the jobs, their history and their output in the project
+const jobsDir = "jobs"
+
+// jobPoll is how often the daemon checks for due jobs
+const jobPoll = 30 * time.Second
+
+// job is a scheduled task of the project
+type job struct {
+	ID       int       `json:"id"`
+	Spec     string    `json:"spec"` // task and schedule as added
+	Created  time.Time `json:"created"`
+	LastRun  time.Time `json:"last_run,omitempty"`
+	LastOK   bool      `json:"last_ok,omitempty"`
+	Duration float64   `json:"duration_s,omitempty"` // of the last run
+}
+
+// jobRun is one run of a job in the history
+type jobRun struct {
+	Job     int       `json:"job"`
+	Spec    string    `json:"spec"`
+	Started time.Time `json:"started"`
+	Seconds float64   `json:"seconds"`
+	OK      bool      `json:"ok"`
+	Output  string    `json:"output"`
+}
+
+// schedule is when a job runs
+type schedule struct {
+	Every   time.Duration // every; otherwise at Hour:Minute
+	Hour    int           // -1 for every hour
+	Minute  int
+	Weekday int // -1 for every day
+}
+
+var (
+	scheduleWords = map[string]bool{"every": true, "hourly": true, "daily": true, "nightly": true, "weekly": true}
+	clockRe       = regexp.MustCompile(`^([01]?\d|2[0-3]):([0-5]\d)$`)
+	weekdays      = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+func init() {
+	commandRegistry["jobs"] = Command{
+		Group:       groupIntegration,
+		Description: "Schedule backups, event log pulls and data logging",
+		Usage:       jobsUsage,
+		Execute:     text(runJobs),
+	}
+	noDryRun["jobs"] = true
+}
+
+func runJobs(args []string) (string, error) {
+	p := activeProject()
+	if p == nil {
+		return "", errors.New("jobs belong to a project; select one with 'use <projectdir>'")
+	}
+	if len(args) == 0 {
+		return listJobs(p)
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			return jobsUsage, nil
+		}
+		return addJob(p, strings.Join(args[1:], " "))
+	case "remove":
+		if len(args) != 2 {
+			return jobsUsage, nil
+		}
+		return removeJob(p, args[1])
+	case "history":
+		return jobHistory(p, args[1:])
+	case "daemon":
+		once := len(args) == 2 && args[1] == "--once"
+		if len(args) > 2 || len(args) == 2 && !once {
+			return jobsUsage, nil
+		}
+		return jobDaemon(p, once)
+	default:
+		return jobsUsage, nil
+	}
+}
+
+// parseJob splits a job spec into its task and schedule. The schedule
+// starts at the last schedule word, so run commands may contain them.
+func parseJob(spec string) ([]string, schedule, error) {
+	words := strings.Fields(spec)
+	at := -1
+	for i, w := range words {
+		if scheduleWords[strings.ToLower(w)] {
+			at = i
+		}
+	}
+	if at < 1 {
+		return nil, schedule{}, fmt.Errorf("no schedule in %q; end it with e.g. 'nightly at 02:00' or 'every 15m'", spec)
+	}
+	s, err := parseSchedule(words[at:])
+	if err != nil {
+		return nil, schedule{}, err
+	}
+	task := words[:at]
+	switch task[0] {
+	case "backup":
+		if len(task) == 4 && task[2] == "keep" {
+			if n, err := strconv.Atoi(task[3]); err != nil || n < 1 {
+				return nil, schedule{}, fmt.Errorf("invalid number of backups to keep %q", task[3])
+			}
+		} else if len(task) != 2 {
+			return nil, schedule{}, fmt.Errorf("use 'backup <target> [keep <n>] <schedule>'")
+		}
+	case "elog", "info":
+		if len(task) != 2 {
+			return nil, schedule{}, fmt.Errorf("use '%s <target> <schedule>'", task[0])
+		}
+	case "log":
+		if len(task) != 5 || task[3] != "for" {
+			return nil, schedule{}, fmt.Errorf("use 'log <target> <signals> for <duration> <schedule>'")
+		}
+		if _, err := time.ParseDuration(task[4]); err != nil {
+			return nil, schedule{}, fmt.Errorf("invalid duration %q", task[4])
+		}
+	case "run":
+		if len(task) < 2 {
+			return nil, schedule{}, fmt.Errorf("use 'run <command line> <schedule>'")
+		}
+		if _, ok := commandRegistry[strings.ToLower(task[1])]; !ok {
+			return nil, schedule{}, fmt.Errorf("unknown command %s", task[1])
+		}
+	default:
+		return nil, schedule{}, fmt.Errorf("unknown task %q (use backup, elog, info, log or run)", task[0])
+	}
+	return task, s, nil
+}
+
+func parseSchedule(words []string) (schedule, error) {
+	s := schedule{Hour: -1, Weekday: -1}
+	invalid := fmt.Errorf("invalid schedule %q", strings.Join(words, " "))
+	switch strings.ToLower(words[0]) {
+	case "every":
+		if len(words) != 2 {
+			return s, invalid
+		}
+		d, err := time.ParseDuration(words[1])
+		if err != nil || d < time.Minute {
+			return s, fmt.Errorf("invalid interval %q; use at least 1m", words[1])
+		}
+		s.Every = d
+	case "hourly":
+		if len(words) == 1 {
+			return s, nil
+		}
+		if len(words) != 3 || words[1] != "at" || !strings.HasPrefix(words[2], ":") {
+			return s, invalid
+		}
+		m, err := strconv.Atoi(words[2][1:])
+		if err != nil || m < 0 || m > 59 {
+			return s, invalid
+		}
+		s.Minute = m
+	case "daily", "nightly", "weekly":
+		rest := words[1:]
+		if strings.ToLower(words[0]) == "weekly" {
+			if len(rest) < 2 || rest[0] != "on" {
+				return s, invalid
+			}
+			day := strings.ToLower(rest[1])
+			for i, w := range weekdays {
+				if strings.HasPrefix(day, w) {
+					s.Weekday = i
+				}
+			}
+			if s.Weekday < 0 {
+				return s, fmt.Errorf("unknown day %q", rest[1])
+			}
+			rest = rest[2:]
+		}
+		if len(rest) != 2 || rest[0] != "at" {
+			return s, invalid
+		}
+		m := clockRe.FindStringSubmatch(rest[1])
+		if m == nil {
+			return s, fmt.Errorf("invalid time %q; use HH:MM", rest[1])
+		}
+		s.Hour, _ = strconv.Atoi(m[1])
+		s.Minute, _ = strconv.Atoi(m[2])
+	default:
+		return s, invalid
+	}
+	return s, nil
+}
+
+// next returns the first time after t the schedule is due
+func (s schedule) next(t time.Time) time.Time {
+	if s.Every > 0 {
+		return t.Add(s.Every)
+	}
+	n := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), s.Minute, 0, 0, t.Location())
+	if s.Hour < 0 {
+		if !n.After(t) {
+			n = n.Add(time.Hour)
+		}
+		return n
+	}
+	n = time.Date(t.Year(), t.Month(), t.Day(), s.Hour, s.Minute, 0, 0, t.Location())
+	for !n.After(t) || s.Weekday >= 0 && int(n.Weekday()) != s.Weekday {
+		n = n.AddDate(0, 0, 1)
+	}
+	return n
+}
+
+// nextRun is when a job is due next, counted from its last run or, before
+// the first, from when it was added
+func (j job) nextRun() time.Time {
+	_, s, err := parseJob(j.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	from := j.Created
+	if !j.LastRun.IsZero() {
+		from = j.LastRun
+	}
+	return s.next(from)
+}
+
+// command returns the command line that runs a job's task at a time
+func (j job) command(p *project.Project, at time.Time) (string, []string, error) {
+	task, _, err := parseJob(j.Spec)
+	if err != nil {
+		return "", nil, err
+	}
+	if task[0] == "run" {
+		return strings.ToLower(task[1]), task[2:], nil
+	}
+	target := task[1]
+	host := target
+	fleet, err := loadFleet()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, t := range fleet {
+		if t.Name == target {
+			host = t.Host
+		}
+	}
+	dir := filepath.Join(p.Dir, jobsDir, task[0], unsafeNameRe.ReplaceAllString(target, "_"))
+	stamp := at.Format("2006-01-02_1504")
+	switch task[0] {
+	case "backup":
+		args := []string{"rws", "backup", "create", "--host", host, "--robot", target}
+		if len(task) == 4 {
+			args = append(args, "--retain", task[3])
+		}
+		return "abb", args, nil
+	case "elog":
+		return "abb", []string{"rws", "elog", "--host", host, "--output", dir + ".csv"}, nil
+	case "info":
+		return "abb", []string{"rws", "info", "--host", host, "--robot", target}, nil
+	default: // log
+		if err := makeDir(dir); err != nil {
+			return "", nil, err
+		}
+		return "log", []string{"record", "--host", host, "--signals", task[2], "--duration", task[4],
+			"--out", filepath.Join(dir, stamp+".csv")}, nil
+	}
+}
+
+func addJob(p *project.Project, spec string) (string, error) {
+	if _, _, err := parseJob(spec); err != nil {
+		return "", err
+	}
+	jobs, err := loadJobs(p)
+	if err != nil {
+		return "", err
+	}
+	j := job{ID: 1, Spec: strings.Join(strings.Fields(spec), " "), Created: time.Now().Truncate(time.Second)}
+	for _, other := range jobs {
+		j.ID = max(j.ID, other.ID+1)
+	}
+	if err := saveJobs(p, append(jobs, j)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Added job %d: %s\nNext run %s; start 'jobs daemon' to run it.", j.ID, j.Spec, j.nextRun().Format("2006-01-02 15:04")), nil
+}
+
+func removeJob(p *project.Project, id string) (string, error) {
+	jobs, err := loadJobs(p)
+	if err != nil {
+		return "", err
+	}
+	for i, j := range jobs {
+		if strconv.Itoa(j.ID) == id {
+			if err := saveJobs(p, append(jobs[:i], jobs[i+1:]...)); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Removed job %d: %s", j.ID, j.Spec), nil
+		}
+	}
+	return "", fmt.Errorf("no job %s", id)
+}
+
+func listJobs(p *project.Project) (string, error) {
+	jobs, err := loadJobs(p)
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return `No jobs in this project. Add one with e.g. jobs add "backup cell3 nightly at 02:00".`, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Jobs of %s:\n", p.Name)
+	for _, j := range jobs {
+		last := "never run"
+		if !j.LastRun.IsZero() {
+			status := "failed"
+			if j.LastOK {
+				status = "ok"
+			}
+			last = fmt.Sprintf("last %s %s", j.LastRun.Format("01-02 15:04"), status)
+		}
+		fmt.Fprintf(&b, "  %3d  next %s  %-25s  %s\n", j.ID, j.nextRun().Format("2006-01-02 15:04"), last, j.Spec)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func jobHistory(p *project.Project, args []string) (string, error) {
+	id, last := "", 20
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--last" {
+			id = args[i]
+			continue
+		}
+		if i+1 >= len(args) {
+			return jobsUsage, nil
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("invalid value %q for --last", args[i+1])
+		}
+		last = n
+		i++
+	}
+	f, err := os.Open(filepath.Join(p.Dir, jobsDir, "history.jsonl"))
+	if os.IsNotExist(err) {
+		return "No job has run yet.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var runs []jobRun
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 16<<20)
+	for scanner.Scan() {
+		var r jobRun
+		if json.Unmarshal(scanner.Bytes(), &r) == nil && (id == "" || strconv.Itoa(r.Job) == id) {
+			runs = append(runs, r)
+		}
+	}
+	if len(runs) > last {
+		runs = runs[len(runs)-last:]
+	}
+	if len(runs) == 0 {
+		return "No runs recorded.", nil
+	}
+	var b strings.Builder
+	for _, r := range runs {
+		status := "ok"
+		if !r.OK {
+			status = "FAILED"
+		}
+		first, _, _ := strings.Cut(r.Output, "\n")
+		fmt.Fprintf(&b, "%s  job %-3d %-6s %6.0fs  %s\n    %s\n", r.Started.Format("2006-01-02 15:04:05"), r.Job, status, r.Seconds, r.Spec, first)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// jobDaemon runs the due jobs until interrupted, or once
+func jobDaemon(p *project.Project, once bool) (string, error) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	if !once {
+		fmt.Printf("Running the jobs of %s; Ctrl+C to stop\n", p.Name)
+	}
+	ran := 0
+	for {
+		// Jobs are read on every check, so jobs added meanwhile are picked up
+		jobs, err := loadJobs(p)
+		if err != nil {
+			return "", err
+		}
+		for i := range jobs {
+			if jobs[i].nextRun().After(time.Now()) {
+				continue
+			}
+			runJob(p, &jobs[i])
+			ran++
+			if err := saveJobs(p, jobs); err != nil {
+				return "", err
+			}
+		}
+		if once {
+			return fmt.Sprintf("Ran %d due jobs", ran), nil
+		}
+		select {
+		case <-interrupt:
+			return fmt.Sprintf("\nStopped after %d job runs", ran), nil
+		case <-time.After(jobPoll):
+		}
+	}
+}
+
+// runJob runs a job without confirmations and records the run in the
+// history
+func runJob(p *project.Project, j *job) {
+	started := time.Now()
+	run := jobRun{Job: j.ID, Spec: j.Spec, Started: started.Truncate(time.Second)}
+	name, args, err := j.command(p, started)
+	if err != nil {
+		run.Output = "Error: " + err.Error()
+	} else if cmd, ok := commandRegistry[name]; !ok {
+		run.Output = "Error: unknown command " + name
+	} else {
+		prev := confirmDisabled
+		confirmDisabled = true
+		r := execute(name, cmd, args)
+		confirmDisabled = prev
+		run.OK = !r.Failed()
+		run.Output = strings.TrimSpace(r.String())
+	}
+	run.Seconds = time.Since(started).Round(100 * time.Millisecond).Seconds()
+	j.LastRun, j.LastOK, j.Duration = run.Started, run.OK, run.Seconds
+
+	status := "ok"
+	if !run.OK {
+		status = "failed"
+	}
+	fmt.Printf("%s job %d %s: %s (%.0f s)\n", started.Format("2006-01-02 15:04:05"), j.ID, j.Spec, status, run.Seconds)
+	severity := "info"
+	if !run.OK {
+		severity = "critical"
+	}
+	emitNotification(notification{Source: "jobs", Severity: severity, Time: started,
+		Title: fmt.Sprintf("Job %d %s in %s: %s", j.ID, status, p.Name, j.Spec), Text: run.Output})
+	if err := appendJobRun(p, run); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the run was not recorded: %v\n", err)
+	}
+	if err := pushJobLogs(p); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: the job logs were not uploaded: %v\n", err)
+	}
+}
+
+// pushJobLogs uploads the new job logs and history to the shared store, if
+// the storage setting names one; the next run or storage push retries
+// what fails
+func pushJobLogs(p *project.Project) error {
+	s, err := config.LoadSettings()
+	if err != nil || s.Storage == "" {
+		return err
+	}
+	store, err := projectStore(p)
+	if err != nil {
+		return err
+	}
+	_, err = pushProject(p, store, s.Storage, []string{jobsDir})
+	return err
+}
+
+func appendJobRun(p *project.Project, run jobRun) error {
+	dir := filepath.Join(p.Dir, jobsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "history.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func loadJobs(p *project.Project) ([]job, error) {
+	path := filepath.Join(p.Dir, jobsDir, "jobs.json")
+	var jobs []job
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return jobs, nil
+}
+
+func saveJobs(p *project.Project, jobs []job) error {
+	dir := filepath.Join(p.Dir, jobsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "jobs.json"), append(data, '\n'), 0o644)
+}
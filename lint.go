@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/result"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+const lintUsage = `Usage: lint <file>...
+Without files, the RAPID files of the active project (see use).
+
+RAPID modules are checked for unbalanced blocks such as a missing ENDIF or
+ENDWHILE, syntax errors, names that are not declared in the module or the
+other modules of the task (those in the same directory and the project),
+unreachable code and ERROR handlers, and suspicious zone and speed
+combinations such as fine points at high speed or outputs set in a zone.
+
+Examples:
+  lint MainModule.mod
+  lint RAPID/TASK1/PROGMOD/*.mod`
+
+const statsUsage = `Usage: stats <file.mod>...
+Without files, the RAPID files of the active project (see use).
+Example: stats MainModule.mod`
+
+func init() {
+	commandRegistry["lint"] = Command{
+		Group:       groupCode,
+		Description: "Check RAPID or Structured Text files for problems",
+		Usage:       lintUsage,
+		Execute: func(args []string) result.Result {
+			if len(args) < 1 {
+				files, err := projectFiles()
+				if err != nil {
+					return result.Errorf("%v", err)
+				}
+				args = files
+			}
+			if len(args) < 1 {
+				return result.New(result.Text{Body: lintUsage})
+			}
+			var r result.Result
+			var failed []string
+			for _, path := range args {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					failed = append(failed, err.Error())
+					continue
+				}
+				d, err := lintDiagnostics(path, string(data))
+				if err != nil {
+					failed = append(failed, err.Error())
+					continue
+				}
+				r.Add(d)
+			}
+			r.Err = strings.Join(failed, "\n")
+			return r
+		},
+	}
+
+	commandRegistry["stats"] = Command{
+		Group:       groupCode,
+		Description: "Show statistics for RAPID modules",
+		Usage:       statsUsage,
+		Execute: func(args []string) result.Result {
+			if len(args) < 1 {
+				files, err := projectFiles()
+				if err != nil {
+					return result.Errorf("%v", err)
+				}
+				args = files
+			}
+			if len(args) < 1 {
+				return result.New(result.Text{Body: statsUsage})
+			}
+			var r result.Result
+			var failed []string
+			for _, path := range args {
+				stats, err := moduleStats(path)
+				if err != nil {
+					failed = append(failed, err.Error())
+					continue
+				}
+				r.Add(result.Text{Title: path, Body: stats.String()})
+			}
+			r.Err = strings.Join(failed, "\n")
+			return r
+		},
+	}
+}
+
+// lintFile runs the linter matching the file type and formats its findings
+func lintFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	d, err := lintDiagnostics(path, string(data))
+	if err != nil {
+		return "", err
+	}
+	return result.New(d).String() + "\n", nil
+}
+
+// lintSource lints code using the language implied by the file name
+func lintSource(name, src string) ([]string, error) {
+	d, err := lintDiagnostics(name, src)
+	if err != nil {
+		return nil, err
+	}
+	var findings []string
+	for _, item := range d.Items {
+		findings = append(findings, fmt.Sprintf("line %d: %s: %s", item.Line, item.Severity, item.Message))
+	}
+	return findings, nil
+}
+
+// lintDiagnostics lints code using the language implied by the file name
+func lintDiagnostics(name, src string) (result.Diagnostics, error) {
+	d := result.Diagnostics{File: name, Items: []result.Diagnostic{}}
+	switch {
+	case rapid.IsSourceFile(name):
+		for _, f := range rapid.LintTask(src, taskGlobals(name)) {
+			d.Items = append(d.Items, result.Diagnostic{Line: f.Line, Severity: f.Severity, Message: f.Message})
+		}
+	case st.IsSourceFile(name):
+		for _, f := range st.Lint(src) {
+			d.Items = append(d.Items, result.Diagnostic{Line: f.Line, Severity: rapid.SeverityError, Message: f.Message})
+		}
+	default:
+		return d, fmt.Errorf("%s: unsupported file type (expected RAPID .mod/.sys or ST .st/.scl)", name)
+	}
+	return d, nil
+}
+
+// taskGlobals returns the upper-cased names that the other RAPID modules
+// of the task declare for all modules: those next to the file and those of
+// the active project. lint does not report them as undeclared.
+func taskGlobals(name string) map[string]bool {
+	files, _ := projectFiles()
+	siblings, _ := filepath.Glob(filepath.Join(filepath.Dir(name), "*"))
+	globals := make(map[string]bool)
+	for _, f := range append(files, siblings...) {
+		if !rapid.IsSourceFile(f) || filepath.Clean(f) == filepath.Clean(name) {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		for _, s := range rapid.AnalyzeModule(string(data)).Exported() {
+			globals[strings.ToUpper(s.Name)] = true
+		}
+	}
+	return globals
+}
+
+// statsFile computes statistics for a RAPID module and formats them
+func statsFile(path string) (string, error) {
+	stats, err := moduleStats(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:\n%s\n", path, stats), nil
+}
+
+// moduleStats computes statistics for a RAPID module
+func moduleStats(path string) (rapid.Stats, error) {
+	if !rapid.IsSourceFile(path) {
+		return rapid.Stats{}, fmt.Errorf("%s: stats supports RAPID modules only", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rapid.Stats{}, err
+	}
+	return rapid.ComputeStats(string(data)), nil
+}
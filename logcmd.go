@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/datalog"
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+func init() {
+	commandRegistry["log"] = Command{
+		Group:       groupIntegration,
+		Description: "Record live signal values from a controller to CSV or Parquet",
+		Usage:       logUsage,
+		Execute:     text(runLog),
+	}
+}
+
+const logUsage = `Usage: log record --signals a,b,... --out run1.csv [--source rws] [--host localhost]
+       [--tcp ROB_1 [--tool tool0]] [--rate 10hz] [--duration 30m] [--rotate 1h|50MB]
+       [--user] [--password]
+Records live I/O values for later analysis. Each sample is a row with a
+timestamp and one column per signal; values that cannot be read are left
+empty (NaN in Parquet). Recording stops after --duration or on Ctrl+C.
+--tcp adds the TCP of a mechanical unit in wobj0 as the columns tcp_x,
+tcp_y, tcp_z and tcp_q1..tcp_q4, for analyze path.
+
+The output format follows the extension: .csv, flushed on every row, or
+.parquet for long captures (uncompressed, one row group per 10000
+samples, written when the file is closed). --rotate starts a new numbered
+file (run1_001.csv, run1_002.csv, ...) after a time or size.
+
+Sources: rws (Robot Web Services, RobotWare 6). Every signal is one HTTP
+request per sample, so rates above 10 Hz only work for a few signals.
+
+Examples:
+  log record --signals ai_Pressure,do_Gripper --rate 10hz --out run1.csv
+  log record --host 192.168.125.1 --signals ai_Flow --rate 2hz --rotate 1h --out flow.parquet
+  log record --tcp ROB_1 --tool tGripper --rate 20hz --duration 2m --out path_before.csv`
+
+func runLog(args []string) (string, error) {
+	if len(args) < 1 || args[0] != "record" {
+		return logUsage, nil
+	}
+	source, host, out := "rws", "localhost", ""
+	var user, password string
+	var ch logChannels
+	ch.tool = "tool0"
+	var duration time.Duration
+	var rotation datalog.Rotation
+	interval := 100 * time.Millisecond
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return logUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--source":
+			source = strings.ToLower(value)
+		case "--host":
+			host = value
+		case "--signals":
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					ch.signals = append(ch.signals, s)
+				}
+			}
+		case "--rate":
+			d, err := parseRate(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value %q for --rate; use e.g. 10hz or 250ms", value)
+			}
+			interval = d
+		case "--duration":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid value %q for --duration", value)
+			}
+			duration = d
+		case "--rotate":
+			r, err := datalog.ParseRotation(value)
+			if err != nil {
+				return "", err
+			}
+			rotation = r
+		case "--tcp":
+			ch.mechUnit = value
+		case "--tool":
+			ch.tool = value
+		case "--out":
+			out = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		default:
+			return logUsage, nil
+		}
+		i++
+	}
+	if len(ch.signals) == 0 && ch.mechUnit == "" || out == "" {
+		return logUsage, nil
+	}
+	if source != "rws" {
+		return "", fmt.Errorf("unknown source %q. Available: rws", source)
+	}
+
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	w := &datalog.Rotating{Path: out, Columns: ch.columns(), Rotation: rotation}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Printf("Recording %d values from %s every %s (Ctrl+C to stop)\n", len(ch.columns()), c.Base, interval)
+	start := time.Now()
+	samples, failures, err := recordSignals(c, ch, w, interval, duration, interrupt)
+	if cerr := w.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	result := fmt.Sprintf("Recorded %d samples in %.1f s to %s", samples, time.Since(start).Seconds(), strings.Join(w.Files, ", "))
+	if failures > 0 {
+		result += fmt.Sprintf("\nWarning: %d reads failed and were left empty", failures)
+	}
+	return result, nil
+}
+
+// logChannels are the values log record samples: signals and optionally
+// the TCP of a mechanical unit
+type logChannels struct {
+	signals        []string
+	mechUnit, tool string
+}
+
+func (ch logChannels) columns() []string {
+	columns := append([]string{}, ch.signals...)
+	if ch.mechUnit != "" {
+		columns = append(columns, "tcp_x", "tcp_y", "tcp_z", "tcp_q1", "tcp_q2", "tcp_q3", "tcp_q4")
+	}
+	return columns
+}
+
+// recordSignals samples until the duration passes or stop fires. A
+// controller that cannot be reached ends the recording, a signal it refuses
+// to read is logged as empty.
+func recordSignals(c *rws.Client, ch logChannels, w datalog.Writer, interval, duration time.Duration, stop <-chan os.Signal) (samples, failures int, err error) {
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		values := make([]float64, len(ch.columns()))
+		for i, s := range ch.signals {
+			values[i] = math.NaN()
+			text, err := c.ReadSignal(s)
+			if _, refused := err.(*rws.Error); err != nil && !refused {
+				return samples, failures, err
+			}
+			if err != nil {
+				failures++
+				continue
+			}
+			if v, err := strconv.ParseFloat(text, 64); err == nil {
+				values[i] = v
+			}
+		}
+		if ch.mechUnit != "" {
+			pose, err := c.RobotTarget(ch.mechUnit, ch.tool, "wobj0")
+			if _, refused := err.(*rws.Error); err != nil && !refused {
+				return samples, failures, err
+			}
+			for i := range pose {
+				values[len(ch.signals)+i] = math.NaN()
+				if err == nil {
+					values[len(ch.signals)+i] = pose[i]
+				}
+			}
+			if err != nil {
+				failures++
+			}
+		}
+		if err := w.Write(now, values); err != nil {
+			return samples, failures, err
+		}
+		samples++
+		if duration > 0 && time.Since(start) >= duration {
+			return samples, failures, nil
+		}
+		select {
+		case <-stop:
+			fmt.Println()
+			return samples, failures, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseRate reads a sample rate as frequency (10hz) or interval (100ms)
+func parseRate(s string) (time.Duration, error) {
+	lower := strings.ToLower(s)
+	if strings.HasSuffix(lower, "hz") {
+		hz, err := strconv.ParseFloat(strings.TrimSuffix(lower, "hz"), 64)
+		if err != nil || hz <= 0 || hz > 1000 {
+			return 0, fmt.Errorf("invalid rate")
+		}
+		return time.Duration(float64(time.Second) / hz), nil
+	}
+	d, err := time.ParseDuration(lower)
+	if err != nil || d < time.Millisecond {
+		return 0, fmt.Errorf("invalid rate")
+	}
+	return d, nil
+}
@@ -4,56 +4,148 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/polyfant/automation-helper-cli/abb"
-	"github.com/polyfant/automation-helper-cli/ai"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/i18n"
+	"github.com/polyfant/automation-helper-cli/result"
+	"golang.org/x/term"
 )
 
 // Command represents an automation command with its description and implementation
 type Command struct {
+	Group       string
 	Description string
-	Execute     func(args []string) string
+	// Usage is the detailed help shown by help <command>; its Examples
+	// section lists runnable command lines
+	Usage   string
+	Execute func(args []string) result.Result
 }
 
+// text adapts a command that formats its own output as text. Output
+// returned with an error is shown before it.
+func text(fn func(args []string) (string, error)) func(args []string) result.Result {
+	return func(args []string) result.Result {
+		out, err := fn(args)
+		return result.FromString(out, err)
+	}
+}
+
+// Command groups, in the order they are shown by help
+const (
+	groupReference   = "Reference"
+	groupCode        = "Code"
+	groupCalc        = "Calculators"
+	groupAI          = "AI"
+	groupIntegration = "Integration"
+	groupSystem      = "System"
+)
+
+var groupOrder = []string{groupReference, groupCode, groupCalc, groupAI, groupIntegration, groupSystem}
+
 // commandRegistry stores all available commands
 var commandRegistry = make(map[string]Command)
 
-func init() {
-	// Register commands
-	commandRegistry["sensor"] = Command{
-		Description: "Generate sensor code",
-		Execute:     generateSensorCode,
+// input is shared by the REPL and commands that need to ask follow-up questions
+var input = bufio.NewScanner(os.Stdin)
+
+// readLine prints a prompt and returns the next trimmed line of user input
+func readLine(prompt string) (string, bool) {
+	fmt.Print(prompt)
+	if !input.Scan() {
+		return "", false
 	}
+	return strings.TrimSpace(input.Text()), true
+}
 
-	commandRegistry["ai"] = Command{
-		Description: "Get AI assistance with ABB RAPID code",
-		Execute: func(args []string) string {
-			if len(args) < 2 {
-				return "Usage: ai help \"your question about the code\""
-			}
+// readPassword is readLine for secrets: the input is not echoed when it
+// comes from a terminal
+func readPassword(prompt string) (string, bool) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(prompt)
+	}
+	fmt.Print(prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
 
-			apiKey := os.Getenv("OPENAI_API_KEY")
-			if apiKey == "" {
-				return "Error: OPENAI_API_KEY environment variable not set"
+// splitArgs splits a command line into arguments like a shell: single
+// quotes keep their text as it is and double quotes allow \" and \\.
+// Other backslashes are kept, for Windows paths.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var b strings.Builder
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
 			}
-
-			assistant := ai.NewAssistant(apiKey)
-			response, err := assistant.GetHelp(strings.Join(args[1:], " "))
-			if err != nil {
-				return fmt.Sprintf("Error getting AI help: %v", err)
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' quote")
+			}
+			b.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+					i++
+				}
+				b.WriteByte(line[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated \" quote")
 			}
+			inArg = true
+		default:
+			b.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
 
-			return response
-		},
+// quoteArgs joins arguments into a command line that splitArgs splits back
+// into the same arguments, quoting those with spaces or quotes
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		switch {
+		case a != "" && !strings.ContainsAny(a, " \t'\""):
+			quoted[i] = a
+		case !strings.Contains(a, "'"):
+			quoted[i] = "'" + a + "'"
+		default:
+			quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a) + `"`
+		}
 	}
+	return strings.Join(quoted, " ")
+}
 
-	// Add ABB specific commands
-	commandRegistry["abb"] = Command{
-		Description: "Get ABB robot programming information and examples",
-		Execute: func(args []string) string {
-			if len(args) < 1 {
-				return `Usage: abb <topic> [subtopic]
+const abbUsage = `Usage: abb <topic> [subtopic]
 Available topics:
 1. command  - Show RAPID command details
 2. quickref - Show programming reference
@@ -62,135 +154,439 @@ Available topics:
 Examples:
   abb command move_j     - Show MoveJ command details
   abb quickref io        - Show I/O handling guide
-  abb list              - List all available commands`
+  abb list              - List all available commands
+  abb list --group      - List commands grouped by category
+  abb search gripper     - Search commands, topics and error codes
+  abb header Main.mod    - Insert or refresh the module header
+  abb bump-rev Main.mod "Added pick routine" - Add a revision entry
+  abb backup sync <backup> <project> - Copy a controller backup into the project
+  abb transform Main.mod --translate 0,0,50 - Move robtargets
+  abb mirror Main.mod --plane xz - Mirror robtargets for the opposite-hand cell
+  abb deps ./T_ROB1      - Check module dependencies and load order
+  abb extract-const Main.mod - Replace repeated numbers with named constants
+  abb extract-proc Main.mod --lines 12-30 --name PickPart - Move lines into a new PROC
+  abb rename-targets Main.mod - Give p10-style targets meaningful names
+  abb round-targets Main.mod --mm 0.1 --deg 0.01 - Round re-taught robtargets
+  abb generate welddata --plate 3mm --process MAG - Starting weld parameters
+  abb generate dispense glue.csv - Analog scaling and RAPID code from a datasheet
+  abb generate vision --ip 192.168.125.50 - Camera-guided picking module
+  abb generate trace --pattern AAA-9999 - Barcode/RFID traceability code
+  abb format ./RAPID     - Lay out modules in the project's code style
+  abb score ./RAPID --trend 10 - Rate code quality and its trend over commits
+  abb parts irb6700 battery - Spare part numbers for a robot
+  abb revcounter guide ./Backup - Revolution counter update with calibration log
+  abb compat ./RAPID --target rw7 - Check modules before moving to OmniCore
+  abb modernize Main.mod --dry-run - Rewrite legacy constructs, showing the diff
+  abb vc run ./RAPID --entry RunTests - Run modules on a virtual controller
+  abb rws pp-to-main --host 192.168.125.1 - Program pointer, start, stop and motors
+  abb rws info --robot cell3-r1 - Snapshot controller serial, RobotWare and options
+  abb rws backup create --retain 14 - Back up a controller into the project
+  abb rws module put MainModule.mod --target cell3 - Deploy modules to a controller
+  abb uas list ./Backup  - Users, groups and grants, with common mistakes
+  abb options ./RAPID --backup ./Backup - RobotWare options the program needs
+  abb examples build --output ./examples - Verify reference examples as modules`
+
+const sensorUsage = `Usage: sensor <type> <action>
+Example: sensor digital when_on`
+
+func init() {
+	// Register commands
+	commandRegistry["sensor"] = Command{
+		Group:       groupCode,
+		Description: "Generate sensor code",
+		Usage:       sensorUsage,
+		Execute:     text(generateSensorCode),
+	}
+
+	commandRegistry["ai"] = Command{
+		Group:       groupAI,
+		Description: "Get AI assistance with ABB RAPID code",
+		Usage:       aiUsage,
+		Execute:     text(runAI),
+	}
+
+	// Add ABB specific commands
+	commandRegistry["abb"] = Command{
+		Group:       groupReference,
+		Description: "Get ABB robot programming information and examples",
+		Usage:       abbUsage,
+		Execute: text(func(args []string) (string, error) {
+			if len(args) < 1 {
+				return abbUsage, nil
 			}
 
 			switch args[0] {
 			case "command":
 				if len(args) < 2 {
 					// List all available commands
-					var cmdList []string
-					for cmd := range abb.Commands {
-						cmdList = append(cmdList, cmd)
-					}
-					return "Available commands:\n" + strings.Join(cmdList, ", ")
+					return "Available commands:\n" + strings.Join(abb.CommandKeys(), ", "), nil
 				}
-				if cmd, exists := abb.Commands[args[1]]; exists {
-					return fmt.Sprintf("\nCommand: %s\nSyntax: %s\n\nExample:\n%s\n\nDescription:\n%s",
-						cmd.Name, cmd.Syntax, cmd.Example, cmd.Description)
+				if cmd, exists := abb.Command(args[1]); exists {
+					text := fmt.Sprintf("\n%s: %s\n%s: %s\n\n%s:\n%s\n\n%s:\n%s",
+						i18n.T("label.command"), cmd.Name, i18n.T("label.syntax"), cmd.Syntax,
+						i18n.T("label.example"), cmd.Example, i18n.T("label.description"), cmd.Description)
+					if c, ok := abb.Compatibility(cmd.Name); ok {
+						text += "\n\n" + formatCompat(c)
+					}
+					return text, nil
 				}
-				return "Unknown ABB command. Type 'abb command' to see available commands."
+				return "Unknown ABB command. Type 'abb command' to see available commands.", nil
 
 			case "quickref":
 				if len(args) < 2 {
 					// List all quick reference topics
-					var topics []string
-					for topic := range abb.QuickReference {
-						topics = append(topics, topic)
-					}
-					return "Available quick reference topics:\n" + strings.Join(topics, ", ")
+					return "Available quick reference topics:\n" + strings.Join(abb.Topics(), ", "), nil
 				}
-				if info, exists := abb.QuickReference[args[1]]; exists {
-					return info
+				if info, exists := abb.Topic(args[1]); exists {
+					return info, nil
 				}
-				return "Unknown topic. Type 'abb quickref' to see available topics."
+				return "Unknown topic. Type 'abb quickref' to see available topics.", nil
 
 			case "list":
 				var result strings.Builder
 				result.WriteString("\nABB RAPID Commands:\n")
 				result.WriteString("================\n")
-				for _, cmd := range abb.Commands {
+				if len(args) > 1 && args[1] == "--group" {
+					for _, category := range abb.CommandCategories() {
+						var lines []string
+						for _, key := range abb.CommandKeys() {
+							if cmd, _ := abb.Command(key); cmd.Category == category {
+								summary, _, _ := strings.Cut(cmd.Description, "\n")
+								lines = append(lines, fmt.Sprintf("  %-14s - %s\n", cmd.Name, summary))
+							}
+						}
+						if len(lines) == 0 {
+							continue
+						}
+						if category == "" {
+							category = "Other"
+						}
+						result.WriteString("\n" + category + ":\n" + strings.Join(lines, ""))
+					}
+					return result.String(), nil
+				}
+				for _, key := range abb.CommandKeys() {
+					cmd, _ := abb.Command(key)
 					result.WriteString(fmt.Sprintf("%-10s - %s\n", cmd.Name, cmd.Description))
 				}
-				return result.String()
+				return result.String(), nil
+
+			case "search":
+				if len(args) < 2 {
+					return "Usage: abb search <words>\nExample: abb search gripper signal", nil
+				}
+				matches := abb.Search(strings.Join(args[1:], " "))
+				if len(matches) == 0 {
+					return "No matches found.", nil
+				}
+				var result strings.Builder
+				result.WriteString("\nSearch results:\n")
+				for i, m := range matches {
+					if i == 10 {
+						break
+					}
+					result.WriteString(fmt.Sprintf("  %-9s %s\n", m.Kind, m.Key))
+				}
+				return result.String(), nil
+
+			case "header":
+				return abbHeader(args[1:])
+
+			case "bump-rev":
+				return abbBumpRev(args[1:])
+
+			case "backup":
+				return abbBackup(args[1:])
+
+			case "transform":
+				return abbTransform(args[1:])
+
+			case "mirror":
+				return abbMirror(args[1:])
+
+			case "deps":
+				return abbDeps(args[1:])
+
+			case "extract-const":
+				return abbExtractConst(args[1:])
+
+			case "extract-proc":
+				return abbExtractProc(args[1:])
+
+			case "rename-targets":
+				return abbRenameTargets(args[1:])
+
+			case "round-targets":
+				return abbRoundTargets(args[1:])
+
+			case "generate":
+				return abbGenerate(args[1:])
+
+			case "format":
+				return abbFormat(args[1:])
+
+			case "score":
+				return abbScore(args[1:])
+
+			case "parts":
+				return abbParts(args[1:])
+
+			case "revcounter":
+				return abbRevCounter(args[1:])
+
+			case "compat":
+				return abbCompat(args[1:])
+
+			case "modernize":
+				return abbModernize(args[1:])
+
+			case "vc":
+				return abbVC(args[1:])
+
+			case "rws":
+				return abbRWS(args[1:])
+
+			case "uas":
+				return abbUAS(args[1:])
+
+			case "options":
+				return abbOptions(args[1:])
+
+			case "examples":
+				return abbExamples(args[1:])
 
 			default:
-				return "Unknown ABB subcommand. Available: command, quickref, list"
+				return "Unknown ABB subcommand. Available: command, quickref, list, search, header, bump-rev, backup, transform, mirror, deps, extract-const, extract-proc, rename-targets, round-targets, generate, format, score, parts, revcounter, compat, modernize, vc, rws, uas, options, examples", nil
 			}
-		},
+		}),
 	}
 }
 
-func generateSensorCode(args []string) string {
+func generateSensorCode(args []string) (string, error) {
 	if len(args) < 2 {
-		return "Usage: sensor <type> <action>\nExample: sensor digital when_on"
+		return sensorUsage, nil
 	}
 
-	sensorType := args[0]
-	action := args[1]
+	return generating("sensor", args, func() (string, error) {
+		return generate.SensorCode(args[0], args[1])
+	})
+}
 
-	switch sensorType {
-	case "digital":
-		return generateDigitalSensorCode(action)
-	case "analog":
-		return generateAnalogSensorCode(action)
-	default:
-		return "Unknown sensor type. Available types: digital, analog"
+// commandNames returns the registered command names in sorted order
+func commandNames() []string {
+	names := make([]string, 0, len(commandRegistry))
+	for name := range commandRegistry {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
-func generateDigitalSensorCode(action string) string {
-	switch action {
-	case "when_on":
-		return `
-PLC Ladder Logic:
-|--[INPUT]--|--[OUTPUT]--|
+func printHelp(args []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		printCommandHelp(args)
+		return
+	}
+	fmt.Println("\nAutomation Helper CLI")
+	fmt.Println("====================")
+
+	flat, all := false, false
+	for _, a := range args {
+		switch a {
+		case "--flat":
+			flat = true
+		case "--all":
+			all = true
+		}
+	}
+	profileName, p := activeProfile()
+	if all {
+		profileName, p = "", profile{}
+	}
+	hidden := 0
+	var visible []string
+	for _, name := range commandNames() {
+		if hiddenByProfile(p, name) {
+			hidden++
+			continue
+		}
+		visible = append(visible, name)
+	}
 
-ABB Robot:
-IF DI_01 = 1 THEN
-    ! Your action here
-ENDIF
+	if flat {
+		fmt.Println("\nAvailable commands:")
+		for _, name := range visible {
+			fmt.Printf("  %-8s %s\n", name, commandRegistry[name].Description)
+		}
+		printHelpFooter(profileName, p, hidden, "Type 'exit' to quit")
+		return
+	}
 
-Siemens S7:
-IF "Input_Bit" THEN
-    // Your action here
-END_IF`
-	default:
-		return "Unknown action for digital sensor"
+	featured := make(map[string]bool)
+	if len(p.Featured) > 0 {
+		fmt.Printf("\nFor %s:\n", profileName)
+		for _, name := range p.Featured {
+			if cmd, ok := commandRegistry[name]; ok {
+				featured[name] = true
+				fmt.Printf("  %-8s %s\n", name, cmd.Description)
+			}
+		}
+	}
+	known := make(map[string]bool)
+	for _, group := range groupOrder {
+		known[group] = true
 	}
+	for _, group := range append(groupOrder, "Other") {
+		var names []string
+		for _, name := range visible {
+			g := commandRegistry[name].Group
+			if !featured[name] && (g == group || (group == "Other" && !known[g])) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", group)
+		for _, name := range names {
+			fmt.Printf("  %-8s %s\n", name, commandRegistry[name].Description)
+		}
+	}
+	printHelpFooter(profileName, p, hidden, "Type 'help --flat' for a plain sorted list, 'exit' to quit")
 }
 
-func generateAnalogSensorCode(action string) string {
-	return `
-PLC Ladder Logic:
-|--[ANALOG_IN]--|--[SCALE]--|--[COMPARE]--|--[OUTPUT]--|
+// printHelpFooter ends help with the profile notes and the closing hint
+func printHelpFooter(profileName string, p profile, hidden int, closing string) {
+	fmt.Println()
+	if hidden > 0 {
+		fmt.Printf("%d commands are hidden by the %s profile; 'help --all' shows them\n", hidden, profileName)
+	}
+	if p.Verbose {
+		fmt.Println("Type a command on its own, such as 'abb' or 'calc', to see its usage")
+	}
+	fmt.Println("Type 'help <command>' for its usage and runnable examples")
+	if readOnly() {
+		fmt.Println("Read-only mode: commands that write to controllers or files are disabled")
+	} else {
+		fmt.Println("Add --dry-run to a command to see the files it would change without writing them")
+	}
+	fmt.Println(closing)
+}
 
-ABB Robot:
-IF AI_01 > SET_POINT THEN
-    ! Your action here
-ENDIF
+// cliUsage describes running a single command from a shell
+const cliUsage = `Usage: automation-helper [options] <command> [arguments]
+       automation-helper              Start the interactive prompt
+
+Options, given before the command:
+  -o, --output <format>  Output format: plain, color, json or markdown
+                         (default: color on a terminal, plain otherwise)
+      --no-color         Plain text even on a terminal (as does NO_COLOR)
+      --read-only        Disable everything that writes to controllers or
+                         files; without a command, for the whole session
+  -h, --help             Show this help, or the help of a command
+      --version          Show the version
+      --                 End the options; the next word is the command
+
+The exit status is 1 for an unknown command or a command that reports an
+error, 2 for invalid options. With --output json, errors are written to
+standard output as {"blocks": [], "error": "..."}, so scripts read one
+stream.
 
-Siemens S7:
-IF "Analog_Input" > "Set_Point" THEN
-    // Your action here
-END_IF`
+Examples:
+  automation-helper abb command move_j
+  automation-helper --output json sensor digital when_on
+  automation-helper --no-color lint RAPID/T_ROB1/*.mod > lint.txt
+  automation-helper --read-only`
+
+// cliOptions are the options given before the command name
+type cliOptions struct {
+	renderer result.Renderer // nil chooses by the terminal
+	readOnly bool
+	help     bool
+	version  bool
 }
 
-func printHelp() {
-	fmt.Println("\nAutomation Helper CLI")
-	fmt.Println("====================")
-	fmt.Println("\nAvailable commands:")
-	for cmd, info := range commandRegistry {
-		fmt.Printf("  %s: %s\n", cmd, info.Description)
+// parseCLI splits the options before the command name from the command
+// line. Options after the command name belong to the command.
+func parseCLI(args []string) (cliOptions, []string, error) {
+	var opts cliOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return opts, args[i:], nil
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--":
+			return opts, args[i+1:], nil
+		case "-o", "--output":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, nil, fmt.Errorf("%s needs a format", name)
+				}
+				value = args[i+1]
+				i++
+			}
+			r, ok := result.Renderers[strings.ToLower(value)]
+			if !ok {
+				return opts, nil, fmt.Errorf("unknown output format %q; use plain, color, json or markdown", value)
+			}
+			opts.renderer = r
+		case "--no-color":
+			if opts.renderer == nil || opts.renderer == result.Color {
+				opts.renderer = result.Plain
+			}
+		case "--read-only":
+			opts.readOnly = true
+		case "-h", "--help":
+			opts.help = true
+		case "--version":
+			opts.version = true
+		default:
+			return opts, nil, fmt.Errorf("unknown option %s", arg)
+		}
 	}
-	fmt.Println("\nType 'exit' to quit")
+	return opts, nil, nil
 }
 
 func main() {
+	applySettings()
+
+	// Arguments run a single command and exit, for scripts, Makefiles,
+	// editors and git hooks
+	if len(os.Args) > 1 {
+		opts, args, err := parseCLI(os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n\n%s\n", err, cliUsage)
+			os.Exit(2)
+		}
+		readOnlyFlag = opts.readOnly
+		if len(args) > 0 || opts.help || opts.version || !opts.readOnly {
+			os.Exit(runOnce(opts, args))
+		}
+	}
+
 	fmt.Println("Welcome to Automation Helper CLI!")
+	if readOnly() {
+		fmt.Println("Read-only mode: commands that write to controllers or files are disabled")
+	}
 	fmt.Println("Type 'help' for available commands or 'exit' to quit")
-
-	scanner := bufio.NewScanner(os.Stdin)
+	if s, err := config.LoadSettings(); err == nil && len(s.Tour) == 0 {
+		fmt.Println("New here? Type 'tour' for a guided walkthrough of the main workflows")
+	}
 
 	for {
-		fmt.Print("\n> ")
-		if !scanner.Scan() {
+		line, ok := readLine("\n> ")
+		if !ok {
+			lockVaults()
 			break
 		}
 
-		input := scanner.Text()
-		args := strings.Fields(input)
-
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
 		if len(args) == 0 {
 			continue
 		}
@@ -199,17 +595,70 @@ func main() {
 
 		switch command {
 		case "exit":
+			lockVaults()
 			fmt.Println("Goodbye!")
 			return
 		case "help":
-			printHelp()
+			printHelp(args[1:])
 		default:
 			if cmd, exists := commandRegistry[command]; exists {
-				result := cmd.Execute(args[1:])
-				fmt.Println(result)
+				fmt.Println(render(execute(command, cmd, args[1:]), os.Stdout))
 			} else {
 				fmt.Printf("Unknown command: %s\nType 'help' for available commands\n", command)
 			}
 		}
 	}
 }
+
+// runOnce executes one command outside the REPL. It returns exit status 1 for
+// unknown commands and results reporting an error.
+func runOnce(opts cliOptions, args []string) int {
+	switch {
+	case opts.version:
+		fmt.Println("automation-helper " + version)
+		return 0
+	case opts.help && len(args) > 0:
+		printHelp(args[:1])
+		return 0
+	case opts.help || len(args) == 0:
+		fmt.Println(cliUsage)
+		return 0
+	}
+	command := strings.ToLower(args[0])
+	if command == "help" {
+		printHelp(args[1:])
+		return 0
+	}
+	cmd, exists := commandRegistry[command]
+	if !exists {
+		if opts.renderer == result.JSON {
+			fmt.Println(result.JSON.Render(result.Errorf("unknown command: %s", command)))
+		} else {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		}
+		return 1
+	}
+	r := execute(command, cmd, args[1:])
+	out := os.Stdout
+	if r.Failed() && opts.renderer != result.JSON {
+		out = os.Stderr
+	}
+	if opts.renderer != nil {
+		fmt.Fprintln(out, opts.renderer.Render(r))
+	} else {
+		fmt.Fprintln(out, render(r, out))
+	}
+	if r.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// render formats a result for a terminal, in color unless the output is
+// redirected or NO_COLOR is set
+func render(r result.Result, out *os.File) string {
+	if info, err := out.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == "" {
+		return result.Color.Render(r)
+	}
+	return result.Plain.Render(r)
+}
@@ -0,0 +1,219 @@
+// Package message renders protocol test messages from templates with
+// field substitution and checksums
+package message
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/checksum"
+)
+
+// Template is a named message with default field values
+type Template struct {
+	Name        string
+	Description string
+	Text        string
+	Defaults    map[string]string
+}
+
+// Builtin are the templates for the socket code the generators write
+var Builtin = []Template{
+	{
+		Name:        "vision_result",
+		Description: "Camera result as read by generate vision (ok,x,y,angle,score)",
+		Text:        "{ok},{x},{y},{angle},{score}",
+		Defaults:    map[string]string{"ok": "1", "x": "12.5", "y": "-4.2", "angle": "1.5", "score": "0.93"},
+	},
+	{
+		Name:        "scan_code",
+		Description: "Code reader result as read by generate trace",
+		Text:        "{code}\\r\\n",
+		Defaults:    map[string]string{"code": "AAA-1234567"},
+	},
+	{
+		Name:        "mes_ack",
+		Description: "MES acknowledge of a traceability record",
+		Text:        "ACK,{serial}\\r\\n",
+		Defaults:    map[string]string{"serial": "AAA-1234567"},
+	},
+	{
+		Name:        "stx_etx",
+		Description: "STX data ETX framing with an XOR check byte as two hex digits",
+		Text:        "\\x02{data}\\x03{xor}",
+		Defaults:    map[string]string{"data": "JOB,{seq}"},
+	},
+}
+
+// Find returns a built-in template by name
+func Find(name string) (Template, bool) {
+	for _, t := range Builtin {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Checksums that can be placed in a template. They cover the bytes before
+// them, from the last STX (0x02) if there is one. The value is written as
+// hex digits; {name:bin} writes the raw bytes, CRCs low byte first.
+var Checksums = []string{"xor", "lrc", "sum8", "crc16"}
+
+// Render builds a message. Placeholders are {field}, filled from values
+// and then from the template defaults (which may contain placeholders
+// themselves), {seq} for the message number, {time} and {date}, and the
+// checksums. The escapes \r, \n, \t, \\, \{ and \xHH give special bytes.
+func Render(t Template, values map[string]string, seq int) ([]byte, error) {
+	return render(t.Text, t, values, seq, 0)
+}
+
+func render(text string, t Template, values map[string]string, seq, depth int) ([]byte, error) {
+	if depth > 4 {
+		return nil, fmt.Errorf("fields of %s refer to each other", t.Name)
+	}
+	var out []byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			b, next, err := unescape(text, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+			i = next
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed { in %q", text)
+			}
+			name := text[i+1 : i+end]
+			i += end
+			part, err := placeholder(name, out, t, values, seq, depth)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, part...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// unescape reads the escape at text[i] and returns its byte and the index
+// of its last character
+func unescape(text string, i int) (byte, int, error) {
+	i++
+	switch text[i] {
+	case 'r':
+		return '\r', i, nil
+	case 'n':
+		return '\n', i, nil
+	case 't':
+		return '\t', i, nil
+	case 'x':
+		if i+2 >= len(text) {
+			return 0, i, fmt.Errorf("incomplete \\x escape")
+		}
+		b, err := strconv.ParseUint(text[i+1:i+3], 16, 8)
+		if err != nil {
+			return 0, i, fmt.Errorf("invalid escape \\x%s", text[i+1:i+3])
+		}
+		return byte(b), i + 2, nil
+	}
+	return text[i], i, nil
+}
+
+// Unescape turns text with the escapes of templates into bytes
+func Unescape(text string) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			out = append(out, text[i])
+			continue
+		}
+		b, next, err := unescape(text, i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+		i = next
+	}
+	return out, nil
+}
+
+func placeholder(name string, before []byte, t Template, values map[string]string, seq, depth int) ([]byte, error) {
+	field, format, _ := strings.Cut(name, ":")
+	switch field {
+	case "seq":
+		return []byte(strconv.Itoa(seq)), nil
+	case "time":
+		return []byte(time.Now().Format("15:04:05")), nil
+	case "date":
+		return []byte(time.Now().Format("2006-01-02")), nil
+	case "xor", "lrc", "sum8", "crc16":
+		data := before
+		if stx := bytes.LastIndexByte(before, 0x02); stx >= 0 {
+			data = before[stx+1:]
+		}
+		var sum []byte
+		switch field {
+		case "xor":
+			sum = []byte{checksum.XOR(data)}
+		case "lrc":
+			sum = []byte{checksum.LRC(data)}
+		case "sum8":
+			sum = []byte{checksum.Sum8(data)}
+		case "crc16":
+			sum = binary.LittleEndian.AppendUint16(nil, checksum.CRC16Modbus(data))
+		}
+		if format == "bin" {
+			return sum, nil
+		}
+		if field == "crc16" {
+			return []byte(fmt.Sprintf("%04X", binary.LittleEndian.Uint16(sum))), nil
+		}
+		return []byte(fmt.Sprintf("%02X", sum[0])), nil
+	}
+	if v, ok := values[field]; ok {
+		return []byte(v), nil
+	}
+	if v, ok := t.Defaults[field]; ok {
+		return render(v, t, values, seq, depth+1)
+	}
+	return nil, fmt.Errorf("no value for {%s}; set it with --set %s=...", field, field)
+}
+
+// Fields lists the placeholders of a template that take values
+func Fields(t Template) []string {
+	seen := map[string]bool{}
+	var fields []string
+	rest := t.Text
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			break
+		}
+		name, _, _ := strings.Cut(rest[start+1:start+end], ":")
+		rest = rest[start+end+1:]
+		switch name {
+		case "seq", "time", "date", "xor", "lrc", "sum8", "crc16":
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
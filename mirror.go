@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const mirrorUsage = `Usage: abb mirror <file.mod> --plane <yz|xz|xy> [options]
+Writes a mirrored copy of a station program for the opposite-hand cell.
+Positions and orientations are reflected; configuration data is checked
+and flagged where it needs to be recomputed.
+
+Options:
+  --plane <yz|xz|xy>   Mirror plane in the target frame (required)
+  --offset <mm>        Position of the plane along its normal (default 0)
+  --only-prefix <name> Only mirror targets whose name starts with <name>
+  --module <name>      Rename the module in the copy
+  --output <file>      Output file (default <file>_mirror.mod)
+
+Example:
+  abb mirror StationLeft.mod --plane xz --module StationRight --output StationRight.mod`
+
+// abbMirror writes a mirrored copy of a module's robtargets
+func abbMirror(args []string) (string, error) {
+	if len(args) < 3 {
+		return mirrorUsage, nil
+	}
+	path := args[0]
+	ext := filepath.Ext(path)
+	output := strings.TrimSuffix(path, ext) + "_mirror" + ext
+	var m calc.Mirror
+	prefix, module := "", ""
+	havePlane := false
+
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return mirrorUsage, nil
+		}
+		opt, value := args[i], args[i+1]
+		i++
+		switch opt {
+		case "--plane":
+			axis, err := calc.ParseMirrorPlane(value)
+			if err != nil {
+				return "", err
+			}
+			m.Axis, havePlane = axis, true
+		case "--offset":
+			offset, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid offset: %s", value)
+			}
+			m.Offset = offset
+		case "--only-prefix":
+			prefix = value
+		case "--module":
+			module = value
+		case "--output":
+			output = value
+		default:
+			return mirrorUsage, nil
+		}
+	}
+	if !havePlane {
+		return mirrorUsage, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var mirrored, warnings []string
+	out := rapid.ReplaceTargets(string(data), func(t rapid.Target) (rapid.Target, bool) {
+		if !strings.HasPrefix(t.Name, prefix) {
+			return t, false
+		}
+		t.Trans, t.Rot = m.Apply(t.Trans, t.Rot)
+		mirrored = append(mirrored, t.Name)
+		// Only axis 1 can be estimated from the position; the wrist
+		// quadrants depend on the robot's kinematics
+		if cf1 := calc.AxisOneQuadrant(t.Trans); cf1 != int(t.Conf[0]) {
+			warnings = append(warnings, fmt.Sprintf("  %s: cf1 %g -> %d (estimated from position)", t.Name, t.Conf[0], cf1))
+			t.Conf[0] = float64(cf1)
+		}
+		return t.Rounded(2, 9), true
+	})
+	if len(mirrored) == 0 {
+		return "No matching robtargets found; nothing written.", nil
+	}
+	if module != "" {
+		if out, err = rapid.RenameModule(out, module); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFile(output, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Mirrored %d robtargets into %s\n", len(mirrored), output))
+	if len(warnings) > 0 {
+		result.WriteString("\nConfiguration changed for:\n" + strings.Join(warnings, "\n") + "\n")
+	}
+	result.WriteString("\nWarning: cf4 and cf6 were kept and may not match the mirrored wrist pose.\n" +
+		"The mirrored tool must be a mirror image of the original. Verify every target\n" +
+		"at reduced speed, using ConfL\\Off/ConfJ\\Off where the robot reports configuration errors.")
+	return result.String(), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/signals"
+)
+
+func init() {
+	commandRegistry["modbus"] = Command{
+		Group:       groupIntegration,
+		Description: "Generate a Modbus register map and PLC mapping from a signal list",
+		Usage:       modbusUsage,
+		Execute:     text(runModbus),
+	}
+}
+
+const modbusUsage = `Usage: modbus map <signals.csv|EIO.cfg> [--base 0] [--title text] [--output dir]
+Assigns holding registers to the signals of the cell interface and produces
+a markdown interface specification and Structured Text mapping code.
+
+The CSV needs the columns name and type, and optionally direction,
+description and unit. Types: bool, int, uint, dint, real or the ABB signal
+types DI, DO, GI, GO, AI, AO. Directions (seen from the robot):
+robot_to_plc or plc_to_robot; ABB signal types imply the direction.
+
+Examples:
+  modbus map signals.csv
+  modbus map signals.csv --base 100 --output doc/interface`
+
+func runModbus(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "map" {
+		return modbusUsage, nil
+	}
+	path := args[1]
+	base, title, output := 0, "Robot cell interface", ""
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return modbusUsage, nil
+		}
+		switch args[i] {
+		case "--base":
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 || n > 65535 {
+				return "", errors.New("--base must be a register offset between 0 and 65535")
+			}
+			base = n
+		case "--title":
+			title = args[i+1]
+		case "--output":
+			output = args[i+1]
+		default:
+			return modbusUsage, nil
+		}
+		i++
+	}
+
+	list, err := signals.Load(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	m := signals.Modbus(list, base)
+	if m.End > 65536 {
+		return "", fmt.Errorf("the map needs registers up to offset %d, beyond the Modbus address range", m.End-1)
+	}
+	spec := m.Markdown(title)
+	st := m.ST(filepath.Base(path))
+
+	if output == "" {
+		return spec + "\n```\n" + strings.TrimRight(st, "\n") + "\n```", nil
+	}
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	specPath := filepath.Join(output, "modbus_map.md")
+	stPath := filepath.Join(output, "ModbusMapping.st")
+	if err := writeFile(specPath, []byte(spec), 0644); err != nil {
+		return "", err
+	}
+	if err := writeFile(stPath, []byte(st), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Mapped %d signals to registers %d-%d\nWrote %s and %s", len(list), m.Base, m.End-1, specPath, stPath), nil
+}
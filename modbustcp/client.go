@@ -0,0 +1,130 @@
+// Package modbustcp is a minimal Modbus TCP client for reading PLC coils
+// and registers
+package modbustcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client is a connection to one Modbus TCP server
+type Client struct {
+	Addr    string // host:port, port 502 by default
+	UnitID  byte
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	txID uint16
+}
+
+// NewClient creates a client; the connection opens on the first request
+func NewClient(addr string) *Client {
+	if !strings.Contains(addr, ":") {
+		addr += ":502"
+	}
+	return &Client{Addr: addr, UnitID: 1, Timeout: 3 * time.Second}
+}
+
+// ReadCoils reads count coils (function 1) starting at a zero-based address
+func (c *Client) ReadCoils(start, count uint16) ([]bool, error) {
+	data, err := c.request(1, start, count)
+	if err != nil {
+		return nil, err
+	}
+	bits := make([]bool, count)
+	for i := range bits {
+		if int(i/8) < len(data) {
+			bits[i] = data[i/8]&(1<<(i%8)) != 0
+		}
+	}
+	return bits, nil
+}
+
+// ReadHoldingRegisters reads count registers (function 3)
+func (c *Client) ReadHoldingRegisters(start, count uint16) ([]uint16, error) {
+	data, err := c.request(3, start, count)
+	if err != nil {
+		return nil, err
+	}
+	regs := make([]uint16, count)
+	for i := range regs {
+		if 2*i+1 < len(data) {
+			regs[i] = binary.BigEndian.Uint16(data[2*i:])
+		}
+	}
+	return regs, nil
+}
+
+// request sends a read request and returns the data bytes of the response
+func (c *Client) request(function byte, start, count uint16) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.Addr, c.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+	c.txID++
+	req := make([]byte, 12)
+	binary.BigEndian.PutUint16(req[0:], c.txID)
+	binary.BigEndian.PutUint16(req[4:], 6)
+	req[6], req[7] = c.UnitID, function
+	binary.BigEndian.PutUint16(req[8:], start)
+	binary.BigEndian.PutUint16(req[10:], count)
+
+	c.conn.SetDeadline(time.Now().Add(c.Timeout))
+	data, err := c.exchange(req)
+	if err != nil {
+		// Reconnect on the next request
+		c.conn.Close()
+		c.conn = nil
+		return nil, err
+	}
+	if data[0] == function|0x80 {
+		return nil, fmt.Errorf("modbus exception %d for function %d at %d", data[1], function, start)
+	}
+	if data[0] != function || len(data) < 2 || int(data[1]) != len(data)-2 {
+		return nil, fmt.Errorf("invalid modbus response")
+	}
+	return data[2:], nil
+}
+
+// exchange writes a request and reads the PDU of the matching response
+func (c *Client) exchange(req []byte) ([]byte, error) {
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[4:]))
+	if binary.BigEndian.Uint16(header[0:]) != c.txID || length < 3 || length > 260 {
+		return nil, fmt.Errorf("invalid modbus response header")
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, pdu); err != nil {
+		return nil, err
+	}
+	return pdu, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const modernizeUsage = `Usage: abb modernize <file.mod> [--dry-run] [--rules id,...] [--target rw7] [--output file]
+Rewrites legacy RAPID constructs into their current equivalents and lists
+the changed lines. --dry-run prints the diff without writing; --rules limits the
+rewrite to some rules (abb modernize --list shows them). With --target the
+instructions abb compat reports as unavailable are listed for manual work;
+they have no safe automatic replacement.
+
+Examples:
+  abb modernize Main.mod --dry-run
+  abb modernize Main.mod --rules incr,bool-compare --output Main_new.mod
+  abb modernize Main.mod --target rw7`
+
+// abbModernize applies the modernization rules to a module
+func abbModernize(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "--list" {
+		var result strings.Builder
+		for _, rule := range rapid.ModernizeRules {
+			fmt.Fprintf(&result, "  %-13s %s\n", rule.ID, rule.Description)
+		}
+		return "Modernization rules:\n" + strings.TrimRight(result.String(), "\n"), nil
+	}
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return modernizeUsage, nil
+	}
+	path := args[0]
+	output := path
+	var enabled map[string]bool
+	var target string
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return modernizeUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--rules":
+			enabled = make(map[string]bool)
+			for _, id := range strings.Split(value, ",") {
+				id = strings.TrimSpace(id)
+				if !modernizeRuleExists(id) {
+					return "", fmt.Errorf("unknown rule %q. See abb modernize --list", id)
+				}
+				enabled[id] = true
+			}
+		case "--target":
+			target = value
+		case "--output":
+			output = value
+		default:
+			return modernizeUsage, nil
+		}
+		i++
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := string(data)
+	out, rewrites := rapid.Modernize(src, enabled)
+
+	var result strings.Builder
+	if len(rewrites) == 0 {
+		result.WriteString("Nothing to modernize in " + path + "\n")
+	} else {
+		for _, r := range rewrites {
+			fmt.Fprintf(&result, "line %d: %s\n", r.Line, r.Rule)
+		}
+	}
+
+	if target != "" {
+		manual, err := compatManual(out, target)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(manual)
+	}
+
+	if len(rewrites) > 0 {
+		if err := writeFile(output, []byte(out), 0o644); err != nil {
+			return "", err
+		}
+		if !dryRun() {
+			fmt.Fprintf(&result, "\nModernized %d lines in %s.", len(rewrites), output)
+		}
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+func modernizeRuleExists(id string) bool {
+	for _, rule := range rapid.ModernizeRules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// compatManual lists the uses the compat data marks as removed in the
+// target version, which need rework by hand
+func compatManual(src, target string) (string, error) {
+	to, err := abb.ParseVersion(target)
+	if err != nil {
+		return "", err
+	}
+	index := abb.CompatIndex()
+	var result strings.Builder
+	for _, occ := range rapid.Occurrences(src) {
+		c, ok := index[strings.ToUpper(occ.Name)]
+		if !ok || !c.RemovedIn(to) {
+			continue
+		}
+		if result.Len() == 0 {
+			fmt.Fprintf(&result, "\nManual changes for RobotWare %s:\n", to)
+		}
+		fmt.Fprintf(&result, "  line %d: %s%s\n", occ.Line, c.Name, compatHint(c))
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,326 @@
+// Package netplan assigns IP addresses to the devices of a robot cell and
+// checks subnets against the ranges robot controllers reserve
+package netplan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Role describes a kind of cell device: its name prefix and the block of
+// host numbers it gets in a /24, so addresses stay recognizable across cells
+type Role struct {
+	Kind     string
+	Prefix   string
+	From, To int // Host numbers in the fourth octet
+	Note     string
+}
+
+// Roles are the known device kinds. Host .1 is the gateway and .200-.254
+// stay free for service laptops and DHCP.
+var Roles = []Role{
+	{Kind: "switch", Prefix: "SW", From: 2, To: 9, Note: "Managed switch"},
+	{Kind: "plc", Prefix: "PLC", From: 10, To: 19},
+	{Kind: "safety", Prefix: "SPLC", From: 20, To: 29, Note: "Safety PLC"},
+	{Kind: "robot", Prefix: "R", From: 30, To: 49, Note: "Controller WAN port; the service port stays 192.168.125.1"},
+	{Kind: "hmi", Prefix: "HMI", From: 50, To: 59},
+	{Kind: "camera", Prefix: "CAM", From: 60, To: 79},
+	{Kind: "scanner", Prefix: "SCAN", From: 80, To: 89, Note: "Barcode/RFID reader"},
+	{Kind: "io", Prefix: "IO", From: 90, To: 119, Note: "Remote I/O"},
+	{Kind: "drive", Prefix: "DRV", From: 120, To: 149},
+	{Kind: "pc", Prefix: "PC", From: 150, To: 169},
+	{Kind: "other", From: 170, To: 199},
+}
+
+// Service range left free in every plan
+const serviceFrom, serviceTo = 200, 254
+
+// Reserved is an address range devices in the cell must not use
+type Reserved struct {
+	Prefix netip.Prefix
+	Reason string
+}
+
+// ReservedRanges are subnets ABB controllers use internally. A cell
+// network overlapping them makes the controller unreachable or refuse the
+// WAN configuration.
+var ReservedRanges = []Reserved{
+	{netip.MustParsePrefix("192.168.125.0/24"), "ABB service port (IRC5 and OmniCore, fixed at 192.168.125.1)"},
+	{netip.MustParsePrefix("192.168.126.0/24"), "ABB controller private network (IRC5 main and axis computers)"},
+	{netip.MustParsePrefix("169.254.0.0/16"), "link-local addresses, assigned when DHCP fails"},
+}
+
+// Request asks for count devices of one kind
+type Request struct {
+	Kind  string
+	Count int
+}
+
+// ParseDevices reads a device list such as robot=2,plc,camera=3,hmi
+func ParseDevices(s string) ([]Request, error) {
+	var requests []Request
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, count, ok := strings.Cut(part, "=")
+		r := Request{Kind: strings.ToLower(strings.TrimSpace(kind)), Count: 1}
+		if ok {
+			n, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid device count in %q", part)
+			}
+			r.Count = n
+		}
+		requests = append(requests, r)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no devices given")
+	}
+	return requests, nil
+}
+
+// Subnet summarizes a network prefix
+type Subnet struct {
+	Prefix    netip.Prefix
+	Mask      netip.Addr
+	First     netip.Addr
+	Last      netip.Addr
+	Broadcast netip.Addr
+	Hosts     int
+	Warnings  []string
+}
+
+// ParseSubnet reads a prefix such as 192.168.10.0/24; host bits are cleared
+func ParseSubnet(s string) (Subnet, error) {
+	p, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return Subnet{}, fmt.Errorf("invalid subnet %q, expected e.g. 192.168.10.0/24", s)
+	}
+	if !p.Addr().Is4() {
+		return Subnet{}, fmt.Errorf("%s: only IPv4 subnets are supported", s)
+	}
+	if p.Bits() > 30 || p.Bits() < 8 {
+		return Subnet{}, fmt.Errorf("%s: prefix length must be between /8 and /30", s)
+	}
+	p = p.Masked()
+	sn := Subnet{Prefix: p, Mask: mask(p.Bits())}
+	base := toUint(p.Addr())
+	size := uint32(1) << (32 - p.Bits())
+	sn.First = fromUint(base + 1)
+	sn.Last = fromUint(base + size - 2)
+	sn.Broadcast = fromUint(base + size - 1)
+	sn.Hosts = int(size - 2)
+	for _, r := range ReservedRanges {
+		if r.Prefix.Overlaps(p) {
+			sn.Warnings = append(sn.Warnings, fmt.Sprintf("%s overlaps %s: %s", p, r.Prefix, r.Reason))
+		}
+	}
+	if !p.Addr().IsPrivate() {
+		sn.Warnings = append(sn.Warnings, fmt.Sprintf("%s is not a private range (10/8, 172.16/12, 192.168/16)", p))
+	}
+	return sn, nil
+}
+
+// Contains reports whether an address is a usable host of the subnet
+func (s Subnet) Contains(a netip.Addr) bool {
+	return s.Prefix.Contains(a) && a != s.Prefix.Addr() && a != s.Broadcast
+}
+
+// host returns the address with host number n, counted from the network
+// address
+func (s Subnet) host(n int) netip.Addr {
+	return fromUint(toUint(s.Prefix.Addr()) + uint32(n))
+}
+
+// Assignment is one device with its address
+type Assignment struct {
+	Name string
+	Kind string
+	Addr netip.Addr
+	Note string
+}
+
+// Plan is the address table of a cell network
+type Plan struct {
+	Subnet      Subnet
+	Gateway     netip.Addr
+	Assignments []Assignment
+	Service     string // Range left free for laptops and DHCP
+	Warnings    []string
+}
+
+// Generate assigns addresses to the requested devices. In a /24 or larger
+// subnet every kind gets its fixed block of host numbers; smaller subnets
+// are filled in order after the gateway.
+func Generate(subnet Subnet, gateway netip.Addr, requests []Request) (*Plan, error) {
+	p := &Plan{Subnet: subnet, Gateway: gateway, Warnings: append([]string(nil), subnet.Warnings...)}
+	if !gateway.IsValid() {
+		p.Gateway = subnet.First
+	} else if !subnet.Contains(gateway) {
+		return nil, fmt.Errorf("gateway %s is not a host of %s", gateway, subnet.Prefix)
+	}
+	used := map[netip.Addr]bool{p.Gateway: true}
+
+	total := 0
+	for _, r := range requests {
+		total += r.Count
+	}
+	if total+1 > subnet.Hosts {
+		return nil, fmt.Errorf("%d devices and the gateway do not fit in %s (%d hosts)", total, subnet.Prefix, subnet.Hosts)
+	}
+
+	blocks := subnet.Prefix.Bits() <= 24
+	next := 1
+	counts := make(map[string]int)
+	for _, r := range requests {
+		role := findRole(r.Kind)
+		prefix := role.Prefix
+		if prefix == "" {
+			prefix = strings.ToUpper(r.Kind)
+		}
+		for i := 0; i < r.Count; i++ {
+			counts[prefix]++
+			a := Assignment{Name: fmt.Sprintf("%s%d", prefix, counts[prefix]), Kind: r.Kind, Note: role.Note}
+			if blocks {
+				a.Addr = p.freeIn(role, used)
+				if !a.Addr.IsValid() {
+					// Block full: spill over into the spare block
+					a.Addr = p.freeIn(findRole("other"), used)
+					p.Warnings = append(p.Warnings, fmt.Sprintf("%s: the %s block .%d-.%d is full, assigned from the spare block", a.Name, r.Kind, role.From, role.To))
+				}
+				if !a.Addr.IsValid() {
+					return nil, fmt.Errorf("no free address for %s", a.Name)
+				}
+			} else {
+				for used[subnet.host(next)] {
+					next++
+				}
+				a.Addr = subnet.host(next)
+			}
+			used[a.Addr] = true
+			p.Assignments = append(p.Assignments, a)
+		}
+	}
+	if blocks {
+		if n := int(toUint(p.Gateway) - toUint(subnet.Prefix.Addr())); n >= serviceFrom && n <= serviceTo {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("gateway %s is in the service range, DHCP servers must exclude it", p.Gateway))
+		}
+		p.Service = fmt.Sprintf("%s - %s", subnet.host(serviceFrom), subnet.host(serviceTo))
+	} else if next < subnet.Hosts {
+		p.Service = fmt.Sprintf("%s - %s", subnet.host(next+1), subnet.Last)
+	}
+	sort.SliceStable(p.Assignments, func(i, j int) bool { return p.Assignments[i].Addr.Less(p.Assignments[j].Addr) })
+	return p, nil
+}
+
+func (p *Plan) freeIn(role Role, used map[netip.Addr]bool) netip.Addr {
+	for n := role.From; n <= role.To; n++ {
+		if a := p.Subnet.host(n); !used[a] {
+			return a
+		}
+	}
+	return netip.Addr{}
+}
+
+func findRole(kind string) Role {
+	for _, r := range Roles {
+		if r.Kind == kind {
+			return r
+		}
+	}
+	other := Roles[len(Roles)-1]
+	other.Kind = kind
+	return other
+}
+
+func (p *Plan) rows() [][]string {
+	rows := [][]string{{"Gateway", "gateway", p.Gateway.String(), p.Subnet.Mask.String(), "", "Default gateway of the cell"}}
+	for _, a := range p.Assignments {
+		rows = append(rows, []string{a.Name, a.Kind, a.Addr.String(), p.Subnet.Mask.String(), p.Gateway.String(), a.Note})
+	}
+	return rows
+}
+
+var header = []string{"Device", "Type", "IP address", "Subnet mask", "Gateway", "Notes"}
+
+// Text formats the plan as a table for the terminal
+func (p *Plan) Text() string {
+	rows := append([][]string{header}, p.rows()...)
+	widths := make([]int, len(header)-1)
+	for _, row := range rows {
+		for i := range widths {
+			if len(row[i]) > widths[i] {
+				widths[i] = len(row[i])
+			}
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Subnet %s, mask %s, hosts %s - %s (%d)\n\n", p.Subnet.Prefix, p.Subnet.Mask, p.Subnet.First, p.Subnet.Last, p.Subnet.Hosts)
+	for _, row := range rows {
+		var line strings.Builder
+		for i, w := range widths {
+			fmt.Fprintf(&line, "%-*s  ", w, row[i])
+		}
+		line.WriteString(row[len(row)-1])
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	b.WriteString("\n" + p.summary())
+	return b.String()
+}
+
+// Markdown formats the plan for the cell documentation
+func (p *Plan) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# IP plan %s\n\n", p.Subnet.Prefix)
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, row := range p.rows() {
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(p.summary(), "\n"), "\n") {
+		b.WriteString("- " + line + "\n")
+	}
+	return b.String()
+}
+
+// CSV formats the plan for spreadsheets and switch configuration tools
+func (p *Plan) CSV() string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(header)
+	w.WriteAll(p.rows())
+	return b.String()
+}
+
+func (p *Plan) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Mask %s, broadcast %s\n", p.Subnet.Mask, p.Subnet.Broadcast)
+	if p.Service != "" {
+		fmt.Fprintf(&b, "Keep %s free for service laptops and DHCP\n", p.Service)
+	}
+	fmt.Fprintf(&b, "Robot service ports stay at 192.168.125.1; connect laptops there for RobotStudio\n")
+	for _, w := range p.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", w)
+	}
+	return b.String()
+}
+
+func mask(bits int) netip.Addr {
+	return fromUint(^uint32(0) << (32 - bits))
+}
+
+func toUint(a netip.Addr) uint32 {
+	b := a.As4()
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func fromUint(v uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/netplan"
+)
+
+func init() {
+	commandRegistry["netplan"] = Command{
+		Group:       groupCalc,
+		Description: "IP plan of a cell network and subnet checks",
+		Usage:       netplanUsage,
+		Execute:     text(runNetplan),
+	}
+}
+
+const netplanUsage = `Usage:
+  netplan generate --devices robot=2,plc,camera,hmi --subnet 192.168.10.0/24
+                   [--gateway 192.168.10.1] [--format text|markdown|csv] [--output plan.md]
+  netplan subnet 192.168.10.0/24 [192.168.10.37 ...]
+
+generate assigns addresses by device kind, so the same kind gets the same
+host numbers in every cell of a plant:
+  .1 gateway, .2-9 switch, .10-19 plc, .20-29 safety, .30-49 robot,
+  .50-59 hmi, .60-79 camera, .80-89 scanner, .90-119 io, .120-149 drive,
+  .150-169 pc, .170-199 other kinds, .200-254 free for laptops and DHCP
+Subnets smaller than /24 are filled in order. Both commands warn about
+subnets overlapping the ABB service port (192.168.125.0/24) and controller
+private network (192.168.126.0/24).
+
+--output writes markdown or CSV depending on the file extension.
+
+Examples:
+  netplan generate --devices robot=2,plc,safety,camera=2,hmi --subnet 10.20.5.0/24 --output ip-plan.md
+  netplan subnet 192.168.125.0/24`
+
+func runNetplan(args []string) (string, error) {
+	if len(args) < 1 {
+		return netplanUsage, nil
+	}
+	switch args[0] {
+	case "generate":
+		return netplanGenerate(args[1:])
+	case "subnet":
+		return netplanSubnet(args[1:])
+	default:
+		return netplanUsage, nil
+	}
+}
+
+func netplanGenerate(args []string) (string, error) {
+	devices, subnet, gateway, format, output := "", "", "", "text", ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return netplanUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--devices":
+			devices = value
+		case "--subnet":
+			subnet = value
+		case "--gateway":
+			gateway = value
+		case "--format":
+			format = value
+		case "--output":
+			output = value
+		default:
+			return netplanUsage, nil
+		}
+		i++
+	}
+	if devices == "" || subnet == "" {
+		return netplanUsage, nil
+	}
+	requests, err := netplan.ParseDevices(devices)
+	if err != nil {
+		return "", err
+	}
+	sn, err := netplan.ParseSubnet(subnet)
+	if err != nil {
+		return "", err
+	}
+	var gw netip.Addr
+	if gateway != "" {
+		if gw, err = netip.ParseAddr(gateway); err != nil {
+			return "", fmt.Errorf("invalid value %q for --gateway", gateway)
+		}
+	}
+	plan, err := netplan.Generate(sn, gw, requests)
+	if err != nil {
+		return "", err
+	}
+
+	if output != "" {
+		var content string
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".csv":
+			content = plan.CSV()
+		case ".md":
+			content = plan.Markdown()
+		default:
+			return "", errors.New("--output must be a .md or .csv file")
+		}
+		if err := writeFile(output, []byte(content), 0644); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(plan.Text(), "\n") + "\nWrote " + output, nil
+	}
+	switch format {
+	case "text":
+		return strings.TrimRight(plan.Text(), "\n"), nil
+	case "markdown":
+		return strings.TrimRight(plan.Markdown(), "\n"), nil
+	case "csv":
+		return strings.TrimRight(plan.CSV(), "\n"), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use text, markdown or csv)", format)
+	}
+}
+
+func netplanSubnet(args []string) (string, error) {
+	if len(args) < 1 {
+		return netplanUsage, nil
+	}
+	sn, err := netplan.ParseSubnet(args[0])
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Network:   %s\n", sn.Prefix)
+	fmt.Fprintf(&b, "Mask:      %s\n", sn.Mask)
+	fmt.Fprintf(&b, "Hosts:     %s - %s (%d)\n", sn.First, sn.Last, sn.Hosts)
+	fmt.Fprintf(&b, "Broadcast: %s\n", sn.Broadcast)
+	for _, s := range args[1:] {
+		a, err := netip.ParseAddr(s)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&b, "%s: not an IPv4 address\n", s)
+		case sn.Contains(a):
+			fmt.Fprintf(&b, "%s: in the subnet\n", a)
+		case a == sn.Prefix.Addr() || a == sn.Broadcast:
+			fmt.Fprintf(&b, "%s: network or broadcast address, not usable for a device\n", a)
+		default:
+			fmt.Fprintf(&b, "%s: outside the subnet, needs a router\n", a)
+		}
+	}
+	for _, w := range sn.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", w)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
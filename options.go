@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/cfg"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const optionsUsage = `Usage: abb options <dir|file.mod>... [--backup <backupdir> | --options <list.txt>]
+Lists the RobotWare options a program needs because of the instructions it
+uses (MultiMove, Arc, Force Control, World Zones, Socket Messaging, ...).
+With --backup the options of the target system are read from
+BACKINFO/backinfo.txt of its backup and every missing option is flagged;
+--options takes a text file with one option per line (e.g. "608-1 World
+Zones") instead. Run it before loading a program on another robot.
+
+The instruction data is shared with 'abb compat'; install a data pack with
+"compat" and "options" sections to add instructions and options.
+
+Examples:
+  abb options ./RAPID/T_ROB1 --backup ./Backups/Cell2_2024-05-02
+  abb options Main.mod`
+
+// optionUse is the first place an option is needed
+type optionUse struct {
+	instructions []string
+	at           string
+}
+
+// abbOptions checks the RobotWare options a program needs against a system
+func abbOptions(args []string) (string, error) {
+	var sources []string
+	backup, list := "", ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--backup", "--options":
+			if i+1 >= len(args) {
+				return optionsUsage, nil
+			}
+			if args[i] == "--backup" {
+				backup = args[i+1]
+			} else {
+				list = args[i+1]
+			}
+			i++
+		default:
+			if strings.HasPrefix(args[i], "--") {
+				return optionsUsage, nil
+			}
+			sources = append(sources, args[i])
+		}
+	}
+	if len(sources) == 0 || backup != "" && list != "" {
+		return optionsUsage, nil
+	}
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+
+	index := abb.CompatIndex()
+	uses := make(map[string]*optionUse)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		for _, occ := range rapid.Occurrences(string(data)) {
+			c, ok := index[strings.ToUpper(occ.Name)]
+			if !ok || c.Option == "" {
+				continue
+			}
+			u, ok := uses[c.Option]
+			if !ok {
+				u = &optionUse{at: fmt.Sprintf("%s:%d", path, occ.Line)}
+				uses[c.Option] = u
+			}
+			if !containsFold(u.instructions, c.Name) {
+				u.instructions = append(u.instructions, c.Name)
+			}
+		}
+	}
+	names := make([]string, 0, len(uses))
+	for name := range uses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if backup != "" {
+		list = filepath.Join(backup, "BACKINFO", "backinfo.txt")
+		if _, err := os.Stat(list); err != nil {
+			return "", fmt.Errorf("%s does not look like a controller backup (no BACKINFO/backinfo.txt)", backup)
+		}
+	}
+	var installed []string
+	if list != "" {
+		f, err := os.Open(list)
+		if err != nil {
+			return "", err
+		}
+		installed, err = readOptionList(f, backup != "")
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", list, err)
+		}
+		if len(installed) == 0 {
+			return "", fmt.Errorf("no options found in %s", list)
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("%d files checked: no instructions that need a RobotWare option", len(files)), nil
+	}
+	var result strings.Builder
+	missing := 0
+	for _, name := range names {
+		u := uses[name]
+		status := ""
+		if list != "" {
+			status = "installed"
+			o, known := abb.RobotWareOption(name)
+			switch {
+			case !known:
+				status = "unknown, check manually"
+			case !o.Installed(installed):
+				status = "missing"
+				missing++
+			}
+		}
+		fmt.Fprintf(&result, "%s\n", strings.TrimRight(fmt.Sprintf("%-40s %s", name, status), " "))
+		fmt.Fprintf(&result, "  %s (first at %s)\n", strings.Join(u.instructions, ", "), u.at)
+		if o, ok := abb.RobotWareOption(name); ok {
+			if status == "missing" && len(o.IDs) > 0 {
+				fmt.Fprintf(&result, "  Order option %s\n", strings.Join(o.IDs, " or "))
+			}
+			if o.Note != "" {
+				fmt.Fprintf(&result, "  Note: %s\n", o.Note)
+			}
+		}
+	}
+	summary := fmt.Sprintf("%d files checked: %d RobotWare options needed", len(files), len(names))
+	switch {
+	case list == "":
+		return summary + "\n" + strings.TrimRight(result.String(), "\n"), nil
+	case missing > 0:
+		return "", fmt.Errorf("%s, %d missing on the system of %s\n%s", summary, missing, list, strings.TrimRight(result.String(), "\n"))
+	}
+	return fmt.Sprintf("%s, all on the system of %s\n%s", summary, list, strings.TrimRight(result.String(), "\n")), nil
+}
+
+// readOptionList reads the options of a system from the product sections
+// of backinfo.txt, or from a plain list with one option per line
+func readOptionList(r io.Reader, backInfo bool) ([]string, error) {
+	if backInfo {
+		info, err := cfg.ParseBackInfo(r)
+		if err != nil {
+			return nil, err
+		}
+		var options []string
+		for _, section := range optionSections(info) {
+			options = append(options, info[section]...)
+		}
+		return options, nil
+	}
+	var options []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			options = append(options, line)
+		}
+	}
+	return options, sc.Err()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/datapack"
+	"github.com/polyfant/automation-helper-cli/update"
+)
+
+func init() {
+	commandRegistry["pack"] = Command{
+		Group:       groupSystem,
+		Description: "List, install and remove optional reference data packs",
+		Usage:       packUsage,
+		Execute:     text(runPack),
+	}
+}
+
+const packUsage = `Usage:
+  pack list
+  pack install <name>...
+  pack remove <name>...
+
+Optional data packs (other robot vendors, error code sets, robot models)
+are listed in the signed release manifest, ` + updateURLEnv + `
+(default: ` + update.DefaultURL + `).
+Installed packs are verified against the manifest, stored in the data
+directory and kept up to date by 'update install'.
+
+Examples:
+  pack list
+  pack install fanuc kuka-errors
+  pack remove fanuc`
+
+func runPack(args []string) (string, error) {
+	if len(args) < 1 {
+		return packUsage, nil
+	}
+	switch args[0] {
+	case "list":
+		return packList()
+	case "install":
+		if len(args) < 2 {
+			return packUsage, nil
+		}
+		return packInstall(args[1:])
+	case "remove":
+		if len(args) < 2 {
+			return packUsage, nil
+		}
+		var result strings.Builder
+		var failed []string
+		for _, name := range args[1:] {
+			if err := datapack.Remove(name); err != nil {
+				failed = append(failed, err.Error())
+				continue
+			}
+			fmt.Fprintf(&result, "Removed pack %s\n", name)
+		}
+		if len(failed) > 0 {
+			return strings.TrimRight(result.String(), "\n"), errors.New(strings.Join(failed, "\n"))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+	default:
+		return packUsage, nil
+	}
+}
+
+func packList() (string, error) {
+	installed, err := datapack.Installed()
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	result.WriteString("Installed packs:\n")
+	if len(installed) == 0 {
+		result.WriteString("  none\n")
+	}
+	byName := make(map[string]datapack.Pack)
+	for _, p := range installed {
+		byName[p.Name] = p
+		fmt.Fprintf(&result, "  %-20s %-20s installed %s\n", p.Name, p.Version, p.Installed.Format("2006-01-02"))
+	}
+
+	m, err := update.NewClient(os.Getenv(updateURLEnv)).Fetch()
+	if err != nil {
+		fmt.Fprintf(&result, "\nAvailable packs could not be listed: %v", err)
+		return result.String(), nil
+	}
+	result.WriteString("\nAvailable packs:\n")
+	for _, p := range m.Packs {
+		status := "included"
+		if p.Optional {
+			status = "optional"
+		}
+		if local, ok := byName[p.Name]; ok {
+			status = "installed"
+			if update.NewerVersion(p.Version, local.Version) {
+				status = "update available"
+			}
+		}
+		fmt.Fprintf(&result, "  %-20s %-12s %-16s %s\n", p.Name, p.Version, status, p.Description)
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+func packInstall(names []string) (string, error) {
+	client := update.NewClient(os.Getenv(updateURLEnv))
+	m, err := client.Fetch()
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	var failed []string
+	for _, name := range names {
+		p, ok := m.FindPack(name)
+		if !ok {
+			var available []string
+			for _, p := range m.Packs {
+				available = append(available, p.Name)
+			}
+			failed = append(failed, fmt.Sprintf("no pack %q in the registry. Available: %s", name, strings.Join(available, ", ")))
+			continue
+		}
+		if err := client.InstallPack(p); err != nil {
+			failed = append(failed, fmt.Sprintf("installing pack %s: %v", name, err))
+			continue
+		}
+		fmt.Fprintf(&result, "Installed pack %s %s\n", p.Name, p.Version)
+	}
+	if len(failed) > 0 {
+		return strings.TrimRight(result.String(), "\n"), errors.New(strings.Join(failed, "\n"))
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
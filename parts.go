@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+)
+
+const partsUsage = `Usage: abb parts <robot|controller|part number> [keyword...]
+Lists common spare parts and consumables (SMB batteries, wrist seals,
+FlexPendant cables, lubricants) for a robot and its controllers. Install a
+data pack with a "parts" section to add numbers. Always confirm against the
+spare parts list for the robot's serial number.
+
+Examples:
+  abb parts irb6700
+  abb parts irb2600-12 battery
+  abb parts irc5 pendant cable
+  abb parts 3HAC044075-001`
+
+// abbParts looks up spare parts by robot or controller, or a single part by number
+func abbParts(args []string) (string, error) {
+	if len(args) < 1 {
+		return partsUsage, nil
+	}
+	if p, ok := abb.SparePart(args[0]); ok {
+		return formatPart(p, true), nil
+	}
+
+	parts, ok := abb.PartsFor(args[0], strings.Join(args[1:], " "))
+	if !ok {
+		return "", fmt.Errorf("unknown robot or controller %q. Available robots: %s", args[0], strings.Join(abb.RobotKeys(), ", "))
+	}
+	if len(parts) == 0 {
+		if len(args) > 1 {
+			return fmt.Sprintf("No parts for %s match %q.", args[0], strings.Join(args[1:], " ")), nil
+		}
+		return fmt.Sprintf("No parts known for %s.", args[0]), nil
+	}
+	var result strings.Builder
+	fmt.Fprintf(&result, "Spare parts for %s:\n", args[0])
+	category := ""
+	for _, p := range parts {
+		if p.Category != category {
+			category = p.Category
+			fmt.Fprintf(&result, "\n%s:\n", category)
+		}
+		result.WriteString(formatPart(p, false))
+	}
+	result.WriteString("\nConfirm numbers against the spare parts list for the serial number.")
+	return result.String(), nil
+}
+
+// formatPart formats a part as a list line or, in detail, with the models it fits
+func formatPart(p abb.Part, detail bool) string {
+	if !detail {
+		line := fmt.Sprintf("  %-16s %s\n", p.Number, p.Description)
+		if p.Note != "" {
+			line += fmt.Sprintf("  %-16s Note: %s\n", "", p.Note)
+		}
+		return line
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", p.Number, p.Description)
+	fmt.Fprintf(&b, "Category: %s\n", p.Category)
+	fmt.Fprintf(&b, "Fits: %s", strings.Join(p.Models, ", "))
+	if p.Note != "" {
+		fmt.Fprintf(&b, "\nNote: %s", p.Note)
+	}
+	return b.String()
+}
@@ -0,0 +1,251 @@
+// Package pdf renders simple markdown documents as PDF, so reports and
+// checklists can be handed out without external tools
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page layout in points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+	bodySize   = 10.0
+	codeSize   = 9.0
+)
+
+// Standard fonts; Type 1 base fonts need no embedding
+const (
+	regular  = "F1"
+	bold     = "F2"
+	mono     = "F3"
+	monoBold = "F4"
+)
+
+var baseFonts = []string{"Helvetica", "Helvetica-Bold", "Courier", "Courier-Bold"}
+
+// Render lays out markdown on A4 pages: headings, paragraphs, bullet and
+// checkbox lists, tables, code blocks and rules. Inline emphasis and code
+// markers are dropped. Only the standard PDF fonts are used, so characters
+// outside Windows-1252 print as '?'.
+func Render(markdown string) []byte {
+	d := &document{}
+	d.newPage()
+
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			d.wrapped(regular, bodySize, margin, strings.Join(paragraph, " "))
+			d.space(4)
+			paragraph = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				d.line(mono, codeSize, margin+10, strings.ReplaceAll(lines[i], "\t", "    "))
+			}
+			d.space(4)
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			size := map[int]float64{1: 18, 2: 14}[level]
+			if size == 0 {
+				size = 12
+			}
+			d.space(size * 0.6)
+			d.ensure(size * 3)
+			d.wrapped(bold, size, margin, inline(strings.TrimSpace(trimmed[level:])))
+			d.space(3)
+		case trimmed == "---" || trimmed == "***":
+			flush()
+			d.ensure(10)
+			d.y -= 4
+			fmt.Fprintf(d.page, "0.5 w %.1f %.1f m %.1f %.1f l S\n", margin, d.y, pageWidth-margin, d.y)
+			d.y -= 6
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			var rows []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			d.table(rows)
+			d.space(4)
+		case isItem(trimmed):
+			flush()
+			indent := margin + float64(len(line)-len(strings.TrimLeft(line, " ")))/2*6
+			d.item(indent, trimmed[2:])
+		default:
+			paragraph = append(paragraph, inline(trimmed))
+		}
+	}
+	flush()
+	return d.bytes()
+}
+
+func isItem(s string) bool {
+	return strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "* ")
+}
+
+// inline removes emphasis and code markers
+func inline(s string) string {
+	return strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+}
+
+type document struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func (d *document) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - margin
+}
+
+func (d *document) atTop() bool {
+	return d.y == pageHeight-margin
+}
+
+// ensure starts a new page unless height points fit above the bottom margin
+func (d *document) ensure(height float64) {
+	if d.y-height < margin+20 {
+		d.newPage()
+	}
+}
+
+func (d *document) space(h float64) {
+	if !d.atTop() {
+		d.y -= h
+	}
+}
+
+func (d *document) text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, size, x, y, escape(s))
+}
+
+// line writes a single line, cut at the right margin
+func (d *document) line(font string, size, x float64, s string) {
+	lead := size * 1.35
+	d.ensure(lead)
+	d.y -= lead
+	for width(font, size, s) > pageWidth-margin-x && len(s) > 0 {
+		r := []rune(s)
+		s = string(r[:len(r)-1])
+	}
+	d.text(font, size, x, d.y+size*0.3, s)
+}
+
+// wrapped writes text broken into lines that fit between x and the margin
+func (d *document) wrapped(font string, size, x float64, s string) {
+	for _, l := range wrap(font, size, pageWidth-margin-x, s) {
+		d.line(font, size, x, l)
+	}
+}
+
+// item writes a bullet or, for "[ ]" and "[x]", a checkbox
+func (d *document) item(x float64, s string) {
+	d.ensure(bodySize * 1.35)
+	top := d.y
+	switch {
+	case strings.HasPrefix(s, "[ ] "), strings.HasPrefix(strings.ToLower(s), "[x] "):
+		box := top - bodySize*1.35 + 2.5
+		fmt.Fprintf(d.page, "0.7 w %.1f %.1f 8 8 re S\n", x, box)
+		if s[1] != ' ' {
+			fmt.Fprintf(d.page, "%.1f %.1f m %.1f %.1f l %.1f %.1f l S\n", x+1.5, box+4, x+3.5, box+1.5, x+7, box+7)
+		}
+		s = s[4:]
+	default:
+		d.text(regular, bodySize, x+2, top-bodySize*1.35+bodySize*0.3, "•")
+	}
+	d.wrapped(regular, bodySize, x+14, inline(s))
+	d.y -= 2
+}
+
+// table writes markdown table rows in a monospaced grid
+func (d *document) table(rows []string) {
+	var cells [][]string
+	var widths []int
+	for _, row := range rows {
+		parts := strings.Split(strings.Trim(row, "|"), "|")
+		if strings.Trim(strings.Join(parts, ""), "-: ") == "" {
+			continue // separator row
+		}
+		for j, p := range parts {
+			p = inline(strings.TrimSpace(p))
+			parts[j] = p
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := len([]rune(p)); n > widths[j] {
+				widths[j] = n
+			}
+		}
+		cells = append(cells, parts)
+	}
+	for i, row := range cells {
+		var b strings.Builder
+		for j, c := range row {
+			b.WriteString(c + strings.Repeat(" ", widths[j]-len([]rune(c))+2))
+		}
+		font := mono
+		if i == 0 && len(cells) > 1 {
+			font = monoBold
+		}
+		d.line(font, codeSize, margin, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// bytes assembles the PDF file with page numbers in the footer
+func (d *document) bytes() []byte {
+	for i, p := range d.pages {
+		footer := fmt.Sprintf("%d / %d", i+1, len(d.pages))
+		fmt.Fprintf(p, "BT /%s 8 Tf %.1f %.1f Td (%s) Tj ET\n", regular, pageWidth-margin-width(regular, 8, footer), margin-20, footer)
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	firstPage := 3 + len(baseFonts)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	var resources strings.Builder
+	for i, f := range baseFonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f))
+		fmt.Fprintf(&resources, "/F%d %d 0 R ", i+1, 3+i)
+	}
+	for i, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, resources.String(), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
@@ -0,0 +1,103 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// Glyph widths of printable ASCII (32-126) in 1/1000 em, from the Adobe
+// font metrics of the standard fonts
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// width returns the width of s in points
+func width(font string, size float64, s string) float64 {
+	total := 0
+	for _, r := range s {
+		switch {
+		case font == mono || font == monoBold:
+			total += 600
+		case r < 32 || r > 126:
+			total += 556
+		case font == bold:
+			total += helveticaBoldWidths[r-32]
+		default:
+			total += helveticaWidths[r-32]
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap breaks text into lines no wider than max points, splitting words
+// only when a single word does not fit
+func wrap(font string, size, max float64, s string) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if width(font, size, candidate) <= max {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		current = ""
+		for _, r := range word {
+			if width(font, size, current+string(r)) > max && current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			current += string(r)
+		}
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// winAnsi maps the typographic characters Windows-1252 places in 0x80-0x9F
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// escape encodes s as a PDF string body in WinAnsiEncoding
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 128:
+			b.WriteRune(r)
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
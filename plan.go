@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/plan"
+)
+
+func init() {
+	commandRegistry["plan"] = Command{
+		Group:       groupCalc,
+		Description: "Concept planning: cycle time budget and critical path",
+		Usage:       planUsage,
+		Execute:     text(runPlan),
+	}
+}
+
+const planUsage = `Usage: plan cycletime <steps.csv> [--takt 45] [--output budget.md]
+Computes the cycle time of a concept from estimated step durations. Steps
+run in parallel unless "after" makes them wait for other steps; steps on the
+same resource (e.g. R1, fixture) run one after the other in listed order.
+
+CSV columns: step, duration (s), after (steps separated by ;), resource
+
+  step,duration,after,resource
+  Load part,4,,Operator
+  Pick,3.5,Load part,R1
+  Weld seam 1,12,Pick,R1
+  Clamp check,1.5,Load part,
+  Unload,4,Weld seam 1,Operator
+
+Example:
+  plan cycletime concept.csv --takt 45 --output budget.md`
+
+func runPlan(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "cycletime" {
+		return planUsage, nil
+	}
+	path := args[1]
+	takt := 0.0
+	output := ""
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return planUsage, nil
+		}
+		switch args[i] {
+		case "--takt":
+			v, err := strconv.ParseFloat(strings.TrimSuffix(args[i+1], "s"), 64)
+			if err != nil || v <= 0 {
+				return "", errors.New("--takt must be a positive number of seconds")
+			}
+			takt = v
+		case "--output":
+			output = args[i+1]
+		default:
+			return planUsage, nil
+		}
+		i++
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	steps, err := plan.ParseSteps(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	sched, err := plan.Plan(steps)
+	if err != nil {
+		return "", err
+	}
+
+	result := strings.TrimRight(sched.Text(takt), "\n")
+	if output != "" {
+		md := "# Cycle time budget\n\n" + sched.Markdown(takt)
+		if err := writeFile(output, []byte(md), 0644); err != nil {
+			return "", err
+		}
+		result += "\nWrote " + output
+	}
+	return result, nil
+}
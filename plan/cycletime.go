@@ -0,0 +1,268 @@
+// Package plan provides concept-phase planning calculations for robot cells
+package plan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Step is a process step with its estimated duration
+type Step struct {
+	Name     string
+	Duration float64 // Seconds
+	After    []string
+	Resource string // Steps on the same resource (robot, fixture) cannot overlap
+
+	Start    float64
+	End      float64
+	Slack    float64
+	Critical bool
+}
+
+// Schedule is the result of planning the steps
+type Schedule struct {
+	Steps    []*Step
+	Cycle    float64
+	Critical []string // Steps on the critical path, in order
+}
+
+// ParseSteps reads a CSV with the columns step, duration, after and
+// resource. "after" lists predecessor steps separated by semicolons, and
+// durations are seconds with an optional s suffix.
+func ParseSteps(r io.Reader) ([]*Step, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("need a header row and at least one step")
+	}
+	cols := make(map[string]int)
+	for i, name := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"step", "duration"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("missing column %q (columns: step, duration, after, resource)", required)
+		}
+	}
+	field := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var steps []*Step
+	for n, row := range rows[1:] {
+		name := field(row, "step")
+		if name == "" {
+			continue
+		}
+		d, err := strconv.ParseFloat(strings.TrimSuffix(field(row, "duration"), "s"), 64)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("line %d: invalid duration %q", n+2, field(row, "duration"))
+		}
+		s := &Step{Name: name, Duration: d, Resource: field(row, "resource")}
+		for _, name := range strings.Split(field(row, "after"), ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				s.After = append(s.After, name)
+			}
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+// Plan computes the earliest schedule of the steps, their slack and the
+// critical path. Steps sharing a resource run in the order they are listed.
+func Plan(steps []*Step) (*Schedule, error) {
+	index := make(map[string]*Step)
+	for _, s := range steps {
+		if _, dup := index[s.Name]; dup {
+			return nil, fmt.Errorf("step %q is listed twice", s.Name)
+		}
+		index[s.Name] = s
+	}
+
+	// Predecessors: explicit dependencies plus the previous step on the same resource
+	preds := make(map[*Step][]*Step)
+	lastOn := make(map[string]*Step)
+	for _, s := range steps {
+		for _, name := range s.After {
+			p, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("step %q comes after unknown step %q", s.Name, name)
+			}
+			preds[s] = append(preds[s], p)
+		}
+		if s.Resource != "" {
+			if prev, ok := lastOn[s.Resource]; ok {
+				preds[s] = append(preds[s], prev)
+			}
+			lastOn[s.Resource] = s
+		}
+	}
+
+	order, err := topoOrder(steps, preds)
+	if err != nil {
+		return nil, err
+	}
+
+	sched := &Schedule{Steps: steps}
+	for _, s := range order {
+		s.Start = 0
+		for _, p := range preds[s] {
+			if p.End > s.Start {
+				s.Start = p.End
+			}
+		}
+		s.End = s.Start + s.Duration
+		if s.End > sched.Cycle {
+			sched.Cycle = s.End
+		}
+	}
+
+	// Latest finish times, walking backwards
+	succs := make(map[*Step][]*Step)
+	for s, ps := range preds {
+		for _, p := range ps {
+			succs[p] = append(succs[p], s)
+		}
+	}
+	latest := make(map[*Step]float64)
+	for i := len(order) - 1; i >= 0; i-- {
+		s := order[i]
+		lf := sched.Cycle
+		for _, next := range succs[s] {
+			if ls := latest[next] - next.Duration; ls < lf {
+				lf = ls
+			}
+		}
+		latest[s] = lf
+		s.Slack = lf - s.End
+		s.Critical = s.Slack < 1e-9
+	}
+
+	for _, s := range order {
+		if s.Critical {
+			sched.Critical = append(sched.Critical, s.Name)
+		}
+	}
+	return sched, nil
+}
+
+// topoOrder sorts the steps so that every step follows its predecessors
+func topoOrder(steps []*Step, preds map[*Step][]*Step) ([]*Step, error) {
+	state := make(map[*Step]int) // 1 = visiting, 2 = done
+	var order []*Step
+	var visit func(s *Step, path []string) error
+	visit = func(s *Step, path []string) error {
+		switch state[s] {
+		case 1:
+			return fmt.Errorf("circular dependency: %s", strings.Join(append(path, s.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[s] = 1
+		for _, p := range preds[s] {
+			if err := visit(p, append(path, s.Name)); err != nil {
+				return err
+			}
+		}
+		state[s] = 2
+		order = append(order, s)
+		return nil
+	}
+	for _, s := range steps {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Start < order[j].Start })
+	return order, nil
+}
+
+// Markdown formats the schedule as a table, compared against the takt time
+// when takt is positive
+func (s *Schedule) Markdown(takt float64) string {
+	var b strings.Builder
+	b.WriteString("| Step | Resource | Duration (s) | Start (s) | End (s) | Slack (s) | Critical |\n")
+	b.WriteString("|---|---|---:|---:|---:|---:|:---:|\n")
+	for _, st := range s.Steps {
+		critical := ""
+		if st.Critical {
+			critical = "yes"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %.1f | %.1f | %.1f | %.1f | %s |\n",
+			st.Name, st.Resource, st.Duration, st.Start, st.End, st.Slack, critical)
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(s.summary(takt, "**"), "\n"), "\n") {
+		b.WriteString("- " + line + "\n")
+	}
+	return b.String()
+}
+
+// ganttWidth is the number of characters for the cycle in Text output
+const ganttWidth = 40
+
+// Text formats the schedule with a bar chart for the terminal
+func (s *Schedule) Text(takt float64) string {
+	nameWidth := len("Step")
+	for _, st := range s.Steps {
+		if len(st.Name) > nameWidth {
+			nameWidth = len(st.Name)
+		}
+	}
+	scale := s.Cycle
+	if takt > scale {
+		scale = takt
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s %8s %8s %8s\n", nameWidth, "Step", "Start", "End", "Slack")
+	for _, st := range s.Steps {
+		bar := []byte(strings.Repeat(" ", ganttWidth))
+		if scale > 0 {
+			from := int(st.Start / scale * ganttWidth)
+			to := int(st.End/scale*ganttWidth + 0.5)
+			for i := from; i < to && i < ganttWidth; i++ {
+				bar[i] = '='
+				if st.Critical {
+					bar[i] = '#'
+				}
+			}
+		}
+		fmt.Fprintf(&b, "%-*s %8.1f %8.1f %8.1f  |%s|\n", nameWidth, st.Name, st.Start, st.End, st.Slack, bar)
+	}
+	b.WriteString("\n# = critical path\n")
+	b.WriteString(s.summary(takt, ""))
+	return b.String()
+}
+
+// summary states the cycle time, critical path and takt comparison; em
+// marks the important figures
+func (s *Schedule) summary(takt float64, em string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cycle time: %s%.1f s%s\n", em, s.Cycle, em)
+	fmt.Fprintf(&b, "Critical path: %s\n", strings.Join(s.Critical, " -> "))
+	if takt > 0 {
+		margin := takt - s.Cycle
+		if margin >= 0 {
+			fmt.Fprintf(&b, "Takt time: %.1f s, margin %.1f s (%.0f %%)\n", takt, margin, margin/takt*100)
+		} else {
+			fmt.Fprintf(&b, "Takt time: %.1f s, %sover by %.1f s%s (%.0f %%); shorten or parallelize steps on the critical path\n",
+				takt, em, -margin, em, -margin/takt*100)
+		}
+	}
+	return b.String()
+}
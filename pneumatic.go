@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+const pneumaticUsage = `Usage: calc pneumatic --bore <mm> --stroke <mm> --cycle <s> [options]
+
+Air consumption, forces and valve duty of a double-acting gripper or
+cylinder from cycle data, as a summary for the pneumatic specification.
+
+Options:
+  --pressure <bar>       Supply pressure, gauge (default 6bar; also MPa, kPa, psi)
+  --rod <mm>             Piston rod diameter (default 0: retract air is overestimated)
+  --grip-time <s>        Time per cycle the valve is energized (gripper closed)
+  --stroke-time <s>      Time of one stroke, for the peak flow the valve must deliver
+  --cylinders <n>        Cylinders switched by the same valve (default 1)
+  --tube-id <mm>         Inner diameter of the tubing valve to cylinder
+  --tube-length <mm>     Length of that tubing, also in m
+  --hours <h>            Operating hours per day (default 16)
+  --days <n>             Operating days per year (default 250)
+  --valve-life <cycles>  Rated valve life in switching cycles, e.g. 50e6
+  --format text|markdown
+  --output <file>        Write the summary to a file (markdown for .md)
+
+Examples:
+  calc pneumatic --bore 32 --stroke 20 --rod 12 --cycle 12s --grip-time 8s
+  calc pneumatic --bore 25 --stroke 10 --cycle 6 --cylinders 2 --tube-id 4 --tube-length 2m --stroke-time 0.15 --valve-life 50e6 --output gripper_air.md`
+
+func calcPneumatic(args []string) (string, error) {
+	var cyl calc.Cylinder
+	pressure, cycle, grip, strokeTime, valveLife := 6.0, 0.0, 0.0, 0.0, 0.0
+	cylinders, hours, days := 1, 16.0, 250.0
+	format, output := "text", ""
+	seconds := map[string]float64{"s": 1, "ms": 0.001}
+	mm := map[string]float64{"mm": 1, "m": 1000}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return pneumaticUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--bore":
+			cyl.Bore, err = parseUnit(value, mm)
+		case "--stroke":
+			cyl.Stroke, err = parseUnit(value, mm)
+		case "--rod":
+			cyl.Rod, err = parseUnit(value, mm)
+		case "--tube-id":
+			cyl.TubeID, err = parseUnit(value, mm)
+		case "--tube-length":
+			cyl.TubeLength, err = parseUnit(value, mm)
+		case "--pressure":
+			pressure, err = parseUnit(value, map[string]float64{"bar": 1, "mpa": 10, "kpa": 0.01, "psi": 0.0689476})
+		case "--cycle":
+			cycle, err = parseUnit(value, seconds)
+		case "--grip-time":
+			grip, err = parseUnit(value, seconds)
+		case "--stroke-time":
+			strokeTime, err = parseUnit(value, seconds)
+		case "--cylinders":
+			cylinders, err = strconv.Atoi(value)
+			if cylinders < 1 {
+				err = fmt.Errorf("at least one cylinder")
+			}
+		case "--hours":
+			hours, err = strconv.ParseFloat(value, 64)
+		case "--days":
+			days, err = strconv.ParseFloat(value, 64)
+		case "--valve-life":
+			valveLife, err = strconv.ParseFloat(value, 64)
+		case "--format":
+			format = value
+			if format != "text" && format != "markdown" {
+				err = fmt.Errorf("unknown format")
+			}
+		case "--output":
+			output = value
+		default:
+			return pneumaticUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if cyl.Bore == 0 || cyl.Stroke == 0 || cycle <= 0 {
+		return pneumaticUsage, nil
+	}
+	if grip >= cycle {
+		return "", errors.New("grip time must be shorter than the cycle time")
+	}
+	p, err := calc.Pneumatic(cyl, pressure)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(strings.ToLower(output), ".md") {
+		format = "markdown"
+	}
+
+	n := float64(cylinders)
+	cyclesPerHour := 3600 / cycle
+	perYear := cyclesPerHour * hours * days
+	rows := [][2]string{
+		{"Cylinder", fmt.Sprintf("bore %g mm, rod %g mm, stroke %g mm, %d per valve", cyl.Bore, cyl.Rod, cyl.Stroke, cylinders)},
+		{"Supply pressure", fmt.Sprintf("%g bar gauge", pressure)},
+		{"Force extend / retract", fmt.Sprintf("%.0f N / %.0f N per cylinder (theoretical, about 85%% in practice)", p.ExtendForce, p.RetractForce)},
+		{"Air per cycle", fmt.Sprintf("%.3f NL (extend %.3f NL, retract %.3f NL per cylinder)", p.CycleAir()*n, p.ExtendAir, p.RetractAir)},
+		{"Average consumption", fmt.Sprintf("%.1f NL/min at a %g s cycle", p.CycleAir()*n*60/cycle, cycle)},
+	}
+	if strokeTime > 0 {
+		peak := p.ExtendAir
+		if p.RetractAir > peak {
+			peak = p.RetractAir
+		}
+		rows = append(rows, [2]string{"Peak flow", fmt.Sprintf("%.0f NL/min during a %g s stroke; size the valve and tubing for it", peak*n*60/strokeTime, strokeTime)})
+	}
+	if grip > 0 {
+		rows = append(rows, [2]string{"Valve duty", fmt.Sprintf("%.0f%% energized (%g s of %g s)", grip/cycle*100, grip, cycle)})
+	}
+	rows = append(rows,
+		[2]string{"Switching cycles", fmt.Sprintf("%.0f per hour, %.2f million per year (%g h x %g days)", cyclesPerHour, perYear/1e6, hours, days)},
+		[2]string{"Exhaust", fmt.Sprintf("%.0f exhaust events per minute; fit silencers on the valve exhausts", 2*60/cycle)},
+	)
+	if valveLife > 0 {
+		rows = append(rows, [2]string{"Valve life", fmt.Sprintf("about %.1f years at %g million switching cycles", valveLife/perYear, valveLife/1e6)})
+	}
+	if cyl.TubeLength > 0 && cyl.TubeID > 0 {
+		rows = append(rows, [2]string{"Tubing", fmt.Sprintf("%g mm inner diameter, %g mm long, included in the air per stroke", cyl.TubeID, cyl.TubeLength)})
+	}
+
+	var result strings.Builder
+	if format == "markdown" {
+		result.WriteString("# Pneumatic summary\n\n| Item | Value |\n|---|---|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&result, "| %s | %s |\n", r[0], r[1])
+		}
+		result.WriteString("\nFree air at 1.013 bar; add 10-20% for leakage and valve volume.\n")
+	} else {
+		for _, r := range rows {
+			fmt.Fprintf(&result, "%-23s %s\n", r[0]+":", r[1])
+		}
+		result.WriteString("\nFree air at 1.013 bar; add 10-20% for leakage and valve volume.")
+	}
+	if output != "" {
+		if err := writeFile(output, []byte(result.String()), 0644); err != nil {
+			return "", err
+		}
+		return "Summary written to " + output, nil
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
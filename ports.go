@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+)
+
+func init() {
+	commandRegistry["ports"] = Command{
+		Group:       groupReference,
+		Description: "Firewall ports needed by controllers, fieldbuses and cell devices",
+		Usage:       portsUsage,
+		Execute:     text(runPorts),
+	}
+}
+
+const portsUsage = `Usage: ports <device> [device...] [--format text|markdown|csv]
+Lists the TCP/UDP ports a device type needs, for firewall requests to the
+customer's IT. Combine the devices of a cell to get one table. Ports marked
+"required" are needed for normal operation; the others depend on the
+options and tools in use. Install a data pack with a "ports" section to add
+device types.
+
+Devices: %s
+
+Examples:
+  ports irc5
+  ports omnicore profinet cognex --format markdown > firewall.md`
+
+func runPorts(args []string) (string, error) {
+	usage := fmt.Sprintf(portsUsage, strings.Join(abb.PortDevices(), ", "))
+	format := "text"
+	var devices []abb.PortDevice
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--format" {
+			if i+1 >= len(args) {
+				return usage, nil
+			}
+			format = args[i+1]
+			i++
+			continue
+		}
+		d, ok := abb.PortsFor(args[i])
+		if !ok {
+			return "", fmt.Errorf("unknown device %q. Available: %s", args[i], strings.Join(abb.PortDevices(), ", "))
+		}
+		devices = append(devices, d)
+	}
+	if len(devices) == 0 {
+		return usage, nil
+	}
+
+	switch format {
+	case "text":
+		return formatPortsText(devices), nil
+	case "markdown":
+		return formatPortsMarkdown(devices), nil
+	case "csv":
+		return formatPortsCSV(devices), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use text, markdown or csv)", format)
+	}
+}
+
+func portRequired(p abb.Port) string {
+	if p.Required {
+		return "required"
+	}
+	return "optional"
+}
+
+func formatPortsText(devices []abb.PortDevice) string {
+	var b strings.Builder
+	for i, d := range devices {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s: %s\n", d.Name, d.Description)
+		for _, p := range d.Ports {
+			fmt.Fprintf(&b, "  %-8s %-11s %-4s %-8s %s\n", p.Protocol, p.Port, p.Direction, portRequired(p), p.Service)
+			if p.Note != "" {
+				fmt.Fprintf(&b, "  %-34s %s\n", "", p.Note)
+			}
+		}
+		if d.Note != "" {
+			fmt.Fprintf(&b, "Note: %s\n", d.Note)
+		}
+	}
+	b.WriteString("\nDirection: in = to the device, out = from the device. Layer 2 (ethernet) traffic cannot pass routers or firewalls.")
+	return b.String()
+}
+
+func formatPortsMarkdown(devices []abb.PortDevice) string {
+	var b strings.Builder
+	b.WriteString("| Device | Protocol | Port | Direction | Service | Required | Note |\n")
+	b.WriteString("|---|---|---|---|---|:---:|---|\n")
+	for _, d := range devices {
+		for _, p := range d.Ports {
+			required := ""
+			if p.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", d.Name, p.Protocol, p.Port, p.Direction, p.Service, required, p.Note)
+		}
+	}
+	for _, d := range devices {
+		if d.Note != "" {
+			fmt.Fprintf(&b, "\n- **%s**: %s", d.Name, d.Note)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatPortsCSV(devices []abb.PortDevice) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"device", "protocol", "port", "direction", "service", "required", "note"})
+	for _, d := range devices {
+		for _, p := range d.Ports {
+			w.Write([]string{d.Name, p.Protocol, p.Port, p.Direction, p.Service, portRequired(p), p.Note})
+		}
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const positionerUsage = `Usage: generate positioner [--unit STN1] [--axes 1|2] [--ratio 100]
+       [--wobj wobjStn1] [--module Station] [--output dir]
+Generates coordinated motion with a custom workpiece positioner: a RAPID
+module with the moving work object, ActUnit/DeactUnit, indexing with
+MoveExtJ and a coordinated seam example, plus the MOC.cfg entries for the
+unit (mechanical unit, singles, joints, arms and transmissions). --axes 2
+generates a tilt/rotation positioner on eax_a and eax_b. --output writes
+<module>.mod and MOC_<unit>.cfg.
+
+Standard IRBP positioners come with their configuration from the RobotWare
+media pool; use the RAPID module only. See also: abb quickref positioner_coordination
+
+Example:
+  generate positioner --unit STN1 --axes 2 --ratio 121`
+
+// generatePositioner writes the coordinated motion module and MOC.cfg entries
+func generatePositioner(args []string) (string, error) {
+	opts := generate.PositionerOptions{Unit: "STN1", Axes: 1, Ratio: 100, Module: "Station"}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return positionerUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--unit":
+			opts.Unit = strings.ToUpper(value)
+		case "--axes":
+			opts.Axes, err = strconv.Atoi(value)
+		case "--ratio":
+			opts.Ratio, err = strconv.ParseFloat(value, 64)
+		case "--wobj":
+			opts.Wobj = value
+		case "--module":
+			opts.Module = value
+		case "--output":
+			output = value
+		default:
+			return positionerUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if opts.Wobj == "" {
+		opts.Wobj = "wobj" + strings.ToUpper(opts.Unit[:1]) + strings.ToLower(opts.Unit[1:])
+	}
+
+	p, err := generate.PositionerCode(opts)
+	if err != nil {
+		return "", err
+	}
+	mocName := "MOC_" + opts.Unit + ".cfg"
+	if output == "" {
+		return p.Guidance + "\n--- " + opts.Module + ".mod ---\n" + p.Rapid + "\n--- " + mocName + " ---\n" + strings.TrimRight(p.MOC, "\n"), nil
+	}
+
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	files := []struct{ name, content string }{
+		{opts.Module + ".mod", p.Rapid},
+		{mocName, p.MOC},
+	}
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(output, f.name)
+		if err := writeFile(path, []byte(f.content), 0644); err != nil {
+			return "", err
+		}
+		written = append(written, path)
+	}
+	return p.Guidance + "\nWrote " + strings.Join(written, ", "), nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/db"
+	"github.com/polyfant/automation-helper-cli/history"
+	"github.com/polyfant/automation-helper-cli/result"
+)
+
+// profile tailors the CLI to a role: which commands help shows, how much
+// guidance is printed and which commands ask before they act
+type profile struct {
+	Description string
+	// Featured commands are listed first in help
+	Featured []string
+	// Hidden commands are left out of help; they still run when typed
+	Hidden []string
+	// Verbose adds hints on how to get usage and examples
+	Verbose bool
+	// Confirm asks before commands that act on a controller or the cell
+	Confirm bool
+	// ReadOnly disables the commands that write to controllers or files,
+	// as --read-only does
+	ReadOnly bool
+}
+
+// profiles are the roles that can be selected with config set profile
+var profiles = map[string]profile{
+	"programmer": {
+		Description: "All commands; code tools first",
+		Featured:    []string{"abb", "lint", "generate", "vcs", "calc"},
+	},
+	"maintenance": {
+		Description: "Troubleshooting and error lookups first, code generators hidden, confirmations on",
+		Featured:    []string{"error", "alarms", "dashboard", "abb", "cfg", "timecheck", "glossary"},
+		Hidden:      []string{"generate", "sensor", "snippet", "edit", "modbus", "plan", "timing", "watch", "vcs", "advise", "data", "grpc", "serve", "bot"},
+		Confirm:     true,
+	},
+	"auditor": {
+		Description: "Read-only: lookups, lint, reports and controller reads; nothing is written",
+		Featured:    []string{"abb", "lint", "error", "alarms", "audit", "report", "cfg"},
+		Hidden:      []string{"generate", "sensor", "edit", "snippet", "modbus", "socket", "serial", "watch", "grpc", "serve", "bot", "pack", "update", "vault"},
+		ReadOnly:    true,
+	},
+	"student": {
+		Description: "Reference and learning commands, extra hints, confirmations on",
+		Featured:    []string{"tour", "abb", "glossary", "error", "lint", "calc"},
+		Hidden:      []string{"grpc", "serve", "bot", "update", "pack", "data", "secrets", "alarms", "log", "vcs", "watch"},
+		Verbose:     true,
+		Confirm:     true,
+	},
+}
+
+// confirmations lists commands, optionally with their subcommand, that act
+// on a controller or cell devices, with what they do
+var confirmations = map[string]string{
+	"abb vc":        "loads modules into a controller and runs them",
+	"scenario play": "sets I/O signals on a controller",
+	"socket":        "sends messages to a socket server on the robot",
+}
+
+// confirmDisabled skips the profile's confirmations for commands run
+// unattended, such as scheduled jobs
+var confirmDisabled bool
+
+// profileNames returns the profile names in sorted order
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activeProfile returns the profile selected in the settings; without one
+// every command is shown and nothing asks for confirmation
+func activeProfile() (string, profile) {
+	s, err := config.LoadSettings()
+	if err != nil || s.Profile == "" {
+		return "", profile{}
+	}
+	return s.Profile, profiles[s.Profile]
+}
+
+// execute runs a command under the active profile: it asks before commands
+// that act on a controller when the profile wants confirmations, and adds a
+// usage hint to errors for verbose profiles. Files the command writes are
+// recorded for undo; with --dry-run they are shown as a diff instead. With
+// --target it runs once for each fleet target.
+func execute(name string, cmd Command, args []string) result.Result {
+	if name != "fleet" && strings.Contains(cmd.Usage, "--host") {
+		targets, rest, err := fleetTargets(args, false)
+		if err != nil {
+			return result.Errorf("%v", err)
+		}
+		if targets != nil {
+			return onTargets(name, cmd, targets, rest)
+		}
+	}
+	args, dry := dryRunFlag(args)
+	if dry && noDryRun[name] {
+		return result.Errorf("%s does not support --dry-run", name)
+	}
+	ro := readOnly()
+	if ro && !readOnlyAllowed(name, args) {
+		what := name
+		if len(args) > 0 {
+			what += " " + args[0]
+		}
+		return result.Errorf("'%s' is disabled in read-only mode: it writes to controllers or files", what)
+	}
+	setReadOnly(ro)
+	// In read-only mode the commands run as a dry run, which is only
+	// reported when it kept files from being written
+	readOnlyRun := ro && !dry && !noDryRun[name]
+	dry = dry || readOnlyRun
+	_, p := activeProfile()
+	if p.Confirm && !dry && !confirmDisabled {
+		if what, ok := needsConfirmation(name, args); ok {
+			answer, ok := readLine(fmt.Sprintf("'%s' %s. Is the cell clear and safe to continue? [y/N]: ", name, what))
+			if !ok || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+				return result.Errorf("canceled")
+			}
+		}
+	}
+	prev := changes
+	changes = &history.Changeset{Command: strings.TrimSpace(name + " " + strings.Join(args, " ")), DryRun: dry}
+	start := time.Now()
+	r := cmd.Execute(args)
+	// The usage record is a convenience; a locked or unwritable database
+	// must not fail the command
+	_ = db.RecordUse(db.Use{Command: name, Time: start, Failed: r.Failed(), Duration: time.Since(start)})
+	if dry && !r.Failed() && (!readOnlyRun || len(changes.Changes) > 0) {
+		r.Add(dryRunReport(*changes)...)
+	}
+	if err := history.Record(*changes); err != nil {
+		r.Add(result.Text{Body: "Warning: the change was not recorded for undo: " + err.Error()})
+	}
+	changes = prev
+	if p.Verbose && r.Failed() {
+		r.Err += fmt.Sprintf("\nHint: type 'help %s' for its usage and examples.", name)
+	}
+	return r
+}
+
+// needsConfirmation reports whether a command line acts on a controller
+func needsConfirmation(name string, args []string) (string, bool) {
+	if what, ok := confirmations[name]; ok && len(args) > 0 {
+		return what, true
+	}
+	if len(args) > 0 {
+		if what, ok := confirmations[name+" "+args[0]]; ok && len(args) > 1 {
+			return what, true
+		}
+	}
+	return "", false
+}
+
+// validProfile checks a profile name for config set
+func validProfile(name string) error {
+	if _, ok := profiles[name]; !ok && name != "" {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(profileNames(), ", "))
+	}
+	return nil
+}
+
+// hiddenByProfile reports whether help leaves a command out
+func hiddenByProfile(p profile, name string) bool {
+	for _, h := range p.Hidden {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Package project holds the per-project context selected with use: where the
+// RAPID sources are, the robot model, the target controller and naming
+// conventions, so commands can default to them
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+// File is the project settings file in the project directory
+const File = "automation-helper.json"
+
+// Project is the context of one robot cell project. Empty fields leave the
+// command defaults in place.
+type Project struct {
+	Dir        string   `json:"-"`
+	Name       string   `json:"name"`
+	Robot      string   `json:"robot,omitempty"`      // robot key, e.g. irb6700-150
+	Controller string   `json:"controller,omitempty"` // target RobotWare, e.g. rw7 or 6.14
+	Cell       string   `json:"cell,omitempty"`       // cell name for module headers
+	Sources    []string `json:"sources,omitempty"`    // RAPID directories relative to Dir
+	// TargetPrefix starts the robtarget names suggested by rename-targets
+	TargetPrefix string `json:"target_prefix,omitempty"`
+	// Style names the code style profile of generated and formatted code
+	Style string `json:"style,omitempty"`
+}
+
+// Load reads the project in dir. A directory without a project file is a
+// project with default settings named after the directory.
+func Load(dir string) (*Project, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	p := &Project{Dir: abs, Name: filepath.Base(abs)}
+	data, err := os.ReadFile(filepath.Join(abs, File))
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Join(abs, File), err)
+	}
+	return p, nil
+}
+
+// Save writes the project file
+func (p *Project) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p.Dir, File), append(data, '\n'), 0o644)
+}
+
+// SourceDirs returns the RAPID source directories, the project directory
+// itself when none are configured
+func (p *Project) SourceDirs() []string {
+	if len(p.Sources) == 0 {
+		return []string{p.Dir}
+	}
+	dirs := make([]string, len(p.Sources))
+	for i, s := range p.Sources {
+		if filepath.IsAbs(s) {
+			dirs[i] = s
+		} else {
+			dirs[i] = filepath.Join(p.Dir, s)
+		}
+	}
+	return dirs
+}
+
+// Active returns the project selected with use, or nil when there is none
+func Active() (*Project, error) {
+	s, err := config.LoadSettings()
+	if err != nil || s.Project == "" {
+		return nil, err
+	}
+	p, err := Load(s.Project)
+	if err != nil {
+		return nil, fmt.Errorf("active project: %v", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const regenUsage = `Usage: regen <file> [--<option> <value>]...
+Re-runs the generator that wrote a file with the parameters recorded in its
+provenance comment, using the templates of this version. Options given here
+replace or add to the recorded ones. Every file of the generator is written
+again, so edits made to them are lost: add --dry-run to review the diff
+first, and undo to go back.
+
+RAPID and PLC files written by 'generate ... --output' carry the comment.
+
+Examples:
+  regen ./handshake/Handshake.mod
+  regen ./handshake/Handshake.mod --timeout 20 --dry-run`
+
+func init() {
+	commandRegistry["regen"] = Command{
+		Group:       groupCode,
+		Description: "Re-run the generator of a generated file",
+		Usage:       regenUsage,
+		Execute:     text(runRegen),
+	}
+}
+
+// generation is the generator run the files being written come from; set by
+// abbGenerate so writeFile can stamp them
+var generation *provenance
+
+// provenance records how a file was generated: the tool version, the
+// generator template and its parameters
+type provenance struct {
+	Version  string
+	Template string
+	Args     []string
+	Hash     string // of the parameters as recorded; empty when stamping
+	Style    rapid.Style
+}
+
+var (
+	provenanceRe = regexp.MustCompile(`Generated by automation-helper-cli (\S+) from template (\S+), parameters ([0-9a-f]+)`)
+	regenLineRe  = regexp.MustCompile(`regen: generate (.+?)\s*(\*\))?$`)
+	moduleLineRe = regexp.MustCompile(`(?mi)^[ \t]*MODULE[ \t].*\n`)
+	endModuleRe  = regexp.MustCompile(`(?mi)^[ \t]*ENDMODULE\b.*`)
+)
+
+// parametersHash identifies the parameters of a generator run
+func parametersHash(template string, args []string) string {
+	sum := sha256.Sum256([]byte(template + " " + strings.Join(args, " ")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// generatedWith replaces the parameters recorded for the running generator,
+// for generators that complete their options first, such as wizards
+func generatedWith(args []string) {
+	if generation != nil {
+		generation.Args = args
+	}
+}
+
+// stampProvenance adds the provenance comment to generated RAPID and PLC
+// code: after the MODULE line of a RAPID module, at the top of ST and SCL
+// sources. Other files are returned unchanged.
+func stampProvenance(path string, data []byte) []byte {
+	if generation == nil {
+		return data
+	}
+	lines := []string{
+		fmt.Sprintf("Generated by automation-helper-cli %s from template %s, parameters %s",
+			version, generation.Template, parametersHash(generation.Template, generation.Args)),
+		"regen: generate " + quoteArgs(append([]string{generation.Template}, generation.Args...)),
+	}
+	content := string(data)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mod", ".modx", ".sys":
+		loc := moduleLineRe.FindStringIndex(content)
+		if loc == nil {
+			return data
+		}
+		end := loc[1]
+		var comment strings.Builder
+		for _, l := range lines {
+			comment.WriteString(generation.Style.Unit() + "! " + l + "\n")
+		}
+		return []byte(content[:end] + comment.String() + content[end:])
+	case ".st", ".scl":
+		var comment strings.Builder
+		for _, l := range lines {
+			comment.WriteString("(* " + l + " *)\n")
+		}
+		return []byte(comment.String() + content)
+	}
+	return data
+}
+
+// readProvenance finds the provenance comment of a generated file
+func readProvenance(content string) (*provenance, bool) {
+	m := provenanceRe.FindStringSubmatch(content)
+	if m == nil {
+		return nil, false
+	}
+	p := &provenance{Version: m[1], Template: m[2], Hash: m[3]}
+	for _, line := range strings.Split(content, "\n") {
+		if r := regenLineRe.FindStringSubmatch(strings.TrimSpace(line)); r != nil {
+			fields, err := splitArgs(r[1])
+			if err != nil || len(fields) == 0 || fields[0] != p.Template {
+				return nil, false
+			}
+			p.Args = fields[1:]
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// runRegen re-runs the generator of a file
+func runRegen(args []string) (string, error) {
+	if len(args) < 1 || len(args)%2 == 0 {
+		return regenUsage, nil
+	}
+	path := args[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	p, ok := readProvenance(string(data))
+	if !ok {
+		return "", fmt.Errorf("%s has no provenance comment; only files written by 'generate ... --output' can be regenerated", path)
+	}
+
+	var notes []string
+	if parametersHash(p.Template, p.Args) != p.Hash {
+		notes = append(notes, "The recorded parameters were edited since the file was generated; regenerating with the edited ones.")
+	}
+	if p.Version != version {
+		notes = append(notes, fmt.Sprintf("Generated by version %s, regenerating with %s.", p.Version, version))
+	}
+
+	// The output goes where the file is now: its directory, or the file
+	// itself for generators that write a single file
+	genArgs := append([]string(nil), p.Args...)
+	genArgs = setOption(genArgs, "--output", func(output string) string {
+		if filepath.Base(output) == filepath.Base(path) {
+			return path
+		}
+		return filepath.Dir(path)
+	})
+	for i := 1; i+1 < len(args); i += 2 {
+		if !strings.HasPrefix(args[i], "--") {
+			return regenUsage, nil
+		}
+		value := args[i+1]
+		genArgs = setOption(genArgs, args[i], func(string) string { return value })
+	}
+
+	out, err := abbGenerate(append([]string{p.Template}, genArgs...))
+	if err != nil {
+		return "", err
+	}
+	notes = append(notes, fmt.Sprintf("Regenerated with: generate %s %s", p.Template, quoteArgs(genArgs)))
+	return strings.Join(notes, "\n") + "\n" + out, nil
+}
+
+// setOption sets an option of a generator command line to value(previous),
+// adding it when missing
+func setOption(args []string, name string, value func(previous string) string) []string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == name {
+			args[i+1] = value(args[i+1])
+			return args
+		}
+	}
+	return append(args, name, value(""))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteArgs(t *testing.T) {
+	tests := [][]string{
+		{"handshake", "--signals", "job_start,job_done"},
+		{"--title", "Cell 3: {{.Title}}"},
+		{"--note", "it's"},
+		{"--note", `say "hi" it's`},
+		{"--output", `C:\cells\cell 3\`},
+		{"--prefix", ""},
+		{"--sep", "\t"},
+	}
+	for _, args := range tests {
+		line := quoteArgs(args)
+		got, err := splitArgs(line)
+		if err != nil || !reflect.DeepEqual(got, args) {
+			t.Errorf("quoteArgs(%q) = %s, splits back to %q, %v", args, line, got, err)
+		}
+	}
+}
+
+func TestProvenanceRoundTrip(t *testing.T) {
+	args := []string{"--signals", "job_start,job_done", "--title", "Cell 3 \"north\"", "--output", `C:\robot data\cell3`}
+	tests := []struct {
+		path, content string
+	}{
+		{"Handshake.mod", "MODULE Handshake\n    PROC Main()\n    ENDPROC\nENDMODULE\n"},
+		{"FB_JobHandshake.st", "FUNCTION_BLOCK FB_JobHandshake\nEND_FUNCTION_BLOCK\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			prev := generation
+			generation = &provenance{Template: "handshake", Args: args}
+			defer func() { generation = prev }()
+
+			stamped := string(stampProvenance(tt.path, []byte(tt.content)))
+			p, ok := readProvenance(stamped)
+			if !ok {
+				t.Fatalf("no provenance in\n%s", stamped)
+			}
+			if p.Template != "handshake" || !reflect.DeepEqual(p.Args, args) {
+				t.Errorf("read %s %q, want handshake %q", p.Template, p.Args, args)
+			}
+			if p.Hash != parametersHash("handshake", args) {
+				t.Errorf("hash %s does not match the parameters", p.Hash)
+			}
+		})
+	}
+}
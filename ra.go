@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/pdf"
+	"github.com/polyfant/automation-helper-cli/risk"
+)
+
+func init() {
+	commandRegistry["ra"] = Command{
+		Group:       groupCalc,
+		Description: "Risk assessment aid: questionnaire and draft risk register",
+		Usage:       raUsage,
+		Execute:     text(runRA),
+	}
+}
+
+const raUsage = `Usage:
+  ra wizard [--answers cell.json] [--output register.md|register.csv|register.pdf]
+  ra plr <S1|S2> <F1|F2> <P1|P2>
+
+wizard asks about the cell (speeds, safeguarding, collaborative modes,
+tools, stored energy) and drafts a risk register with hazards, risk graph
+parameters, required performance levels and proposed measures, oriented on
+ISO 10218-2 and ISO/TS 15066. --answers keeps the answers in a file: known
+answers are not asked again, so the register can be regenerated after
+editing the file. Type ? at a question for help.
+
+This is an aid for the safety engineer. It is not a risk assessment and
+does not certify anything; the engineer completes and signs the register.
+
+plr looks up the required performance level in the ISO 13849-1 risk graph.
+
+Examples:
+  ra wizard --answers cell4.json --output cell4_risks.pdf
+  ra plr S2 F1 P2`
+
+func runRA(args []string) (string, error) {
+	if len(args) < 1 {
+		return raUsage, nil
+	}
+	switch args[0] {
+	case "wizard":
+		return raWizard(args[1:])
+	case "plr":
+		return raPLr(args[1:])
+	default:
+		return raUsage, nil
+	}
+}
+
+func raWizard(args []string) (string, error) {
+	answersPath, output := "", ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return raUsage, nil
+		}
+		switch args[i] {
+		case "--answers":
+			answersPath = args[i+1]
+		case "--output":
+			output = args[i+1]
+		default:
+			return raUsage, nil
+		}
+		i++
+	}
+
+	answers := risk.Answers{}
+	if answersPath != "" {
+		data, err := os.ReadFile(answersPath)
+		if err == nil {
+			if err := json.Unmarshal(data, &answers); err != nil {
+				return "", fmt.Errorf("%s: %v", answersPath, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	fmt.Println("Risk assessment questionnaire. " + risk.Disclaimer)
+	fmt.Println(wizardHint)
+	if err := wizardSession.Run(risk.Questions, answers); err != nil {
+		return "", errors.New("questionnaire canceled")
+	}
+
+	if answersPath != "" {
+		data, err := json.MarshalIndent(answers, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		if err := writeFile(answersPath, append(data, '\n'), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	hazards := risk.Assess(answers)
+	register := risk.Markdown(answers, hazards)
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "\n%d hazards drafted for %s:\n", len(hazards), answers["cell"])
+	for _, h := range hazards {
+		fmt.Fprintf(&summary, "  %s PLr %s  %s\n", h.ID, h.PLr(), h.Hazard)
+	}
+	if answersPath != "" {
+		fmt.Fprintf(&summary, "Answers saved to %s\n", answersPath)
+	}
+	if output == "" {
+		return summary.String() + "\n" + strings.TrimRight(register, "\n"), nil
+	}
+	var content []byte
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".csv":
+		content = []byte(risk.CSV(hazards))
+	case ".pdf":
+		content = pdf.Render(register)
+	default:
+		content = []byte(register)
+	}
+	if err := writeFile(output, content, 0644); err != nil {
+		return "", err
+	}
+	return summary.String() + "Wrote " + output, nil
+}
+
+func raPLr(args []string) (string, error) {
+	if len(args) != 3 {
+		return raUsage, nil
+	}
+	var values [3]int
+	for i, prefix := range []string{"S", "F", "P"} {
+		v := strings.ToUpper(args[i])
+		if v != prefix+"1" && v != prefix+"2" {
+			return "", fmt.Errorf("invalid value %q, expected %s1 or %s2", args[i], prefix, prefix)
+		}
+		values[i] = int(v[1] - '0')
+	}
+	return fmt.Sprintf("PLr %s (S%d F%d P%d, ISO 13849-1 Annex A)", risk.PLr(values[0], values[1], values[2]), values[0], values[1], values[2]), nil
+}
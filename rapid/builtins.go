@@ -0,0 +1,75 @@
+package rapid
+
+import "strings"
+
+// keywords are reserved words of the RAPID language
+var keywords = toSet(`ALIAS AND BACKWARD CASE CONNECT CONST DEFAULT DIV DO ELSE ELSEIF
+	ENDFOR ENDFUNC ENDIF ENDMODULE ENDPROC ENDRECORD ENDTEST ENDTRAP ENDWHILE ERROR
+	EXIT FALSE FOR FROM FUNC GOTO IF INOUT LOCAL MOD MODULE NOSTEPIN NOT NOVIEW OR
+	PERS PROC RAISE READONLY RECORD RETRY RETURN STEP SYSMODULE TASK TEST THEN TO
+	TRAP TRUE TRYNEXT UNDO VAR VIEWONLY WHILE WITH XOR`)
+
+// builtins are data types, instructions, functions and predefined data that
+// the controller provides, so references to them need no module
+var builtins = toSet(`
+	num dnum bool string byte robtarget jointtarget tooldata wobjdata speeddata
+	zonedata loaddata pos orient pose confdata extjoint robjoint intnum errnum
+	signaldi signaldo signalai signalao signalgi signalgo clock iodev dir
+	triggdata stoppointdata errdomain errtype symnum btnres listitem wzstationary
+	wztemporary shapedata mecunit taskid syncident tasks rawbytes socketdev
+	socketstatus stringdig opnum egmident
+
+	MoveJ MoveL MoveC MoveAbsJ MoveJDO MoveLDO MoveCDO MoveJSync MoveLSync
+	MoveExtJ SearchL SearchC SearchExtJ TriggL TriggJ TriggC TriggIO TriggEquip
+	TriggInt StopMove StartMove StartMoveRetry StorePath RestoPath ClearPath
+	ConfJ ConfL SingArea AccSet VelSet PathResol SoftAct SoftDeact
+	Set Reset SetDO SetAO SetGO PulseDO WaitDI WaitDO WaitAI WaitAO WaitGI WaitGO
+	WaitTime WaitUntil WaitSyncTask WaitRob WaitLoad Stop Break EXIT ExitCycle
+	TPWrite TPErase TPReadFK TPReadNum TPShow ErrWrite ErrLog UIMsgBox UINumEntry
+	UIListView UIAlphaEntry Open Close Write WriteBin WriteStrBin Rewind
+	IDelete ISignalDI ISignalDO ISignalAI ISignalGI ITimer ISleep IWatch IEnable
+	IDisable IError CONNECT Load UnLoad StartLoad WaitLoad EraseModule
+	GripLoad MechUnitLoad ActUnit DeactUnit CallByVar Incr Decr Add Clear
+	ClkStart ClkStop ClkReset SocketCreate SocketConnect SocketSend SocketReceive
+	SocketClose SocketBind SocketListen SocketAccept SetSysData SpyStart SpyStop
+	WZBoxDef WZCylDef WZSphDef WZLimSup WZDOSet WZEnable WZDisable WZFree
+	SkipWarn RaiseToUser ReadErrData SetDataSearch GetDataVal SetDataVal
+	TestSignDefine TestSignReset SyncMoveOn SyncMoveOff SyncMoveUndo
+	EOffsSet EOffsOff EOffsOn PDispOn PDispOff PDispSet ProcerrRecovery
+	CRobT CJointT CPos CTool CWObj Offs RelTool Abs Sqrt Pow Sin Cos Tan ASin
+	ACos ATan ATan2 Round Trunc Exp DOutput DInput AOutput GOutput TestDI
+	StrLen StrPart StrFind StrMatch StrMemb StrOrder StrMap NumToStr StrToVal
+	ValToStr ByteToStr StrToByte DnumToNum NumToDnum Dim Present IsPers IsVar
+	ReadNum ReadStr ReadBin ReadMotor ClkRead CDate CTime GetTime OpMode RunMode
+	RobOS IsStopStateEvent MaxRobSpeed ArgName GetTaskName GetMecUnitName
+	CalcRobT CalcJointT CalcRotAxisFrame DefFrame DefDFrame OrientZYX EulerZYX
+	NOrient PoseInv PoseMult PoseVect VectMagn DotProd CrossProd ReadVar
+	Distance ModTime ModExist IsFile FileSize IsSysId ErrRaise TaskRunMec
+	TaskRunRob SocketGetStatus
+
+	tool0 wobj0 load0 fine vmax v5 v10 v20 v30 v40 v50 v60 v80 v100 v150 v200
+	v300 v400 v500 v600 v800 v1000 v1500 v2000 v2500 v3000 v4000 v5000 v6000
+	v7000 z0 z1 z5 z10 z15 z20 z30 z40 z50 z60 z80 z100 z150 z200 ERRNO
+	INTNO pi diskhome diskram EOF_BIN EOF_NUM EOF high low edge stEmpty inpos20
+	inpos50 inpos100 stoptime0_5 stoptime1_0 stoptime1_5 fllwtime0_5 fllwtime1_0
+	fllwtime1_5 OP_AUTO OP_MAN_PROG OP_MAN_TEST OP_UNDEF RUN_CONT_CYCLE
+	RUN_INSTR_FWD RUN_INSTR_BWD RUN_SIM RUN_STEP_MOVE RUN_UNDEF TYPE_ALL TYPE_ERR
+	TYPE_STATE TYPE_WARN COMMON_ERR OP_STATE SYSTEM_ERR HARDWARE_ERR PROGRAM_ERR
+	MOTION_ERR OPERATOR_ERR IO_COM_ERR USER_DEF_ERR SAFETY_ERR PROCESS_ERR CFG_ERR
+	STR_DIGIT STR_UPPER STR_LOWER STR_WHITE reg1 reg2 reg3 reg4 reg5
+`)
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[strings.ToUpper(w)] = true
+	}
+	return set
+}
+
+// IsBuiltin reports whether name is a keyword or is provided by the controller.
+// Names are compared case-insensitively, as RAPID does.
+func IsBuiltin(name string) bool {
+	upper := strings.ToUpper(name)
+	return keywords[upper] || builtins[upper] || strings.HasPrefix(upper, "ERR_")
+}
@@ -0,0 +1,325 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Thresholds of the zone and speed checks
+const (
+	fineSpeed  = 2000.0 // mm/s; stopping at a fine point from this speed is suspicious
+	creepSpeed = 20.0   // mm/s
+	largeZone  = 50.0   // mm
+)
+
+var (
+	zoneRe = regexp.MustCompile(`(?i)^z(\d+)$`)
+	// speedNameRe matches the predefined speeddata, including the vrot and
+	// vlin data of external axes
+	speedNameRe = regexp.MustCompile(`(?i)^v(max|(rot|lin)?\d+)$`)
+	// signalNameRe matches the usual names of I/O signals, which are
+	// configured in EIO.cfg rather than declared in RAPID
+	signalNameRe = regexp.MustCompile(`^(?i:s?d[io]|[ag][io])(_|\d|[A-Z])`)
+)
+
+// speedArg is the position of the speed argument of the motion instructions
+// whose zone and speed are checked; the zone follows it
+var speedArg = map[string]int{
+	"MOVEL": 1, "MOVEJ": 1, "MOVEABSJ": 1, "MOVELDO": 1, "MOVEJDO": 1,
+	"MOVELSYNC": 1, "MOVEJSYNC": 1, "MOVEC": 2, "MOVECDO": 2,
+}
+
+// configFuncs take a signal or mechanical unit as their first argument.
+// Both are configured in the controller (EIO.cfg, MOC.cfg) rather than
+// declared in RAPID.
+var configFuncs = map[string]bool{
+	"DINPUT": true, "DOUTPUT": true, "AINPUT": true, "AOUTPUT": true, "GINPUT": true,
+	"GOUTPUT": true, "TESTDI": true, "VALIDIO": true, "ISIGNALDI": true, "ISIGNALDO": true,
+	"ISIGNALAI": true, "ISIGNALAO": true, "ISIGNALGI": true, "ISIGNALGO": true,
+	"WAITAO": true, "WAITGO": true, "TRIGGIO": true, "ACTUNIT": true, "DEACTUNIT": true,
+	"MECHUNITLOAD": true, "ISMECHUNITACTIVE": true, "GETMECUNITNAME": true, "INDRESET": true,
+}
+
+// outputInstructions change an output as soon as the program pointer
+// reaches them
+var outputInstructions = map[string]bool{
+	"SET": true, "RESET": true, "SETDO": true, "PULSEDO": true, "SETAO": true, "SETGO": true,
+}
+
+// checker runs the checks that need a parsed module
+type checker struct {
+	module  *Module
+	globals map[string]bool // names declared by the other modules of the task
+	config  map[string]bool // names used as signals or mechanical units
+	diags   []Diagnostic
+}
+
+// checkModule finds undeclared names, unreachable code and suspicious zone
+// and speed combinations in a parsed module
+func checkModule(m *Module, globals map[string]bool) []Diagnostic {
+	c := &checker{module: m, globals: globals, config: make(map[string]bool)}
+	for _, r := range m.Routines {
+		c.routine(r)
+	}
+	sort.SliceStable(c.diags, func(i, j int) bool { return c.diags[i].Line < c.diags[j].Line })
+	return c.diags
+}
+
+func (c *checker) warn(line int, format string, args ...any) {
+	c.diags = append(c.diags, Diagnostic{line, SeverityWarning, fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) routine(r *RoutineDecl) {
+	declared := make(map[string]bool)
+	for _, name := range c.module.Types {
+		declared[strings.ToUpper(name)] = true
+	}
+	for _, d := range c.module.Data {
+		declared[strings.ToUpper(d.Name)] = true
+	}
+	for _, other := range c.module.Routines {
+		declared[strings.ToUpper(other.Name)] = true
+	}
+	for _, p := range r.Params {
+		declared[strings.ToUpper(p.Name)] = true
+	}
+	for _, d := range r.Data {
+		declared[strings.ToUpper(d.Name)] = true
+	}
+	blocks := [][]*Stmt{r.Body, r.Backward, r.Undo}
+	if r.Error != nil {
+		blocks = append(blocks, r.Error.Body)
+	}
+	for _, b := range blocks {
+		walk(b, func(s *Stmt) {
+			if s.Kind == StmtLabel || s.Kind == StmtFor {
+				declared[strings.ToUpper(s.Name)] = true
+			}
+			if s.Kind == StmtCall && len(s.Args) > 0 && (ioInstructions[strings.ToUpper(s.Name)] || configFuncs[strings.ToUpper(s.Name)]) {
+				c.configured(s.Args[0].Value)
+			}
+			for _, tokens := range stmtExprs(s) {
+				for i, t := range tokens {
+					if t.Kind == TokenIdent && configFuncs[strings.ToUpper(t.Text)] && i+2 < len(tokens) && tokens[i+1].Is("(") {
+						c.configured(tokens[i+2 : i+3])
+					}
+				}
+			}
+		})
+	}
+
+	reported := make(map[string]bool)
+	check := func(t Token) {
+		key := strings.ToUpper(t.Text)
+		if declared[key] || c.globals[key] || c.config[key] || reported[key] || IsBuiltin(t.Text) ||
+			speedNameRe.MatchString(t.Text) || zoneRe.MatchString(t.Text) || signalNameRe.MatchString(t.Text) {
+			return
+		}
+		reported[key] = true
+		where := " in this module"
+		if c.globals != nil {
+			where = " in this module or the other modules of the task"
+		}
+		c.warn(t.Line, "%s is not declared%s", t.Text, where)
+	}
+	for _, d := range r.Data {
+		c.names(d.Init, check)
+	}
+	for _, b := range blocks {
+		walk(b, func(s *Stmt) {
+			if s.Kind == StmtAssign {
+				check(s.Target[0])
+			}
+			for _, tokens := range stmtExprs(s) {
+				c.names(tokens, check)
+			}
+		})
+		c.unreachable(b, r.Error != nil && sameBlock(b, r.Error.Body))
+		c.motion(b)
+	}
+	if r.Error != nil && len(r.Error.Errors) == 0 && !canRaise(r.Body) {
+		c.warn(r.Error.Line, "the ERROR handler of %s is never reached: nothing in the routine can raise an error", r.Name)
+	}
+}
+
+// configured records the name of a signal or mechanical unit argument
+func (c *checker) configured(tokens []Token) {
+	if len(tokens) > 0 && tokens[0].Kind == TokenIdent {
+		c.config[strings.ToUpper(tokens[0].Text)] = true
+	}
+}
+
+// names calls check for the identifiers in an expression that name data:
+// not functions, record components, optional argument names or the
+// strings of late-bound calls
+func (c *checker) names(tokens []Token, check func(Token)) {
+	for i, t := range tokens {
+		if t.Kind != TokenIdent {
+			continue
+		}
+		if i > 0 && (tokens[i-1].Is(".") || tokens[i-1].Is("\\")) {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].Is("(") {
+			continue
+		}
+		check(t)
+	}
+}
+
+// stmtExprs returns the expressions of a statement that read data
+func stmtExprs(s *Stmt) [][]Token {
+	exprs := [][]Token{s.Expr}
+	if s.Kind == StmtAssign && len(s.Target) > 1 {
+		exprs = append(exprs, s.Target[1:])
+	}
+	if s.Kind == StmtConnect {
+		exprs = append(exprs, s.Target)
+	}
+	for _, a := range s.Args {
+		exprs = append(exprs, a.Value)
+	}
+	for _, c := range s.Cases {
+		exprs = append(exprs, c.Values...)
+	}
+	return exprs
+}
+
+// walk calls fn for each statement of a block and the blocks nested in it
+func walk(stmts []*Stmt, fn func(*Stmt)) {
+	for _, s := range stmts {
+		fn(s)
+		walk(s.Body, fn)
+		walk(s.Else, fn)
+		for _, c := range s.Cases {
+			walk(c.Body, fn)
+		}
+	}
+}
+
+func sameBlock(a, b []*Stmt) bool {
+	return len(a) > 0 && len(b) > 0 && a[0] == b[0]
+}
+
+// unreachable reports statements after RETURN, RAISE, EXIT, GOTO, RETRY
+// or TRYNEXT, RETRY and TRYNEXT outside the ERROR handler and CASE values
+// that an earlier CASE of the TEST already handles
+func (c *checker) unreachable(stmts []*Stmt, handler bool) {
+	for i, s := range stmts {
+		switch s.Kind {
+		case StmtRetry, StmtTryNext:
+			if !handler {
+				c.diags = append(c.diags, Diagnostic{s.Line, SeverityError, s.Kind + " is only allowed in an ERROR handler"})
+			}
+			fallthrough
+		case StmtReturn, StmtRaise, StmtExit, StmtGoto:
+			if i+1 < len(stmts) && stmts[i+1].Kind != StmtLabel {
+				c.warn(stmts[i+1].Line, "unreachable code after %s", s.Kind)
+			}
+		case StmtTest:
+			seen := make(map[string]int)
+			for _, cs := range s.Cases {
+				for _, v := range cs.Values {
+					key := strings.ToUpper(tokenText(v))
+					if line, ok := seen[key]; ok {
+						c.warn(cs.Line, "CASE %s is unreachable: it is handled on line %d", tokenText(v), line)
+						continue
+					}
+					seen[key] = cs.Line
+				}
+			}
+		}
+		c.unreachable(s.Body, handler)
+		c.unreachable(s.Else, handler)
+		for _, cs := range s.Cases {
+			c.unreachable(cs.Body, handler)
+		}
+	}
+}
+
+// canRaise reports whether a block can raise an error the ERROR handler
+// catches: any call, function, division or RAISE can, while plain
+// assignments of literals and control flow cannot
+func canRaise(stmts []*Stmt) bool {
+	raises := false
+	walk(stmts, func(s *Stmt) {
+		switch s.Kind {
+		case StmtCall, StmtRaise, StmtConnect:
+			raises = true
+		}
+		for _, tokens := range stmtExprs(s) {
+			for i, t := range tokens {
+				if t.Is("/") || t.Is("DIV") || t.Is("MOD") || t.Kind == TokenIdent && i+1 < len(tokens) && tokens[i+1].Is("(") {
+					raises = true
+				}
+			}
+		}
+	})
+	return raises
+}
+
+// motion checks the speed and zone of the motion instructions in a block
+func (c *checker) motion(stmts []*Stmt) {
+	for i, s := range stmts {
+		c.motion(s.Body)
+		c.motion(s.Else)
+		for _, cs := range s.Cases {
+			c.motion(cs.Body)
+		}
+		speed, zone, ok := moveSpeedZone(s)
+		if !ok {
+			continue
+		}
+		v, knownSpeed := speedValue(speed)
+		z, knownZone := zoneSize(zone)
+		switch {
+		case strings.EqualFold(zone, "fine") && knownSpeed && v >= fineSpeed:
+			c.warn(s.Line, "%s at %s to a fine point: the robot brakes to a full stop; use a zone unless the point must be reached exactly", s.Name, speed)
+		case knownZone && knownSpeed && z >= largeZone && v <= creepSpeed:
+			c.warn(s.Line, "%s %s %s: a %g mm corner zone at creep speed cuts the approach short; use a small zone or fine", s.Name, speed, zone, z)
+		}
+		if i+1 < len(stmts) && knownZone && stmts[i+1].Kind == StmtCall && outputInstructions[strings.ToUpper(stmts[i+1].Name)] {
+			c.warn(stmts[i+1].Line, "%s after a move with zone %s: the output changes up to %g mm before the robot reaches the point; use fine or a Trigg instruction",
+				stmts[i+1].Name, zone, z)
+		}
+	}
+}
+
+// moveSpeedZone returns the speed and zone of a motion instruction, with
+// the \V and \Z optional arguments that override them
+func moveSpeedZone(s *Stmt) (speed, zone string, ok bool) {
+	at, ok := speedArg[strings.ToUpper(s.Name)]
+	if s.Kind != StmtCall || !ok {
+		return "", "", false
+	}
+	var required []string
+	for _, a := range s.Args {
+		switch {
+		case a.Name == "":
+			required = append(required, tokenText(a.Value))
+		case strings.EqualFold(a.Name, "Z") && len(required) > at:
+			zone = "z" + tokenText(a.Value)
+		}
+	}
+	if len(required) < at+2 {
+		return "", "", false
+	}
+	speed = required[at]
+	if zone == "" {
+		zone = required[at+1]
+	}
+	return speed, zone, true
+}
+
+// zoneSize returns the TCP zone radius of a standard zonedata name
+func zoneSize(name string) (float64, bool) {
+	m := zoneRe.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	return v, err == nil
+}
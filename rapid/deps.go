@@ -0,0 +1,169 @@
+package rapid
+
+import (
+	"sort"
+	"strings"
+)
+
+// Graph records which modules of a task use symbols declared in others
+type Graph struct {
+	Modules []ModuleInfo
+	// Uses maps a module to the modules it depends on and the names involved
+	Uses map[string]map[string][]string
+	// Missing maps a module to names that no module declares
+	Missing map[string][]string
+	// Duplicates maps a name to the modules that all export it
+	Duplicates map[string][]string
+}
+
+// BuildGraph resolves the references of each module against the exported
+// symbols of the others
+func BuildGraph(modules []ModuleInfo) Graph {
+	g := Graph{
+		Modules:    modules,
+		Uses:       make(map[string]map[string][]string),
+		Missing:    make(map[string][]string),
+		Duplicates: make(map[string][]string),
+	}
+	owners := make(map[string][]string)
+	for _, m := range modules {
+		for _, s := range m.Exported() {
+			key := strings.ToUpper(s.Name)
+			owners[key] = append(owners[key], m.Name)
+		}
+	}
+	for name, mods := range owners {
+		if len(mods) > 1 {
+			sort.Strings(mods)
+			g.Duplicates[name] = mods
+		}
+	}
+
+	for _, m := range modules {
+		g.Uses[m.Name] = make(map[string][]string)
+		for _, ref := range m.RefNames() {
+			mods, ok := owners[strings.ToUpper(ref)]
+			if !ok {
+				g.Missing[m.Name] = append(g.Missing[m.Name], ref)
+				continue
+			}
+			for _, owner := range mods {
+				if owner != m.Name {
+					g.Uses[m.Name][owner] = append(g.Uses[m.Name][owner], ref)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// names returns the module names in sorted order
+func (g Graph) names() []string {
+	names := make([]string, 0, len(g.Modules))
+	for _, m := range g.Modules {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g Graph) deps(module string) []string {
+	deps := make([]string, 0, len(g.Uses[module]))
+	for d := range g.Uses[module] {
+		deps = append(deps, d)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// Cycles returns groups of modules that depend on each other, found as the
+// strongly connected components of the graph (Tarjan's algorithm)
+func (g Graph) Cycles() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+	next := 0
+
+	var visit func(string)
+	visit = func(v string) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.deps(v) {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] == index[v] {
+			var group []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				group = append(group, w)
+				if w == v {
+					break
+				}
+			}
+			if len(group) > 1 {
+				sort.Strings(group)
+				cycles = append(cycles, group)
+			}
+		}
+	}
+	for _, name := range g.names() {
+		if _, seen := index[name]; !seen {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+// LoadOrder lists the modules so that each comes after the modules it uses:
+// system modules first, then program modules. Dependencies inside a cycle
+// cannot be ordered and are ignored between its members.
+func (g Graph) LoadOrder() []string {
+	system := make(map[string]bool)
+	for _, m := range g.Modules {
+		system[m.Name] = m.System
+	}
+	cycle := make(map[string]int)
+	for i, c := range g.Cycles() {
+		for _, name := range c {
+			cycle[name] = i + 1
+		}
+	}
+	placed := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var order []string
+
+	var place func(string)
+	place = func(name string) {
+		if placed[name] || visiting[name] {
+			return
+		}
+		visiting[name] = true
+		for _, d := range g.deps(name) {
+			if cycle[d] == 0 || cycle[d] != cycle[name] {
+				place(d)
+			}
+		}
+		visiting[name] = false
+		placed[name] = true
+		order = append(order, name)
+	}
+	for _, wantSystem := range []bool{true, false} {
+		for _, name := range g.names() {
+			if system[name] == wantSystem {
+				place(name)
+			}
+		}
+	}
+	return order
+}
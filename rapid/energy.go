@@ -0,0 +1,230 @@
+package rapid
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Advice is an energy or wear finding with a suggested improvement
+type Advice struct {
+	Line       int
+	Message    string
+	Suggestion string
+	Impact     string
+}
+
+func (a Advice) String() string {
+	return fmt.Sprintf("line %d: %s\n    -> %s\n    impact: %s", a.Line, a.Message, a.Suggestion, a.Impact)
+}
+
+// Thresholds for the energy checks
+const (
+	shortSegment = 200.0   // mm; high speeds cannot be reached on shorter moves
+	highSpeed    = 3000.0  // mm/s
+	typicalAccel = 10000.0 // mm/s², a mid-size robot's path acceleration
+	longWait     = 5.0     // s
+)
+
+// move is a parsed motion instruction
+type move struct {
+	line   int
+	instr  string
+	target string
+	offset [3]float64
+	speed  string
+	zone   string
+}
+
+var (
+	moveInstrRe = regexp.MustCompile(`(?i)^(MoveL|MoveJ|MoveC|MoveAbsJ|MoveLDO|MoveJDO)\s+(.*);`)
+	offsRe      = regexp.MustCompile(`(?i)^Offs\s*\(\s*(\w+)\s*,\s*([-\d.eE+]+)\s*,\s*([-\d.eE+]+)\s*,\s*([-\d.eE+]+)\s*\)$`)
+	waitRe      = regexp.MustCompile(`(?i)^(WaitDI|WaitDO|WaitGI|WaitGO|WaitAI|WaitUntil|WaitSyncTask|WaitTime)\b(.*);`)
+	maxTimeRe   = regexp.MustCompile(`(?i)\\MaxTime\s*:=\s*([\d.]+)`)
+	speedRe     = regexp.MustCompile(`(?i)^v(max|\d+)$`)
+)
+
+// splitArgs splits an argument list at top-level commas and drops optional
+// arguments (\T:=2, \WObj:=wobj1) that are attached to a required one
+func splitArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	inString := false
+	for i, c := range s {
+		switch {
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, s[start:i])
+			start = i + 1
+		}
+	}
+	args = append(args, s[start:])
+	for i, a := range args {
+		if j := strings.Index(a, `\`); j >= 0 {
+			a = a[:j]
+		}
+		args[i] = strings.TrimSpace(a)
+	}
+	return args
+}
+
+func parseMove(line int, code string) (move, bool) {
+	m := moveInstrRe.FindStringSubmatch(code)
+	if m == nil {
+		return move{}, false
+	}
+	args := splitArgs(m[2])
+	mv := move{line: line, instr: m[1]}
+	first := 0
+	if strings.EqualFold(mv.instr, "MoveC") {
+		first = 1
+	}
+	if len(args) < first+3 {
+		return move{}, false
+	}
+	mv.target, mv.speed, mv.zone = args[first], args[first+1], args[first+2]
+	if o := offsRe.FindStringSubmatch(mv.target); o != nil {
+		mv.target = o[1]
+		for i := 0; i < 3; i++ {
+			mv.offset[i], _ = strconv.ParseFloat(o[i+2], 64)
+		}
+	}
+	return mv, true
+}
+
+// speedValue returns the TCP speed of a standard speeddata name
+func speedValue(name string) (float64, bool) {
+	m := speedRe.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	if strings.EqualFold(m[1], "max") {
+		return 7000, true
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	return v, err == nil
+}
+
+// EnergyAdvice flags patterns that waste energy or cause wear: fine points
+// between plain moves, high speeds on short segments and long waits where the
+// robot is held by its servos
+func EnergyAdvice(src string) []Advice {
+	targets := make(map[string]Target)
+	for _, t := range ParseTargets(src) {
+		targets[strings.ToLower(t.Name)] = t
+	}
+	position := func(mv move) ([3]float64, bool) {
+		t, ok := targets[strings.ToLower(mv.target)]
+		if !ok || strings.EqualFold(mv.instr, "MoveAbsJ") {
+			return [3]float64{}, false
+		}
+		return [3]float64{t.Trans[0] + mv.offset[0], t.Trans[1] + mv.offset[1], t.Trans[2] + mv.offset[2]}, true
+	}
+
+	var advice []Advice
+	var prev *move
+	var pendingFine *move
+	stopped := false // The robot stands still after a wait until the next move
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if code == "" {
+			continue
+		}
+		word := firstWord(code)
+
+		if mv, ok := parseMove(line.Number, code); ok {
+			if pendingFine != nil {
+				advice = append(advice, Advice{
+					Line:       pendingFine.line,
+					Message:    fmt.Sprintf("%s to %s stops at a fine point although the next instruction is another move", pendingFine.instr, pendingFine.target),
+					Suggestion: "use a zone (z1-z10) unless the robot must be exactly in position here",
+					Impact:     "saves a stop and restart: typically 0.1-0.3 s per point and the acceleration peaks",
+				})
+			}
+			pendingFine = nil
+			if strings.EqualFold(mv.zone, "fine") {
+				m := mv
+				pendingFine = &m
+			}
+
+			if v, ok := speedValue(mv.speed); ok && v >= highSpeed && prev != nil {
+				from, ok1 := position(*prev)
+				to, ok2 := position(mv)
+				if ok1 && ok2 {
+					dist := math.Sqrt(sq(to[0]-from[0]) + sq(to[1]-from[1]) + sq(to[2]-from[2]))
+					// Distance needed to reach and leave the speed at the typical acceleration
+					needed := v * v / typicalAccel
+					if dist < shortSegment && dist < needed {
+						reached := math.Sqrt(dist * typicalAccel)
+						advice = append(advice, Advice{
+							Line:       mv.line,
+							Message:    fmt.Sprintf("%s on a %.0f mm segment; about %.0f mm are needed to reach %.0f mm/s", mv.speed, dist, needed, v),
+							Suggestion: fmt.Sprintf("program a speed the robot can reach, e.g. v%d", roundSpeed(reached)),
+							Impact:     "same cycle time with lower acceleration peaks, less motor heat and gearbox wear",
+						})
+					}
+				}
+			}
+			m := mv
+			prev = &m
+			stopped = false
+			continue
+		}
+
+		if w := waitRe.FindStringSubmatch(code); w != nil {
+			instr := w[1]
+			long := false
+			what := ""
+			if strings.EqualFold(instr, "WaitTime") {
+				if t, err := strconv.ParseFloat(strings.TrimSpace(strings.Split(strings.TrimSpace(w[2]), `\`)[0]), 64); err == nil && t >= longWait {
+					long, what = true, fmt.Sprintf("WaitTime %g s", t)
+				}
+			} else if mt := maxTimeRe.FindStringSubmatch(code); mt == nil {
+				long, what = true, instr+" without \\MaxTime"
+			} else if t, _ := strconv.ParseFloat(mt[1], 64); t >= longWait {
+				long, what = true, fmt.Sprintf("%s with up to %g s", instr, t)
+			}
+			if long {
+				msg := what + " can keep the robot standing with servos active"
+				suggestion := "wait at a fine point and keep the brake-on time in the motion configuration short, so the brakes engage during the wait"
+				if pendingFine == nil && !stopped && prev != nil && !strings.EqualFold(prev.zone, "fine") {
+					msg = fmt.Sprintf("%s after a zone point (%s to %s): the corner path fails and the robot holds position on its servos", what, prev.instr, prev.target)
+					suggestion = "end the preceding move with fine and keep the brake-on time short, so the brakes engage during the wait"
+				}
+				advice = append(advice, Advice{
+					Line:       line.Number,
+					Message:    msg,
+					Suggestion: suggestion,
+					Impact:     "a mid-size robot draws roughly 0.3-0.6 kW holding position on its servos",
+				})
+			}
+			stopped = true
+		}
+
+		// Any other instruction (I/O, process calls) justifies a fine point
+		if word != "" {
+			pendingFine = nil
+		}
+	}
+	return advice
+}
+
+func sq(v float64) float64 { return v * v }
+
+// roundSpeed rounds a speed down to a standard speeddata value
+func roundSpeed(v float64) int {
+	for _, s := range []int{2500, 2000, 1500, 1000, 800, 600, 500, 400, 300, 200, 150, 100} {
+		if v >= float64(s) {
+			return s
+		}
+	}
+	return 50
+}
@@ -0,0 +1,88 @@
+package rapid
+
+import (
+	"regexp"
+	"strings"
+)
+
+var nonIdentRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// ExampleModuleName turns a command key such as move_l into a module name
+// within the 32 characters RAPID allows
+func ExampleModuleName(key string) string {
+	name := "Ex_" + nonIdentRe.ReplaceAllString(key, "_")
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// WrapExample places a reference example in a complete module: data
+// declarations and RECORD definitions go to module level, TRAP routines
+// after the routine, an ERROR handler at the end of the routine and all
+// other lines into the routine body
+func WrapExample(module, routine, comment, code string) string {
+	var decls, body, handler, traps []string
+	dest := &body
+	block := ""
+	for _, line := range rawLines(code) {
+		text, _ := splitComment(line)
+		word := firstWord(text)
+		if word == "LOCAL" || word == "TASK" {
+			word = firstWord(strings.TrimSpace(strings.TrimSpace(text)[len(word):]))
+		}
+		switch {
+		case block != "":
+			// Inside a TRAP or RECORD until its end
+		case word == "TRAP":
+			block, dest = "ENDTRAP", &traps
+		case word == "RECORD":
+			block, dest = "ENDRECORD", &decls
+		case word == "ERROR":
+			dest = &handler
+		case word == "CONST" || word == "PERS" || word == "VAR":
+			if dest == &body {
+				decls = append(decls, line)
+				continue
+			}
+		}
+		*dest = append(*dest, line)
+		if block != "" && word == block {
+			block, dest = "", &body
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("MODULE " + module + "\n")
+	if comment != "" {
+		b.WriteString("    ! " + comment + "\n")
+	}
+	for _, line := range decls {
+		b.WriteString(indent(line, 1))
+	}
+	b.WriteString("\n    PROC " + routine + "()\n")
+	for _, line := range body {
+		b.WriteString(indent(line, 2))
+	}
+	for _, line := range handler {
+		// The example indents the handler body relative to ERROR
+		b.WriteString(indent(line, 1))
+	}
+	b.WriteString("    ENDPROC\n")
+	if len(traps) > 0 {
+		b.WriteString("\n")
+	}
+	for _, line := range traps {
+		b.WriteString(indent(line, 1))
+	}
+	b.WriteString("ENDMODULE\n")
+	return Normalize(b.String())
+}
+
+// indent prefixes a non-empty line with four spaces per level
+func indent(line string, level int) string {
+	if strings.TrimSpace(line) == "" {
+		return "\n"
+	}
+	return strings.Repeat("    ", level) + line + "\n"
+}
@@ -0,0 +1,222 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Param is a parameter of an extracted routine
+type Param struct {
+	Mode  string // "" (IN), INOUT, VAR or PERS
+	Type  string
+	Name  string
+	Array bool
+}
+
+func (p Param) String() string {
+	s := p.Type + " " + p.Name
+	if p.Array {
+		s += "{*}"
+	}
+	if p.Mode != "" {
+		s = p.Mode + " " + s
+	}
+	return s
+}
+
+var (
+	paramRe = regexp.MustCompile(`(?i)^(INOUT\s+|VAR\s+|PERS\s+)?(\w+)\s+(\w+)(\{[^}]*\})?$`)
+	// modifyingInstructions change their first argument
+	modifyingInstructions = map[string]bool{"INCR": true, "DECR": true, "ADD": true, "CLEAR": true}
+	// branchWords continue a block opened outside a selection
+	branchWords = map[string]bool{"ELSE": true, "ELSEIF": true, "CASE": true, "DEFAULT": true}
+)
+
+// ExtractProc moves lines from..to (1-based, inclusive) of a routine into a
+// new PROC called name and replaces them with a call. Data local to the
+// enclosing routine that the lines use becomes parameters, passed INOUT when
+// the lines change it. It returns the new source and the parameters.
+func ExtractProc(src string, from, to int, name string) (string, []Param, error) {
+	lines := rawLines(src)
+	if from < 1 || to < from || to > len(lines) {
+		return "", nil, fmt.Errorf("line range %d-%d is outside the file (1-%d)", from, to, len(lines))
+	}
+	info := AnalyzeModule(src)
+	for _, s := range info.Symbols {
+		if strings.EqualFold(s.Name, name) {
+			return "", nil, fmt.Errorf("%s is already declared on line %d", name, s.Line)
+		}
+	}
+
+	var outer *Routine
+	for _, r := range Routines(src) {
+		if r.Line < from && r.EndLine > to {
+			outer = &r
+			break
+		}
+	}
+	if outer == nil {
+		return "", nil, fmt.Errorf("lines %d-%d are not inside a single routine body", from, to)
+	}
+
+	locals, order := routineLocals(lines, *outer)
+	if err := checkSelection(lines, from, to); err != nil {
+		return "", nil, err
+	}
+
+	used := make(map[string]usage)
+	for n := from; n <= to; n++ {
+		code, _ := splitComment(lines[n-1])
+		recordUses(used, code, n)
+	}
+	var params []Param
+	for _, key := range order {
+		if _, ok := used[key]; !ok {
+			continue
+		}
+		p := locals[key]
+		if p.Mode == "" && modifiedIn(lines[from-1:to], p.Name) {
+			p.Mode = "INOUT"
+		}
+		params = append(params, p)
+	}
+
+	indent := leadingSpace(lines[from-1])
+	call := indent + name
+	if len(params) > 0 {
+		names := make([]string, len(params))
+		for i, p := range params {
+			names[i] = p.Name
+		}
+		call += " " + strings.Join(names, ", ")
+	}
+	call += ";"
+
+	// Re-indent the body to routine level, keeping relative indentation
+	body := make([]string, 0, to-from+1)
+	for _, l := range lines[from-1 : to] {
+		if strings.TrimSpace(l) == "" {
+			body = append(body, "")
+			continue
+		}
+		body = append(body, "        "+strings.TrimPrefix(strings.ReplaceAll(l, "\t", "    "), indent))
+	}
+	decl := make([]string, len(params))
+	for i, p := range params {
+		decl[i] = p.String()
+	}
+	routine := append([]string{"", fmt.Sprintf("    PROC %s(%s)", name, strings.Join(decl, ", "))}, body...)
+	routine = append(routine, "    ENDPROC")
+
+	var out []string
+	out = append(out, lines[:from-1]...)
+	out = append(out, call)
+	out = append(out, lines[to:outer.EndLine]...)
+	out = append(out, routine...)
+	out = append(out, lines[outer.EndLine:]...)
+	return strings.Join(out, "\n"), params, nil
+}
+
+// routineLocals returns the parameters and local data of a routine keyed by
+// upper-cased name, plus the keys in declaration order
+func routineLocals(lines []string, r Routine) (map[string]Param, []string) {
+	locals := make(map[string]Param)
+	var order []string
+	add := func(p Param) {
+		key := strings.ToUpper(p.Name)
+		if _, exists := locals[key]; !exists {
+			order = append(order, key)
+		}
+		locals[key] = p
+	}
+
+	header, _ := splitComment(lines[r.Line-1])
+	if open := strings.Index(header, "("); open >= 0 {
+		list := strings.NewReplacer("\\", ",", "|", ",").Replace(strings.Trim(header[open:], "() "))
+		for _, param := range strings.Split(list, ",") {
+			if m := paramRe.FindStringSubmatch(strings.TrimSpace(param)); m != nil {
+				mode := strings.ToUpper(strings.TrimSpace(m[1]))
+				add(Param{Mode: mode, Type: m[2], Name: m[3], Array: m[4] != ""})
+			}
+		}
+	}
+	for n := r.Line + 1; n < r.EndLine; n++ {
+		code, _ := splitComment(lines[n-1])
+		if m := dataDeclRe.FindStringSubmatch(strings.TrimSpace(code)); m != nil {
+			rest := strings.TrimSpace(code)[len(m[0]):]
+			p := Param{Type: m[3], Name: m[4], Array: strings.HasPrefix(strings.TrimSpace(rest), "{")}
+			if strings.EqualFold(m[2], "PERS") {
+				p.Mode = "PERS"
+			}
+			add(p)
+		}
+	}
+	return locals, order
+}
+
+// checkSelection rejects ranges that would break the program when moved:
+// declarations, unbalanced blocks, branches of outer blocks and jumps
+func checkSelection(lines []string, from, to int) error {
+	depth := 0
+	for n := from; n <= to; n++ {
+		code, _ := splitComment(lines[n-1])
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		word := firstWord(code)
+		switch {
+		case dataDeclRe.MatchString(code):
+			return fmt.Errorf("line %d declares data; select only instructions", n)
+		case word == "RETURN" || word == "GOTO" || word == "RETRY" || word == "TRYNEXT":
+			return fmt.Errorf("line %d uses %s, which would behave differently in a new routine", n, word)
+		case word == "ERROR" || word == "UNDO" || word == "BACKWARD":
+			return fmt.Errorf("line %d starts the %s handler; select instructions before it", n, word)
+		case strings.HasSuffix(code, ":") && identRe.MatchString(strings.TrimSuffix(code, ":")):
+			return fmt.Errorf("line %d is a label; jumps cannot cross routines", n)
+		case branchWords[word] && depth == 0:
+			return fmt.Errorf("line %d continues a block opened before the selection", n)
+		}
+		if end, ok := blockEnds[word]; ok && end != "ENDMODULE" {
+			if word == "IF" && !strings.Contains(strings.ToUpper(code), "THEN") {
+				continue
+			}
+			depth++
+			continue
+		}
+		for _, end := range blockEnds {
+			if word == end {
+				depth--
+			}
+		}
+		if depth < 0 {
+			return fmt.Errorf("line %d closes a block opened before the selection", n)
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("the selection leaves %d block(s) open", depth)
+	}
+	return nil
+}
+
+// modifiedIn reports whether the lines assign to name or pass it to an
+// instruction that changes its first argument
+func modifiedIn(lines []string, name string) bool {
+	assign := regexp.MustCompile(`(?i)(^|[^\w.])` + regexp.QuoteMeta(name) + `(\{[^}]*\})?(\.\w+)*\s*:=`)
+	for _, l := range lines {
+		code, _ := splitComment(l)
+		if assign.MatchString(code) {
+			return true
+		}
+		fields := strings.FieldsFunc(code, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' || r == ';' })
+		if len(fields) > 1 && modifyingInstructions[strings.ToUpper(fields[0])] && strings.EqualFold(strings.Split(fields[1], "{")[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
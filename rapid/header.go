@@ -0,0 +1,206 @@
+package rapid
+
+import (
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// DefaultHeaderTemplate renders the standard module header. Custom templates
+// receive a Header and must keep the "Key: value" field lines, the revision
+// lines ("<rev> <date> <author> <text>") and the "Checksum:" line so the
+// header can be read back when revisions are added.
+const DefaultHeaderTemplate = `    !***********************************************************
+    ! Module:   {{.Module}}
+    ! Author:   {{.Author}}
+    ! Cell:     {{.Cell}}
+    ! Created:  {{.Created}}
+    !
+    ! Revision history:
+{{- range .Revisions}}
+    !   {{.Number}}  {{.Date}}  {{.Author}}  {{.Text}}
+{{- end}}
+    !
+    ! Checksum: {{.Checksum}}
+    !***********************************************************
+`
+
+// Revision is one entry in a module's revision history
+type Revision struct {
+	Number int
+	Date   string // YYYY-MM-DD
+	Author string
+	Text   string
+}
+
+// Header is the documentation block placed directly after the MODULE line
+type Header struct {
+	Module    string
+	Author    string
+	Cell      string
+	Created   string
+	Revisions []Revision
+	Checksum  string
+}
+
+var (
+	moduleRe   = regexp.MustCompile(`(?i)^\s*MODULE\s+(\w+)`)
+	revisionRe = regexp.MustCompile(`^\s*(\d+)\s+(\d{4}-\d{2}-\d{2})\s+(\S+)\s+(.*)$`)
+	fieldRe    = regexp.MustCompile(`^\s*(\w+):\s*(.*)$`)
+)
+
+// ModuleName returns the name declared on the MODULE line
+func ModuleName(src string) (string, bool) {
+	for _, line := range SplitLines(src) {
+		if m := moduleRe.FindStringSubmatch(line.Code); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// rawLines splits source into lines without separating comments
+func rawLines(src string) []string {
+	return strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+}
+
+// headerSpan finds the MODULE line and the header that follows it. A header
+// is a run of comment-only lines after MODULE containing a "Checksum:" line;
+// it ends after that line and any purely decorative lines such as "!*****".
+// Without a header, end is the line after MODULE.
+func headerSpan(lines []string) (module, end int, ok bool) {
+	for i, line := range lines {
+		code, _ := splitComment(line)
+		if !moduleRe.MatchString(code) {
+			continue
+		}
+		end = i + 1
+		for j := i + 1; j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), "!"); j++ {
+			_, comment := splitComment(lines[j])
+			if m := fieldRe.FindStringSubmatch(comment); m != nil && strings.EqualFold(m[1], "checksum") {
+				end = j + 1
+				for end < len(lines) && isDecoration(lines[end]) {
+					end++
+				}
+				break
+			}
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// isDecoration reports whether a line is a comment without any text
+func isDecoration(line string) bool {
+	text := strings.TrimSpace(line)
+	if !strings.HasPrefix(text, "!") {
+		return false
+	}
+	return !strings.ContainsFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+}
+
+// ParseHeader reads the header of a module. It reports false if the module
+// has no header block.
+func ParseHeader(src string) (Header, bool) {
+	lines := SplitLines(src)
+	module, end, ok := headerSpan(rawLines(src))
+	if !ok || end == module+1 {
+		return Header{}, false
+	}
+
+	h := Header{Module: moduleRe.FindStringSubmatch(lines[module].Code)[1]}
+	for _, line := range lines[module+1 : end] {
+		if m := revisionRe.FindStringSubmatch(line.Comment); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			h.Revisions = append(h.Revisions, Revision{Number: n, Date: m[2], Author: m[3], Text: strings.TrimSpace(m[4])})
+			continue
+		}
+		if m := fieldRe.FindStringSubmatch(line.Comment); m != nil {
+			switch strings.ToLower(m[1]) {
+			case "author":
+				h.Author = m[2]
+			case "cell":
+				h.Cell = m[2]
+			case "created":
+				h.Created = m[2]
+			case "checksum":
+				h.Checksum = m[2]
+			}
+		}
+	}
+	return h, true
+}
+
+// Checksum returns a CRC-32 of the module code outside the header, ignoring
+// indentation and blank lines, so it changes only when the program does
+func Checksum(src string) string {
+	lines := rawLines(src)
+	module, end, ok := headerSpan(lines)
+	crc := crc32.NewIEEE()
+	for i, line := range lines {
+		if ok && i > module && i < end {
+			continue
+		}
+		if text := strings.TrimSpace(line); text != "" {
+			crc.Write([]byte(text + "\n"))
+		}
+	}
+	return fmt.Sprintf("%08x", crc.Sum32())
+}
+
+// BumpRevision appends a revision after the highest existing number
+func (h *Header) BumpRevision(date, author, text string) Revision {
+	next := 1
+	for _, r := range h.Revisions {
+		if r.Number >= next {
+			next = r.Number + 1
+		}
+	}
+	rev := Revision{Number: next, Date: date, Author: author, Text: text}
+	h.Revisions = append(h.Revisions, rev)
+	return rev
+}
+
+// ApplyHeader renders the header with tmpl and places it after the MODULE
+// line, replacing any existing header. The checksum is recomputed.
+func ApplyHeader(src string, h Header, tmpl *template.Template) (string, error) {
+	lines := rawLines(src)
+	module, end, ok := headerSpan(lines)
+	if !ok {
+		return "", fmt.Errorf("no MODULE declaration found")
+	}
+	if h.Module == "" {
+		code, _ := splitComment(lines[module])
+		h.Module = moduleRe.FindStringSubmatch(code)[1]
+	}
+	h.Checksum = Checksum(src)
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, h); err != nil {
+		return "", err
+	}
+	header := strings.Split(strings.TrimRight(rendered.String(), "\n"), "\n")
+
+	out := make([]string, 0, len(lines)+len(header))
+	out = append(out, lines[:module+1]...)
+	out = append(out, header...)
+	out = append(out, lines[end:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+// RenameModule changes the name on the MODULE line
+func RenameModule(src, name string) (string, error) {
+	lines := rawLines(src)
+	module, _, ok := headerSpan(lines)
+	if !ok {
+		return "", fmt.Errorf("no MODULE declaration found")
+	}
+	code, _ := splitComment(lines[module])
+	m := moduleRe.FindStringSubmatchIndex(code)
+	lines[module] = lines[module][:m[2]] + name + lines[module][m[3]:]
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,122 @@
+package rapid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity levels for lint findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single lint finding in a RAPID module
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s: %s", d.Line, d.Severity, d.Message)
+}
+
+// blockEnds maps each block-opening keyword to its closing keyword
+var blockEnds = map[string]string{
+	"MODULE": "ENDMODULE",
+	"PROC":   "ENDPROC",
+	"FUNC":   "ENDFUNC",
+	"TRAP":   "ENDTRAP",
+	"RECORD": "ENDRECORD",
+	"IF":     "ENDIF",
+	"WHILE":  "ENDWHILE",
+	"FOR":    "ENDFOR",
+	"TEST":   "ENDTEST",
+}
+
+type openBlock struct {
+	keyword string
+	line    int
+}
+
+// Lint checks a RAPID module for structural problems such as unbalanced
+// blocks. A well-formed module is also parsed and checked for undeclared
+// names, unreachable code and suspicious zone and speed combinations.
+func Lint(src string) []Diagnostic {
+	return LintTask(src, nil)
+}
+
+// LintTask is Lint for a module of a task: globals holds the upper-cased
+// names the other modules declare, which are not reported as undeclared
+func LintTask(src string, globals map[string]bool) []Diagnostic {
+	if diags := lintBlocks(src); len(diags) > 0 {
+		return diags
+	}
+	m, diags := Parse(src)
+	if len(diags) > 0 {
+		return diags
+	}
+	return checkModule(m, globals)
+}
+
+// lintBlocks reports unbalanced blocks
+func lintBlocks(src string) []Diagnostic {
+	var diags []Diagnostic
+	var stack []openBlock
+
+	closers := make(map[string]string, len(blockEnds))
+	for open, end := range blockEnds {
+		closers[end] = open
+	}
+
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if code == "" {
+			continue
+		}
+		word := firstWord(code)
+		if word == "LOCAL" || word == "TASK" {
+			word = firstWord(strings.TrimSpace(code[len(word):]))
+		}
+
+		if _, ok := blockEnds[word]; ok {
+			// A compact IF without THEN is a single-line statement
+			if word == "IF" && !strings.Contains(strings.ToUpper(code), "THEN") {
+				continue
+			}
+			stack = append(stack, openBlock{keyword: word, line: line.Number})
+			continue
+		}
+
+		open, ok := closers[word]
+		if !ok {
+			continue
+		}
+		if len(stack) == 0 {
+			diags = append(diags, Diagnostic{line.Number, SeverityError,
+				fmt.Sprintf("%s without matching %s", word, open)})
+			continue
+		}
+		top := stack[len(stack)-1]
+		if top.keyword == open {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		diags = append(diags, Diagnostic{line.Number, SeverityError,
+			fmt.Sprintf("%s found but %s opened on line %d is still open", word, top.keyword, top.line)})
+		// Recover by unwinding to the matching opener if there is one
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].keyword == open {
+				stack = stack[:i]
+				break
+			}
+		}
+	}
+
+	for _, b := range stack {
+		diags = append(diags, Diagnostic{b.line, SeverityError,
+			fmt.Sprintf("%s is missing %s", b.keyword, blockEnds[b.keyword])})
+	}
+	return diags
+}
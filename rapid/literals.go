@@ -0,0 +1,207 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NumberUse is one numeric literal in the code
+type NumberUse struct {
+	Line   int
+	Column int // Byte offset of the literal in the line
+	Value  string
+	Kind   string // Suggested constant name prefix derived from the context
+}
+
+// Constant is a repeated literal proposed as a named constant
+type Constant struct {
+	Name  string
+	Value string
+	Uses  []NumberUse
+}
+
+var (
+	numberRe   = regexp.MustCompile(`\d+(?:\.\d+)?(?:[eE][+-]?\d+)?`)
+	namedArgRe = regexp.MustCompile(`\\(\w+)\s*:=\s*-?$`)
+)
+
+// trivialNumbers are too common to be worth naming
+var trivialNumbers = map[string]bool{"0": true, "1": true}
+
+// offsetArgs names the x, y and z arguments of offset functions
+var offsetArgs = map[string][]string{
+	"OFFS":    {"", "nOffsX", "nOffsY", "nOffsZ"},
+	"RELTOOL": {"", "nToolX", "nToolY", "nToolZ", "nToolRx", "nToolRy", "nToolRz"},
+}
+
+// NumberUses lists the numeric literals in executable code. Declarations are
+// skipped since their values already have a name.
+func NumberUses(src string) []NumberUse {
+	var uses []NumberUse
+	for i, raw := range rawLines(src) {
+		code, _ := splitComment(raw)
+		trimmed := strings.TrimSpace(code)
+		if trimmed == "" || dataDeclRe.MatchString(trimmed) || routineDecl.MatchString(trimmed) || moduleRe.MatchString(trimmed) {
+			continue
+		}
+		// Blank out strings so digits inside them are ignored
+		masked := stringRe.ReplaceAllStringFunc(code, func(s string) string { return strings.Repeat(" ", len(s)) })
+		for _, loc := range numberRe.FindAllStringIndex(masked, -1) {
+			if loc[0] > 0 {
+				prev := masked[loc[0]-1]
+				if prev == '_' || prev == '.' || prev == '{' || isIdentChar(prev) {
+					continue
+				}
+			}
+			value := masked[loc[0]:loc[1]]
+			if trivialNumbers[value] {
+				continue
+			}
+			uses = append(uses, NumberUse{Line: i + 1, Column: loc[0], Value: value, Kind: literalKind(masked, loc[0])})
+		}
+	}
+	return uses
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// literalKind suggests a name prefix from the literal's surroundings: a named
+// argument, the argument of an offset function or the instruction
+func literalKind(code string, at int) string {
+	before := code[:at]
+	if m := namedArgRe.FindStringSubmatch(before); m != nil {
+		return "n" + m[1]
+	}
+
+	// Find the innermost open call and the argument position
+	depth, arg := 0, 0
+	for i := len(before) - 1; i >= 0; i-- {
+		switch before[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			name := strings.ToUpper(lastIdent(before[:i]))
+			if names, ok := offsetArgs[name]; ok && arg < len(names) && names[arg] != "" {
+				return names[arg]
+			}
+			return "nValue"
+		case ',':
+			if depth == 0 {
+				arg++
+			}
+		}
+	}
+	switch firstWord(code) {
+	case "WAITTIME":
+		return "nWait"
+	case "SETAO":
+		return "nAnalog"
+	case "SETGO":
+		return "nGroup"
+	}
+	return "nValue"
+}
+
+func lastIdent(s string) string {
+	s = strings.TrimRight(s, " \t")
+	end := len(s)
+	start := end
+	for start > 0 && isIdentChar(s[start-1]) {
+		start--
+	}
+	return s[start:end]
+}
+
+// MagicNumbers proposes constants for literals used at least minUses times
+// in the same kind of context, so an offset of 100 and an analog value of 100
+// are not merged. Names are built from the context and the value and do not
+// clash with names already declared in the module.
+func MagicNumbers(src string, minUses int) []Constant {
+	groups := make(map[string][]NumberUse)
+	for _, u := range NumberUses(src) {
+		key := u.Kind + "\x00" + u.Value
+		groups[key] = append(groups[key], u)
+	}
+
+	taken := make(map[string]bool)
+	for _, s := range AnalyzeModule(src).Symbols {
+		taken[strings.ToUpper(s.Name)] = true
+	}
+
+	var consts []Constant
+	for _, key := range sortedKeysOf(groups) {
+		uses := groups[key]
+		if len(uses) < minUses {
+			continue
+		}
+		kind, value := uses[0].Kind, uses[0].Value
+		base := kind + strings.NewReplacer(".", "_", "+", "", "-", "_").Replace(value)
+		name := base
+		for n := 2; taken[strings.ToUpper(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		taken[strings.ToUpper(name)] = true
+		consts = append(consts, Constant{Name: name, Value: value, Uses: uses})
+	}
+	sort.SliceStable(consts, func(i, j int) bool { return len(consts[i].Uses) > len(consts[j].Uses) })
+	return consts
+}
+
+func sortedKeysOf(m map[string][]NumberUse) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ApplyConstants declares the constants after the module header and
+// replaces their uses
+func ApplyConstants(src string, consts []Constant) (string, error) {
+	lines := rawLines(src)
+	_, end, ok := headerSpan(lines)
+	if !ok {
+		return "", fmt.Errorf("no MODULE declaration found")
+	}
+
+	// Replace from the right so earlier columns stay valid
+	type edit struct {
+		use  NumberUse
+		name string
+	}
+	var edits []edit
+	for _, c := range consts {
+		for _, u := range c.Uses {
+			edits = append(edits, edit{u, c.Name})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].use.Line != edits[j].use.Line {
+			return edits[i].use.Line < edits[j].use.Line
+		}
+		return edits[i].use.Column > edits[j].use.Column
+	})
+	for _, e := range edits {
+		line := lines[e.use.Line-1]
+		lines[e.use.Line-1] = line[:e.use.Column] + e.name + line[e.use.Column+len(e.use.Value):]
+	}
+
+	indent := "    "
+	decls := make([]string, 0, len(consts))
+	for _, c := range consts {
+		decls = append(decls, fmt.Sprintf("%sCONST num %s := %s;", indent, c.Name, c.Value))
+	}
+	out := append([]string{}, lines[:end]...)
+	out = append(out, decls...)
+	out = append(out, lines[end:]...)
+	return strings.Join(out, "\n"), nil
+}
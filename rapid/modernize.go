@@ -0,0 +1,148 @@
+package rapid
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ModernizeRule rewrites one legacy construct on a code line. Rewrite gets
+// the code without its comment and whether the line is in an ERROR handler,
+// and returns the new code and whether it changed.
+type ModernizeRule struct {
+	ID          string
+	Description string
+	Rewrite     func(code string, inError bool) (string, bool)
+}
+
+// Rewrite is one line changed by a rule
+type Rewrite struct {
+	Line   int
+	Rule   string
+	Before string
+	After  string
+}
+
+var (
+	compareBoolRe = regexp.MustCompile(`(?i)^(\s*(?:IF|ELSEIF|WHILE)\s+)(\w+)\s*=\s*(TRUE|FALSE)(\s+(?:THEN|DO)\b.*)$`)
+	incrRe        = regexp.MustCompile(`^(\s*)(\w+)\s*:=\s*(\w+)\s*([+-])\s*1\s*;\s*$`)
+	wobj0Re       = regexp.MustCompile(`(?i)\s*\\WObj\s*:=\s*wobj0\b`)
+	legacyDiskRe  = regexp.MustCompile(`(?i)"(flp1|ram1disk):`)
+	spyRe         = regexp.MustCompile(`(?i)^(\s*)(Spy(?:Start|Stop)\b.*)$`)
+	errTPWriteRe  = regexp.MustCompile(`(?i)^(\s*)TPWrite\s+("(?:[^"]|"")*")\s*;\s*$`)
+)
+
+// errWriteHeaderLimit is the longest header ErrWrite accepts
+const errWriteHeaderLimit = 46
+
+// ModernizeRules are the rewrites abb modernize applies, in order
+var ModernizeRules = []ModernizeRule{
+	{
+		ID:          "bool-compare",
+		Description: "IF bFlag = TRUE THEN becomes IF bFlag THEN, = FALSE becomes NOT",
+		Rewrite: func(code string, _ bool) (string, bool) {
+			m := compareBoolRe.FindStringSubmatch(code)
+			if m == nil {
+				return code, false
+			}
+			if strings.EqualFold(m[3], "TRUE") {
+				return m[1] + m[2] + m[4], true
+			}
+			return m[1] + "NOT " + m[2] + m[4], true
+		},
+	},
+	{
+		ID:          "incr",
+		Description: "n := n + 1; becomes Incr n; (and Decr for - 1)",
+		Rewrite: func(code string, _ bool) (string, bool) {
+			m := incrRe.FindStringSubmatch(code)
+			if m == nil || !strings.EqualFold(m[2], m[3]) {
+				return code, false
+			}
+			if m[4] == "+" {
+				return m[1] + "Incr " + m[2] + ";", true
+			}
+			return m[1] + "Decr " + m[2] + ";", true
+		},
+	},
+	{
+		ID:          "wobj0",
+		Description: "Drop \\WObj:=wobj0, which is the default",
+		Rewrite: func(code string, _ bool) (string, bool) {
+			out := wobj0Re.ReplaceAllString(code, "")
+			return out, out != code
+		},
+	},
+	{
+		ID:          "disk",
+		Description: "S4-era file devices flp1: and ram1disk: become HOME: and RAMDISK:",
+		Rewrite: func(code string, _ bool) (string, bool) {
+			out := legacyDiskRe.ReplaceAllStringFunc(code, func(s string) string {
+				if strings.EqualFold(s, `"flp1:`) {
+					return `"HOME:`
+				}
+				return `"RAMDISK:`
+			})
+			return out, out != code
+		},
+	},
+	{
+		ID:          "spy",
+		Description: "Comment out SpyStart/SpyStop, deprecated since RobotWare 6",
+		Rewrite: func(code string, _ bool) (string, bool) {
+			m := spyRe.FindStringSubmatch(code)
+			if m == nil {
+				return code, false
+			}
+			return m[1] + "! " + strings.TrimRight(m[2], " ") + " (deprecated, use the RAPID Profiler)", true
+		},
+	},
+	{
+		ID:          "error-log",
+		Description: "TPWrite in an ERROR handler becomes ErrWrite, so the error reaches the event log",
+		Rewrite: func(code string, inError bool) (string, bool) {
+			m := errTPWriteRe.FindStringSubmatch(code)
+			if !inError || m == nil || len(m[2])-2 > errWriteHeaderLimit {
+				return code, false
+			}
+			return m[1] + "ErrWrite " + m[2] + ", \"\";", true
+		},
+	},
+}
+
+// Modernize applies the rules whose IDs are enabled (all when enabled is
+// nil) and returns the new source with the changed lines
+func Modernize(src string, enabled map[string]bool) (string, []Rewrite) {
+	lines := rawLines(src)
+	var rewrites []Rewrite
+	inError := false
+	for i, line := range lines {
+		code, _ := splitComment(line)
+		switch firstWord(code) {
+		case "ERROR":
+			inError = true
+		case "PROC", "FUNC", "TRAP", "LOCAL", "ENDPROC", "ENDFUNC", "ENDTRAP", "UNDO":
+			inError = false
+		}
+		comment := line[len(code):]
+		changed := code
+		var applied []string
+		for _, rule := range ModernizeRules {
+			if enabled != nil && !enabled[rule.ID] {
+				continue
+			}
+			if out, ok := rule.Rewrite(changed, inError); ok {
+				changed = out
+				applied = append(applied, rule.ID)
+			}
+		}
+		if len(applied) == 0 {
+			continue
+		}
+		if comment != "" && !strings.HasSuffix(changed, " ") {
+			comment = " " + strings.TrimLeft(comment, " ")
+		}
+		lines[i] = changed + comment
+		rewrites = append(rewrites, Rewrite{Line: i + 1, Rule: strings.Join(applied, ","), Before: line, After: lines[i]})
+	}
+	return strings.Join(lines, "\n"), rewrites
+}
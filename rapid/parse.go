@@ -0,0 +1,727 @@
+package rapid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Module is a parsed RAPID module
+type Module struct {
+	Name     string
+	Line     int
+	Attrs    []string // SYSMODULE, NOVIEW, ...
+	Types    []string // RECORD and ALIAS names
+	Data     []*DataDecl
+	Routines []*RoutineDecl
+}
+
+// DataDecl declares a variable, persistent or constant
+type DataDecl struct {
+	Storage string // VAR, PERS or CONST
+	Type    string
+	Name    string
+	Local   bool
+	Init    []Token // the value after :=, if any
+	Line    int
+}
+
+// RoutineDecl is a PROC, FUNC or TRAP
+type RoutineDecl struct {
+	Kind     string // PROC, FUNC or TRAP
+	Name     string
+	Type     string // return type of a FUNC
+	Local    bool
+	Params   []Parameter
+	Data     []*DataDecl // routine data
+	Body     []*Stmt
+	Backward []*Stmt
+	Error    *Handler // nil without an ERROR handler
+	Undo     []*Stmt
+	Line     int
+	End      int
+}
+
+// Parameter is a routine parameter
+type Parameter struct {
+	Name     string
+	Type     string
+	Access   string // VAR, PERS, INOUT or empty
+	Optional bool
+}
+
+// Handler is the ERROR handler of a routine
+type Handler struct {
+	Line   int
+	Errors []Token // the error numbers of ERROR (...), if listed
+	Body   []*Stmt
+}
+
+// Statement kinds
+const (
+	StmtCall        = "call"
+	StmtAssign      = ":="
+	StmtIf          = "IF"
+	StmtWhile       = "WHILE"
+	StmtFor         = "FOR"
+	StmtTest        = "TEST"
+	StmtReturn      = "RETURN"
+	StmtRaise       = "RAISE"
+	StmtRetry       = "RETRY"
+	StmtTryNext     = "TRYNEXT"
+	StmtExit        = "EXIT"
+	StmtGoto        = "GOTO"
+	StmtConnect     = "CONNECT"
+	StmtLabel       = "label"
+	StmtPlaceholder = "placeholder"
+)
+
+// Stmt is a statement of a routine
+type Stmt struct {
+	Kind string
+	Line int
+	// Name is the called routine, the assigned variable, the FOR loop
+	// variable, the label or the GOTO target. Late-bound calls have no name.
+	Name   string
+	Target []Token // the assigned data, e.g. reg1 or p.trans.x
+	Expr   []Token // condition, assigned value, test value, FOR range, RETURN or RAISE value
+	Args   []Arg
+	Body   []*Stmt
+	Else   []*Stmt // an ELSEIF is an IF alone in Else
+	Cases  []Case  // of a TEST
+}
+
+// Arg is an argument of a call
+type Arg struct {
+	Name  string  // of an optional argument, without the backslash; empty for required ones
+	Value []Token // empty for a switch such as \Conc
+	Line  int
+}
+
+// Case is a CASE or the DEFAULT of a TEST
+type Case struct {
+	Line   int
+	Values [][]Token // nil for DEFAULT
+	Body   []*Stmt
+}
+
+// Parse parses a RAPID module. Syntax errors are returned as diagnostics;
+// the module holds what could be parsed.
+func Parse(src string) (*Module, []Diagnostic) {
+	p := &parser{tokens: Tokenize(src)}
+	m := p.module()
+	return m, p.diags
+}
+
+type parser struct {
+	tokens []Token
+	pos    int
+	diags  []Diagnostic
+}
+
+func (p *parser) peek() Token { return p.tokens[p.pos] }
+
+// peekAt returns the token n places ahead
+func (p *parser) peekAt(n int) Token {
+	if p.pos+n < len(p.tokens) {
+		return p.tokens[p.pos+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() Token {
+	t := p.tokens[p.pos]
+	if t.Kind != TokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is s
+func (p *parser) accept(s string) bool {
+	if p.peek().Is(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes s or reports that it is missing
+func (p *parser) expect(s string) bool {
+	if p.accept(s) {
+		return true
+	}
+	p.errorf("expected %s, found %s", s, describe(p.peek()))
+	return false
+}
+
+// ident consumes an identifier, reporting what it is for when there is none
+func (p *parser) ident(what string) string {
+	if t := p.peek(); t.Kind == TokenIdent {
+		p.next()
+		return t.Text
+	}
+	p.errorf("expected %s, found %s", what, describe(p.peek()))
+	return ""
+}
+
+func (p *parser) errorf(format string, args ...any) {
+	line := p.peek().Line
+	// One error per line is enough; the rest usually follow from it
+	if n := len(p.diags); n > 0 && p.diags[n-1].Line == line {
+		return
+	}
+	p.diags = append(p.diags, Diagnostic{line, SeverityError, fmt.Sprintf(format, args...)})
+}
+
+func describe(t Token) string {
+	if t.Kind == TokenEOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// skip resynchronizes after an error: it consumes up to the end of the
+// statement, or stops before a keyword that ends a block
+func (p *parser) skip() {
+	for {
+		t := p.peek()
+		if t.Kind == TokenEOF || t.Kind == TokenIdent && blockWord(t.Text) {
+			return
+		}
+		p.next()
+		if t.Is(";") {
+			return
+		}
+	}
+}
+
+// blockWord reports whether word continues or ends a block
+func blockWord(word string) bool {
+	switch strings.ToUpper(word) {
+	case "ENDMODULE", "ENDPROC", "ENDFUNC", "ENDTRAP", "ENDRECORD", "ENDIF", "ELSE", "ELSEIF",
+		"ENDWHILE", "ENDFOR", "ENDTEST", "CASE", "DEFAULT", "ERROR", "UNDO", "BACKWARD":
+		return true
+	}
+	return false
+}
+
+func (p *parser) module() *Module {
+	m := &Module{}
+	if !p.peek().Is("MODULE") {
+		p.errorf("expected MODULE, found %s", describe(p.peek()))
+		return m
+	}
+	m.Line = p.next().Line
+	m.Name = p.ident("module name")
+	if p.accept("(") {
+		for !p.peek().Is(")") && p.peek().Kind != TokenEOF {
+			if t := p.next(); t.Kind == TokenIdent {
+				m.Attrs = append(m.Attrs, strings.ToUpper(t.Text))
+			}
+		}
+		p.expect(")")
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.Kind == TokenEOF:
+			p.errorf("MODULE %s is missing ENDMODULE", m.Name)
+			return m
+		case t.Is("ENDMODULE"):
+			p.next()
+			return m
+		case t.Is("RECORD"):
+			p.next()
+			m.Types = append(m.Types, p.ident("record name"))
+			for !p.peek().Is("ENDRECORD") && p.peek().Kind != TokenEOF {
+				p.next()
+			}
+			p.expect("ENDRECORD")
+		case t.Is("ALIAS"):
+			p.next()
+			p.ident("data type")
+			m.Types = append(m.Types, p.ident("alias name"))
+			p.expect(";")
+		default:
+			local := p.accept("LOCAL")
+			task := !local && p.accept("TASK")
+			switch {
+			case p.peek().Is("PROC"), p.peek().Is("FUNC"), p.peek().Is("TRAP"):
+				r := p.routine()
+				r.Local = local
+				m.Routines = append(m.Routines, r)
+			case isStorage(p.peek()):
+				d := p.data()
+				d.Local = local
+				m.Data = append(m.Data, d)
+			default:
+				if !task {
+					p.errorf("expected a declaration, found %s", describe(p.peek()))
+				}
+				p.next()
+				p.skip()
+			}
+		}
+	}
+}
+
+func isStorage(t Token) bool { return t.Is("VAR") || t.Is("PERS") || t.Is("CONST") }
+
+// data parses VAR num count{2} := [0, 0];
+func (p *parser) data() *DataDecl {
+	t := p.next()
+	d := &DataDecl{Storage: strings.ToUpper(t.Text), Line: t.Line}
+	d.Type = p.ident("data type")
+	d.Name = p.ident("data name")
+	if p.accept("{") {
+		p.until("}")
+		p.expect("}")
+	}
+	if p.accept(":=") {
+		d.Init = p.until(";")
+	}
+	p.end()
+	return d
+}
+
+// until collects the tokens up to stop at bracket depth 0, leaving stop
+func (p *parser) until(stop ...string) []Token {
+	var out []Token
+	depth := 0
+	for {
+		t := p.peek()
+		// An assignment or a keyword that ends a block cannot be part of the
+		// statement; the ; before it is missing
+		if t.Kind == TokenEOF || depth == 0 && (t.Is(";") && !contains(stop, ";") || t.Is(":=") ||
+			t.Kind == TokenIdent && blockWord(t.Text) || len(out) > 0 && startsStatement(out[len(out)-1], t)) {
+			return out
+		}
+		if depth == 0 {
+			for _, s := range stop {
+				if t.Is(s) {
+					return out
+				}
+			}
+		}
+		switch {
+		case t.Is("("), t.Is("["), t.Is("{"):
+			depth++
+		case t.Is(")"), t.Is("]"), t.Is("}"):
+			if depth == 0 {
+				return out
+			}
+			depth--
+		}
+		out = append(out, p.next())
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) routine() *RoutineDecl {
+	t := p.next()
+	r := &RoutineDecl{Kind: strings.ToUpper(t.Text), Line: t.Line}
+	if r.Kind == "FUNC" {
+		r.Type = p.ident("return type")
+	}
+	r.Name = p.ident("routine name")
+	if r.Kind != "TRAP" && p.expect("(") {
+		r.Params = p.params()
+		p.expect(")")
+	}
+	for isStorage(p.peek()) || p.peek().Is("LOCAL") && isStorage(p.peekAt(1)) {
+		p.accept("LOCAL")
+		r.Data = append(r.Data, p.data())
+	}
+	end := "END" + r.Kind
+	r.Body = p.block(end, "BACKWARD", "ERROR", "UNDO")
+	if p.accept("BACKWARD") {
+		r.Backward = p.block(end, "ERROR", "UNDO")
+	}
+	if t := p.peek(); t.Is("ERROR") {
+		p.next()
+		r.Error = &Handler{Line: t.Line}
+		if p.accept("(") {
+			for _, e := range p.until(")") {
+				if e.Kind == TokenIdent || e.Kind == TokenNumber {
+					r.Error.Errors = append(r.Error.Errors, e)
+				}
+			}
+			p.expect(")")
+		}
+		r.Error.Body = p.block(end, "UNDO")
+	}
+	if p.accept("UNDO") {
+		r.Undo = p.block(end)
+	}
+	r.End = p.peek().Line
+	if !p.expect(end) {
+		p.skip()
+	}
+	return r
+}
+
+// params parses (VAR num a, \switch b | switch c, PERS tooldata t{*})
+func (p *parser) params() []Parameter {
+	var params []Parameter
+	optional := false
+	for {
+		t := p.peek()
+		switch {
+		case t.Is(")"), t.Kind == TokenEOF:
+			return params
+		case t.Is(","):
+			p.next()
+			optional = false
+			continue
+		case t.Is("\\"):
+			p.next()
+			optional = true
+			continue
+		case t.Is("|"):
+			// Alternatives of an optional parameter
+			p.next()
+			continue
+		}
+		param := Parameter{Optional: optional}
+		if t.Is("VAR") || t.Is("PERS") || t.Is("INOUT") {
+			param.Access = strings.ToUpper(p.next().Text)
+		}
+		param.Type = p.ident("parameter type")
+		param.Name = p.ident("parameter name")
+		if param.Name == "" {
+			p.until(",", ")")
+			continue
+		}
+		if p.accept("{") {
+			p.until("}")
+			p.expect("}")
+		}
+		params = append(params, param)
+	}
+}
+
+// block parses statements up to one of the keywords that end it
+func (p *parser) block(ends ...string) []*Stmt {
+	var stmts []*Stmt
+	for {
+		t := p.peek()
+		if t.Kind == TokenEOF {
+			return stmts
+		}
+		for _, e := range ends {
+			if t.Is(e) {
+				return stmts
+			}
+		}
+		if t.Kind == TokenIdent && blockWord(t.Text) {
+			p.errorf("unexpected %s", strings.ToUpper(t.Text))
+			p.next()
+			continue
+		}
+		if s := p.statement(); s != nil {
+			stmts = append(stmts, s)
+		}
+	}
+}
+
+func (p *parser) statement() *Stmt {
+	t := p.peek()
+	s := &Stmt{Line: t.Line}
+	switch {
+	case t.Kind == TokenPlaceholder:
+		p.next()
+		s.Kind = StmtPlaceholder
+		p.accept(";")
+		return s
+	case t.Is(";"):
+		p.next()
+		return nil
+	case t.Is("IF"):
+		p.next()
+		return p.ifStatement(s)
+	case t.Is("WHILE"):
+		p.next()
+		s.Kind = StmtWhile
+		s.Expr = p.until("DO")
+		p.expect("DO")
+		s.Body = p.block("ENDWHILE")
+		p.expect("ENDWHILE")
+		return s
+	case t.Is("FOR"):
+		p.next()
+		s.Kind = StmtFor
+		s.Name = p.ident("loop variable")
+		p.expect("FROM")
+		s.Expr = p.until("DO")
+		p.expect("DO")
+		s.Body = p.block("ENDFOR")
+		p.expect("ENDFOR")
+		return s
+	case t.Is("TEST"):
+		p.next()
+		return p.testStatement(s)
+	case t.Is("RETURN"), t.Is("RAISE"):
+		s.Kind = strings.ToUpper(p.next().Text)
+		s.Expr = p.until(";")
+	case t.Is("RETRY"), t.Is("TRYNEXT"), t.Is("EXIT"):
+		s.Kind = strings.ToUpper(p.next().Text)
+	case t.Is("GOTO"):
+		p.next()
+		s.Kind = StmtGoto
+		s.Name = p.ident("label")
+	case t.Is("CONNECT"):
+		p.next()
+		s.Kind = StmtConnect
+		s.Target = p.until("WITH")
+		p.expect("WITH")
+		s.Name = p.ident("trap routine")
+	case t.Kind == TokenIdent && p.peekAt(1).Is(":"):
+		p.next()
+		p.next()
+		s.Kind = StmtLabel
+		s.Name = t.Text
+		return s
+	case t.Is("%"):
+		// Late binding: %"proc" + suffix% args;
+		p.next()
+		s.Kind = StmtCall
+		s.Expr = p.until("%")
+		p.expect("%")
+		s.Args = p.args()
+	case t.Kind == TokenIdent:
+		target := p.target()
+		if p.accept(":=") {
+			s.Kind = StmtAssign
+			s.Name = t.Text
+			s.Target = target
+			s.Expr = p.until(";")
+			if len(s.Expr) == 0 {
+				p.errorf("expected a value after %s :=", tokenText(target))
+			}
+		} else {
+			s.Kind = StmtCall
+			s.Name = t.Text
+			if len(target) > 1 {
+				p.errorf("expected := after %s", tokenText(target))
+			}
+			s.Args = p.args()
+		}
+	default:
+		p.errorf("unexpected %s", describe(t))
+		p.skip()
+		return nil
+	}
+	p.end()
+	return s
+}
+
+// target parses the data an assignment changes: name{index}.component
+func (p *parser) target() []Token {
+	out := []Token{p.next()}
+	for {
+		switch t := p.peek(); {
+		case t.Is("{"):
+			out = append(out, p.next())
+			out = append(out, p.until("}")...)
+			if p.peek().Is("}") {
+				out = append(out, p.next())
+			}
+		case t.Is(".") && p.peekAt(1).Kind == TokenIdent:
+			out = append(out, p.next(), p.next())
+		default:
+			return out
+		}
+	}
+}
+
+// args parses the arguments of a call: required ones separated by commas
+// and optional ones starting with a backslash, such as \WObj:=wobj1
+func (p *parser) args() []Arg {
+	var args []Arg
+	for {
+		t := p.peek()
+		switch {
+		case t.Is(";"), t.Kind == TokenEOF, t.Kind == TokenIdent && blockWord(t.Text):
+			return args
+		case t.Is(","):
+			p.next()
+			continue
+		case t.Is("\\"):
+			p.next()
+			a := Arg{Name: p.ident("argument name"), Line: t.Line}
+			if p.accept(":=") || p.accept("?") {
+				a.Value = p.until(",", "\\")
+			}
+			args = append(args, a)
+			continue
+		}
+		value := p.until(",", "\\")
+		if len(value) == 0 {
+			p.errorf("unexpected %s", describe(p.peek()))
+			p.skip()
+			return args
+		}
+		args = append(args, Arg{Value: value, Line: t.Line})
+		if !p.peek().Is(",") && !p.peek().Is("\\") {
+			return args
+		}
+	}
+}
+
+// startsStatement reports whether t cannot continue the expression that
+// ends with prev: two operands in a row mean a missing operator, comma or ;
+func startsStatement(prev, t Token) bool {
+	return isOperandEnd(prev) && (t.Kind == TokenIdent && !isOperator(t) || t.Kind == TokenPlaceholder ||
+		t.Kind == TokenNumber || t.Kind == TokenString)
+}
+
+// end consumes the ; that ends a statement or declaration. A missing one is
+// reported on the line it belongs to, not on the next statement.
+func (p *parser) end() {
+	if p.accept(";") {
+		return
+	}
+	if prev := p.tokens[p.pos-1]; prev.Line < p.peek().Line {
+		// The next line starts the next statement
+		p.diags = append(p.diags, Diagnostic{prev.Line, SeverityError, "statement does not end with ;"})
+		return
+	}
+	p.expect(";")
+	p.skip()
+}
+
+// ifStatement parses an IF after the keyword: a compact IF with a single
+// statement, or IF ... THEN with ELSEIF and ELSE parts
+func (p *parser) ifStatement(s *Stmt) *Stmt {
+	s.Kind = StmtIf
+	if !p.hasThen() {
+		s.Expr = p.compactCondition()
+		if body := p.statement(); body != nil {
+			s.Body = []*Stmt{body}
+		}
+		return s
+	}
+	s.Expr = p.until("THEN")
+	p.expect("THEN")
+	s.Body = p.block("ELSEIF", "ELSE", "ENDIF")
+	switch t := p.peek(); {
+	case t.Is("ELSEIF"):
+		p.next()
+		s.Else = []*Stmt{p.ifStatement(&Stmt{Line: t.Line})}
+		return s
+	case t.Is("ELSE"):
+		p.next()
+		s.Else = p.block("ENDIF")
+	}
+	p.expect("ENDIF")
+	return s
+}
+
+// hasThen reports whether THEN comes before the end of the statement
+func (p *parser) hasThen() bool {
+	for i := p.pos; i < len(p.tokens); i++ {
+		switch t := p.tokens[i]; {
+		case t.Is("THEN"):
+			return true
+		case t.Is(";"), t.Kind == TokenEOF:
+			return false
+		}
+	}
+	return false
+}
+
+// compactCondition collects the condition of a compact IF. It ends where
+// two operands meet, as in IF reg1 = 0 reg2 := 1; where the statement
+// starts at reg2.
+func (p *parser) compactCondition() []Token {
+	var out []Token
+	depth := 0
+	for {
+		t := p.peek()
+		if t.Kind == TokenEOF || t.Is(";") {
+			return out
+		}
+		if depth == 0 && len(out) > 0 && isOperandEnd(out[len(out)-1]) && (t.Kind == TokenIdent && !isOperator(t) || t.Is("%")) {
+			return out
+		}
+		switch {
+		case t.Is("("), t.Is("["), t.Is("{"):
+			depth++
+		case t.Is(")"), t.Is("]"), t.Is("}"):
+			depth--
+		}
+		out = append(out, p.next())
+	}
+}
+
+// isOperator reports whether t is a word operator, or TO and STEP that
+// separate the range of a FOR
+func isOperator(t Token) bool {
+	switch strings.ToUpper(t.Text) {
+	case "AND", "OR", "XOR", "NOT", "DIV", "MOD", "TO", "STEP":
+		return t.Kind == TokenIdent
+	}
+	return false
+}
+
+// isOperandEnd reports whether an expression can end with t
+func isOperandEnd(t Token) bool {
+	switch t.Kind {
+	case TokenIdent:
+		return !isOperator(t)
+	case TokenNumber, TokenString, TokenPlaceholder:
+		return true
+	}
+	return t.Is(")") || t.Is("]") || t.Is("}")
+}
+
+// testStatement parses a TEST after the keyword
+func (p *parser) testStatement(s *Stmt) *Stmt {
+	s.Kind = StmtTest
+	s.Expr = p.until("CASE", "DEFAULT", "ENDTEST")
+	for {
+		t := p.peek()
+		switch {
+		case t.Is("CASE"):
+			p.next()
+			c := Case{Line: t.Line}
+			for {
+				c.Values = append(c.Values, p.until(",", ":"))
+				if !p.accept(",") {
+					break
+				}
+			}
+			p.expect(":")
+			c.Body = p.block("CASE", "DEFAULT", "ENDTEST")
+			s.Cases = append(s.Cases, c)
+		case t.Is("DEFAULT"):
+			p.next()
+			p.expect(":")
+			s.Cases = append(s.Cases, Case{Line: t.Line, Body: p.block("CASE", "DEFAULT", "ENDTEST")})
+		default:
+			p.expect("ENDTEST")
+			return s
+		}
+	}
+}
+
+// tokenText joins tokens back into source text for messages
+func tokenText(tokens []Token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && (t.Kind == TokenIdent || t.Kind == TokenNumber) &&
+			(tokens[i-1].Kind == TokenIdent || tokens[i-1].Kind == TokenNumber) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package rapid
+
+import (
+	"strings"
+	"testing"
+)
+
+const parseExample = `MODULE Cell(SYSMODULE, NOVIEW)
+    RECORD part
+        num id;
+        string name;
+    ENDRECORD
+    CONST num nParts := 4;
+    PERS tooldata tGripper := [TRUE, [[0, 0, 150], [1, 0, 0, 0]], [2, [0, 0, 50], [1, 0, 0, 0], 0, 0, 0]];
+    LOCAL VAR num nCount;
+
+    PROC Main()
+        VAR num reg;
+        FOR i FROM 1 TO nParts DO
+            Pick i, \Fast;
+        ENDFOR
+        IF nCount > 10 THEN
+            reg := 1;
+        ELSEIF nCount > 5 THEN
+            reg := 2;
+        ELSE
+            reg := 3;
+        ENDIF
+        TEST reg
+        CASE 1, 2:
+            TPWrite "low";
+        DEFAULT:
+            TPWrite "high";
+        ENDTEST
+    ENDPROC
+
+    LOCAL FUNC num Double(num x, INOUT num y, \switch Fast)
+        RETURN x * 2;
+    ERROR
+        RETURN 0;
+    ENDFUNC
+
+    PROC Pick(num n, \switch Fast)
+        nCount := nCount + n;
+    ENDPROC
+
+    TRAP OnStop
+        Stop;
+    ENDTRAP
+ENDMODULE
+`
+
+func TestParseModule(t *testing.T) {
+	m, diags := Parse(parseExample)
+	if len(diags) > 0 {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+	if m.Name != "Cell" || strings.Join(m.Attrs, ",") != "SYSMODULE,NOVIEW" {
+		t.Errorf("module %s %v", m.Name, m.Attrs)
+	}
+	if len(m.Types) != 1 || m.Types[0] != "part" {
+		t.Errorf("types %v, want [part]", m.Types)
+	}
+
+	data := []struct {
+		storage, typ, name string
+		local              bool
+		init               bool
+	}{
+		{"CONST", "num", "nParts", false, true},
+		{"PERS", "tooldata", "tGripper", false, true},
+		{"VAR", "num", "nCount", true, false},
+	}
+	if len(m.Data) != len(data) {
+		t.Fatalf("%d data declarations, want %d", len(m.Data), len(data))
+	}
+	for i, want := range data {
+		d := m.Data[i]
+		if d.Storage != want.storage || d.Type != want.typ || d.Name != want.name || d.Local != want.local || (len(d.Init) > 0) != want.init {
+			t.Errorf("data %d: %+v, want %+v", i, d, want)
+		}
+	}
+
+	routines := []struct {
+		kind, name, typ string
+		local           bool
+		params          int
+		line, end       int
+	}{
+		{"PROC", "Main", "", false, 0, 10, 28},
+		{"FUNC", "Double", "num", true, 3, 30, 34},
+		{"PROC", "Pick", "", false, 2, 36, 38},
+		{"TRAP", "OnStop", "", false, 0, 40, 42},
+	}
+	if len(m.Routines) != len(routines) {
+		t.Fatalf("%d routines, want %d", len(m.Routines), len(routines))
+	}
+	for i, want := range routines {
+		r := m.Routines[i]
+		if r.Kind != want.kind || r.Name != want.name || r.Type != want.typ || r.Local != want.local ||
+			len(r.Params) != want.params || r.Line != want.line || r.End != want.end {
+			t.Errorf("routine %d: %s %s %s local=%v %d params, lines %d-%d; want %+v",
+				i, r.Kind, r.Name, r.Type, r.Local, len(r.Params), r.Line, r.End, want)
+		}
+	}
+
+	double := m.Routines[1]
+	params := []Parameter{{Name: "x", Type: "num"}, {Name: "y", Type: "num", Access: "INOUT"}, {Name: "Fast", Type: "switch", Optional: true}}
+	for i, want := range params {
+		if i < len(double.Params) && double.Params[i] != want {
+			t.Errorf("parameter %d: %+v, want %+v", i, double.Params[i], want)
+		}
+	}
+	if double.Error == nil || double.Error.Line != 32 || len(double.Error.Body) != 1 {
+		t.Errorf("ERROR handler of Double: %+v", double.Error)
+	}
+
+	main := m.Routines[0]
+	if len(main.Data) != 1 || main.Data[0].Name != "reg" {
+		t.Errorf("routine data of Main: %v", main.Data)
+	}
+	kinds := []string{StmtFor, StmtIf, StmtTest}
+	if len(main.Body) != len(kinds) {
+		t.Fatalf("Main has %d statements, want %d", len(main.Body), len(kinds))
+	}
+	for i, kind := range kinds {
+		if main.Body[i].Kind != kind {
+			t.Errorf("statement %d is %s, want %s", i, main.Body[i].Kind, kind)
+		}
+	}
+	loop := main.Body[0]
+	if loop.Name != "i" || len(loop.Body) != 1 {
+		t.Fatalf("FOR: %+v", loop)
+	}
+	call := loop.Body[0]
+	if call.Kind != StmtCall || call.Name != "Pick" || len(call.Args) != 2 || call.Args[1].Name != "Fast" || len(call.Args[1].Value) != 0 {
+		t.Errorf("call: %+v", call)
+	}
+	// An ELSEIF is an IF alone in Else
+	branch := main.Body[1]
+	if len(branch.Else) != 1 || branch.Else[0].Kind != StmtIf || len(branch.Else[0].Else) != 1 || branch.Else[0].Else[0].Kind != StmtAssign {
+		t.Errorf("IF/ELSEIF/ELSE: %+v", branch)
+	}
+	test := main.Body[2]
+	if len(test.Cases) != 2 || len(test.Cases[0].Values) != 2 || test.Cases[1].Values != nil {
+		t.Errorf("TEST cases: %+v", test.Cases)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		want string
+	}{
+		{"not a module", "PROC Main()\nENDPROC\n", 1, "expected MODULE"},
+		{"missing ENDMODULE", "MODULE M\n  PROC Main()\n  ENDPROC\n", 4, "MODULE M is missing ENDMODULE"},
+		{"assignment without a value", "MODULE M\n  PROC Main()\n    reg1 := ;\n  ENDPROC\nENDMODULE\n", 3, "expected a value after reg1 :="},
+		{"stray keyword", "MODULE M\n  PROC Main()\n    ENDIF\n  ENDPROC\nENDMODULE\n", 3, "unexpected ENDIF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := Parse(tt.src)
+			if len(diags) == 0 {
+				t.Fatal("no diagnostics")
+			}
+			d := diags[0]
+			if d.Severity != SeverityError || d.Line != tt.line || !strings.Contains(d.Message, tt.want) {
+				t.Errorf("got %v, want an error on line %d containing %q", d, tt.line, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string // messages, in line order
+	}{
+		{"clean", `
+        MoveL p10, v1000, z50, tool0;
+        MoveL p20, v100, fine, tool0;
+        SetDO doGrip, 1;`, nil},
+		{"undeclared name", `
+        reg1 := nMissing + 1;
+        nMissing := 2;`, []string{"nMissing is not declared in this module"}},
+		{"unreachable code", `
+        RETURN;
+        TPWrite "never";`, []string{"unreachable code after RETURN"}},
+		{"RETRY outside the ERROR handler", `
+        RETRY;`, []string{"RETRY is only allowed in an ERROR handler"}},
+		{"duplicate CASE", `
+        TEST reg1
+        CASE 1:
+            reg1 := 2;
+        CASE 2, 1:
+            reg1 := 3;
+        ENDTEST`, []string{"CASE 1 is unreachable: it is handled on line 6"}},
+		{"fine point at high speed", `
+        MoveL p10, v3000, fine, tool0;`, []string{"MoveL at v3000 to a fine point"}},
+		{"large zone at creep speed", `
+        MoveL p10, v10, z100, tool0;`, []string{"a 100 mm corner zone at creep speed"}},
+		{"output after a zone", `
+        MoveL p10, v1000, z50, tool0;
+        SetDO doGrip, 1;`, []string{"SetDO after a move with zone z50"}},
+		{"zone overridden by \\Z", `
+        MoveL p10, v1000, fine \Z:=50, tool0;
+        SetDO doGrip, 1;`, []string{"SetDO after a move with zone z50"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "MODULE M\n    CONST robtarget p10 := [[0,0,0],[1,0,0,0],[0,0,0,0],[9E9,9E9,9E9,9E9,9E9,9E9]];\n" +
+				"    CONST robtarget p20 := [[0,0,0],[1,0,0,0],[0,0,0,0],[9E9,9E9,9E9,9E9,9E9,9E9]];\n" +
+				"    PROC Main()" + tt.body + "\n    ENDPROC\nENDMODULE\n"
+			diags := Lint(src)
+			if len(diags) != len(tt.want) {
+				t.Fatalf("got %v, want %d findings", diags, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(diags[i].Message, want) {
+					t.Errorf("finding %d: %v, want %q", i, diags[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLintTaskGlobals(t *testing.T) {
+	src := "MODULE M\n    PROC Main()\n        nShared := 1;\n    ENDPROC\nENDMODULE\n"
+	diags := LintTask(src, map[string]bool{})
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "or the other modules of the task") {
+		t.Errorf("without the global: %v", diags)
+	}
+	if diags := LintTask(src, map[string]bool{"NSHARED": true}); len(diags) != 0 {
+		t.Errorf("with the global: %v", diags)
+	}
+}
@@ -0,0 +1,183 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RenameSymbol renames every use of an identifier in code, leaving comments,
+// strings, record components (.name) and named arguments (\name) alone.
+// RAPID names are case-insensitive, so all spellings are replaced.
+func RenameSymbol(src, oldName, newName string) string {
+	lines := rawLines(src)
+	for i, line := range lines {
+		code, _ := splitComment(line)
+		masked := stringRe.ReplaceAllStringFunc(code, func(s string) string { return strings.Repeat(" ", len(s)) })
+		locs := identRe.FindAllStringIndex(masked, -1)
+		// Replace from the right so earlier offsets stay valid
+		for j := len(locs) - 1; j >= 0; j-- {
+			start, end := locs[j][0], locs[j][1]
+			if !strings.EqualFold(masked[start:end], oldName) {
+				continue
+			}
+			if start > 0 && (masked[start-1] == '.' || masked[start-1] == '\\' || isIdentChar(masked[start-1])) {
+				continue
+			}
+			line = line[:start] + newName + line[end:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TargetRole is the suggested purpose of a generically named robtarget
+type TargetRole struct {
+	Target    string
+	Role      string // Home, Pick, Place, Approach or Via
+	Reason    string
+	Suggested string
+}
+
+// genericNameRe matches the names robots and offline tools generate
+var genericNameRe = regexp.MustCompile(`(?i)^(p|pos|rt|t|target_?)\d+$`)
+
+// IsGenericName reports whether a target name carries no meaning, like p10
+// or Target_140
+func IsGenericName(name string) bool {
+	return genericNameRe.MatchString(name)
+}
+
+// motion is a move to a target in program order
+type motion struct {
+	line    int
+	instr   string
+	target  string
+	routine string
+	// gripper is "close" or "open" when a gripper signal follows the move
+	gripper string
+}
+
+var (
+	motionTargetRe = regexp.MustCompile(`(?i)^(Move\w*|Search\w*|Trigg\w*)\s+(?:Offs\s*\(\s*|RelTool\s*\(\s*)?(\w+)`)
+	ioRe           = regexp.MustCompile(`(?i)^(SetDO|Set|Reset|PulseDO)\s+(?:\\\w+\s*(?::=\s*[^,]+)?\s*,?\s*)*(\w+)\s*(?:,\s*(\w+))?`)
+	gripperRe      = regexp.MustCompile(`(?i)grip|vac|clamp|suction|suck|jaw|tool_?on`)
+	releaseRe      = regexp.MustCompile(`(?i)open|release|off|blow|drop`)
+)
+
+// gripperAction classifies an I/O instruction as closing or opening a gripper
+func gripperAction(code string) string {
+	m := ioRe.FindStringSubmatch(code)
+	if m == nil || !gripperRe.MatchString(m[2]) {
+		return ""
+	}
+	on := true
+	switch strings.ToUpper(m[1]) {
+	case "RESET":
+		on = false
+	case "SETDO":
+		on = m[3] != "0" && !strings.EqualFold(m[3], "low")
+	}
+	if releaseRe.MatchString(m[2]) {
+		on = !on
+	}
+	if on {
+		return "close"
+	}
+	return "open"
+}
+
+// InferTargetRoles suggests meaningful names for generically named
+// robtargets from how the program uses them: a gripper closing after the move
+// marks a pick, opening marks a place, the move just before either is an
+// approach, and the first and last move of main is home.
+func InferTargetRoles(src string) []TargetRole {
+	var motions []motion
+	routine := ""
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if m := routineDecl.FindStringSubmatch(code); m != nil {
+			routine = m[5]
+			continue
+		}
+		if m := motionTargetRe.FindStringSubmatch(code); m != nil {
+			motions = append(motions, motion{line: line.Number, instr: m[1], target: m[2], routine: routine})
+			continue
+		}
+		if action := gripperAction(code); action != "" && len(motions) > 0 && motions[len(motions)-1].gripper == "" {
+			motions[len(motions)-1].gripper = action
+		}
+	}
+
+	roles := make(map[string]TargetRole)
+	var order []string
+	assign := func(target, role, reason string) {
+		key := strings.ToUpper(target)
+		if _, done := roles[key]; done || !IsGenericName(target) {
+			return
+		}
+		roles[key] = TargetRole{Target: target, Role: role, Reason: reason}
+		order = append(order, key)
+	}
+
+	for i, m := range motions {
+		switch m.gripper {
+		case "close":
+			assign(m.target, "Pick", fmt.Sprintf("gripper closes after the move on line %d", m.line))
+		case "open":
+			assign(m.target, "Place", fmt.Sprintf("gripper opens after the move on line %d", m.line))
+		default:
+			continue
+		}
+		if i > 0 && motions[i-1].routine == m.routine && !strings.EqualFold(motions[i-1].target, m.target) {
+			prev := motions[i-1]
+			role := "Approach"
+			if m.gripper == "close" {
+				role = "PickApproach"
+			} else {
+				role = "PlaceApproach"
+			}
+			assign(prev.target, role, fmt.Sprintf("moved to on line %d just before %s", prev.line, m.target))
+		}
+	}
+
+	var inMain []motion
+	for _, m := range motions {
+		if strings.EqualFold(m.routine, "main") {
+			inMain = append(inMain, m)
+		}
+	}
+	if len(inMain) > 1 && strings.EqualFold(inMain[0].target, inMain[len(inMain)-1].target) {
+		assign(inMain[0].target, "Home", "first and last position in main")
+	}
+	for _, m := range motions {
+		assign(m.target, "Via", fmt.Sprintf("intermediate position on line %d", m.line))
+	}
+
+	// Name the roles, numbering them when a role occurs more than once
+	count := make(map[string]int)
+	for _, key := range order {
+		count[roles[key].Role]++
+	}
+	taken := make(map[string]bool)
+	for _, s := range AnalyzeModule(src).Symbols {
+		taken[strings.ToUpper(s.Name)] = true
+	}
+	seen := make(map[string]int)
+	result := make([]TargetRole, 0, len(order))
+	for _, key := range order {
+		r := roles[key]
+		name := "p" + r.Role
+		if count[r.Role] > 1 {
+			seen[r.Role]++
+			name = fmt.Sprintf("%s%d", name, seen[r.Role])
+		}
+		for n := 2; taken[strings.ToUpper(name)]; n++ {
+			name = fmt.Sprintf("p%s_%d", r.Role, n)
+		}
+		taken[strings.ToUpper(name)] = true
+		r.Suggested = name
+		result = append(result, r)
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package rapid
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Target is a robtarget declaration: TCP position, orientation quaternion,
+// axis configuration and external axis values
+type Target struct {
+	Line    int
+	Storage string // CONST, PERS or VAR
+	Name    string
+	Trans   [3]float64
+	Rot     [4]float64
+	Conf    [4]float64
+	Ext     [6]float64
+}
+
+// unusedExt is the value RAPID uses for external axes that are not present
+const unusedExt = 9e9
+
+var targetRe = regexp.MustCompile(`(?i)^\s*(?:(?:LOCAL|TASK)\s+)?(CONST|PERS|VAR)\s+robtarget\s+(\w+)\s*:=\s*(\[.*\])\s*;`)
+
+// ParseTargets returns the robtarget declarations with literal values in src
+func ParseTargets(src string) []Target {
+	var targets []Target
+	for _, line := range SplitLines(src) {
+		m := targetRe.FindStringSubmatch(line.Code)
+		if m == nil {
+			continue
+		}
+		t, err := ParseTargetValue(m[3])
+		if err != nil {
+			continue
+		}
+		t.Line, t.Storage, t.Name = line.Number, strings.ToUpper(m[1]), m[2]
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// ParseTargetValue parses a robtarget literal such as
+// [[x,y,z],[q1,q2,q3,q4],[cf1,cf4,cf6,cfx],[e1,e2,e3,e4,e5,e6]]
+func ParseTargetValue(literal string) (Target, error) {
+	fields := strings.FieldsFunc(literal, func(r rune) bool {
+		return r == '[' || r == ']' || r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) != 17 {
+		return Target{}, fmt.Errorf("robtarget needs 17 values, got %d", len(fields))
+	}
+	var values [17]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid robtarget value %q", f)
+		}
+		values[i] = v
+	}
+	var t Target
+	copy(t.Trans[:], values[0:3])
+	copy(t.Rot[:], values[3:7])
+	copy(t.Conf[:], values[7:11])
+	copy(t.Ext[:], values[11:17])
+	return t, nil
+}
+
+// Value formats the target as a RAPID robtarget literal
+func (t Target) Value() string {
+	ext := make([]string, len(t.Ext))
+	for i, e := range t.Ext {
+		ext[i] = formatNumber(e)
+	}
+	return fmt.Sprintf("[[%s,%s,%s],[%s,%s,%s,%s],[%s,%s,%s,%s],[%s]]",
+		formatNumber(t.Trans[0]), formatNumber(t.Trans[1]), formatNumber(t.Trans[2]),
+		formatNumber(t.Rot[0]), formatNumber(t.Rot[1]), formatNumber(t.Rot[2]), formatNumber(t.Rot[3]),
+		formatNumber(t.Conf[0]), formatNumber(t.Conf[1]), formatNumber(t.Conf[2]), formatNumber(t.Conf[3]),
+		strings.Join(ext, ","))
+}
+
+// Describe formats the target for people: position, orientation,
+// configuration and only the external axes in use
+func (t Target) Describe() string {
+	s := fmt.Sprintf("x=%.2f y=%.2f z=%.2f  q=[%.5f %.5f %.5f %.5f]  cf=[%g %g %g %g]",
+		t.Trans[0], t.Trans[1], t.Trans[2], t.Rot[0], t.Rot[1], t.Rot[2], t.Rot[3],
+		t.Conf[0], t.Conf[1], t.Conf[2], t.Conf[3])
+	var ext []string
+	for i, e := range t.Ext {
+		if e < unusedExt {
+			ext = append(ext, fmt.Sprintf("e%d=%.2f", i+1, e))
+		}
+	}
+	if len(ext) > 0 {
+		s += "  " + strings.Join(ext, " ")
+	}
+	return s
+}
+
+// Distance returns the distance in mm between two target positions
+func (t Target) Distance(other Target) float64 {
+	dx, dy, dz := t.Trans[0]-other.Trans[0], t.Trans[1]-other.Trans[1], t.Trans[2]-other.Trans[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// formatNumber writes a number the way RAPID programs usually show it
+func formatNumber(v float64) string {
+	if v >= unusedExt {
+		return "9E+09"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ReplaceTargets rewrites the literal value of robtarget declarations.
+// update receives each parsed target and returns the new value and whether
+// to change it; everything else in the source is left untouched.
+func ReplaceTargets(src string, update func(Target) (Target, bool)) string {
+	targets := make(map[int]Target)
+	for _, t := range ParseTargets(src) {
+		targets[t.Line] = t
+	}
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		t, ok := targets[i+1]
+		if !ok {
+			continue
+		}
+		changed, ok := update(t)
+		if !ok {
+			continue
+		}
+		code, _ := splitComment(line)
+		m := targetRe.FindStringSubmatchIndex(code)
+		lines[i] = line[:m[6]] + changed.Value() + line[m[7]:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Rounded returns the target with positions and quaternion components
+// rounded to the given number of decimals
+func (t Target) Rounded(posDecimals, rotDecimals int) Target {
+	for i := range t.Trans {
+		t.Trans[i] = roundTo(t.Trans[i], posDecimals)
+	}
+	for i := range t.Rot {
+		t.Rot[i] = roundTo(t.Rot[i], rotDecimals)
+	}
+	return t
+}
+
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	r := math.Round(v*scale) / scale
+	if r == 0 {
+		// Avoid writing -0
+		return 0
+	}
+	return r
+}
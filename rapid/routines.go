@@ -0,0 +1,45 @@
+package rapid
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Routine is a PROC, FUNC or TRAP in a module
+type Routine struct {
+	Kind    string // PROC, FUNC or TRAP
+	Name    string
+	Line    int
+	EndLine int
+	Body    string // Code of the routine without comments, indentation or blank lines
+}
+
+var routineRe = regexp.MustCompile(`(?i)^\s*(?:(?:LOCAL|TASK)\s+)?(PROC|TRAP|FUNC\s+\w+)\s+(\w+)`)
+
+// Routines lists the routines declared in src in source order
+func Routines(src string) []Routine {
+	var routines []Routine
+	var current *Routine
+	var body []string
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if current == nil {
+			if m := routineRe.FindStringSubmatch(code); m != nil {
+				kind := strings.ToUpper(strings.Fields(m[1])[0])
+				current = &Routine{Kind: kind, Name: m[2], Line: line.Number}
+				body = []string{code}
+			}
+			continue
+		}
+		if code != "" {
+			body = append(body, code)
+		}
+		if firstWord(code) == "END"+current.Kind {
+			current.EndLine = line.Number
+			current.Body = strings.Join(body, "\n")
+			routines = append(routines, *current)
+			current = nil
+		}
+	}
+	return routines
+}
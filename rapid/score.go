@@ -0,0 +1,141 @@
+package rapid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ScorePart is one criterion of the quality score
+type ScorePart struct {
+	Name   string
+	Points float64
+	Max    float64
+	Detail string
+}
+
+// Quality is the best-practice score of a set of modules
+type Quality struct {
+	Files int
+	Score int // 0-100
+	Parts []ScorePart
+}
+
+// Targets for a full score
+const (
+	maxFindingsPerKLOC = 5.0  // Lint findings per 1000 code lines that give zero points
+	goodCommentRatio   = 0.15 // Comment lines per code line
+	maxRoutineLines    = 50   // Longer routines are hard to follow
+	goodErrorCoverage  = 0.5  // Share of moving or I/O routines with an ERROR handler
+)
+
+// Score rates RAPID modules against best practices: lint findings,
+// comment density, routine length, target naming and error handling coverage.
+// files maps file names to their source.
+func Score(files map[string]string) Quality {
+	q := Quality{Files: len(files)}
+	var findings, codeLines, commentLines int
+	var routines, shortRoutines, active, handled int
+	var targets, generic int
+
+	for _, src := range files {
+		findings += len(Lint(src))
+		stats := ComputeStats(src)
+		codeLines += stats.CodeLines
+		commentLines += stats.CommentLines
+
+		for _, r := range Routines(src) {
+			routines++
+			lines := strings.Split(r.Body, "\n")
+			if len(lines) <= maxRoutineLines {
+				shortRoutines++
+			}
+			moves, hasError := false, false
+			for _, l := range lines {
+				word := firstWord(l)
+				if motionInstructions[word] || ioInstructions[word] {
+					moves = true
+				}
+				if word == "ERROR" {
+					hasError = true
+				}
+			}
+			if moves {
+				active++
+				if hasError {
+					handled++
+				}
+			}
+		}
+		for _, t := range ParseTargets(src) {
+			targets++
+			if IsGenericName(t.Name) {
+				generic++
+			}
+		}
+	}
+
+	density := 0.0
+	if codeLines > 0 {
+		density = float64(findings) / float64(codeLines) * 1000
+	}
+	q.add("Lint", 30, 1-density/maxFindingsPerKLOC,
+		fmt.Sprintf("%d finding(s), %.1f per 1000 code lines", findings, density))
+
+	ratio := 0.0
+	if codeLines > 0 {
+		ratio = float64(commentLines) / float64(codeLines)
+	}
+	q.add("Comments", 15, ratio/goodCommentRatio,
+		fmt.Sprintf("%d comment lines for %d code lines (%.0f %%)", commentLines, codeLines, ratio*100))
+
+	q.add("Routine length", 15, share(shortRoutines, routines),
+		fmt.Sprintf("%d of %d routine(s) within %d lines", shortRoutines, routines, maxRoutineLines))
+
+	q.add("Target naming", 20, 1-share(generic, targets)+boolFloat(targets == 0),
+		fmt.Sprintf("%d of %d robtarget(s) with generic names like p10", generic, targets))
+
+	coverage := share(handled, active)
+	if active == 0 {
+		coverage = 1
+	}
+	q.add("Error handling", 20, coverage/goodErrorCoverage,
+		fmt.Sprintf("%d of %d routine(s) with motion or I/O have an ERROR handler", handled, active))
+
+	total := 0.0
+	for _, p := range q.Parts {
+		total += p.Points
+	}
+	q.Score = int(math.Round(total))
+	return q
+}
+
+// add records a criterion; fraction is clamped to 0..1
+func (q *Quality) add(name string, max, fraction float64, detail string) {
+	fraction = math.Max(0, math.Min(1, fraction))
+	q.Parts = append(q.Parts, ScorePart{Name: name, Points: max * fraction, Max: max, Detail: detail})
+}
+
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// String formats the score with its criteria
+func (q Quality) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Score: %d/100 (%d module(s))\n", q.Score, q.Files)
+	for _, p := range q.Parts {
+		fmt.Fprintf(&b, "  %-15s %4.1f/%-3.0f %s\n", p.Name, p.Points, p.Max, p.Detail)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
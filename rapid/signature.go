@@ -0,0 +1,113 @@
+package rapid
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Signature is the interface of a routine as seen by a caller
+type Signature struct {
+	Local    bool
+	Returns  string // Data type returned by a FUNC
+	Params   []Param
+	Optional []string // Optional parameters (\name), which callers may leave out
+}
+
+var signatureRe = regexp.MustCompile(`(?i)^(LOCAL\s+)?(?:TASK\s+)?(?:PROC|TRAP|FUNC\s+(\w+))\s+\w+\s*(?:\((.*)\))?`)
+
+// RoutineSignature parses the declaration line of a routine
+func RoutineSignature(r Routine) Signature {
+	header, _, _ := strings.Cut(r.Body, "\n")
+	m := signatureRe.FindStringSubmatch(header)
+	if m == nil {
+		return Signature{}
+	}
+	sig := Signature{Local: m[1] != "", Returns: m[2]}
+	for _, field := range strings.Split(m[3], ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.HasPrefix(field, `\`) {
+			for _, alt := range strings.Split(field, "|") {
+				words := strings.Fields(strings.TrimPrefix(strings.TrimSpace(alt), `\`))
+				if len(words) > 0 {
+					sig.Optional = append(sig.Optional, words[len(words)-1])
+				}
+			}
+			continue
+		}
+		if p := paramRe.FindStringSubmatch(field); p != nil {
+			sig.Params = append(sig.Params, Param{
+				Mode:  strings.ToUpper(strings.TrimSpace(p[1])),
+				Type:  p[2],
+				Name:  p[3],
+				Array: p[4] != "",
+			})
+		}
+	}
+	return sig
+}
+
+// IOSignal is an I/O signal a module uses, with its RAPID data type such
+// as signaldi
+type IOSignal struct {
+	Name string
+	Type string
+}
+
+// Input reports whether the controller sets the signal, so a test has to
+// drive it
+func (s IOSignal) Input() bool {
+	return s.Type == "signaldi" || s.Type == "signalgi" || s.Type == "signalai"
+}
+
+var (
+	// signalInstructions take a signal as their first argument
+	signalInstructions = map[string]string{
+		"SET": "signaldo", "RESET": "signaldo", "SETDO": "signaldo", "PULSEDO": "signaldo",
+		"WAITDO": "signaldo", "SETGO": "signalgo", "WAITGO": "signalgo", "SETAO": "signalao",
+		"WAITDI": "signaldi", "WAITGI": "signalgi", "WAITAI": "signalai",
+	}
+	signalPrefixRe = regexp.MustCompile(`^(di|do|gi|go|ai|ao)[A-Z0-9_]`)
+)
+
+// IOSignals returns the signals used in src, found from the instructions
+// that take signals and from the di/do/gi/go/ai/ao naming convention.
+// Signals declared in the module itself (aliased with AliasIO) are left out.
+func IOSignals(src string) []IOSignal {
+	declared := make(map[string]bool)
+	for _, s := range AnalyzeModule(src).Symbols {
+		declared[strings.ToUpper(s.Name)] = true
+	}
+	found := make(map[string]IOSignal)
+	add := func(name, typ string) {
+		key := strings.ToUpper(name)
+		if _, seen := found[key]; !seen && !declared[key] && !IsBuiltin(name) {
+			found[key] = IOSignal{Name: name, Type: typ}
+		}
+	}
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if typ, ok := signalInstructions[firstWord(code)]; ok {
+			fields := strings.FieldsFunc(code, func(r rune) bool {
+				return r == ' ' || r == '\t' || r == ',' || r == ';'
+			})
+			if len(fields) > 1 && identRe.MatchString(fields[1]) && !strings.HasPrefix(fields[1], `\`) {
+				add(fields[1], typ)
+			}
+		}
+		for _, occ := range Occurrences(line.Code) {
+			if m := signalPrefixRe.FindStringSubmatch(occ.Name); m != nil {
+				add(occ.Name, "signal"+m[1])
+			}
+		}
+	}
+	signals := make([]IOSignal, 0, len(found))
+	for _, s := range found {
+		signals = append(signals, s)
+	}
+	sort.Slice(signals, func(i, j int) bool { return signals[i].Name < signals[j].Name })
+	return signals
+}
@@ -0,0 +1,78 @@
+// Package rapid provides analysis of ABB RAPID source files
+package rapid
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Extensions lists the file extensions used for RAPID modules
+var Extensions = []string{".mod", ".modx", ".sys", ".sysx", ".prg"}
+
+// IsSourceFile reports whether the path looks like a RAPID module
+func IsSourceFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Line is a single source line split into code and comment parts
+type Line struct {
+	Number  int
+	Code    string // Code with the trailing comment removed
+	Comment string // Comment text without the leading '!'
+}
+
+// SplitLines breaks RAPID source into lines, separating code from
+// '!' comments while respecting string literals
+func SplitLines(src string) []Line {
+	raw := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	lines := make([]Line, 0, len(raw))
+	for i, text := range raw {
+		code, comment := splitComment(text)
+		lines = append(lines, Line{Number: i + 1, Code: code, Comment: comment})
+	}
+	return lines
+}
+
+func splitComment(text string) (string, string) {
+	inString := false
+	for i, r := range text {
+		switch r {
+		case '"':
+			inString = !inString
+		case '!':
+			if !inString {
+				return text[:i], strings.TrimSpace(text[i+1:])
+			}
+		}
+	}
+	return text, ""
+}
+
+// firstWord returns the upper-cased first identifier on a code line
+func firstWord(code string) string {
+	fields := strings.FieldsFunc(code, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '(' || r == ';' || r == ','
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// Normalize gives RAPID source a canonical layout: no byte order mark, LF line
+// endings, tabs expanded to four spaces, no trailing whitespace and exactly
+// one final newline
+func Normalize(src string) string {
+	src = strings.TrimPrefix(src, "\uFEFF")
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), " ")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
@@ -0,0 +1,80 @@
+package rapid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stats summarizes the size and content of a RAPID module
+type Stats struct {
+	Lines        int `json:"lines"`
+	CodeLines    int `json:"code_lines"`
+	CommentLines int `json:"comment_lines"`
+	BlankLines   int `json:"blank_lines"`
+	Procs        int `json:"procs"`
+	Funcs        int `json:"funcs"`
+	Traps        int `json:"traps"`
+	Robtargets   int `json:"robtargets"`
+	Motions      int `json:"motions"`
+	IOCalls      int `json:"io_calls"`
+}
+
+var motionInstructions = map[string]bool{
+	"MOVEJ": true, "MOVEL": true, "MOVEC": true, "MOVEABSJ": true,
+	"SEARCHL": true, "SEARCHC": true, "MOVEJDO": true, "MOVELDO": true,
+}
+
+var ioInstructions = map[string]bool{
+	"SETDO": true, "RESET": true, "SET": true, "PULSEDO": true, "SETAO": true,
+	"SETGO": true, "WAITDI": true, "WAITDO": true, "WAITAI": true, "WAITGI": true,
+}
+
+// ComputeStats counts lines, routines, targets and instructions in RAPID source
+func ComputeStats(src string) Stats {
+	var s Stats
+	for _, line := range SplitLines(src) {
+		s.Lines++
+		code := strings.TrimSpace(line.Code)
+		switch {
+		case code == "" && line.Comment == "":
+			s.BlankLines++
+			continue
+		case code == "":
+			s.CommentLines++
+			continue
+		}
+		s.CodeLines++
+
+		word := firstWord(code)
+		if word == "LOCAL" || word == "TASK" {
+			word = firstWord(strings.TrimSpace(code[len(word):]))
+		}
+		switch word {
+		case "PROC":
+			s.Procs++
+		case "FUNC":
+			s.Funcs++
+		case "TRAP":
+			s.Traps++
+		}
+		if strings.Contains(strings.ToLower(code), "robtarget") && strings.Contains(code, ":=") {
+			s.Robtargets++
+		}
+		if motionInstructions[word] {
+			s.Motions++
+		}
+		if ioInstructions[word] {
+			s.IOCalls++
+		}
+	}
+	return s
+}
+
+// String formats the statistics as a short report
+func (s Stats) String() string {
+	return fmt.Sprintf("Lines: %d (code %d, comments %d, blank %d)\n"+
+		"Routines: %d PROC, %d FUNC, %d TRAP\n"+
+		"Robtargets: %d, Motion instructions: %d, I/O instructions: %d",
+		s.Lines, s.CodeLines, s.CommentLines, s.BlankLines,
+		s.Procs, s.Funcs, s.Traps, s.Robtargets, s.Motions, s.IOCalls)
+}
@@ -0,0 +1,231 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Style is a code layout customers mandate for RAPID: generated modules and
+// the formatter follow it
+type Style struct {
+	Indent   int    `json:"indent"`   // spaces per block level, 0 for tabs
+	Keywords string `json:"keywords"` // upper or lower
+	Comments string `json:"comments"` // language of generated comments
+	Signals  string `json:"signals"`  // naming scheme of generated signals
+}
+
+// DefaultStyle is the layout of the ABB examples and the generators
+var DefaultStyle = Style{Indent: 4, Keywords: "upper", Comments: "en", Signals: "prefix"}
+
+// SignalSchemes name I/O signals, shown for a digital input JobStart
+var SignalSchemes = map[string]string{
+	"prefix":     "diJobStart",
+	"underscore": "di_JobStart",
+	"upper":      "DI_JobStart",
+	"suffix":     "JobStart_DI",
+}
+
+// Validate checks the values of a style; the comment language is checked
+// by the caller against the supported languages
+func (s Style) Validate() error {
+	if s.Indent < 0 || s.Indent > 8 {
+		return fmt.Errorf("indent must be 0 (tabs) to 8 spaces")
+	}
+	if s.Keywords != "upper" && s.Keywords != "lower" {
+		return fmt.Errorf("keywords must be upper or lower")
+	}
+	if _, ok := SignalSchemes[s.Signals]; !ok {
+		return fmt.Errorf("unknown signal scheme %q (use prefix, underscore, upper or suffix)", s.Signals)
+	}
+	return nil
+}
+
+// generatedSignalRe matches the signal names the generators produce, such
+// as diJobStart or goPartType
+var generatedSignalRe = regexp.MustCompile(`\b(di|do|gi|go|ai|ao)([A-Z][A-Za-z0-9]*)\b`)
+
+// RenameSignals renames the signals of generated code to the style's
+// scheme. It applies to RAPID, PLC code and I/O configuration alike, so
+// the names stay matched.
+func (s Style) RenameSignals(src string) string {
+	if s.Signals == "" || s.Signals == "prefix" {
+		return src
+	}
+	return generatedSignalRe.ReplaceAllStringFunc(src, func(name string) string {
+		m := generatedSignalRe.FindStringSubmatch(name)
+		switch s.Signals {
+		case "underscore":
+			return m[1] + "_" + m[2]
+		case "upper":
+			return strings.ToUpper(m[1]) + "_" + m[2]
+		case "suffix":
+			return m[2] + "_" + strings.ToUpper(m[1])
+		}
+		return name
+	})
+}
+
+// Block structure for indentation: openers indent the statements after
+// them and closers end the block. Middle words, and CASE and DEFAULT of a
+// TEST, sit at the level of their opener with their statements indented.
+var (
+	blockOpeners = map[string]bool{
+		"MODULE": true, "PROC": true, "FUNC": true, "TRAP": true, "RECORD": true,
+		"IF": true, "WHILE": true, "FOR": true, "TEST": true,
+	}
+	blockClosers = map[string]bool{
+		"ENDMODULE": true, "ENDPROC": true, "ENDFUNC": true, "ENDTRAP": true,
+		"ENDRECORD": true, "ENDIF": true, "ENDWHILE": true, "ENDFOR": true, "ENDTEST": true,
+	}
+	blockMiddles = map[string]bool{
+		"ELSE": true, "ELSEIF": true, "ERROR": true, "UNDO": true, "BACKWARD": true,
+		"CASE": true, "DEFAULT": true,
+	}
+	declWords = map[string]bool{"LOCAL": true, "TASK": true}
+	wordRe    = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// Format re-indents RAPID source by its block structure and sets the case
+// of the keywords. Comments, strings and names are left as they are; lines
+// continuing a statement are indented one level deeper.
+func (s Style) Format(src string) string {
+	unit := s.Unit()
+	var out []string
+	level := 0
+	statement := "" // first word of the statement in progress
+	for _, text := range strings.Split(Normalize(src), "\n") {
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			out = append(out, "")
+			continue
+		}
+		code, _ := splitComment(trimmed)
+		code = strings.TrimSpace(code)
+		if code == "" {
+			out = append(out, strings.Repeat(unit, level)+trimmed)
+			continue
+		}
+
+		lineLevel := level
+		if statement == "" {
+			statement = strings.TrimSuffix(firstWord(code), ":")
+			for declWords[statement] {
+				code = strings.TrimSpace(code[len(statement):])
+				statement = firstWord(code)
+			}
+			switch {
+			case blockClosers[statement]:
+				level--
+				lineLevel = level
+			case blockMiddles[statement]:
+				lineLevel = level - 1
+			}
+		} else {
+			lineLevel = level + 1
+		}
+		if lineLevel < 0 {
+			lineLevel = 0
+		}
+		out = append(out, strings.Repeat(unit, lineLevel)+s.keywordCase(trimmed))
+
+		// A statement ends with a semicolon; block headers end with THEN or
+		// DO, or with the line
+		upper := strings.ToUpper(code)
+		ends := strings.HasSuffix(code, ";")
+		switch statement {
+		case "IF", "ELSEIF":
+			if strings.HasSuffix(upper, "THEN") {
+				ends = true
+				if statement == "IF" {
+					level++
+				}
+			}
+		case "WHILE", "FOR":
+			if strings.HasSuffix(upper, "DO") {
+				ends = true
+				level++
+			}
+		default:
+			if headerWords[statement] || strings.HasSuffix(code, ":") {
+				ends = true
+				if blockOpeners[statement] {
+					level++
+				}
+			}
+		}
+		if level < 0 {
+			level = 0
+		}
+		if ends {
+			statement = ""
+		}
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// Unit is the indentation of one block level
+func (s Style) Unit() string {
+	if s.Indent == 0 {
+		return "\t"
+	}
+	return strings.Repeat(" ", s.Indent)
+}
+
+// keywordCase sets the case of the keywords in the code of a line
+func (s Style) keywordCase(line string) string {
+	code, comment := line, ""
+	if i := commentStart(line); i >= 0 {
+		code, comment = line[:i], line[i:]
+	}
+	var b strings.Builder
+	inString := false
+	start := 0
+	for i, r := range code {
+		if r != '"' {
+			continue
+		}
+		if !inString {
+			b.WriteString(s.caseWords(code[start:i]))
+			start = i
+		} else {
+			b.WriteString(code[start : i+1])
+			start = i + 1
+		}
+		inString = !inString
+	}
+	if inString {
+		b.WriteString(code[start:])
+	} else {
+		b.WriteString(s.caseWords(code[start:]))
+	}
+	return b.String() + comment
+}
+
+func (s Style) caseWords(code string) string {
+	return wordRe.ReplaceAllStringFunc(code, func(w string) string {
+		if !keywords[strings.ToUpper(w)] {
+			return w
+		}
+		if s.Keywords == "lower" {
+			return strings.ToLower(w)
+		}
+		return strings.ToUpper(w)
+	})
+}
+
+// commentStart returns the index of the '!' starting a comment, or -1
+func commentStart(text string) int {
+	inString := false
+	for i, r := range text {
+		switch r {
+		case '"':
+			inString = !inString
+		case '!':
+			if !inString {
+				return i
+			}
+		}
+	}
+	return -1
+}
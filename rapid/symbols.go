@@ -0,0 +1,192 @@
+package rapid
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Symbol is a name declared at module level
+type Symbol struct {
+	Name  string
+	Kind  string // PROC, FUNC, TRAP, RECORD, ALIAS, CONST, PERS or VAR
+	Type  string // Data type of data and functions
+	Local bool   // LOCAL symbols are not visible to other modules
+	Line  int
+}
+
+// ModuleInfo lists what a module declares and which other names it uses
+type ModuleInfo struct {
+	Name    string
+	System  bool // Declared with the SYSMODULE attribute
+	Symbols []Symbol
+	// Refs maps names used but not declared in the module to the first
+	// line using them. Keywords and controller built-ins are left out.
+	Refs map[string]int
+}
+
+var (
+	moduleAttrRe = regexp.MustCompile(`(?i)^\s*MODULE\s+\w+\s*\(([^)]*)\)`)
+	dataDeclRe   = regexp.MustCompile(`(?i)^\s*(LOCAL\s+|TASK\s+)?(CONST|PERS|VAR)\s+(\w+)\s+(\w+)`)
+	routineDecl  = regexp.MustCompile(`(?i)^\s*(LOCAL\s+)?(PROC|TRAP|FUNC\s+(\w+)|RECORD|ALIAS\s+(\w+))\s+(\w+)`)
+	identRe      = regexp.MustCompile(`[A-Za-z_]\w*`)
+	stringRe     = regexp.MustCompile(`"(?:[^"]|"")*"`)
+)
+
+// AnalyzeModule collects the declarations and outside references of a module
+func AnalyzeModule(src string) ModuleInfo {
+	info := ModuleInfo{Refs: make(map[string]int)}
+	declared := make(map[string]bool)
+	used := make(map[string]usage)
+	depth := 0 // Nesting inside routines and records
+
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if code == "" {
+			continue
+		}
+		if name, ok := ModuleName(code); ok && info.Name == "" {
+			info.Name = name
+			if m := moduleAttrRe.FindStringSubmatch(code); m != nil {
+				info.System = strings.Contains(strings.ToUpper(m[1]), "SYSMODULE")
+			}
+			continue
+		}
+
+		word := firstWord(code)
+		switch {
+		case routineDecl.MatchString(code):
+			m := routineDecl.FindStringSubmatch(code)
+			kind := strings.ToUpper(strings.Fields(m[2])[0])
+			typ := m[3] + m[4]
+			if depth == 0 {
+				info.Symbols = append(info.Symbols, Symbol{Name: m[5], Kind: kind, Type: typ, Local: m[1] != "", Line: line.Number})
+			}
+			declared[strings.ToUpper(m[5])] = true
+			depth++
+			if typ != "" {
+				recordUses(used, typ, line.Number)
+			}
+			// Parameters are local names, but their types are references
+			if open := strings.Index(code, "("); open >= 0 && kind != "RECORD" {
+				params := strings.NewReplacer("\\", ",", "|", ",").Replace(strings.Trim(code[open:], "() "))
+				for _, param := range strings.Split(params, ",") {
+					fields := strings.Fields(param)
+					for i, f := range fields {
+						upper := strings.ToUpper(f)
+						if upper == "INOUT" || upper == "VAR" || upper == "PERS" {
+							continue
+						}
+						if i == len(fields)-1 {
+							declared[strings.ToUpper(strings.Trim(f, "{*}"))] = true
+						} else {
+							recordUses(used, f, line.Number)
+						}
+					}
+				}
+			}
+			continue
+		case word == "ENDPROC" || word == "ENDFUNC" || word == "ENDTRAP" || word == "ENDRECORD":
+			depth--
+			continue
+		case word == "ENDMODULE":
+			continue
+		}
+
+		if m := dataDeclRe.FindStringSubmatch(code); m != nil {
+			if depth == 0 {
+				info.Symbols = append(info.Symbols, Symbol{Name: m[4], Kind: strings.ToUpper(m[2]), Type: m[3],
+					Local: strings.EqualFold(strings.TrimSpace(m[1]), "LOCAL"), Line: line.Number})
+			}
+			declared[strings.ToUpper(m[4])] = true
+			recordUses(used, m[3], line.Number)
+			if _, value, ok := strings.Cut(code, ":="); ok {
+				recordUses(used, value, line.Number)
+			}
+			continue
+		}
+		recordUses(used, code, line.Number)
+	}
+
+	for key, u := range used {
+		if !declared[key] && !IsBuiltin(key) {
+			info.Refs[u.name] = u.line
+		}
+	}
+	return info
+}
+
+// usage is the first use of a name, keyed by its upper-cased form
+type usage struct {
+	name string
+	line int
+}
+
+// recordUses adds the identifiers in code, skipping strings, named
+// arguments (\Name), record components (.name) and numbers
+func recordUses(used map[string]usage, code string, line int) {
+	code = stringRe.ReplaceAllString(code, `""`)
+	for _, loc := range identRe.FindAllStringIndex(code, -1) {
+		if loc[0] > 0 {
+			prev := code[loc[0]-1]
+			if prev == '\\' || prev == '.' || (prev >= '0' && prev <= '9') {
+				continue
+			}
+		}
+		// A label such as "retry:" is not a reference
+		rest := strings.TrimLeft(code[loc[1]:], " ")
+		if strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, ":=") {
+			continue
+		}
+		name := code[loc[0]:loc[1]]
+		if _, seen := used[strings.ToUpper(name)]; !seen {
+			used[strings.ToUpper(name)] = usage{name, line}
+		}
+	}
+}
+
+// Occurrence is one use of a name on a source line
+type Occurrence struct {
+	Name string
+	Line int
+}
+
+// Occurrences returns every identifier used in code, in source order,
+// skipping comments, strings, named arguments and record components
+func Occurrences(src string) []Occurrence {
+	var out []Occurrence
+	for _, line := range SplitLines(src) {
+		code := stringRe.ReplaceAllString(line.Code, `""`)
+		for _, loc := range identRe.FindAllStringIndex(code, -1) {
+			if loc[0] > 0 {
+				prev := code[loc[0]-1]
+				if prev == '\\' || prev == '.' || (prev >= '0' && prev <= '9') {
+					continue
+				}
+			}
+			out = append(out, Occurrence{code[loc[0]:loc[1]], line.Number})
+		}
+	}
+	return out
+}
+
+// Exported returns the module-level symbols visible to other modules
+func (m ModuleInfo) Exported() []Symbol {
+	var out []Symbol
+	for _, s := range m.Symbols {
+		if !s.Local {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RefNames returns the outside references in sorted order
+func (m ModuleInfo) RefNames() []string {
+	names := make([]string, 0, len(m.Refs))
+	for name := range m.Refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,117 @@
+package rapid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// headerWords start lines that are not statements and need no semicolon
+var headerWords = map[string]bool{
+	"MODULE": true, "ENDMODULE": true, "PROC": true, "ENDPROC": true,
+	"FUNC": true, "ENDFUNC": true, "TRAP": true, "ENDTRAP": true,
+	"RECORD": true, "ENDRECORD": true, "IF": true, "ELSEIF": true,
+	"ELSE": true, "ENDIF": true, "WHILE": true, "ENDWHILE": true,
+	"FOR": true, "ENDFOR": true, "TEST": true, "CASE": true,
+	"DEFAULT": true, "ENDTEST": true, "ERROR": true, "UNDO": true,
+	"BACKWARD": true,
+}
+
+var (
+	labelRe = regexp.MustCompile(`^\w+:$`)
+	// missingCommaRe finds aggregate elements or strings (replaced by \x00)
+	// that follow each other without a comma
+	missingCommaRe = regexp.MustCompile(`[\]\x00]\s*[\[\x00]`)
+)
+
+// CheckSyntax checks the blocks like Lint and also checks every statement: it must end with
+// a semicolon, brackets and quotes must balance, and aggregate elements
+// must be separated by commas. Statements may span lines.
+func CheckSyntax(src string) []Diagnostic {
+	diags := lintBlocks(src)
+	var stmt strings.Builder
+	start := 0
+	for _, line := range SplitLines(src) {
+		code := strings.TrimSpace(line.Code)
+		if code == "" {
+			continue
+		}
+		word := firstWord(code)
+		if word == "LOCAL" || word == "TASK" {
+			word = firstWord(strings.TrimSpace(code[len(word):]))
+		}
+		header := headerWords[word] || labelRe.MatchString(code)
+		if word == "IF" && !strings.HasSuffix(strings.ToUpper(code), "THEN") {
+			// A compact IF is a statement ending in a semicolon
+			header = false
+		}
+		if header {
+			if stmt.Len() > 0 {
+				diags = append(diags, Diagnostic{start, SeverityError, "statement does not end with ;"})
+				stmt.Reset()
+			}
+			diags = append(diags, checkStatement(line.Number, code)...)
+			continue
+		}
+		if stmt.Len() == 0 {
+			start = line.Number
+		}
+		stmt.WriteString(code + " ")
+		if strings.HasSuffix(code, ";") {
+			diags = append(diags, checkStatement(start, stmt.String())...)
+			stmt.Reset()
+		}
+	}
+	if stmt.Len() > 0 {
+		diags = append(diags, Diagnostic{start, SeverityError, "statement does not end with ;"})
+	}
+	return diags
+}
+
+// checkStatement checks the brackets, quotes and commas of one statement
+func checkStatement(number int, code string) []Diagnostic {
+	var diags []Diagnostic
+	var shape strings.Builder
+	inString := false
+	for _, r := range code {
+		if r == '"' {
+			// "" inside a string is an escaped quote and toggles twice
+			if !inString {
+				shape.WriteRune(0)
+			}
+			inString = !inString
+			continue
+		}
+		if !inString {
+			shape.WriteRune(r)
+		}
+	}
+	if inString {
+		return append(diags, Diagnostic{number, SeverityError, "unterminated string"})
+	}
+	s := shape.String()
+	depth := map[rune]int{}
+	for _, r := range s {
+		switch r {
+		case '(', '[':
+			depth[r]++
+		case ')':
+			depth['(']--
+		case ']':
+			depth['[']--
+		}
+		if depth['('] < 0 || depth['['] < 0 {
+			return append(diags, Diagnostic{number, SeverityError, fmt.Sprintf("unexpected %c", r)})
+		}
+	}
+	if depth['('] != 0 {
+		diags = append(diags, Diagnostic{number, SeverityError, "unbalanced parentheses"})
+	}
+	if depth['['] != 0 {
+		diags = append(diags, Diagnostic{number, SeverityError, "unbalanced brackets"})
+	}
+	if missingCommaRe.MatchString(s) {
+		diags = append(diags, Diagnostic{number, SeverityError, "missing comma between aggregate elements"})
+	}
+	return diags
+}
@@ -0,0 +1,127 @@
+package rapid
+
+import "strings"
+
+// TokenKind classifies a token of RAPID source
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenNumber
+	TokenString
+	TokenOp          // operators and punctuation such as := , ; ( [ \
+	TokenPlaceholder // <SMT>, <EXP> and the like left by the FlexPendant
+)
+
+// Token is a word, literal or operator with the line it starts on
+type Token struct {
+	Kind TokenKind
+	Text string
+	Line int
+}
+
+// Is reports whether the token is the keyword or operator s. Keywords are
+// compared case-insensitively, as RAPID does.
+func (t Token) Is(s string) bool {
+	if t.Kind == TokenIdent {
+		return strings.EqualFold(t.Text, s)
+	}
+	return t.Kind == TokenOp && t.Text == s
+}
+
+// twoCharOps are the operators of two characters
+var twoCharOps = map[string]bool{":=": true, "<>": true, "<=": true, ">=": true}
+
+// Tokenize splits RAPID source into tokens, leaving out comments. The last
+// token is always TokenEOF.
+func Tokenize(src string) []Token {
+	var tokens []Token
+	src = strings.TrimPrefix(src, "\uFEFF")
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '!':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			// "" inside a string is an escaped quote; a string ends with its line
+			j := i + 1
+			for j < len(src) && src[j] != '\n' {
+				if src[j] == '"' {
+					if j+1 < len(src) && src[j+1] == '"' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, Token{TokenString, src[i:j], line})
+			i = j
+		case isIdentChar(c) && !(c >= '0' && c <= '9'):
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			tokens = append(tokens, Token{TokenIdent, src[i:j], line})
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '.' || isExponentSign(src[i:j], src[j])) {
+				j++
+			}
+			tokens = append(tokens, Token{TokenNumber, src[i:j], line})
+			i = j
+		case c == '<' && i+1 < len(src) && src[i+1] >= 'A' && src[i+1] <= 'Z':
+			j := strings.IndexByte(src[i:], '>')
+			if j > 0 && isPlaceholder(src[i+1:i+j]) {
+				tokens = append(tokens, Token{TokenPlaceholder, src[i : i+j+1], line})
+				i += j + 1
+				continue
+			}
+			tokens = append(tokens, Token{TokenOp, "<", line})
+			i++
+		default:
+			if i+1 < len(src) && twoCharOps[src[i:i+2]] {
+				tokens = append(tokens, Token{TokenOp, src[i : i+2], line})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, Token{TokenOp, string(c), line})
+			i++
+		}
+	}
+	return append(tokens, Token{TokenEOF, "", line})
+}
+
+// isExponentSign reports whether c is the sign of the exponent of the
+// number that starts with num, as in 1.5E-3
+func isExponentSign(num string, c byte) bool {
+	if c != '+' && c != '-' || strings.HasPrefix(strings.ToLower(num), "0x") {
+		return false
+	}
+	last := num[len(num)-1]
+	return last == 'e' || last == 'E'
+}
+
+// isPlaceholder reports whether s is the name of a placeholder like SMT
+func isPlaceholder(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+// readOnlyFlag is set by the --read-only option
+var readOnlyFlag bool
+
+// readOnlySafe lists what may run in read-only mode, by command and first
+// argument: "" for the command on its own and "*" for any. Commands with
+// --dry-run run as a dry run, so "*" suits those that write files only
+// through writeFile or check dryRun; the RWS client refuses any request
+// that changes a controller. Anything not listed is disabled, so a new
+// command must be added here before it runs in read-only mode.
+var readOnlySafe = map[string][]string{
+	"abb":       {"*"},
+	"advise":    {"*"},
+	"ai":        {"help", "continue", "sessions", "show", "review"},
+	"alarms":    {"*"},
+	"analyze":   {"*"},
+	"approve":   {"", "trusted"},
+	"audit":     {"*"},
+	"calc":      {"*"},
+	"cfg":       {"*"},
+	"config":    {"", "show", "get"},
+	"dashboard": {"*"},
+	"db":        {"", "usage"},
+	"error":     {"*"},
+	"fieldbus":  {"*"},
+	"fleet":     {"*"},
+	"generate":  {"*"},
+	"glossary":  {"*"},
+	"jobs":      {"", "history"},
+	"lint":      {"*"},
+	"modbus":    {"*"},
+	"netplan":   {"*"},
+	"notify":    {"*"},
+	"pack":      {"list"},
+	"plan":      {"*"},
+	"ports":     {"*"},
+	"ra":        {"*"},
+	"regen":     {"*"},
+	"report":    {"*"},
+	"scenario":  {"*"},
+	"secrets":   {"list"},
+	"sensor":    {"*"},
+	"snippet":   {"list", "show"},
+	"stats":     {"*"},
+	"storage":   {"*"},
+	"style":     {"*"},
+	"timecheck": {"*"},
+	"timing":    {"*"},
+	"tour":      {"*"},
+	"undo":      {"--list"},
+	"update":    {"check"},
+	"use":       {""},
+	"vault":     {""},
+	"vcs":       {"pre-commit", "textconv", "changelog"},
+}
+
+// readOnly reports whether commands may only read: with --read-only or
+// a read-only profile
+func readOnly() bool {
+	if readOnlyFlag {
+		return true
+	}
+	_, p := activeProfile()
+	return p.ReadOnly
+}
+
+// readOnlyAllowed reports whether a command line can run in read-only
+// mode
+func readOnlyAllowed(name string, args []string) bool {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	for _, s := range readOnlySafe[name] {
+		if s == "*" || s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// setReadOnly applies read-only mode to the controller clients
+func setReadOnly(on bool) {
+	rws.ReadOnly = on
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z]\w{0,31}$`)
+
+const extractConstUsage = `Usage: abb extract-const <file.mod> [--min 2] [--list] [--yes]
+Finds numeric literals repeated in the code (speeds, offsets, delays) and
+replaces them with named CONST declarations. Each proposal is confirmed
+interactively unless --yes is given; --list only shows the proposals.
+
+Example:
+  abb extract-const MainModule.mod --min 3`
+
+// abbExtractConst turns repeated numeric literals into named constants
+func abbExtractConst(args []string) (string, error) {
+	if len(args) < 1 {
+		return extractConstUsage, nil
+	}
+	path := args[0]
+	minUses, listOnly, yes := 2, false, false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--list":
+			listOnly = true
+		case "--yes":
+			yes = true
+		case "--min":
+			if i+1 >= len(args) {
+				return extractConstUsage, nil
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 2 {
+				return "", errors.New("--min must be a number of at least 2")
+			}
+			minUses = n
+			i++
+		default:
+			return extractConstUsage, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := string(data)
+	proposals := rapid.MagicNumbers(src, minUses)
+	if len(proposals) == 0 {
+		return fmt.Sprintf("No numeric literal is used %d or more times.", minUses), nil
+	}
+
+	if listOnly {
+		var result strings.Builder
+		result.WriteString(fmt.Sprintf("\nRepeated literals in %s:\n", path))
+		for _, c := range proposals {
+			result.WriteString(fmt.Sprintf("  %-8s x%-3d -> CONST num %s (lines %s)\n", c.Value, len(c.Uses), c.Name, useLines(c.Uses)))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+	}
+
+	var accepted []rapid.Constant
+	for _, c := range proposals {
+		if !yes {
+			fmt.Printf("\n%s is used %d times (lines %s)\n", c.Value, len(c.Uses), useLines(c.Uses))
+			answer, ok := readLine(fmt.Sprintf("Replace with CONST num %s? [y]es, [n]o, [r]ename, [q]uit: ", c.Name))
+			if !ok {
+				break
+			}
+			switch strings.ToLower(answer) {
+			case "q", "quit":
+				return applyConstants(path, src, accepted)
+			case "n", "no":
+				continue
+			case "r", "rename":
+				name, ok := readLine("Constant name: ")
+				if !ok || !identifierRe.MatchString(name) {
+					fmt.Println("Not a valid RAPID name, skipped.")
+					continue
+				}
+				c.Name = name
+			case "y", "yes", "":
+			default:
+				continue
+			}
+		}
+		accepted = append(accepted, c)
+	}
+	return applyConstants(path, src, accepted)
+}
+
+func applyConstants(path, src string, consts []rapid.Constant) (string, error) {
+	if len(consts) == 0 {
+		return "No changes made.", nil
+	}
+	out, err := rapid.ApplyConstants(src, consts)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(path, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+	uses := 0
+	for _, c := range consts {
+		uses += len(c.Uses)
+	}
+	return fmt.Sprintf("Added %d constants and replaced %d literals in %s", len(consts), uses, path), nil
+}
+
+// useLines lists the line numbers of literal uses
+func useLines(uses []rapid.NumberUse) string {
+	lines := make([]string, 0, len(uses))
+	last := 0
+	for _, u := range uses {
+		if u.Line != last {
+			lines = append(lines, strconv.Itoa(u.Line))
+			last = u.Line
+		}
+	}
+	return strings.Join(lines, ", ")
+}
+
+const extractProcUsage = `Usage: abb extract-proc <file.mod> --lines <from-to> --name <ProcName> [--output <file>]
+Moves a range of instructions into a new PROC placed after the enclosing
+routine and replaces them with a call. Local data used in the range becomes
+parameters (INOUT when the range changes it).
+
+Example:
+  abb extract-proc MainModule.mod --lines 120-160 --name PickPart`
+
+// abbExtractProc moves a line range into a new routine
+func abbExtractProc(args []string) (string, error) {
+	if len(args) < 5 {
+		return extractProcUsage, nil
+	}
+	path, output := args[0], args[0]
+	from, to := 0, 0
+	name := ""
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return extractProcUsage, nil
+		}
+		opt, value := args[i], args[i+1]
+		i++
+		switch opt {
+		case "--lines":
+			a, b, ok := strings.Cut(value, "-")
+			var errA, errB error
+			from, errA = strconv.Atoi(a)
+			to, errB = strconv.Atoi(b)
+			if !ok || errA != nil || errB != nil {
+				return "", errors.New("--lines expects a range such as 120-160")
+			}
+		case "--name":
+			if !identifierRe.MatchString(value) {
+				return "", fmt.Errorf("not a valid RAPID routine name: %s", value)
+			}
+			name = value
+		case "--output":
+			output = value
+		default:
+			return extractProcUsage, nil
+		}
+	}
+	if name == "" || from == 0 {
+		return extractProcUsage, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	out, params, err := rapid.ExtractProc(string(data), from, to, name)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(output, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+
+	result := fmt.Sprintf("Extracted lines %d-%d of %s into PROC %s", from, to, output, name)
+	if len(params) > 0 {
+		decl := make([]string, len(params))
+		for i, p := range params {
+			decl[i] = p.String()
+		}
+		result += "\nParameters: " + strings.Join(decl, ", ")
+	}
+	return result, nil
+}
+
+const renameTargetsUsage = `Usage: abb rename-targets <file.mod> [other.mod...] [--list] [--yes]
+Finds generically named robtargets (p10, Target_140) and suggests names from
+how they are used: pick and place (gripper closes or opens after the move),
+approach, home and via points. Accepted names are updated in the module and
+in any other modules given that reference the targets.
+
+Example:
+  abb rename-targets Station1.mod MainModule.mod`
+
+// abbRenameTargets renames generic teach points after their role
+func abbRenameTargets(args []string) (string, error) {
+	var files []string
+	listOnly, yes := false, false
+	for _, a := range args {
+		switch a {
+		case "--list":
+			listOnly = true
+		case "--yes":
+			yes = true
+		default:
+			files = append(files, a)
+		}
+	}
+	if len(files) == 0 {
+		return renameTargetsUsage, nil
+	}
+
+	sources := make([]string, len(files))
+	for i, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		sources[i] = string(data)
+	}
+	roles := rapid.InferTargetRoles(sources[0])
+	if len(roles) == 0 {
+		return "No generically named robtargets with a recognizable use found.", nil
+	}
+	if p := activeProject(); p != nil && p.TargetPrefix != "" {
+		for i := range roles {
+			roles[i].Suggested = p.TargetPrefix + strings.TrimPrefix(roles[i].Suggested, "p")
+		}
+	}
+
+	if listOnly {
+		var result strings.Builder
+		result.WriteString(fmt.Sprintf("\nSuggested names for %s:\n", files[0]))
+		for _, r := range roles {
+			result.WriteString(fmt.Sprintf("  %-12s -> %-16s %s\n", r.Target, r.Suggested, r.Reason))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+	}
+
+	var renamed []string
+	for _, r := range roles {
+		name := r.Suggested
+		if !yes {
+			fmt.Printf("\n%s: %s\n", r.Target, r.Reason)
+			answer, ok := readLine(fmt.Sprintf("Rename to %s? [y]es, [n]o, [r]ename, [q]uit: ", name))
+			if !ok {
+				break
+			}
+			switch strings.ToLower(answer) {
+			case "q", "quit":
+				return writeRenamed(files, sources, renamed)
+			case "n", "no":
+				continue
+			case "r", "rename":
+				custom, ok := readLine("New name: ")
+				if !ok || !identifierRe.MatchString(custom) {
+					fmt.Println("Not a valid RAPID name, skipped.")
+					continue
+				}
+				name = custom
+			case "y", "yes", "":
+			default:
+				continue
+			}
+		}
+		for i := range sources {
+			sources[i] = rapid.RenameSymbol(sources[i], r.Target, name)
+		}
+		renamed = append(renamed, r.Target+" -> "+name)
+	}
+	return writeRenamed(files, sources, renamed)
+}
+
+func writeRenamed(files, sources, renamed []string) (string, error) {
+	if len(renamed) == 0 {
+		return "No changes made.", nil
+	}
+	for i, path := range files {
+		if err := writeFile(path, []byte(sources[i]), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Renamed %d robtargets in %s:\n  %s", len(renamed), strings.Join(files, ", "), strings.Join(renamed, "\n  ")), nil
+}
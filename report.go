@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/calib"
+	"github.com/polyfant/automation-helper-cli/cfg"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/pdf"
+	"github.com/polyfant/automation-helper-cli/report"
+)
+
+func init() {
+	commandRegistry["report"] = Command{
+		Group:       groupCode,
+		Description: "Assemble project reports such as the commissioning report",
+		Usage:       reportUsage,
+		Execute:     text(runReport),
+	}
+}
+
+// commissioningTemplateFile overrides report.DefaultCommissioningTemplate
+// when present in the data directory
+const commissioningTemplateFile = "commissioning.tmpl"
+
+const reportUsage = `Usage: report commissioning [--rapid <dir|file.mod>]... [--io-log run.csv]...
+       [--project .] [--serial 6700-501234]... [--backup <backupdir>]
+       [--title ...] [--customer ...] [--cell ...] [--author ...]
+       [--template report.tmpl] [--output report.md|report.pdf]
+Assembles a commissioning report from:
+  --rapid    lint results of the RAPID modules
+  --io-log   I/O test logs written by 'scenario play --log'
+  --serial   calibration logs of 'abb revcounter record' in <project>/calibration
+             (all logs in the project when --project is given without --serial)
+  --backup   products, options and configuration of a controller backup
+Only the requested sections are included. Author and cell default to
+'config set author' and 'config set cell'.
+
+The layout is a Go text/template; start from 'report template' and pass it
+with --template, or save it as templates/` + commissioningTemplateFile + ` in the
+data directory. --output writes markdown, or PDF when the name ends in .pdf.
+
+Example:
+  report commissioning --rapid ./RAPID --io-log io_test.csv --project . --backup ./Backups/SAT --customer "ACME" --output SAT_report.pdf`
+
+func runReport(args []string) (string, error) {
+	if len(args) < 1 {
+		return reportUsage, nil
+	}
+	switch args[0] {
+	case "commissioning":
+		return reportCommissioning(args[1:])
+	case "template":
+		return strings.TrimRight(report.DefaultCommissioningTemplate, "\n"), nil
+	default:
+		return reportUsage, nil
+	}
+}
+
+func reportCommissioning(args []string) (string, error) {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+	data := report.Commissioning{
+		Title:  "Commissioning report",
+		Author: settings.Author,
+		Cell:   settings.Cell,
+		Date:   time.Now().Format("2006-01-02"),
+	}
+	var rapidSources, ioLogs, serials []string
+	project, backup, templatePath, output := "", "", "", ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return reportUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--rapid":
+			rapidSources = append(rapidSources, value)
+		case "--io-log":
+			ioLogs = append(ioLogs, value)
+		case "--serial":
+			serials = append(serials, value)
+		case "--project":
+			project = value
+		case "--backup":
+			backup = value
+		case "--title":
+			data.Title = value
+		case "--customer":
+			data.Customer = value
+		case "--cell":
+			data.Cell = value
+		case "--author":
+			data.Author = value
+		case "--template":
+			templatePath = value
+		case "--output":
+			output = value
+		default:
+			return reportUsage, nil
+		}
+		i++
+	}
+	if len(rapidSources) == 0 && len(ioLogs) == 0 && len(serials) == 0 && project == "" && backup == "" {
+		return reportUsage, nil
+	}
+
+	if len(rapidSources) > 0 {
+		if data.Lint, err = lintSection(rapidSources); err != nil {
+			return "", err
+		}
+	}
+	if len(ioLogs) > 0 {
+		if data.IO, err = ioSection(ioLogs); err != nil {
+			return "", err
+		}
+	}
+	if project != "" || len(serials) > 0 {
+		if project == "" {
+			project = "."
+		}
+		if data.Calibration, err = calibrationSection(project, serials); err != nil {
+			return "", err
+		}
+	}
+	if backup != "" {
+		if data.Config, err = configSection(backup); err != nil {
+			return "", err
+		}
+	}
+
+	name, text, err := commissioningTemplate(templatePath)
+	if err != nil {
+		return "", err
+	}
+	doc, err := report.Render(name, text, &data)
+	if err != nil {
+		return "", fmt.Errorf("template %v", err)
+	}
+
+	var summary strings.Builder
+	for _, s := range data.Sections() {
+		fmt.Fprintf(&summary, "%-22s %s\n", s.Title, s.Status)
+	}
+	if output == "" {
+		return strings.TrimRight(doc, "\n"), nil
+	}
+	content := []byte(doc)
+	if strings.EqualFold(filepath.Ext(output), ".pdf") {
+		content = pdf.Render(doc)
+	}
+	if err := writeFile(output, content, 0644); err != nil {
+		return "", err
+	}
+	return summary.String() + "Wrote " + output, nil
+}
+
+// commissioningTemplate loads the template given, the user's template or
+// the built-in default
+func commissioningTemplate(path string) (string, string, error) {
+	if path == "" {
+		userPath, err := config.Path("templates", commissioningTemplateFile)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := os.Stat(userPath); err != nil {
+			return commissioningTemplateFile, report.DefaultCommissioningTemplate, nil
+		}
+		path = userPath
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Base(path), string(data), nil
+}
+
+// lintSection lints the modules and lists every finding
+func lintSection(sources []string) (*report.Section, error) {
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return nil, err
+	}
+	var body strings.Builder
+	total, affected := 0, 0
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		findings, err := lintSource(path, string(src))
+		if err != nil {
+			return nil, err
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		total += len(findings)
+		affected++
+		fmt.Fprintf(&body, "\n%s:\n\n", filepath.ToSlash(path))
+		for _, f := range findings {
+			fmt.Fprintf(&body, "- %s\n", f)
+		}
+	}
+	s := &report.Section{Title: "Program check"}
+	if total == 0 {
+		s.Status = fmt.Sprintf("No findings in %d modules", len(files))
+		s.Body = s.Status + "."
+		return s, nil
+	}
+	s.Status = fmt.Sprintf("%d findings in %d of %d modules", total, affected, len(files))
+	s.Body = s.Status + ":\n" + strings.TrimRight(body.String(), "\n")
+	return s, nil
+}
+
+// ioSection summarizes scenario logs: every expect and wait_for step
+// passed or failed
+func ioSection(logs []string) (*report.Section, error) {
+	var body strings.Builder
+	body.WriteString("| Test log | Checks | Passed | Failed |\n|---|---:|---:|---:|\n")
+	var failures []string
+	passed, failed := 0, 0
+	for _, path := range logs {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("%s: empty log", path)
+		}
+		cols := make(map[string]int)
+		for i, name := range records[0] {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		resultCol, ok := cols["result"]
+		if !ok {
+			return nil, fmt.Errorf("%s: no result column; expected a log of 'scenario play --log'", path)
+		}
+		field := func(row []string, name string) string {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		p, n := 0, 0
+		for _, row := range records[1:] {
+			if resultCol >= len(row) {
+				continue
+			}
+			switch strings.ToLower(row[resultCol]) {
+			case "pass":
+				p++
+			case "fail":
+				n++
+				failures = append(failures, fmt.Sprintf("%s, cycle %s at %s s: %s %s=%s %s", filepath.Base(path),
+					field(row, "cycle"), field(row, "time_s"), field(row, "kind"), field(row, "signal"), field(row, "value"), field(row, "text")))
+			}
+		}
+		fmt.Fprintf(&body, "| %s | %d | %d | %d |\n", filepath.Base(path), p+n, p, n)
+		passed += p
+		failed += n
+	}
+	if len(failures) > 0 {
+		body.WriteString("\nFailed checks:\n\n")
+		for _, f := range failures {
+			fmt.Fprintf(&body, "- %s\n", strings.TrimSpace(f))
+		}
+	}
+	s := &report.Section{Title: "I/O test", Body: strings.TrimRight(body.String(), "\n")}
+	if failed > 0 {
+		s.Status = fmt.Sprintf("%d of %d checks failed", failed, passed+failed)
+	} else {
+		s.Status = fmt.Sprintf("All %d checks passed", passed)
+	}
+	return s, nil
+}
+
+// calibrationSection lists the reference offsets and the latest event of
+// each robot's calibration log
+func calibrationSection(project string, serials []string) (*report.Section, error) {
+	var paths []string
+	if len(serials) == 0 {
+		found, err := filepath.Glob(filepath.Join(project, "calibration", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no calibration logs in %s; record them with 'abb revcounter record'", filepath.Join(project, "calibration"))
+		}
+		sort.Strings(found)
+		paths = found
+	}
+	for _, serial := range serials {
+		path := calib.LogPath(project, serial)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("no calibration log for %s at %s", serial, path)
+		}
+		paths = append(paths, path)
+	}
+
+	var body strings.Builder
+	missing := 0
+	for _, path := range paths {
+		log, err := calib.LoadLog(path, "")
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&body, "### Robot %s\n\n", log.Serial)
+		ref, ok := log.Reference()
+		if !ok {
+			missing++
+			body.WriteString("No calibration offsets recorded.\n\n")
+			continue
+		}
+		fmt.Fprintf(&body, "Reference: %s, %s (%s)\n\n", ref.Date.Format("2006-01-02"), ref.Event, ref.Source)
+		body.WriteString("| Axis | Offset (rad) |\n|---|---:|\n")
+		for _, name := range sortedOffsetNames(ref.Offsets) {
+			fmt.Fprintf(&body, "| %s | %.4f |\n", name, ref.Offsets[name])
+		}
+		last := log.Entries[len(log.Entries)-1]
+		fmt.Fprintf(&body, "\nLast event: %s, %s", last.Date.Format("2006-01-02"), last.Event)
+		if last.Note != "" {
+			fmt.Fprintf(&body, " (%s)", last.Note)
+		}
+		fmt.Fprintf(&body, "; %d events recorded.\n\n", len(log.Entries))
+	}
+	s := &report.Section{Title: "Calibration", Body: strings.TrimRight(body.String(), "\n")}
+	s.Status = fmt.Sprintf("%d robots recorded", len(paths)-missing)
+	if missing > 0 {
+		s.Status += fmt.Sprintf(", %d without offsets", missing)
+	}
+	return s, nil
+}
+
+// configSection summarizes the products, options and configuration of a
+// backup
+func configSection(backup string) (*report.Section, error) {
+	domains, info, err := loadConfig(backup)
+	if err != nil {
+		return nil, err
+	}
+	var body strings.Builder
+	if id := info["SYSTEM_ID"]; len(id) > 0 {
+		fmt.Fprintf(&body, "System: %s\n\n", id[0])
+	}
+	for _, section := range optionSections(info) {
+		fmt.Fprintf(&body, "%s:\n\n", strings.ReplaceAll(section, "_", " "))
+		for _, line := range info[section] {
+			fmt.Fprintf(&body, "- %s\n", line)
+		}
+		body.WriteString("\n")
+	}
+	names := sortedDomains(domains)
+	for _, name := range names {
+		fmt.Fprintf(&body, "```\n%s```\n\n", cfg.Report(domains[name]))
+	}
+	return &report.Section{
+		Title:  "Configuration",
+		Status: fmt.Sprintf("Backup %s, %d configuration domains", filepath.Base(filepath.Clean(backup)), len(names)),
+		Body:   strings.TrimRight(body.String(), "\n"),
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Package report assembles project documents such as the commissioning
+// report from markdown sections and a user-editable template
+package report
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Section is one part of a report with its result for the summary table.
+// Body is markdown.
+type Section struct {
+	Title  string
+	Status string
+	Body   string
+}
+
+// Commissioning is the data available to commissioning report templates.
+// Sections that were not requested are nil.
+type Commissioning struct {
+	Title    string
+	Customer string
+	Cell     string
+	Author   string
+	Date     string
+
+	Lint        *Section
+	IO          *Section
+	Calibration *Section
+	Config      *Section
+}
+
+// Sections returns the sections present, in report order
+func (c *Commissioning) Sections() []*Section {
+	var sections []*Section
+	for _, s := range []*Section{c.Lint, c.IO, c.Calibration, c.Config} {
+		if s != nil {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// DefaultCommissioningTemplate lays out the report with a summary table,
+// every section and a sign-off table
+const DefaultCommissioningTemplate = `# {{.Title}}
+
+| Field | Value |
+|---|---|
+| Customer | {{.Customer}} |
+| Cell | {{.Cell}} |
+| Prepared by | {{.Author}} |
+| Date | {{.Date}} |
+
+## Summary
+
+| Item | Result |
+|---|---|
+{{range .Sections}}| {{.Title}} | {{.Status}} |
+{{end}}
+{{- range .Sections}}
+## {{.Title}}
+
+{{.Body}}
+{{end}}
+## Sign-off
+
+| Role | Name | Signature | Date |
+|---|---|---|---|
+| Integrator |  |  |  |
+| Customer |  |  |  |
+`
+
+// Render fills a template with the report data
+func Render(name, text string, data interface{}) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
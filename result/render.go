@@ -0,0 +1,228 @@
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Renderer turns a result into output for one kind of consumer
+type Renderer interface {
+	Render(r Result) string
+}
+
+// The built-in renderers
+var (
+	Plain    Renderer = textRenderer{}
+	Color    Renderer = textRenderer{color: true}
+	JSON     Renderer = jsonRenderer{}
+	Markdown Renderer = markdownRenderer{}
+)
+
+// Renderers maps format names to renderers; integrations can add their own
+var Renderers = map[string]Renderer{
+	"plain":    Plain,
+	"color":    Color,
+	"json":     JSON,
+	"markdown": Markdown,
+}
+
+// ANSI colors of the color renderer
+const (
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+	ansiReset  = "\033[0m"
+)
+
+// textRenderer renders for a terminal, with ANSI colors when color is set.
+// Blocks are separated by a blank line; diagnostics of several files are
+// listed together.
+type textRenderer struct {
+	color bool
+}
+
+func (t textRenderer) paint(code, s string) string {
+	if !t.color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (t textRenderer) Render(r Result) string {
+	var b strings.Builder
+	for i, block := range r.Blocks {
+		if i > 0 {
+			_, prevDiag := r.Blocks[i-1].(Diagnostics)
+			_, diag := block.(Diagnostics)
+			if prevDiag && diag {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		t.block(&b, block)
+	}
+	if r.Failed() {
+		if len(r.Blocks) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(t.paint(ansiRed, r.errorText()))
+	}
+	return b.String()
+}
+
+func (t textRenderer) block(b *strings.Builder, block Block) {
+	switch v := block.(type) {
+	case Text:
+		if v.Title != "" {
+			b.WriteString(t.paint(ansiBold, v.Title+":") + "\n")
+		}
+		b.WriteString(v.Body)
+	case Code:
+		if v.Title != "" {
+			b.WriteString(t.paint(ansiBold, v.Title+":") + "\n")
+		}
+		b.WriteString(t.paint(ansiCyan, strings.TrimRight(v.Source, "\n")))
+	case Table:
+		if v.Title != "" {
+			b.WriteString(t.paint(ansiBold, v.Title+":") + "\n")
+		}
+		widths := make([]int, len(v.Columns))
+		for c, name := range v.Columns {
+			widths[c] = len(name)
+		}
+		for _, row := range v.Rows {
+			for c, cell := range row {
+				if c < len(widths) && len(cell) > widths[c] {
+					widths[c] = len(cell)
+				}
+			}
+		}
+		line := func(cells []string, style string) {
+			var l strings.Builder
+			for c, cell := range cells {
+				if c > 0 {
+					l.WriteString("  ")
+				}
+				if c < len(cells)-1 && c < len(widths) {
+					fmt.Fprintf(&l, "%-*s", widths[c], cell)
+				} else {
+					l.WriteString(cell)
+				}
+			}
+			b.WriteString(t.paint(style, strings.TrimRight(l.String(), " ")))
+		}
+		line(v.Columns, ansiBold)
+		for _, row := range v.Rows {
+			b.WriteString("\n")
+			line(row, "")
+		}
+	case Diagnostics:
+		if len(v.Items) == 0 {
+			fmt.Fprintf(b, "%s: no problems found", v.File)
+			return
+		}
+		for i, d := range v.Items {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			severity := d.Severity
+			switch d.Severity {
+			case "error":
+				severity = t.paint(ansiRed, severity)
+			case "warning":
+				severity = t.paint(ansiYellow, severity)
+			}
+			fmt.Fprintf(b, "%s: line %d: %s: %s", v.File, d.Line, severity, d.Message)
+		}
+	}
+}
+
+// jsonRenderer renders {"blocks": [{"kind": ..., ...}], "error": ...}
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(r Result) string {
+	out := struct {
+		Blocks []map[string]interface{} `json:"blocks"`
+		Error  string                   `json:"error,omitempty"`
+	}{Blocks: []map[string]interface{}{}, Error: strings.TrimPrefix(r.Err, "Error: ")}
+	for _, block := range r.Blocks {
+		data, err := json.Marshal(block)
+		if err != nil {
+			continue
+		}
+		m := map[string]interface{}{}
+		if json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		m["kind"] = block.Kind()
+		out.Blocks = append(out.Blocks, m)
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(out)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// markdownRenderer renders for chat and reports: fenced code, pipe tables
+// and diagnostic lists
+type markdownRenderer struct{}
+
+func (markdownRenderer) Render(r Result) string {
+	var parts []string
+	for _, block := range r.Blocks {
+		var b strings.Builder
+		switch v := block.(type) {
+		case Text:
+			if v.Title != "" {
+				fmt.Fprintf(&b, "**%s**\n\n", v.Title)
+			}
+			b.WriteString(v.Body)
+		case Code:
+			if v.Title != "" {
+				fmt.Fprintf(&b, "**%s**\n\n", v.Title)
+			}
+			fmt.Fprintf(&b, "```%s\n%s\n```", v.Lang, strings.TrimRight(v.Source, "\n"))
+		case Table:
+			if v.Title != "" {
+				fmt.Fprintf(&b, "**%s**\n\n", v.Title)
+			}
+			cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+			row := func(cells []string) {
+				b.WriteString("|")
+				for _, c := range cells {
+					b.WriteString(" " + cell(c) + " |")
+				}
+			}
+			row(v.Columns)
+			b.WriteString("\n|")
+			for range v.Columns {
+				b.WriteString(" --- |")
+			}
+			for _, r := range v.Rows {
+				b.WriteString("\n")
+				row(r)
+			}
+		case Diagnostics:
+			if len(v.Items) == 0 {
+				fmt.Fprintf(&b, "`%s`: no problems found", v.File)
+				break
+			}
+			for i, d := range v.Items {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(&b, "- `%s:%d` **%s**: %s", v.File, d.Line, d.Severity, d.Message)
+			}
+		}
+		parts = append(parts, b.String())
+	}
+	if r.Failed() {
+		parts = append(parts, "**Error:** "+strings.TrimPrefix(r.errorText(), "Error: "))
+	}
+	return strings.Join(parts, "\n\n")
+}
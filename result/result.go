@@ -0,0 +1,101 @@
+// Package result is the structured output of commands: text, code, tables
+// and diagnostics, which renderers turn into plain text, colored terminal
+// output, JSON or markdown. The REPL, the REST API and the chat bots share
+// the same results instead of parsing formatted strings.
+package result
+
+import "fmt"
+
+// Block is one part of a result
+type Block interface {
+	Kind() string
+}
+
+// Text is a paragraph of text with an optional title
+type Text struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body"`
+}
+
+// Code is source code or a file's content, such as generated RAPID
+type Code struct {
+	Title  string `json:"title,omitempty"`
+	Lang   string `json:"lang,omitempty"` // rapid, st, csv, json, ...
+	Source string `json:"source"`
+}
+
+// Table is rows of values under column headings
+type Table struct {
+	Title   string     `json:"title,omitempty"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Diagnostic is a problem found in a file
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"` // error or warning
+	Message  string `json:"message"`
+}
+
+// Diagnostics are the problems found in one file; none means the file is
+// clean
+type Diagnostics struct {
+	File  string       `json:"file"`
+	Items []Diagnostic `json:"items"`
+}
+
+func (Text) Kind() string        { return "text" }
+func (Code) Kind() string        { return "code" }
+func (Table) Kind() string       { return "table" }
+func (Diagnostics) Kind() string { return "diagnostics" }
+
+// Result is the output of a command: blocks shown in order, and an error
+// when the command failed
+type Result struct {
+	Blocks []Block
+	Err    string
+}
+
+// New returns a result with the blocks
+func New(blocks ...Block) Result {
+	return Result{Blocks: blocks}
+}
+
+// Errorf returns a failed result
+func Errorf(format string, a ...interface{}) Result {
+	return Result{Err: fmt.Sprintf(format, a...)}
+}
+
+// FromString turns the formatted output of a command into a result: the
+// text, if any, and the error when the command failed
+func FromString(s string, err error) Result {
+	var r Result
+	if s != "" {
+		r.Add(Text{Body: s})
+	}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	return r
+}
+
+// Add appends blocks
+func (r *Result) Add(blocks ...Block) {
+	r.Blocks = append(r.Blocks, blocks...)
+}
+
+// Failed reports whether the command failed
+func (r Result) Failed() bool {
+	return r.Err != ""
+}
+
+// String renders the result as plain text
+func (r Result) String() string {
+	return Plain.Render(r)
+}
+
+// errorText is the error as shown to the user
+func (r Result) errorText() string {
+	return "Error: " + r.Err
+}
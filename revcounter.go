@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/calib"
+)
+
+const revcounterUsage = `Usage: abb revcounter <guide|record|check|log|window> ...
+  guide [MOC.cfg|backup]                 - Update procedure, with offsets, axis ranges and sync windows
+  record <serial> --from <MOC.cfg|backup> - Log the calibration offsets of a backup
+  record <serial> --offsets 4.7120,...    - Log the offsets from the calibration label
+  check <serial> <MOC.cfg|backup>        - Compare offsets with the recorded reference
+  log <serial>                           - Show the calibration history
+  window <gear ratio>...                 - Sync mark window in arm degrees
+
+Options for record, check and log:
+  --project <dir>  Project folder holding calibration/<serial>.json (default .)
+  --event <name>   label, backup, battery, revcounter or calibration (record)
+  --note <text>    Free text such as "SMB battery replaced" (record)
+  --robot <name>   Axis name prefix for --offsets (default rob1)
+
+Examples:
+  abb revcounter guide ./Backups/2024-05-02
+  abb revcounter record 6700-501234 --offsets 4.7120,1.2503,0.8830,5.0021,2.9942,3.1102 --event label
+  abb revcounter record 6700-501234 --from ./Backups/2024-05-02 --event battery --note "SMB battery replaced"
+  abb revcounter check 6700-501234 ./Backups/2024-09-12`
+
+// abbRevCounter dispatches the revolution counter and calibration log tools
+func abbRevCounter(args []string) (string, error) {
+	if len(args) < 1 {
+		return revcounterUsage, nil
+	}
+	sub := args[0]
+	var positional []string
+	opts := map[string]string{"project": ".", "robot": "rob1"}
+	for i := 1; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "--") {
+			if i+1 >= len(args) {
+				return revcounterUsage, nil
+			}
+			opts[strings.TrimPrefix(args[i], "--")] = args[i+1]
+			i++
+			continue
+		}
+		positional = append(positional, args[i])
+	}
+
+	switch sub {
+	case "guide":
+		if len(positional) == 0 {
+			return strings.TrimRight(calib.Guide(nil), "\n"), nil
+		}
+		axes, err := mocAxes(positional[0])
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(calib.Guide(axes), "\n"), nil
+
+	case "window":
+		if len(positional) == 0 {
+			return revcounterUsage, nil
+		}
+		var result strings.Builder
+		for _, arg := range positional {
+			ratio, err := strconv.ParseFloat(arg, 64)
+			if err != nil || ratio == 0 {
+				return "", fmt.Errorf("invalid gear ratio %q", arg)
+			}
+			half, rev := calib.Axis{Ratio: math.Abs(ratio)}.Window()
+			fmt.Fprintf(&result, "Ratio %s: align within ±%.3f°, one motor revolution = %.3f° at the arm\n", arg, half, rev)
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	case "record", "check", "log":
+		if len(positional) < 1 {
+			return revcounterUsage, nil
+		}
+		serial := positional[0]
+		path := calib.LogPath(opts["project"], serial)
+		log, err := calib.LoadLog(path, serial)
+		if err != nil {
+			return "", err
+		}
+		switch sub {
+		case "record":
+			return recordCalibration(log, path, opts)
+		case "check":
+			if len(positional) < 2 {
+				return revcounterUsage, nil
+			}
+			return checkCalibration(log, positional[1])
+		default:
+			return formatCalibrationLog(log), nil
+		}
+
+	default:
+		return revcounterUsage, nil
+	}
+}
+
+// mocAxes reads the calibrated axes from a MOC.cfg file or a backup
+func mocAxes(source string) ([]calib.Axis, error) {
+	domains, _, err := loadConfig(source)
+	if err != nil {
+		return nil, err
+	}
+	moc, ok := domains["MOC"]
+	if !ok {
+		return nil, fmt.Errorf("%s has no MOC configuration", source)
+	}
+	axes := calib.FromMOC(moc)
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("%s has no MOTOR_CALIB offsets", source)
+	}
+	return axes, nil
+}
+
+// recordCalibration appends an entry with offsets from a backup or the label
+func recordCalibration(log *calib.Log, path string, opts map[string]string) (string, error) {
+	entry := calib.Entry{Date: time.Now().UTC().Truncate(time.Second), Event: opts["event"], Note: opts["note"]}
+	switch {
+	case opts["from"] != "":
+		axes, err := mocAxes(opts["from"])
+		if err != nil {
+			return "", err
+		}
+		entry.Source = opts["from"]
+		entry.Offsets = calib.Offsets(axes)
+		if entry.Event == "" {
+			entry.Event = "backup"
+		}
+	case opts["offsets"] != "":
+		offsets, err := calib.ParseOffsets(opts["offsets"], opts["robot"])
+		if err != nil {
+			return "", err
+		}
+		entry.Source = "label"
+		entry.Offsets = offsets
+		if entry.Event == "" {
+			entry.Event = "label"
+		}
+	case entry.Note == "":
+		return "", errors.New("record needs --from, --offsets or at least a --note")
+	}
+
+	var result strings.Builder
+	if ref, ok := log.Reference(); ok && len(entry.Offsets) > 0 {
+		for _, f := range calib.Validate(ref.Offsets, entry.Offsets) {
+			result.WriteString("Warning: " + f.String() + "\n")
+		}
+	}
+	log.Entries = append(log.Entries, entry)
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := makeDir(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	if err := writeFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&result, "Recorded %s entry %d in %s", entry.Event, len(log.Entries), path)
+	return result.String(), nil
+}
+
+// checkCalibration validates the offsets of a backup against the reference
+func checkCalibration(log *calib.Log, source string) (string, error) {
+	axes, err := mocAxes(source)
+	if err != nil {
+		return "", err
+	}
+	ref, ok := log.Reference()
+	var reference map[string]float64
+	if ok {
+		reference = ref.Offsets
+	}
+	findings := calib.Validate(reference, calib.Offsets(axes))
+	for _, a := range axes {
+		if !a.Valid {
+			findings = append(findings, calib.Finding{Axis: a.Name, Message: "calibration offset not marked valid"})
+		}
+	}
+
+	var result strings.Builder
+	if ok {
+		origin := "calibration label"
+		if ref.Source != "label" {
+			origin = ref.Event + " from " + ref.Source
+		}
+		fmt.Fprintf(&result, "Reference: %s, recorded %s\n", origin, ref.Date.Format("2006-01-02"))
+	} else {
+		result.WriteString("No reference recorded for " + log.Serial + "; only the offset ranges were checked\n")
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(&result, "%d axes match", len(axes))
+		return result.String(), nil
+	}
+	for _, f := range findings {
+		result.WriteString("  " + f.String() + "\n")
+	}
+	return "", fmt.Errorf("calibration offsets do not match\n%s", strings.TrimRight(result.String(), "\n"))
+}
+
+// formatCalibrationLog lists the recorded events, oldest first
+func formatCalibrationLog(log *calib.Log) string {
+	if len(log.Entries) == 0 {
+		return "No calibration events recorded for " + log.Serial + "."
+	}
+	var result strings.Builder
+	fmt.Fprintf(&result, "Calibration log for %s:\n", log.Serial)
+	for _, e := range log.Entries {
+		fmt.Fprintf(&result, "\n%s  %-11s %s\n", e.Date.Format("2006-01-02 15:04"), e.Event, e.Source)
+		if e.Note != "" {
+			fmt.Fprintf(&result, "  %s\n", e.Note)
+		}
+		var offsets []string
+		for _, name := range sortedOffsetNames(e.Offsets) {
+			offsets = append(offsets, fmt.Sprintf("%s=%.4f", name, e.Offsets[name]))
+		}
+		if len(offsets) > 0 {
+			result.WriteString("  " + strings.Join(offsets, " ") + "\n")
+		}
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+func sortedOffsetNames(offsets map[string]float64) []string {
+	names := make([]string, 0, len(offsets))
+	for name := range offsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
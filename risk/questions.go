@@ -0,0 +1,49 @@
+// Package risk drafts a risk register for a robot cell from a questionnaire
+// oriented on ISO 10218-2 and ISO/TS 15066. The result is an aid for the
+// safety engineer, not a risk assessment or a certification.
+package risk
+
+import "github.com/polyfant/automation-helper-cli/wizard"
+
+// Questions is the questionnaire in the order it is asked
+var Questions = []wizard.Question{
+	{ID: "cell", Text: "Cell name", Kind: wizard.Text},
+	{ID: "robot", Text: "Robot model", Kind: wizard.Text, Default: "unknown"},
+	{ID: "payload", Text: "Heaviest payload including gripper (kg)", Kind: wizard.Number, Default: "10"},
+	{ID: "speed", Text: "Highest TCP speed in automatic mode (mm/s)", Kind: wizard.Number, Default: "1500"},
+	{ID: "mode", Text: "Safeguarding concept", Kind: wizard.Choice, Choices: []string{"fenced", "collaborative", "mixed"}, Default: "fenced",
+		Help: "fenced: people never share the working space in automatic; collaborative: people work next to the moving robot"},
+	{ID: "collab", Text: "Collaborative operation modes (ISO 10218-1 5.10)", Kind: wizard.Multi, When: "mode=collaborative,mixed",
+		Choices: []string{"sms", "hg", "ssm", "pfl"},
+		Help:    "sms: safety-rated monitored stop, hg: hand guiding, ssm: speed and separation monitoring, pfl: power and force limiting"},
+	{ID: "regions", Text: "Body regions the robot may contact", Kind: wizard.Multi, When: "collab=pfl",
+		Choices: []string{"hand", "lower_arm", "upper_arm", "shoulder", "chest", "abdomen", "thigh", "lower_leg", "face", "neck"}},
+	{ID: "stop_time", Text: "Robot stopping time at the highest speed (s)", Kind: wizard.Number, Default: "0.5", When: "collab=ssm",
+		Help: "Measure it or take category 1 stopping data from the product specification"},
+	{ID: "stop_distance", Text: "Robot stopping distance at the highest speed (mm)", Kind: wizard.Number, Default: "400", When: "collab=ssm"},
+	{ID: "sensor_time", Text: "Reaction time of the sensing device, safety controller and robot until stopping starts (s)", Kind: wizard.Number, Default: "0.1", When: "collab=ssm"},
+	{ID: "loading", Text: "Do operators load or unload parts inside the safeguarded space", Kind: wizard.YesNo, Default: "no"},
+	{ID: "station", Text: "Loading station", Kind: wizard.Choice, When: "loading",
+		Choices: []string{"turntable", "light_curtain", "door", "none"}, Default: "light_curtain"},
+	{ID: "entries", Text: "How often do people enter the cell", Kind: wizard.Choice, Choices: []string{"rarely", "hourly", "cycle"}, Default: "rarely",
+		Help: "rarely: maintenance only, hourly: several times per shift, cycle: every cycle"},
+	{ID: "tool", Text: "End effector hazards", Kind: wizard.Multi,
+		Choices: []string{"gripper", "vacuum", "sharp", "welding", "cutting", "dispensing", "screwdriving", "hot"}, Default: "gripper"},
+	{ID: "energy", Text: "Stored energy in the cell", Kind: wizard.Multi, Choices: []string{"pneumatic", "hydraulic", "spring", "gravity_axis"}},
+	{ID: "teach", Text: "Is the robot taught or jogged with people inside the cell", Kind: wizard.YesNo, Default: "yes"},
+	{ID: "safemove", Text: "Are safety-rated zones or speed limits used (SafeMove or similar)", Kind: wizard.YesNo, Default: "no"},
+	{ID: "external", Text: "Other machines in the cell (conveyors, presses, positioners)", Kind: wizard.YesNo, Default: "no"},
+}
+
+// Answers maps question IDs to answers
+type Answers = wizard.Answers
+
+// Find returns the question with the given ID
+func Find(id string) (wizard.Question, bool) {
+	for _, q := range Questions {
+		if q.ID == id {
+			return q, true
+		}
+	}
+	return wizard.Question{}, false
+}
@@ -0,0 +1,350 @@
+package risk
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+// Disclaimer heads every draft register
+const Disclaimer = "DRAFT - aid for the risk assessment, not a risk assessment or certification. " +
+	"The safety engineer must review every entry, add hazards specific to the cell and " +
+	"verify the measures against ISO 10218-2, ISO/TS 15066 and ISO 13849."
+
+// Hazard is one entry of the risk register. S, F and P are the risk graph
+// parameters of ISO 13849-1 Annex A.
+type Hazard struct {
+	ID          string
+	Hazard      string
+	Situation   string
+	Consequence string
+	S, F, P     int
+	Measures    []string
+	Reference   string
+}
+
+// PLr returns the required performance level from the risk graph
+func (h Hazard) PLr() string {
+	return PLr(h.S, h.F, h.P)
+}
+
+// PLr maps severity (1 slight, 2 serious), frequency of exposure (1 seldom,
+// 2 frequent) and possibility of avoidance (1 possible, 2 scarcely) to the
+// required performance level a to e
+func PLr(s, f, p int) string {
+	return string(rune('a' + 2*(s-1) + (f - 1) + (p - 1)))
+}
+
+// Assumptions for the protective separation distance of ISO/TS 15066 5.5.4
+const (
+	humanSpeed = 1600.0 // mm/s, walking speed of ISO 13855
+	intrusion  = 850.0  // mm, reach of an arm over the detection zone
+	uncertain  = 100.0  // mm, position uncertainty of sensor and robot
+)
+
+// SeparationDistance returns the protective separation distance in mm for
+// speed and separation monitoring
+func SeparationDistance(a Answers) float64 {
+	tr, ts := a.Number("sensor_time"), a.Number("stop_time")
+	return humanSpeed*(tr+ts) + a.Number("speed")*tr + a.Number("stop_distance") + intrusion + uncertain
+}
+
+// Assess drafts the hazards that follow from the answers
+func Assess(a Answers) []Hazard {
+	var list []Hazard
+	add := func(h Hazard) {
+		h.ID = fmt.Sprintf("H%02d", len(list)+1)
+		list = append(list, h)
+	}
+	exposure := 1
+	if a["entries"] == "hourly" || a["entries"] == "cycle" {
+		exposure = 2
+	}
+	avoid := 1
+	if a.Number("speed") > 250 {
+		avoid = 2
+	}
+	collaborative := a["mode"] == "collaborative" || a["mode"] == "mixed"
+
+	if a["mode"] != "collaborative" {
+		add(Hazard{
+			Hazard:      "Impact and crushing by the moving robot",
+			Situation:   "Person in the robot working space during automatic operation",
+			Consequence: "Serious injury or death",
+			S:           2, F: exposure, P: avoid,
+			Measures: []string{
+				"Fixed guards with heights and distances per ISO 13857",
+				"Interlocked doors (ISO 14119) causing a protective stop; guard locking when the robot cannot stop before a person reaches it",
+				"Reset only from outside the cell with a view of the whole cell",
+			},
+			Reference: "ISO 10218-2 safeguarding, ISO 14120, ISO 14119, ISO 13857",
+		})
+	}
+
+	if a.Yes("loading") {
+		h := Hazard{
+			Hazard:      "Robot reaches the operator at the loading station",
+			Situation:   "Operator loads or unloads parts while the robot works",
+			Consequence: "Serious injury",
+			S:           2, F: 2, P: avoid,
+			Reference: "ISO 10218-2, ISO 13855",
+		}
+		switch a["station"] {
+		case "turntable":
+			h.Measures = []string{
+				"Partition wall on the turntable separating operator and robot side",
+				"Safety-rated position monitoring of the turntable; rotation only with the loading side clear",
+				"Shearing and crushing points between table and frame guarded or at least 500 mm apart",
+			}
+		case "light_curtain":
+			h.Measures = []string{
+				"Light curtain at a safety distance S = K*T + C per ISO 13855 (K 2000 mm/s, 1600 mm/s when S > 500 mm)",
+				"Safety-rated zone keeping the robot out of the loading area while the light curtain is interrupted",
+				"No muting of the light curtain while the robot can reach the station",
+			}
+		case "door":
+			h.Measures = []string{
+				"Interlocked loading door; the robot stays out of the loading area while the door is open",
+				"Guard locking when the stopping time exceeds the time to reach the hazard",
+			}
+		default:
+			h.P = 2
+			h.Measures = []string{"No protective device at the loading point: add a light curtain, door or turntable"}
+		}
+		add(h)
+	}
+
+	if collaborative {
+		if a.Has("collab", "sms") {
+			add(Hazard{
+				Hazard:      "Robot moves while the operator is in the collaborative workspace",
+				Situation:   "Operator enters the collaborative workspace for a task",
+				Consequence: "Serious injury",
+				S:           2, F: exposure, P: avoid,
+				Measures: []string{
+					"Safety-rated monitored stop (standstill monitoring) on presence detection",
+					"Automatic resume only after the operator has left the workspace",
+				},
+				Reference: "ISO 10218-1 safety-rated monitored stop, ISO/TS 15066 5.5.2",
+			})
+		}
+		if a.Has("collab", "hg") {
+			add(Hazard{
+				Hazard:      "Unexpected motion during hand guiding",
+				Situation:   "Operator guides the robot by hand",
+				Consequence: "Crushing or impact",
+				S:           2, F: exposure, P: 1,
+				Measures: []string{
+					"Hand guiding device near the end effector with emergency stop and enabling device",
+					"Safety-rated reduced speed while guiding",
+				},
+				Reference: "ISO 10218-1 hand guiding, ISO/TS 15066 5.5.3",
+			})
+		}
+		if a.Has("collab", "ssm") {
+			add(Hazard{
+				Hazard:      "Contact because the separation distance is too small",
+				Situation:   "Operator approaches the moving robot",
+				Consequence: "Impact at full speed",
+				S:           2, F: exposure, P: avoid,
+				Measures: []string{
+					fmt.Sprintf("Protective separation distance about %.0f mm (ISO/TS 15066 5.5.4, assuming human speed %.0f mm/s, intrusion %.0f mm, uncertainties %.0f mm)",
+						SeparationDistance(a), humanSpeed, intrusion, uncertain),
+					"Measure the stopping time and distance on the robot with the actual payload",
+					"Safety-rated speed monitoring tied to the measured separation",
+				},
+				Reference: "ISO/TS 15066 5.5.4, ISO 13855",
+			})
+		}
+		if a.Has("collab", "pfl") {
+			var limits []string
+			for _, region := range strings.Split(a["regions"], ",") {
+				if r, ok := calc.FindBodyRegion(region); ok {
+					limits = append(limits, fmt.Sprintf("%s %.0f N", region, r.Force))
+				}
+			}
+			measures := []string{
+				"Measure contact forces and pressures at every possible contact point with the actual tool",
+				"Quasi-static limits (ISO/TS 15066 Table A.2): " + strings.Join(limits, ", ") + "; transient contact up to twice the value",
+				"No clamping against fixed objects where the robot cannot back off",
+			}
+			if a.Has("regions", "face") || a.Has("regions", "neck") {
+				measures = append(measures, "Contact with face, head and neck must be prevented by the design of the cell")
+			}
+			if a.Number("payload") > 10 {
+				measures = append(measures, fmt.Sprintf("Payload %.0f kg makes power and force limiting hard to achieve; consider speed and separation monitoring", a.Number("payload")))
+			}
+			add(Hazard{
+				Hazard:      "Contact exceeding biomechanical limits",
+				Situation:   "Robot contacts the operator during collaborative operation",
+				Consequence: "Bruising, fractures",
+				S:           1, F: 2, P: 1,
+				Measures:  measures,
+				Reference: "ISO/TS 15066 5.5.5 and Annex A",
+			})
+		}
+	}
+
+	toolHazards := map[string]Hazard{
+		"gripper": {Hazard: "Crushing of fingers in the gripper jaws", Situation: "Manual loading of the gripper, teaching, faults",
+			Consequence: "Finger injury", S: 1, F: exposure, P: 1,
+			Measures: []string{"Limit grip force and stroke", "Jaw gaps below 4 mm or above 25 mm (ISO 13854)"}},
+		"vacuum": {Hazard: "Part drops on loss of vacuum", Situation: "Compressed air or power failure, worn cups",
+			Consequence: "Impact by falling part", S: 2, F: exposure, P: 2,
+			Measures: []string{"Vacuum monitoring with a safe stop", "Check valves or ejector with hold function", "No transport over walkways"}},
+		"sharp": {Hazard: "Cuts and punctures by a sharp tool or part", Situation: "Any contact with the end effector",
+			Consequence: "Serious cuts", S: 2, F: exposure, P: avoid,
+			Measures: []string{"Guard or cover sharp edges", "Power and force limiting is not a sufficient measure for sharp tools"}},
+		"welding": {Hazard: "Arc radiation, fumes, spatter and burns", Situation: "Welding in automatic operation",
+			Consequence: "Eye damage, burns, respiratory harm", S: 2, F: exposure, P: 1,
+			Measures: []string{"Welding screens and curtains", "Fume extraction", "Fire protection and cooling-down time before entry"}},
+		"cutting": {Hazard: "Cutting tool, ejected chips or fragments", Situation: "Spindle or blade running",
+			Consequence: "Serious cuts, eye injury", S: 2, F: exposure, P: 2,
+			Measures: []string{"Enclosure strong enough for ejected parts", "Spindle stop and run-down monitoring before entry"}},
+		"dispensing": {Hazard: "Material under pressure, hot or hazardous media", Situation: "Nozzle cleaning, hose failure",
+			Consequence: "Injection injury, chemical exposure", S: 2, F: exposure, P: 1,
+			Measures: []string{"Pressure relief before maintenance", "Safety data sheets and PPE", "Extraction of vapours"}},
+		"screwdriving": {Hazard: "Entanglement at the rotating spindle", Situation: "Operator near the screwdriver",
+			Consequence: "Hand and finger injury", S: 1, F: exposure, P: 1,
+			Measures: []string{"Spindle guard", "Torque reaction limited or guarded"}},
+		"hot": {Hazard: "Burns from hot surfaces", Situation: "Contact with tool or part",
+			Consequence: "Burns", S: 1, F: exposure, P: 1,
+			Measures: []string{"Insulation or guards on surfaces above 60 C (ISO 13732-1)", "Warning signs"}},
+	}
+	for _, tool := range strings.Split(a["tool"], ",") {
+		if h, ok := toolHazards[tool]; ok {
+			h.Reference = "ISO 10218-2 end effector hazards, ISO 12100"
+			add(h)
+		}
+	}
+
+	if a.Number("payload") > 0 {
+		add(Hazard{
+			Hazard:      "Part or tool thrown or dropped",
+			Situation:   fmt.Sprintf("Loss of grip at up to %.0f mm/s with %.0f kg", a.Number("speed"), a.Number("payload")),
+			Consequence: "Impact by projectile",
+			S:           2, F: exposure, P: 2,
+			Measures: []string{
+				"Gripper holding the part on loss of energy",
+				"Guards designed for the impact energy of the heaviest part at the highest speed",
+			},
+			Reference: "ISO 10218-2, ISO 14120",
+		})
+	}
+
+	energies := map[string]string{
+		"pneumatic":    "Exhaust valves with lockout, pressure indication (ISO 4414)",
+		"hydraulic":    "Accumulator discharge and lockout (ISO 4413)",
+		"spring":       "Release or block springs before maintenance",
+		"gravity_axis": "Cyclic brake test and mechanical supports for axes that can fall",
+	}
+	for _, e := range strings.Split(a["energy"], ",") {
+		if m, ok := energies[e]; ok {
+			add(Hazard{
+				Hazard:      "Unexpected movement from stored " + strings.ReplaceAll(e, "_", " ") + " energy",
+				Situation:   "Maintenance or fault clearing after the cell is stopped",
+				Consequence: "Crushing, impact",
+				S:           2, F: 1, P: 2,
+				Measures:  []string{m, "Lockout and tagout procedure for all energies (ISO 14118)"},
+				Reference: "ISO 14118, ISO 10218-2",
+			})
+		}
+	}
+
+	if a.Yes("teach") {
+		add(Hazard{
+			Hazard:      "Robot motion while teaching in manual mode",
+			Situation:   "Programmer in the cell with the FlexPendant",
+			Consequence: "Crushing between robot and fixed parts",
+			S:           2, F: 1, P: 1,
+			Measures: []string{
+				"Manual reduced speed at most 250 mm/s",
+				"Three-position enabling device and emergency stop on the pendant",
+				"Single point of control; automatic start impossible from elsewhere",
+				"At least 500 mm clearance from fixed parts or avoid teaching there",
+			},
+			Reference: "ISO 10218-1 manual mode, ISO 10218-2",
+		})
+	}
+
+	if a.Yes("safemove") {
+		add(Hazard{
+			Hazard:      "Safety-rated limits configured wrong",
+			Situation:   "Zones, tool geometry or speed limits do not match the cell",
+			Consequence: "Robot leaves the protected area",
+			S:           2, F: exposure, P: 2,
+			Measures: []string{
+				"Validate every zone and limit by test and record the configuration checksum",
+				"Restrict changes of the safety configuration to the safety role (UAS)",
+			},
+			Reference: "ISO 10218-1 safety-rated soft axis and space limiting",
+		})
+	} else if collaborative {
+		add(Hazard{
+			Hazard:      "Collaborative workspace without safety-rated limits",
+			Situation:   "Robot program or jogging moves the robot beyond the intended area",
+			Consequence: "Contact outside the assessed area",
+			S:           2, F: exposure, P: avoid,
+			Measures:  []string{"Safety-rated zones and speed limits (e.g. SafeMove) for the collaborative workspace"},
+			Reference: "ISO 10218-1 safety-rated soft axis and space limiting",
+		})
+	}
+
+	if a.Yes("external") {
+		add(Hazard{
+			Hazard:      "Interaction with other machines of the cell",
+			Situation:   "Robot and machines started, stopped or reset independently",
+			Consequence: "Unexpected motion of either machine",
+			S:           2, F: exposure, P: 2,
+			Measures: []string{
+				"Common safety concept and emergency stop for the integrated system",
+				"Safety interlocks between robot and machines for shared areas",
+			},
+			Reference: "ISO 11161, ISO 10218-2",
+		})
+	}
+	return list
+}
+
+// Markdown formats the draft register with the answers it is based on
+func Markdown(a Answers, hazards []Hazard) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Risk register draft: %s\n\n", a["cell"])
+	fmt.Fprintf(&b, "**%s**\n\n", Disclaimer)
+	b.WriteString("## Cell data\n\n| Question | Answer |\n|---|---|\n")
+	for _, q := range Questions {
+		if v, ok := a[q.ID]; ok && q.Asked(a) {
+			fmt.Fprintf(&b, "| %s | %s |\n", q.Text, v)
+		}
+	}
+	b.WriteString("\n## Register\n\n| ID | Hazard | S | F | P | PLr |\n|---|---|---|---|---|---|\n")
+	for _, h := range hazards {
+		fmt.Fprintf(&b, "| %s | %s | S%d | F%d | P%d | %s |\n", h.ID, h.Hazard, h.S, h.F, h.P, h.PLr())
+	}
+	for _, h := range hazards {
+		fmt.Fprintf(&b, "\n### %s %s\n\n", h.ID, h.Hazard)
+		fmt.Fprintf(&b, "- Situation: %s\n- Consequence: %s\n", h.Situation, h.Consequence)
+		fmt.Fprintf(&b, "- Risk graph: S%d F%d P%d, PLr %s\n- Reference: %s\n\nProposed measures:\n\n", h.S, h.F, h.P, h.PLr(), h.Reference)
+		for _, m := range h.Measures {
+			fmt.Fprintf(&b, "- [ ] %s\n", m)
+		}
+		b.WriteString("\nResidual risk: ______  Verified by: ______\n")
+	}
+	return b.String()
+}
+
+// CSV formats the draft register for spreadsheets
+func CSV(hazards []Hazard) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"id", "hazard", "situation", "consequence", "s", "f", "p", "plr", "measures", "reference", "residual_risk", "verified_by"})
+	for _, h := range hazards {
+		w.Write([]string{h.ID, h.Hazard, h.Situation, h.Consequence,
+			fmt.Sprintf("S%d", h.S), fmt.Sprintf("F%d", h.F), fmt.Sprintf("P%d", h.P), h.PLr(),
+			strings.Join(h.Measures, "; "), h.Reference, "", ""})
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const roundTargetsUsage = `Usage: abb round-targets <file.mod> [--mm 0.1] [--deg 0.01] [--output <file>]
+Rounds robtarget positions and external axes to --mm and quaternions to the
+precision that keeps every orientation within --deg of the taught one. The
+quaternions are normalized to unit length with a non-negative q1 first, and
+keep the decimals they need to stay normalized, so re-taught points only
+change where they moved. The controller writes far more decimals than the
+robot can repeat, which makes every re-teach a large diff in version control.
+
+Examples:
+  abb round-targets Station1.mod
+  abb round-targets Station1.mod --mm 0.5 --deg 0.05 --dry-run`
+
+// abbRoundTargets rounds the robtargets of a module
+func abbRoundTargets(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return roundTargetsUsage, nil
+	}
+	path := args[0]
+	output := path
+	mm, deg := 0.1, 0.01
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return roundTargetsUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--mm":
+			mm, err = strconv.ParseFloat(value, 64)
+		case "--deg":
+			deg, err = strconv.ParseFloat(value, 64)
+		case "--output":
+			output = value
+		default:
+			return roundTargetsUsage, nil
+		}
+		if err != nil || (args[i] != "--output" && (mm <= 0 || deg <= 0)) {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Rounding each component by at most step/2 turns the orientation by at
+	// most about 2*step radians
+	quatDecimals := int(math.Ceil(-math.Log10(deg * math.Pi / 360)))
+	var lines []string
+	maxMove, maxTurn := 0.0, 0.0
+	out := rapid.ReplaceTargets(string(data), func(t rapid.Target) (rapid.Target, bool) {
+		r := roundTarget(t, mm, quatDecimals)
+		if r.Value() == t.Value() {
+			return t, false
+		}
+		move := t.Distance(r)
+		turn := calc.QuaternionAngle(calc.NormalizeQuaternion(t.Rot), r.Rot)
+		maxMove, maxTurn = math.Max(maxMove, move), math.Max(maxTurn, turn)
+		lines = append(lines, fmt.Sprintf("  line %d: %s (moved %.3f mm, turned %.4f deg)", t.Line, t.Name, move, turn))
+		return r, true
+	})
+	if len(lines) == 0 {
+		return fmt.Sprintf("All robtargets in %s are already rounded to %g mm and %g deg", path, mm, deg), nil
+	}
+	if err := writeFile(output, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("Rounded %d robtargets (largest change %.3f mm, %.4f deg)", len(lines), maxMove, maxTurn)
+	if !dryRun() {
+		summary += " in " + output
+	}
+	return summary + ":\n" + strings.Join(lines, "\n"), nil
+}
+
+// roundTarget rounds positions and external axes to multiples of step and
+// the normalized quaternion to decimals; unused external axes stay 9E9
+func roundTarget(t rapid.Target, step float64, quatDecimals int) rapid.Target {
+	for i := range t.Trans {
+		t.Trans[i] = roundToStep(t.Trans[i], step)
+	}
+	for i, e := range t.Ext {
+		if e < 9e9 {
+			t.Ext[i] = roundToStep(e, step)
+		}
+	}
+	q := calc.NormalizeQuaternion(t.Rot)
+	for decimals := quatDecimals; ; decimals++ {
+		scale := math.Pow(10, float64(decimals))
+		sum := 0.0
+		for i := range q {
+			t.Rot[i] = math.Round(q[i]*scale) / scale
+			if t.Rot[i] == 0 {
+				t.Rot[i] = 0 // no -0
+			}
+			sum += t.Rot[i] * t.Rot[i]
+		}
+		// The controller rejects quaternions that are not normalized
+		if math.Abs(sum-1) <= quatTolerance || decimals >= 9 {
+			return t
+		}
+	}
+}
+
+// quatTolerance is how far the squares of a rounded quaternion may sum from
+// one, well inside what the controller accepts as normalized
+const quatTolerance = 1e-5
+
+// roundToStep rounds v to a multiple of step, without the binary noise of
+// steps such as 0.1
+func roundToStep(v, step float64) float64 {
+	r := math.Round(v/step) * step
+	decimals := 0
+	for s := step; s != math.Trunc(s) && decimals < 9; s *= 10 {
+		decimals++
+	}
+	r, _ = strconv.ParseFloat(strconv.FormatFloat(r, 'f', decimals, 64), 64)
+	if r == 0 {
+		return 0
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+package rws
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Backup creates a system backup in a controller directory such as
+// HOME:/Backups/Cell3_nightly and waits until the controller has written it
+func (c *Client) Backup(controllerPath string, timeout time.Duration) error {
+	if err := c.Post("/ctrl/backup?action=backup", url.Values{"backup": {DevicePath(controllerPath)}}); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		var s state
+		if err := c.Get("/ctrl/backup/state", &s); err != nil {
+			return err
+		}
+		if s.first("backup-state") != "active" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("backup not finished after %s", timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Entry is a file or directory on the controller
+type Entry struct {
+	Name string
+	Dir  bool
+	Size int64
+}
+
+// ListDir returns the files and directories in a controller directory
+func (c *Client) ListDir(controllerPath string) ([]Entry, error) {
+	var s state
+	if err := c.Get(FilePath(controllerPath), &s); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, item := range s.Embedded.State {
+		name, _ := item["_title"].(string)
+		kind, _ := item["_type"].(string)
+		if name == "" || (kind != "fs-dir" && kind != "fs-file") {
+			continue
+		}
+		e := Entry{Name: name, Dir: kind == "fs-dir"}
+		e.Size, _ = strconv.ParseInt(fmt.Sprint(item["fs-size"]), 10, 64)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Download reads a controller directory with its subdirectories and passes
+// each file with its path relative to the directory to save
+func (c *Client) Download(controllerPath string, save func(rel string, data []byte) error) error {
+	return c.download(controllerPath, "", save)
+}
+
+func (c *Client) download(dir, rel string, save func(string, []byte) error) error {
+	entries, err := c.ListDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name)
+		if e.Dir {
+			if err := c.download(p, path.Join(rel, e.Name), save); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := c.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := save(path.Join(rel, e.Name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveAll deletes a controller directory and everything in it
+func (c *Client) RemoveAll(controllerPath string) error {
+	entries, err := c.ListDir(controllerPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(controllerPath, e.Name)
+		if e.Dir {
+			err = c.RemoveAll(p)
+		} else {
+			err = c.Delete(FilePath(p))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return c.Delete(FilePath(controllerPath))
+}
@@ -0,0 +1,304 @@
+// Package rws talks to ABB controllers and RobotStudio virtual controllers
+// over Robot Web Services 1.0 (RobotWare 6)
+package rws
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Factory credentials of RobotWare controllers
+const (
+	DefaultUser     = "Default User"
+	DefaultPassword = "robotics"
+)
+
+// maxResponse caps the size of any response read from the controller
+const maxResponse = 32 << 20
+
+// Client is a session with one controller. The controller keeps the
+// session in a cookie, so digest authentication only runs on the first
+// request and after the session expires.
+type Client struct {
+	Base     string // e.g. http://localhost
+	User     string
+	Password string
+	HTTP     *http.Client
+
+	mu     sync.Mutex
+	digest *digest
+}
+
+// NewClient creates a client for a host such as localhost or
+// 192.168.125.1:80, with the factory credentials
+func NewClient(host string) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		Base:     baseURL(host),
+		User:     DefaultUser,
+		Password: DefaultPassword,
+		HTTP:     &http.Client{Timeout: 30 * time.Second, Jar: jar},
+	}
+}
+
+// baseURL adds the default scheme to a host and drops a trailing slash
+func baseURL(host string) string {
+	base := host
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// HostPort returns the host name in lower case and the port that a client
+// for a host connects to, with the default port of the scheme, so that
+// 10.0.0.1, http://10.0.0.1:80 and 10.0.0.1/ compare equal
+func HostPort(host string) (string, string, error) {
+	u, err := url.Parse(baseURL(strings.TrimSpace(host)))
+	if err != nil || u.Hostname() == "" {
+		return "", "", fmt.Errorf("invalid host %q", host)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(u.Scheme, "https") {
+			port = "443"
+		}
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), port, nil
+}
+
+// OnWrite, when set, is called after every request that changes the
+// controller (POST, PUT and DELETE) with its outcome, for the audit log
+var OnWrite func(c *Client, method, path string, body []byte, err error)
+
+// ReadOnly makes the client refuse every request that would change a
+// controller, for the CLI's read-only mode
+var ReadOnly bool
+
+// ErrReadOnly is returned for a write in read-only mode
+var ErrReadOnly = errors.New("read-only mode: the controller is not changed")
+
+// Error is a request the controller refused
+type Error struct {
+	Method string
+	Path   string
+	Status int
+	Detail string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %s", e.Method, e.Path, http.StatusText(e.Status))
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Get reads a resource as JSON into v
+func (c *Client) Get(path string, v interface{}) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	data, err := c.do(http.MethodGet, path+sep+"json=1", "", nil)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("GET %s: unexpected response: %v", path, err)
+	}
+	return nil
+}
+
+// Post sends a form to an action URL such as /rw/rapid/execution?action=stop
+func (c *Client) Post(path string, form url.Values) error {
+	_, err := c.do(http.MethodPost, path, "application/x-www-form-urlencoded", []byte(form.Encode()))
+	return err
+}
+
+// Put uploads raw content, used for files
+func (c *Client) Put(path string, content []byte) error {
+	_, err := c.do(http.MethodPut, path, "text/plain", content)
+	return err
+}
+
+// Delete removes a resource
+func (c *Client) Delete(path string) error {
+	_, err := c.do(http.MethodDelete, path, "", nil)
+	return err
+}
+
+// Raw reads a resource without JSON decoding, used for files
+func (c *Client) Raw(path string) ([]byte, error) {
+	return c.do(http.MethodGet, path, "", nil)
+}
+
+func (c *Client) do(method, path, contentType string, body []byte) ([]byte, error) {
+	if ReadOnly && method != http.MethodGet {
+		return nil, fmt.Errorf("%s %s: %w", method, path, ErrReadOnly)
+	}
+	data, err := c.send(method, path, contentType, body)
+	if method != http.MethodGet && OnWrite != nil {
+		OnWrite(c, method, path, body, err)
+	}
+	return data, err
+}
+
+func (c *Client) send(method, path, contentType string, body []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, c.Base+path, strings.NewReader(string(body)))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		c.mu.Lock()
+		if c.digest != nil {
+			req.Header.Set("Authorization", c.digest.authorize(method, req.URL.RequestURI(), c.User, c.Password))
+		}
+		c.mu.Unlock()
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			d, err := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+			if err != nil {
+				return nil, err
+			}
+			c.mu.Lock()
+			c.digest = d
+			c.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return nil, &Error{Method: method, Path: path, Status: resp.StatusCode, Detail: errorDetail(data)}
+		}
+		return data, nil
+	}
+}
+
+// errorDetail extracts the message of an RWS error response
+func errorDetail(data []byte) string {
+	var status struct {
+		Status struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		} `json:"status"`
+	}
+	if json.Unmarshal(data, &status) == nil && status.Status.Msg != "" {
+		return status.Status.Msg
+	}
+	return ""
+}
+
+// digest holds the server challenge for HTTP digest authentication
+type digest struct {
+	realm, nonce, opaque, qop string
+	count                     int
+}
+
+func parseChallenge(header string) (*digest, error) {
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Digest") {
+		return nil, fmt.Errorf("controller asked for %q authentication; check the user and password", scheme)
+	}
+	d := &digest{}
+	for _, part := range splitParams(params) {
+		key, value, _ := strings.Cut(part, "=")
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "realm":
+			d.realm = value
+		case "nonce":
+			d.nonce = value
+		case "opaque":
+			d.opaque = value
+		case "qop":
+			// The controller offers "auth"; auth-int is not needed
+			for _, q := range strings.Split(value, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					d.qop = "auth"
+				}
+			}
+		}
+	}
+	if d.nonce == "" {
+		return nil, fmt.Errorf("invalid digest challenge %q", header)
+	}
+	return d, nil
+}
+
+// splitParams splits challenge parameters at commas outside quotes
+func splitParams(s string) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func (d *digest) authorize(method, uri, user, password string) string {
+	d.count++
+	ha1 := md5Hex(user + ":" + d.realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	nc := fmt.Sprintf("%08x", d.count)
+	cnonce := randomHex(8)
+	var response string
+	if d.qop != "" {
+		response = md5Hex(strings.Join([]string{ha1, d.nonce, nc, cnonce, d.qop, ha2}, ":"))
+	} else {
+		response = md5Hex(ha1 + ":" + d.nonce + ":" + ha2)
+	}
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		user, d.realm, d.nonce, uri, response)
+	if d.opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, d.opaque)
+	}
+	if d.qop != "" {
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, d.qop, nc, cnonce)
+	}
+	return header
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
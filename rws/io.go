@@ -0,0 +1,58 @@
+package rws
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// signalPath returns the resource of a signal given as name or as
+// network/device/name; signals without a device are addressed by name
+func signalPath(signal string) string {
+	parts := strings.Split(signal, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return "/rw/iosystem/signals/" + strings.Join(parts, "/")
+}
+
+// ReadSignal returns the logical value of an I/O signal as text, e.g. 1 or
+// 12.5
+func (c *Client) ReadSignal(signal string) (string, error) {
+	var s state
+	if err := c.Get(signalPath(signal)+";state", &s); err != nil {
+		return "", err
+	}
+	for _, item := range s.Embedded.State {
+		if v, ok := item["lvalue"]; ok {
+			return fmt.Sprint(v), nil
+		}
+	}
+	return "", fmt.Errorf("no value for signal %s", signal)
+}
+
+// WriteSignal sets the logical value of an I/O signal. Inputs can only be
+// set when they are simulated or have no device, as on a virtual controller.
+func (c *Client) WriteSignal(signal, value string) error {
+	return c.Post(signalPath(signal)+"?action=set", url.Values{"lvalue": {value}})
+}
+
+// RobotTarget returns the TCP of a mechanical unit as x, y, z (mm) and the
+// orientation quaternion q1..q4, for a tool and work object
+func (c *Client) RobotTarget(mechUnit, tool, wobj string) ([7]float64, error) {
+	var pose [7]float64
+	var s state
+	q := url.Values{"tool": {tool}, "wobj": {wobj}, "coordinate": {"Wobj"}}
+	if err := c.Get("/rw/motionsystem/mechunits/"+url.PathEscape(mechUnit)+"/robtarget?"+q.Encode(), &s); err != nil {
+		return pose, err
+	}
+	for i, field := range []string{"x", "y", "z", "q1", "q2", "q3", "q4"} {
+		v, err := strconv.ParseFloat(s.first(field), 64)
+		if err != nil {
+			return pose, fmt.Errorf("robtarget of %s: invalid %s", mechUnit, field)
+		}
+		pose[i] = v
+	}
+	return pose, nil
+}
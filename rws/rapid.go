@@ -0,0 +1,239 @@
+package rws
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// state is the list of resources RWS returns in JSON responses
+type state struct {
+	Embedded struct {
+		State []map[string]interface{} `json:"_state"`
+	} `json:"_embedded"`
+}
+
+func (s state) first(field string) string {
+	for _, item := range s.Embedded.State {
+		if v, ok := item[field].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// RequestMastership takes write access to the controller
+func (c *Client) RequestMastership() error {
+	return c.Post("/rw/mastership?action=request", nil)
+}
+
+// ReleaseMastership gives write access back so the FlexPendant can edit
+func (c *Client) ReleaseMastership() error {
+	return c.Post("/rw/mastership?action=release", nil)
+}
+
+// OperationMode returns AUTO, MANR or MANF
+func (c *Client) OperationMode() (string, error) {
+	var s state
+	if err := c.Get("/rw/panel/opmode", &s); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(s.first("opmode")), nil
+}
+
+// ControllerState returns motoron, motoroff, guardstop, emergencystop and
+// similar states of the controller
+func (c *Client) ControllerState() (string, error) {
+	var s state
+	if err := c.Get("/rw/panel/ctrlstate", &s); err != nil {
+		return "", err
+	}
+	return s.first("ctrlstate"), nil
+}
+
+// Clock returns the controller's date and time. The controller keeps
+// local time without a zone, so it is read in the local zone of this
+// computer.
+func (c *Client) Clock() (time.Time, error) {
+	var s state
+	if err := c.Get("/ctrl/clock", &s); err != nil {
+		return time.Time{}, err
+	}
+	v := s.first("datetime")
+	t, err := time.ParseInLocation("2006-01-02 T 15:04:05", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected controller time %q", v)
+	}
+	return t, nil
+}
+
+// MotorsOn switches the motors on; only possible in automatic mode
+func (c *Client) MotorsOn() error {
+	return c.Post("/rw/panel/ctrlstate?action=setctrlstate", url.Values{"ctrl-state": {"motoron"}})
+}
+
+// MotorsOff switches the motors off, which also stops a running program
+func (c *Client) MotorsOff() error {
+	return c.Post("/rw/panel/ctrlstate?action=setctrlstate", url.Values{"ctrl-state": {"motoroff"}})
+}
+
+// ExecutionState returns running or stopped
+func (c *Client) ExecutionState() (string, error) {
+	var s state
+	if err := c.Get("/rw/rapid/execution", &s); err != nil {
+		return "", err
+	}
+	return s.first("ctrlexecstate"), nil
+}
+
+// ResetPP moves the program pointer of all tasks to main
+func (c *Client) ResetPP() error {
+	return c.Post("/rw/rapid/execution?action=resetpp", nil)
+}
+
+// Start runs the program once from the program pointer
+func (c *Client) Start() error {
+	return c.StartCycle("once")
+}
+
+// StartCycle runs the program from the program pointer, once or forever
+func (c *Client) StartCycle(cycle string) error {
+	return c.Post("/rw/rapid/execution?action=start", url.Values{
+		"regain":       {"continue"},
+		"execmode":     {"continue"},
+		"cycle":        {cycle},
+		"condition":    {"none"},
+		"stopatbp":     {"disabled"},
+		"alltaskbytsp": {"false"},
+	})
+}
+
+// Stop stops program execution
+func (c *Client) Stop() error {
+	return c.Post("/rw/rapid/execution?action=stop", url.Values{"stopmode": {"stop"}})
+}
+
+// WaitStopped polls the execution state until the program stops or the
+// timeout passes
+func (c *Client) WaitStopped(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		s, err := c.ExecutionState()
+		if err != nil {
+			return err
+		}
+		if s == "stopped" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("program still running after %s", timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// FilePath turns a controller path such as HOME:/dir/file.mod into its
+// file service URL
+func FilePath(p string) string {
+	if device, rest, ok := strings.Cut(p, ":"); ok && !strings.Contains(device, "/") {
+		p = "$" + strings.ToUpper(device) + "/" + strings.TrimLeft(rest, "/")
+	}
+	return "/fileservice/" + p
+}
+
+// DevicePath turns HOME:/dir/file.mod into the $HOME/dir/file.mod form
+// RWS actions expect
+func DevicePath(p string) string {
+	return strings.TrimPrefix(FilePath(p), "/fileservice/")
+}
+
+// CreateDir creates a directory under a controller path; an existing
+// directory is not an error
+func (c *Client) CreateDir(parent, name string) error {
+	err := c.Post(FilePath(parent), url.Values{"fs-newname": {name}, "fs-action": {"create"}})
+	if e, ok := err.(*Error); ok && e.Status == 409 {
+		return nil
+	}
+	return err
+}
+
+// Upload writes a file on the controller
+func (c *Client) Upload(controllerPath string, content []byte) error {
+	return c.Put(FilePath(controllerPath), content)
+}
+
+// ReadFile reads a file from the controller
+func (c *Client) ReadFile(controllerPath string) ([]byte, error) {
+	return c.Raw(FilePath(controllerPath))
+}
+
+// LoadModule loads a module file into a task, replacing a module with the
+// same name
+func (c *Client) LoadModule(task, controllerPath string) error {
+	return c.Post("/rw/rapid/tasks/"+url.PathEscape(task)+"?action=loadmod", url.Values{
+		"modulepath": {controllerPath},
+		"replace":    {"true"},
+	})
+}
+
+// UnloadModule removes a module from a task
+func (c *Client) UnloadModule(task, module string) error {
+	return c.Post("/rw/rapid/tasks/"+url.PathEscape(task)+"?action=unloadmod", url.Values{"module": {module}})
+}
+
+// Event is an event log message
+type Event struct {
+	Seq   int
+	Type  int // 1 information, 2 warning, 3 error
+	Code  string
+	Time  string
+	Title string
+	Desc  string
+}
+
+func (e Event) String() string {
+	kind := map[int]string{1: "info", 2: "warning", 3: "error"}[e.Type]
+	return fmt.Sprintf("%s %-7s %s %s", e.Time, kind, e.Code, e.Title)
+}
+
+// Events returns the messages of the common event log with a sequence
+// number above since, oldest first
+func (c *Client) Events(since int) ([]Event, error) {
+	var s state
+	if err := c.Get("/rw/elog/0?lang=en", &s); err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, item := range s.Embedded.State {
+		if item["_type"] != "elog-message-li" {
+			continue
+		}
+		title, _ := item["_title"].(string)
+		seq, err := strconv.Atoi(path.Base(title))
+		if err != nil || seq <= since {
+			continue
+		}
+		e := Event{Seq: seq}
+		e.Type, _ = strconv.Atoi(fmt.Sprint(item["msgtype"]))
+		e.Code, _ = item["code"].(string)
+		e.Time, _ = item["tstamp"].(string)
+		e.Title, _ = item["title"].(string)
+		e.Desc, _ = item["desc"].(string)
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
+
+// LastEvent returns the sequence number of the newest event log message
+func (c *Client) LastEvent() (int, error) {
+	events, err := c.Events(-1)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	return events[len(events)-1].Seq, nil
+}
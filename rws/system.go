@@ -0,0 +1,130 @@
+package rws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Identity is the name and serial number of a controller
+type Identity struct {
+	Name   string `json:"name"`
+	Serial string `json:"serial"`
+	Type   string `json:"type"` // Real Controller or Virtual Controller
+}
+
+// Identity returns the controller's name, serial number and type
+func (c *Client) Identity() (Identity, error) {
+	var s state
+	if err := c.Get("/ctrl/identity", &s); err != nil {
+		return Identity{}, err
+	}
+	return Identity{Name: s.first("ctrl-name"), Serial: s.first("ctrl-id"), Type: s.first("ctrl-type")}, nil
+}
+
+// System is the RobotWare system installed on a controller
+type System struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	RobotWare string `json:"robotware"` // e.g. 6.14.00.00
+	Started   string `json:"started,omitempty"`
+}
+
+// System returns the name and RobotWare version of the running system
+func (c *Client) System() (System, error) {
+	var s state
+	if err := c.Get("/rw/system", &s); err != nil {
+		return System{}, err
+	}
+	version := s.first("rwversion")
+	if version == "" {
+		version = s.first("rwversionname")
+	}
+	return System{Name: s.first("name"), ID: s.first("sysid"), RobotWare: version, Started: s.first("starttm")}, nil
+}
+
+// Options returns the RobotWare options of the system, sorted
+func (c *Client) Options() ([]string, error) {
+	var s state
+	if err := c.Get("/rw/system/options", &s); err != nil {
+		return nil, err
+	}
+	var options []string
+	for _, item := range s.Embedded.State {
+		if v, ok := item["option"].(string); ok && v != "" {
+			options = append(options, v)
+		}
+	}
+	sort.Strings(options)
+	return options, nil
+}
+
+// RobotType returns the model of the robots, e.g. IRB 6700-150/3.20
+func (c *Client) RobotType() (string, error) {
+	var s state
+	if err := c.Get("/rw/system/robottype", &s); err != nil {
+		return "", err
+	}
+	return s.first("robot-type"), nil
+}
+
+// MechUnit is a mechanical unit driven by the controller's drive modules
+type MechUnit struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"` // TCPRobot, Robot or Single
+	Axes string `json:"axes,omitempty"`
+	Task string `json:"task,omitempty"`
+	Mode string `json:"mode,omitempty"` // Activated or Deactivated
+}
+
+// MechUnits returns the mechanical units of the motion system
+func (c *Client) MechUnits() ([]MechUnit, error) {
+	var s state
+	if err := c.Get("/rw/motionsystem/mechunits", &s); err != nil {
+		return nil, err
+	}
+	var units []MechUnit
+	for _, item := range s.Embedded.State {
+		name, _ := item["_title"].(string)
+		if name == "" {
+			continue
+		}
+		u := MechUnit{Name: name[strings.LastIndex(name, "/")+1:]}
+		u.Type, _ = item["type"].(string)
+		u.Task, _ = item["task-name"].(string)
+		u.Mode, _ = item["mode"].(string)
+		if axes, ok := item["axes"]; ok {
+			u.Axes = fmt.Sprint(axes)
+		}
+		units = append(units, u)
+	}
+	return units, nil
+}
+
+// DriveModules returns the drive module instances of the motion
+// configuration, keyed by name with their configured attributes
+func (c *Client) DriveModules() (map[string]map[string]string, error) {
+	var s state
+	if err := c.Get("/rw/cfg/MOC/DRIVE_MODULE_USER_DATA/instances", &s); err != nil {
+		return nil, err
+	}
+	modules := make(map[string]map[string]string)
+	for _, item := range s.Embedded.State {
+		name, _ := item["_title"].(string)
+		if name == "" {
+			continue
+		}
+		attrs := make(map[string]string)
+		if list, ok := item["attrib"].([]interface{}); ok {
+			for _, a := range list {
+				if m, ok := a.(map[string]interface{}); ok {
+					if k, ok := m["_title"].(string); ok {
+						attrs[k] = fmt.Sprint(m["value"])
+					}
+				}
+			}
+		}
+		modules[name] = attrs
+	}
+	return modules, nil
+}
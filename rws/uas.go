@@ -0,0 +1,22 @@
+package rws
+
+import "sort"
+
+// Grants returns the UAS grants of the logged-in user, e.g. UAS_RAPID_EXECUTE
+func (c *Client) Grants() ([]string, error) {
+	var s state
+	if err := c.Get("/uas/grants", &s); err != nil {
+		return nil, err
+	}
+	var grants []string
+	for _, item := range s.Embedded.State {
+		for _, field := range []string{"grant-name", "_title"} {
+			if v, ok := item[field].(string); ok && v != "" {
+				grants = append(grants, v)
+				break
+			}
+		}
+	}
+	sort.Strings(grants)
+	return grants, nil
+}
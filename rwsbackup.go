@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/storage"
+)
+
+const rwsBackupUsage = `Usage:
+  abb rws backup create [--robot <name>] [--keep] [--retain <n>] [--output <dir>]
+  abb rws backup fetch <controller-dir> [--robot <name>] [--retain <n>] [--output <dir>]
+  abb rws backup list [--robot <name>]
+  abb rws backup prune --retain <n> [--robot <name>]
+  (with --host, --user and --password as for abb rws)
+
+create makes a system backup on the controller under HOME:/Backups and
+downloads it; the controller copy is deleted unless --keep. fetch downloads
+a backup that is already on the controller, such as one taken on the
+FlexPendant: a name under HOME:/Backups or a path like HOME:/Cell3_before.
+
+Backups are stored in the project under backups/<robot>/<date>, or in the
+shared store of the storage setting (see storage), next to a <date>.json
+with the system, RobotWare version, serial number and size.
+The robot defaults to the controller name. --retain keeps the newest n
+backups of the robot and deletes older ones; prune applies it on its own.
+--output downloads to a directory outside the store instead.
+
+Examples:
+  abb rws backup create --host 192.168.125.1 --robot cell3 --retain 14
+  abb rws backup fetch Cell3_before_upgrade --robot cell3
+  abb rws backup list
+  abb rws backup prune --retain 30`
+
+// backupsDir holds the stored backups in the project directory, one
+// directory per robot
+const backupsDir = "backups"
+
+// backupTimeout bounds how long abb rws backup waits for the controller to
+// write the backup
+const backupTimeout = 10 * time.Minute
+
+// storedBackup is the metadata kept next to a stored backup
+type storedBackup struct {
+	Robot      string `json:"robot"`
+	Host       string `json:"host"`
+	Source     string `json:"source"` // backup directory on the controller
+	Created    string `json:"created"`
+	System     string `json:"system"`
+	RobotWare  string `json:"robotware"`
+	Serial     string `json:"serial"`
+	Files      int    `json:"files"`
+	Bytes      int    `json:"bytes"`
+	KeptOnCtrl bool   `json:"kept_on_controller"`
+}
+
+// rwsBackup runs abb rws backup
+func rwsBackup(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return rwsBackupUsage, nil
+	}
+	action := args[0]
+	args = args[1:]
+	source := ""
+	if action == "fetch" {
+		if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+			return rwsBackupUsage, nil
+		}
+		source, args = args[0], args[1:]
+	}
+	host := "localhost"
+	var user, password, robot, output string
+	keep, retain := false, 0
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--keep" {
+			keep = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return rwsBackupUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		case "--robot":
+			robot = value
+		case "--output":
+			output = value
+		case "--retain":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return "", fmt.Errorf("invalid value %q for --retain", value)
+			}
+			retain = n
+		default:
+			return rwsBackupUsage, nil
+		}
+		i++
+	}
+
+	switch action {
+	case "list":
+		return listBackups(robot)
+	case "prune":
+		if retain == 0 {
+			return "", errors.New("--retain is required")
+		}
+		return pruneBackups(robot, retain)
+	case "create", "fetch":
+	default:
+		return rwsBackupUsage, nil
+	}
+	if output == "" && activeProject() == nil {
+		return "", errors.New("backups are stored in the project; select one with 'use <projectdir>' or give --output")
+	}
+	if dryRun() {
+		what := "create a backup of the system on " + host + " and download it"
+		if action == "fetch" {
+			what = "download " + controllerBackupPath(source) + " from " + host
+		}
+		if output == "" {
+			output = filepath.Join(backupsDir, orDefault(robot, "<robot>"), "<date>")
+		}
+		return fmt.Sprintf("Would %s to %s", what, output), nil
+	}
+
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	id, err := c.Identity()
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", host, err)
+	}
+	sys, err := c.System()
+	if err != nil {
+		return "", err
+	}
+	if robot == "" {
+		robot = orDefault(id.Name, host)
+	}
+	now := time.Now()
+	meta := storedBackup{Robot: robot, Host: host, Created: now.Format(time.RFC3339),
+		System: sys.Name, RobotWare: sys.RobotWare, Serial: id.Serial, KeptOnCtrl: true}
+
+	if action == "create" {
+		meta.Source = "HOME:/Backups/" + unsafeNameRe.ReplaceAllString(sys.Name, "_") + "_" + now.Format("2006-01-02_150405")
+		if err := c.CreateDir("HOME:", "Backups"); err != nil {
+			return "", err
+		}
+		if err := c.Backup(meta.Source, backupTimeout); err != nil {
+			return "", fmt.Errorf("backup failed: %v", err)
+		}
+	} else {
+		meta.Source = controllerBackupPath(source)
+	}
+
+	// Backups are written directly rather than recorded for undo: they are
+	// large and only ever added
+	var store storage.Store
+	key := ""
+	put := func(rel string, data []byte) error {
+		p := filepath.Join(output, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(p, data, 0o644)
+	}
+	if output == "" {
+		if store, err = projectStore(activeProject()); err != nil {
+			return "", err
+		}
+		key = path.Join(backupsDir, unsafeNameRe.ReplaceAllString(robot, "_"), now.Format("2006-01-02_150405"))
+		output = storage.Location(store, key)
+		put = func(rel string, data []byte) error { return store.Put(key+"/"+rel, data) }
+	}
+	err = c.Download(meta.Source, func(rel string, data []byte) error {
+		meta.Files++
+		meta.Bytes += len(data)
+		return put(rel, data)
+	})
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %v", meta.Source, err)
+	}
+	if meta.Files == 0 {
+		return "", fmt.Errorf("%s on %s is empty or does not exist", meta.Source, host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Downloaded the backup of %s (%d files, %d KB) to %s\n", sys.Name, meta.Files, (meta.Bytes+1023)/1024, output)
+	if action == "create" && !keep {
+		if err := c.RemoveAll(meta.Source); err != nil {
+			fmt.Fprintf(&b, "Warning: %s stays on the controller: %v\n", meta.Source, err)
+		} else {
+			meta.KeptOnCtrl = false
+		}
+	}
+	if store != nil {
+		data, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		if err := store.Put(key+".json", append(data, '\n')); err != nil {
+			return "", err
+		}
+		if retain > 0 {
+			pruned, err := pruneBackups(robot, retain)
+			if err != nil {
+				return strings.TrimRight(b.String(), "\n"), err
+			}
+			if !strings.HasPrefix(pruned, "No backups") {
+				b.WriteString(pruned)
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// controllerBackupPath completes a backup name to a directory under
+// HOME:/Backups; paths with a device stay as they are
+func controllerBackupPath(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return path.Join("HOME:/Backups", name)
+}
+
+// robotBackups returns the store of the active project with the keys of
+// the stored backups of the robots, or of one robot, oldest first per robot
+func robotBackups(robot string) (storage.Store, map[string][]string, error) {
+	p := activeProject()
+	if p == nil {
+		return nil, nil, fmt.Errorf("backups are stored in the project; select one with 'use <projectdir>'")
+	}
+	store, err := projectStore(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix := backupsDir + "/"
+	if robot != "" {
+		prefix += unsafeNameRe.ReplaceAllString(robot, "_") + "/"
+	}
+	keys, err := store.List(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	backups := make(map[string][]string)
+	for _, k := range keys {
+		// The metadata files backups/<robot>/<date>.json name the backups
+		parts := strings.Split(k, "/")
+		if len(parts) != 3 || !strings.HasSuffix(k, ".json") {
+			continue
+		}
+		backups[parts[1]] = append(backups[parts[1]], strings.TrimSuffix(k, ".json"))
+	}
+	return store, backups, nil
+}
+
+func listBackups(robot string) (string, error) {
+	store, backups, err := robotBackups(robot)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "No stored backups. Create one with 'abb rws backup create'.", nil
+	}
+	robots := make([]string, 0, len(backups))
+	for name := range backups {
+		robots = append(robots, name)
+	}
+	sort.Strings(robots)
+	var b strings.Builder
+	for _, name := range robots {
+		fmt.Fprintf(&b, "%s (%d):\n", name, len(backups[name]))
+		for _, key := range backups[name] {
+			var meta storedBackup
+			if data, err := store.Get(key + ".json"); err == nil {
+				json.Unmarshal(data, &meta)
+			}
+			fmt.Fprintf(&b, "  %-17s  %-20s RobotWare %-12s %4d files %7d KB  %s\n", path.Base(key),
+				meta.System, orDefault(meta.RobotWare, "-"), meta.Files, (meta.Bytes+1023)/1024, meta.Source)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// pruneBackups deletes all but the newest n stored backups of each robot
+func pruneBackups(robot string, n int) (string, error) {
+	store, backups, err := robotBackups(robot)
+	if err != nil {
+		return "", err
+	}
+	var removed []string
+	for _, keys := range backups {
+		if len(keys) <= n {
+			continue
+		}
+		for _, key := range keys[:len(keys)-n] {
+			removed = append(removed, storage.Location(store, key))
+			files, err := store.List(key + "/")
+			if err != nil {
+				return "", err
+			}
+			for _, f := range append(files, key+".json") {
+				if err := store.Delete(f); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return fmt.Sprintf("No backups to prune; every robot has at most %d", n), nil
+	}
+	sort.Strings(removed)
+	verb := "Deleted"
+	if dryRun() {
+		verb = "Would delete"
+	}
+	return fmt.Sprintf("%s %d old backups, keeping the newest %d per robot:\n  %s", verb, len(removed), n, strings.Join(removed, "\n  ")), nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// rwsElog appends the event log messages newer than the last one in a CSV
+// file, so repeated pulls build a complete log
+func rwsElog(c *rws.Client, host, output string) (string, error) {
+	data, err := os.ReadFile(output)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	last := lastEventSeq(data)
+	events, err := c.Events(last)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", host, err)
+	}
+	if len(events) == 0 {
+		return fmt.Sprintf("No new event log messages on %s", host), nil
+	}
+
+	var b bytes.Buffer
+	b.Write(data)
+	w := csv.NewWriter(&b)
+	if len(data) == 0 {
+		w.Write([]string{"seq", "time", "type", "code", "title"})
+	}
+	for _, e := range events {
+		kind := map[int]string{1: "info", 2: "warning", 3: "error"}[e.Type]
+		w.Write([]string{strconv.Itoa(e.Seq), e.Time, kind, e.Code, e.Title})
+	}
+	w.Flush()
+	if err := makeDir(filepath.Dir(output)); err != nil {
+		return "", err
+	}
+	if err := writeFile(output, b.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Appended %d event log messages from %s to %s", len(events), host, output), nil
+}
+
+// lastEventSeq returns the sequence number in the last row of an event log
+// CSV, -1 for an empty file
+func lastEventSeq(data []byte) int {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		seq, _, _ := strings.Cut(lines[i], ",")
+		if n, err := strconv.Atoi(seq); err == nil {
+			return n
+		}
+	}
+	return -1
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+const rwsUsage = `Usage: abb rws <action> [--host localhost] [--user <name>] [--password <pw>] [--yes]
+Controls program execution over Robot Web Services, for scripted test cycles
+while debugging, and records what is installed on a controller:
+  state        Operation mode, controller state and program execution
+  info         Save a snapshot of serial number, RobotWare, options, clock,
+               battery and drive modules to inventory/<robot> in the project
+               or its store (see storage)
+               (--robot <name>, default the controller name)
+  backup       Create, fetch, list and prune controller backups; see
+               'abb rws backup'
+  elog         Append the event log messages not yet in --output (CSV)
+  module       Deploy modules to a task; see 'abb rws module'
+  pp-to-main   Move the program pointer of all tasks to main
+  start        Run the program from the program pointer (--cycle once|forever)
+  stop         Stop the program
+  motors-on    Switch the motors on
+  motors-off   Switch the motors off
+
+pp-to-main, start and motors-on need automatic mode and ask to confirm that
+the cell is clear; --yes confirms in scripts. Stopping never asks. The
+credentials default to those stored with 'secrets set rws <host>'.
+Each info snapshot is compared with the previous one of the robot, so
+RobotWare upgrades, swapped controllers and new options show up.
+RobotWare 6 (RWS 1.0) only. --dry-run shows the steps of an action without
+connecting, and for info the snapshot it would write.
+
+Examples:
+  abb rws state --host 192.168.125.1
+  abb rws info --host 192.168.125.1 --robot cell3-r1
+  abb rws backup create --host 192.168.125.1 --robot cell3 --retain 14
+  abb rws module put MainModule.mod --host 192.168.125.1
+  abb rws elog --output cell3_events.csv
+  abb rws pp-to-main --yes
+  abb rws start --cycle forever
+  abb rws stop`
+
+// rwsAction is an execution control action of abb rws
+type rwsAction struct {
+	describe string // what it does, for confirmation and --dry-run
+	auto     bool   // needs automatic mode and confirmation
+	run      func(c *rws.Client, cycle string) error
+}
+
+var rwsActions = map[string]rwsAction{
+	"pp-to-main": {"move the program pointer of all tasks to main", true,
+		func(c *rws.Client, _ string) error { return c.ResetPP() }},
+	"start": {"start the program from the program pointer", true,
+		func(c *rws.Client, cycle string) error { return c.StartCycle(cycle) }},
+	"stop": {"stop the program", false,
+		func(c *rws.Client, _ string) error { return c.Stop() }},
+	"motors-on": {"switch the motors on", true,
+		func(c *rws.Client, _ string) error { return c.MotorsOn() }},
+	"motors-off": {"switch the motors off", false,
+		func(c *rws.Client, _ string) error { return c.MotorsOff() }},
+}
+
+// abbRWS runs the Robot Web Services commands
+func abbRWS(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return rwsUsage, nil
+	}
+	name := args[0]
+	if name == "backup" {
+		return rwsBackup(args[1:])
+	}
+	if name == "module" {
+		return rwsModule(args[1:])
+	}
+	host, cycle := "localhost", "once"
+	var user, password, robot, output string
+	confirmed := false
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--yes" {
+			confirmed = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return rwsUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		case "--robot":
+			robot = value
+		case "--output":
+			output = value
+		case "--cycle":
+			if value != "once" && value != "forever" {
+				return "", fmt.Errorf("invalid value %q for --cycle (use once or forever)", value)
+			}
+			cycle = value
+		default:
+			return rwsUsage, nil
+		}
+		i++
+	}
+
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	switch name {
+	case "state":
+		return rwsState(c)
+	case "info":
+		return rwsInfo(c, host, robot)
+	case "elog":
+		if output == "" {
+			return "", errors.New("--output is required")
+		}
+		return rwsElog(c, host, output)
+	}
+	action, ok := rwsActions[name]
+	if !ok {
+		return rwsUsage, nil
+	}
+	if dryRun() {
+		return fmt.Sprintf("Would %s on %s", action.describe, host), nil
+	}
+
+	mode, err := c.OperationMode()
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", host, err)
+	}
+	if action.auto {
+		if mode != "AUTO" {
+			return "", fmt.Errorf("the controller is in %s mode; %s needs automatic mode", mode, name)
+		}
+		if !confirmed {
+			answer, ok := readLine(fmt.Sprintf("This will %s on %s and the robot may move. Is the cell clear? [y/N]: ", action.describe, host))
+			if !ok {
+				return "", errors.New("confirmation needed; add --yes to confirm in scripts")
+			}
+			if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+				return "", errors.New("canceled")
+			}
+		}
+	}
+
+	if err := c.RequestMastership(); err != nil {
+		return "", err
+	}
+	defer c.ReleaseMastership()
+	if err := action.run(c, cycle); err != nil {
+		return "", err
+	}
+	state, err := rwsState(c)
+	if err != nil {
+		return "Done: " + action.describe, err
+	}
+	return fmt.Sprintf("Done: %s\n%s", action.describe, state), nil
+}
+
+// rwsState reports the mode and states of the controller
+func rwsState(c *rws.Client) (string, error) {
+	mode, err := c.OperationMode()
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", c.Base, err)
+	}
+	ctrl, err := c.ControllerState()
+	if err != nil {
+		return "", err
+	}
+	exec, err := c.ExecutionState()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Operation mode:   %s\nController state: %s\nExecution:        %s", mode, ctrl, exec), nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/storage"
+)
+
+// inventoryDir holds the controller snapshots of abb rws info in the project
+// directory, one directory per robot
+const inventoryDir = "inventory"
+
+// batteryCodes are the event log messages about the serial measurement
+// board battery that keeps the revolution counters
+var batteryCodes = map[string]bool{"38200": true, "38213": true}
+
+// controllerSnapshot is what abb rws info records about a controller
+type controllerSnapshot struct {
+	Taken        string                       `json:"taken"`
+	Host         string                       `json:"host"`
+	Controller   rws.Identity                 `json:"controller"`
+	System       rws.System                   `json:"system"`
+	RobotType    string                       `json:"robot_type,omitempty"`
+	Options      []string                     `json:"options"`
+	Clock        string                       `json:"clock,omitempty"`
+	ClockOffset  float64                      `json:"clock_offset_s"` // controller minus this computer
+	Battery      string                       `json:"battery"`
+	MechUnits    []rws.MechUnit               `json:"mech_units,omitempty"`
+	DriveModules map[string]map[string]string `json:"drive_modules,omitempty"`
+	// Unavailable lists what the controller did not answer, such as
+	// resources a RobotWare version does not have
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+var unsafeNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// rwsInfo takes a snapshot of the controller and stores it in the active
+// project under inventory/<robot>, reporting what changed since the last one
+func rwsInfo(c *rws.Client, host, robot string) (string, error) {
+	p := activeProject()
+	if p == nil {
+		return "", errors.New("abb rws info stores its snapshots in the project; select one with 'use <projectdir>'")
+	}
+	snap, err := takeSnapshot(c, host)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", host, err)
+	}
+	if robot == "" {
+		robot = snap.Controller.Name
+	}
+	if robot == "" {
+		robot = host
+	}
+	store, err := projectStore(p)
+	if err != nil {
+		return "", err
+	}
+	dir := inventoryDir + "/" + unsafeNameRe.ReplaceAllString(robot, "_")
+	previous, prevName := lastSnapshot(store, dir)
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	taken, _ := time.Parse(time.RFC3339, snap.Taken)
+	key := dir + "/" + taken.Format("20060102-150405") + ".json"
+	if err := store.Put(key, append(data, '\n')); err != nil {
+		return "", fmt.Errorf("storing the snapshot in %s: %v", store, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Controller %s (%s):\n", robot, host)
+	units := make([]string, len(snap.MechUnits))
+	for i, u := range snap.MechUnits {
+		units[i] = u.Name
+		if u.Axes != "" || u.Task != "" {
+			units[i] += " (" + strings.Trim(strings.Join([]string{axesText(u.Axes), u.Task}, ", "), ", ") + ")"
+		}
+	}
+	modules := make([]string, 0, len(snap.DriveModules))
+	for name := range snap.DriveModules {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	clock := "-"
+	if snap.Clock != "" {
+		clock = fmt.Sprintf("%s (%+.0f s from this computer)", snap.Clock, snap.ClockOffset)
+	}
+	for _, f := range [][2]string{
+		{"Serial", snap.Controller.Serial},
+		{"Type", snap.Controller.Type},
+		{"System", snap.System.Name},
+		{"RobotWare", snap.System.RobotWare},
+		{"Robot type", snap.RobotType},
+		{"Options", fmt.Sprintf("%d installed", len(snap.Options))},
+		{"Clock", clock},
+		{"Battery", snap.Battery},
+		{"Mech units", strings.Join(units, ", ")},
+		{"Drive modules", strings.Join(modules, ", ")},
+		{"Not available", strings.Join(snap.Unavailable, ", ")},
+	} {
+		if f[1] == "" {
+			if f[0] == "Not available" {
+				continue
+			}
+			f[1] = "-"
+		}
+		fmt.Fprintf(&b, "  %-14s %s\n", f[0]+":", f[1])
+	}
+	if previous != nil {
+		changes := snapshotChanges(previous, snap)
+		if len(changes) == 0 {
+			fmt.Fprintf(&b, "\nNo changes since %s\n", prevName)
+		} else {
+			fmt.Fprintf(&b, "\nChanges since %s:\n  %s\n", prevName, strings.Join(changes, "\n  "))
+		}
+	}
+	if dryRun() {
+		fmt.Fprintf(&b, "\nWould write %s\n", storage.Location(store, key))
+	} else {
+		fmt.Fprintf(&b, "\nWrote %s\n", storage.Location(store, key))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func axesText(axes string) string {
+	if axes == "" {
+		return ""
+	}
+	return axes + " axes"
+}
+
+// takeSnapshot reads the controller. Only the identity is required; what
+// else a controller does not answer is listed as unavailable.
+func takeSnapshot(c *rws.Client, host string) (*controllerSnapshot, error) {
+	id, err := c.Identity()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	snap := &controllerSnapshot{Taken: now.Format(time.RFC3339), Host: host, Controller: id, Options: []string{}}
+	missing := func(what string, err error) bool {
+		if err != nil {
+			snap.Unavailable = append(snap.Unavailable, what)
+			return true
+		}
+		return false
+	}
+
+	if s, err := c.System(); !missing("system", err) {
+		snap.System = s
+	}
+	if t, err := c.RobotType(); !missing("robot type", err) {
+		snap.RobotType = t
+	}
+	if o, err := c.Options(); !missing("options", err) {
+		snap.Options = o
+	}
+	if t, err := c.Clock(); !missing("clock", err) {
+		snap.Clock = t.Format("2006-01-02 15:04:05")
+		snap.ClockOffset = t.Sub(now).Round(time.Second).Seconds()
+	}
+	if events, err := c.Events(-1); !missing("battery (event log)", err) {
+		snap.Battery = "ok, no battery messages in the event log"
+		for i := len(events) - 1; i >= 0; i-- {
+			if batteryCodes[events[i].Code] {
+				e := events[i]
+				snap.Battery = fmt.Sprintf("%s %s (%s)", e.Code, e.Title, e.Time)
+				break
+			}
+		}
+	}
+	if u, err := c.MechUnits(); !missing("mechanical units", err) {
+		snap.MechUnits = u
+	}
+	if m, err := c.DriveModules(); !missing("drive modules", err) {
+		snap.DriveModules = m
+	}
+	return snap, nil
+}
+
+// lastSnapshot reads the newest snapshot under dir, nil when there is none
+func lastSnapshot(store storage.Store, dir string) (*controllerSnapshot, string) {
+	keys, err := store.List(dir + "/")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil, ""
+	}
+	var last string
+	for _, k := range keys {
+		if path.Dir(k) == dir && strings.HasSuffix(k, ".json") {
+			last = k
+		}
+	}
+	if last == "" {
+		return nil, ""
+	}
+	data, err := store.Get(last)
+	if err != nil {
+		return nil, ""
+	}
+	var snap controllerSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid snapshot %s: %v\n", storage.Location(store, last), err)
+		return nil, ""
+	}
+	return &snap, strings.TrimSuffix(path.Base(last), ".json")
+}
+
+// snapshotChanges lists what differs between two snapshots of a robot, such
+// as a RobotWare upgrade, a swapped controller or new options
+func snapshotChanges(old, cur *controllerSnapshot) []string {
+	var changes []string
+	for _, f := range []struct{ name, old, cur string }{
+		{"Serial", old.Controller.Serial, cur.Controller.Serial},
+		{"Controller name", old.Controller.Name, cur.Controller.Name},
+		{"System", old.System.Name, cur.System.Name},
+		{"RobotWare", old.System.RobotWare, cur.System.RobotWare},
+		{"Robot type", old.RobotType, cur.RobotType},
+		{"Battery", old.Battery, cur.Battery},
+	} {
+		if f.old != f.cur && f.cur != "" {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", f.name, orDefault(f.old, "-"), f.cur))
+		}
+	}
+	added, removed := stringDiff(old.Options, cur.Options)
+	if len(added) > 0 {
+		changes = append(changes, "Options added: "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 && !containsFold(cur.Unavailable, "options") {
+		changes = append(changes, "Options removed: "+strings.Join(removed, ", "))
+	}
+	return changes
+}
+
+// stringDiff returns the entries only in b and only in a
+func stringDiff(a, b []string) (added, removed []string) {
+	in := func(list []string, s string) bool {
+		for _, v := range list {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, s := range b {
+		if !in(a, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !in(b, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
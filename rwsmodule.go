@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+const rwsModuleUsage = `Usage: abb rws module put <dir|file.mod>... [--task T_ROB1] [--host localhost]
+       [--user <name>] [--password <pw>] [--approval <token>] [--ticket <token>]
+Deploys RAPID modules to a controller: copies them to HOME:/` + deployDir + ` and
+loads them into the task, replacing modules of the same name. The program
+must not be running.
+
+Production fleet targets need an approval: a token from a second person
+with --approval (see approve) or a signed change ticket with --ticket (see
+approve ticket), as set with 'fleet add --production'. The approval is recorded in the audit
+log with the deployment. --dry-run checks the approval and lists the
+modules without connecting.
+
+Examples:
+  abb rws module put MainModule.mod --host 192.168.125.1
+  abb rws module put RAPID/T_ROB1 --target cell3 --ticket ahc1.eyJh...`
+
+// deployDir is the folder under HOME: that module put deploys to
+const deployDir = "deploy"
+
+// rwsModule deploys modules to a controller
+func rwsModule(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "put" {
+		return rwsModuleUsage, nil
+	}
+	host, task := "localhost", "T_ROB1"
+	var user, password, token, ticket string
+	var sources []string
+	for i := 1; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			sources = append(sources, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return rwsModuleUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--task":
+			task = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		case "--approval":
+			token = value
+		case "--ticket":
+			ticket = value
+		default:
+			return rwsModuleUsage, nil
+		}
+		i++
+	}
+	if len(sources) == 0 {
+		return rwsModuleUsage, nil
+	}
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+	modules, _, err := vcModules(files)
+	if err != nil {
+		return "", err
+	}
+	approved, err := approveModules(host, token, ticket, modules)
+	if err != nil {
+		return "", err
+	}
+
+	if dryRun() {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Would deploy to %s, task %s:\n", host, task)
+		for _, m := range modules {
+			fmt.Fprintf(&b, "  upload HOME:/%s/%s and load module %s\n", deployDir, m.file, m.module)
+		}
+		if approved != "" {
+			fmt.Fprintf(&b, "Deployment %s\n", approved)
+		}
+		return strings.TrimRight(b.String(), "\n"), nil
+	}
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	if state, err := c.ExecutionState(); err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", host, err)
+	} else if state == "running" {
+		return "", errors.New("the program is running; stop it before loading modules")
+	}
+	if approved != "" {
+		recordAction(host, "deploy "+strings.Join(moduleNames(modules), ", "), approved, nil)
+	}
+	if err := c.RequestMastership(); err != nil {
+		return "", err
+	}
+	defer c.ReleaseMastership()
+	if err := c.CreateDir("HOME:", deployDir); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, m := range modules {
+		path := "HOME:/" + deployDir + "/" + m.file
+		if err := c.Upload(path, m.content); err != nil {
+			return strings.TrimRight(b.String(), "\n"), err
+		}
+		if err := c.LoadModule(task, rws.DevicePath(path)); err != nil {
+			return strings.TrimRight(b.String(), "\n"), fmt.Errorf("loading %s: %v", m.file, err)
+		}
+		fmt.Fprintf(&b, "Loaded %s into %s\n", m.module, task)
+	}
+	if approved != "" {
+		fmt.Fprintf(&b, "Deployment %s\n", approved)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// approveModules checks the approval of deploying modules to a host; see
+// deployApproval
+func approveModules(host, token, ticket string, modules []vcModule) (string, error) {
+	files := make(map[string][]byte, len(modules))
+	for _, m := range modules {
+		files[m.file] = m.content
+	}
+	return deployApproval(host, token, ticket, files)
+}
+
+func moduleNames(modules []vcModule) []string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.module
+	}
+	return names
+}
@@ -0,0 +1,273 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IO reads and writes controller signals; rws.Client implements it
+type IO interface {
+	ReadSignal(signal string) (string, error)
+	WriteSignal(signal, value string) error
+}
+
+// Entry is one line of the play log. Kind is a step kind, "watch" for a
+// change of a watched signal or "restore" for the end of a fault.
+type Entry struct {
+	At     time.Duration
+	Cycle  int
+	Kind   string
+	Signal string
+	Value  string
+	Text   string
+	Failed bool
+}
+
+func (e Entry) String() string {
+	s := fmt.Sprintf("%8.3f  #%d %-8s", e.At.Seconds(), e.Cycle, e.Kind)
+	if e.Signal != "" {
+		s += " " + e.Signal + "=" + e.Value
+	}
+	if e.Text != "" {
+		s += "  " + e.Text
+	}
+	if e.Failed {
+		s += "  FAIL"
+	}
+	return s
+}
+
+// Clock is the time a scenario is played in. A simulation implements it so
+// its time passes only as the player waits, as fast as it can compute.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration) error
+}
+
+// ErrCanceled is returned when the Cancel channel closes during a scenario
+var ErrCanceled = errors.New("scenario canceled")
+
+// Player plays scenarios against a controller, sampling the watched signals
+// every Poll while it waits
+type Player struct {
+	IO      IO
+	Poll    time.Duration
+	OnEntry func(Entry)     // Called for every entry as it happens
+	Cancel  <-chan struct{} // Stops the scenario at the next wait when closed
+	Clock   Clock           // The wall clock when nil
+
+	start   time.Time
+	cycle   int
+	watch   []string
+	last    map[string]string
+	entries []Entry
+}
+
+// Play runs all cycles of the scenario and returns the log. Failed checks
+// do not stop the scenario; communication errors do, after faults have
+// been restored.
+func (p *Player) Play(sc *Scenario) ([]Entry, error) {
+	if p.Poll <= 0 {
+		p.Poll = 50 * time.Millisecond
+	}
+	p.start, p.watch, p.last, p.entries = p.now(), sc.Watch, map[string]string{}, nil
+	if err := p.sample(); err != nil {
+		return p.entries, err
+	}
+	for p.cycle = 1; p.cycle <= sc.Repeat; p.cycle++ {
+		if err := p.playCycle(sc.Steps); err != nil {
+			return p.entries, err
+		}
+	}
+	return p.entries, nil
+}
+
+func (p *Player) playCycle(steps []Step) (err error) {
+	// Faults held to the end of the cycle, with the values to restore
+	var held []Step
+	defer func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			if rerr := p.write(held[i].Signal, held[i].Value); rerr == nil {
+				p.add(Entry{Kind: "restore", Signal: held[i].Signal, Value: held[i].Value})
+			} else if err == nil {
+				err = rerr
+			}
+		}
+	}()
+	for _, s := range steps {
+		switch s.Kind {
+		case Log:
+			p.add(Entry{Kind: Log, Text: s.Text})
+		case Wait:
+			if err := p.sleep(s.Duration); err != nil {
+				return err
+			}
+		case Set:
+			if err := p.write(s.Signal, s.Value); err != nil {
+				return err
+			}
+			p.add(Entry{Kind: Set, Signal: s.Signal, Value: s.Value, Text: s.Text})
+		case Pulse:
+			if err := p.write(s.Signal, s.Value); err != nil {
+				return err
+			}
+			p.add(Entry{Kind: Pulse, Signal: s.Signal, Value: s.Value, Text: fmt.Sprintf("for %s", s.Duration)})
+			serr := p.sleep(s.Duration)
+			if err := p.write(s.Signal, opposite(s.Value)); err != nil {
+				return err
+			}
+			if serr != nil {
+				return serr
+			}
+		case Fault:
+			before, err := p.IO.ReadSignal(s.Signal)
+			if err != nil {
+				return err
+			}
+			if err := p.write(s.Signal, s.Value); err != nil {
+				return err
+			}
+			p.add(Entry{Kind: Fault, Signal: s.Signal, Value: s.Value, Text: s.Text})
+			if s.Duration == 0 {
+				held = append(held, Step{Signal: s.Signal, Value: before})
+				continue
+			}
+			// Restore even when canceled, so no fault stays injected
+			serr := p.sleep(s.Duration)
+			if err := p.write(s.Signal, before); err != nil {
+				return err
+			}
+			p.add(Entry{Kind: "restore", Signal: s.Signal, Value: before})
+			if serr != nil {
+				return serr
+			}
+		case Expect:
+			v, err := p.IO.ReadSignal(s.Signal)
+			if err != nil {
+				return err
+			}
+			e := Entry{Kind: Expect, Signal: s.Signal, Value: v, Text: s.Text}
+			if !Equal(v, s.Value) {
+				e.Failed = true
+				e.Text = strings.TrimSpace(fmt.Sprintf("expected %s %s", s.Value, s.Text))
+			}
+			p.add(e)
+		case WaitFor:
+			if err := p.waitFor(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Player) waitFor(s Step) error {
+	begin := p.now()
+	for {
+		v, err := p.IO.ReadSignal(s.Signal)
+		if err != nil {
+			return err
+		}
+		if Equal(v, s.Value) {
+			p.add(Entry{Kind: WaitFor, Signal: s.Signal, Value: v, Text: strings.TrimSpace(fmt.Sprintf("after %.2f s %s", p.now().Sub(begin).Seconds(), s.Text))})
+			return nil
+		}
+		if p.now().Sub(begin) >= s.Timeout {
+			p.add(Entry{Kind: WaitFor, Signal: s.Signal, Value: v, Failed: true,
+				Text: strings.TrimSpace(fmt.Sprintf("expected %s within %s %s", s.Value, s.Timeout, s.Text))})
+			return nil
+		}
+		if err := p.sleep(p.Poll); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits while logging changes of the watched signals
+func (p *Player) sleep(d time.Duration) error {
+	end := p.now().Add(d)
+	for {
+		if err := p.sample(); err != nil {
+			return err
+		}
+		left := end.Sub(p.now())
+		if left <= 0 {
+			return nil
+		}
+		step := time.Duration(math.Min(float64(left), float64(p.Poll)))
+		if p.Clock != nil {
+			select {
+			case <-p.Cancel:
+				return ErrCanceled
+			default:
+			}
+			if err := p.Clock.Sleep(step); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-p.Cancel:
+			return ErrCanceled
+		case <-time.After(step):
+		}
+	}
+}
+
+func (p *Player) now() time.Time {
+	if p.Clock != nil {
+		return p.Clock.Now()
+	}
+	return time.Now()
+}
+
+func (p *Player) sample() error {
+	for _, signal := range p.watch {
+		v, err := p.IO.ReadSignal(signal)
+		if err != nil {
+			return err
+		}
+		if last, ok := p.last[signal]; !ok || last != v {
+			p.last[signal] = v
+			p.add(Entry{Kind: "watch", Signal: signal, Value: v})
+		}
+	}
+	return nil
+}
+
+func (p *Player) write(signal, value string) error {
+	if err := p.IO.WriteSignal(signal, value); err != nil {
+		return fmt.Errorf("setting %s: %v", signal, err)
+	}
+	return nil
+}
+
+func (p *Player) add(e Entry) {
+	e.At, e.Cycle = p.now().Sub(p.start), p.cycle
+	p.entries = append(p.entries, e)
+	if p.OnEntry != nil {
+		p.OnEntry(e)
+	}
+}
+
+// Equal compares signal values numerically when both are numbers
+func Equal(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return math.Abs(x-y) < 1e-6
+	}
+	return strings.EqualFold(a, b)
+}
+
+// opposite is the value a pulse returns to
+func opposite(v string) string {
+	if Equal(v, "0") {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,319 @@
+// Package scenario reads and plays I/O scenarios: scripted sequences of
+// signal changes, such as part present pulses and injected faults, with
+// checks of how the robot responds
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Step kinds
+const (
+	Set     = "set"      // set: diPartPresent=1
+	Pulse   = "pulse"    // pulse: diStart, duration: 200ms
+	Wait    = "wait"     // wait: 2s
+	WaitFor = "wait_for" // wait_for: doGripClosed=1, timeout: 5s
+	Expect  = "expect"   // expect: doCycleDone=1
+	Fault   = "fault"    // fault: diAirOK=0, hold: 3s
+	Log     = "log"      // log: part 2
+)
+
+// Defaults for steps that leave out their duration
+const (
+	DefaultPulse   = 200 * time.Millisecond
+	DefaultTimeout = 10 * time.Second
+)
+
+// Step is one line of a scenario
+type Step struct {
+	Line     int
+	Kind     string
+	Signal   string
+	Value    string
+	Duration time.Duration // Pulse width, wait time or fault hold time
+	Timeout  time.Duration // For wait_for
+	Text     string        // Log message or note
+}
+
+func (s Step) String() string {
+	switch s.Kind {
+	case Wait:
+		return fmt.Sprintf("wait %s", s.Duration)
+	case Log:
+		return "log " + s.Text
+	case Pulse:
+		return fmt.Sprintf("pulse %s=%s for %s", s.Signal, s.Value, s.Duration)
+	case WaitFor:
+		return fmt.Sprintf("wait for %s=%s (timeout %s)", s.Signal, s.Value, s.Timeout)
+	case Fault:
+		if s.Duration == 0 {
+			return fmt.Sprintf("fault %s=%s until the end of the cycle", s.Signal, s.Value)
+		}
+		return fmt.Sprintf("fault %s=%s for %s", s.Signal, s.Value, s.Duration)
+	}
+	return fmt.Sprintf("%s %s=%s", s.Kind, s.Signal, s.Value)
+}
+
+// Scenario is a named sequence of steps, played Repeat times. The Watch
+// signals are logged whenever they change.
+type Scenario struct {
+	Name   string
+	Repeat int
+	Watch  []string
+	Steps  []Step
+}
+
+// Load reads a scenario file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
+
+// Parse reads a scenario in the YAML subset the player understands:
+// top-level keys name, repeat, watch (a list) and steps, a list of maps
+// whose first key is the step kind.
+//
+//	name: Part cycle
+//	watch: [doGripClosed, doCycleDone]
+//	steps:
+//	  - set: diPartPresent=1
+//	  - pulse: diStart
+//	    duration: 200ms
+//	  - wait_for: doCycleDone=1
+//	    timeout: 30s
+func Parse(src string) (*Scenario, error) {
+	sc := &Scenario{Repeat: 1}
+	section := ""
+	var step *Step
+	finish := func() error {
+		if step == nil {
+			return nil
+		}
+		if err := check(step); err != nil {
+			return fmt.Errorf("line %d: %v", step.Line, err)
+		}
+		sc.Steps = append(sc.Steps, *step)
+		step = nil
+		return nil
+	}
+	for n, raw := range strings.Split(src, "\n") {
+		line := stripComment(strings.TrimRight(raw, " \t\r"))
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		text := strings.TrimSpace(line)
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("line %d: %s", n+1, fmt.Sprintf(format, args...))
+		}
+
+		if indent == 0 {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			key, value, ok := strings.Cut(text, ":")
+			if !ok {
+				return nil, errorf("expected key: value")
+			}
+			section = strings.TrimSpace(key)
+			value = unquote(strings.TrimSpace(value))
+			switch section {
+			case "name":
+				sc.Name = value
+			case "repeat":
+				r, err := strconv.Atoi(value)
+				if err != nil || r < 1 {
+					return nil, errorf("repeat must be a positive number")
+				}
+				sc.Repeat = r
+			case "watch":
+				if value != "" {
+					sc.Watch = append(sc.Watch, flowList(value)...)
+				}
+			case "steps":
+				if value != "" {
+					return nil, errorf("steps must be a list")
+				}
+			default:
+				return nil, errorf("unknown key %q", section)
+			}
+			continue
+		}
+
+		item := strings.HasPrefix(text, "- ")
+		if item {
+			text = strings.TrimSpace(text[2:])
+		}
+		switch section {
+		case "watch":
+			if !item {
+				return nil, errorf("expected a list item")
+			}
+			sc.Watch = append(sc.Watch, unquote(text))
+		case "steps":
+			key, value, ok := strings.Cut(text, ":")
+			if !ok {
+				return nil, errorf("expected key: value")
+			}
+			key, value = strings.TrimSpace(key), unquote(strings.TrimSpace(value))
+			if item {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+				step = &Step{Line: n + 1, Kind: key}
+				if err := setMain(step, value); err != nil {
+					return nil, errorf("%v", err)
+				}
+				continue
+			}
+			if step == nil {
+				return nil, errorf("expected a list item")
+			}
+			if err := setField(step, key, value); err != nil {
+				return nil, errorf("%v", err)
+			}
+		default:
+			return nil, errorf("unexpected indentation")
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(sc.Steps) == 0 {
+		return nil, fmt.Errorf("the scenario has no steps")
+	}
+	return sc, nil
+}
+
+// setMain interprets the value of the key that names the step
+func setMain(s *Step, value string) error {
+	switch s.Kind {
+	case Wait:
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.Duration = d
+	case Log:
+		s.Text = value
+	case Set, Pulse, WaitFor, Expect, Fault:
+		signal, v, ok := strings.Cut(value, "=")
+		s.Signal = strings.TrimSpace(signal)
+		s.Value = "1"
+		if ok {
+			s.Value = normalize(strings.TrimSpace(v))
+		} else if s.Kind != Pulse {
+			return fmt.Errorf("%s needs signal=value", s.Kind)
+		}
+		if s.Kind == Pulse {
+			s.Duration = DefaultPulse
+		}
+		if s.Kind == WaitFor {
+			s.Timeout = DefaultTimeout
+		}
+	default:
+		return fmt.Errorf("unknown step %q (set, pulse, wait, wait_for, expect, fault, log)", s.Kind)
+	}
+	return nil
+}
+
+func setField(s *Step, key, value string) error {
+	switch key {
+	case "duration", "hold":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.Duration = d
+	case "timeout":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.Timeout = d
+	case "note":
+		s.Text = value
+	default:
+		return fmt.Errorf("unknown field %q", key)
+	}
+	return nil
+}
+
+func check(s *Step) error {
+	switch s.Kind {
+	case Set, Pulse, WaitFor, Expect, Fault:
+		if s.Signal == "" {
+			return fmt.Errorf("%s needs a signal", s.Kind)
+		}
+	}
+	if s.Kind == WaitFor && s.Timeout <= 0 {
+		return fmt.Errorf("wait_for needs a positive timeout")
+	}
+	if s.Kind == Pulse && s.Duration <= 0 {
+		return fmt.Errorf("pulse needs a positive duration")
+	}
+	return nil
+}
+
+// parseDuration accepts Go durations such as 250ms or 1.5s, and plain
+// numbers as seconds
+func parseDuration(s string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 0 {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// normalize turns the boolean words of YAML into signal values
+func normalize(v string) string {
+	switch strings.ToLower(v) {
+	case "true", "on", "high", "yes":
+		return "1"
+	case "false", "off", "low", "no":
+		return "0"
+	}
+	return v
+}
+
+func stripComment(line string) string {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '#' && !quoted && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// flowList reads [a, b, c]
+func flowList(s string) []string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
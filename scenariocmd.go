@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/scenario"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+func init() {
+	commandRegistry["scenario"] = Command{
+		Group:       groupIntegration,
+		Description: "Play I/O scenarios against a controller or simulated PLC logic and log the response",
+		Usage:       scenarioUsage,
+		Execute:     text(runScenario),
+	}
+}
+
+const scenarioUsage = `Usage: scenario play <cycle.yaml> [--host localhost] [--poll 50ms]
+       [--log responses.csv] [--dry-run] [--user "Default User"] [--password robotics]
+       scenario sim <logic.st> <cycle.yaml> [--block <name>] [--cycle 10ms]
+       [--log responses.csv]
+Drives I/O sequences against a real or virtual controller over Robot Web
+Services (RobotWare 6) for repeatable commissioning tests: part present
+pulses, fault injections and checks of the robot's response. Watched
+signals are logged with a timestamp whenever they change. Faults are
+restored when their hold time ends, at the end of the cycle or on Ctrl+C.
+
+Scenario file (YAML subset):
+  name: Part cycle with air pressure fault
+  repeat: 3
+  watch: [doGripClosed, doCycleDone]
+  steps:
+    - set: diPartPresent=1
+    - pulse: diStart            # duration: 200ms by default
+    - wait_for: doGripClosed=1
+      timeout: 5s
+    - fault: diAirPressureOK=0  # hold: 2s, or until the end of the cycle
+      hold: 2s
+    - expect: doAlarm=1
+    - wait: 1s
+    - set: diPartPresent=0
+
+Steps: set, pulse, wait, wait_for, expect, fault, log. Signals are given
+by name, or as network/device/name for signals on a device. Inputs can
+only be set on a virtual controller or when simulated.
+
+scenario sim plays the scenario against PLC logic in Structured Text
+instead, such as the function blocks of 'generate handshake' and 'generate
+zone-interlock', so the PLC side of an interface can be tested before the
+hardware exists. The block (--block, or the only PROGRAM or FUNCTION_BLOCK
+of the file) is scanned every --cycle in simulated time, which runs as fast
+as it can be computed. Signals are its variables: inputs are set, outputs
+and internal variables are read, by name or as Permit[1,2] and Watchdog.ET.
+BOOL values are 1 and 0 and TIME values literals such as T#30s. A set takes
+effect at the next scan. Supported are the elementary types, arrays,
+functions, function blocks and the standard timers, triggers and counters.
+
+Examples:
+  scenario play cycle.yaml --dry-run
+  scenario play cycle.yaml --host 192.168.125.1 --log run1.csv
+  scenario sim FB_JobHandshake.st handshake.yaml --log sim.csv`
+
+func runScenario(args []string) (string, error) {
+	if len(args) >= 3 && args[0] == "sim" {
+		return simulateScenario(args[1:])
+	}
+	if len(args) < 2 || args[0] != "play" {
+		return scenarioUsage, nil
+	}
+	path := args[1]
+	host, logPath := "localhost", ""
+	var user, password string
+	poll := 50 * time.Millisecond
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return scenarioUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--poll":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 10*time.Millisecond {
+				return "", fmt.Errorf("invalid value %q for --poll (at least 10ms)", value)
+			}
+			poll = d
+		case "--log":
+			logPath = value
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		default:
+			return scenarioUsage, nil
+		}
+		i++
+	}
+
+	sc, err := scenario.Load(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	if dryRun() {
+		return describeScenario(sc), nil
+	}
+
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	cancel := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			close(cancel)
+		}
+	}()
+
+	fmt.Printf("Playing %s against %s (Ctrl+C to stop)\n", scenarioName(sc, path), c.Base)
+	player := &scenario.Player{
+		IO:      c,
+		Poll:    poll,
+		Cancel:  cancel,
+		OnEntry: func(e scenario.Entry) { fmt.Println(e) },
+	}
+	entries, playErr := player.Play(sc)
+	return scenarioResult(sc, entries, playErr, logPath)
+}
+
+// simulateScenario plays a scenario against Structured Text logic
+func simulateScenario(args []string) (string, error) {
+	source, path := args[0], args[1]
+	var block, logPath string
+	cycle := 10 * time.Millisecond
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return scenarioUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--block":
+			block = value
+		case "--cycle":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < time.Millisecond {
+				return "", fmt.Errorf("invalid value %q for --cycle (at least 1ms)", value)
+			}
+			cycle = d
+		case "--log":
+			logPath = value
+		default:
+			return scenarioUsage, nil
+		}
+		i++
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", err
+	}
+	prog, err := st.Parse(string(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+	machine, err := st.NewMachine(prog, block)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+	sc, err := scenario.Load(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	sim, err := st.NewSim(machine, cycle)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+
+	fmt.Printf("Simulating %s against %s, scanned every %s\n", scenarioName(sc, path), machine.Name(), cycle)
+	player := &scenario.Player{
+		IO:      sim,
+		Clock:   sim,
+		Poll:    cycle,
+		OnEntry: func(e scenario.Entry) { fmt.Println(e) },
+	}
+	entries, playErr := player.Play(sc)
+	return scenarioResult(sc, entries, playErr, logPath)
+}
+
+// scenarioResult writes the log and sums up the checks of a played
+// scenario
+func scenarioResult(sc *scenario.Scenario, entries []scenario.Entry, playErr error, logPath string) (string, error) {
+	var result strings.Builder
+	if logPath != "" {
+		if err := writeScenarioLog(logPath, entries); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&result, "Log written to %s\n", logPath)
+	}
+	failed := 0
+	for _, e := range entries {
+		if e.Failed {
+			failed++
+		}
+	}
+	if playErr != nil {
+		return strings.TrimRight(result.String(), "\n"), playErr
+	}
+	if failed > 0 {
+		return strings.TrimRight(result.String(), "\n"), fmt.Errorf("%d of the checks failed", failed)
+	}
+	fmt.Fprintf(&result, "%d cycles played, all checks passed", sc.Repeat)
+	return result.String(), nil
+}
+
+func scenarioName(sc *scenario.Scenario, path string) string {
+	if sc.Name != "" {
+		return sc.Name
+	}
+	return path
+}
+
+// describeScenario lists the steps without touching a controller
+func describeScenario(sc *scenario.Scenario) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d steps", scenarioName(sc, "Scenario"), len(sc.Steps))
+	if sc.Repeat > 1 {
+		fmt.Fprintf(&b, ", %d cycles", sc.Repeat)
+	}
+	b.WriteString("\n")
+	if len(sc.Watch) > 0 {
+		fmt.Fprintf(&b, "Watching: %s\n", strings.Join(sc.Watch, ", "))
+	}
+	var minimum time.Duration
+	for i, s := range sc.Steps {
+		line := fmt.Sprintf("%3d. %s", i+1, s)
+		if s.Text != "" && s.Kind != scenario.Log {
+			line += "  (" + s.Text + ")"
+		}
+		b.WriteString(line + "\n")
+		if s.Kind != scenario.WaitFor {
+			minimum += s.Duration
+		}
+	}
+	fmt.Fprintf(&b, "Shortest cycle: %.1f s plus the wait_for steps", minimum.Seconds())
+	return b.String()
+}
+
+func writeScenarioLog(path string, entries []scenario.Entry) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	records := [][]string{{"time_s", "cycle", "kind", "signal", "value", "result", "text"}}
+	for _, e := range entries {
+		result := ""
+		if e.Kind == scenario.Expect || e.Kind == scenario.WaitFor {
+			result = "pass"
+			if e.Failed {
+				result = "fail"
+			}
+		}
+		records = append(records, []string{
+			strconv.FormatFloat(e.At.Seconds(), 'f', 3, 64),
+			strconv.Itoa(e.Cycle), e.Kind, e.Signal, e.Value, result, e.Text,
+		})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const scoreUsage = `Usage: abb score <dir|file.mod>... [--trend 10]
+Rates RAPID code against best practices on a 0-100 scale: lint findings,
+comment density, routine length, target naming and error handling coverage.
+--trend also scores the last N commits that touched the directory, oldest
+first, so gradual improvement of legacy code becomes visible.
+
+Examples:
+  abb score ./RAPID/TASK1
+  abb score ./RAPID --trend 20`
+
+// abbScore prints the quality score and optionally its trend over commits
+func abbScore(args []string) (string, error) {
+	var sources []string
+	trend := 0
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--trend" {
+			if i+1 >= len(args) {
+				return scoreUsage, nil
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return "", errors.New("--trend needs a number of commits")
+			}
+			trend = n
+			i++
+			continue
+		}
+		sources = append(sources, args[i])
+	}
+	if len(sources) == 0 {
+		sources = projectSources()
+	}
+	if len(sources) == 0 {
+		return scoreUsage, nil
+	}
+
+	paths, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", errors.New("no RAPID modules found")
+	}
+	files := make(map[string]string)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		files[p] = string(data)
+	}
+	result := rapid.Score(files).String()
+
+	if trend > 0 {
+		history, err := scoreTrend(sources[0], trend)
+		if err != nil {
+			return result, err
+		}
+		result += "\n\n" + history
+	}
+	return result, nil
+}
+
+// scoreTrend scores the modules under path at each of the last n commits
+// that changed it
+func scoreTrend(path string, n int) (string, error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+
+	log, err := git(root, "log", "-n", strconv.Itoa(n), "--format=%H %h %ad", "--date=short", "--", rel)
+	if err != nil {
+		return "", err
+	}
+	if log == "" {
+		return "", fmt.Errorf("no commits touch %s", path)
+	}
+	commits := strings.Split(log, "\n")
+
+	var b strings.Builder
+	b.WriteString("Trend (oldest first):\n")
+	prev := -1
+	for i := len(commits) - 1; i >= 0; i-- {
+		fields := strings.Fields(commits[i])
+		hash, short, date := fields[0], fields[1], fields[2]
+		list, err := git(root, "ls-tree", "-r", "--name-only", hash, "--", rel)
+		if err != nil {
+			return "", err
+		}
+		files := make(map[string]string)
+		for _, name := range strings.Split(list, "\n") {
+			if name == "" || !rapid.IsSourceFile(name) {
+				continue
+			}
+			src, err := git(root, "show", hash+":"+name)
+			if err != nil {
+				return "", err
+			}
+			files[name] = src
+		}
+		if len(files) == 0 {
+			continue
+		}
+		score := rapid.Score(files).Score
+		delta := ""
+		if prev >= 0 && score != prev {
+			delta = fmt.Sprintf(" (%+d)", score-prev)
+		}
+		fmt.Fprintf(&b, "  %s %s %3d%s\n", date, short, score, delta)
+		prev = score
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// KeychainEnv set to off keeps the encryption key in the key file even when
+// an OS keychain is available, e.g. on build servers without a desktop session
+const KeychainEnv = "AUTOMATION_HELPER_KEYCHAIN"
+
+// keychain entry holding the encryption key
+const (
+	service = "automation-helper"
+	account = "secrets-key"
+)
+
+// keychain stores one value in the OS credential store through its command
+// line tool
+type keychain struct {
+	name string
+	get  func() (string, error)
+	set  func(value string) error
+}
+
+// osKeychain returns the credential store of this system, or nil when there
+// is none the tool can use
+func osKeychain() *keychain {
+	if strings.EqualFold(os.Getenv(KeychainEnv), "off") {
+		return nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil
+		}
+		get := func() (string, error) {
+			out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+			return strings.TrimSpace(string(out)), err
+		}
+		return &keychain{
+			name: "macOS keychain",
+			get:  get,
+			set: func(value string) error {
+				// security -i reads the command from stdin, which keeps the
+				// key out of the process list; it reports a failed command
+				// only on stderr, so the key is read back
+				quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+				cmd := exec.Command("security", "-i")
+				cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w \"%s\"\n", service, account, quoted))
+				if err := cmd.Run(); err != nil {
+					return err
+				}
+				if stored, err := get(); err != nil || stored != value {
+					return fmt.Errorf("the macOS keychain did not store the key")
+				}
+				return nil
+			},
+		}
+	case "linux", "freebsd":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil
+		}
+		return &keychain{
+			name: "Secret Service (secret-tool)",
+			get: func() (string, error) {
+				out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+				return strings.TrimSpace(string(out)), err
+			},
+			set: func(value string) error {
+				cmd := exec.Command("secret-tool", "store", "--label=Automation Helper secrets key", "service", service, "account", account)
+				cmd.Stdin = bytes.NewBufferString(value)
+				return cmd.Run()
+			},
+		}
+	}
+	return nil
+}
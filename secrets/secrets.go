@@ -0,0 +1,239 @@
+// Package secrets stores API keys and controller credentials encrypted
+// (AES-256-GCM) in the data directory. The encryption key is kept in the OS
+// keychain where one is available (macOS Keychain, Secret Service through
+// secret-tool) and otherwise in a key file only the user can read, which
+// keeps secrets out of scripts and shell history but is no protection
+// against someone with access to the user account.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+// Files in the data directory
+const (
+	storeFile = "secrets.enc"
+	keyFile   = "secrets.key"
+)
+
+// Known maps the secrets the commands use to the environment variables that
+// override them
+var Known = map[string]string{
+	"openai_api_key":       "OPENAI_API_KEY",
+	"azure_openai_api_key": "AZURE_OPENAI_API_KEY",
+	"anthropic_api_key":    "ANTHROPIC_API_KEY",
+	"slack_signing_secret": "SLACK_SIGNING_SECRET",
+	"teams_webhook_secret": "TEAMS_WEBHOOK_SECRET",
+	"grpc_token":           "AUTOMATION_HELPER_GRPC_TOKEN",
+	"pushover_token":       "PUSHOVER_TOKEN",
+	"smtp_url":             "AUTOMATION_HELPER_SMTP_URL",
+	"s3_access_key":        "AWS_ACCESS_KEY_ID",
+	"s3_secret_key":        "AWS_SECRET_ACCESS_KEY",
+}
+
+var nameRe = regexp.MustCompile(`^[a-z0-9_]+(/[^\s/]+)?$`)
+
+// Get returns a secret, from its environment variable when set and
+// otherwise from the store; an unknown secret is empty
+func Get(name string) (string, error) {
+	if env, ok := Known[name]; ok {
+		if v := os.Getenv(env); v != "" {
+			return v, nil
+		}
+	}
+	store, err := read()
+	if err != nil {
+		return "", err
+	}
+	return store[name], nil
+}
+
+// Set stores a secret, replacing an older value
+func Set(name, value string) error {
+	if !nameRe.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q; use lower case letters, digits and _", name)
+	}
+	store, err := read()
+	if err != nil {
+		return err
+	}
+	store[name] = value
+	return write(store)
+}
+
+// Delete removes a secret from the store
+func Delete(name string) error {
+	store, err := read()
+	if err != nil {
+		return err
+	}
+	if _, ok := store[name]; !ok {
+		return fmt.Errorf("secret %q is not stored", name)
+	}
+	delete(store, name)
+	return write(store)
+}
+
+// Names returns the names of the stored secrets in sorted order
+func Names() ([]string, error) {
+	store, err := read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(store))
+	for name := range store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RWSName is the name controller credentials are stored under
+func RWSName(host string) string {
+	return "rws/" + strings.ToLower(host)
+}
+
+// RWSCredentials returns the stored user and password of a controller
+func RWSCredentials(host string) (user, password string, ok bool, err error) {
+	value, err := Get(RWSName(host))
+	if err != nil || value == "" {
+		return "", "", false, err
+	}
+	user, password, _ = strings.Cut(value, "\n")
+	return user, password, true, nil
+}
+
+// SetRWSCredentials stores the user and password of a controller
+func SetRWSCredentials(host, user, password string) error {
+	return Set(RWSName(host), user+"\n"+password)
+}
+
+// Backend describes where the encryption key is kept
+func Backend() string {
+	if k := osKeychain(); k != nil {
+		if v, err := k.get(); err == nil && v != "" {
+			return k.name
+		}
+	}
+	if path, err := config.Path(keyFile); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			return "key file " + path
+		}
+	}
+	if k := osKeychain(); k != nil {
+		return k.name
+	}
+	return "key file"
+}
+
+// key returns the encryption key, creating one when create is set and none
+// exists yet. The keychain is tried first, then the key file.
+func key(create bool) ([]byte, error) {
+	kc := osKeychain()
+	if kc != nil {
+		if v, err := kc.get(); err == nil && v != "" {
+			return base64.StdEncoding.DecodeString(v)
+		}
+	}
+	path, err := config.Path(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !create {
+		return nil, nil
+	}
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(k)
+	if kc != nil && kc.set(encoded) == nil {
+		return k, nil
+	}
+	return k, os.WriteFile(path, []byte(encoded+"\n"), 0o600)
+}
+
+func read() (map[string]string, error) {
+	store := make(map[string]string)
+	path, err := config.Path(storeFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	k, err := key(false)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, fmt.Errorf("the key of %s is missing from the keychain and %s; delete the file and store the secrets again", path, keyFile)
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is corrupt", path)
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cannot be decrypted with the stored key", path)
+	}
+	if err := json.Unmarshal(plain, &store); err != nil {
+		return nil, fmt.Errorf("%s is corrupt: %v", path, err)
+	}
+	return store, nil
+}
+
+func write(store map[string]string) error {
+	path, err := config.Path(storeFile)
+	if err != nil {
+		return err
+	}
+	k, err := key(true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return os.WriteFile(path, gcm.Seal(nonce, nonce, plain, nil), 0o600)
+}
+
+func newGCM(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
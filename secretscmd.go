@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/secrets"
+)
+
+func init() {
+	commandRegistry["secrets"] = Command{
+		Group:       groupSystem,
+		Description: "Store API keys and controller credentials encrypted",
+		Usage:       secretsUsage,
+		Execute:     text(runSecrets),
+	}
+}
+
+const secretsUsage = `Usage:
+  secrets list
+  secrets set <name>           Prompts for the value
+  secrets set rws <host>       Prompts for the controller user and password
+  secrets delete <name>
+  secrets delete rws <host>
+
+Secrets are encrypted in the data directory with a key kept in the OS
+keychain where available. Commands read them when the matching environment
+variable is not set:
+%s
+Controller credentials are used by the RWS commands (alarms, dashboard, log,
+scenario, timecheck, abb uas, abb vc) for that host when --user and
+--password are not given.
+
+Examples:
+  secrets set openai_api_key
+  secrets set rws 192.168.125.1
+  secrets list`
+
+func runSecrets(args []string) (string, error) {
+	usage := fmt.Sprintf(secretsUsage, knownSecrets())
+	if len(args) < 1 {
+		return usage, nil
+	}
+	switch args[0] {
+	case "list":
+		return secretsList()
+	case "set":
+		switch {
+		case len(args) == 3 && args[1] == "rws":
+			return secretsSetRWS(args[2])
+		case len(args) == 2:
+			value, ok := readPassword(fmt.Sprintf("Value for %s: ", args[1]))
+			if !ok || value == "" {
+				return "", errors.New("no value given")
+			}
+			if err := secrets.Set(args[1], value); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Stored %s (key in %s).", args[1], secrets.Backend()), nil
+		}
+	case "delete":
+		name := ""
+		switch {
+		case len(args) == 3 && args[1] == "rws":
+			name = secrets.RWSName(args[2])
+		case len(args) == 2:
+			name = args[1]
+		default:
+			return usage, nil
+		}
+		if err := secrets.Delete(name); err != nil {
+			return "", err
+		}
+		return "Deleted " + name, nil
+	}
+	return usage, nil
+}
+
+// knownSecrets lists the secrets commands use with their variables
+func knownSecrets() string {
+	names := make([]string, 0, len(secrets.Known))
+	for name := range secrets.Known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-22s %s\n", name, secrets.Known[name])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func secretsList() (string, error) {
+	names, err := secrets.Names()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Key storage: %s\n", secrets.Backend())
+	if len(names) == 0 {
+		b.WriteString("No secrets stored.\n")
+	}
+	for _, name := range names {
+		note := ""
+		if env, ok := secrets.Known[name]; ok && os.Getenv(env) != "" {
+			note = " (overridden by " + env + ")"
+		}
+		fmt.Fprintf(&b, "  %s%s\n", name, note)
+	}
+	for name, env := range secrets.Known {
+		if os.Getenv(env) != "" && !containsFold(names, name) {
+			fmt.Fprintf(&b, "  %s (from %s, not stored)\n", name, env)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func secretsSetRWS(host string) (string, error) {
+	user, ok := readLine(fmt.Sprintf("User [%s]: ", rws.DefaultUser))
+	if !ok {
+		return "", errors.New("no user given")
+	}
+	if user == "" {
+		user = rws.DefaultUser
+	}
+	password, ok := readPassword("Password: ")
+	if !ok || password == "" {
+		return "", errors.New("no password given")
+	}
+	if err := secrets.SetRWSCredentials(host, user, password); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Stored credentials of %s for %s (key in %s).", user, host, secrets.Backend()), nil
+}
+
+// rwsCredentials completes the --user and --password options of a
+// controller command: without either, the credentials stored for the host
+// are used, and the factory credentials fill whatever is still missing
+func rwsCredentials(host, user, password string) (string, string) {
+	if user == "" && password == "" {
+		stored, pw, ok, err := secrets.RWSCredentials(host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if ok {
+			return stored, pw
+		}
+	}
+	if user == "" {
+		user = rws.DefaultUser
+	}
+	if password == "" {
+		password = rws.DefaultPassword
+	}
+	return user, password
+}
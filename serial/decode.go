@@ -0,0 +1,122 @@
+package serial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/checksum"
+)
+
+// Decoders are the display modes of the terminal
+var Decoders = []string{"hex", "ascii", "modbus-rtu"}
+
+// Decode formats one received frame for a display mode
+func Decode(mode string, frame []byte) string {
+	switch mode {
+	case "ascii":
+		return ASCII(frame)
+	case "modbus-rtu":
+		return Hex(frame) + "  " + ModbusRTU(frame)
+	}
+	return Hex(frame)
+}
+
+// Hex writes bytes as 01 03 00 0A
+func Hex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, " ")
+}
+
+// controlNames are the ASCII control characters of framed protocols
+var controlNames = map[byte]string{
+	0x00: "NUL", 0x01: "SOH", 0x02: "STX", 0x03: "ETX", 0x04: "EOT", 0x05: "ENQ",
+	0x06: "ACK", 0x0a: "LF", 0x0d: "CR", 0x10: "DLE", 0x15: "NAK", 0x17: "ETB", 0x1b: "ESC",
+}
+
+// ASCII writes printable text as is and other bytes as <STX> or <x8F>
+func ASCII(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c >= 0x20 && c < 0x7f:
+			b.WriteByte(c)
+		case controlNames[c] != "":
+			b.WriteString("<" + controlNames[c] + ">")
+		default:
+			fmt.Fprintf(&b, "<x%02X>", c)
+		}
+	}
+	return b.String()
+}
+
+var modbusFunctions = map[byte]string{
+	1: "read coils", 2: "read discrete inputs", 3: "read holding registers",
+	4: "read input registers", 5: "write single coil", 6: "write single register",
+	15: "write multiple coils", 16: "write multiple registers",
+}
+
+var modbusExceptions = map[byte]string{
+	1: "illegal function", 2: "illegal data address", 3: "illegal data value",
+	4: "server device failure", 5: "acknowledge", 6: "server device busy",
+	11: "gateway target failed to respond",
+}
+
+// ModbusRTU describes a Modbus RTU frame: unit, function, addresses or
+// data, and whether the CRC matches
+func ModbusRTU(frame []byte) string {
+	if len(frame) < 4 {
+		return "too short for Modbus RTU"
+	}
+	body := frame[:len(frame)-2]
+	crc := "CRC ok"
+	if want := checksum.CRC16Modbus(body); binary.LittleEndian.Uint16(frame[len(frame)-2:]) != want {
+		crc = fmt.Sprintf("CRC BAD (expected %02X %02X)", byte(want), byte(want>>8))
+	}
+	unit, fn, pdu := body[0], body[1], body[2:]
+	u16 := func(i int) int { return int(binary.BigEndian.Uint16(pdu[i:])) }
+	desc := ""
+	switch {
+	case fn&0x80 != 0 && len(pdu) == 1:
+		name := modbusExceptions[pdu[0]]
+		if name == "" {
+			name = fmt.Sprintf("code %d", pdu[0])
+		}
+		desc = fmt.Sprintf("exception to %s: %s", functionName(fn&0x7f), name)
+	case fn >= 1 && fn <= 4 && len(pdu) == 4:
+		desc = fmt.Sprintf("%s %d..%d (%d)", functionName(fn), u16(0), u16(0)+u16(2)-1, u16(2))
+	case fn >= 1 && fn <= 4 && len(pdu) >= 1 && int(pdu[0]) == len(pdu)-1:
+		data := pdu[1:]
+		if fn >= 3 && len(data)%2 == 0 {
+			var values []string
+			for i := 0; i < len(data); i += 2 {
+				values = append(values, fmt.Sprint(binary.BigEndian.Uint16(data[i:])))
+			}
+			desc = fmt.Sprintf("response to %s: %s", functionName(fn), strings.Join(values, ", "))
+		} else {
+			desc = fmt.Sprintf("response to %s: %d bytes", functionName(fn), len(data))
+		}
+	case (fn == 5 || fn == 6) && len(pdu) == 4:
+		desc = fmt.Sprintf("%s %d = %d", functionName(fn), u16(0), u16(2))
+		if fn == 5 {
+			desc = fmt.Sprintf("%s %d = %v", functionName(fn), u16(0), u16(2) == 0xff00)
+		}
+	case (fn == 15 || fn == 16) && len(pdu) == 4:
+		desc = fmt.Sprintf("response to %s %d..%d", functionName(fn), u16(0), u16(0)+u16(2)-1)
+	case (fn == 15 || fn == 16) && len(pdu) >= 5 && int(pdu[4]) == len(pdu)-5:
+		desc = fmt.Sprintf("%s %d..%d (%d)", functionName(fn), u16(0), u16(0)+u16(2)-1, u16(2))
+	default:
+		desc = fmt.Sprintf("%s, %d data bytes", functionName(fn), len(pdu))
+	}
+	return fmt.Sprintf("unit %d %s, %s", unit, desc, crc)
+}
+
+func functionName(fn byte) string {
+	if name := modbusFunctions[fn]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("function %d", fn)
+}
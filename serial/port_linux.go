@@ -0,0 +1,68 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var bauds = map[int]uint32{
+	1200: syscall.B1200, 2400: syscall.B2400, 4800: syscall.B4800, 9600: syscall.B9600,
+	19200: syscall.B19200, 38400: syscall.B38400, 57600: syscall.B57600,
+	115200: syscall.B115200, 230400: syscall.B230400, 460800: syscall.B460800,
+	921600: syscall.B921600,
+}
+
+// Port is an open serial port
+type Port struct {
+	f *os.File
+}
+
+// Open opens a port in raw mode with the given line settings. Reads return
+// after timeout without data, so a reader can notice frame gaps.
+func Open(path string, c Config, timeout time.Duration) (*Port, error) {
+	speed, ok := bauds[c.Baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", c.Baud)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	var t syscall.Termios
+	t.Cflag = speed | syscall.CREAD | syscall.CLOCAL
+	t.Cflag |= map[int]uint32{5: syscall.CS5, 6: syscall.CS6, 7: syscall.CS7, 8: syscall.CS8}[c.DataBits]
+	switch c.Parity {
+	case 'E':
+		t.Cflag |= syscall.PARENB
+	case 'O':
+		t.Cflag |= syscall.PARENB | syscall.PARODD
+	}
+	if c.StopBits == 2 {
+		t.Cflag |= syscall.CSTOPB
+	}
+	t.Ispeed, t.Ospeed = speed, speed
+	// VTIME is in tenths of a second; VMIN 0 makes read return on timeout
+	t.Cc[syscall.VMIN] = 0
+	t.Cc[syscall.VTIME] = uint8(max(1, min(255, int(timeout/(100*time.Millisecond)))))
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(&t))); errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s: cannot set line settings: %v", path, errno)
+	}
+	return &Port{f: f}, nil
+}
+
+// Read returns 0 bytes and no error when the timeout passes without data
+func (p *Port) Read(b []byte) (int, error) {
+	n, err := p.f.Read(b)
+	if n == 0 && err == io.EOF {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (p *Port) Write(b []byte) (int, error) { return p.f.Write(b) }
+func (p *Port) Close() error                { return p.f.Close() }
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package serial
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Port is an open serial port
+type Port struct{}
+
+// Open is only implemented on Linux
+func Open(path string, c Config, timeout time.Duration) (*Port, error) {
+	return nil, fmt.Errorf("serial ports are not supported on %s yet", runtime.GOOS)
+}
+
+func (p *Port) Read(b []byte) (int, error)  { return 0, fmt.Errorf("not supported") }
+func (p *Port) Write(b []byte) (int, error) { return 0, fmt.Errorf("not supported") }
+func (p *Port) Close() error                { return nil }
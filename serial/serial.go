@@ -0,0 +1,55 @@
+// Package serial opens RS-232/RS-485 ports and decodes the traffic of
+// common field protocols for the serial terminal
+package serial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the line setting of a port, e.g. 9600,8N1
+type Config struct {
+	Baud     int
+	DataBits int
+	Parity   byte // N, E or O
+	StopBits int
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("%d,%d%c%d", c.Baud, c.DataBits, c.Parity, c.StopBits)
+}
+
+// ParseConfig reads baud[,8N1]
+func ParseConfig(s string) (Config, error) {
+	c := Config{DataBits: 8, Parity: 'N', StopBits: 1}
+	baud, frame, hasFrame := strings.Cut(s, ",")
+	var err error
+	if c.Baud, err = strconv.Atoi(strings.TrimSpace(baud)); err != nil || c.Baud <= 0 {
+		return c, fmt.Errorf("invalid baud rate %q", baud)
+	}
+	if hasFrame {
+		frame = strings.ToUpper(strings.TrimSpace(frame))
+		if len(frame) != 3 || frame[0] < '5' || frame[0] > '8' || !strings.ContainsRune("NEO", rune(frame[1])) || (frame[2] != '1' && frame[2] != '2') {
+			return c, fmt.Errorf("invalid frame %q; use e.g. 8N1 or 8E1", frame)
+		}
+		c.DataBits, c.Parity, c.StopBits = int(frame[0]-'0'), frame[1], int(frame[2]-'0')
+	}
+	return c, nil
+}
+
+// CharTime is the time one character takes on the line
+func (c Config) CharTime() time.Duration {
+	bits := 1 + c.DataBits + c.StopBits
+	if c.Parity != 'N' {
+		bits++
+	}
+	return time.Duration(float64(bits) / float64(c.Baud) * float64(time.Second))
+}
+
+// FrameGap is the silence that separates Modbus RTU frames: 3.5
+// characters, but at least 1.75 ms as the standard fixes above 19200 baud
+func (c Config) FrameGap() time.Duration {
+	return max(c.CharTime()*7/2, 1750*time.Microsecond)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/checksum"
+	"github.com/polyfant/automation-helper-cli/serial"
+)
+
+func init() {
+	commandRegistry["serial"] = Command{
+		Group:       groupIntegration,
+		Description: "Serial terminal with timestamps and protocol decoding",
+		Usage:       serialUsage,
+		Execute:     text(runSerial),
+	}
+}
+
+const serialUsage = `Usage: serial open <port> [--baud 9600,8N1] [--decode hex|ascii|modbus-rtu]
+       [--eol crlf|cr|lf|none] [--log file]
+       serial list
+A terminal for RS-232/RS-485 devices. Received data is printed with a
+timestamp, one frame per line: frames end at a silence on the line (3.5
+characters, as in Modbus RTU) and, in ascii mode, at a line feed.
+
+Typed lines are sent to the device. In ascii mode the text is sent with
+the --eol ending; start a line with "hex:" to send bytes. In hex and
+modbus-rtu mode lines are bytes ("01 03 00 00 00 02"), and modbus-rtu
+appends the CRC. Press Ctrl+D to close the port. Linux only.
+
+Examples:
+  serial open /dev/ttyUSB0 --baud 19200,8E1 --decode modbus-rtu
+  serial open /dev/ttyS0 --baud 9600 --decode ascii --eol cr --log scale.log`
+
+func runSerial(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "list" {
+		return listSerialPorts(), nil
+	}
+	if len(args) < 2 || args[0] != "open" {
+		return serialUsage, nil
+	}
+	path := args[1]
+	cfg := serial.Config{Baud: 9600, DataBits: 8, Parity: 'N', StopBits: 1}
+	mode, eol, logPath := "hex", "\r\n", ""
+	for i := 2; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return serialUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--baud":
+			c, err := serial.ParseConfig(value)
+			if err != nil {
+				return "", err
+			}
+			cfg = c
+		case "--decode":
+			if !containsFold(serial.Decoders, value) {
+				return "", fmt.Errorf("invalid value %q for --decode (%s)", value, strings.Join(serial.Decoders, ", "))
+			}
+			mode = strings.ToLower(value)
+		case "--eol":
+			endings := map[string]string{"crlf": "\r\n", "cr": "\r", "lf": "\n", "none": ""}
+			e, ok := endings[strings.ToLower(value)]
+			if !ok {
+				return "", fmt.Errorf("invalid value %q for --eol", value)
+			}
+			eol = e
+		case "--log":
+			logPath = value
+		default:
+			return serialUsage, nil
+		}
+		i++
+	}
+
+	port, err := serial.Open(path, cfg, 100*time.Millisecond)
+	if err != nil {
+		return "", err
+	}
+	defer port.Close()
+	out := io.Writer(os.Stdout)
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		out = io.MultiWriter(os.Stdout, f)
+	}
+	fmt.Printf("Connected to %s at %s, decoding %s (Ctrl+D to close)\n", path, cfg, mode)
+	go serialReceive(port, cfg, mode, out)
+
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		data, err := serialOutgoing(in.Text(), mode, eol)
+		if err != nil {
+			fmt.Println("Error: " + err.Error())
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		_, err = port.Write(data)
+		auditSent(path, "serial send", data, err)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(out, "%s > %s\n", time.Now().Format("15:04:05.000"), serial.Decode(mode, data))
+	}
+	return "Closed " + path, nil
+}
+
+// serialOutgoing turns a typed line into the bytes to send
+func serialOutgoing(line, mode, eol string) ([]byte, error) {
+	if mode == "ascii" {
+		if hexText, ok := strings.CutPrefix(line, "hex:"); ok {
+			return checksum.ParseHex(hexText)
+		}
+		return []byte(line + eol), nil
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+	data, err := checksum.ParseHex(line)
+	if err != nil {
+		return nil, err
+	}
+	if mode == "modbus-rtu" {
+		data = binary.LittleEndian.AppendUint16(data, checksum.CRC16Modbus(data))
+	}
+	return data, nil
+}
+
+// serialReceive prints incoming frames until the port closes
+func serialReceive(port *serial.Port, cfg serial.Config, mode string, out io.Writer) {
+	var frame []byte
+	var started, last time.Time
+	flush := func() {
+		if len(frame) > 0 {
+			fmt.Fprintf(out, "%s < %s\n", started.Format("15:04:05.000"), serial.Decode(mode, frame))
+			frame = nil
+		}
+	}
+	buf := make([]byte, 512)
+	for {
+		n, err := port.Read(buf)
+		now := time.Now()
+		// The silence before this chunk is the time since the last one
+		// minus the time its own characters took on the line
+		silence := now.Sub(last) - time.Duration(n)*cfg.CharTime()
+		if n == 0 || silence > cfg.FrameGap() {
+			flush()
+		}
+		if err != nil {
+			flush()
+			return
+		}
+		for _, b := range buf[:n] {
+			if len(frame) == 0 {
+				started = now.Add(-time.Duration(n) * cfg.CharTime())
+			}
+			frame = append(frame, b)
+			if mode == "ascii" && b == '\n' {
+				flush()
+			}
+		}
+		last = now
+	}
+}
+
+// listSerialPorts shows the serial devices of a Linux system
+func listSerialPorts() string {
+	var ports []string
+	for _, pattern := range []string{"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/ttyS*", "/dev/serial/by-id/*"} {
+		matches, _ := filepath.Glob(pattern)
+		ports = append(ports, matches...)
+	}
+	if len(ports) == 0 {
+		return "No serial ports found"
+	}
+	return strings.Join(ports, "\n")
+}
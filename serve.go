@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/result"
+	"github.com/polyfant/automation-helper-cli/server"
+)
+
+func init() {
+	commandRegistry["serve"] = Command{
+		Group:       groupIntegration,
+		Description: "Run a local JSON REST API for lookups, generators, lint and calculators",
+		Usage:       serveUsage,
+		Execute:     text(runServe),
+	}
+}
+
+const serveUsage = `Usage: serve [--port 8080] [--host 127.0.0.1]
+Endpoints:
+  GET  /api/commands               GET /api/commands/{key}
+  GET  /api/quickref               GET /api/quickref/{topic}
+  GET  /api/generate/sensor?type=digital&action=when_on
+  POST /api/lint   {"filename": "x.mod", "source": "..."}
+  POST /api/stats  {"source": "..."}
+  GET  /api/calc/reorient?length=100&angle=90&speed=v1000
+  POST /api/run    {"command": "calc", "args": ["reorient", "100", "90"]}
+/api/run returns the structured result of the calc, error, glossary and
+ports commands; --output is not accepted.`
+
+// serveCommands are the registry commands offered by POST /api/run; they
+// look things up and compute, and only write files with --output
+var serveCommands = []string{"calc", "error", "glossary", "ports"}
+
+// serveMu serializes the API commands, which share the state of execute
+var serveMu sync.Mutex
+
+// serveRun runs a command for the REST API
+func serveRun(name string, args []string) (result.Result, bool) {
+	cmd, exists := commandRegistry[strings.ToLower(name)]
+	if !exists || !containsFold(serveCommands, name) {
+		return result.Result{}, false
+	}
+	for _, a := range args {
+		if a == "--output" {
+			return result.Errorf("--output is not available over the API"), true
+		}
+	}
+	serveMu.Lock()
+	defer serveMu.Unlock()
+	return execute(strings.ToLower(name), cmd, args), true
+}
+
+func runServe(args []string) (string, error) {
+	host, port := "127.0.0.1", 8080
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--port":
+			if i+1 >= len(args) {
+				return serveUsage, nil
+			}
+			p, err := strconv.Atoi(args[i+1])
+			if err != nil || p < 1 || p > 65535 {
+				return "", fmt.Errorf("invalid port: %s", args[i+1])
+			}
+			port = p
+			i++
+		case "--host":
+			if i+1 >= len(args) {
+				return serveUsage, nil
+			}
+			host = args[i+1]
+			i++
+		default:
+			return serveUsage, nil
+		}
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           server.NewHandler(serveRun),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// API commands never ask for confirmation and never write
+	prevReadOnly, prevConfirm := readOnlyFlag, confirmDisabled
+	readOnlyFlag, confirmDisabled = true, true
+	defer func() { readOnlyFlag, confirmDisabled = prevReadOnly, prevConfirm }()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Printf("Serving REST API on http://%s/api (Ctrl+C to stop)\n", addr)
+
+	select {
+	case err := <-errc:
+		return "", err
+	case <-interrupt:
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return "", fmt.Errorf("stopping server: %v", err)
+		}
+		return "Server stopped", nil
+	}
+}
@@ -0,0 +1,224 @@
+// Package server exposes the reference data, generators, linter and
+// calculators as a JSON REST API
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/result"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+// maxBodySize limits uploaded source files to keep the server responsive
+const maxBodySize = 4 << 20
+
+// commandInfo is the JSON representation of an ABB command
+type commandInfo struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Syntax      string `json:"syntax"`
+	Example     string `json:"example"`
+	Description string `json:"description"`
+}
+
+// sourceRequest carries a source file for lint and stats endpoints
+type sourceRequest struct {
+	Filename string `json:"filename"`
+	Source   string `json:"source"`
+}
+
+// Runner runs a CLI command; ok is false for commands the API does not
+// offer
+type Runner func(name string, args []string) (r result.Result, ok bool)
+
+// runRequest is the body of POST /api/run
+type runRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// NewHandler returns the HTTP handler serving all API routes. With run
+// set, POST /api/run runs CLI commands and returns their structured result.
+func NewHandler(run Runner) http.Handler {
+	mux := http.NewServeMux()
+	if run != nil {
+		mux.HandleFunc("POST /api/run", runCommand(run))
+	}
+	mux.HandleFunc("GET /api/commands", listCommands)
+	mux.HandleFunc("GET /api/commands/{key}", getCommand)
+	mux.HandleFunc("GET /api/quickref", listTopics)
+	mux.HandleFunc("GET /api/quickref/{topic}", getTopic)
+	mux.HandleFunc("GET /api/generate/sensor", generateSensor)
+	mux.HandleFunc("POST /api/lint", lint)
+	mux.HandleFunc("POST /api/stats", stats)
+	mux.HandleFunc("GET /api/calc/reorient", calcReorient)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func runCommand(run Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req runRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		res, ok := run(req.Command, req.Args)
+		if !ok {
+			writeError(w, http.StatusNotFound, "command not available: "+req.Command)
+			return
+		}
+		status := http.StatusOK
+		if res.Failed() {
+			status = http.StatusUnprocessableEntity
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, result.JSON.Render(res)+"\n")
+	}
+}
+
+func listCommands(w http.ResponseWriter, r *http.Request) {
+	keys := abb.CommandKeys()
+	list := make([]commandInfo, 0, len(keys))
+	for _, key := range keys {
+		cmd, _ := abb.Command(key)
+		list = append(list, newCommandInfo(key, cmd))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func getCommand(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	cmd, exists := abb.Command(key)
+	if !exists {
+		writeError(w, http.StatusNotFound, "unknown ABB command: "+key)
+		return
+	}
+	writeJSON(w, http.StatusOK, newCommandInfo(key, cmd))
+}
+
+func newCommandInfo(key string, cmd abb.ABBCommand) commandInfo {
+	return commandInfo{
+		Key:         key,
+		Name:        cmd.Name,
+		Syntax:      cmd.Syntax,
+		Example:     cmd.Example,
+		Description: cmd.Description,
+	}
+}
+
+func listTopics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, abb.Topics())
+}
+
+func getTopic(w http.ResponseWriter, r *http.Request) {
+	topic := r.PathValue("topic")
+	content, exists := abb.Topic(topic)
+	if !exists {
+		writeError(w, http.StatusNotFound, "unknown topic: "+topic)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"topic": topic, "content": content})
+}
+
+func generateSensor(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	code, err := generate.SensorCode(q.Get("type"), q.Get("action"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"code": code})
+}
+
+func readSource(w http.ResponseWriter, r *http.Request) (sourceRequest, bool) {
+	var req sourceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return req, false
+	}
+	if req.Filename == "" {
+		req.Filename = "module.mod"
+	}
+	return req, true
+}
+
+func lint(w http.ResponseWriter, r *http.Request) {
+	req, ok := readSource(w, r)
+	if !ok {
+		return
+	}
+	switch {
+	case rapid.IsSourceFile(req.Filename):
+		diags := rapid.Lint(req.Source)
+		if diags == nil {
+			diags = []rapid.Diagnostic{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"filename": req.Filename, "diagnostics": diags})
+	case st.IsSourceFile(req.Filename):
+		diags := st.Lint(req.Source)
+		if diags == nil {
+			diags = []st.Diagnostic{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"filename": req.Filename, "diagnostics": diags})
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported file type: "+req.Filename)
+	}
+}
+
+func stats(w http.ResponseWriter, r *http.Request) {
+	req, ok := readSource(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, rapid.ComputeStats(req.Source))
+}
+
+func calcReorient(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	length, err := strconv.ParseFloat(q.Get("length"), 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid length")
+		return
+	}
+	angle, err := strconv.ParseFloat(q.Get("angle"), 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid angle")
+		return
+	}
+	speed, err := calc.ParseSpeed(q.Get("speed"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	res, err := calc.Reorient(length, angle, speed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"limited":        res.Limited,
+		"tcp_time":       res.TCPTime,
+		"ori_time":       res.OriTime,
+		"effective_tcp":  res.EffectiveTCP,
+		"required_ori":   res.RequiredOri,
+		"suggested_data": res.SuggestSpeed(),
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/ai"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+// langEnv overrides the configured display language for a single session
+const langEnv = "AUTOMATION_HELPER_LANG"
+
+func init() {
+	commandRegistry["config"] = Command{
+		Group:       groupSystem,
+		Description: "Show or change settings such as the display language",
+		Usage:       configUsage,
+		Execute:     text(runConfig),
+	}
+}
+
+// applySettings selects the display language from the environment or the
+// settings file. Problems are reported but never stop the CLI from starting.
+func applySettings() {
+	lang := os.Getenv(langEnv)
+	if lang == "" {
+		s, err := config.LoadSettings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return
+		}
+		lang = s.Language
+	}
+	if err := i18n.SetLanguage(lang); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// configUsage lists the settings with the languages and profiles available
+var configUsage = `Usage: config <show|get|set> [key] [value]
+Settings:
+  language - Display language: ` + strings.Join(i18n.Languages, ", ") + `
+  author   - Default author for module headers and revisions
+  cell     - Default cell name for module headers
+  profile  - Role that tailors help and confirmations: ` + strings.Join(profileNames(), ", ") + `
+  storage  - Where snapshots, backups and job logs are kept: a directory,
+             s3://bucket/prefix[?endpoint=<url>&region=<region>] or
+             sqlite:///path/data.db (default: the project directory)
+  ai_provider - Language model service of the ai command: ` + strings.Join(ai.Providers, ", ") + `
+             (default: openai)
+  ai_model    - Model, or the deployment for azure (default: the provider's)
+  ai_endpoint - Base URL of the service, for azure, ollama or an OpenAI
+             compatible server (default: the provider's public API)
+
+The auditor profile is read-only: it disables every command that writes to
+controllers or files, config set included. To leave it, set "profile" in
+config.json in the data directory.
+
+Examples:
+  config show
+  config set language sv
+  config set author jdoe
+  config set profile maintenance
+  config set storage "s3://plant-data/robots?endpoint=http://minio.plant.local:9000"
+  config set ai_provider ollama`
+
+func runConfig(args []string) (string, error) {
+	if len(args) < 1 {
+		return configUsage, nil
+	}
+
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+
+	switch args[0] {
+	case "show":
+		var result strings.Builder
+		for _, key := range config.Keys() {
+			value, _ := s.Get(key)
+			if value == "" {
+				value = "(default)"
+			}
+			result.WriteString(fmt.Sprintf("%-12s %s\n", key, value))
+		}
+		if lang := os.Getenv(langEnv); lang != "" {
+			result.WriteString(fmt.Sprintf("\nlanguage is overridden by %s=%s\n", langEnv, lang))
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	case "get":
+		if len(args) < 2 {
+			return "Usage: config get <key>", nil
+		}
+		value, err := s.Get(args[1])
+		if err != nil {
+			return "", err
+		}
+		return value, nil
+
+	case "set":
+		if len(args) < 3 {
+			return "Usage: config set <key> <value>", nil
+		}
+		key, value := args[1], args[2]
+		if key == "language" {
+			if err := i18n.SetLanguage(value); err != nil {
+				return "", err
+			}
+			value = i18n.Language()
+		}
+		if key == "profile" {
+			if err := validProfile(value); err != nil {
+				return "", err
+			}
+		}
+		if key == "ai_provider" && value != "" && !slices.Contains(ai.Providers, value) {
+			return "", fmt.Errorf("unknown AI provider %q (use %s)", value, strings.Join(ai.Providers, ", "))
+		}
+		if key == "storage" && value != "" {
+			if value, err = storageLocation(value); err != nil {
+				return "", err
+			}
+		}
+		if err := s.Set(key, value); err != nil {
+			return "", err
+		}
+		if err := config.SaveSettings(s); err != nil {
+			return "", err
+		}
+		if key == "language" && !hasTranslations(value) {
+			return fmt.Sprintf("Set %s = %s (no reference translations installed, English is shown)", key, value), nil
+		}
+		return fmt.Sprintf("Set %s = %s", key, value), nil
+
+	default:
+		return configUsage, nil
+	}
+}
+
+// hasTranslations reports whether reference data is available in lang
+func hasTranslations(lang string) bool {
+	if lang == i18n.Default {
+		return true
+	}
+	for _, l := range abb.Languages() {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
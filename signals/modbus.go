@@ -0,0 +1,190 @@
+package signals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Register is the location of a signal in the Modbus holding registers
+type Register struct {
+	Signal
+	Offset int // Register offset (0-based protocol address)
+	Bit    int // Bit within the register for booleans, -1 for words
+}
+
+// Address returns the register in 4xxxx notation
+func (r Register) Address() string {
+	return fmt.Sprintf("%d", 40001+r.Offset)
+}
+
+// ModbusMap is the holding register layout of an interface. Signals from the
+// robot come first, followed by the signals to the robot; each block starts
+// with the packed booleans.
+type ModbusMap struct {
+	Base    int
+	ToPLC   []Register
+	ToRobot []Register
+	End     int // First offset after the map
+}
+
+// Modbus assigns holding registers to the signals, starting at base
+func Modbus(list []Signal, base int) *ModbusMap {
+	m := &ModbusMap{Base: base}
+	next := base
+	m.ToPLC, next = allocate(list, ToPLC, next)
+	m.ToRobot, next = allocate(list, ToRobot, next)
+	m.End = next
+	return m
+}
+
+func allocate(list []Signal, direction string, offset int) ([]Register, int) {
+	var regs []Register
+	bit := 0
+	for _, s := range list {
+		if s.Direction != direction || s.Type != Bool {
+			continue
+		}
+		if bit == 16 {
+			offset++
+			bit = 0
+		}
+		regs = append(regs, Register{Signal: s, Offset: offset, Bit: bit})
+		bit++
+	}
+	if bit > 0 {
+		offset++
+	}
+	for _, s := range list {
+		if s.Direction != direction || s.Type == Bool {
+			continue
+		}
+		regs = append(regs, Register{Signal: s, Offset: offset, Bit: -1})
+		offset += s.Words()
+	}
+	return regs, offset
+}
+
+// Markdown returns the interface specification
+func (m *ModbusMap) Markdown(title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "Modbus holding registers %d-%d (offsets %d-%d).\n\n", 40001+m.Base, 40000+m.End, m.Base, m.End-1)
+	b.WriteString("- Booleans are packed into words, bit 0 is the least significant bit.\n")
+	b.WriteString("- 32-bit values (DINT, REAL) use two registers, high word first.\n")
+	b.WriteString("- REAL values are IEEE 754 single precision.\n")
+	for _, block := range []struct {
+		title string
+		regs  []Register
+	}{{"Robot to PLC", m.ToPLC}, {"PLC to robot", m.ToRobot}} {
+		fmt.Fprintf(&b, "\n## %s\n\n", block.title)
+		if len(block.regs) == 0 {
+			b.WriteString("No signals.\n")
+			continue
+		}
+		b.WriteString("| Register | Offset | Bit | Signal | Type | Unit | Description |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, r := range block.regs {
+			bit, reg := "", r.Address()
+			if r.Bit >= 0 {
+				bit = fmt.Sprintf("%d", r.Bit)
+			}
+			if r.Words() == 2 {
+				reg = fmt.Sprintf("%d-%d", 40001+r.Offset, 40002+r.Offset)
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s | %s |\n",
+				reg, r.Offset, bit, r.Name, strings.ToUpper(r.Type), r.Unit, strings.ReplaceAll(r.Description, "|", "/"))
+		}
+	}
+	return b.String()
+}
+
+// stTypes are the IEC 61131-3 types of the signal data types
+var stTypes = map[string]string{Bool: "BOOL", Int: "INT", UInt: "UINT", DInt: "DINT", Real: "REAL"}
+
+// ST returns IEC 61131-3 Structured Text (CODESYS dialect) that maps the
+// register array to named variables
+func (m *ModbusMap) ST(source string) string {
+	var b strings.Builder
+	w := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	w("// Modbus register mapping generated from %s", source)
+	w("// Offsets %s robot to PLC, %s PLC to robot; 32-bit values high word first",
+		offsetRange(m.Base, m.toRobotStart()), offsetRange(m.toRobotStart(), m.End))
+	w("VAR_GLOBAL")
+	w("    MbRegs : ARRAY[%d..%d] OF WORD; // Holding registers exchanged with the robot", m.Base, m.End-1)
+	for _, r := range append(append([]Register{}, m.ToPLC...), m.ToRobot...) {
+		comment := r.Description
+		if r.Unit != "" {
+			comment = strings.TrimSpace(comment + " [" + r.Unit + "]")
+		}
+		if comment != "" {
+			comment = " // " + comment
+		}
+		w("    %s : %s;%s", r.Name, stTypes[r.Type], comment)
+	}
+	w("END_VAR")
+	w("")
+	w("PROGRAM ModbusMapping")
+	w("VAR")
+	w("    dwTmp : DWORD;")
+	w("    pReal : POINTER TO REAL;")
+	w("END_VAR")
+	w("pReal := ADR(dwTmp);")
+	w("")
+	w("// Robot to PLC")
+	for _, r := range m.ToPLC {
+		hi, lo := r.Offset, r.Offset+1
+		switch r.Type {
+		case Bool:
+			w("%s := MbRegs[%d].%d;", r.Name, r.Offset, r.Bit)
+		case Int:
+			w("%s := WORD_TO_INT(MbRegs[%d]);", r.Name, r.Offset)
+		case UInt:
+			w("%s := WORD_TO_UINT(MbRegs[%d]);", r.Name, r.Offset)
+		case DInt:
+			w("dwTmp := SHL(WORD_TO_DWORD(MbRegs[%d]), 16) OR WORD_TO_DWORD(MbRegs[%d]);", hi, lo)
+			w("%s := DWORD_TO_DINT(dwTmp);", r.Name)
+		case Real:
+			w("dwTmp := SHL(WORD_TO_DWORD(MbRegs[%d]), 16) OR WORD_TO_DWORD(MbRegs[%d]);", hi, lo)
+			w("%s := pReal^;", r.Name)
+		}
+	}
+	w("")
+	w("// PLC to robot")
+	for _, r := range m.ToRobot {
+		hi, lo := r.Offset, r.Offset+1
+		switch r.Type {
+		case Bool:
+			w("MbRegs[%d].%d := %s;", r.Offset, r.Bit, r.Name)
+		case Int:
+			w("MbRegs[%d] := INT_TO_WORD(%s);", r.Offset, r.Name)
+		case UInt:
+			w("MbRegs[%d] := UINT_TO_WORD(%s);", r.Offset, r.Name)
+		case DInt, Real:
+			if r.Type == DInt {
+				w("dwTmp := DINT_TO_DWORD(%s);", r.Name)
+			} else {
+				w("pReal^ := %s;", r.Name)
+			}
+			w("MbRegs[%d] := DWORD_TO_WORD(SHR(dwTmp, 16));", hi)
+			w("MbRegs[%d] := DWORD_TO_WORD(dwTmp);", lo)
+		}
+	}
+	w("END_PROGRAM")
+	return b.String()
+}
+
+func (m *ModbusMap) toRobotStart() int {
+	if len(m.ToRobot) > 0 {
+		return m.ToRobot[0].Offset
+	}
+	return m.End
+}
+
+// offsetRange formats the offsets from start up to end, exclusive
+func offsetRange(start, end int) string {
+	if end <= start {
+		return "(none)"
+	}
+	return fmt.Sprintf("%d-%d", start, end-1)
+}
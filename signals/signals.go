@@ -0,0 +1,197 @@
+// Package signals reads project signal lists, the interface definition
+// between a robot cell and the line PLC
+package signals
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/cfg"
+)
+
+// Data types of interface signals
+const (
+	Bool = "bool"
+	Int  = "int"  // 16-bit signed
+	UInt = "uint" // 16-bit unsigned
+	DInt = "dint" // 32-bit signed
+	Real = "real" // 32-bit float
+)
+
+// Directions, seen from the robot controller
+const (
+	ToPLC   = "robot_to_plc"
+	ToRobot = "plc_to_robot"
+)
+
+// Signal is one entry of the signal list
+type Signal struct {
+	Name        string
+	Type        string
+	Direction   string
+	Description string
+	Unit        string
+}
+
+// Words returns the number of 16-bit registers the signal occupies;
+// booleans are packed and return 0
+func (s Signal) Words() int {
+	switch s.Type {
+	case DInt, Real:
+		return 2
+	case Bool:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// abbTypes maps ABB signal types to data type and direction
+var abbTypes = map[string][2]string{
+	"DO": {Bool, ToPLC},
+	"DI": {Bool, ToRobot},
+	"GO": {UInt, ToPLC},
+	"GI": {UInt, ToRobot},
+	"AO": {Real, ToPLC},
+	"AI": {Real, ToRobot},
+}
+
+// typeAliases accepts the usual PLC spellings of the data types
+var typeAliases = map[string]string{
+	"bool": Bool, "bit": Bool,
+	"int": Int, "word": UInt, "uint": UInt,
+	"dint": DInt, "dword": DInt, "udint": DInt,
+	"real": Real, "float": Real,
+}
+
+// directionAliases accepts short forms of the directions
+var directionAliases = map[string]string{
+	ToPLC: ToPLC, "to_plc": ToPLC, "r2p": ToPLC, "out": ToPLC, "output": ToPLC,
+	ToRobot: ToRobot, "to_robot": ToRobot, "p2r": ToRobot, "in": ToRobot, "input": ToRobot,
+}
+
+// Load reads a signal list from a CSV file or the EIO_SIGNAL section of an
+// EIO.cfg
+func Load(path string) ([]Signal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".cfg") {
+		return ParseEIO(f)
+	}
+	return ParseCSV(f)
+}
+
+// ParseCSV reads a signal list with the columns name, type, direction,
+// description and unit. The type may be a data type (bool, int, dint, real)
+// or an ABB signal type (DI, DO, GI, GO, AI, AO); ABB types imply the
+// direction, so that column may then be empty.
+func ParseCSV(r io.Reader) ([]Signal, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("signal list needs a header row and at least one signal")
+	}
+
+	cols := make(map[string]int)
+	for i, name := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, fmt.Errorf("signal list needs a name column")
+	}
+	if _, ok := cols["type"]; !ok {
+		return nil, fmt.Errorf("signal list needs a type column")
+	}
+	field := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var list []Signal
+	seen := make(map[string]int)
+	for n, row := range rows[1:] {
+		line := n + 2
+		name := field(row, "name")
+		if name == "" {
+			continue
+		}
+		s, err := newSignal(name, field(row, "type"), field(row, "direction"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if prev, dup := seen[strings.ToLower(name)]; dup {
+			return nil, fmt.Errorf("line %d: %s is already defined on line %d", line, name, prev)
+		}
+		seen[strings.ToLower(name)] = line
+		s.Description = field(row, "description")
+		s.Unit = field(row, "unit")
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// ParseEIO reads the signals of an EIO.cfg; the signal type gives the data
+// type and direction
+func ParseEIO(r io.Reader) ([]Signal, error) {
+	f, err := cfg.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var list []Signal
+	for _, t := range f.Types {
+		if t.Name != "EIO_SIGNAL" {
+			continue
+		}
+		for _, inst := range t.Instances {
+			typ, _ := inst.Get("SignalType")
+			s, err := newSignal(inst.Name(), typ, "")
+			if err != nil {
+				return nil, err
+			}
+			s.Description, _ = inst.Get("Label")
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no EIO_SIGNAL entries found")
+	}
+	return list, nil
+}
+
+func newSignal(name, typ, direction string) (Signal, error) {
+	s := Signal{Name: name}
+	if abb, ok := abbTypes[strings.ToUpper(typ)]; ok {
+		s.Type, s.Direction = abb[0], abb[1]
+	} else if t, ok := typeAliases[strings.ToLower(typ)]; ok {
+		s.Type = t
+	} else {
+		return s, fmt.Errorf("%s: unknown type %q", name, typ)
+	}
+	if direction != "" {
+		d, ok := directionAliases[strings.ToLower(direction)]
+		if !ok {
+			return s, fmt.Errorf("%s: unknown direction %q (use %s or %s)", name, direction, ToPLC, ToRobot)
+		}
+		s.Direction = d
+	}
+	if s.Direction == "" {
+		return s, fmt.Errorf("%s: direction is required for type %s", name, typ)
+	}
+	return s, nil
+}
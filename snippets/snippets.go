@@ -0,0 +1,136 @@
+// Package snippets manages the user's library of stored code snippets
+package snippets
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/db"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// Snippet is a named piece of code kept in the library
+type Snippet struct {
+	Name string
+	Ext  string // File extension deciding the language, e.g. ".mod" or ".st"
+	Code string
+}
+
+// open returns the database after reading in the snippet files earlier
+// versions kept in the snippets directory. The files are left in place.
+func open() (*sql.DB, error) {
+	err := db.ImportOnce("snippets", func(tx *sql.Tx) error {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, "snippets"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			name := strings.TrimSuffix(e.Name(), ext)
+			if e.IsDir() || !validName.MatchString(name) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			code, err := os.ReadFile(filepath.Join(dir, "snippets", e.Name()))
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO snippets (name, ext, code, modified) VALUES (?, ?, ?, ?)`,
+				name, ext, string(code), info.ModTime().UTC().Format(time.RFC3339Nano)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db.Open()
+}
+
+// List returns the stored snippets sorted by name, without their code
+func List() ([]Snippet, error) {
+	d, err := open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.Query(`SELECT name, ext FROM snippets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Snippet
+	for rows.Next() {
+		var s Snippet
+		if err := rows.Scan(&s.Name, &s.Ext); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// Load reads a snippet by name regardless of its extension
+func Load(name string) (Snippet, error) {
+	d, err := open()
+	if err != nil {
+		return Snippet{}, err
+	}
+	s := Snippet{Name: name}
+	err = d.QueryRow(`SELECT ext, code FROM snippets WHERE name = ?`, name).Scan(&s.Ext, &s.Code)
+	if err == sql.ErrNoRows {
+		return Snippet{}, fmt.Errorf("snippet %q not found", name)
+	}
+	return s, err
+}
+
+// Save stores a snippet in the library, replacing any snippet with the same name
+func Save(s Snippet) error {
+	if !validName.MatchString(s.Name) {
+		return fmt.Errorf("invalid snippet name %q (use letters, digits, - and _)", s.Name)
+	}
+	if s.Ext == "" {
+		s.Ext = ".mod"
+	}
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	_, err = d.Exec(`INSERT INTO snippets (name, ext, code, modified) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET ext = excluded.ext, code = excluded.code, modified = excluded.modified`,
+		s.Name, s.Ext, s.Code, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// Delete removes a snippet from the library
+func Delete(name string) error {
+	d, err := open()
+	if err != nil {
+		return err
+	}
+	res, err := d.Exec(`DELETE FROM snippets WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("snippet %q not found", name)
+	}
+	return nil
+}
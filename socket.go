@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/message"
+	"github.com/polyfant/automation-helper-cli/serial"
+)
+
+func init() {
+	commandRegistry["socket"] = Command{
+		Group:       groupIntegration,
+		Description: "Send templated test messages to RAPID socket servers",
+		Usage:       socketUsage,
+		Execute:     text(runSocket),
+	}
+}
+
+const socketUsage = `Usage: socket send --host <ip> --port <n> (--template <name> | --message <text>)
+       [--set field=value]... [--count 1] [--interval 1s] [--timeout 3s]
+       [--udp] [--no-reply] [--templates file]
+       socket templates [--templates file]
+Sends messages to a RAPID program that listens on a socket (SocketListen,
+SocketAccept), as a camera, code reader or MES would, and logs the replies
+with timestamps. Non-printable bytes are shown as <STX>, <CR>, <x8F>.
+
+Templates contain {field} placeholders filled from --set or the template
+defaults, {seq} (message number from 1), {time}, {date} and checksums
+over the bytes before them (from the last STX): {xor}, {lrc}, {sum8},
+{crc16} as hex digits or {xor:bin} as raw bytes. Escapes: \r \n \t \xHH.
+A templates file holds lines of name = text.
+
+Examples:
+  socket send --host 192.168.125.1 --port 1025 --template vision_result --set x=20.1
+  socket send --host 127.0.0.1 --port 1025 --message "\x02START,{seq}\x03{lrc}" --count 5`
+
+func runSocket(args []string) (string, error) {
+	if len(args) < 1 {
+		return socketUsage, nil
+	}
+	templates := append([]message.Template{}, message.Builtin...)
+	var host, tmplName, text string
+	port, count := 0, 1
+	interval, timeout := time.Second, 3*time.Second
+	udp, reply := false, true
+	values := map[string]string{}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--udp":
+			udp = true
+			continue
+		case "--no-reply":
+			reply = false
+			continue
+		}
+		if i+1 >= len(args) {
+			return socketUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--port":
+			port, err = strconv.Atoi(value)
+			if err == nil && (port < 1 || port > 65535) {
+				err = fmt.Errorf("out of range")
+			}
+		case "--template":
+			tmplName = value
+		case "--message":
+			text = value
+		case "--set":
+			k, v, ok := strings.Cut(value, "=")
+			if !ok {
+				err = fmt.Errorf("missing =")
+			}
+			values[strings.TrimSpace(k)] = v
+		case "--count":
+			count, err = strconv.Atoi(value)
+			if err == nil && count < 1 {
+				err = fmt.Errorf("out of range")
+			}
+		case "--interval":
+			interval, err = time.ParseDuration(value)
+		case "--timeout":
+			timeout, err = time.ParseDuration(value)
+		case "--templates":
+			var loaded []message.Template
+			if loaded, err = loadMessageTemplates(value); err != nil {
+				return "", fmt.Errorf("%s: %v", value, err)
+			}
+			templates = append(loaded, templates...)
+		default:
+			return socketUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+
+	switch args[0] {
+	case "templates":
+		return listMessageTemplates(templates), nil
+	case "send":
+	default:
+		return socketUsage, nil
+	}
+	if host == "" || port == 0 || (tmplName == "") == (text == "") {
+		return socketUsage, nil
+	}
+	t := message.Template{Name: "message", Text: text}
+	if tmplName != "" {
+		found := false
+		for _, candidate := range templates {
+			if strings.EqualFold(candidate.Name, tmplName) {
+				t, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("unknown template %q; see socket templates", tmplName)
+		}
+	}
+	// Render once up front, so a missing field fails before connecting
+	if _, err := message.Render(t, values, 1); err != nil {
+		return "", err
+	}
+
+	network := "tcp"
+	if udp {
+		network = "udp"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	fmt.Printf("Connected to %s (%s)\n", addr, network)
+
+	replies, timeouts := 0, 0
+	buf := make([]byte, 4096)
+	for seq := 1; seq <= count; seq++ {
+		data, err := message.Render(t, values, seq)
+		if err != nil {
+			return "", err
+		}
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		_, err = conn.Write(data)
+		auditSent(addr, "socket send ("+network+")", data, err)
+		if err != nil {
+			return "", err
+		}
+		fmt.Printf("%s > %s\n", time.Now().Format("15:04:05.000"), serial.ASCII(data))
+		if reply {
+			sent := time.Now()
+			conn.SetReadDeadline(sent.Add(timeout))
+			n, err := conn.Read(buf)
+			switch {
+			case err == nil:
+				replies++
+				fmt.Printf("%s < %s  (%d ms)\n", time.Now().Format("15:04:05.000"), serial.ASCII(buf[:n]), time.Since(sent).Milliseconds())
+			case isTimeout(err):
+				timeouts++
+				fmt.Printf("%s   no reply within %s\n", time.Now().Format("15:04:05.000"), timeout)
+			default:
+				return "", fmt.Errorf("%v (after %d messages)", err, seq)
+			}
+		}
+		if seq < count {
+			time.Sleep(interval)
+		}
+	}
+	if !reply {
+		return fmt.Sprintf("Sent %d messages", count), nil
+	}
+	if timeouts > 0 {
+		return "", fmt.Errorf("%d of %d messages got no reply", timeouts, count)
+	}
+	return fmt.Sprintf("Sent %d messages, %d replies", count, replies), nil
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// loadMessageTemplates reads name = text lines; # starts a comment line
+func loadMessageTemplates(path string) ([]message.Template, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var templates []message.Template
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, text, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("line %d: expected name = text", n)
+		}
+		templates = append(templates, message.Template{Name: strings.TrimSpace(name), Text: strings.TrimSpace(text)})
+	}
+	return templates, scanner.Err()
+}
+
+func listMessageTemplates(templates []message.Template) string {
+	var b strings.Builder
+	for _, t := range templates {
+		fmt.Fprintf(&b, "%-14s %s\n", t.Name, t.Text)
+		if t.Description != "" {
+			fmt.Fprintf(&b, "%-14s %s\n", "", t.Description)
+		}
+		var defaults []string
+		for _, f := range message.Fields(t) {
+			if v, ok := t.Defaults[f]; ok {
+				defaults = append(defaults, f+"="+v)
+			} else {
+				defaults = append(defaults, f)
+			}
+		}
+		if len(defaults) > 0 {
+			fmt.Fprintf(&b, "%-14s fields: %s\n", "", strings.Join(defaults, " "))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,1087 @@
+package st
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// value is a run-time value: bool, int64, float64, time.Duration, string,
+// *array or *instance
+type value = any
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindInt
+	kindReal
+	kindTime
+	kindString
+	kindArray
+	kindFB
+)
+
+// typ is a resolved type
+type typ struct {
+	kind kind
+	name string
+	bits int  // integers: the width, 0 for 64
+	sign bool // integers: signed
+	elem *typ // arrays
+	lo   []int
+	n    []int
+	pou  *pou   // user function blocks
+	std  *stdFB // standard function blocks
+}
+
+// elementary are the elementary types by name
+var elementary = map[string]*typ{
+	"BOOL":    {kind: kindBool, name: "BOOL"},
+	"SINT":    {kind: kindInt, name: "SINT", bits: 8, sign: true},
+	"INT":     {kind: kindInt, name: "INT", bits: 16, sign: true},
+	"DINT":    {kind: kindInt, name: "DINT", bits: 32, sign: true},
+	"LINT":    {kind: kindInt, name: "LINT", sign: true},
+	"USINT":   {kind: kindInt, name: "USINT", bits: 8},
+	"UINT":    {kind: kindInt, name: "UINT", bits: 16},
+	"UDINT":   {kind: kindInt, name: "UDINT", bits: 32},
+	"ULINT":   {kind: kindInt, name: "ULINT"},
+	"BYTE":    {kind: kindInt, name: "BYTE", bits: 8},
+	"WORD":    {kind: kindInt, name: "WORD", bits: 16},
+	"DWORD":   {kind: kindInt, name: "DWORD", bits: 32},
+	"LWORD":   {kind: kindInt, name: "LWORD"},
+	"REAL":    {kind: kindReal, name: "REAL"},
+	"LREAL":   {kind: kindReal, name: "LREAL"},
+	"TIME":    {kind: kindTime, name: "TIME"},
+	"LTIME":   {kind: kindTime, name: "LTIME"},
+	"STRING":  {kind: kindString, name: "STRING"},
+	"WSTRING": {kind: kindString, name: "WSTRING"},
+}
+
+type array struct {
+	t     *typ
+	elems []value
+}
+
+// instance is the memory of a program, function block or function call
+type instance struct {
+	t     *typ
+	names []string             // declaration order
+	vars  map[string]*variable // by upper-case name
+}
+
+type variable struct {
+	name     string
+	section  string
+	constant bool
+	t        *typ
+	v        value
+}
+
+func (in *instance) lookup(name string) *variable {
+	return in.vars[strings.ToUpper(name)]
+}
+
+// Machine runs a program or function block scan by scan, as a PLC task
+// does. Time only passes between scans, by the cycle time given to Scan.
+type Machine struct {
+	prog    *Program
+	root    *instance
+	globals *instance
+	now     time.Duration
+	loops   int
+}
+
+// maxLoops bounds the loop iterations in one scan, so a loop that never
+// ends is reported instead of hanging, as a PLC watchdog would
+const maxLoops = 1000000
+
+// NewMachine creates the memory of a block with its initial values. The
+// block may be empty when the source has a single program, or else a
+// single function block.
+func NewMachine(prog *Program, block string) (*Machine, error) {
+	u, err := rootPOU(prog, block)
+	if err != nil {
+		return nil, err
+	}
+	m := &Machine{prog: prog}
+	m.globals = &instance{t: &typ{kind: kindFB, name: "globals"}, vars: map[string]*variable{}}
+	if err := m.declare(m.globals, prog.globals); err != nil {
+		return nil, err
+	}
+	t, err := m.resolve(&typeRef{name: u.name}, nil)
+	if err != nil {
+		return nil, err
+	}
+	root, err := m.zero(t)
+	if err != nil {
+		return nil, err
+	}
+	m.root = root.(*instance)
+	return m, nil
+}
+
+func rootPOU(prog *Program, block string) (*pou, error) {
+	if block != "" {
+		u := prog.pou(block)
+		if u == nil {
+			return nil, fmt.Errorf("no block %s (found %s)", block, strings.Join(prog.Blocks(), ", "))
+		}
+		if u.kind == "FUNCTION" {
+			return nil, fmt.Errorf("%s is a FUNCTION; choose a PROGRAM or FUNCTION_BLOCK", u.name)
+		}
+		return u, nil
+	}
+	var programs, fbs []*pou
+	for _, u := range prog.pous {
+		switch u.kind {
+		case "PROGRAM":
+			programs = append(programs, u)
+		case "FUNCTION_BLOCK":
+			fbs = append(fbs, u)
+		}
+	}
+	switch {
+	case len(programs) == 1:
+		return programs[0], nil
+	case len(programs) == 0 && len(fbs) == 1:
+		return fbs[0], nil
+	case len(programs) == 0 && len(fbs) == 0:
+		return nil, fmt.Errorf("the source has no PROGRAM or FUNCTION_BLOCK to run")
+	}
+	return nil, fmt.Errorf("choose the block to run: %s", strings.Join(prog.Blocks(), ", "))
+}
+
+// Name returns the name of the block the machine runs
+func (m *Machine) Name() string {
+	return m.root.t.name
+}
+
+// Now returns the time the machine has run
+func (m *Machine) Now() time.Duration {
+	return m.now
+}
+
+// Scan lets a cycle time pass and executes the block once
+func (m *Machine) Scan(cycle time.Duration) error {
+	m.now += cycle
+	m.loops = 0
+	_, err := m.run(m.root)
+	return err
+}
+
+// run executes the body of a user block with its memory
+func (m *Machine) run(in *instance) (flow, error) {
+	if in.t.std != nil {
+		in.t.std.exec(in, m.now)
+		return flowNext, nil
+	}
+	f := &frame{m: m, in: in}
+	return f.exec(in.t.pou.body)
+}
+
+// resolve returns the run-time type of a declared type. Array bounds are
+// evaluated in the memory declared so far, so they may use constants.
+func (m *Machine) resolve(r *typeRef, in *instance) (*typ, error) {
+	if r.elem == nil {
+		upper := strings.ToUpper(r.name)
+		if t, ok := elementary[upper]; ok {
+			return t, nil
+		}
+		if std, ok := stdFBs[upper]; ok {
+			return &typ{kind: kindFB, name: upper, std: std}, nil
+		}
+		if u := m.prog.pou(r.name); u != nil && u.kind != "FUNCTION" {
+			return &typ{kind: kindFB, name: u.name, pou: u}, nil
+		}
+		return nil, fmt.Errorf("unknown type %s", r.name)
+	}
+	elem, err := m.resolve(r.elem, in)
+	if err != nil {
+		return nil, err
+	}
+	t := &typ{kind: kindArray, name: r.String(), elem: elem}
+	if in == nil {
+		in = m.globals
+	}
+	for _, rng := range r.ranges {
+		var bounds [2]int
+		for i, e := range rng {
+			v, err := (&frame{m: m, in: in}).eval(e)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := v.(int64)
+			if !ok {
+				return nil, fmt.Errorf("line %d: array bounds must be integers", e.pos())
+			}
+			bounds[i] = int(n)
+		}
+		if bounds[1] < bounds[0] {
+			return nil, fmt.Errorf("line %d: empty array range %d..%d", rng[0].pos(), bounds[0], bounds[1])
+		}
+		t.lo = append(t.lo, bounds[0])
+		t.n = append(t.n, bounds[1]-bounds[0]+1)
+	}
+	return t, nil
+}
+
+// zero returns the initial value of a type; function blocks get their
+// memory with the declared initial values
+func (m *Machine) zero(t *typ) (value, error) {
+	switch t.kind {
+	case kindFB:
+	case kindArray:
+		size := 1
+		for _, n := range t.n {
+			size *= n
+		}
+		if size > 1<<20 {
+			return nil, fmt.Errorf("the array %s is too large to simulate", t.name)
+		}
+		a := &array{t: t, elems: make([]value, size)}
+		for i := range a.elems {
+			v, err := m.zero(t.elem)
+			if err != nil {
+				return nil, err
+			}
+			a.elems[i] = v
+		}
+		return a, nil
+	default:
+		return zeroOf(t), nil
+	}
+	in := &instance{t: t, vars: map[string]*variable{}}
+	if t.std != nil {
+		for _, p := range t.std.vars {
+			in.names = append(in.names, p.name)
+			in.vars[p.name] = &variable{name: p.name, section: p.section, t: elementary[p.typ], v: zeroOf(elementary[p.typ])}
+		}
+		return in, nil
+	}
+	return in, m.declare(in, t.pou.vars)
+}
+
+// zeroOf returns the initial value of an elementary type
+func zeroOf(t *typ) value {
+	switch t.kind {
+	case kindBool:
+		return false
+	case kindInt:
+		return int64(0)
+	case kindReal:
+		return 0.0
+	case kindTime:
+		return time.Duration(0)
+	}
+	return ""
+}
+
+// declare adds variables with their initial values to a memory
+func (m *Machine) declare(in *instance, decls []*varDecl) error {
+	for _, d := range decls {
+		key := strings.ToUpper(d.name)
+		if _, ok := in.vars[key]; ok {
+			return fmt.Errorf("line %d: %s is declared twice", d.line, d.name)
+		}
+		t, err := m.resolve(d.typ, in)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", d.line, err)
+		}
+		v, err := m.zero(t)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", d.line, err)
+		}
+		variable := &variable{name: d.name, section: d.section, constant: d.constant, t: t, v: v}
+		if d.init != nil {
+			f := &frame{m: m, in: in}
+			if init, ok := d.init.(*arrayInit); ok {
+				a, ok := v.(*array)
+				if !ok {
+					return fmt.Errorf("line %d: %s is not an array", d.line, d.name)
+				}
+				if len(init.elems) > len(a.elems) {
+					return fmt.Errorf("line %d: %d initial values for %d elements", d.line, len(init.elems), len(a.elems))
+				}
+				for i, e := range init.elems {
+					ev, err := f.eval(e)
+					if err != nil {
+						return err
+					}
+					if a.elems[i], err = convert(ev, t.elem); err != nil {
+						return fmt.Errorf("line %d: %v", d.line, err)
+					}
+				}
+			} else {
+				iv, err := f.eval(d.init)
+				if err != nil {
+					return err
+				}
+				if variable.v, err = convert(iv, t); err != nil {
+					return fmt.Errorf("line %d: %s: %v", d.line, d.name, err)
+				}
+			}
+		}
+		in.names = append(in.names, d.name)
+		in.vars[key] = variable
+	}
+	return nil
+}
+
+// convert checks that a value can be stored in a variable of a type and
+// returns it as stored. Integers widen to reals and wrap around at the
+// width of their type, as on a PLC.
+func convert(v value, t *typ) (value, error) {
+	switch t.kind {
+	case kindBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case kindInt:
+		if n, ok := v.(int64); ok {
+			return wrap(n, t), nil
+		}
+	case kindReal:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case int64:
+			return float64(n), nil
+		}
+	case kindTime:
+		if d, ok := v.(time.Duration); ok {
+			return d, nil
+		}
+	case kindString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case kindArray:
+		if a, ok := v.(*array); ok && sameShape(a.t, t) {
+			c := &array{t: t, elems: make([]value, len(a.elems))}
+			for i, e := range a.elems {
+				var err error
+				if c.elems[i], err = convert(e, t.elem); err != nil {
+					return nil, err
+				}
+			}
+			return c, nil
+		}
+	case kindFB:
+		return nil, fmt.Errorf("cannot assign to the function block instance %s", t.name)
+	}
+	return nil, fmt.Errorf("cannot assign %s to %s", typeName(v), t.name)
+}
+
+func wrap(n int64, t *typ) int64 {
+	if t.bits == 0 {
+		return n
+	}
+	mask := int64(1)<<t.bits - 1
+	n &= mask
+	if t.sign && n >= int64(1)<<(t.bits-1) {
+		n -= int64(1) << t.bits
+	}
+	return n
+}
+
+func sameShape(a, b *typ) bool {
+	if len(a.n) != len(b.n) {
+		return false
+	}
+	for i := range a.n {
+		if a.n[i] != b.n[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// typeName names the type of a value in messages
+func typeName(v value) string {
+	switch v := v.(type) {
+	case bool:
+		return "BOOL"
+	case int64:
+		return "an integer"
+	case float64:
+		return "REAL"
+	case time.Duration:
+		return "TIME"
+	case string:
+		return "STRING"
+	case *array:
+		return v.t.name
+	case *instance:
+		return v.t.name
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+type flow int
+
+const (
+	flowNext flow = iota
+	flowExit
+	flowContinue
+	flowReturn
+)
+
+// frame executes statements in a memory
+type frame struct {
+	m  *Machine
+	in *instance
+}
+
+func (f *frame) exec(list []stmt) (flow, error) {
+	for _, s := range list {
+		fl, err := f.stmt(s)
+		if err != nil || fl != flowNext {
+			return fl, err
+		}
+	}
+	return flowNext, nil
+}
+
+func (f *frame) stmt(s stmt) (flow, error) {
+	switch s := s.(type) {
+	case *assignStmt:
+		v, err := f.eval(s.value)
+		if err != nil {
+			return flowNext, err
+		}
+		return flowNext, f.assign(s.target, v)
+	case *callStmt:
+		if id, ok := s.target.(*ident); ok && f.lookup(id.name) == nil {
+			_, err := f.call(&call{at: s.at, name: id.name, args: s.args})
+			return flowNext, err
+		}
+		return flowNext, f.invoke(s.target, s.args)
+	case *ifStmt:
+		for i, c := range s.conds {
+			ok, err := f.cond(c)
+			if err != nil {
+				return flowNext, err
+			}
+			if ok {
+				return f.exec(s.blocks[i])
+			}
+		}
+		return f.exec(s.els)
+	case *caseStmt:
+		x, err := f.eval(s.x)
+		if err != nil {
+			return flowNext, err
+		}
+		for _, b := range s.branches {
+			for _, l := range b.labels {
+				match, err := f.caseMatch(x, l)
+				if err != nil {
+					return flowNext, err
+				}
+				if match {
+					return f.exec(b.body)
+				}
+			}
+		}
+		return f.exec(s.els)
+	case *forStmt:
+		return f.forLoop(s)
+	case *whileStmt:
+		for {
+			ok, err := f.cond(s.cond)
+			if err != nil || !ok {
+				return flowNext, err
+			}
+			if fl, err := f.loopBody(s.at, s.body); err != nil || fl != flowNext {
+				return endLoop(fl), err
+			}
+		}
+	case *repeatStmt:
+		for {
+			if fl, err := f.loopBody(s.at, s.body); err != nil || fl != flowNext {
+				return endLoop(fl), err
+			}
+			ok, err := f.cond(s.until)
+			if err != nil || ok {
+				return flowNext, err
+			}
+		}
+	case *exitStmt:
+		return flowExit, nil
+	case *continueStmt:
+		return flowContinue, nil
+	case *returnStmt:
+		return flowReturn, nil
+	}
+	return flowNext, fmt.Errorf("line %d: unsupported statement", s.pos())
+}
+
+// loopBody runs one iteration, counting it against maxLoops. CONTINUE
+// ends the iteration; EXIT and RETURN end the loop.
+func (f *frame) loopBody(pos at, body []stmt) (flow, error) {
+	f.m.loops++
+	if f.m.loops > maxLoops {
+		return flowNext, fmt.Errorf("line %d: the loop ran %d times in one scan; it does not end", pos.line, maxLoops)
+	}
+	fl, err := f.exec(body)
+	if fl == flowContinue {
+		fl = flowNext
+	}
+	return fl, err
+}
+
+// endLoop is what a loop ended by a flow passes on: RETURN leaves the
+// block, EXIT only the loop
+func endLoop(fl flow) flow {
+	if fl == flowReturn {
+		return flowReturn
+	}
+	return flowNext
+}
+
+func (f *frame) forLoop(s *forStmt) (flow, error) {
+	counter := &ident{at: s.at, name: s.name}
+	bounds := make([]int64, 3)
+	for i, e := range []expr{s.from, s.to, s.by} {
+		if e == nil {
+			bounds[i] = 1
+			continue
+		}
+		v, err := f.eval(e)
+		if err != nil {
+			return flowNext, err
+		}
+		n, ok := v.(int64)
+		if !ok {
+			return flowNext, fmt.Errorf("line %d: FOR needs integers, found %s", e.pos(), typeName(v))
+		}
+		bounds[i] = n
+	}
+	from, to, by := bounds[0], bounds[1], bounds[2]
+	if by == 0 {
+		return flowNext, fmt.Errorf("line %d: FOR with a step of 0 does not end", s.line)
+	}
+	for i := from; by > 0 && i <= to || by < 0 && i >= to; {
+		if err := f.assign(counter, i); err != nil {
+			return flowNext, err
+		}
+		fl, err := f.loopBody(s.at, s.body)
+		if err != nil || fl != flowNext {
+			return endLoop(fl), err
+		}
+		v, err := f.eval(counter)
+		if err != nil {
+			return flowNext, err
+		}
+		i = v.(int64) + by
+		if err := f.assign(counter, i); err != nil {
+			return flowNext, err
+		}
+	}
+	return flowNext, nil
+}
+
+func (f *frame) cond(e expr) (bool, error) {
+	v, err := f.eval(e)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("line %d: the condition is %s, not BOOL", e.pos(), typeName(v))
+	}
+	return b, nil
+}
+
+func (f *frame) caseMatch(x value, label [2]expr) (bool, error) {
+	lo, err := f.eval(label[0])
+	if err != nil {
+		return false, err
+	}
+	if label[1] == nil {
+		return compare(label[0].pos(), "=", x, lo)
+	}
+	hi, err := f.eval(label[1])
+	if err != nil {
+		return false, err
+	}
+	above, err := compare(label[0].pos(), ">=", x, lo)
+	if err != nil || !above {
+		return false, err
+	}
+	return compare(label[1].pos(), "<=", x, hi)
+}
+
+// lookup finds a variable of the block, or else a global variable
+func (f *frame) lookup(name string) *variable {
+	if v := f.in.lookup(name); v != nil {
+		return v
+	}
+	return f.m.globals.lookup(name)
+}
+
+// ref is an assignable place: a variable or an array element
+type ref struct {
+	v   *variable
+	arr *array
+	i   int
+}
+
+func (r ref) get() value {
+	if r.arr != nil {
+		return r.arr.elems[r.i]
+	}
+	return r.v.v
+}
+
+func (r ref) set(v value) error {
+	if r.arr != nil {
+		c, err := convert(v, r.arr.t.elem)
+		if err == nil {
+			r.arr.elems[r.i] = c
+		}
+		return err
+	}
+	c, err := convert(v, r.v.t)
+	if err == nil {
+		r.v.v = c
+	}
+	return err
+}
+
+// ref resolves a designator; the variable of an element is its array's
+func (f *frame) ref(e expr) (ref, error) {
+	switch e := e.(type) {
+	case *ident:
+		v := f.lookup(e.name)
+		if v == nil {
+			return ref{}, fmt.Errorf("line %d: unknown variable %s", e.line, e.name)
+		}
+		return ref{v: v}, nil
+	case *member:
+		x, err := f.eval(e.x)
+		if err != nil {
+			return ref{}, err
+		}
+		in, ok := x.(*instance)
+		if !ok {
+			return ref{}, fmt.Errorf("line %d: %s has no member %s", e.line, typeName(x), e.name)
+		}
+		v := in.lookup(e.name)
+		if v == nil {
+			return ref{}, fmt.Errorf("line %d: %s has no member %s", e.line, in.t.name, e.name)
+		}
+		return ref{v: v}, nil
+	case *index:
+		base, err := f.ref(e.x)
+		if err != nil {
+			return ref{}, err
+		}
+		a, ok := base.get().(*array)
+		if !ok {
+			return ref{}, fmt.Errorf("line %d: %s is not an array", e.line, typeName(base.get()))
+		}
+		if len(e.indices) != len(a.t.n) {
+			return ref{}, fmt.Errorf("line %d: %s needs %d indices", e.line, a.t.name, len(a.t.n))
+		}
+		offset := 0
+		for d, ie := range e.indices {
+			v, err := f.eval(ie)
+			if err != nil {
+				return ref{}, err
+			}
+			n, ok := v.(int64)
+			if !ok {
+				return ref{}, fmt.Errorf("line %d: an index must be an integer, found %s", ie.pos(), typeName(v))
+			}
+			k := int(n) - a.t.lo[d]
+			if k < 0 || k >= a.t.n[d] {
+				return ref{}, fmt.Errorf("line %d: index %d is outside %d..%d", ie.pos(), n, a.t.lo[d], a.t.lo[d]+a.t.n[d]-1)
+			}
+			offset = offset*a.t.n[d] + k
+		}
+		return ref{v: base.v, arr: a, i: offset}, nil
+	}
+	return ref{}, fmt.Errorf("line %d: expected a variable", e.pos())
+}
+
+func (f *frame) assign(target expr, v value) error {
+	r, err := f.ref(target)
+	if err != nil {
+		return err
+	}
+	if r.v.constant {
+		return fmt.Errorf("line %d: %s is a constant", target.pos(), r.v.name)
+	}
+	if err := r.set(v); err != nil {
+		return fmt.Errorf("line %d: %v", target.pos(), err)
+	}
+	return nil
+}
+
+// invoke calls a function block instance: it sets the inputs, runs the
+// block and copies the outputs given with =>
+func (f *frame) invoke(target expr, args []arg) error {
+	x, err := f.eval(target)
+	if err != nil {
+		return err
+	}
+	in, ok := x.(*instance)
+	if !ok {
+		return fmt.Errorf("line %d: %s is not a function block instance", target.pos(), typeName(x))
+	}
+	inputs := in.inputs()
+	for i, a := range args {
+		if a.output {
+			continue
+		}
+		name := a.name
+		if name == "" {
+			if i >= len(inputs) {
+				return fmt.Errorf("line %d: too many arguments for %s", target.pos(), in.t.name)
+			}
+			name = inputs[i]
+		}
+		v := in.lookup(name)
+		if v == nil || v.section != "VAR_INPUT" && v.section != "VAR_IN_OUT" {
+			return fmt.Errorf("line %d: %s has no input %s", target.pos(), in.t.name, name)
+		}
+		val, err := f.eval(a.value)
+		if err != nil {
+			return err
+		}
+		if err := (ref{v: v}).set(val); err != nil {
+			return fmt.Errorf("line %d: %s.%s: %v", a.value.pos(), in.t.name, v.name, err)
+		}
+	}
+	if _, err := f.m.run(in); err != nil {
+		return err
+	}
+	for _, a := range args {
+		if !a.output {
+			continue
+		}
+		v := in.lookup(a.name)
+		if v == nil || v.section != "VAR_OUTPUT" {
+			return fmt.Errorf("line %d: %s has no output %s", target.pos(), in.t.name, a.name)
+		}
+		if err := f.assign(a.value, v.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputs returns the names of the inputs in declaration order, for
+// positional arguments
+func (in *instance) inputs() []string {
+	var names []string
+	for _, name := range in.names {
+		if s := in.lookup(name).section; s == "VAR_INPUT" || s == "VAR_IN_OUT" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// call evaluates a function call: a user FUNCTION or a standard function
+func (f *frame) call(c *call) (value, error) {
+	u := f.m.prog.pou(c.name)
+	if u == nil || u.kind != "FUNCTION" {
+		fn, ok := lookupStdFunc(c.name)
+		if !ok {
+			if v := f.lookup(c.name); v != nil {
+				return nil, fmt.Errorf("line %d: %s is a function block instance; call it as a statement", c.line, c.name)
+			}
+			return nil, fmt.Errorf("line %d: unknown function %s", c.line, c.name)
+		}
+		args := make([]value, len(c.args))
+		for i, a := range c.args {
+			v, err := f.eval(a.value)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		v, err := fn(args)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", c.line, strings.ToUpper(c.name), err)
+		}
+		return v, nil
+	}
+	// A function has fresh memory on every call, with its result as a
+	// variable of its own name
+	t := &typ{kind: kindFB, name: u.name, pou: u}
+	in := &instance{t: t, vars: map[string]*variable{}}
+	rt, err := f.m.resolve(u.returns, nil)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", u.line, err)
+	}
+	result, err := f.m.zero(rt)
+	if err != nil {
+		return nil, err
+	}
+	in.vars[strings.ToUpper(u.name)] = &variable{name: u.name, section: "VAR", t: rt, v: result}
+	if err := f.m.declare(in, u.vars); err != nil {
+		return nil, err
+	}
+	if err := f.invoke(&literal{c.at, in}, c.args); err != nil {
+		return nil, err
+	}
+	return in.lookup(u.name).v, nil
+}
+
+func (f *frame) eval(e expr) (value, error) {
+	switch e := e.(type) {
+	case *literal:
+		return e.v, nil
+	case *ident, *member, *index:
+		r, err := f.ref(e)
+		if err != nil {
+			return nil, err
+		}
+		return r.get(), nil
+	case *call:
+		return f.call(e)
+	case *unary:
+		x, err := f.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		return unaryOp(e.line, e.op, x)
+	case *binary:
+		x, err := f.eval(e.x)
+		if err != nil {
+			return nil, err
+		}
+		y, err := f.eval(e.y)
+		if err != nil {
+			return nil, err
+		}
+		return binaryOp(e.line, e.op, x, y)
+	case *arrayInit:
+		return nil, fmt.Errorf("line %d: an array initializer is only allowed in a declaration", e.line)
+	}
+	return nil, fmt.Errorf("line %d: unsupported expression", e.pos())
+}
+
+func unaryOp(line int, op string, x value) (value, error) {
+	switch x := x.(type) {
+	case bool:
+		if op == "NOT" {
+			return !x, nil
+		}
+	case int64:
+		switch op {
+		case "-":
+			return -x, nil
+		case "+":
+			return x, nil
+		case "NOT":
+			return ^x, nil
+		}
+	case float64:
+		switch op {
+		case "-":
+			return -x, nil
+		case "+":
+			return x, nil
+		}
+	case time.Duration:
+		switch op {
+		case "-":
+			return -x, nil
+		case "+":
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("line %d: %s cannot be applied to %s", line, op, typeName(x))
+}
+
+func binaryOp(line int, op string, x, y value) (value, error) {
+	switch op {
+	case "=", "<>", "<", ">", "<=", ">=":
+		return compare(line, op, x, y)
+	case "AND", "OR", "XOR":
+		if a, ok := x.(bool); ok {
+			if b, ok := y.(bool); ok {
+				switch op {
+				case "AND":
+					return a && b, nil
+				case "OR":
+					return a || b, nil
+				}
+				return a != b, nil
+			}
+		}
+		if a, ok := x.(int64); ok {
+			if b, ok := y.(int64); ok {
+				switch op {
+				case "AND":
+					return a & b, nil
+				case "OR":
+					return a | b, nil
+				}
+				return a ^ b, nil
+			}
+		}
+	case "+", "-", "*", "/", "MOD", "**":
+		return arithmetic(line, op, x, y)
+	}
+	return nil, fmt.Errorf("line %d: %s cannot combine %s and %s", line, op, typeName(x), typeName(y))
+}
+
+func arithmetic(line int, op string, x, y value) (value, error) {
+	mismatch := fmt.Errorf("line %d: %s cannot combine %s and %s", line, op, typeName(x), typeName(y))
+	// TIME with TIME, and TIME scaled by a number
+	if d, ok := x.(time.Duration); ok {
+		switch y := y.(type) {
+		case time.Duration:
+			switch op {
+			case "+":
+				return d + y, nil
+			case "-":
+				return d - y, nil
+			}
+		case int64, float64:
+			f := toFloat(y)
+			switch op {
+			case "*":
+				return time.Duration(float64(d) * f), nil
+			case "/":
+				if f == 0 {
+					return nil, fmt.Errorf("line %d: division by zero", line)
+				}
+				return time.Duration(float64(d) / f), nil
+			}
+		}
+		return nil, mismatch
+	}
+	if _, ok := y.(time.Duration); ok && op == "*" {
+		return arithmetic(line, op, y, x)
+	}
+	a, aInt := x.(int64)
+	b, bInt := y.(int64)
+	if aInt && bInt && op != "**" {
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		}
+		if b == 0 {
+			return nil, fmt.Errorf("line %d: division by zero", line)
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	}
+	if !isNumber(x) || !isNumber(y) || op == "MOD" {
+		return nil, mismatch
+	}
+	fa, fb := toFloat(x), toFloat(y)
+	switch op {
+	case "+":
+		return fa + fb, nil
+	case "-":
+		return fa - fb, nil
+	case "*":
+		return fa * fb, nil
+	case "/":
+		if fb == 0 {
+			return nil, fmt.Errorf("line %d: division by zero", line)
+		}
+		return fa / fb, nil
+	}
+	return math.Pow(fa, fb), nil
+}
+
+func isNumber(v value) bool {
+	switch v.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func toFloat(v value) float64 {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v.(float64)
+}
+
+func compare(line int, op string, x, y value) (bool, error) {
+	var c int
+	switch a := x.(type) {
+	case bool:
+		b, ok := y.(bool)
+		if !ok {
+			return false, fmt.Errorf("line %d: cannot compare BOOL with %s", line, typeName(y))
+		}
+		c = boolInt(a) - boolInt(b)
+	case time.Duration:
+		b, ok := y.(time.Duration)
+		if !ok {
+			return false, fmt.Errorf("line %d: cannot compare TIME with %s", line, typeName(y))
+		}
+		c = cmp3(float64(a), float64(b))
+	case string:
+		b, ok := y.(string)
+		if !ok {
+			return false, fmt.Errorf("line %d: cannot compare STRING with %s", line, typeName(y))
+		}
+		c = strings.Compare(a, b)
+	case int64, float64:
+		if !isNumber(y) {
+			return false, fmt.Errorf("line %d: cannot compare %s with %s", line, typeName(x), typeName(y))
+		}
+		ai, aInt := a.(int64)
+		bi, bInt := y.(int64)
+		switch {
+		case aInt && bInt && ai < bi:
+			c = -1
+		case aInt && bInt:
+			c = boolInt(ai > bi)
+		default:
+			c = cmp3(toFloat(x), toFloat(y))
+		}
+	default:
+		return false, fmt.Errorf("line %d: cannot compare %s", line, typeName(x))
+	}
+	switch op {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case ">":
+		return c > 0, nil
+	case "<=":
+		return c <= 0, nil
+	}
+	return c >= 0, nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func cmp3(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
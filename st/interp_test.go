@@ -0,0 +1,322 @@
+package st
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// scan is one scan of a test: the inputs set before it, the time it lets
+// pass and the variables expected after it
+type scan struct {
+	set   map[string]string
+	after time.Duration
+	want  map[string]string
+}
+
+func newMachine(t *testing.T, src, block string) *Machine {
+	t.Helper()
+	prog, err := Parse(src)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	m, err := NewMachine(prog, block)
+	if err != nil {
+		t.Fatalf("machine: %v", err)
+	}
+	return m
+}
+
+func runScans(t *testing.T, m *Machine, scans []scan) {
+	t.Helper()
+	for i, s := range scans {
+		for path, v := range s.set {
+			if err := m.Set(path, v); err != nil {
+				t.Fatalf("scan %d: set %s: %v", i, path, err)
+			}
+		}
+		if err := m.Scan(s.after); err != nil {
+			t.Fatalf("scan %d: %v", i, err)
+		}
+		for path, want := range s.want {
+			got, err := m.Get(path)
+			if err != nil {
+				t.Fatalf("scan %d: get %s: %v", i, path, err)
+			}
+			if got != want {
+				t.Errorf("scan %d at %s: %s = %s, want %s", i, m.Now(), path, got, want)
+			}
+		}
+	}
+}
+
+const stdBlocks = `
+FUNCTION_BLOCK Std
+VAR_INPUT
+    In : BOOL;
+    Reset : BOOL;
+END_VAR
+VAR
+    OnDelay : TON;
+    OffDelay : TOF;
+    Pulse : TP;
+    Rising : R_TRIG;
+    Falling : F_TRIG;
+    Count : CTU;
+    Latch : SR;
+END_VAR
+OnDelay(IN := In, PT := T#100ms);
+OffDelay(IN := In, PT := T#100ms);
+Pulse(IN := In, PT := T#100ms);
+Rising(CLK := In);
+Falling(CLK := In);
+Count(CU := In, R := Reset, PV := 2);
+Latch(S1 := Rising.Q, R := Reset);
+END_FUNCTION_BLOCK
+`
+
+func TestStandardBlocks(t *testing.T) {
+	on, off := map[string]string{"In": "1"}, map[string]string{"In": "0"}
+	tests := []struct {
+		name  string
+		scans []scan
+	}{
+		{"TON", []scan{
+			{off, 10 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#0ms"}},
+			{on, 10 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#0ms"}},
+			{nil, 60 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#60ms"}},
+			{nil, 40 * time.Millisecond, map[string]string{"OnDelay.Q": "1", "OnDelay.ET": "T#100ms"}},
+			{nil, time.Second, map[string]string{"OnDelay.Q": "1", "OnDelay.ET": "T#100ms"}},
+			{off, 10 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#0ms"}},
+			// A short pulse of IN restarts the delay
+			{on, 10 * time.Millisecond, map[string]string{"OnDelay.Q": "0"}},
+			{off, 10 * time.Millisecond, map[string]string{"OnDelay.Q": "0"}},
+			{on, 90 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#0ms"}},
+			{nil, 90 * time.Millisecond, map[string]string{"OnDelay.Q": "0", "OnDelay.ET": "T#90ms"}},
+		}},
+		{"TOF", []scan{
+			{off, 10 * time.Millisecond, map[string]string{"OffDelay.Q": "0"}},
+			{on, 10 * time.Millisecond, map[string]string{"OffDelay.Q": "1", "OffDelay.ET": "T#0ms"}},
+			{off, 10 * time.Millisecond, map[string]string{"OffDelay.Q": "1", "OffDelay.ET": "T#0ms"}},
+			{nil, 60 * time.Millisecond, map[string]string{"OffDelay.Q": "1", "OffDelay.ET": "T#60ms"}},
+			{nil, 40 * time.Millisecond, map[string]string{"OffDelay.Q": "0", "OffDelay.ET": "T#100ms"}},
+			{nil, time.Second, map[string]string{"OffDelay.Q": "0", "OffDelay.ET": "T#100ms"}},
+			{on, 10 * time.Millisecond, map[string]string{"OffDelay.Q": "1", "OffDelay.ET": "T#0ms"}},
+		}},
+		{"TP", []scan{
+			{on, 10 * time.Millisecond, map[string]string{"Pulse.Q": "1", "Pulse.ET": "T#0ms"}},
+			// Neither dropping nor raising IN again changes a running pulse
+			{off, 50 * time.Millisecond, map[string]string{"Pulse.Q": "1", "Pulse.ET": "T#50ms"}},
+			{on, 20 * time.Millisecond, map[string]string{"Pulse.Q": "1", "Pulse.ET": "T#70ms"}},
+			{nil, 30 * time.Millisecond, map[string]string{"Pulse.Q": "0", "Pulse.ET": "T#100ms"}},
+			{nil, time.Second, map[string]string{"Pulse.Q": "0", "Pulse.ET": "T#100ms"}},
+			{off, 10 * time.Millisecond, map[string]string{"Pulse.Q": "0", "Pulse.ET": "T#0ms"}},
+			{on, 10 * time.Millisecond, map[string]string{"Pulse.Q": "1"}},
+		}},
+		{"R_TRIG and F_TRIG", []scan{
+			{off, 10 * time.Millisecond, map[string]string{"Rising.Q": "0", "Falling.Q": "0"}},
+			{on, 10 * time.Millisecond, map[string]string{"Rising.Q": "1", "Falling.Q": "0"}},
+			{on, 10 * time.Millisecond, map[string]string{"Rising.Q": "0", "Falling.Q": "0"}},
+			{off, 10 * time.Millisecond, map[string]string{"Rising.Q": "0", "Falling.Q": "1"}},
+			{off, 10 * time.Millisecond, map[string]string{"Rising.Q": "0", "Falling.Q": "0"}},
+		}},
+		{"CTU and SR", []scan{
+			{on, 10 * time.Millisecond, map[string]string{"Count.CV": "1", "Count.Q": "0", "Latch.Q1": "1"}},
+			{on, 10 * time.Millisecond, map[string]string{"Count.CV": "1"}},
+			{off, 10 * time.Millisecond, map[string]string{"Count.CV": "1", "Latch.Q1": "1"}},
+			{on, 10 * time.Millisecond, map[string]string{"Count.CV": "2", "Count.Q": "1"}},
+			{map[string]string{"In": "0", "Reset": "1"}, 10 * time.Millisecond, map[string]string{"Count.CV": "0", "Count.Q": "0", "Latch.Q1": "0"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runScans(t, newMachine(t, stdBlocks, ""), tt.scans)
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		vars string
+		body string
+		want map[string]string
+	}{
+		{"precedence", "x : INT;", "x := 2 + 3 * 4 - 10 / 3;", map[string]string{"x": "11"}},
+		{"integer and real division", "i : INT; r : REAL;", "i := 7 / 2 + 7 MOD 2; r := 7.0 / 2;",
+			map[string]string{"i": "4", "r": "3.5"}},
+		{"boolean operators", "a : BOOL; b : BOOL;", "a := TRUE AND NOT FALSE OR FALSE; b := TRUE XOR TRUE;",
+			map[string]string{"a": "1", "b": "0"}},
+		{"IF ELSIF ELSE", "x : INT := 5; y : INT;", "IF x < 3 THEN y := 1; ELSIF x < 10 THEN y := 2; ELSE y := 3; END_IF;",
+			map[string]string{"y": "2"}},
+		{"CASE with lists and ranges", "x : INT := 7; y : INT;", "CASE x OF 1, 2: y := 1; 5..9: y := 2; ELSE y := 3; END_CASE;",
+			map[string]string{"y": "2"}},
+		{"FOR with BY", "i : INT; sum : INT;", "FOR i := 10 TO 1 BY -3 DO sum := sum + i; END_FOR;",
+			map[string]string{"sum": "22"}},
+		{"WHILE with EXIT", "i : INT;", "WHILE TRUE DO i := i + 1; IF i >= 4 THEN EXIT; END_IF; END_WHILE;",
+			map[string]string{"i": "4"}},
+		{"REPEAT", "i : INT;", "REPEAT i := i + 2; UNTIL i > 5 END_REPEAT;", map[string]string{"i": "6"}},
+		{"arrays", "a : ARRAY[1..3] OF INT := [4, 5, 6]; m : ARRAY[0..1, 1..2] OF BOOL; s : INT;",
+			"m[1, 2] := TRUE; s := a[1] + a[3];", map[string]string{"s": "10", "m[1,2]": "1", "m[0,1]": "0"}},
+		{"TIME arithmetic", "d : TIME;", "d := T#1s + T#500ms * 2;", map[string]string{"d": "T#2s"}},
+		{"standard functions", "x : INT; r : REAL; s : STRING;",
+			"x := LIMIT(0, 150, 100) + MAX(1, 7); r := ABS(-2.5); s := CONCAT('ab', 'cd');",
+			map[string]string{"x": "107", "r": "2.5", "s": "abcd"}},
+		{"conversions", "x : INT; r : REAL;", "x := REAL_TO_INT(2.6); r := INT_TO_REAL(3) / 2;",
+			map[string]string{"x": "3", "r": "1.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "PROGRAM Main\nVAR\n" + tt.vars + "\nEND_VAR\n" + tt.body + "\nEND_PROGRAM\n"
+			runScans(t, newMachine(t, src, ""), []scan{{after: 10 * time.Millisecond, want: tt.want}})
+		})
+	}
+}
+
+func TestFunctionsAndBlocks(t *testing.T) {
+	src := `
+FUNCTION Scale : REAL
+VAR_INPUT
+    Raw : INT;
+    Span : REAL;
+END_VAR
+Scale := INT_TO_REAL(Raw) * Span / 27648.0;
+END_FUNCTION
+
+FUNCTION_BLOCK Debounce
+VAR_INPUT
+    In : BOOL;
+END_VAR
+VAR_OUTPUT
+    Out : BOOL;
+END_VAR
+VAR
+    Delay : TON;
+END_VAR
+Delay(IN := In, PT := T#50ms);
+Out := Delay.Q;
+END_FUNCTION_BLOCK
+
+PROGRAM Main
+VAR_INPUT
+    Sensor : BOOL;
+END_VAR
+VAR
+    Level : REAL;
+    Filter : Debounce;
+    Stable : BOOL;
+END_VAR
+Level := Scale(Raw := 13824, Span := 10.0);
+Filter(In := Sensor, Out => Stable);
+END_PROGRAM
+`
+	m := newMachine(t, src, "")
+	if m.Name() != "Main" {
+		t.Errorf("ran %s, want Main", m.Name())
+	}
+	runScans(t, m, []scan{
+		{map[string]string{"Sensor": "1"}, 10 * time.Millisecond, map[string]string{"Level": "5", "Stable": "0"}},
+		{nil, 40 * time.Millisecond, map[string]string{"Stable": "0", "Filter.Delay.ET": "T#40ms"}},
+		{nil, 10 * time.Millisecond, map[string]string{"Stable": "1"}},
+	})
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		vars string
+		body string
+		want string
+	}{
+		{"division by zero", "x : INT; y : INT;", "x := 1 / y;", "line 6: division by zero"},
+		{"endless loop", "x : INT;", "WHILE TRUE DO x := 1; END_WHILE;", "does not end"},
+		{"index outside the array", "a : ARRAY[1..3] OF INT; i : INT := 4;", "a[i] := 1;", "index 4 is outside 1..3"},
+		{"unknown variable", "x : INT;", "y := 1;", "unknown variable y"},
+		{"assignment to a constant", "x : INT;", "Limit := 1;", "Limit is a constant"},
+		{"type mismatch", "x : INT;", "x := TRUE;", "cannot assign"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "PROGRAM Main\nVAR CONSTANT Limit : INT := 3; END_VAR\nVAR\n" + tt.vars + "\nEND_VAR\n" + tt.body + "\nEND_PROGRAM\n"
+			m := newMachine(t, src, "")
+			err := m.Scan(10 * time.Millisecond)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no block", "// nothing here", "no PROGRAM, FUNCTION_BLOCK or FUNCTION found"},
+		{"unclosed IF", "PROGRAM Main\nVAR x : INT; END_VAR\nIF x = 1 THEN x := 2;\nEND_PROGRAM", "line 4"},
+		{"unclosed comment", "PROGRAM Main (* open\nEND_PROGRAM", "comment not closed"},
+		{"bad duration", "PROGRAM Main\nVAR d : TIME := T#5q; END_VAR\nEND_PROGRAM", "invalid duration"},
+		{"declared twice", "PROGRAM Main\nEND_PROGRAM\nPROGRAM Main\nEND_PROGRAM", "declared twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	m := newMachine(t, stdBlocks, "Std")
+	tests := []struct {
+		path, value string
+		want        string // error text, or empty
+	}{
+		{"In", "TRUE", ""},
+		{"In", "2", "set it to 1 or 0"},
+		{"OnDelay", "1", "not a single value"},
+		{"Missing", "1", "unknown variable Missing"},
+		{"OnDelay.PT", "1.5s", ""},
+		{"OnDelay.PT", "T#2s", ""},
+	}
+	for _, tt := range tests {
+		err := m.Set(tt.path, tt.value)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("set %s=%s: %v", tt.path, tt.value, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("set %s=%s: got %v, want an error containing %q", tt.path, tt.value, err, tt.want)
+		}
+	}
+	if got, _ := m.Get("OnDelay.PT"); got != "T#2s" {
+		t.Errorf("OnDelay.PT = %s, want T#2s", got)
+	}
+	if _, err := m.Get("OnDelay"); err == nil || !strings.Contains(err.Error(), "function block instance") {
+		t.Errorf("get of an instance: got %v", err)
+	}
+}
+
+func TestTimeLiterals(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Duration
+	}{
+		{"1m30s", 90 * time.Second},
+		{"1.5s", 1500 * time.Millisecond},
+		{"2d_4h", 52 * time.Hour},
+		{"250MS", 250 * time.Millisecond},
+		{"-5s", -5 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.text)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTime(%q) = %v, %v; want %v", tt.text, got, err, tt.want)
+		}
+		if back, _ := parseTime(strings.TrimPrefix(formatTime(got), "T#")); back != got {
+			t.Errorf("formatTime(%v) = %s reads back as %v", got, formatTime(got), back)
+		}
+	}
+}
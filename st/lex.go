@@ -0,0 +1,329 @@
+package st
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type lexKind int
+
+const (
+	lexEOF lexKind = iota
+	lexIdent
+	lexInt
+	lexReal
+	lexTime
+	lexString
+	lexAddress // a direct address such as %IX0.0
+	lexOp
+)
+
+// lexeme is a token of the interpreter, with its value for literals
+type lexeme struct {
+	kind lexKind
+	text string // identifiers as written, operators
+	line int
+	i    int64
+	f    float64
+	d    time.Duration
+}
+
+// typedPrefixes are the type names of typed literals such as INT#5, which
+// are read as the plain literal
+var typedPrefixes = map[string]bool{
+	"BOOL": true, "SINT": true, "INT": true, "DINT": true, "LINT": true,
+	"USINT": true, "UINT": true, "UDINT": true, "ULINT": true,
+	"BYTE": true, "WORD": true, "DWORD": true, "LWORD": true,
+	"REAL": true, "LREAL": true, "STRING": true,
+}
+
+// lex splits Structured Text into lexemes, skipping comments and pragmas.
+// SCL's #local prefix and "quoted" names are read as plain identifiers.
+func lex(src string) ([]lexeme, error) {
+	var out []lexeme
+	line := 1
+	errorf := func(format string, args ...any) error {
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+	skip := func(i int, end string) (int, error) {
+		for ; i < len(src); i++ {
+			if strings.HasPrefix(src[i:], end) {
+				return i + len(end), nil
+			}
+			if src[i] == '\n' {
+				line++
+			}
+		}
+		return i, errorf("comment not closed with %s", end)
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "(*"):
+			var err error
+			if i, err = skip(i+2, "*)"); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			var err error
+			if i, err = skip(i+2, "*/"); err != nil {
+				return nil, err
+			}
+		case c == '{':
+			var err error
+			if i, err = skip(i+1, "}"); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\'':
+			s, n, err := readString(src[i:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			out = append(out, lexeme{kind: lexString, text: s, line: line})
+			i += n
+		case c == '"':
+			end := strings.IndexAny(src[i+1:], "\"\n")
+			if end < 0 || src[i+1+end] != '"' {
+				return nil, errorf("quoted name not closed")
+			}
+			out = append(out, lexeme{kind: lexIdent, text: src[i+1 : i+1+end], line: line})
+			i += end + 2
+		case c == '#' && i+1 < len(src) && isIdentStart(src[i+1]):
+			i++
+		case c == '%':
+			start := i
+			for i++; i < len(src) && (isIdentChar(src[i]) || src[i] == '.' || src[i] == '*'); i++ {
+			}
+			out = append(out, lexeme{kind: lexAddress, text: src[start:i], line: line})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			word := src[start:i]
+			if i < len(src) && src[i] == '#' {
+				upper := strings.ToUpper(word)
+				switch {
+				case upper == "T" || upper == "TIME" || upper == "LT" || upper == "LTIME":
+					end := i + 1
+					for end < len(src) && (isIdentChar(src[end]) || src[end] == '.' || src[end] == '-' && end == i+1) {
+						end++
+					}
+					d, err := parseTime(src[i+1 : end])
+					if err != nil {
+						return nil, errorf("%v", err)
+					}
+					out = append(out, lexeme{kind: lexTime, text: src[start:end], line: line, d: d})
+					i = end
+					continue
+				case typedPrefixes[upper]:
+					i++
+					continue
+				}
+			}
+			out = append(out, lexeme{kind: lexIdent, text: word, line: line})
+		case c >= '0' && c <= '9':
+			l, n, err := readNumber(src[i:])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			l.line = line
+			out = append(out, l)
+			i += n
+		default:
+			op := string(c)
+			for _, two := range []string{":=", "=>", "<=", ">=", "<>", "**", ".."} {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+				}
+			}
+			if !strings.Contains("+-*/()[],;:.=<>&", string(c)) {
+				return nil, errorf("unexpected character %q", c)
+			}
+			out = append(out, lexeme{kind: lexOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	return append(out, lexeme{kind: lexEOF, line: line}), nil
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// readNumber reads an integer, a real or a based integer such as 16#FF,
+// with _ allowed between digits
+func readNumber(s string) (lexeme, int, error) {
+	n := 0
+	for n < len(s) && (s[n] >= '0' && s[n] <= '9' || s[n] == '_') {
+		n++
+	}
+	digits := strings.ReplaceAll(s[:n], "_", "")
+	if n < len(s) && s[n] == '#' {
+		base, _ := strconv.Atoi(digits)
+		end := n + 1
+		for end < len(s) && isIdentChar(s[end]) {
+			end++
+		}
+		v, err := strconv.ParseInt(strings.ReplaceAll(s[n+1:end], "_", ""), base, 64)
+		if err != nil {
+			return lexeme{}, 0, fmt.Errorf("invalid number %s", s[:end])
+		}
+		return lexeme{kind: lexInt, text: s[:end], i: v}, end, nil
+	}
+	real := false
+	if n+1 < len(s) && s[n] == '.' && s[n+1] >= '0' && s[n+1] <= '9' {
+		real = true
+		for n++; n < len(s) && (s[n] >= '0' && s[n] <= '9' || s[n] == '_'); n++ {
+		}
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		end := n + 1
+		if end < len(s) && (s[end] == '+' || s[end] == '-') {
+			end++
+		}
+		if end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			real = true
+			for n = end; n < len(s) && s[n] >= '0' && s[n] <= '9'; n++ {
+			}
+		}
+	}
+	text := strings.ReplaceAll(s[:n], "_", "")
+	if real {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return lexeme{}, 0, fmt.Errorf("invalid number %s", s[:n])
+		}
+		return lexeme{kind: lexReal, text: s[:n], f: f}, n, nil
+	}
+	v, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return lexeme{}, 0, fmt.Errorf("invalid number %s", s[:n])
+	}
+	return lexeme{kind: lexInt, text: s[:n], i: v}, n, nil
+}
+
+// readString reads a single-quoted string with its $ escapes and returns
+// the string and the length read
+func readString(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'':
+			return b.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("string not closed")
+		case '$':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("string not closed")
+			}
+			i++
+			switch e := s[i]; e {
+			case 'N', 'n', 'L', 'l':
+				b.WriteByte('\n')
+			case 'R', 'r':
+				b.WriteByte('\r')
+			case 'T', 't':
+				b.WriteByte('\t')
+			case 'P', 'p':
+				b.WriteByte('\f')
+			default:
+				if i+1 < len(s) && isHex(e) && isHex(s[i+1]) {
+					v, _ := strconv.ParseUint(s[i:i+2], 16, 8)
+					b.WriteByte(byte(v))
+					i++
+					continue
+				}
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("string not closed")
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// timeUnits are the units of a duration literal, longest first
+var timeUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"ms", time.Millisecond}, {"us", time.Microsecond}, {"ns", time.Nanosecond},
+	{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+}
+
+// parseTime reads the value of a duration literal such as 1m30s, 1.5s or
+// 2d_4h, after the T#
+func parseTime(s string) (time.Duration, error) {
+	text := strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+	if text == "" {
+		return 0, fmt.Errorf("invalid duration T#%s", s)
+	}
+	var total time.Duration
+	for text != "" {
+		n := 0
+		for n < len(text) && (text[n] >= '0' && text[n] <= '9' || text[n] == '.') {
+			n++
+		}
+		f, err := strconv.ParseFloat(text[:n], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration T#%s", s)
+		}
+		text = text[n:]
+		found := false
+		for _, u := range timeUnits {
+			if strings.HasPrefix(text, u.name) {
+				total += time.Duration(f * float64(u.d))
+				text, found = text[len(u.name):], true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid duration T#%s", s)
+		}
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// formatTime writes a duration as a TIME literal such as T#1m30s
+func formatTime(d time.Duration) string {
+	if d == 0 {
+		return "T#0ms"
+	}
+	var b strings.Builder
+	b.WriteString("T#")
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	for _, u := range []struct {
+		name string
+		d    time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}, {"ms", time.Millisecond}, {"us", time.Microsecond}, {"ns", 1}} {
+		if n := d / u.d; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.name)
+			d -= n * u.d
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+// Package st provides analysis of IEC 61131-3 Structured Text sources and
+// an interpreter to simulate them
+package st
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// IsSourceFile reports whether the path looks like a Structured Text file
+func IsSourceFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".st", ".scl":
+		return true
+	}
+	return false
+}
+
+// Diagnostic is a single lint finding in a Structured Text file
+type Diagnostic struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: error: %s", d.Line, d.Message)
+}
+
+var blockEnds = map[string]string{
+	"PROGRAM":        "END_PROGRAM",
+	"FUNCTION":       "END_FUNCTION",
+	"FUNCTION_BLOCK": "END_FUNCTION_BLOCK",
+	"DATA_BLOCK":     "END_DATA_BLOCK",
+	"IF":             "END_IF",
+	"CASE":           "END_CASE",
+	"FOR":            "END_FOR",
+	"WHILE":          "END_WHILE",
+	"REPEAT":         "END_REPEAT",
+	"VAR":            "END_VAR",
+	"VAR_INPUT":      "END_VAR",
+	"VAR_OUTPUT":     "END_VAR",
+	"VAR_IN_OUT":     "END_VAR",
+	"VAR_GLOBAL":     "END_VAR",
+	"VAR_TEMP":       "END_VAR",
+	"STRUCT":         "END_STRUCT",
+	"TYPE":           "END_TYPE",
+}
+
+type openBlock struct {
+	keyword string
+	line    int
+}
+
+// Lint checks Structured Text for unbalanced control and declaration blocks
+func Lint(src string) []Diagnostic {
+	var diags []Diagnostic
+	var stack []openBlock
+
+	for _, tok := range tokenize(src) {
+		word := strings.ToUpper(tok.text)
+		if _, ok := blockEnds[word]; ok {
+			stack = append(stack, openBlock{word, tok.line})
+			continue
+		}
+		if !strings.HasPrefix(word, "END_") {
+			continue
+		}
+		if len(stack) == 0 {
+			diags = append(diags, Diagnostic{tok.line, word + " without matching block"})
+			continue
+		}
+		top := stack[len(stack)-1]
+		if blockEnds[top.keyword] != word {
+			diags = append(diags, Diagnostic{tok.line,
+				fmt.Sprintf("%s found but %s opened on line %d is still open", word, top.keyword, top.line)})
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	for _, b := range stack {
+		diags = append(diags, Diagnostic{b.line, fmt.Sprintf("%s is missing %s", b.keyword, blockEnds[b.keyword])})
+	}
+	return diags
+}
+
+type token struct {
+	text string
+	line int
+}
+
+// tokenize extracts identifiers with their line numbers, skipping
+// comments and string literals
+func tokenize(src string) []token {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == '(' && i+1 < len(src) && src[i+1] == '*':
+			for i += 2; i+1 < len(src) && !(src[i] == '*' && src[i+1] == ')'); i++ {
+				if src[i] == '\n' {
+					line++
+				}
+			}
+			i++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '\'' || c == '"':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\n' {
+					line++
+				}
+			}
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentStart(src[i]) || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{src[start:i], line})
+			i--
+		}
+	}
+	return tokens
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,932 @@
+package st
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Program is a parsed Structured Text source: its program organisation
+// units and global variables
+type Program struct {
+	pous    []*pou
+	globals []*varDecl
+}
+
+// Blocks returns the names of the programs and function blocks, which a
+// Machine can run
+func (p *Program) Blocks() []string {
+	var names []string
+	for _, u := range p.pous {
+		if u.kind != "FUNCTION" {
+			names = append(names, u.name)
+		}
+	}
+	return names
+}
+
+func (p *Program) pou(name string) *pou {
+	for _, u := range p.pous {
+		if strings.EqualFold(u.name, name) {
+			return u
+		}
+	}
+	return nil
+}
+
+// pou is a PROGRAM, FUNCTION_BLOCK or FUNCTION
+type pou struct {
+	kind    string
+	name    string
+	returns *typeRef // FUNCTION only
+	vars    []*varDecl
+	body    []stmt
+	line    int
+}
+
+type varDecl struct {
+	name     string
+	section  string // VAR, VAR_INPUT, VAR_OUTPUT, VAR_IN_OUT, VAR_TEMP or VAR_GLOBAL
+	constant bool
+	typ      *typeRef
+	init     expr // nil, or an arrayInit for arrays
+	line     int
+}
+
+// typeRef is a type as declared: a name, or an ARRAY with its bounds
+type typeRef struct {
+	name   string
+	ranges [][2]expr
+	elem   *typeRef
+}
+
+func (t *typeRef) String() string {
+	if t.elem == nil {
+		return strings.ToUpper(t.name)
+	}
+	return "ARRAY OF " + t.elem.String()
+}
+
+type expr interface{ pos() int }
+
+type at struct{ line int }
+
+func (a at) pos() int { return a.line }
+
+type (
+	literal struct {
+		at
+		v value
+	}
+	ident struct {
+		at
+		name string
+	}
+	index struct {
+		at
+		x       expr
+		indices []expr
+	}
+	member struct {
+		at
+		x    expr
+		name string
+	}
+	unary struct {
+		at
+		op string
+		x  expr
+	}
+	binary struct {
+		at
+		op   string
+		x, y expr
+	}
+	call struct {
+		at
+		name string
+		args []arg
+	}
+	arrayInit struct {
+		at
+		elems []expr
+	}
+)
+
+// arg is an argument of a call: positional, IN := value or OUT => target
+type arg struct {
+	name   string
+	output bool
+	value  expr
+}
+
+type stmt interface{ pos() int }
+
+type (
+	assignStmt struct {
+		at
+		target, value expr
+	}
+	callStmt struct {
+		at
+		target expr
+		args   []arg
+	}
+	ifStmt struct {
+		at
+		conds  []expr
+		blocks [][]stmt
+		els    []stmt
+	}
+	caseStmt struct {
+		at
+		x        expr
+		branches []caseBranch
+		els      []stmt
+	}
+	forStmt struct {
+		at
+		name         string
+		from, to, by expr
+		body         []stmt
+	}
+	whileStmt struct {
+		at
+		cond expr
+		body []stmt
+	}
+	repeatStmt struct {
+		at
+		body  []stmt
+		until expr
+	}
+	exitStmt     struct{ at }
+	continueStmt struct{ at }
+	returnStmt   struct{ at }
+)
+
+type caseBranch struct {
+	labels [][2]expr // single values have a nil upper bound
+	body   []stmt
+}
+
+// Parse reads Structured Text with programs, function blocks, functions and
+// global variables. User-defined types are not supported.
+func Parse(src string) (*Program, error) {
+	lexemes, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexemes: lexemes}
+	prog := &Program{}
+	for p.peek().kind != lexEOF {
+		switch word := p.keyword(); word {
+		case "PROGRAM", "FUNCTION_BLOCK", "FUNCTION":
+			u, err := p.parsePOU()
+			if err != nil {
+				return nil, err
+			}
+			if prog.pou(u.name) != nil {
+				return nil, fmt.Errorf("line %d: %s is declared twice", u.line, u.name)
+			}
+			prog.pous = append(prog.pous, u)
+		case "VAR_GLOBAL":
+			vars, err := p.parseVars()
+			if err != nil {
+				return nil, err
+			}
+			prog.globals = append(prog.globals, vars...)
+		case "TYPE", "DATA_BLOCK", "CLASS", "INTERFACE":
+			return nil, p.errorf("%s is not supported by the interpreter", word)
+		default:
+			return nil, p.errorf("expected PROGRAM, FUNCTION_BLOCK, FUNCTION or VAR_GLOBAL, found %s", p.describe())
+		}
+	}
+	if len(prog.pous) == 0 {
+		return nil, fmt.Errorf("no PROGRAM, FUNCTION_BLOCK or FUNCTION found")
+	}
+	return prog, nil
+}
+
+// parseExpr reads a single expression, such as a signal path or value
+func parseExpr(src string) (expr, error) {
+	lexemes, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexemes: lexemes}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != lexEOF {
+		return nil, fmt.Errorf("unexpected %s", p.describe())
+	}
+	return e, nil
+}
+
+type parser struct {
+	lexemes []lexeme
+	i       int
+}
+
+func (p *parser) peek() lexeme { return p.lexemes[p.i] }
+
+func (p *parser) peekAt(n int) lexeme {
+	if p.i+n < len(p.lexemes) {
+		return p.lexemes[p.i+n]
+	}
+	return p.lexemes[len(p.lexemes)-1]
+}
+
+func (p *parser) next() lexeme {
+	l := p.lexemes[p.i]
+	if l.kind != lexEOF {
+		p.i++
+	}
+	return l
+}
+
+// keyword returns the next lexeme in upper case when it is an identifier
+func (p *parser) keyword() string {
+	if l := p.peek(); l.kind == lexIdent {
+		return strings.ToUpper(l.text)
+	}
+	return ""
+}
+
+func (p *parser) isOp(op string) bool {
+	l := p.peek()
+	return l.kind == lexOp && l.text == op
+}
+
+func (p *parser) accept(word string) bool {
+	if p.isOp(word) || p.keyword() == word {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(word string) error {
+	if !p.accept(word) {
+		return p.errorf("expected %s, found %s", word, p.describe())
+	}
+	return nil
+}
+
+func (p *parser) describe() string {
+	switch l := p.peek(); l.kind {
+	case lexEOF:
+		return "the end of the file"
+	case lexString:
+		return "'" + l.text + "'"
+	default:
+		return l.text
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.peek().line, fmt.Sprintf(format, args...))
+}
+
+// keywords cannot be names
+var keywords = map[string]bool{
+	"IF": true, "THEN": true, "ELSIF": true, "ELSE": true, "END_IF": true,
+	"CASE": true, "OF": true, "END_CASE": true, "FOR": true, "TO": true, "BY": true, "DO": true,
+	"END_FOR": true, "WHILE": true, "END_WHILE": true, "REPEAT": true, "UNTIL": true,
+	"END_REPEAT": true, "EXIT": true, "CONTINUE": true, "RETURN": true,
+	"AND": true, "OR": true, "XOR": true, "NOT": true, "MOD": true,
+	"VAR": true, "VAR_INPUT": true, "VAR_OUTPUT": true, "VAR_IN_OUT": true, "VAR_TEMP": true,
+	"VAR_GLOBAL": true, "VAR_EXTERNAL": true, "END_VAR": true, "BEGIN": true,
+}
+
+func (p *parser) name() (string, error) {
+	l := p.peek()
+	if l.kind != lexIdent || keywords[strings.ToUpper(l.text)] {
+		return "", p.errorf("expected a name, found %s", p.describe())
+	}
+	p.next()
+	return l.text, nil
+}
+
+func (p *parser) parsePOU() (*pou, error) {
+	u := &pou{kind: p.keyword(), line: p.peek().line}
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	u.name = name
+	if u.kind == "FUNCTION" {
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if u.returns, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+	}
+	for strings.HasPrefix(p.keyword(), "VAR") {
+		vars, err := p.parseVars()
+		if err != nil {
+			return nil, err
+		}
+		u.vars = append(u.vars, vars...)
+	}
+	p.accept("BEGIN")
+	end := "END_" + u.kind
+	if u.body, err = p.stmts(end); err != nil {
+		return nil, err
+	}
+	return u, p.expect(end)
+}
+
+// parseVars reads one VAR... END_VAR section
+func (p *parser) parseVars() ([]*varDecl, error) {
+	section := p.keyword()
+	switch section {
+	case "VAR", "VAR_INPUT", "VAR_OUTPUT", "VAR_IN_OUT", "VAR_TEMP", "VAR_GLOBAL":
+	case "VAR_EXTERNAL":
+		// The globals are visible anyway; skip the declarations
+		for p.keyword() != "END_VAR" && p.peek().kind != lexEOF {
+			p.next()
+		}
+		return nil, p.expect("END_VAR")
+	default:
+		return nil, p.errorf("%s is not supported by the interpreter", section)
+	}
+	p.next()
+	constant := false
+	for {
+		word := p.keyword()
+		if word != "CONSTANT" && word != "RETAIN" && word != "NON_RETAIN" && word != "PERSISTENT" {
+			break
+		}
+		constant = constant || word == "CONSTANT"
+		p.next()
+	}
+	var vars []*varDecl
+	for !p.accept("END_VAR") {
+		line := p.peek().line
+		var names []string
+		for {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if p.accept("AT") {
+			if p.peek().kind != lexAddress {
+				return nil, p.errorf("expected an address after AT, found %s", p.describe())
+			}
+			p.next()
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		t, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		var init expr
+		if p.accept(":=") {
+			if p.isOp("[") {
+				init, err = p.arrayInit()
+			} else {
+				init, err = p.expr()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			vars = append(vars, &varDecl{name: name, section: section, constant: constant, typ: t, init: init, line: line})
+		}
+	}
+	return vars, nil
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	if p.keyword() != "ARRAY" {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		// STRING[80] or STRING(80): the length is not checked
+		if strings.EqualFold(name, "STRING") || strings.EqualFold(name, "WSTRING") {
+			if p.isOp("[") || p.isOp("(") {
+				closing := map[string]string{"[": "]", "(": ")"}[p.next().text]
+				if _, err := p.expr(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(closing); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &typeRef{name: name}, nil
+	}
+	p.next()
+	t := &typeRef{name: "ARRAY"}
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	for {
+		lo, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(".."); err != nil {
+			return nil, err
+		}
+		hi, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		t.ranges = append(t.ranges, [2]expr{lo, hi})
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("OF"); err != nil {
+		return nil, err
+	}
+	elem, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+	if elem.elem != nil {
+		// ARRAY[1..2] OF ARRAY[1..3] OF INT is ARRAY[1..2, 1..3] OF INT
+		t.ranges = append(t.ranges, elem.ranges...)
+		elem = elem.elem
+	}
+	t.elem = elem
+	return t, nil
+}
+
+// arrayInit reads [1, 2, 3] with repetitions such as 3(0)
+func (p *parser) arrayInit() (expr, error) {
+	a := &arrayInit{at: at{p.peek().line}}
+	p.next()
+	for !p.accept("]") {
+		if len(a.elems) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if l := p.peek(); l.kind == lexInt && p.peekAt(1).text == "(" {
+			p.next()
+			p.next()
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			for n := int64(0); n < l.i; n++ {
+				a.elems = append(a.elems, e)
+			}
+			continue
+		}
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		a.elems = append(a.elems, e)
+	}
+	return a, nil
+}
+
+// stmts reads statements until one of the end keywords
+func (p *parser) stmts(ends ...string) ([]stmt, error) {
+	var list []stmt
+	for {
+		if p.peek().kind == lexEOF {
+			return nil, p.errorf("expected %s, found the end of the file", ends[0])
+		}
+		word := p.keyword()
+		for _, end := range ends {
+			if word == end {
+				return list, nil
+			}
+		}
+		if p.accept(";") {
+			continue
+		}
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+}
+
+// caseStmts reads the statements of a CASE branch, which end at the next
+// label
+func (p *parser) caseStmts() ([]stmt, error) {
+	var list []stmt
+	for {
+		switch word := p.keyword(); {
+		case word == "ELSE" || word == "END_CASE":
+			return list, nil
+		case p.isLabel():
+			return list, nil
+		case p.peek().kind == lexEOF:
+			return nil, p.errorf("expected END_CASE, found the end of the file")
+		}
+		if p.accept(";") {
+			continue
+		}
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+}
+
+// isLabel reports whether a CASE label starts here: statements do not
+// start with a number or a name followed by a colon, comma or range
+func (p *parser) isLabel() bool {
+	switch l := p.peek(); l.kind {
+	case lexInt, lexString, lexTime:
+		return true
+	case lexOp:
+		return l.text == "-" || l.text == "+"
+	case lexIdent:
+		n := p.peekAt(1)
+		return n.kind == lexOp && (n.text == ":" || n.text == "," || n.text == "..")
+	}
+	return false
+}
+
+func (p *parser) stmt() (stmt, error) {
+	l := p.peek()
+	pos := at{l.line}
+	var s stmt
+	switch p.keyword() {
+	case "IF":
+		p.next()
+		st := &ifStmt{at: pos}
+		for {
+			cond, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("THEN"); err != nil {
+				return nil, err
+			}
+			block, err := p.stmts("ELSIF", "ELSE", "END_IF")
+			if err != nil {
+				return nil, err
+			}
+			st.conds, st.blocks = append(st.conds, cond), append(st.blocks, block)
+			if !p.accept("ELSIF") {
+				break
+			}
+		}
+		if p.accept("ELSE") {
+			els, err := p.stmts("END_IF")
+			if err != nil {
+				return nil, err
+			}
+			st.els = els
+		}
+		if err := p.expect("END_IF"); err != nil {
+			return nil, err
+		}
+		s = st
+	case "CASE":
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("OF"); err != nil {
+			return nil, err
+		}
+		st := &caseStmt{at: pos, x: x}
+		for p.isLabel() {
+			var b caseBranch
+			for {
+				lo, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				var hi expr
+				if p.accept("..") {
+					if hi, err = p.expr(); err != nil {
+						return nil, err
+					}
+				}
+				b.labels = append(b.labels, [2]expr{lo, hi})
+				if !p.accept(",") {
+					break
+				}
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if b.body, err = p.caseStmts(); err != nil {
+				return nil, err
+			}
+			st.branches = append(st.branches, b)
+		}
+		if p.accept("ELSE") {
+			if st.els, err = p.stmts("END_CASE"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("END_CASE"); err != nil {
+			return nil, err
+		}
+		s = st
+	case "FOR":
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		st := &forStmt{at: pos, name: name}
+		if err := p.expect(":="); err != nil {
+			return nil, err
+		}
+		if st.from, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("TO"); err != nil {
+			return nil, err
+		}
+		if st.to, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if p.accept("BY") {
+			if st.by, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("DO"); err != nil {
+			return nil, err
+		}
+		if st.body, err = p.stmts("END_FOR"); err != nil {
+			return nil, err
+		}
+		p.next()
+		s = st
+	case "WHILE":
+		p.next()
+		cond, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("DO"); err != nil {
+			return nil, err
+		}
+		body, err := p.stmts("END_WHILE")
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		s = &whileStmt{at: pos, cond: cond, body: body}
+	case "REPEAT":
+		p.next()
+		body, err := p.stmts("UNTIL")
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		until, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		p.accept(";")
+		if err := p.expect("END_REPEAT"); err != nil {
+			return nil, err
+		}
+		s = &repeatStmt{at: pos, body: body, until: until}
+	case "EXIT":
+		p.next()
+		s = &exitStmt{pos}
+	case "CONTINUE":
+		p.next()
+		s = &continueStmt{pos}
+	case "RETURN":
+		p.next()
+		s = &returnStmt{pos}
+	default:
+		target, err := p.designator()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.accept(":="):
+			value, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			s = &assignStmt{at: pos, target: target, value: value}
+		case p.isOp("("):
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			s = &callStmt{at: pos, target: target, args: args}
+		default:
+			return nil, p.errorf("expected := or a call after %s, found %s", l.text, p.describe())
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// designator reads a variable with its indices and members, such as
+// Timers[2].Q
+func (p *parser) designator() (expr, error) {
+	pos := at{p.peek().line}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	var x expr = &ident{at: pos, name: name}
+	for {
+		switch {
+		case p.accept("["):
+			ix := &index{at: pos, x: x}
+			for {
+				e, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				ix.indices = append(ix.indices, e)
+				if !p.accept(",") {
+					break
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = ix
+		case p.isOp(".") && p.peekAt(1).kind == lexIdent:
+			p.next()
+			x = &member{at: pos, x: x, name: p.next().text}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// args reads the arguments of a call, from the opening parenthesis
+func (p *parser) args() ([]arg, error) {
+	p.next()
+	var args []arg
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		var a arg
+		if n := p.peekAt(1); p.peek().kind == lexIdent && n.kind == lexOp && (n.text == ":=" || n.text == "=>") {
+			a.name, a.output = p.next().text, p.next().text == "=>"
+		}
+		var err error
+		if a.output {
+			a.value, err = p.designator()
+		} else {
+			a.value, err = p.expr()
+		}
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	return args, nil
+}
+
+// precedence lists the binary operators from the loosest binding
+var precedence = [][]string{
+	{"OR"},
+	{"XOR"},
+	{"AND", "&"},
+	{"=", "<>"},
+	{"<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/", "MOD"},
+}
+
+func (p *parser) expr() (expr, error) {
+	return p.binary(0)
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, o := range precedence[level] {
+			if p.isOp(o) || p.keyword() == o {
+				op = o
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		line := p.next().line
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		if op == "&" {
+			op = "AND"
+		}
+		x = &binary{at: at{line}, op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	line := p.peek().line
+	for _, op := range []string{"-", "+", "NOT"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unary{at: at{line}, op: op, x: x}, nil
+		}
+	}
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("**") {
+		y, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{at: at{line}, op: "**", x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	l := p.peek()
+	pos := at{l.line}
+	switch l.kind {
+	case lexInt:
+		p.next()
+		return &literal{pos, l.i}, nil
+	case lexReal:
+		p.next()
+		return &literal{pos, l.f}, nil
+	case lexTime:
+		p.next()
+		return &literal{pos, l.d}, nil
+	case lexString:
+		p.next()
+		return &literal{pos, l.text}, nil
+	case lexOp:
+		if p.accept("(") {
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	case lexIdent:
+		switch p.keyword() {
+		case "TRUE":
+			p.next()
+			return &literal{pos, true}, nil
+		case "FALSE":
+			p.next()
+			return &literal{pos, false}, nil
+		}
+		if n := p.peekAt(1); n.kind == lexOp && n.text == "(" {
+			p.next()
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			return &call{at: pos, name: l.text, args: args}, nil
+		}
+		return p.designator()
+	}
+	return nil, p.errorf("expected an expression, found %s", p.describe())
+}
@@ -0,0 +1,165 @@
+package st
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Get returns a variable of the block as a signal value: BOOL as 1 or 0,
+// TIME as a literal such as T#1s500ms. The path names a variable, an
+// array element or a member of an instance, such as Step, Permit[1,2] or
+// Watchdog.ET.
+func (m *Machine) Get(path string) (string, error) {
+	_, r, err := m.path(path)
+	if err != nil {
+		return "", err
+	}
+	switch v := r.get().(type) {
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Duration:
+		return formatTime(v), nil
+	case string:
+		return v, nil
+	case *array:
+		return "", fmt.Errorf("%s is an array; name an element such as %s[%d]", path, path, v.t.lo[0])
+	default:
+		return "", fmt.Errorf("%s is a function block instance; name a member such as %s.Q", path, path)
+	}
+}
+
+// Set changes a variable of the block, as the inputs a PLC reads at the
+// start of a scan. Outputs of the block and constants cannot be set.
+// Values are given as signal values or Structured Text literals; a TIME
+// also as a duration such as 500ms.
+func (m *Machine) Set(path, text string) error {
+	f, r, err := m.path(path)
+	if err != nil {
+		return err
+	}
+	if r.v.constant {
+		return fmt.Errorf("%s is a constant", r.v.name)
+	}
+	if r.v.section == "VAR_OUTPUT" && m.root.lookup(r.v.name) == r.v {
+		return fmt.Errorf("%s is an output of %s; the logic sets it", r.v.name, m.Name())
+	}
+	t := r.v.t
+	if r.arr != nil {
+		t = r.arr.t.elem
+	}
+	text = strings.TrimSpace(text)
+	var v value
+	switch t.kind {
+	case kindBool:
+		switch strings.ToUpper(text) {
+		case "1", "TRUE":
+			v = true
+		case "0", "FALSE":
+			v = false
+		default:
+			return fmt.Errorf("%s is BOOL; set it to 1 or 0", path)
+		}
+	case kindString:
+		v = text
+		if s, n, err := readString(text); err == nil && n == len(text) {
+			v = s
+		}
+	case kindArray, kindFB:
+		return fmt.Errorf("%s is not a single value", path)
+	default:
+		if d, err := time.ParseDuration(text); err == nil && t.kind == kindTime {
+			v = d
+			break
+		}
+		e, err := parseExpr(text)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s", text, path)
+		}
+		if v, err = f.eval(e); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", text, path, err)
+		}
+	}
+	if err := r.set(v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// path resolves a variable path in the block's memory
+func (m *Machine) path(path string) (*frame, ref, error) {
+	e, err := parseExpr(path)
+	if err != nil {
+		return nil, ref{}, fmt.Errorf("invalid variable %q: %v", path, err)
+	}
+	switch e.(type) {
+	case *ident, *member, *index:
+	default:
+		return nil, ref{}, fmt.Errorf("invalid variable %q; name one such as Step, Timer.Q or Permit[1,2]", path)
+	}
+	f := &frame{m: m, in: m.root}
+	r, err := f.ref(e)
+	if err != nil {
+		// Drop the line number of the one-line path
+		_, msg, _ := strings.Cut(err.Error(), ": ")
+		return nil, ref{}, fmt.Errorf("%s: %s", path, msg)
+	}
+	return f, r, nil
+}
+
+// Sim runs a machine in simulated time as the IO and Clock of a
+// scenario.Player: it runs the scans that fit in the time the player
+// waits, so a scenario with long timeouts plays in moments.
+type Sim struct {
+	Machine *Machine
+	Cycle   time.Duration
+
+	start   time.Time
+	elapsed time.Duration
+	pending time.Duration // time slept since the last scan
+}
+
+// NewSim runs the first scan, as the PLC would be running when a scenario
+// starts
+func NewSim(m *Machine, cycle time.Duration) (*Sim, error) {
+	if cycle <= 0 {
+		return nil, fmt.Errorf("the cycle time must be positive")
+	}
+	return &Sim{Machine: m, Cycle: cycle, start: time.Now()}, m.Scan(0)
+}
+
+// ReadSignal reads a variable of the block; see Machine.Get
+func (s *Sim) ReadSignal(signal string) (string, error) {
+	return s.Machine.Get(signal)
+}
+
+// WriteSignal sets a variable of the block; see Machine.Set
+func (s *Sim) WriteSignal(signal, value string) error {
+	return s.Machine.Set(signal, value)
+}
+
+// Now returns the simulated time
+func (s *Sim) Now() time.Time {
+	return s.start.Add(s.elapsed)
+}
+
+// Sleep lets simulated time pass, scanning every cycle
+func (s *Sim) Sleep(d time.Duration) error {
+	s.elapsed += d
+	s.pending += d
+	for s.pending >= s.Cycle {
+		s.pending -= s.Cycle
+		if err := s.Machine.Scan(s.Cycle); err != nil {
+			return fmt.Errorf("%s at %.3f s: %v", s.Machine.Name(), s.Machine.Now().Seconds(), err)
+		}
+	}
+	return nil
+}
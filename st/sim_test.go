@@ -0,0 +1,222 @@
+package st
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/scenario"
+)
+
+// play runs a scenario against a block as 'scenario sim' does and returns
+// the failed checks
+func play(t *testing.T, src, steps string) []string {
+	t.Helper()
+	m := newMachine(t, src, "")
+	sim, err := NewSim(m, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := scenario.Parse("name: test\nsteps:\n" + steps)
+	if err != nil {
+		t.Fatalf("scenario: %v", err)
+	}
+	player := &scenario.Player{IO: sim, Clock: sim, Poll: sim.Cycle}
+	entries, err := player.Play(sc)
+	if err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	var failed []string
+	for _, e := range entries {
+		if e.Failed {
+			failed = append(failed, e.String())
+		}
+	}
+	return failed
+}
+
+func TestGeneratedHandshake(t *testing.T) {
+	level, err := generate.HandshakeCode(generate.HandshakeOptions{Signals: []string{"job_start", "job_done", "fault"},
+		Style: generate.StyleLevel, Routine: "DoJob", Timeout: 10, Pulse: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pulse, err := generate.HandshakeCode(generate.HandshakeOptions{Signals: []string{"job_start", "job_done"},
+		Style: generate.StylePulse, Routine: "DoJob", Timeout: 10, Pulse: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		src    string
+		steps  string
+		failed int
+	}{
+		{"level job", level.ST, `
+  - set: Execute=1
+  - wait_for: job_start=1
+    timeout: 50ms
+  - expect: Busy=1
+  - set: job_done=1
+  - wait_for: job_start=0
+    timeout: 50ms
+  - expect: Done=0
+  - set: job_done=0
+  - wait_for: Done=1
+    timeout: 50ms
+  - expect: Busy=0
+  - set: Execute=0
+  - wait: 50ms
+  - expect: Done=0
+`, 0},
+		{"level fault", level.ST, `
+  - set: Execute=1
+  - wait_for: job_start=1
+    timeout: 50ms
+  - set: fault=1
+  - wait_for: Error=1
+    timeout: 50ms
+  - expect: job_start=0
+  - expect: Busy=0
+`, 0},
+		{"level watchdog", level.ST, `
+  - set: Timeout=2s
+  - set: Execute=1
+  - wait: 1.9s
+  - expect: Error=0
+  - wait_for: Error=1
+    timeout: 200ms
+  - expect: job_start=0
+`, 0},
+		{"level without an answer", level.ST, `
+  - set: Execute=1
+  - wait_for: Done=1
+    timeout: 1s
+`, 1},
+		{"pulse job", pulse.ST, `
+  - set: Execute=1
+  - wait_for: job_start=1
+    timeout: 50ms
+  - wait: 600ms
+  - expect: job_start=0
+  - expect: Busy=1
+  - pulse: job_done
+    duration: 100ms
+  - wait_for: Done=1
+    timeout: 50ms
+  - expect: Busy=0
+`, 0},
+		{"pulse done before the request", pulse.ST, `
+  - pulse: job_done
+  - expect: Done=0
+  - set: Execute=1
+  - wait: 1s
+  - expect: Done=0
+  - expect: Busy=1
+`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := play(t, tt.src, tt.steps)
+			if len(failed) != tt.failed {
+				t.Errorf("%d checks failed, want %d:\n%s", len(failed), tt.failed, strings.Join(failed, "\n"))
+			}
+		})
+	}
+}
+
+func TestGeneratedZoneArbiter(t *testing.T) {
+	z, err := generate.ZoneInterlockCode(generate.ZoneOptions{Robots: 2, Zones: 2, Method: generate.ZoneSignals, Timeout: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		steps string
+	}{
+		{"one robot at a time", `
+  - set: Request[1,1]=1
+  - wait_for: Permit[1,1]=1
+    timeout: 50ms
+  - set: InZone[1,1]=1
+  - set: Request[2,1]=1
+  - wait: 100ms
+  - expect: Permit[2,1]=0
+  - set: Request[1,1]=0
+  - wait: 50ms
+  - expect: Permit[2,1]=0
+  - set: InZone[1,1]=0
+  - wait_for: Permit[2,1]=1
+    timeout: 50ms
+  - expect: Permit[1,1]=0
+`},
+		{"round robin", `
+  - set: Request[1,2]=1
+  - set: Request[2,2]=1
+  - wait: 50ms
+  - expect: Permit[1,2]=1
+  - expect: Permit[2,2]=0
+  - set: Request[1,2]=0
+  - wait: 50ms
+  - expect: Permit[2,2]=1
+  - set: Request[1,2]=1
+  - set: Request[2,2]=0
+  - wait_for: Permit[1,2]=1
+    timeout: 50ms
+`},
+		{"zones are independent", `
+  - set: Request[1,1]=1
+  - set: Request[2,2]=1
+  - wait: 50ms
+  - expect: Permit[1,1]=1
+  - expect: Permit[2,2]=1
+`},
+		{"in a zone without permit", `
+  - set: InZone[2,1]=1
+  - wait: 50ms
+  - expect: Fault[1]=1
+  - expect: Fault[2]=0
+  - set: InZone[2,1]=0
+  - wait: 50ms
+  - expect: Fault[1]=0
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if failed := play(t, z.ST, tt.steps); len(failed) > 0 {
+				t.Errorf("failed checks:\n%s", strings.Join(failed, "\n"))
+			}
+		})
+	}
+}
+
+func TestSimTime(t *testing.T) {
+	m := newMachine(t, stdBlocks, "")
+	sim, err := NewSim(m, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := sim.Now()
+	if err := sim.WriteSignal("In", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// 25 ms of waits scan twice; the rest waits for the next sleep
+	for _, d := range []time.Duration{15 * time.Millisecond, 10 * time.Millisecond} {
+		if err := sim.Sleep(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := sim.Now().Sub(start); got != 25*time.Millisecond {
+		t.Errorf("simulated time %v, want 25ms", got)
+	}
+	if got := m.Now(); got != 20*time.Millisecond {
+		t.Errorf("machine time %v, want 20ms", got)
+	}
+	if v, _ := sim.ReadSignal("OnDelay.ET"); v != "T#10ms" {
+		t.Errorf("OnDelay.ET = %s, want T#10ms", v)
+	}
+	if _, err := NewSim(m, 0); err == nil {
+		t.Error("a cycle of 0 was accepted")
+	}
+}
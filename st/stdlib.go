@@ -0,0 +1,453 @@
+package st
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stdFB is a standard function block of IEC 61131-3, run in Go
+type stdFB struct {
+	vars []stdVar
+	exec func(in *instance, now time.Duration)
+}
+
+type stdVar struct {
+	name, section, typ string
+}
+
+func (in *instance) bool(name string) bool          { return in.vars[name].v.(bool) }
+func (in *instance) int(name string) int64          { return in.vars[name].v.(int64) }
+func (in *instance) time(name string) time.Duration { return in.vars[name].v.(time.Duration) }
+
+func (in *instance) put(name string, v value) {
+	in.vars[name].v, _ = convert(v, in.vars[name].t)
+}
+
+// Internal state is kept in VAR variables, which can be watched like the
+// outputs
+var (
+	trigVars  = []stdVar{{"CLK", "VAR_INPUT", "BOOL"}, {"Q", "VAR_OUTPUT", "BOOL"}, {"M", "VAR", "BOOL"}}
+	timerVars = []stdVar{{"IN", "VAR_INPUT", "BOOL"}, {"PT", "VAR_INPUT", "TIME"},
+		{"Q", "VAR_OUTPUT", "BOOL"}, {"ET", "VAR_OUTPUT", "TIME"},
+		{"START", "VAR", "TIME"}, {"RUNNING", "VAR", "BOOL"}, {"M", "VAR", "BOOL"}}
+)
+
+// stdFBs are the standard function blocks by name
+var stdFBs = map[string]*stdFB{
+	"R_TRIG": {vars: trigVars, exec: func(in *instance, _ time.Duration) {
+		clk := in.bool("CLK")
+		in.put("Q", clk && !in.bool("M"))
+		in.put("M", clk)
+	}},
+	"F_TRIG": {vars: trigVars, exec: func(in *instance, _ time.Duration) {
+		clk := in.bool("CLK")
+		in.put("Q", !clk && in.bool("M"))
+		in.put("M", clk)
+	}},
+	// TON: Q rises once IN has been TRUE for PT
+	"TON": {vars: timerVars, exec: func(in *instance, now time.Duration) {
+		if !in.bool("IN") {
+			in.put("RUNNING", false)
+			in.put("Q", false)
+			in.put("ET", time.Duration(0))
+			return
+		}
+		if !in.bool("RUNNING") {
+			in.put("RUNNING", true)
+			in.put("START", now)
+		}
+		et := min(now-in.time("START"), in.time("PT"))
+		in.put("ET", et)
+		in.put("Q", et >= in.time("PT"))
+	}},
+	// TOF: Q follows IN and falls once IN has been FALSE for PT
+	"TOF": {vars: timerVars, exec: func(in *instance, now time.Duration) {
+		if in.bool("IN") {
+			in.put("RUNNING", false)
+			in.put("Q", true)
+			in.put("ET", time.Duration(0))
+			return
+		}
+		if !in.bool("Q") {
+			return
+		}
+		if !in.bool("RUNNING") {
+			in.put("RUNNING", true)
+			in.put("START", now)
+		}
+		et := min(now-in.time("START"), in.time("PT"))
+		in.put("ET", et)
+		if et >= in.time("PT") {
+			in.put("Q", false)
+			in.put("RUNNING", false)
+		}
+	}},
+	// TP: a rising edge of IN starts a pulse of PT that IN cannot shorten
+	// or restart
+	"TP": {vars: timerVars, exec: func(in *instance, now time.Duration) {
+		rising := in.bool("IN") && !in.bool("M")
+		in.put("M", in.bool("IN"))
+		if rising && !in.bool("RUNNING") {
+			in.put("RUNNING", true)
+			in.put("START", now)
+			in.put("Q", true)
+		}
+		if in.bool("RUNNING") {
+			et := min(now-in.time("START"), in.time("PT"))
+			in.put("ET", et)
+			if et >= in.time("PT") {
+				in.put("RUNNING", false)
+				in.put("Q", false)
+			}
+		}
+		if !in.bool("RUNNING") && !in.bool("IN") {
+			in.put("ET", time.Duration(0))
+		}
+	}},
+	"CTU": {vars: []stdVar{{"CU", "VAR_INPUT", "BOOL"}, {"R", "VAR_INPUT", "BOOL"}, {"PV", "VAR_INPUT", "INT"},
+		{"Q", "VAR_OUTPUT", "BOOL"}, {"CV", "VAR_OUTPUT", "INT"}, {"M", "VAR", "BOOL"}},
+		exec: func(in *instance, _ time.Duration) {
+			switch {
+			case in.bool("R"):
+				in.put("CV", int64(0))
+			case in.bool("CU") && !in.bool("M") && in.int("CV") < math.MaxInt16:
+				in.put("CV", in.int("CV")+1)
+			}
+			in.put("M", in.bool("CU"))
+			in.put("Q", in.int("CV") >= in.int("PV"))
+		}},
+	"CTD": {vars: []stdVar{{"CD", "VAR_INPUT", "BOOL"}, {"LD", "VAR_INPUT", "BOOL"}, {"PV", "VAR_INPUT", "INT"},
+		{"Q", "VAR_OUTPUT", "BOOL"}, {"CV", "VAR_OUTPUT", "INT"}, {"M", "VAR", "BOOL"}},
+		exec: func(in *instance, _ time.Duration) {
+			switch {
+			case in.bool("LD"):
+				in.put("CV", in.int("PV"))
+			case in.bool("CD") && !in.bool("M") && in.int("CV") > math.MinInt16:
+				in.put("CV", in.int("CV")-1)
+			}
+			in.put("M", in.bool("CD"))
+			in.put("Q", in.int("CV") <= 0)
+		}},
+	"CTUD": {vars: []stdVar{{"CU", "VAR_INPUT", "BOOL"}, {"CD", "VAR_INPUT", "BOOL"}, {"R", "VAR_INPUT", "BOOL"},
+		{"LD", "VAR_INPUT", "BOOL"}, {"PV", "VAR_INPUT", "INT"},
+		{"QU", "VAR_OUTPUT", "BOOL"}, {"QD", "VAR_OUTPUT", "BOOL"}, {"CV", "VAR_OUTPUT", "INT"},
+		{"MU", "VAR", "BOOL"}, {"MD", "VAR", "BOOL"}},
+		exec: func(in *instance, _ time.Duration) {
+			up, down := in.bool("CU") && !in.bool("MU"), in.bool("CD") && !in.bool("MD")
+			switch {
+			case in.bool("R"):
+				in.put("CV", int64(0))
+			case in.bool("LD"):
+				in.put("CV", in.int("PV"))
+			case up && !down && in.int("CV") < math.MaxInt16:
+				in.put("CV", in.int("CV")+1)
+			case down && !up && in.int("CV") > math.MinInt16:
+				in.put("CV", in.int("CV")-1)
+			}
+			in.put("MU", in.bool("CU"))
+			in.put("MD", in.bool("CD"))
+			in.put("QU", in.int("CV") >= in.int("PV"))
+			in.put("QD", in.int("CV") <= 0)
+		}},
+	// SR: set dominant
+	"SR": {vars: []stdVar{{"S1", "VAR_INPUT", "BOOL"}, {"R", "VAR_INPUT", "BOOL"}, {"Q1", "VAR_OUTPUT", "BOOL"}},
+		exec: func(in *instance, _ time.Duration) {
+			in.put("Q1", in.bool("S1") || !in.bool("R") && in.bool("Q1"))
+		}},
+	// RS: reset dominant
+	"RS": {vars: []stdVar{{"S", "VAR_INPUT", "BOOL"}, {"R1", "VAR_INPUT", "BOOL"}, {"Q1", "VAR_OUTPUT", "BOOL"}},
+		exec: func(in *instance, _ time.Duration) {
+			in.put("Q1", !in.bool("R1") && (in.bool("S") || in.bool("Q1")))
+		}},
+}
+
+type stdFunc func(args []value) (value, error)
+
+// stdFuncs are the standard functions by name; the type conversions are
+// found by lookupStdFunc
+var stdFuncs = map[string]stdFunc{
+	"ABS": func(args []value) (value, error) {
+		if err := numbers(args, 1); err != nil {
+			return nil, err
+		}
+		if n, ok := args[0].(int64); ok {
+			if n < 0 {
+				return -n, nil
+			}
+			return n, nil
+		}
+		return math.Abs(args[0].(float64)), nil
+	},
+	"SQRT":  realFunc(math.Sqrt),
+	"LN":    realFunc(math.Log),
+	"LOG":   realFunc(math.Log10),
+	"EXP":   realFunc(math.Exp),
+	"SIN":   realFunc(math.Sin),
+	"COS":   realFunc(math.Cos),
+	"TAN":   realFunc(math.Tan),
+	"ASIN":  realFunc(math.Asin),
+	"ACOS":  realFunc(math.Acos),
+	"ATAN":  realFunc(math.Atan),
+	"TRUNC": func(args []value) (value, error) { return convertTo("DINT", args, true) },
+	"EXPT": func(args []value) (value, error) {
+		if err := numbers(args, 2); err != nil {
+			return nil, err
+		}
+		return math.Pow(toFloat(args[0]), toFloat(args[1])), nil
+	},
+	"MIN": func(args []value) (value, error) { return extreme(args, "<") },
+	"MAX": func(args []value) (value, error) { return extreme(args, ">") },
+	"LIMIT": func(args []value) (value, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("needs MN, IN and MX")
+		}
+		v, err := extreme([]value{args[0], args[1]}, ">")
+		if err != nil {
+			return nil, err
+		}
+		return extreme([]value{v, args[2]}, "<")
+	},
+	"SEL": func(args []value) (value, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("needs G, IN0 and IN1")
+		}
+		g, ok := args[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("G must be BOOL")
+		}
+		if g {
+			return args[2], nil
+		}
+		return args[1], nil
+	},
+	"MUX": func(args []value) (value, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("needs K and the inputs")
+		}
+		k, ok := args[0].(int64)
+		if !ok || k < 0 || int(k) >= len(args)-1 {
+			return nil, fmt.Errorf("K selects none of the %d inputs", len(args)-1)
+		}
+		return args[k+1], nil
+	},
+	"MOVE": func(args []value) (value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("needs one argument")
+		}
+		return args[0], nil
+	},
+	"LEN": func(args []value) (value, error) {
+		s, err := stringArg(args, 0, 1)
+		return int64(len(s)), err
+	},
+	"CONCAT": func(args []value) (value, error) {
+		var b strings.Builder
+		for i := range args {
+			s, err := stringArg(args, i, len(args))
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(s)
+		}
+		return b.String(), nil
+	},
+	"LEFT": func(args []value) (value, error) {
+		s, n, err := stringAndLength(args)
+		if err != nil {
+			return nil, err
+		}
+		return s[:min(n, len(s))], nil
+	},
+	"RIGHT": func(args []value) (value, error) {
+		s, n, err := stringAndLength(args)
+		if err != nil {
+			return nil, err
+		}
+		return s[len(s)-min(n, len(s)):], nil
+	},
+	"MID": func(args []value) (value, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("needs IN, L and P")
+		}
+		s, n, err := stringAndLength(args[:2])
+		if err != nil {
+			return nil, err
+		}
+		p, ok := args[2].(int64)
+		if !ok || p < 1 || int(p) > len(s)+1 {
+			return nil, fmt.Errorf("P must be a position in the string")
+		}
+		start := int(p) - 1
+		return s[start:min(start+n, len(s))], nil
+	},
+}
+
+// lookupStdFunc finds a standard function, including the conversions
+// such as INT_TO_REAL and TO_STRING
+func lookupStdFunc(name string) (stdFunc, bool) {
+	upper := strings.ToUpper(name)
+	if fn, ok := stdFuncs[upper]; ok {
+		return fn, true
+	}
+	target := ""
+	if i := strings.Index(upper, "_TO_"); i > 0 {
+		target = upper[i+4:]
+	} else if strings.HasPrefix(upper, "TO_") {
+		target = upper[3:]
+	}
+	if _, ok := elementary[target]; !ok {
+		return nil, false
+	}
+	return func(args []value) (value, error) { return convertTo(target, args, false) }, true
+}
+
+// convertTo converts between elementary types. Reals are rounded to
+// integers, or truncated for TRUNC; TIME converts to and from
+// milliseconds.
+func convertTo(target string, args []value, truncate bool) (value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("needs one argument")
+	}
+	t := elementary[target]
+	switch v := args[0].(type) {
+	case bool:
+		switch t.kind {
+		case kindBool:
+			return v, nil
+		case kindInt:
+			return int64(boolInt(v)), nil
+		case kindReal:
+			return float64(boolInt(v)), nil
+		case kindString:
+			return strings.ToUpper(strconv.FormatBool(v)), nil
+		}
+	case int64:
+		switch t.kind {
+		case kindBool:
+			return v != 0, nil
+		case kindInt:
+			return wrap(v, t), nil
+		case kindReal:
+			return float64(v), nil
+		case kindTime:
+			return time.Duration(v) * time.Millisecond, nil
+		case kindString:
+			return strconv.FormatInt(v, 10), nil
+		}
+	case float64:
+		switch t.kind {
+		case kindInt:
+			if truncate {
+				return wrap(int64(v), t), nil
+			}
+			return wrap(int64(math.RoundToEven(v)), t), nil
+		case kindReal:
+			return v, nil
+		case kindTime:
+			return time.Duration(v * float64(time.Millisecond)), nil
+		case kindString:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		}
+	case time.Duration:
+		switch t.kind {
+		case kindInt:
+			return wrap(v.Milliseconds(), t), nil
+		case kindReal:
+			return float64(v) / float64(time.Millisecond), nil
+		case kindTime:
+			return v, nil
+		case kindString:
+			return formatTime(v), nil
+		}
+	case string:
+		s := strings.TrimSpace(v)
+		switch t.kind {
+		case kindString:
+			return v, nil
+		case kindBool:
+			b, err := strconv.ParseBool(strings.ToLower(s))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a BOOL", v)
+			}
+			return b, nil
+		case kindInt:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", v)
+			}
+			return wrap(n, t), nil
+		case kindReal:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a REAL", v)
+			}
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %s to %s", typeName(args[0]), target)
+}
+
+func realFunc(fn func(float64) float64) stdFunc {
+	return func(args []value) (value, error) {
+		if err := numbers(args, 1); err != nil {
+			return nil, err
+		}
+		return fn(toFloat(args[0])), nil
+	}
+}
+
+func numbers(args []value, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("needs %d arguments", n)
+	}
+	for _, a := range args {
+		if !isNumber(a) {
+			return fmt.Errorf("needs numbers, found %s", typeName(a))
+		}
+	}
+	return nil
+}
+
+// extreme returns the smallest or largest argument
+func extreme(args []value, op string) (value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("needs arguments")
+	}
+	best := args[0]
+	for _, a := range args[1:] {
+		better, err := compare(0, op, a, best)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(best))
+		}
+		if better {
+			best = a
+		}
+	}
+	return best, nil
+}
+
+func stringArg(args []value, i, n int) (string, error) {
+	if len(args) != n {
+		return "", fmt.Errorf("needs %d arguments", n)
+	}
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("needs a STRING, found %s", typeName(args[i]))
+	}
+	return s, nil
+}
+
+func stringAndLength(args []value) (string, int, error) {
+	s, err := stringArg(args, 0, 2)
+	if err != nil {
+		return "", 0, err
+	}
+	n, ok := args[1].(int64)
+	if !ok || n < 0 {
+		return "", 0, fmt.Errorf("L must be a positive integer")
+	}
+	return s, int(n), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+const stoppingUsage = `Usage: calc stopping --robot <model> [options]
+
+Estimates the robot stopping time from stopping data (per model, speed,
+arm extension and load) and the minimum light curtain distance by
+ISO 13855, S = K*T + C, printing the calculation trail for the safety file.
+Stopping data comes from data packs; the built-in values are only
+illustrations.
+
+Options:
+  --speed <percent>       Robot speed in percent of maximum (default 100)
+  --extension <percent>   Arm extension in percent of maximum (default 100)
+  --load <percent>        Load in percent of the rated payload (default 100)
+  --category <0|1>        Stop category of the safety function (default 1)
+  --response <s>          Response time of the light curtain (default 0.02)
+  --safety-time <s>       Response time of the safety controller or relay (default 0.01)
+  --resolution <mm>       Detection capability of the light curtain (default 30)
+  --output <file.md>      Write the calculation trail to a file
+
+Examples:
+  calc stopping --robot irb6700-150 --speed 66 --load 100
+  calc stopping --robot irb4600-60 --response 0.014 --resolution 14 --output cell4_distance.md`
+
+func calcStopping(args []string) (string, error) {
+	var robot, output string
+	speed, extension, load := 100.0, 100.0, 100.0
+	category := 1
+	response, safetyTime, resolution := 0.02, 0.01, 30.0
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return stoppingUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--robot":
+			robot = value
+		case "--output":
+			output = value
+		case "--speed":
+			speed, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		case "--extension":
+			extension, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		case "--load":
+			load, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		case "--category":
+			category, err = strconv.Atoi(value)
+			if category != 0 && category != 1 {
+				err = fmt.Errorf("stop category must be 0 or 1")
+			}
+		case "--response":
+			response, err = parseUnit(value, map[string]float64{"s": 1, "ms": 0.001})
+		case "--safety-time":
+			safetyTime, err = parseUnit(value, map[string]float64{"s": 1, "ms": 0.001})
+		case "--resolution":
+			resolution, err = parseUnit(value, map[string]float64{"mm": 1})
+		default:
+			return stoppingUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if robot == "" {
+		robot = projectRobot()
+	}
+	if robot == "" {
+		return stoppingUsage, nil
+	}
+
+	key, data, ok := stoppingRobot(robot)
+	if !ok {
+		return "", fmt.Errorf("no stopping data for %q. Available: %s", robot, strings.Join(abb.StoppingRobots(), ", "))
+	}
+	r, _ := abb.RobotModel(key)
+	estimates, err := data.Estimate(category, speed, extension, load)
+	if err != nil {
+		return "", err
+	}
+	worst := estimates[0]
+	total := response + safetyTime + worst.Point.Time
+	distance, err := calc.LightCurtainDistance(total, resolution)
+	if err != nil {
+		return "", err
+	}
+
+	var trail strings.Builder
+	fmt.Fprintf(&trail, "# Safety distance: %s\n\n", r.Model)
+	fmt.Fprintf(&trail, "Stopping data: %s\n\n", data.Source)
+	trail.WriteString("## Robot stopping performance\n\n")
+	fmt.Fprintf(&trail, "Requested: stop category %d, speed %g%%, extension %g%%, load %g%% (%g kg of %g kg)\n\n", category, speed, extension, load, r.Payload*load/100, r.Payload)
+	trail.WriteString("| Axis | Data point (speed/extension/load %) | Stopping time | Stopping distance |\n|---|---|---|---|\n")
+	for _, e := range estimates {
+		p := e.Point
+		dist := fmt.Sprintf("%.1f°", p.Distance)
+		if e.Axis == 1 && r.Reach > 0 {
+			dist += fmt.Sprintf(" (%.0f mm at %.0f mm reach)", p.Distance*math.Pi/180*r.Reach*1000*extension/100, r.Reach*1000*extension/100)
+		}
+		fmt.Fprintf(&trail, "| %d | %g/%g/%g | %.2f s | %s |\n", e.Axis, p.Speed, p.Extension, p.Load, p.Time, dist)
+	}
+	fmt.Fprintf(&trail, "\nData points at or above the requested values are used, so the estimate does not undercut the data. Longest stopping time: axis %d, %.2f s.\n\n", worst.Axis, worst.Point.Time)
+	trail.WriteString("## Overall stopping performance (ISO 13855 5.2)\n\n")
+	fmt.Fprintf(&trail, "T = t1 light curtain + t2 safety controller + t3 robot\n")
+	fmt.Fprintf(&trail, "T = %.3f + %.3f + %.3f = %.3f s\n\n", response, safetyTime, worst.Point.Time, total)
+	trail.WriteString("## Minimum distance (ISO 13855 6.2.3)\n\n")
+	for _, step := range distance.Steps {
+		trail.WriteString(step + "\n")
+	}
+	fmt.Fprintf(&trail, "\n**Minimum distance S = %.0f mm** (K = %.0f mm/s, T = %.3f s, C = %.0f mm)\n\n", distance.Distance, distance.K, distance.Time, distance.C)
+	trail.WriteString("Verify: stopping time measured on the installed robot with the actual tool and load, response times from the device data sheets, reach over, under and around the light curtain (ISO 13857).\n")
+
+	if output != "" {
+		if err := writeFile(output, []byte(trail.String()), 0644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Minimum distance %.0f mm (T = %.3f s). Calculation trail written to %s", distance.Distance, total, output), nil
+	}
+	return strings.TrimRight(trail.String(), "\n"), nil
+}
+
+// stoppingRobot finds the stopping data of a robot given by key or model name
+func stoppingRobot(name string) (string, abb.Stopping, bool) {
+	if data, ok := abb.StoppingData(strings.ToLower(name)); ok {
+		return strings.ToLower(name), data, true
+	}
+	r, ok := abb.RobotModel(name)
+	if !ok {
+		return "", abb.Stopping{}, false
+	}
+	for _, key := range abb.StoppingRobots() {
+		if candidate, _ := abb.RobotModel(key); candidate.Model == r.Model {
+			data, _ := abb.StoppingData(key)
+			return key, data, true
+		}
+	}
+	return "", abb.Stopping{}, false
+}
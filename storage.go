@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/project"
+	"github.com/polyfant/automation-helper-cli/secrets"
+	"github.com/polyfant/automation-helper-cli/storage"
+)
+
+const storageUsage = `Usage:
+  storage                     Show where the active project keeps its data
+  storage push                Upload the snapshots, backups and job logs kept
+                              in the project directory to the shared store
+  storage list [prefix]       List the stored keys, e.g. storage list inventory/cell3
+  storage pull <prefix> [--output <dir>]
+                              Download stored keys, to the project directory
+                              unless --output
+
+The controller snapshots of abb rws info, the backups of abb rws backup and
+the job logs are kept in the project directory unless the storage setting
+names a shared store:
+  config set storage /mnt/plant-share/robots
+  config set storage "s3://bucket/prefix?endpoint=http://minio:9000&region=eu-north-1"
+  config set storage sqlite:///mnt/plant-share/robots.db
+In a shared store the keys of each project are kept under its name, so the
+laptops of several engineers can write to one place. S3 and MinIO need the
+s3_access_key and s3_secret_key secrets (or AWS_ACCESS_KEY_ID and
+AWS_SECRET_ACCESS_KEY). The jobs daemon uploads its logs after every run;
+storage push uploads what was collected before the store was set up or
+while it was out of reach.
+
+Examples:
+  storage
+  storage push
+  storage list backups/cell3
+  storage pull inventory/cell3 --output ./cell3-history`
+
+// pushedFile records in the project directory which files storage push has
+// uploaded, per store location
+const pushedFile = ".automation-helper-pushed.json"
+
+// storedDirs are the project directories kept in the store
+var storedDirs = []string{inventoryDir, backupsDir, jobsDir}
+
+func init() {
+	commandRegistry["storage"] = Command{
+		Group:       groupIntegration,
+		Description: "Keep snapshots, backups and logs on the local disk, S3/MinIO or SQLite",
+		Usage:       storageUsage,
+		Execute:     text(runStorage),
+	}
+}
+
+func runStorage(args []string) (string, error) {
+	p := activeProject()
+	if p == nil {
+		return "", errors.New("storage works on the active project; select one with 'use <projectdir>'")
+	}
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+	store, err := projectStore(p)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return showStorage(store, s.Storage)
+	}
+	switch args[0] {
+	case "push":
+		if len(args) != 1 {
+			return storageUsage, nil
+		}
+		if s.Storage == "" {
+			return "", errors.New("no shared store; the data stays in the project directory. Set one with 'config set storage <location>'.")
+		}
+		pushed, err := pushProject(p, store, s.Storage, storedDirs)
+		if err != nil {
+			return "", err
+		}
+		if len(pushed) == 0 {
+			return "Nothing to upload; " + store.String() + " is up to date", nil
+		}
+		verb := "Uploaded"
+		if dryRun() {
+			verb = "Would upload"
+		}
+		return fmt.Sprintf("%s %d files to %s:\n  %s", verb, len(pushed), store, strings.Join(pushed, "\n  ")), nil
+	case "list":
+		if len(args) > 2 {
+			return storageUsage, nil
+		}
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+		keys, err := store.List(prefix)
+		if err != nil {
+			return "", err
+		}
+		if len(keys) == 0 {
+			return "No stored keys under " + orDefault(prefix, store.String()), nil
+		}
+		return strings.Join(keys, "\n"), nil
+	case "pull":
+		if len(args) < 2 || strings.HasPrefix(args[1], "--") {
+			return storageUsage, nil
+		}
+		output := p.Dir
+		if len(args) == 4 && args[2] == "--output" {
+			output = args[3]
+		} else if len(args) != 2 {
+			return storageUsage, nil
+		}
+		return pullStorage(store, args[1], output)
+	default:
+		return storageUsage, nil
+	}
+}
+
+func showStorage(store storage.Store, location string) (string, error) {
+	var b strings.Builder
+	if location == "" {
+		fmt.Fprintf(&b, "Storage: the project directory %s\n", store)
+	} else {
+		fmt.Fprintf(&b, "Storage: %s\n", store)
+	}
+	for _, dir := range storedDirs {
+		keys, err := store.List(dir + "/")
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "  %-10s %d files\n", dir+":", len(keys))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func pullStorage(store storage.Store, prefix, output string) (string, error) {
+	keys, err := store.List(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "No stored keys under " + prefix, nil
+	}
+	if dryRun() {
+		return fmt.Sprintf("Would download %d files to %s", len(keys), output), nil
+	}
+	bytes := 0
+	for _, k := range keys {
+		data, err := store.Get(k)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", k, err)
+		}
+		path := filepath.Join(output, filepath.FromSlash(k))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", err
+		}
+		bytes += len(data)
+	}
+	return fmt.Sprintf("Downloaded %d files (%d KB) to %s", len(keys), (bytes+1023)/1024, output), nil
+}
+
+// projectStore returns the store of the active project's snapshots, backups
+// and job logs: the project directory, or its part of the shared store the
+// storage setting names. In a dry run nothing is written to it.
+func projectStore(p *project.Project) (storage.Store, error) {
+	s, err := config.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	var store storage.Store = &storage.Local{Dir: p.Dir}
+	if s.Storage != "" {
+		shared, err := openStore(s.Storage)
+		if err != nil {
+			return nil, err
+		}
+		name := orDefault(p.Name, filepath.Base(p.Dir))
+		store = storage.Prefixed{Store: shared, Prefix: unsafeNameRe.ReplaceAllString(name, "_")}
+	}
+	if dryRun() {
+		store = dryRunStore{store}
+	}
+	return store, nil
+}
+
+// openStore opens a storage location with the S3 credentials from the
+// secrets
+func openStore(location string) (storage.Store, error) {
+	store, err := storage.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	if s3, ok := store.(*storage.S3); ok {
+		if s3.AccessKey, err = secrets.Get("s3_access_key"); err != nil {
+			return nil, err
+		}
+		if s3.SecretKey, err = secrets.Get("s3_secret_key"); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// storageLocation checks the value of the storage setting and makes a
+// directory absolute, so it does not depend on where the CLI is started
+func storageLocation(value string) (string, error) {
+	store, err := storage.Open(value)
+	if err != nil {
+		return "", err
+	}
+	if l, ok := store.(*storage.Local); ok {
+		return filepath.Abs(l.Dir)
+	}
+	return value, nil
+}
+
+// dryRunStore reads from a store but writes nothing
+type dryRunStore struct{ storage.Store }
+
+func (dryRunStore) Put(string, []byte) error { return nil }
+
+func (dryRunStore) Delete(string) error { return nil }
+
+// pushProject uploads the files below dirs of the project directory that
+// changed since they were last pushed to location, returning their keys
+func pushProject(p *project.Project, store storage.Store, location string, dirs []string) ([]string, error) {
+	manifest := filepath.Join(p.Dir, pushedFile)
+	pushed := make(map[string]map[string]string)
+	if data, err := os.ReadFile(manifest); err == nil {
+		if err := json.Unmarshal(data, &pushed); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", manifest, err)
+		}
+	}
+	if pushed[location] == nil {
+		pushed[location] = make(map[string]string)
+	}
+	var keys []string
+	var walkErr error
+	for _, dir := range dirs {
+		walkErr = filepath.WalkDir(filepath.Join(p.Dir, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(p.Dir, path)
+			key := filepath.ToSlash(rel)
+			sum := sha256.Sum256(data)
+			hash := hex.EncodeToString(sum[:])
+			if pushed[location][key] == hash {
+				return nil
+			}
+			if err := store.Put(key, data); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			pushed[location][key] = hash
+			keys = append(keys, key)
+			return nil
+		})
+		if walkErr != nil {
+			break
+		}
+	}
+	// Record what was uploaded even when a later file failed
+	if len(keys) > 0 && !dryRun() {
+		data, err := json.MarshalIndent(pushed, "", "  ")
+		if err != nil {
+			return keys, err
+		}
+		if err := os.WriteFile(manifest, append(data, '\n'), 0o644); err != nil {
+			return keys, err
+		}
+	}
+	return keys, walkErr
+}
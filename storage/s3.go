@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 keeps the keys as objects in a bucket of Amazon S3 or a compatible
+// store such as MinIO. Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	Bucket   string
+	Prefix   string // keys are stored below it
+	Endpoint string // e.g. http://minio.plant.local:9000; AWS when empty
+	Region   string // us-east-1 when empty, as MinIO expects by default
+
+	AccessKey string
+	SecretKey string
+
+	HTTP *http.Client
+}
+
+// defaultRegion is the region of an S3 store that names none
+const defaultRegion = "us-east-1"
+
+func (s *S3) region() string {
+	if s.Region == "" {
+		return defaultRegion
+	}
+	return s.Region
+}
+
+// maxObject caps the size of an object read from the store
+const maxObject = 1 << 30
+
+// objectURL returns the URL of a key, or of the bucket for an empty key.
+// Endpoints use path-style URLs, which MinIO expects; AWS gets
+// virtual-hosted ones.
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	u := &url.URL{Scheme: "https", Host: s.Bucket + ".s3." + s.region() + ".amazonaws.com", Path: "/"}
+	if s.Endpoint != "" {
+		e, _ := url.Parse(s.Endpoint)
+		u = &url.URL{Scheme: e.Scheme, Host: e.Host, Path: "/" + s.Bucket + "/"}
+	}
+	if key != "" {
+		u.Path += key
+	}
+	u.RawQuery = canonicalQuery(query)
+	return u
+}
+
+func (s *S3) objectKey(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return path.Join(s.Prefix, key), nil
+}
+
+func (s *S3) Put(key string, data []byte) error {
+	k, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodPut, k, nil, data)
+	return err
+}
+
+func (s *S3) Get(key string) ([]byte, error) {
+	k, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(http.MethodGet, k, nil, nil)
+}
+
+func (s *S3) Delete(key string) error {
+	k, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodDelete, k, nil, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// listResult is the response of ListObjectsV2
+type listResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3) List(prefix string) ([]string, error) {
+	full := prefix
+	if s.Prefix != "" {
+		full = s.Prefix + "/" + prefix
+	}
+	var keys []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {full}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		data, err := s.do(http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var r listResult
+		if err := xml.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("unexpected list response: %v", err)
+		}
+		for _, c := range r.Contents {
+			if s.Prefix != "" {
+				c.Key = strings.TrimPrefix(c.Key, s.Prefix+"/")
+			}
+			keys = append(keys, c.Key)
+		}
+		if !r.IsTruncated || r.NextContinuationToken == "" {
+			break
+		}
+		token = r.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *S3) String() string {
+	return "s3://" + path.Join(s.Bucket, s.Prefix)
+}
+
+func (s *S3) do(method, key string, query url.Values, body []byte) ([]byte, error) {
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return nil, fmt.Errorf("no S3 credentials; store them with 'secrets set s3_access_key' and 'secrets set s3_secret_key'")
+	}
+	u := s.objectURL(key, query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body, time.Now().UTC())
+	client := s.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxObject))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var e struct{ Code, Message string }
+		if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, u.Path, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, u.Path, resp.Status)
+	}
+	return data, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to a request
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+	stamp := now.Format("20060102T150405Z")
+	day := stamp[:8]
+	req.Header.Set("x-amz-date", stamp)
+	req.Header.Set("x-amz-content-sha256", payload)
+
+	names := []string{"host"}
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		value := req.Host
+		if name != "host" {
+			value = strings.TrimSpace(req.Header.Get(name))
+		}
+		if value == "" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{req.Method, uriEncode(req.URL.Path, false), req.URL.RawQuery,
+		headers.String(), signed, payload}, "\n")
+
+	scope := day + "/" + s.region() + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	for _, part := range []string{s.region(), "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// canonicalQuery encodes a query as SigV4 requires: sorted, with every
+// character but the unreserved ones percent-encoded
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes all but the unreserved characters; slashes are
+// kept in paths
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SQLite keeps the keys as rows of a database file, which can sit on a
+// network share
+type SQLite struct {
+	Path string
+
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS files (
+	key      TEXT PRIMARY KEY,
+	data     BLOB NOT NULL,
+	modified TEXT NOT NULL
+)`
+
+// open opens the database on first use and creates the table
+func (s *SQLite) open() (*sql.DB, error) {
+	s.once.Do(func() {
+		if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+			s.err = err
+			return
+		}
+		s.db, s.err = sql.Open("sqlite", s.Path+"?_pragma=busy_timeout(5000)")
+		if s.err == nil {
+			_, s.err = s.db.Exec(sqliteSchema)
+		}
+	})
+	return s.db, s.err
+}
+
+func (s *SQLite) Put(key string, data []byte) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO files (key, data, modified) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data, modified = excluded.modified`,
+		key, data, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *SQLite) Get(key string) ([]byte, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	err = db.QueryRow(`SELECT data FROM files WHERE key = ?`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *SQLite) List(prefix string) ([]string, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	// substr instead of LIKE, which would treat _ and % in the prefix as
+	// wildcards
+	rows, err := db.Query(`SELECT key FROM files WHERE substr(key, 1, ?) = ? ORDER BY key`, len(prefix), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLite) Delete(key string) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM files WHERE key = ?`, key)
+	return err
+}
+
+func (s *SQLite) String() string { return "sqlite://" + s.Path }
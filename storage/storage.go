@@ -0,0 +1,115 @@
+// Package storage keeps the files the tool collects, such as controller
+// snapshots, backups and logs, under slash-separated keys like
+// inventory/cell3/20261016-020000.json. The local disk is the default;
+// S3-compatible object stores (AWS, MinIO) and SQLite databases let plant
+// IT collect the data of every engineer's laptop in one place.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key that is not stored
+var ErrNotFound = errors.New("not found")
+
+// Store is a storage backend
+type Store interface {
+	// Put stores data under key, replacing what was there
+	Put(key string, data []byte) error
+	// Get returns the data under key, or ErrNotFound
+	Get(key string) ([]byte, error)
+	// List returns the keys starting with prefix, sorted
+	List(prefix string) ([]string, error)
+	// Delete removes a key; a missing key is not an error
+	Delete(key string) error
+	// String describes the store for messages
+	String() string
+}
+
+// Open returns the store for a location: a directory, file:///dir,
+// s3://bucket/prefix?endpoint=http://minio:9000&region=eu-north-1 or
+// sqlite:///path/data.db. S3 credentials are left for the caller to fill in.
+func Open(location string) (Store, error) {
+	scheme, rest, ok := strings.Cut(location, "://")
+	if !ok {
+		return &Local{Dir: location}, nil
+	}
+	switch scheme {
+	case "file":
+		return &Local{Dir: rest}, nil
+	case "sqlite":
+		if rest == "" {
+			return nil, fmt.Errorf("sqlite storage needs a database path, e.g. sqlite:///data/ahc.db")
+		}
+		return &SQLite{Path: rest}, nil
+	case "s3":
+		u, err := url.Parse(location)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid S3 location %q; use s3://bucket/prefix", location)
+		}
+		q := u.Query()
+		return &S3{
+			Bucket:   u.Host,
+			Prefix:   strings.Trim(u.Path, "/"),
+			Endpoint: strings.TrimRight(q.Get("endpoint"), "/"),
+			Region:   q.Get("region"),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage %q; use a directory, s3://bucket/prefix or sqlite:///path", location)
+}
+
+// Prefixed keeps the keys of a store under a prefix, such as the project
+// name in a store shared by several projects
+type Prefixed struct {
+	Store
+	Prefix string
+}
+
+func (p Prefixed) key(k string) string { return path.Join(p.Prefix, k) }
+
+func (p Prefixed) Put(key string, data []byte) error { return p.Store.Put(p.key(key), data) }
+
+func (p Prefixed) Get(key string) ([]byte, error) { return p.Store.Get(p.key(key)) }
+
+func (p Prefixed) Delete(key string) error { return p.Store.Delete(p.key(key)) }
+
+func (p Prefixed) List(prefix string) ([]string, error) {
+	full := p.Prefix + "/" + prefix
+	keys, err := p.Store.List(full)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, p.Prefix+"/")
+	}
+	return keys, nil
+}
+
+func (p Prefixed) String() string { return p.Store.String() + " under " + p.Prefix }
+
+// Location describes where a key is stored: a file path on the local disk,
+// an s3:// URL or the key in a database
+func Location(s Store, key string) string {
+	switch v := s.(type) {
+	case *Local:
+		return filepath.Join(v.Dir, filepath.FromSlash(key))
+	case *S3:
+		return "s3://" + path.Join(v.Bucket, v.Prefix, key)
+	case Prefixed:
+		return Location(v.Store, v.key(key))
+	}
+	return key + " in " + s.String()
+}
+
+// validKey rejects keys that would leave the store's root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/i18n"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const styleUsage = `Usage:
+  style                      List the style profiles and the one in use
+  style show <profile>       Show the settings of a profile
+  style set <profile> [--indent <spaces|tab>] [--keywords upper|lower]
+            [--comments <language>] [--signals prefix|underscore|upper|suffix]
+  style delete <profile>     Delete a custom profile
+
+A style profile sets the indentation, keyword case, comment language and
+signal naming of the code the generators write, and the layout abb format
+gives existing modules. Select the profile of a project with
+'use <projectdir> --style <profile>'; without one the abb profile applies.
+Custom profiles are saved in ` + stylesFile + ` in the data directory and start from
+the abb profile.
+
+Comments are translated where the generators have translations, and signal
+schemes rename the generated signals in RAPID, PLC code and I/O
+configuration alike.
+
+Examples:
+  style set customer-x --indent 2 --keywords upper --signals upper --comments de
+  use ./Cell4 --style customer-x
+  style show customer-x`
+
+// stylesFile holds the custom style profiles in the data directory
+const stylesFile = "styles.json"
+
+// defaultStyle is the profile used without a project style
+const defaultStyle = "abb"
+
+// builtinStyles are the style profiles every installation has
+var builtinStyles = map[string]rapid.Style{
+	"abb":     rapid.DefaultStyle,
+	"compact": {Indent: 2, Keywords: "upper", Comments: "en", Signals: "prefix"},
+	"tabs":    {Indent: 0, Keywords: "upper", Comments: "en", Signals: "prefix"},
+}
+
+func init() {
+	commandRegistry["style"] = Command{
+		Group:       groupCode,
+		Description: "Code style profiles for generators and abb format",
+		Usage:       styleUsage,
+		Execute:     text(runStyle),
+	}
+}
+
+func runStyle(args []string) (string, error) {
+	if len(args) == 0 {
+		return listStyles()
+	}
+	switch args[0] {
+	case "show":
+		if len(args) != 2 {
+			return styleUsage, nil
+		}
+		s, err := styleProfile(args[1])
+		if err != nil {
+			return "", err
+		}
+		return describeStyle(args[1], s), nil
+	case "set":
+		if len(args) < 2 {
+			return styleUsage, nil
+		}
+		return setStyle(args[1], args[2:])
+	case "delete":
+		if len(args) != 2 {
+			return styleUsage, nil
+		}
+		custom, err := loadStyles()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := custom[args[1]]; !ok {
+			return "", fmt.Errorf("no custom style profile %q", args[1])
+		}
+		delete(custom, args[1])
+		if err := saveStyles(custom); err != nil {
+			return "", err
+		}
+		return "Deleted style profile " + args[1], nil
+	default:
+		return styleUsage, nil
+	}
+}
+
+func listStyles() (string, error) {
+	custom, err := loadStyles()
+	if err != nil {
+		return "", err
+	}
+	active, _ := activeStyle()
+	names := make([]string, 0, len(builtinStyles)+len(custom))
+	for name := range builtinStyles {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := builtinStyles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Style profiles:\n")
+	for _, name := range names {
+		s, _ := styleProfile(name)
+		marker := " "
+		if name == active {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, " %s %-12s %s\n", marker, name, styleSummary(s))
+	}
+	b.WriteString("\n* in use; select one with 'use <projectdir> --style <profile>'")
+	return b.String(), nil
+}
+
+func describeStyle(name string, s rapid.Style) string {
+	indent := strconv.Itoa(s.Indent) + " spaces"
+	if s.Indent == 0 {
+		indent = "tab"
+	}
+	return fmt.Sprintf("Style profile %s:\n  Indent:    %s\n  Keywords:  %s\n  Comments:  %s\n  Signals:   %s (%s)",
+		name, indent, s.Keywords, s.Comments, s.Signals, rapid.SignalSchemes[s.Signals])
+}
+
+func styleSummary(s rapid.Style) string {
+	indent := fmt.Sprintf("indent %d", s.Indent)
+	if s.Indent == 0 {
+		indent = "tabs"
+	}
+	return fmt.Sprintf("%s, %s keywords, %s comments, signals like %s", indent, s.Keywords, s.Comments, rapid.SignalSchemes[s.Signals])
+}
+
+// setStyle creates or changes a custom profile
+func setStyle(name string, args []string) (string, error) {
+	if _, ok := builtinStyles[name]; ok {
+		return "", fmt.Errorf("%s is a built-in profile; create a custom one with another name", name)
+	}
+	if !identifierRe.MatchString(strings.ReplaceAll(name, "-", "_")) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	custom, err := loadStyles()
+	if err != nil {
+		return "", err
+	}
+	s, ok := custom[name]
+	if !ok {
+		s = rapid.DefaultStyle
+	}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return styleUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--indent":
+			if value == "tab" {
+				s.Indent = 0
+			} else if s.Indent, err = strconv.Atoi(value); err != nil || s.Indent == 0 {
+				return "", fmt.Errorf("invalid value %q for --indent", value)
+			}
+		case "--keywords":
+			s.Keywords = strings.ToLower(value)
+		case "--comments":
+			s.Comments = i18n.Normalize(value)
+			if !containsFold(i18n.Languages, s.Comments) {
+				return "", fmt.Errorf("unsupported comment language %q (available: %s)", value, strings.Join(i18n.Languages, ", "))
+			}
+		case "--signals":
+			s.Signals = strings.ToLower(value)
+		default:
+			return styleUsage, nil
+		}
+		i++
+	}
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+	custom[name] = s
+	if err := saveStyles(custom); err != nil {
+		return "", err
+	}
+	return describeStyle(name, s), nil
+}
+
+// styleProfile returns a built-in or custom profile
+func styleProfile(name string) (rapid.Style, error) {
+	if s, ok := builtinStyles[name]; ok {
+		return s, nil
+	}
+	custom, err := loadStyles()
+	if err != nil {
+		return rapid.Style{}, err
+	}
+	if s, ok := custom[name]; ok {
+		return s, nil
+	}
+	return rapid.Style{}, fmt.Errorf("unknown style profile %q; 'style' lists them", name)
+}
+
+// activeStyle returns the profile of the active project, or the default. An
+// unknown profile is reported once as a warning.
+func activeStyle() (string, rapid.Style) {
+	name := defaultStyle
+	if p := activeProject(); p != nil && p.Style != "" {
+		name = p.Style
+	}
+	s, err := styleProfile(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return defaultStyle, rapid.DefaultStyle
+	}
+	return name, s
+}
+
+func loadStyles() (map[string]rapid.Style, error) {
+	path, err := config.Path(stylesFile)
+	if err != nil {
+		return nil, err
+	}
+	styles := map[string]rapid.Style{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return styles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &styles); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return styles, nil
+}
+
+func saveStyles(styles map[string]rapid.Style) error {
+	path, err := config.Path(stylesFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(styles, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'), 0o644)
+}
+
+// generating runs a generator under the code style of the active project:
+// its comments are written in the style's language, and the RAPID modules
+// in its output take the style's layout and signal names. Files it writes
+// are styled and stamped by writeFile.
+func generating(template string, args []string, run func() (string, error)) (string, error) {
+	prev := generation
+	_, style := activeStyle()
+	generation = &provenance{Template: template, Args: args, Style: style}
+	defer func() { generation = prev }()
+
+	if style.Comments != "" && style.Comments != i18n.Language() {
+		lang := i18n.Language()
+		if err := i18n.SetLanguage(style.Comments); err == nil {
+			defer i18n.SetLanguage(lang)
+		}
+	}
+	out, err := run()
+	if err != nil {
+		return out, err
+	}
+	return styleText(style, out), nil
+}
+
+// styleText formats the RAPID modules in text and renames its signals; the
+// text around them is left alone
+func styleText(style rapid.Style, text string) string {
+	text = style.RenameSignals(text)
+	if style == rapid.DefaultStyle {
+		return text
+	}
+	var b strings.Builder
+	for {
+		loc := moduleLineRe.FindStringIndex(text)
+		if loc == nil {
+			break
+		}
+		end := endModuleRe.FindStringIndex(text[loc[0]:])
+		if end == nil {
+			break
+		}
+		stop := loc[0] + end[1]
+		b.WriteString(text[:loc[0]])
+		b.WriteString(strings.TrimSuffix(style.Format(text[loc[0]:stop]), "\n"))
+		text = text[stop:]
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// styleGenerated applies the generator's style to a file it writes: layout
+// and signal names to RAPID, signal names to PLC code and I/O configuration
+func styleGenerated(path string, data []byte) []byte {
+	if generation == nil {
+		return data
+	}
+	style := generation.Style
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case rapid.IsSourceFile(path) && style != rapid.DefaultStyle:
+		return []byte(style.Format(style.RenameSignals(string(data))))
+	case rapid.IsSourceFile(path):
+		return data
+	case ext == ".st" || ext == ".scl" || ext == ".cfg":
+		return []byte(style.RenameSignals(string(data)))
+	}
+	return data
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const sweepUsage = `Usage: generate sweep --param name=5..15step2 [--param name=8,10,12]...
+       [--routine ProcessTrial] [--grid] [--repeat 1] [--pause] [--module Sweep] [--output dir]
+Generates a RAPID module that runs a process routine once per trial of a
+parameter sweep, for process development. The parameters become PERS num
+variables the routine reads; every trial is labeled on the FlexPendant
+(stTrialLabel, e.g. "T3/6 weldSpeed=9", also usable to mark the part) and
+logged to HOME:/<module>.csv with date and time.
+
+By default one parameter is varied at a time while the others keep their
+first value; --grid runs every combination. --repeat runs each trial
+several times, --pause asks the operator to continue, repeat or stop after
+every trial. An interrupted series resumes at nStartTrial.
+
+Examples:
+  generate sweep --param weldSpeed=5..15step2 --routine WeldTrial
+  generate sweep --param weldSpeed=8,10 --param wireFeed=6..9 --grid --pause`
+
+// generateSweep writes the sweep module
+func generateSweep(args []string) (string, error) {
+	opts := generate.SweepOptions{Module: "Sweep", Routine: "ProcessTrial", Repeat: 1}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--grid":
+			opts.Grid = true
+			continue
+		case "--pause":
+			opts.Pause = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return sweepUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--param":
+			p, err := generate.ParseSweepParam(value)
+			if err != nil {
+				return "", err
+			}
+			opts.Params = append(opts.Params, p)
+		case "--routine":
+			opts.Routine = value
+		case "--repeat":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return "", fmt.Errorf("invalid value %q for --repeat", value)
+			}
+			opts.Repeat = n
+		case "--module":
+			opts.Module = value
+		case "--output":
+			output = value
+		default:
+			return sweepUsage, nil
+		}
+		i++
+	}
+	if len(opts.Params) == 0 {
+		return sweepUsage, nil
+	}
+	for _, name := range []string{opts.Module, opts.Routine} {
+		if !identifierRe.MatchString(name) {
+			return "", fmt.Errorf("%q is not a valid RAPID name", name)
+		}
+	}
+
+	module, err := generate.SweepCode(opts)
+	if err != nil {
+		return "", err
+	}
+	summary := sweepSummary(opts)
+	if output == "" {
+		return summary + "\n--- " + opts.Module + ".mod ---\n" + strings.TrimRight(module, "\n"), nil
+	}
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	path := filepath.Join(output, opts.Module+".mod")
+	if err := writeFile(path, []byte(module), 0644); err != nil {
+		return "", err
+	}
+	return summary + "\nWrote " + path, nil
+}
+
+// sweepSummary lists the trials as a table
+func sweepSummary(opts generate.SweepOptions) string {
+	trials := generate.SweepTrials(opts)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d trials, run %s from %s (call RunSweep)\n\n", len(trials), opts.Routine, opts.Module)
+	b.WriteString("  Trial")
+	for _, p := range opts.Params {
+		fmt.Fprintf(&b, "  %10s", p.Name)
+	}
+	b.WriteString("\n")
+	const shown = 20
+	for i, t := range trials {
+		if i == shown && len(trials) > shown+1 {
+			fmt.Fprintf(&b, "  ... %d more\n", len(trials)-shown)
+			break
+		}
+		fmt.Fprintf(&b, "  %5d", i+1)
+		for _, v := range t {
+			fmt.Fprintf(&b, "  %10s", strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const testsUsage = `Usage: generate tests <file.mod> [--max-cases 12] [--output dir]
+Generates a test module that calls every PROC and FUNC of a library module
+with boundary values (0, 1, -1, empty and 80-character strings, TRUE and
+FALSE), one parameter at a time. Each case must complete without error;
+results, INOUT arguments and the outputs the routine sets are reported as
+TODO until you fill in the expected values. Digital inputs the module reads
+are stubbed with outputs cross-connected on the virtual controller and
+reached through AliasIO. Results go to the FlexPendant and
+HOME:/TestResult_<module>.txt.
+
+LOCAL routines, TRAPs, main and routines with parameters other than num,
+dnum, bool, string or byte are skipped. --output writes Test<module>.mod and
+EIO_stubs.cfg.
+
+Example:
+  generate tests GripperLib.mod --output ./tests`
+
+var movesRe = regexp.MustCompile(`(?im)^(Move|Search|Trigg)\w*\s`)
+
+// generateTests writes a test module for the routines of a RAPID module
+func generateTests(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return testsUsage, nil
+	}
+	path := args[0]
+	opts := generate.TestOptions{}
+	output := ""
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return testsUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--max-cases":
+			opts.MaxCases, err = strconv.Atoi(value)
+		case "--output":
+			output = value
+		default:
+			return testsUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := string(data)
+	opts.Module = rapid.AnalyzeModule(src).Name
+	if opts.Module == "" {
+		return "", fmt.Errorf("%s has no MODULE declaration", path)
+	}
+	signals := make(map[string]rapid.IOSignal)
+	for _, s := range rapid.IOSignals(src) {
+		signals[strings.ToUpper(s.Name)] = s
+		if s.Input() {
+			opts.Inputs = append(opts.Inputs, generate.TestSignal{Name: s.Name, Type: s.Type})
+		}
+	}
+
+	var skipped []string
+	for _, r := range rapid.Routines(src) {
+		sig := rapid.RoutineSignature(r)
+		switch {
+		case r.Kind == "TRAP":
+			continue
+		case sig.Local:
+			skipped = append(skipped, r.Name+": LOCAL routines cannot be called from another module")
+			continue
+		}
+		tr := generate.TestRoutine{Name: r.Name, Returns: sig.Returns, Moves: movesRe.MatchString(r.Body)}
+		for _, p := range sig.Params {
+			tr.Params = append(tr.Params, generate.TestParam{Mode: p.Mode, Type: p.Type, Name: p.Name, Array: p.Array})
+		}
+		for _, used := range rapid.IOSignals(r.Body) {
+			s, ok := signals[strings.ToUpper(used.Name)]
+			switch {
+			case !ok:
+				// A signal parameter of the routine
+			case s.Type == "signaldi":
+				tr.Inputs = append(tr.Inputs, s.Name)
+			case !s.Input():
+				tr.Outputs = append(tr.Outputs, generate.TestSignal{Name: s.Name, Type: s.Type})
+			}
+		}
+		opts.Routines = append(opts.Routines, tr)
+	}
+
+	suite, err := generate.TestCode(opts)
+	if err != nil {
+		return "", err
+	}
+	skipped = append(skipped, suite.Skipped...)
+	var notes strings.Builder
+	if len(skipped) > 0 {
+		notes.WriteString("Not covered:\n")
+		for _, s := range skipped {
+			notes.WriteString("  " + s + "\n")
+		}
+	}
+
+	if output == "" {
+		result := notes.String() + "--- " + suite.Name + ".mod ---\n" + suite.Rapid
+		if suite.EIO != "" {
+			result += "--- EIO_stubs.cfg ---\n" + suite.EIO
+		}
+		return strings.TrimRight(result, "\n"), nil
+	}
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	files := []struct{ name, content string }{{suite.Name + ".mod", suite.Rapid}}
+	if suite.EIO != "" {
+		files = append(files, struct{ name, content string }{"EIO_stubs.cfg", suite.EIO})
+	}
+	var written []string
+	for _, f := range files {
+		target := filepath.Join(output, f.name)
+		if err := writeFile(target, []byte(f.content), 0644); err != nil {
+			return "", err
+		}
+		written = append(written, target)
+	}
+	return notes.String() + "Wrote " + strings.Join(written, ", "), nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rws"
+	"github.com/polyfant/automation-helper-cli/timesync"
+)
+
+func init() {
+	commandRegistry["timecheck"] = Command{
+		Group:       groupIntegration,
+		Description: "Compare device clocks and show NTP configuration steps",
+		Usage:       timecheckUsage,
+		Execute:     text(runTimecheck),
+	}
+}
+
+const timecheckUsage = `Usage:
+  timecheck <host> [host...] [--via auto|ntp|rws|http] [--tolerance 1s]
+            [--timeout 2s] [--user name --password pw]
+  timecheck config <ntp server> [device...]
+
+Compares the clock of each device with this computer. auto tries an NTP
+query (devices running an NTP server, most PLCs and switches), then the
+controller clock over Robot Web Services, then the Date header of the
+device's web server. RWS and HTTP report whole seconds, so offsets below a
+second are not meaningful for them. Controller time is read in the time
+zone of this computer.
+
+config prints how to point controllers, PLCs and PCs at an NTP server.
+Devices: %s
+
+Examples:
+  timecheck 192.168.10.10 192.168.10.30 192.168.10.60
+  timecheck 192.168.10.30 --via rws --tolerance 500ms
+  timecheck config 192.168.10.1 irc5 s7 windows`
+
+func runTimecheck(args []string) (string, error) {
+	usage := fmt.Sprintf(timecheckUsage, strings.Join(timesync.GuideNames(), ", "))
+	if len(args) < 1 {
+		return usage, nil
+	}
+	if args[0] == "config" {
+		return timecheckConfig(args[1:], usage)
+	}
+
+	via := "auto"
+	tolerance := time.Second
+	timeout := 2 * time.Second
+	var user, password string
+	var hosts []string
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			hosts = append(hosts, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return usage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--via":
+			if value != "auto" && value != "ntp" && value != "rws" && value != "http" {
+				return "", fmt.Errorf("invalid value %q for --via", value)
+			}
+			via = value
+		case "--tolerance", "--timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+			}
+			if args[i] == "--tolerance" {
+				tolerance = d
+			} else {
+				timeout = d
+			}
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		default:
+			return usage, nil
+		}
+		i++
+	}
+	if len(hosts) == 0 {
+		return usage, nil
+	}
+
+	type result struct {
+		host, method string
+		reading      timesync.Reading
+		err          error
+	}
+	results := make([]result, len(hosts))
+	done := make(chan struct{})
+	for i, host := range hosts {
+		go func(i int, host string) {
+			r := result{host: host}
+			r.method, r.reading, r.err = readClock(host, via, timeout, user, password)
+			results[i] = r
+			done <- struct{}{}
+		}(i, host)
+	}
+	for range hosts {
+		<-done
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Local clock: %s\n\n", time.Now().Format("2006-01-02 15:04:05.000 MST"))
+	fmt.Fprintf(&b, "%-22s %-6s %-23s %10s %8s\n", "Device", "Via", "Device clock", "Offset", "RTT")
+	var offsets []time.Duration
+	var warnings []string
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(&b, "%-22s %-6s %s\n", r.host, r.method, "Error: "+r.err.Error())
+			continue
+		}
+		format := "2006-01-02 15:04:05"
+		if r.reading.Resolution < time.Second {
+			format += ".000"
+		}
+		fmt.Fprintf(&b, "%-22s %-6s %-23s %10s %8s\n", r.host, r.method, r.reading.Clock.In(time.Local).Format(format),
+			formatOffset(r.reading.Offset), r.reading.RTT.Round(time.Millisecond))
+		offsets = append(offsets, r.reading.Offset)
+		if abs := r.reading.Offset.Abs(); abs > tolerance+r.reading.Resolution/2 {
+			warnings = append(warnings, fmt.Sprintf("%s is %s off", r.host, formatOffset(r.reading.Offset)))
+		}
+	}
+	if len(offsets) > 1 {
+		sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+		fmt.Fprintf(&b, "\nSpread between devices: %s\n", (offsets[len(offsets)-1] - offsets[0]).Round(time.Millisecond))
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(&b, "\nWarning: clocks differ by more than %s:\n", tolerance)
+		for _, w := range warnings {
+			fmt.Fprintf(&b, "  %s\n", w)
+		}
+		b.WriteString("Point all devices at one NTP server; run 'timecheck config <server>' for the steps.")
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// readClock reads the clock of a device with the given method, or the first
+// that answers
+func readClock(host, via string, timeout time.Duration, user, password string) (string, timesync.Reading, error) {
+	methods := []string{via}
+	if via == "auto" {
+		methods = []string{"ntp", "rws", "http"}
+	}
+	var err error
+	for _, m := range methods {
+		var r timesync.Reading
+		switch m {
+		case "ntp":
+			r, err = timesync.QuerySNTP(host, timeout)
+		case "rws":
+			c := rws.NewClient(host)
+			c.User, c.Password = rwsCredentials(host, user, password)
+			c.HTTP.Timeout = timeout
+			start := time.Now()
+			var clock time.Time
+			if clock, err = c.Clock(); err == nil {
+				r = timesync.Compare(clock, start, time.Now())
+			}
+		case "http":
+			r, err = httpDate(host, timeout)
+		}
+		if err == nil {
+			return m, r, nil
+		}
+	}
+	if via == "auto" {
+		return "-", timesync.Reading{}, fmt.Errorf("no NTP, RWS or web server answered (last: %v)", err)
+	}
+	return via, timesync.Reading{}, err
+}
+
+// httpDate reads the Date header of the device's web server
+func httpDate(host string, timeout time.Duration) (timesync.Reading, error) {
+	url := host
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	client := &http.Client{Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Head(url)
+	end := time.Now()
+	if err != nil {
+		return timesync.Reading{}, err
+	}
+	resp.Body.Close()
+	clock, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return timesync.Reading{}, fmt.Errorf("web server sends no Date header")
+	}
+	return timesync.Compare(clock, start, end), nil
+}
+
+func formatOffset(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+func timecheckConfig(args []string, usage string) (string, error) {
+	if len(args) < 1 {
+		return usage, nil
+	}
+	server := args[0]
+	devices := args[1:]
+	if len(devices) == 0 {
+		devices = timesync.GuideNames()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "NTP server: %s (UDP 123 must be open from every device)\n", server)
+	for _, d := range devices {
+		g, ok := timesync.FindGuide(d)
+		if !ok {
+			return "", fmt.Errorf("no guide for %q. Available: %s", d, strings.Join(timesync.GuideNames(), ", "))
+		}
+		b.WriteString("\n" + g.Text(server))
+	}
+	b.WriteString("\nUse one time zone setting per site, and prefer UTC in logs exchanged between devices.")
+	return b.String(), nil
+}
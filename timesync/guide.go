@@ -0,0 +1,107 @@
+package timesync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Guide explains how to make one kind of device use an NTP server. %s in
+// the steps is replaced with the server address.
+type Guide struct {
+	Device string
+	Title  string
+	Steps  []string
+	Note   string
+}
+
+var guides = map[string]Guide{
+	"irc5": {
+		Title: "ABB IRC5 (RobotWare 6)",
+		Steps: []string{
+			"RobotStudio: Controller tab > Properties > Date and Time, or FlexPendant: Control Panel > Date and Time",
+			"Select Network time and enter the time server %s",
+			"Set the time zone of the plant and apply",
+			"The server must be reachable from the WAN port (UDP 123)",
+		},
+		Note: "The event log uses controller time; without NTP it drifts several seconds per month.",
+	},
+	"omnicore": {
+		Title: "ABB OmniCore (RobotWare 7)",
+		Steps: []string{
+			"FlexPendant: Settings > Date & Time, or RobotStudio: Controller tab > Properties > Date and Time",
+			"Enable NTP and add the server %s",
+			"Select the time zone of the plant and apply",
+		},
+	},
+	"s7": {
+		Title: "Siemens S7-1200/1500 (TIA Portal)",
+		Steps: []string{
+			"Device configuration > PROFINET interface > Time synchronization",
+			"Enable time synchronization via NTP server and enter %s",
+			"Set the update interval (e.g. 10 s), download the hardware configuration",
+			"Set the local time zone under General > Time of day",
+		},
+		Note: "S7 diagnostics buffers use UTC; HMI alarms show local time.",
+	},
+	"rockwell": {
+		Title: "Rockwell ControlLogix/CompactLogix",
+		Steps: []string{
+			"Controller Properties > Date/Time: enable Time Synchronize (CIP Sync, IEEE 1588) when the network has a grandmaster clock",
+			"Without a grandmaster, write WallClockTime (SSV) from a source synchronized to %s, e.g. the HMI",
+		},
+	},
+	"cognex": {
+		Title: "Cognex In-Sight",
+		Steps: []string{
+			"In-Sight Explorer: Sensor > Network Settings > Enable SNTP",
+			"Enter the server %s and the time zone",
+		},
+		Note: "Image and result timestamps then match the robot event log.",
+	},
+	"windows": {
+		Title: "Windows PC or panel",
+		Steps: []string{
+			`w32tm /config /manualpeerlist:"%s" /syncfromflags:manual /reliable:no /update`,
+			"w32tm /resync",
+			"w32tm /query /status",
+		},
+	},
+	"linux": {
+		Title: "Linux PC or gateway (chrony)",
+		Steps: []string{
+			"Add to /etc/chrony/chrony.conf: server %s iburst",
+			"systemctl restart chrony && chronyc tracking",
+		},
+	},
+}
+
+// GuideNames returns the devices with configuration guides
+func GuideNames() []string {
+	names := make([]string, 0, len(guides))
+	for name := range guides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FindGuide returns the guide for a device kind
+func FindGuide(device string) (Guide, bool) {
+	g, ok := guides[strings.ToLower(device)]
+	g.Device = strings.ToLower(device)
+	return g, ok
+}
+
+// Text formats the guide for the NTP server
+func (g Guide) Text(server string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", g.Title)
+	for i, step := range g.Steps {
+		fmt.Fprintf(&b, "  %d. %s\n", i+1, strings.ReplaceAll(step, "%s", server))
+	}
+	if g.Note != "" {
+		fmt.Fprintf(&b, "  Note: %s\n", g.Note)
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+// Package timesync compares the clocks of cell devices and explains how to
+// point them at a common NTP server
+package timesync
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpoch is the NTP era 0 start, 1900-01-01
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Reading is one device clock compared with the clock of this computer
+type Reading struct {
+	Clock      time.Time
+	Offset     time.Duration // Device clock minus local clock
+	RTT        time.Duration
+	Resolution time.Duration // Granularity of the time the device reports
+}
+
+// QuerySNTP asks an NTP server (addr is host or host:port) for its time,
+// as in RFC 4330
+func QuerySNTP(addr string, timeout time.Duration) (Reading, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "123")
+	}
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return Reading{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	t1 := time.Now()
+	putTimestamp(req[40:], t1)
+	if _, err := conn.Write(req); err != nil {
+		return Reading{}, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	if err != nil {
+		return Reading{}, fmt.Errorf("no NTP reply: %v", err)
+	}
+	if n < 48 || resp[0]&0x07 != 4 {
+		return Reading{}, fmt.Errorf("invalid NTP reply")
+	}
+	if resp[1] == 0 {
+		return Reading{}, fmt.Errorf("NTP server refused the request (kiss code %q)", resp[12:16])
+	}
+	if binary.BigEndian.Uint64(resp[24:32]) != binary.BigEndian.Uint64(req[40:48]) {
+		return Reading{}, fmt.Errorf("NTP reply does not match the request")
+	}
+	t2, t3 := timestamp(resp[32:]), timestamp(resp[40:])
+	r := Reading{
+		Clock:      t3,
+		Offset:     (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		RTT:        t4.Sub(t1) - t3.Sub(t2),
+		Resolution: time.Millisecond,
+	}
+	return r, nil
+}
+
+// Compare turns a device clock read with whole-second resolution between
+// start and end into a reading. The device time lies within the second
+// after the reported one, so its middle is taken.
+func Compare(clock, start, end time.Time) Reading {
+	rtt := end.Sub(start)
+	local := start.Add(rtt / 2)
+	return Reading{
+		Clock:      clock,
+		Offset:     clock.Add(500 * time.Millisecond).Sub(local),
+		RTT:        rtt,
+		Resolution: time.Second,
+	}
+}
+
+func timestamp(b []byte) time.Time {
+	sec := binary.BigEndian.Uint32(b[0:4])
+	frac := binary.BigEndian.Uint32(b[4:8])
+	nsec := (int64(frac) * 1e9) >> 32
+	return ntpEpoch.Add(time.Duration(sec)*time.Second + time.Duration(nsec))
+}
+
+func putTimestamp(b []byte, t time.Time) {
+	d := t.Sub(ntpEpoch)
+	sec := uint32(d / time.Second)
+	frac := uint32((int64(d%time.Second) << 32) / 1e9)
+	binary.BigEndian.PutUint32(b[0:4], sec)
+	binary.BigEndian.PutUint32(b[4:8], frac)
+}
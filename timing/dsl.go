@@ -0,0 +1,150 @@
+package timing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parse reads the timing description language:
+//
+//	# comment
+//	title Door interlock
+//	signal door_closed PLC        (optional owner; "=1" after the name starts high)
+//	0: door_closed=1
+//	+2: robot_in_zone=1 "robot enters the cell"
+//	5: robot_in_zone=0 door_closed=0
+//
+// A step is absolute or, with a leading +, relative to the previous line.
+// Signals used in a step without a declaration are added in order of use.
+func Parse(r io.Reader) (*Diagram, error) {
+	d := &Diagram{}
+	sc := bufio.NewScanner(r)
+	lineNo, step := 0, 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, args...))
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch strings.ToLower(keyword) {
+		case "title":
+			d.Title = strings.TrimSpace(rest)
+			continue
+		case "signal":
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				return nil, fail("signal needs a name")
+			}
+			name, initial, hasInitial := strings.Cut(fields[0], "=")
+			if d.signal(name) != nil {
+				return nil, fail("signal %s is declared twice", name)
+			}
+			owner := ""
+			if len(fields) > 1 {
+				owner = fields[1]
+			}
+			s := d.AddSignal(name, owner)
+			if hasInitial {
+				high, err := parseLevel(initial)
+				if err != nil {
+					return nil, fail("%v", err)
+				}
+				s.Initial = high
+			}
+			continue
+		}
+
+		at, body, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fail("expected title, signal or <step>: <signal>=<0|1>")
+		}
+		at = strings.TrimSpace(at)
+		n, err := strconv.Atoi(strings.TrimPrefix(at, "+"))
+		if err != nil || n < 0 {
+			return nil, fail("invalid step %q", at)
+		}
+		if strings.HasPrefix(at, "+") {
+			step += n
+		} else {
+			step = n
+		}
+
+		note := ""
+		if i := strings.Index(body, `"`); i >= 0 {
+			note = strings.Trim(strings.TrimSpace(body[i:]), `"`)
+			body = body[:i]
+		}
+		changes := strings.Fields(body)
+		if len(changes) == 0 {
+			return nil, fail("step %d changes no signal", step)
+		}
+		for _, c := range changes {
+			name, value, ok := strings.Cut(c, "=")
+			if !ok {
+				return nil, fail("expected <signal>=<0|1>, got %q", c)
+			}
+			high, err := parseLevel(value)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			if d.signal(name) == nil {
+				d.AddSignal(name, "")
+			}
+			d.Set(step, name, high, note)
+			note = ""
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(d.Signals) == 0 {
+		return nil, fmt.Errorf("no signals defined")
+	}
+	return d, nil
+}
+
+func parseLevel(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "high", "true", "on":
+		return true, nil
+	case "0", "low", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid level %q (use 0 or 1)", s)
+}
+
+// Source returns the diagram in the description language, so generated
+// diagrams can be edited and re-rendered
+func (d *Diagram) Source() string {
+	var b strings.Builder
+	if d.Title != "" {
+		fmt.Fprintf(&b, "title %s\n", d.Title)
+	}
+	for _, s := range d.Signals {
+		name := s.Name
+		if s.Initial {
+			name += "=1"
+		}
+		b.WriteString(strings.TrimSpace("signal "+name+" "+s.Owner) + "\n")
+	}
+	for _, e := range d.sortedEvents() {
+		level := 0
+		if e.High {
+			level = 1
+		}
+		fmt.Fprintf(&b, "%d: %s=%d", e.Step, e.Signal, level)
+		if e.Note != "" {
+			fmt.Fprintf(&b, " %q", e.Note)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,211 @@
+// Package timing renders signal timing diagrams for interlock and handshake
+// documentation
+package timing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diagram is a set of digital signals changing at discrete steps
+type Diagram struct {
+	Title   string
+	Signals []*Signal
+	Events  []Event
+}
+
+// Signal is one trace of the diagram. Owner is the side that drives it
+// (e.g. PLC or Robot); it becomes a participant in Mermaid output.
+type Signal struct {
+	Name    string
+	Owner   string
+	Initial bool
+}
+
+// Event sets a signal at a step; Note optionally explains the transition
+type Event struct {
+	Step   int
+	Signal string
+	High   bool
+	Note   string
+}
+
+// AddSignal adds a trace and returns it
+func (d *Diagram) AddSignal(name, owner string) *Signal {
+	s := &Signal{Name: name, Owner: owner}
+	d.Signals = append(d.Signals, s)
+	return s
+}
+
+// Set records a transition
+func (d *Diagram) Set(step int, signal string, high bool, note string) {
+	d.Events = append(d.Events, Event{Step: step, Signal: signal, High: high, Note: note})
+}
+
+// signal looks up a trace by name
+func (d *Diagram) signal(name string) *Signal {
+	for _, s := range d.Signals {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Steps returns the number of steps shown, one past the last event
+func (d *Diagram) Steps() int {
+	last := 0
+	for _, e := range d.Events {
+		if e.Step > last {
+			last = e.Step
+		}
+	}
+	return last + 2
+}
+
+// levels returns the value of every signal at every step
+func (d *Diagram) levels() map[string][]bool {
+	steps := d.Steps()
+	levels := make(map[string][]bool)
+	for _, s := range d.Signals {
+		v := make([]bool, steps)
+		for i := range v {
+			v[i] = s.Initial
+		}
+		levels[s.Name] = v
+	}
+	for _, e := range d.sortedEvents() {
+		v := levels[e.Signal]
+		for i := e.Step; i < len(v); i++ {
+			v[i] = e.High
+		}
+	}
+	return levels
+}
+
+func (d *Diagram) sortedEvents() []Event {
+	events := append([]Event(nil), d.Events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Step < events[j].Step })
+	return events
+}
+
+// stepWidth is the number of characters per step in ASCII output
+const stepWidth = 4
+
+// ASCII renders the diagram with two text rows per signal. Numbered notes
+// below the traces explain the transitions.
+func (d *Diagram) ASCII() string {
+	levels := d.levels()
+	steps := d.Steps()
+	nameWidth := 0
+	for _, s := range d.Signals {
+		if len(s.Name) > nameWidth {
+			nameWidth = len(s.Name)
+		}
+	}
+
+	var b strings.Builder
+	if d.Title != "" {
+		b.WriteString(d.Title + "\n\n")
+	}
+	// Step ruler
+	var ruler strings.Builder
+	ruler.WriteString(strings.Repeat(" ", nameWidth+1))
+	for i := 0; i < steps; i++ {
+		fmt.Fprintf(&ruler, "%-*d", stepWidth, i)
+	}
+	b.WriteString(strings.TrimRight(ruler.String(), " ") + "\n")
+
+	for _, s := range d.Signals {
+		v := levels[s.Name]
+		var high, low strings.Builder
+		for i := 0; i < steps; i++ {
+			edge := i > 0 && v[i] != v[i-1]
+			for c := 0; c < stepWidth; c++ {
+				switch {
+				case c == 0 && edge:
+					high.WriteByte(' ')
+					low.WriteByte('|')
+				case v[i]:
+					high.WriteByte('_')
+					low.WriteByte(' ')
+				default:
+					high.WriteByte(' ')
+					low.WriteByte('_')
+				}
+			}
+		}
+		b.WriteString(strings.TrimRight(strings.Repeat(" ", nameWidth+1)+high.String(), " ") + "\n")
+		fmt.Fprintf(&b, "%-*s %s\n", nameWidth, s.Name, strings.TrimRight(low.String(), " "))
+	}
+
+	n := 0
+	for _, e := range d.sortedEvents() {
+		if e.Note == "" {
+			continue
+		}
+		if n == 0 {
+			b.WriteString("\n")
+		}
+		n++
+		fmt.Fprintf(&b, "%2d. step %d, %s %s: %s\n", n, e.Step, e.Signal, edgeName(e.High), e.Note)
+	}
+	return b.String()
+}
+
+// Mermaid renders the transitions as a sequence diagram between the signal
+// owners, which reads well in markdown documentation
+func (d *Diagram) Mermaid() string {
+	var owners []string
+	seen := make(map[string]bool)
+	for _, s := range d.Signals {
+		owner := s.Owner
+		if owner == "" {
+			owner = "System"
+		}
+		if !seen[owner] {
+			seen[owner] = true
+			owners = append(owners, owner)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	if d.Title != "" {
+		fmt.Fprintf(&b, "    title %s\n", d.Title)
+	}
+	for _, o := range owners {
+		fmt.Fprintf(&b, "    participant %s\n", o)
+	}
+	for _, e := range d.sortedEvents() {
+		from := "System"
+		if s := d.signal(e.Signal); s != nil && s.Owner != "" {
+			from = s.Owner
+		}
+		to := from
+		for _, o := range owners {
+			if o != from {
+				to = o
+				break
+			}
+		}
+		value := 0
+		if e.High {
+			value = 1
+		}
+		label := fmt.Sprintf("%s = %d", e.Signal, value)
+		if e.Note != "" {
+			label += " (" + e.Note + ")"
+		}
+		fmt.Fprintf(&b, "    %s->>%s: %s\n", from, to, strings.ReplaceAll(label, ";", ","))
+	}
+	return b.String()
+}
+
+func edgeName(high bool) string {
+	if high {
+		return "rises"
+	}
+	return "falls"
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/timing"
+)
+
+func init() {
+	commandRegistry["timing"] = Command{
+		Group:       groupIntegration,
+		Description: "Render timing diagrams of interlocks and handshakes",
+		Usage:       timingUsage,
+		Execute:     text(runTiming),
+	}
+}
+
+const timingUsage = `Usage: timing <file.timing> [--format ascii|mermaid|markdown]
+Renders a timing diagram from a small description:
+
+  title Door interlock
+  signal door_closed PLC
+  signal robot_in_zone Robot
+  0: door_closed=1 "operator closes the door"
+  +2: robot_in_zone=1
+  +3: robot_in_zone=0 "robot leaves"
+  +1: door_closed=0
+
+Steps are absolute or relative (+n) to the previous line. The owner of a
+signal (PLC, Robot, ...) becomes a participant in Mermaid output.
+"generate handshake --output" writes the description of its handshake.
+
+Example:
+  timing interlock.timing --format markdown > interlock.md`
+
+func runTiming(args []string) (string, error) {
+	if len(args) < 1 {
+		return timingUsage, nil
+	}
+	format := "ascii"
+	path := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" && i+1 < len(args):
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--"):
+			return timingUsage, nil
+		default:
+			path = args[i]
+		}
+	}
+	if path == "" {
+		return timingUsage, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	d, err := timing.Parse(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+
+	switch format {
+	case "ascii":
+		return strings.TrimRight(d.ASCII(), "\n"), nil
+	case "mermaid":
+		return strings.TrimRight(d.Mermaid(), "\n"), nil
+	case "markdown":
+		title := d.Title
+		if title == "" {
+			title = "Timing"
+		}
+		return fmt.Sprintf("# %s\n\n```\n%s```\n\n```mermaid\n%s```", title, d.ASCII(), d.Mermaid()), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use ascii, mermaid or markdown)", format)
+	}
+}
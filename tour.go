@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/config"
+)
+
+func init() {
+	commandRegistry["tour"] = Command{
+		Group:       groupSystem,
+		Description: "Guided tour of the main workflows with real examples",
+		Usage:       tourUsage,
+		Execute:     text(runTour),
+	}
+}
+
+const tourUsage = `Usage:
+  tour            Continue the tour with the steps not completed yet
+  tour <step>     Take one step again (lookup, generate, lint, ai)
+  tour --list     Show the steps and which are completed
+  tour --reset    Forget completed steps and start over
+
+Each step explains a workflow, runs real commands and ends with a
+checkpoint: a command to type or a question to answer. Type skip to move
+on without completing the step, or quit to stop; completed steps are
+remembered in the settings.`
+
+// tourStep is one workflow shown by the tour
+type tourStep struct {
+	Name  string
+	Title string
+	Intro string
+	// Demo are the command lines run for the user
+	Demo [][]string
+	// Try asks the user to type a command starting with Prefix; the
+	// checkpoint passes when it runs without an error or unknown topic
+	Try    string
+	Prefix string
+	// Question is answered instead of Try when set
+	Question string
+	Answers  []string
+	// Hint is shown after a wrong checkpoint answer
+	Hint string
+}
+
+// tourModule is the sample module the lint step checks. The IF on line 4
+// is never closed.
+const tourModule = `MODULE TourDemo
+    PROC main()
+        MoveJ pHome, v1000, z50, tool0;
+        IF diPartPresent = 1 THEN
+            MoveL pPick, v200, fine, tool0;
+        WaitTime 0.5;
+    ENDPROC
+ENDMODULE
+`
+
+// tourSteps returns the steps in order; module is the path of the lint
+// sample
+func tourSteps(module string) []tourStep {
+	return []tourStep{
+		{
+			Name:  "lookup",
+			Title: "Look up RAPID instructions and errors",
+			Intro: "abb command shows the syntax and an example of a RAPID instruction;\n" +
+				"abb search finds instructions, topics and error codes by keyword.",
+			Demo:   [][]string{{"abb", "command", "move_l"}, {"abb", "search", "gripper"}},
+			Try:    "Your turn: show the details of MoveJ (the key is move_j).",
+			Prefix: "abb command",
+			Hint:   "Type: abb command move_j",
+		},
+		{
+			Name:  "generate",
+			Title: "Generate code",
+			Intro: "generate writes starting code for common cell tasks: weld data,\n" +
+				"handshakes, vision, checklists and more. Review it before use.",
+			Demo:   [][]string{{"generate", "handshake", "--signals", "job_start,job_done", "--style", "level"}},
+			Try:    "Your turn: generate starting weld data for a 5 mm plate with MAG.",
+			Prefix: "generate welddata",
+			Hint:   "Type: generate welddata --plate 5mm --process MAG",
+		},
+		{
+			Name:  "lint",
+			Title: "Check RAPID code",
+			Intro: "lint finds unbalanced blocks and other problems before the code\n" +
+				"reaches the controller. Here is a sample module with a mistake:\n\n" + tourModule,
+			Demo:     [][]string{{"lint", module}},
+			Question: "Which line opens the block that is never closed?",
+			Answers:  []string{"4", "line 4"},
+			Hint:     "lint names the line where the IF was opened.",
+		},
+		{
+			Name:  "ai",
+			Title: "Ask the AI assistant",
+			Intro: "ai help sends a question about RAPID code to the AI provider (OpenAI\n" +
+				"unless ai_provider is set) and prints the answer. It needs an API key,\n" +
+				"stored with 'secrets set openai_api_key'.",
+			Demo:   [][]string{{"ai", "help", "When", "should", "I", "use", "MoveL", "instead", "of", "MoveJ?"}},
+			Try:    "Your turn: ask the assistant a question with ai help.",
+			Prefix: "ai help",
+			Hint:   "Type: ai help How do I wait for a digital input?",
+		},
+	}
+}
+
+func runTour(args []string) (string, error) {
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+	module := filepath.Join(os.TempDir(), "TourDemo.mod")
+	steps := tourSteps(module)
+
+	var selected []tourStep
+	switch {
+	case len(args) == 0:
+		for _, step := range steps {
+			if !containsFold(s.Tour, step.Name) {
+				selected = append(selected, step)
+			}
+		}
+		if len(selected) == 0 {
+			return "You have completed the tour. Type 'tour <step>' to take a step again or 'tour --reset' to start over.", nil
+		}
+	case args[0] == "--list":
+		var b strings.Builder
+		b.WriteString("Tour steps:\n")
+		for _, step := range steps {
+			mark := " "
+			if containsFold(s.Tour, step.Name) {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "  [%s] %-8s %s\n", mark, step.Name, step.Title)
+		}
+		return strings.TrimRight(b.String(), "\n"), nil
+	case args[0] == "--reset":
+		s.Tour = nil
+		if err := config.SaveSettings(s); err != nil {
+			return "", err
+		}
+		return "Tour progress cleared. Type 'tour' to start.", nil
+	case len(args) == 1:
+		for _, step := range steps {
+			if strings.EqualFold(step.Name, args[0]) {
+				selected = append(selected, step)
+			}
+		}
+		if len(selected) == 0 {
+			return "", fmt.Errorf("unknown tour step %q\n\n%s", args[0], tourUsage)
+		}
+	default:
+		return tourUsage, nil
+	}
+
+	if err := os.WriteFile(module, []byte(tourModule), 0o644); err != nil {
+		return "", err
+	}
+	defer os.Remove(module)
+
+	completed := 0
+	for i, step := range selected {
+		fmt.Printf("\n== Step %d of %d: %s ==\n%s\n", i+1, len(selected), step.Title, step.Intro)
+		done, quit := takeTourStep(step)
+		if done {
+			completed++
+			if !containsFold(s.Tour, step.Name) {
+				s.Tour = append(s.Tour, step.Name)
+			}
+			if err := config.SaveSettings(s); err != nil {
+				return "", err
+			}
+		}
+		if quit {
+			break
+		}
+	}
+	remaining := 0
+	for _, step := range steps {
+		if !containsFold(s.Tour, step.Name) {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return fmt.Sprintf("\nSteps completed: %d. That was the whole tour; type 'help' to see all commands.", completed), nil
+	}
+	return fmt.Sprintf("\nSteps completed: %d, left: %d. Type 'tour' to continue later.", completed, remaining), nil
+}
+
+// takeTourStep runs the demo of a step and its checkpoint. It reports
+// whether the checkpoint passed and whether the user wants to stop.
+func takeTourStep(step tourStep) (done, quit bool) {
+	if step.Name == "ai" {
+		if _, err := aiAssistant(); err != nil {
+			fmt.Printf("\nThis step is skipped: %v.\nType 'tour ai' to take it once the assistant is set up.\n", err)
+			return false, false
+		}
+		answer, ok := readLine("\nThe example sends a question to the AI provider. Continue? [Y/n/quit]: ")
+		if !ok || strings.EqualFold(answer, "quit") {
+			return false, true
+		}
+		if strings.EqualFold(answer, "n") || strings.EqualFold(answer, "no") {
+			return false, false
+		}
+	}
+
+	for _, demo := range step.Demo {
+		fmt.Printf("\n> %s\n", strings.Join(demo, " "))
+		fmt.Println(render(execute(demo[0], commandRegistry[demo[0]], demo[1:]), os.Stdout))
+	}
+
+	prompt := step.Try
+	if step.Question != "" {
+		prompt = step.Question
+	}
+	fmt.Printf("\nCheckpoint: %s\n", prompt)
+	for {
+		line, ok := readLine("tour> ")
+		if !ok || strings.EqualFold(line, "quit") {
+			return false, true
+		}
+		switch {
+		case strings.EqualFold(line, "skip"):
+			return false, false
+		case line == "":
+			continue
+		case step.Question != "":
+			if containsFold(step.Answers, line) {
+				fmt.Println("Correct.")
+				return true, false
+			}
+		default:
+			fields := strings.Fields(line)
+			// generate is also reached as abb generate
+			if len(fields) > 1 && strings.EqualFold(fields[0], "abb") && strings.EqualFold(fields[1], "generate") {
+				fields = fields[1:]
+			}
+			name := strings.ToLower(fields[0])
+			cmd, exists := commandRegistry[name]
+			if exists && strings.HasPrefix(strings.ToLower(strings.Join(fields, " ")), step.Prefix) {
+				r := execute(name, cmd, fields[1:])
+				fmt.Println(render(r, os.Stdout))
+				if !r.Failed() && !strings.HasPrefix(r.String(), "Unknown") {
+					fmt.Println("Well done.")
+					return true, false
+				}
+			}
+		}
+		fmt.Printf("Not quite. %s (or type skip, quit)\n", step.Hint)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+	"github.com/polyfant/automation-helper-cli/i18n"
+)
+
+const traceUsage = `Usage: generate trace [--reader socket|serial] [--scanner 192.168.125.60|COM1:]
+       [--port 23] [--trigger T] [--pattern AAA-9999999] [--record file|mes]
+       [--file HOME:/trace.csv] [--mes 192.168.125.10:5000] [--station ST10]
+       [--tries 3] [--module Trace] [--output dir]
+Generates a RAPID module and S7 SCL blocks that read a barcode or RFID
+scanner, check the code format and write traceability records to a file
+or to the MES (with a local file as fallback).
+Pattern: ` + generate.PatternHelp + `
+
+Example:
+  generate trace --scanner 192.168.125.60 --pattern AAA-9999999 --record mes --mes 192.168.125.10:5000`
+
+// generateTrace writes the traceability module and PLC blocks
+func generateTrace(args []string) (string, error) {
+	opts := generate.TraceOptions{
+		Module:   "Trace",
+		Station:  "ST10",
+		Reader:   "socket",
+		Port:     23,
+		Record:   "file",
+		File:     "HOME:/trace.csv",
+		MaxTries: 3,
+	}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return traceUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--reader":
+			opts.Reader = strings.ToLower(value)
+		case "--scanner":
+			opts.Scanner = value
+		case "--port":
+			opts.Port, err = strconv.Atoi(value)
+		case "--trigger":
+			opts.Trigger = value
+		case "--pattern":
+			opts.Pattern = value
+		case "--record":
+			opts.Record = strings.ToLower(value)
+		case "--file":
+			opts.File = value
+		case "--mes":
+			host, port, ok := strings.Cut(value, ":")
+			if !ok {
+				return "", errors.New("--mes needs host:port")
+			}
+			opts.MES = host
+			opts.MESPort, err = strconv.Atoi(port)
+		case "--station":
+			opts.Station = value
+		case "--tries":
+			opts.MaxTries, err = strconv.Atoi(value)
+			if err == nil && opts.MaxTries < 1 {
+				err = fmt.Errorf("at least one try")
+			}
+		case "--module":
+			opts.Module = value
+		case "--output":
+			output = value
+		default:
+			return traceUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if opts.Scanner == "" {
+		opts.Scanner = "192.168.125.60"
+		if opts.Reader == "serial" {
+			opts.Scanner = "COM1:"
+		}
+	}
+	if opts.Record == "mes" && opts.MES == "" {
+		return "", errors.New("--record mes needs --mes host:port")
+	}
+	if !identifierRe.MatchString(opts.Module) {
+		return "", fmt.Errorf("%q is not a valid RAPID module name", opts.Module)
+	}
+
+	rapidCode, scl, err := generate.TraceCode(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return fmt.Sprintf("--- %s (%s.mod) ---\n%s\n--- %s (Trace.scl) ---\n%s",
+			i18n.T("generate.robot"), opts.Module, rapidCode, i18n.T("generate.s7"), strings.TrimRight(scl, "\n")), nil
+	}
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	modPath := filepath.Join(output, opts.Module+".mod")
+	sclPath := filepath.Join(output, "Trace.scl")
+	if err := writeFile(modPath, []byte(rapidCode), 0644); err != nil {
+		return "", err
+	}
+	if err := writeFile(sclPath, []byte(scl), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %s and %s", modPath, sclPath), nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const trackUsage = `Usage: calc track <file.mod> --robot <model> --travel <min:max> [--pedestal 0]
+       [--axis a] [--output file.mod]
+Chooses a track position for every robtarget of a module and checks that
+the arm reaches the target from there. Targets with a track value already
+programmed are checked at that position. --output writes the module with
+the chosen positions in the external axis (eax_a unless --axis), keeping
+programmed values the arm reaches.
+
+Targets are in world coordinates with the track along world X; at track
+position 0 the robot base is at the world origin (base frame coordinated
+with the track). The arm envelope is the sphere of calc workspace.
+See also: abb quickref track_motion
+
+Example:
+  calc track Station1.mod --robot irb6700-150 --travel 0:12000 --pedestal 300 --output Station1_track.mod`
+
+// calcTrack places the targets of a module along a robot track
+func calcTrack(args []string) (string, error) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "--") {
+		return trackUsage, nil
+	}
+	path := args[0]
+	var robot, travel, output string
+	axis := 0
+	pedestal := 0.0
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return trackUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--robot":
+			robot = value
+		case "--travel":
+			travel = value
+		case "--pedestal":
+			pedestal, err = strconv.ParseFloat(strings.TrimSuffix(value, "mm"), 64)
+		case "--axis":
+			v := strings.TrimPrefix(strings.ToLower(value), "eax_")
+			if len(v) != 1 || v[0] < 'a' || v[0] > 'f' {
+				err = fmt.Errorf("axis")
+			}
+			axis = int(v[0] - 'a')
+		case "--output":
+			output = value
+		default:
+			return trackUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if robot == "" || travel == "" {
+		return trackUsage, nil
+	}
+	track, err := parseTravel(travel)
+	if err != nil {
+		return "", err
+	}
+	r, ok := abb.RobotModel(robot)
+	if !ok {
+		return "", fmt.Errorf("unknown or ambiguous robot %q. Available: %s", robot, strings.Join(abb.RobotKeys(), ", "))
+	}
+	envelope, err := calc.Workspace(r, "floor", pedestal)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	src := string(data)
+	targets := rapid.ParseTargets(src)
+	if len(targets) == 0 {
+		return "No robtargets with literal values found in " + path, nil
+	}
+
+	eax := "eax_" + string(rune('a'+axis))
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s on a %.0f to %.0f mm track (%s), pedestal %.0f mm:\n\n", r.Model, track.Min, track.Max, eax, pedestal)
+	fmt.Fprintf(&result, "  %-20s %10s %9s %9s  %6s  %s\n", "Target", "Programmed", "Chosen", "Distance", "Reach", "Note")
+	placed := make(map[string]float64)
+	unreachable := 0
+	from := track.Min
+	for _, t := range targets {
+		p := envelope.PlaceOnTrack(track, t.Trans, from)
+		from = p.Position
+		programmed := "-"
+		judged := p
+		if t.Ext[axis] < 9e9 {
+			programmed = fmt.Sprintf("%.0f", t.Ext[axis])
+			judged = envelope.CheckOnTrack(track, t.Trans, t.Ext[axis])
+			if !judged.Reachable && p.Reachable {
+				judged.Warning += fmt.Sprintf("; reachable at %.0f", p.Position)
+			}
+			from = t.Ext[axis]
+		}
+		if programmed == "-" || !judged.Reachable {
+			placed[t.Name] = math.Round(p.Position)
+		}
+		if !judged.Reachable {
+			unreachable++
+			judged.Warning = "UNREACHABLE: " + judged.Warning
+		}
+		use := "-"
+		if judged.Available > 0 {
+			use = fmt.Sprintf("%.0f%%", judged.Distance/judged.Available*100)
+		}
+		fmt.Fprintf(&result, "  %-20s %10s %9.0f %9.0f  %6s  %s\n", t.Name, programmed, p.Position, judged.Distance, use, judged.Warning)
+	}
+	if unreachable > 0 {
+		fmt.Fprintf(&result, "\nWarning: %d of %d targets cannot be reached; move the track, raise or lower the pedestal, or re-fixture the part.", unreachable, len(targets))
+	}
+
+	if output != "" {
+		out := rapid.ReplaceTargets(src, func(t rapid.Target) (rapid.Target, bool) {
+			position, ok := placed[t.Name]
+			if !ok {
+				return t, false
+			}
+			t.Ext[axis] = position
+			return t, true
+		})
+		if err := writeFile(output, []byte(out), 0o644); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&result, "\nWrote %s with %d chosen %s values; verify the targets in the simulation before running.", output, len(placed), eax)
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+// parseTravel reads a track travel such as 0:12000 in mm
+func parseTravel(s string) (calc.Track, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return calc.Track{}, fmt.Errorf("give the track travel as min:max in mm")
+	}
+	min, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	max, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil || max <= min {
+		return calc.Track{}, fmt.Errorf("invalid track travel %q", s)
+	}
+	return calc.Track{Min: min, Max: max}, nil
+}
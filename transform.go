@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/calc"
+	"github.com/polyfant/automation-helper-cli/rapid"
+)
+
+const transformUsage = `Usage: abb transform <file.mod> [options]
+Applies a rigid transform to robtargets and rewrites the module.
+Rotations are applied in the order given, about --origin, then --translate.
+
+Options:
+  --translate x,y,z      Move targets by this offset in mm
+  --rotate-x <deg>       Rotate about the x axis (also --rotate-y, --rotate-z)
+  --origin x,y,z         Rotation center (default 0,0,0 of the target frame)
+  --only-prefix <name>   Only change targets whose name starts with <name>
+  --output <file>        Write the result to another file
+
+Example:
+  abb transform Station1.mod --translate 0,0,50 --rotate-z 90 --only-prefix pPick`
+
+// abbTransform applies a rigid transform to the robtargets of a module
+func abbTransform(args []string) (string, error) {
+	if len(args) < 2 {
+		return transformUsage, nil
+	}
+	path := args[0]
+	output := path
+	prefix := ""
+	t := calc.Identity()
+	rotated := false
+
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return transformUsage, nil
+		}
+		opt, value := args[i], args[i+1]
+		i++
+		switch opt {
+		case "--translate", "--origin":
+			v, err := calc.ParseVector(value)
+			if err != nil {
+				return "", err
+			}
+			if opt == "--translate" {
+				t.Translate = v
+			} else {
+				t.Origin = v
+			}
+		case "--rotate-x", "--rotate-y", "--rotate-z":
+			deg, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid angle: %s", value)
+			}
+			q, _ := calc.AxisRotation(opt[len(opt)-1], deg)
+			t.Rotate(q)
+			rotated = true
+		case "--only-prefix":
+			prefix = value
+		case "--output":
+			output = value
+		default:
+			return transformUsage, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var changed []string
+	out := rapid.ReplaceTargets(string(data), func(target rapid.Target) (rapid.Target, bool) {
+		if !strings.HasPrefix(target.Name, prefix) {
+			return target, false
+		}
+		target.Trans, target.Rot = t.Apply(target.Trans, target.Rot)
+		changed = append(changed, target.Name)
+		return target.Rounded(2, 9), true
+	})
+	if len(changed) == 0 {
+		return "No matching robtargets found; nothing written.", nil
+	}
+	if err := writeFile(output, []byte(out), 0o644); err != nil {
+		return "", err
+	}
+
+	result := fmt.Sprintf("Transformed %d robtargets in %s: %s", len(changed), output, strings.Join(changed, ", "))
+	if rotated && t.RotationAngle() > 45 {
+		result += fmt.Sprintf("\nWarning: rotation of %.0f deg - the configuration data (cf1, cf4, cf6) of these\n"+
+			"targets is unchanged and probably wrong. Jog to each target and run ModPos, or\n"+
+			"use ConfL\\Off/ConfJ\\Off for the first test run at reduced speed.", t.RotationAngle())
+	}
+	return result, nil
+}
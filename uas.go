@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+const uasUsage = `Usage:
+  abb uas list <backupdir|uas.xml>     - Users, groups and grants saved in a backup
+  abb uas grants --host <ip> [--user name --password pw]
+                                       - Grants of a user on a live controller (RWS)
+  abb uas recommend [--format text|markdown]
+                                       - Recommended groups and grants for a cell
+
+The User Authorization System (UAS) decides who may run, edit and configure
+the controller. list checks for common mistakes such as an unrestricted
+Default User. See also 'abb quickref uas' and 'abb quickref uas_grants'.
+
+Examples:
+  abb uas list ./Backups/IRB6700_2024-05-02
+  abb uas grants --host 192.168.125.1 --user Maintenance --password ****
+  abb uas recommend --format markdown > uas.md`
+
+// abbUAS dispatches the UAS subcommands
+func abbUAS(args []string) (string, error) {
+	if len(args) < 1 {
+		return uasUsage, nil
+	}
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return uasUsage, nil
+		}
+		return uasList(args[1])
+	case "grants":
+		return uasGrants(args[1:])
+	case "recommend":
+		format := "text"
+		if len(args) == 3 && args[1] == "--format" {
+			format = args[2]
+		} else if len(args) != 1 {
+			return uasUsage, nil
+		}
+		return uasRecommend(format)
+	default:
+		return uasUsage, nil
+	}
+}
+
+// uasFile finds the UAS settings in a backup, or takes the file itself
+func uasFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+	found := ""
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.ToLower(d.Name())
+		if !d.IsDir() && strings.HasPrefix(name, "uas") && strings.HasSuffix(name, ".xml") {
+			found = p
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", fmt.Errorf("no UAS settings (uas*.xml) in %s; the backup may have been taken without them", path)
+	}
+	return found, nil
+}
+
+func uasList(path string) (string, error) {
+	file, err := uasFile(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	cfg, err := abb.ParseUAS(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", file, err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "UAS settings from %s\n\nUsers:\n", file)
+	for _, u := range cfg.Users {
+		groups := strings.Join(u.Groups, ", ")
+		if !u.Active {
+			groups = strings.TrimSpace(groups + " (deactivated)")
+		}
+		fmt.Fprintf(&b, "  %-20s %s\n", u.Name, groups)
+	}
+	b.WriteString("\nGroups:\n")
+	for _, g := range cfg.Groups {
+		fmt.Fprintf(&b, "  %s\n", g.Name)
+		for _, grant := range g.Grants {
+			fmt.Fprintf(&b, "    %s\n", grant)
+		}
+	}
+	findings := cfg.Check()
+	if len(findings) > 0 {
+		b.WriteString("\nFindings:\n")
+		for _, f := range findings {
+			fmt.Fprintf(&b, "  - %s\n", f)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func uasGrants(args []string) (string, error) {
+	host := ""
+	var user, password string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return uasUsage, nil
+		}
+		switch args[i] {
+		case "--host":
+			host = args[i+1]
+		case "--user":
+			user = args[i+1]
+		case "--password":
+			password = args[i+1]
+		default:
+			return uasUsage, nil
+		}
+		i++
+	}
+	if host == "" {
+		return uasUsage, nil
+	}
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	grants, err := c.Grants()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Grants of %q on %s (%d):\n", c.User, host, len(grants))
+	for _, g := range grants {
+		fmt.Fprintf(&b, "  %s\n", g)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func uasRecommend(format string) (string, error) {
+	var b strings.Builder
+	switch format {
+	case "text":
+		b.WriteString("Recommended UAS groups:\n")
+		for _, r := range abb.UASRoles {
+			fmt.Fprintf(&b, "\n%s - %s\n", r.Name, r.Description)
+			for _, g := range r.Grants {
+				fmt.Fprintf(&b, "  %s\n", g)
+			}
+		}
+		b.WriteString("\nCreate the groups in RobotStudio (Controller tab > Authenticate > Edit User Accounts),")
+		b.WriteString("\ngive every person their own user, and take a backup afterwards.")
+	case "markdown":
+		b.WriteString("# Controller user groups\n\n| Group | Purpose | Grants |\n|---|---|---|\n")
+		for _, r := range abb.UASRoles {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", r.Name, r.Description, strings.Join(r.Grants, ", "))
+		}
+		b.WriteString("\n- Every person gets their own user; shared accounts hide who changed what\n")
+		b.WriteString("- Default User keeps only the Operator grants\n")
+		b.WriteString("- Integrations use their own service account, never Default User or a person's account")
+	default:
+		return "", fmt.Errorf("unknown format %q (use text or markdown)", format)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/history"
+)
+
+func init() {
+	commandRegistry["undo"] = Command{
+		Group:       groupCode,
+		Description: "Restore the files changed by the last file-rewriting command",
+		Usage:       undoUsage,
+		Execute:     text(runUndo),
+	}
+	commandRegistry["redo"] = Command{
+		Group:       groupCode,
+		Description: "Apply an undone change again",
+		Usage:       undoUsage,
+		Execute:     text(runRedo),
+	}
+}
+
+const undoUsage = `Usage:
+  undo [--force]   Restore the files changed by the last command
+  undo --list      Show what undo and redo would restore
+  redo [--force]   Apply the last undone change again
+
+Commands that write files, such as abb header, transform, mirror,
+modernize, rename-targets and the generators, record the files they
+change; undo removes files a command created. undo refuses when a file was
+edited since; --force overwrites it anyway. The last 20 changes are kept.`
+
+func runUndo(args []string) (string, error) {
+	force := false
+	for _, a := range args {
+		switch a {
+		case "--force":
+			force = true
+		case "--list":
+			return listHistory()
+		default:
+			return undoUsage, nil
+		}
+	}
+	cs, err := history.Undo(force)
+	if err != nil {
+		return "", err
+	}
+	return describeChangeset("Undid", cs), nil
+}
+
+func runRedo(args []string) (string, error) {
+	force := false
+	for _, a := range args {
+		if a != "--force" {
+			return undoUsage, nil
+		}
+		force = true
+	}
+	cs, err := history.Redo(force)
+	if err != nil {
+		return "", err
+	}
+	return describeChangeset("Redid", cs), nil
+}
+
+func listHistory() (string, error) {
+	undo, redo, err := history.List()
+	if err != nil {
+		return "", err
+	}
+	if len(undo) == 0 && len(redo) == 0 {
+		return "No recorded changes.", nil
+	}
+	var b strings.Builder
+	for _, section := range []struct {
+		title string
+		sets  []history.Changeset
+	}{{"Undo", undo}, {"Redo", redo}} {
+		if len(section.sets) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s (newest first):\n", section.title)
+		for _, cs := range section.sets {
+			fmt.Fprintf(&b, "  %s  %s (%d files)\n", cs.Time.Format("2006-01-02 15:04"), cs.Command, len(cs.Changes))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func describeChangeset(verb string, cs history.Changeset) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s '%s' from %s:", verb, cs.Command, cs.Time.Format("2006-01-02 15:04"))
+	for _, c := range cs.Changes {
+		switch {
+		case verb == "Undid" && !c.Existed:
+			fmt.Fprintf(&b, "\n  removed  %s", c.Path)
+		case verb == "Undid":
+			fmt.Fprintf(&b, "\n  restored %s", c.Path)
+		default:
+			fmt.Fprintf(&b, "\n  wrote    %s", c.Path)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/update"
+)
+
+// updateURLEnv overrides the release manifest location, e.g. for an internal mirror
+const updateURLEnv = "AUTOMATION_HELPER_UPDATE_URL"
+
+func init() {
+	commandRegistry["update"] = Command{
+		Group:       groupSystem,
+		Description: "Check for and install new releases and reference data packs",
+		Usage:       updateUsage,
+		Execute:     text(runUpdate),
+	}
+}
+
+const updateUsage = `Usage: update <check|install> [--packs-only|--binary-only]
+Downloads the signed release manifest from ` + updateURLEnv + `
+(default: ` + update.DefaultURL + `)
+and installs verified binaries and data packs into the data directory.`
+
+func runUpdate(args []string) (string, error) {
+	if len(args) < 1 {
+		return updateUsage, nil
+	}
+
+	packs, binary := true, true
+	for _, a := range args[1:] {
+		switch a {
+		case "--packs-only":
+			binary = false
+		case "--binary-only":
+			packs = false
+		default:
+			return updateUsage, nil
+		}
+	}
+
+	client := update.NewClient(os.Getenv(updateURLEnv))
+	m, err := client.Fetch()
+	if err != nil {
+		return "", err
+	}
+	pending, err := update.PendingPacks(m)
+	if err != nil {
+		return "", err
+	}
+	// Development builds are never replaced by release binaries
+	newBinary := version != "dev" && update.NewerVersion(m.Version, version)
+
+	switch args[0] {
+	case "check":
+		var result strings.Builder
+		result.WriteString(fmt.Sprintf("Installed version: %s\nLatest release:    %s\n", version, m.Version))
+		if newBinary {
+			result.WriteString("A new version is available. Run 'update install' to upgrade.\n")
+		}
+		if len(pending) == 0 {
+			result.WriteString("All data packs are up to date.")
+		} else {
+			result.WriteString("Data pack updates:\n")
+			for _, p := range pending {
+				result.WriteString(fmt.Sprintf("  %s %s\n", p.Name, p.Version))
+			}
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	case "install":
+		var result strings.Builder
+		var failed []string
+		if packs {
+			for _, p := range pending {
+				if err := client.InstallPack(p); err != nil {
+					failed = append(failed, fmt.Sprintf("installing pack %s: %v", p.Name, err))
+					continue
+				}
+				result.WriteString(fmt.Sprintf("Installed pack %s %s\n", p.Name, p.Version))
+			}
+		}
+		if binary && newBinary {
+			if err := client.InstallBinary(m); err != nil {
+				failed = append(failed, fmt.Sprintf("updating binary: %v", err))
+			} else {
+				result.WriteString(fmt.Sprintf("Updated to %s. Restart the CLI to use the new version.\n", m.Version))
+			}
+		}
+		if len(failed) > 0 {
+			return strings.TrimRight(result.String(), "\n"), errors.New(strings.Join(failed, "\n"))
+		}
+		if result.Len() == 0 {
+			return "Everything is up to date.", nil
+		}
+		return strings.TrimRight(result.String(), "\n"), nil
+
+	default:
+		return updateUsage, nil
+	}
+}
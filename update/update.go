@@ -0,0 +1,249 @@
+// Package update checks a release endpoint for new binaries and data packs
+// and installs them after verifying the signed release manifest
+package update
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/datapack"
+)
+
+// DefaultURL is the release manifest location used when no override is configured
+const DefaultURL = "https://github.com/polyfant/automation-helper-cli/releases/latest/download/manifest.json"
+
+// maxDownload caps the size of any artifact fetched during an update
+const maxDownload = 256 << 20
+
+// PublicKey is the base64 ed25519 key that signs release manifests.
+// Release builds set it with -ldflags "-X .../update.PublicKey=...".
+var PublicKey = ""
+
+// Artifact is a downloadable file listed in the manifest
+type Artifact struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// PackRelease is a data pack offered by the release endpoint. Optional
+// packs (other vendors, large error code sets) are only installed on
+// request with pack install and then kept up to date.
+type PackRelease struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Artifact
+}
+
+// Manifest describes the latest release. It is signed as a whole and the
+// detached signature is published next to it with a ".sig" suffix.
+type Manifest struct {
+	Version  string              `json:"version"`
+	Binaries map[string]Artifact `json:"binaries"` // keyed by GOOS-GOARCH
+	Packs    []PackRelease       `json:"packs"`
+}
+
+// Client fetches and verifies release data
+type Client struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// NewClient creates a client for the given manifest URL
+func NewClient(url string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{URL: url, HTTP: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+// Fetch downloads the manifest and verifies its signature
+func (c *Client) Fetch() (*Manifest, error) {
+	key, err := publicKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.download(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := c.download(c.URL + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("manifest signature: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return nil, fmt.Errorf("manifest signature is not base64: %v", err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return nil, fmt.Errorf("manifest signature verification failed")
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return &m, nil
+}
+
+func publicKey() (ed25519.PublicKey, error) {
+	if PublicKey == "" {
+		return nil, fmt.Errorf("this build has no release signing key; updates cannot be verified")
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key in this build")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func (c *Client) download(url string) ([]byte, error) {
+	resp, err := c.HTTP.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDownload))
+}
+
+// fetchArtifact downloads an artifact and checks it against the signed hash
+func (c *Client) fetchArtifact(a Artifact) ([]byte, error) {
+	data, err := c.download(a.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), a.SHA256) {
+		return nil, fmt.Errorf("checksum mismatch for %s", a.URL)
+	}
+	return data, nil
+}
+
+// PendingPacks returns the packs that are missing or older than the
+// release; optional packs only when they are installed
+func PendingPacks(m *Manifest) ([]PackRelease, error) {
+	var pending []PackRelease
+	for _, p := range m.Packs {
+		installed, ok, err := datapack.Find(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && p.Optional {
+			continue
+		}
+		if !ok || NewerVersion(p.Version, installed.Version) {
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+// FindPack returns the pack of the manifest with the given name
+func (m *Manifest) FindPack(name string) (PackRelease, bool) {
+	for _, p := range m.Packs {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PackRelease{}, false
+}
+
+// InstallPack downloads, verifies and installs a single data pack
+func (c *Client) InstallPack(p PackRelease) error {
+	data, err := c.fetchArtifact(p.Artifact)
+	if err != nil {
+		return err
+	}
+	_, err = datapack.Install(p.Name, p.Version, packExt(p.URL), p.SHA256, data)
+	return err
+}
+
+// packExt returns the file extension a downloaded pack is installed with,
+// keeping .json.gz whole so compressed packs are read as JSON
+func packExt(url string) string {
+	if strings.HasSuffix(url, ".json.gz") {
+		return ".json.gz"
+	}
+	return path.Ext(url)
+}
+
+// Platform returns the manifest key for the running binary
+func Platform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// InstallBinary replaces the running executable with the release binary
+func (c *Client) InstallBinary(m *Manifest) error {
+	a, ok := m.Binaries[Platform()]
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s", m.Version, Platform())
+	}
+	data, err := c.fetchArtifact(a)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, data, 0o755); err != nil {
+		return err
+	}
+	// Windows cannot overwrite a running executable but allows renaming it
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	if runtime.GOOS != "windows" {
+		os.Remove(old)
+	}
+	return nil
+}
+
+// NewerVersion reports whether version a is newer than b, comparing
+// dot-separated numeric components (a leading "v" is ignored)
+func NewerVersion(a, b string) bool {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			return na > nb
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package update
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/datapack"
+)
+
+func TestPackExt(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/abb-errors.json":         ".json",
+		"https://example.com/abb-errors.json.gz":      ".json.gz",
+		"https://example.com/releases/fanuc.pack.csv": ".csv",
+	}
+	for url, want := range tests {
+		if got := packExt(url); got != want {
+			t.Errorf("packExt(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
+
+// A compressed pack installed by update must be merged into the abb lookups
+func TestInstallCompressedPack(t *testing.T) {
+	t.Setenv(config.HomeEnv, t.TempDir())
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevKey := PublicKey
+	PublicKey = base64.StdEncoding.EncodeToString(public)
+	defer func() { PublicKey = prevKey }()
+
+	var pack bytes.Buffer
+	gz := gzip.NewWriter(&pack)
+	if err := json.NewEncoder(gz).Encode(abb.Pack{Vendor: "abb", ErrorCodes: map[string]abb.ErrorCode{
+		"ERR_TEST_PACK": {Name: "ERR_TEST_PACK", Cause: "Installed from a compressed pack", Recovery: "None"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(pack.Bytes())
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	manifest, err := json.Marshal(Manifest{Version: "1.0.0", Packs: []PackRelease{{Name: "abb-errors", Version: "2026.10",
+		Artifact: Artifact{URL: srv.URL + "/abb-errors.json.gz", SHA256: hex.EncodeToString(sum[:])}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(data []byte) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write(data) }
+	}
+	mux.HandleFunc("/manifest.json", serve(manifest))
+	mux.HandleFunc("/manifest.json.sig", serve([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, manifest)))))
+	mux.HandleFunc("/abb-errors.json.gz", serve(pack.Bytes()))
+
+	c := NewClient(srv.URL + "/manifest.json")
+	m, err := c.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := PendingPacks(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("%d pending packs, want 1", len(pending))
+	}
+	if err := c.InstallPack(pending[0]); err != nil {
+		t.Fatal(err)
+	}
+	p, ok, err := datapack.Find("abb-errors")
+	if err != nil || !ok || p.File != "abb-errors.json.gz" {
+		t.Fatalf("installed %+v, %v, %v; want abb-errors.json.gz", p, ok, err)
+	}
+	if pending, _ := PendingPacks(m); len(pending) != 0 {
+		t.Errorf("%d packs still pending after the install", len(pending))
+	}
+	code, ok := abb.Error("ERR_TEST_PACK")
+	if !ok || code.Cause != "Installed from a compressed pack" {
+		t.Errorf("abb.Error(ERR_TEST_PACK) = %+v, %v; want the entry of the pack", code, ok)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/config"
+	"github.com/polyfant/automation-helper-cli/project"
+)
+
+func init() {
+	commandRegistry["use"] = Command{
+		Group:       groupSystem,
+		Description: "Select the active project that commands default to",
+		Usage:       useUsage,
+		Execute:     text(runUse),
+	}
+}
+
+const useUsage = `Usage:
+  use                      Show the active project
+  use <projectdir> [--robot <model>] [--controller <rw6|rw7|version>] [--cell <name>]
+      [--sources <dir,dir>] [--target-prefix <prefix>] [--style <profile>]
+  use --clear              Work without a project again
+
+With an active project, lint, stats, abb deps and abb score check the
+project's RAPID files when no files are given; calc workspace, stopping and
+collaborative use its robot; abb compat its controller; module headers its
+cell; abb rename-targets its robtarget prefix; and the generators and
+abb format its code style (see style). Options are saved in
+` + project.File + ` in the project directory.
+
+Examples:
+  use ./Cell4 --robot irb6700-150 --controller rw7 --sources RAPID/T_ROB1
+  use ./Cell4
+  use --clear`
+
+func runUse(args []string) (string, error) {
+	s, err := config.LoadSettings()
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		p, err := project.Active()
+		if err != nil {
+			return "", err
+		}
+		if p == nil {
+			return "No active project. Select one with use <projectdir>.", nil
+		}
+		return describeProject(p), nil
+	}
+	if args[0] == "--clear" {
+		s.Project = ""
+		if err := config.SaveSettings(s); err != nil {
+			return "", err
+		}
+		return "No active project.", nil
+	}
+
+	p, err := project.Load(args[0])
+	if err != nil {
+		return "", err
+	}
+	changed := false
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return useUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--robot":
+			p.Robot = value
+		case "--controller":
+			p.Controller = value
+		case "--cell":
+			p.Cell = value
+		case "--sources":
+			p.Sources = strings.Split(value, ",")
+		case "--target-prefix":
+			if !identifierRe.MatchString(value) {
+				return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+			}
+			p.TargetPrefix = value
+		case "--style":
+			if _, err := styleProfile(value); err != nil {
+				return "", err
+			}
+			p.Style = value
+		default:
+			return useUsage, nil
+		}
+		changed = true
+		i++
+	}
+	if changed {
+		if err := p.Save(); err != nil {
+			return "", err
+		}
+	}
+	s.Project = p.Dir
+	if err := config.SaveSettings(s); err != nil {
+		return "", err
+	}
+	return "Active project:\n" + describeProject(p), nil
+}
+
+func describeProject(p *project.Project) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  Name:          %s\n", p.Name)
+	fmt.Fprintf(&b, "  Directory:     %s\n", p.Dir)
+	fmt.Fprintf(&b, "  Sources:       %s\n", strings.Join(p.SourceDirs(), ", "))
+	for _, f := range [][2]string{
+		{"Robot", p.Robot}, {"Controller", p.Controller}, {"Cell", p.Cell}, {"Target prefix", p.TargetPrefix},
+		{"Style", p.Style},
+	} {
+		value := f[1]
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "  %-14s %s\n", f[0]+":", value)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// activeProject returns the active project, or nil when there is none or it
+// cannot be read; a broken project is reported once as a warning
+func activeProject() *project.Project {
+	p, err := project.Active()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	if p != nil {
+		if w := vaultWarning(p); w != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+	}
+	return p
+}
+
+// projectSources returns the source directories of the active project,
+// nil when there is none
+func projectSources() []string {
+	if p := activeProject(); p != nil {
+		return p.SourceDirs()
+	}
+	return nil
+}
+
+// projectRobot returns the robot of the active project, empty when there
+// is none
+func projectRobot() string {
+	if p := activeProject(); p != nil {
+		return p.Robot
+	}
+	return ""
+}
+
+// projectFiles returns the RAPID files of the active project for commands
+// run without file arguments
+func projectFiles() ([]string, error) {
+	sources := projectSources()
+	if sources == nil {
+		return nil, nil
+	}
+	return rapidFiles(sources)
+}
@@ -0,0 +1,367 @@
+// Package vault encrypts the files of a project directory at rest
+// (AES-256-GCM). A random data key encrypts the files and is itself sealed
+// with a key derived from a passphrase (scrypt), so the passphrase can be
+// changed without encrypting the files again. Locked files are kept under
+// random names, so neither the customer programs nor their names can be
+// read from a lost laptop.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/project"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// File holds the sealed data key in the project directory
+	File = ".automation-helper-vault.json"
+	// Dir holds the encrypted files of a locked project
+	Dir = ".vault"
+	// PassphraseEnv supplies the passphrase to scripts instead of a prompt
+	PassphraseEnv = "AUTOMATION_HELPER_VAULT_PASSPHRASE"
+)
+
+// scrypt cost parameters recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrPassphrase is returned for a passphrase that does not open the vault
+var ErrPassphrase = errors.New("wrong passphrase")
+
+// header is the content of File
+type header struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	// Key is the data key sealed with the passphrase key
+	Key []byte `json:"key"`
+}
+
+// entry is the plaintext of one locked file
+type entry struct {
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+	Data []byte      `json:"data"`
+}
+
+// Exists reports whether the project directory has a vault
+func Exists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, File))
+	return err == nil
+}
+
+// Locked returns the number of encrypted files of the project directory
+func Locked(dir string) int {
+	blobs, _ := filepath.Glob(filepath.Join(dir, Dir, "*.enc"))
+	return len(blobs)
+}
+
+// Init creates the vault of a project directory and returns its data key.
+// The files are not encrypted until Lock.
+func Init(dir, passphrase string) ([]byte, error) {
+	if Exists(dir) {
+		return nil, fmt.Errorf("%s already has a vault", dir)
+	}
+	if passphrase == "" {
+		return nil, errors.New("the passphrase is empty")
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writeHeader(dir, key, passphrase); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Open returns the data key of a project's vault
+func Open(dir, passphrase string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, File))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has no vault; create one with 'vault init'", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", File, err)
+	}
+	if h.Version != 1 {
+		return nil, fmt.Errorf("%s has version %d; this version reads version 1", File, h.Version)
+	}
+	kek, err := scrypt.Key([]byte(passphrase), h.Salt, h.N, h.R, h.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	key, err := open(kek, h.Key)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return key, nil
+}
+
+// ChangePassphrase seals the data key with a new passphrase
+func ChangePassphrase(dir, old, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase is empty")
+	}
+	key, err := Open(dir, old)
+	if err != nil {
+		return err
+	}
+	return writeHeader(dir, key, passphrase)
+}
+
+func writeHeader(dir string, key []byte, passphrase string) error {
+	h := header{Version: 1, Salt: make([]byte, 16), N: scryptN, R: scryptR, P: scryptP}
+	if _, err := rand.Read(h.Salt); err != nil {
+		return err
+	}
+	kek, err := scrypt.Key([]byte(passphrase), h.Salt, h.N, h.R, h.P, 32)
+	if err != nil {
+		return err
+	}
+	if h.Key, err = seal(kek, key); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, File), append(data, '\n'), 0o600)
+}
+
+// skipped are the entries of the project directory that stay readable: the
+// vault itself, the project settings that use needs and version control
+func skipped(rel string) bool {
+	top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	switch top {
+	case File, Dir, project.File, ".git", ".svn", ".hg":
+		return true
+	}
+	return false
+}
+
+// Lock encrypts the files of the project directory into Dir and removes
+// them, with the directories they leave empty. It returns the relative
+// paths of the files it locked.
+func Lock(dir string, key []byte) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." {
+			return nil
+		}
+		if skipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Files created while the project was locked must not shadow a locked
+	// file of the same name
+	locked, err := read(dir, key)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range locked {
+		for _, rel := range paths {
+			if filepath.ToSlash(rel) == e.Path {
+				return nil, fmt.Errorf("%s is already locked; unlock the project before changing it", e.Path)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, Dir), 0o700); err != nil {
+		return nil, err
+	}
+	// Write every blob before removing any file, so a failure leaves the
+	// project as it was
+	var blobs []string
+	for _, rel := range paths {
+		blob, err := lockFile(dir, rel, key)
+		if err != nil {
+			for _, b := range blobs {
+				os.Remove(b)
+			}
+			return nil, fmt.Errorf("%s: %v", rel, err)
+		}
+		blobs = append(blobs, blob)
+	}
+	for _, rel := range paths {
+		if err := os.Remove(filepath.Join(dir, rel)); err != nil {
+			return nil, err
+		}
+	}
+	removeEmptyDirs(dir)
+	return paths, nil
+}
+
+func lockFile(dir, rel string, key []byte) (string, error) {
+	path := filepath.Join(dir, rel)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	plain, err := json.Marshal(entry{Path: filepath.ToSlash(rel), Mode: info.Mode().Perm(), Data: data})
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(key, plain)
+	if err != nil {
+		return "", err
+	}
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	blob := filepath.Join(dir, Dir, hex.EncodeToString(name)+".enc")
+	return blob, os.WriteFile(blob, sealed, 0o600)
+}
+
+// Unlock decrypts the locked files back into the project directory and
+// removes Dir. A file that exists with other content is a conflict unless
+// force is set. It returns the relative paths of the files it restored.
+func Unlock(dir string, key []byte, force bool) ([]string, error) {
+	entries, err := read(dir, key)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		for _, e := range entries {
+			current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+			if err == nil && string(current) != string(e.Data) {
+				return nil, fmt.Errorf("%s was created while the project was locked; use --force to overwrite it", e.Path)
+			}
+		}
+	}
+	var paths []string
+	for _, e := range entries {
+		path := filepath.Join(dir, filepath.FromSlash(e.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, e.Data, e.Mode); err != nil {
+			return nil, err
+		}
+		paths = append(paths, e.Path)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, Dir)); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// read decrypts the locked files of the project directory
+func read(dir string, key []byte) ([]entry, error) {
+	blobs, err := filepath.Glob(filepath.Join(dir, Dir, "*.enc"))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(blobs))
+	for _, b := range blobs {
+		sealed, err := os.ReadFile(b)
+		if err != nil {
+			return nil, err
+		}
+		plain, err := open(key, sealed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(b), ErrPassphrase)
+		}
+		var e entry
+		if err := json.Unmarshal(plain, &e); err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(b), err)
+		}
+		if rel := filepath.FromSlash(e.Path); !filepath.IsLocal(rel) || skipped(rel) {
+			return nil, fmt.Errorf("%s: invalid path %q", filepath.Base(b), e.Path)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// removeEmptyDirs removes the directories below dir that hold no files,
+// deepest first
+func removeEmptyDirs(dir string) {
+	var dirs []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil
+		}
+		if rel, _ := filepath.Rel(dir, path); skipped(rel) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i]) // fails, as intended, for directories that are not empty
+	}
+}
+
+// seal encrypts data with a 256-bit key, prefixing the random nonce
+func seal(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts the output of seal
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("truncated data")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
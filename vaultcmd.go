@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/polyfant/automation-helper-cli/project"
+	"github.com/polyfant/automation-helper-cli/vault"
+)
+
+func init() {
+	commandRegistry["vault"] = Command{
+		Group:       groupSystem,
+		Description: "Encrypt the active project's files at rest and unlock them per session",
+		Usage:       vaultUsage,
+		Execute:     text(runVault),
+	}
+}
+
+const vaultUsage = `Usage:
+  vault                       Show whether the active project is locked
+  vault init                  Create the project's vault and lock its files
+  vault unlock [--force]      Decrypt the files for this session
+  vault lock                  Encrypt the files again
+  vault passphrase            Change the passphrase
+
+The vault encrypts every file of the project directory (AES-256-GCM with a
+key sealed by your passphrase) and keeps them under random names in ` + vault.Dir + `,
+so customer programs and credentials on a lost laptop cannot be read.
+Left readable are ` + project.File + `, the vault key file and the
+.git directory; lock a project before committing it or keep its repository
+elsewhere.
+
+Unlock a project at the start of a session; the REPL locks it again on
+exit. Outside the REPL, or when the CLI is not left with exit, run
+vault lock yourself. The passphrase is asked for unless ` + vault.PassphraseEnv + `
+is set. There is no way to recover a forgotten passphrase.
+
+Examples:
+  vault init
+  vault unlock
+  vault lock`
+
+// unlockedVaults holds the data keys of the projects unlocked in this
+// session, so they can be locked without asking again
+var unlockedVaults = make(map[string][]byte)
+
+func runVault(args []string) (string, error) {
+	// project.Active rather than activeProject, which warns about the
+	// locked files this command is about
+	p, err := project.Active()
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", errors.New("the vault works on the active project; select one with 'use <projectdir>'")
+	}
+	if len(args) == 0 {
+		return vaultStatus(p), nil
+	}
+	switch args[0] {
+	case "init":
+		if len(args) != 1 {
+			return vaultUsage, nil
+		}
+		if vault.Exists(p.Dir) {
+			return "", fmt.Errorf("%s already has a vault", p.Name)
+		}
+		passphrase, ok := newPassphrase()
+		if !ok {
+			return "", errors.New("the passphrases do not match")
+		}
+		key, err := vault.Init(p.Dir, passphrase)
+		if err != nil {
+			return "", err
+		}
+		locked, err := vault.Lock(p.Dir, key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created the vault of %s and locked %d files. Keep the passphrase safe; it cannot be recovered.", p.Name, len(locked)), nil
+	case "unlock":
+		force := false
+		for _, a := range args[1:] {
+			if a != "--force" {
+				return vaultUsage, nil
+			}
+			force = true
+		}
+		key, err := vaultKey(p)
+		if err != nil {
+			return "", err
+		}
+		files, err := vault.Unlock(p.Dir, key, force)
+		if err != nil {
+			return "", err
+		}
+		unlockedVaults[p.Dir] = key
+		return fmt.Sprintf("Unlocked %d files of %s. Lock them again with 'vault lock' when you are done.", len(files), p.Name), nil
+	case "lock":
+		if len(args) != 1 {
+			return vaultUsage, nil
+		}
+		key, err := vaultKey(p)
+		if err != nil {
+			return "", err
+		}
+		files, err := vault.Lock(p.Dir, key)
+		if err != nil {
+			return "", err
+		}
+		delete(unlockedVaults, p.Dir)
+		return fmt.Sprintf("Locked %d files of %s.", len(files), p.Name), nil
+	case "passphrase":
+		if len(args) != 1 {
+			return vaultUsage, nil
+		}
+		old, ok := readPassword("Current passphrase: ")
+		if !ok {
+			return "", errors.New("no passphrase given")
+		}
+		passphrase, ok := newPassphrase()
+		if !ok {
+			return "", errors.New("the passphrases do not match")
+		}
+		if err := vault.ChangePassphrase(p.Dir, old, passphrase); err != nil {
+			return "", err
+		}
+		return "Changed the passphrase of the vault of " + p.Name, nil
+	default:
+		return vaultUsage, nil
+	}
+}
+
+func vaultStatus(p *project.Project) string {
+	switch {
+	case !vault.Exists(p.Dir):
+		return fmt.Sprintf("%s has no vault; its files are not encrypted. Create one with 'vault init'.", p.Name)
+	case vault.Locked(p.Dir) > 0:
+		return fmt.Sprintf("%s is locked: %d encrypted files. Unlock it with 'vault unlock'.", p.Name, vault.Locked(p.Dir))
+	default:
+		return fmt.Sprintf("%s is unlocked. Lock it with 'vault lock'.", p.Name)
+	}
+}
+
+// vaultKey returns the data key of the project's vault, asking for the
+// passphrase unless the project was unlocked in this session
+func vaultKey(p *project.Project) ([]byte, error) {
+	if key, ok := unlockedVaults[p.Dir]; ok {
+		return key, nil
+	}
+	passphrase := os.Getenv(vault.PassphraseEnv)
+	if passphrase == "" {
+		var ok bool
+		if passphrase, ok = readPassword("Vault passphrase: "); !ok {
+			return nil, fmt.Errorf("no passphrase given")
+		}
+	}
+	return vault.Open(p.Dir, passphrase)
+}
+
+// newPassphrase asks for a new passphrase twice, or takes it from the
+// environment
+func newPassphrase() (string, bool) {
+	if passphrase := os.Getenv(vault.PassphraseEnv); passphrase != "" {
+		return passphrase, true
+	}
+	passphrase, ok := readPassword("New passphrase: ")
+	if !ok || passphrase == "" {
+		return "", false
+	}
+	again, ok := readPassword("Repeat the passphrase: ")
+	return passphrase, ok && again == passphrase
+}
+
+// lockVaults locks the projects unlocked in this session, when the REPL
+// ends
+func lockVaults() {
+	for dir, key := range unlockedVaults {
+		files, err := vault.Lock(dir, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s is still unlocked: %v\n", dir, err)
+			continue
+		}
+		fmt.Printf("Locked %d files of %s\n", len(files), dir)
+	}
+}
+
+// vaultWarning tells that the active project's files are locked away
+func vaultWarning(p *project.Project) string {
+	if n := vault.Locked(p.Dir); n > 0 {
+		return fmt.Sprintf("the %d files of %s are locked in its vault; unlock them with 'vault unlock'", n, p.Name)
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/rws"
+)
+
+const vcUsage = `Usage: abb vc run <dir|file.mod>... [--host localhost] [--task T_ROB1]
+       [--entry RunTests] [--timeout 120] [--result HOME:/file.txt]
+       [--user "Default User"] [--password robotics] [--dry-run]
+       [--approval <token>] [--ticket <token>]
+Deploys RAPID modules to a RobotStudio virtual controller over Robot Web
+Services, runs the program once from main and collects the outcome: event
+log messages raised during the run (ErrWrite, errors) and result files.
+The modules are copied to HOME:/vcrun and loaded into the task, replacing
+modules of the same name.
+
+--entry runs a routine of a project without main, such as the RunTests of
+a module from generate tests. Test modules' TestResult files are collected
+automatically; TPWrite text stays on the FlexPendant, so tests should also
+write a file. The result is an error when the program does not stop within
+the timeout (seconds), raises errors or a result file reports FAIL.
+
+The controller must be in automatic mode. RobotWare 6 (RWS 1.0) only.
+--dry-run lists the modules and steps without connecting. A production
+fleet target needs an approval, as for 'abb rws module put'.
+
+Example:
+  generate tests GripperLib.mod --output ./tests
+  abb vc run GripperLib.mod ./tests --entry RunTests`
+
+// vcDir is the folder under HOME: that vc run deploys to
+const vcDir = "vcrun"
+
+// abbVC dispatches the virtual controller commands
+func abbVC(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "run" {
+		return vcUsage, nil
+	}
+	host, task, entry := "localhost", "T_ROB1", ""
+	var user, password, token, ticket string
+	timeout := 120 * time.Second
+	var sources, results []string
+	for i := 1; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			sources = append(sources, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return vcUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--host":
+			host = value
+		case "--task":
+			task = value
+		case "--entry":
+			entry = value
+		case "--timeout":
+			seconds, err := strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+			if err != nil || seconds <= 0 {
+				return "", fmt.Errorf("invalid value %q for --timeout", value)
+			}
+			timeout = time.Duration(seconds * float64(time.Second))
+		case "--result":
+			results = append(results, value)
+		case "--user":
+			user = value
+		case "--password":
+			password = value
+		case "--approval":
+			token = value
+		case "--ticket":
+			ticket = value
+		default:
+			return vcUsage, nil
+		}
+		i++
+	}
+	if len(sources) == 0 {
+		return vcUsage, nil
+	}
+
+	files, err := rapidFiles(sources)
+	if err != nil {
+		return "", err
+	}
+	modules, hasMain, err := vcModules(files)
+	if err != nil {
+		return "", err
+	}
+	approved, err := approveModules(host, token, ticket, modules)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range modules {
+		if name := m.module; strings.HasPrefix(name, "Test") && len(name) > 4 {
+			results = append(results, "HOME:/TestResult_"+name[4:]+".txt")
+		}
+	}
+	if entry != "" && !strings.EqualFold(entry, "main") {
+		if hasMain {
+			return "", errors.New("the modules already declare main; run without --entry")
+		}
+		if !identifierRe.MatchString(entry) {
+			return "", fmt.Errorf("%q is not a valid routine name", entry)
+		}
+		// A wrapper main, since RWS starts programs at main
+		wrapper := fmt.Sprintf("MODULE VcRunMain\n    PROC main()\n        %s;\n    ENDPROC\nENDMODULE\n", entry)
+		modules = append(modules, vcModule{file: "VcRunMain.mod", module: "VcRunMain", content: []byte(wrapper)})
+	} else if !hasMain {
+		return "", errors.New("no module declares main; give the routine to run with --entry")
+	}
+
+	if dryRun() {
+		return describeVCRun(host, task, modules, results), nil
+	}
+	if approved != "" {
+		recordAction(host, "deploy "+strings.Join(moduleNames(modules), ", "), approved, nil)
+	}
+	c := rws.NewClient(host)
+	c.User, c.Password = rwsCredentials(host, user, password)
+	return vcRun(c, task, modules, results, timeout)
+}
+
+// describeVCRun lists what vc run would do on the controller, for --dry-run
+func describeVCRun(host, task string, modules []vcModule, results []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Would deploy to %s, task %s:\n", host, task)
+	for _, m := range modules {
+		fmt.Fprintf(&b, "  upload HOME:/%s/%s and load module %s\n", vcDir, m.file, m.module)
+	}
+	b.WriteString("Then reset the program pointer to main, run once and collect the event log")
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n  read %s", r)
+	}
+	return b.String()
+}
+
+type vcModule struct {
+	file    string
+	module  string
+	content []byte
+}
+
+// vcModules reads the modules to deploy and reports whether one declares main
+func vcModules(files []string) ([]vcModule, bool, error) {
+	var modules []vcModule
+	hasMain := false
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, false, err
+		}
+		info := rapid.AnalyzeModule(string(data))
+		if info.Name == "" {
+			return nil, false, fmt.Errorf("%s has no MODULE declaration", f)
+		}
+		for _, s := range info.Symbols {
+			if s.Kind == "PROC" && strings.EqualFold(s.Name, "main") {
+				hasMain = true
+			}
+		}
+		modules = append(modules, vcModule{file: filepath.Base(f), module: info.Name, content: data})
+	}
+	return modules, hasMain, nil
+}
+
+// vcRun deploys the modules, runs the program and reports what happened
+func vcRun(c *rws.Client, task string, modules []vcModule, results []string, timeout time.Duration) (string, error) {
+	mode, err := c.OperationMode()
+	if err != nil {
+		return "", fmt.Errorf("cannot reach the controller at %s: %v", c.Base, err)
+	}
+	if mode != "AUTO" {
+		return "", fmt.Errorf("the controller is in %s mode; switch the virtual controller to automatic", mode)
+	}
+	lastEvent, err := c.LastEvent()
+	if err != nil {
+		return "", err
+	}
+	if err := c.RequestMastership(); err != nil {
+		return "", err
+	}
+	defer c.ReleaseMastership()
+	if state, err := c.ExecutionState(); err == nil && state == "running" {
+		if err := c.Stop(); err != nil {
+			return "", err
+		}
+	}
+
+	var result strings.Builder
+	if err := c.CreateDir("HOME:", vcDir); err != nil {
+		return "", err
+	}
+	for _, m := range modules {
+		path := "HOME:/" + vcDir + "/" + m.file
+		if err := c.Upload(path, m.content); err != nil {
+			return "", err
+		}
+		if err := c.LoadModule(task, rws.DevicePath(path)); err != nil {
+			return "", fmt.Errorf("loading %s: %v", m.file, err)
+		}
+	}
+	fmt.Fprintf(&result, "Loaded %d modules into %s\n", len(modules), task)
+
+	for _, step := range []func() error{c.ResetPP, c.MotorsOn, c.Start} {
+		if err := step(); err != nil {
+			return strings.TrimRight(result.String(), "\n"), err
+		}
+	}
+	started := time.Now()
+	failed := false
+	if err := c.WaitStopped(timeout); err != nil {
+		c.Stop()
+		failed = true
+		fmt.Fprintf(&result, "Stopped: %v\n", err)
+	} else {
+		fmt.Fprintf(&result, "Program stopped after %.1f s\n", time.Since(started).Seconds())
+	}
+
+	events, err := c.Events(lastEvent)
+	if err != nil {
+		return strings.TrimRight(result.String(), "\n"), err
+	}
+	if len(events) > 0 {
+		result.WriteString("\nEvent log:\n")
+		for _, e := range events {
+			result.WriteString("  " + e.String() + "\n")
+			if e.Type == 3 {
+				failed = true
+			}
+		}
+	}
+	for _, path := range results {
+		data, err := c.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(&result, "\n%s: not found (%v)\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&result, "\n--- %s ---\n%s\n", path, strings.TrimRight(string(data), "\n"))
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "FAIL ") {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return "", fmt.Errorf("the run on the virtual controller failed\n%s", strings.TrimRight(result.String(), "\n"))
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
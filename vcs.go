@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+func init() {
+	commandRegistry["vcs"] = Command{
+		Group:       groupCode,
+		Description: "Git helpers: pre-commit lint hook, readable robtarget diffs, changelog",
+		Usage:       vcsUsage,
+		Execute:     text(runVCS),
+	}
+}
+
+const vcsUsage = `Usage: vcs <install|pre-commit|textconv|changelog> [arguments]
+  install [repo]      - Install the pre-commit lint hook and RAPID diff driver
+  pre-commit          - Lint staged RAPID/ST files (run by the hook)
+  textconv <file>     - Print a module with robtargets expanded for git diff
+  changelog [tag]     - Summarize module changes since a tag (default: latest)
+
+Examples:
+  vcs install
+  vcs changelog v1.4.0`
+
+// vcsHookMarker identifies hooks written by vcs install so they can be replaced
+const vcsHookMarker = "# installed by automation-helper vcs install"
+
+func runVCS(args []string) (string, error) {
+	if len(args) < 1 {
+		return vcsUsage, nil
+	}
+	switch args[0] {
+	case "install":
+		dir := "."
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return vcsInstall(dir)
+	case "pre-commit":
+		return vcsPreCommit()
+	case "textconv":
+		if len(args) < 2 {
+			return "Usage: vcs textconv <file>", nil
+		}
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return "", err
+		}
+		return expandTargets(string(data)), nil
+	case "changelog":
+		tag := ""
+		if len(args) > 1 {
+			tag = args[1]
+		}
+		return vcsChangelog(tag)
+	default:
+		return vcsUsage, nil
+	}
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exit.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// vcsInstall writes the pre-commit hook and registers the RAPID textconv driver
+func vcsInstall(dir string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	hooks, err := git(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+
+	hook := filepath.Join(hooks, "pre-commit")
+	if data, err := os.ReadFile(hook); err == nil && !strings.Contains(string(data), vcsHookMarker) {
+		return "", fmt.Errorf("%s already exists; add '%s vcs pre-commit' to it manually", hook, exe)
+	}
+	script := fmt.Sprintf("#!/bin/sh\n%s\n# Lints staged RAPID and ST files; bypass with git commit --no-verify\nexec %q vcs pre-commit\n", vcsHookMarker, filepath.ToSlash(exe))
+	if err := makeDir(hooks); err != nil {
+		return "", err
+	}
+	if err := writeFile(hook, []byte(script), 0o755); err != nil {
+		return "", err
+	}
+
+	if _, err := git(dir, "config", "diff.rapid.textconv", fmt.Sprintf("%q vcs textconv", filepath.ToSlash(exe))); err != nil {
+		return "", err
+	}
+	added, err := addGitAttributes(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString("Installed pre-commit lint hook: " + hook + "\n")
+	result.WriteString("Registered RAPID diff driver (git config diff.rapid.textconv)\n")
+	if added > 0 {
+		result.WriteString(fmt.Sprintf("Added %d RAPID patterns to .gitattributes - commit it to share the diff driver", added))
+	} else {
+		result.WriteString(".gitattributes already maps RAPID files to the diff driver")
+	}
+	return result.String(), nil
+}
+
+// addGitAttributes maps RAPID extensions to the rapid diff driver in the
+// repository's .gitattributes and returns the number of lines added
+func addGitAttributes(dir string) (int, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return 0, err
+	}
+	path := filepath.Join(top, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	existing := string(data)
+	var lines []string
+	for _, ext := range rapid.Extensions {
+		line := "*" + ext + " diff=rapid"
+		if !strings.Contains(existing, line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return len(lines), writeFile(path, []byte(existing+strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// vcsPreCommit lints the staged version of every RAPID and ST file
+func vcsPreCommit() (string, error) {
+	staged, err := git(".", "diff", "--cached", "--name-only", "--diff-filter=ACMR")
+	if err != nil {
+		return "", err
+	}
+	var report strings.Builder
+	checked, failed := 0, 0
+	for _, path := range strings.Split(staged, "\n") {
+		if path == "" || !(rapid.IsSourceFile(path) || st.IsSourceFile(path)) {
+			continue
+		}
+		src, err := git(".", "show", ":"+path)
+		if err != nil {
+			return "", err
+		}
+		checked++
+		d, err := lintDiagnostics(path, src)
+		if err != nil {
+			return "", err
+		}
+		// Warnings are shown but only errors stop the commit
+		hasErrors := false
+		for _, item := range d.Items {
+			report.WriteString(fmt.Sprintf("%s: line %d: %s: %s\n", path, item.Line, item.Severity, item.Message))
+			hasErrors = hasErrors || item.Severity == rapid.SeverityError
+		}
+		if hasErrors {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return strings.TrimRight(report.String(), "\n"), fmt.Errorf("lint found problems in %d of %d staged files "+
+			"(use git commit --no-verify to skip)", failed, checked)
+	}
+	if report.Len() > 0 {
+		return fmt.Sprintf("Lint passed for %d staged files with warnings:\n%s", checked, strings.TrimRight(report.String(), "\n")), nil
+	}
+	return fmt.Sprintf("Lint passed for %d staged files", checked), nil
+}
+
+// expandTargets rewrites robtarget declarations one component per line so
+// git diff shows which part of a position changed
+func expandTargets(src string) string {
+	targets := make(map[int]rapid.Target)
+	for _, t := range rapid.ParseTargets(src) {
+		targets[t.Line] = t
+	}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	for i, line := range lines {
+		t, ok := targets[i+1]
+		if !ok {
+			out.WriteString(line + "\n")
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		out.WriteString(fmt.Sprintf("%s%s robtarget %s\n", indent, t.Storage, t.Name))
+		out.WriteString(fmt.Sprintf("%s    pos  x=%g y=%g z=%g\n", indent, t.Trans[0], t.Trans[1], t.Trans[2]))
+		out.WriteString(fmt.Sprintf("%s    rot  q1=%g q2=%g q3=%g q4=%g\n", indent, t.Rot[0], t.Rot[1], t.Rot[2], t.Rot[3]))
+		out.WriteString(fmt.Sprintf("%s    conf cf1=%g cf4=%g cf6=%g cfx=%g\n", indent, t.Conf[0], t.Conf[1], t.Conf[2], t.Conf[3]))
+		for n, e := range t.Ext {
+			if e < 9e9 {
+				out.WriteString(fmt.Sprintf("%s    ext  e%d=%g\n", indent, n+1, e))
+			}
+		}
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// vcsChangelog summarizes routine and robtarget changes per module since tag
+func vcsChangelog(tag string) (string, error) {
+	if tag == "" {
+		latest, err := git(".", "describe", "--tags", "--abbrev=0")
+		if err != nil {
+			return "", fmt.Errorf("no tag given and none found: %v", err)
+		}
+		tag = latest
+	}
+	changed, err := git(".", "diff", "--name-status", tag, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("\nChanges since %s:\n", tag))
+	modules := 0
+	for _, entry := range strings.Split(changed, "\n") {
+		fields := strings.Split(entry, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		status, path := fields[0], fields[len(fields)-1]
+		if !rapid.IsSourceFile(path) {
+			continue
+		}
+		modules++
+		var before, after string
+		if status[0] != 'A' {
+			oldPath := fields[1]
+			before, _ = git(".", "show", tag+":"+oldPath)
+		}
+		if status[0] != 'D' {
+			after, _ = git(".", "show", "HEAD:"+path)
+		}
+		result.WriteString(moduleChangelog(path, status[0], before, after))
+	}
+	if modules == 0 {
+		result.WriteString("  No RAPID modules changed.\n")
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+// moduleChangelog describes the difference between two versions of a module
+func moduleChangelog(path string, status byte, before, after string) string {
+	name, ok := rapid.ModuleName(after)
+	if !ok {
+		name, _ = rapid.ModuleName(before)
+	}
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("\n%s (%s)\n", name, path))
+	switch status {
+	case 'A':
+		result.WriteString("  new module\n")
+	case 'D':
+		result.WriteString("  module removed\n")
+		return result.String()
+	}
+
+	oldRoutines := make(map[string]rapid.Routine)
+	for _, r := range rapid.Routines(before) {
+		oldRoutines[r.Name] = r
+	}
+	var notes []string
+	for _, r := range rapid.Routines(after) {
+		old, existed := oldRoutines[r.Name]
+		delete(oldRoutines, r.Name)
+		switch {
+		case !existed:
+			notes = append(notes, fmt.Sprintf("  + %s %s added", r.Kind, r.Name))
+		case old.Body != r.Body:
+			notes = append(notes, fmt.Sprintf("  ~ %s %s changed", r.Kind, r.Name))
+		}
+	}
+	for _, r := range rapid.Routines(before) {
+		if _, removed := oldRoutines[r.Name]; removed {
+			notes = append(notes, fmt.Sprintf("  - %s %s removed", r.Kind, r.Name))
+		}
+	}
+
+	oldTargets := make(map[string]rapid.Target)
+	for _, t := range rapid.ParseTargets(before) {
+		oldTargets[t.Name] = t
+	}
+	for _, t := range rapid.ParseTargets(after) {
+		old, existed := oldTargets[t.Name]
+		delete(oldTargets, t.Name)
+		switch {
+		case !existed:
+			notes = append(notes, fmt.Sprintf("  + robtarget %s added", t.Name))
+		case old.Value() != t.Value():
+			notes = append(notes, fmt.Sprintf("  ~ robtarget %s moved %.2f mm", t.Name, old.Distance(t)))
+		}
+	}
+	for _, t := range rapid.ParseTargets(before) {
+		if _, removed := oldTargets[t.Name]; removed {
+			notes = append(notes, fmt.Sprintf("  - robtarget %s removed", t.Name))
+		}
+	}
+
+	if len(notes) == 0 {
+		notes = append(notes, "  comments or formatting only")
+	}
+	result.WriteString(strings.Join(notes, "\n") + "\n")
+	return result.String()
+}
@@ -0,0 +1,4 @@
+package main
+
+// version is the release version, set at build time with -ldflags "-X main.version=1.2.0"
+var version = "dev"
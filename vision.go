@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const visionUsage = `Usage: generate vision [--protocol tcp|udp] [--ip 192.168.125.50] [--port 2000]
+       [--fields ok,x,y,angle,score] [--trigger TRIGGER] [--min-score 0.7]
+       [--max-offset 50] [--tries 3] [--robot-ip 192.168.125.1] [--module VisionPick] [--output dir]
+Generates a RAPID module for camera-guided picking: socket handshake,
+result parsing, validation with retry and work object correction.
+--fields gives the order of the comma-separated values in the camera reply;
+known names are ok, x, y, angle and score, other names are skipped.
+See also: abb quickref vision_result_parsing, vision_frame_correction, vision_retry
+
+Example:
+  generate vision --ip 192.168.125.50 --port 2000 --fields x,y,angle,score`
+
+// generateVision writes the camera integration module
+func generateVision(args []string) (string, error) {
+	opts := generate.VisionOptions{
+		Module:    "VisionPick",
+		Protocol:  "tcp",
+		Address:   "192.168.125.50",
+		Port:      2000,
+		RobotIP:   "192.168.125.1",
+		Trigger:   "TRIGGER",
+		Fields:    generate.VisionFields,
+		MinScore:  0.7,
+		MaxOffset: 50,
+		MaxTries:  3,
+	}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return visionUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--protocol":
+			opts.Protocol = value
+		case "--ip":
+			opts.Address = value
+		case "--robot-ip":
+			opts.RobotIP = value
+		case "--port":
+			opts.Port, err = strconv.Atoi(value)
+		case "--fields":
+			opts.Fields = strings.Split(strings.ToLower(value), ",")
+		case "--trigger":
+			opts.Trigger = value
+		case "--min-score":
+			opts.MinScore, err = strconv.ParseFloat(value, 64)
+		case "--max-offset":
+			opts.MaxOffset, err = strconv.ParseFloat(value, 64)
+		case "--tries":
+			opts.MaxTries, err = strconv.Atoi(value)
+			if err == nil && opts.MaxTries < 1 {
+				err = fmt.Errorf("at least one try")
+			}
+		case "--module":
+			opts.Module = value
+		case "--output":
+			output = value
+		default:
+			return visionUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if !identifierRe.MatchString(opts.Module) {
+		return "", fmt.Errorf("%q is not a valid RAPID module name", opts.Module)
+	}
+
+	code, err := generate.VisionModule(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		return strings.TrimRight(code, "\n"), nil
+	}
+	path := filepath.Join(output, opts.Module+".mod")
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	if err := writeFile(path, []byte(code), 0644); err != nil {
+		return "", err
+	}
+	return "Wrote " + path, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polyfant/automation-helper-cli/rapid"
+	"github.com/polyfant/automation-helper-cli/result"
+	"github.com/polyfant/automation-helper-cli/st"
+)
+
+// watchInterval is how often the watched directory is polled for changes
+const watchInterval = time.Second
+
+func init() {
+	commandRegistry["watch"] = Command{
+		Group:       groupCode,
+		Description: "Re-run lint, stats and generators when RAPID/ST files change",
+		Usage:       watchUsage,
+		Execute:     text(runWatch),
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+const watchUsage = `Usage: watch <dir> [--run <command> [args]]
+Monitors RAPID (.mod/.sys) and ST (.st/.scl) files and re-runs lint and stats on save.
+Use {file} in --run arguments to pass the changed file to a command.
+Lint errors and failing --run commands in changed files are sent to the
+notification rules set up with notify.
+Press Ctrl+C to stop watching.
+
+Examples:
+  watch ./RAPID
+  watch ./RAPID --run stats {file}`
+
+func runWatch(args []string) (string, error) {
+	if len(args) < 1 {
+		return watchUsage, nil
+	}
+
+	dir := args[0]
+	var runArgs []string
+	if len(args) > 1 {
+		if args[1] != "--run" || len(args) < 3 {
+			return "Usage: watch <dir> [--run <command> [args]]", nil
+		}
+		runArgs = args[2:]
+		if _, exists := commandRegistry[strings.ToLower(runArgs[0])]; !exists {
+			return fmt.Sprintf("Unknown command for --run: %s", runArgs[0]), nil
+		}
+	}
+
+	known, err := scanWatchedFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Watching %s (%d files). Press Ctrl+C to stop.\n", dir, len(known))
+	for _, path := range sortedPaths(known) {
+		report, _ := watchReport(path, runArgs)
+		fmt.Print(report)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			return fmt.Sprintf("\nStopped watching %s", dir), nil
+		case <-ticker.C:
+			current, err := scanWatchedFiles(dir)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			for _, path := range sortedPaths(current) {
+				if prev, ok := known[path]; ok && prev == current[path] {
+					continue
+				}
+				fmt.Printf("\n[%s] changed: %s\n", time.Now().Format("15:04:05"), path)
+				report, events := watchReport(path, runArgs)
+				fmt.Print(report)
+				for _, ev := range events {
+					emitNotification(ev)
+				}
+			}
+			for path := range known {
+				if _, ok := current[path]; !ok {
+					fmt.Printf("\n[%s] removed: %s\n", time.Now().Format("15:04:05"), path)
+				}
+			}
+			known = current
+		}
+	}
+}
+
+// scanWatchedFiles collects RAPID and ST files below dir with their modification state
+func scanWatchedFiles(dir string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !(rapid.IsSourceFile(path) || st.IsSourceFile(path)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+func sortedPaths(files map[string]fileState) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// watchReport runs lint, stats and the configured command for one file, and
+// returns lint errors and a failing command as notification events
+func watchReport(path string, runArgs []string) (string, []notification) {
+	var b strings.Builder
+	var events []notification
+	data, err := os.ReadFile(path)
+	var d result.Diagnostics
+	if err == nil {
+		d, err = lintDiagnostics(path, string(data))
+	}
+	if err != nil {
+		b.WriteString(fmt.Sprintf("Error: %v\n", err))
+	} else {
+		b.WriteString(result.New(d).String() + "\n")
+		errors := 0
+		for _, item := range d.Items {
+			if item.Severity == "error" {
+				errors++
+			}
+		}
+		if errors > 0 {
+			events = append(events, notification{Source: "watch", Severity: "warning",
+				Title: fmt.Sprintf("Lint errors in %s: %d", filepath.Base(path), errors), Text: result.New(d).String()})
+		}
+	}
+	if rapid.IsSourceFile(path) {
+		if report, err := statsFile(path); err == nil {
+			b.WriteString(report)
+		}
+	}
+	if len(runArgs) > 0 {
+		args := make([]string, len(runArgs))
+		for i, a := range runArgs {
+			args[i] = strings.ReplaceAll(a, "{file}", path)
+		}
+		name := strings.ToLower(args[0])
+		r := execute(name, commandRegistry[name], args[1:])
+		b.WriteString(r.String() + "\n")
+		if r.Failed() {
+			events = append(events, notification{Source: "watch", Severity: "critical",
+				Title: fmt.Sprintf("%s failed for %s", args[0], filepath.Base(path)), Text: r.String()})
+		}
+	}
+	return b.String(), events
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const generateUsage = `Usage: generate <welddata|dispense|vision|trace|handshake|checklist|brakecheck|zone-interlock|positioner|tests|sweep> ...
+Also available as abb generate.
+
+Examples:
+  generate welddata --plate 3mm --process MAG
+  generate dispense glue.csv --temp 25
+  generate vision --ip 192.168.125.50 --port 2000
+  generate trace --pattern AAA-9999999 --record mes --mes 192.168.125.10:5000
+  generate handshake --signals job_start,job_done,fault --style level
+  generate checklist --robot irb6700 --interval 6m
+  generate brakecheck --interval 24 --prewarning 4
+  generate zone-interlock --robots 2 --zones 3
+  generate positioner --unit STN1 --axes 2
+  generate tests GripperLib.mod --output ./tests
+  generate sweep --param weldSpeed=5..15step2 --routine WeldTrial`
+
+const welddataUsage = `Usage: generate welddata --plate <thickness> --process <MAG|MIG> [--material <name>]
+Produces seamdata, welddata and weavedata declarations from the built-in
+parameter table. MAG defaults to steel and MIG to aluminium. The values are starting points for test welds, not
+qualified procedure parameters.
+
+Example:
+  generate welddata --plate 3mm --process MAG
+  generate welddata --plate 4 --process MIG --material aluminium`
+
+func init() {
+	commandRegistry["generate"] = Command{
+		Group:       groupCode,
+		Description: "Generate cell code and maintenance checklists",
+		Usage:       generateUsage,
+		Execute:     text(abbGenerate),
+	}
+}
+
+// abbGenerate dispatches the code generators, also reached as abb generate
+func abbGenerate(args []string) (string, error) {
+	if len(args) < 1 {
+		return generateUsage, nil
+	}
+	return generating(args[0], args[1:], func() (string, error) { return runGenerator(args) })
+}
+
+// runGenerator runs the generator named by the first argument
+func runGenerator(args []string) (string, error) {
+	switch args[0] {
+	case "welddata":
+		return generateWeldData(args[1:])
+	case "dispense":
+		return generateDispense(args[1:])
+	case "vision":
+		return generateVision(args[1:])
+	case "trace":
+		return generateTrace(args[1:])
+	case "handshake":
+		return generateHandshake(args[1:])
+	case "checklist":
+		return generateChecklist(args[1:])
+	case "brakecheck":
+		return generateBrakeCheck(args[1:])
+	case "zone-interlock":
+		return generateZoneInterlock(args[1:])
+	case "positioner":
+		return generatePositioner(args[1:])
+	case "tests":
+		return generateTests(args[1:])
+	case "sweep":
+		return generateSweep(args[1:])
+	default:
+		return generateUsage, nil
+	}
+}
+
+// generateWeldData looks up weld parameters and formats the declarations
+func generateWeldData(args []string) (string, error) {
+	var plate, process, material string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return welddataUsage, nil
+		}
+		switch args[i] {
+		case "--plate":
+			plate = args[i+1]
+		case "--process":
+			process = args[i+1]
+		case "--material":
+			material = args[i+1]
+		default:
+			return welddataUsage, nil
+		}
+		i++
+	}
+	if plate == "" || process == "" {
+		return welddataUsage, nil
+	}
+
+	thickness, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(plate), "mm"), 64)
+	if err != nil || thickness <= 0 {
+		return "", fmt.Errorf("invalid plate thickness %q (use e.g. 3mm)", plate)
+	}
+
+	processes := generate.WeldProcesses()
+	materials, ok := processes[strings.ToUpper(process)]
+	if !ok {
+		names := make([]string, 0, len(processes))
+		for name := range processes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("unknown process %q. Available: %s", process, strings.Join(names, ", "))
+	}
+	if material != "" && !containsFold(materials, material) {
+		return "", fmt.Errorf("no %s data for %q. Available: %s", strings.ToUpper(process), material, strings.Join(materials, ", "))
+	}
+
+	code, err := generate.WeldData(process, material, thickness)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(code, "\n"), nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
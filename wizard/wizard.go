@@ -0,0 +1,240 @@
+// Package wizard asks a series of questions on the terminal: answers are
+// checked against the question kind, Enter takes the default, ? shows the
+// help and < goes back to the previous question. The generator wizards and
+// the risk questionnaire share it.
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Question kinds
+const (
+	Text   = "text"
+	Number = "number"
+	YesNo  = "yesno"
+	Choice = "choice" // One of Choices
+	Multi  = "multi"  // Comma separated list of Choices, or none
+)
+
+// Back is the answer that returns to the previous question
+const Back = "<"
+
+// ErrCanceled is returned when the input ends before the last question
+var ErrCanceled = errors.New("wizard canceled")
+
+// Question is one step of a wizard. When is the ID of a yes/no question
+// that must be answered yes, or "id=value" for a choice or multi answer
+// containing value, for the question to be asked. Validate checks an answer
+// further after it is normalized.
+type Question struct {
+	ID       string
+	Text     string
+	Kind     string
+	Choices  []string
+	Default  string
+	Help     string
+	When     string
+	Validate func(answer string) error
+}
+
+// Answers maps question IDs to answers
+type Answers map[string]string
+
+// Session asks the questions: Ask shows a prompt and returns the line typed,
+// false when the input ended; help and validation messages go to Out
+type Session struct {
+	Ask func(prompt string) (string, bool)
+	Out io.Writer
+}
+
+// Run asks the questions that apply and are not answered yet. Valid answers
+// given beforehand are kept without asking; going back to a question shows
+// its earlier answer as the default.
+func (s Session) Run(questions []Question, a Answers) error {
+	given := make(map[string]bool, len(a))
+	for id := range a {
+		given[id] = true
+	}
+	var asked []int // indexes of the questions answered here, for going back
+	for i := 0; i < len(questions); i++ {
+		q := questions[i]
+		if !q.Asked(a) {
+			delete(a, q.ID)
+			continue
+		}
+		if given[q.ID] {
+			if normalized, err := q.Normalize(a[q.ID]); err == nil {
+				a[q.ID] = normalized
+				continue
+			}
+			delete(given, q.ID)
+			delete(a, q.ID)
+		}
+
+		line, ok := s.Ask(q.prompt(a[q.ID]))
+		if !ok {
+			return ErrCanceled
+		}
+		switch line {
+		case "?":
+			help := q.Help
+			if help == "" {
+				help = "No further help for this question."
+			}
+			fmt.Fprintln(s.Out, help)
+			i--
+			continue
+		case Back:
+			if len(asked) == 0 {
+				fmt.Fprintln(s.Out, "This is the first question.")
+				i--
+				continue
+			}
+			i = asked[len(asked)-1] - 1
+			asked = asked[:len(asked)-1]
+			continue
+		}
+		if line == "" && a[q.ID] != "" {
+			line = a[q.ID]
+		}
+		v, err := q.Normalize(line)
+		if err != nil {
+			fmt.Fprintln(s.Out, err)
+			i--
+			continue
+		}
+		a[q.ID] = v
+		asked = append(asked, i)
+	}
+	return nil
+}
+
+// prompt is the question with its choices and the default in brackets
+func (q Question) prompt(previous string) string {
+	p := "\n" + q.Text
+	if len(q.Choices) > 0 {
+		p += " (" + strings.Join(q.Choices, ", ") + ")"
+		if q.Kind == Multi {
+			p += ", comma separated or none"
+		}
+	}
+	if q.Kind == YesNo {
+		p += " (yes/no)"
+	}
+	def := q.Default
+	if previous != "" {
+		def = previous
+	}
+	if def != "" {
+		p += " [" + def + "]"
+	}
+	return p + ": "
+}
+
+// Asked reports whether the question applies given the answers so far
+func (q Question) Asked(a Answers) bool {
+	if q.When == "" {
+		return true
+	}
+	id, values, ok := strings.Cut(q.When, "=")
+	if !ok {
+		return a.Yes(id)
+	}
+	for _, v := range strings.Split(values, ",") {
+		if a.Has(id, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize checks an answer and returns it in canonical form
+func (q Question) Normalize(answer string) (string, error) {
+	v, err := q.normalize(answer)
+	if err == nil && q.Validate != nil {
+		err = q.Validate(v)
+	}
+	if err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+func (q Question) normalize(answer string) (string, error) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		answer = q.Default
+	}
+	switch q.Kind {
+	case Number:
+		v, err := strconv.ParseFloat(answer, 64)
+		if err != nil || v < 0 {
+			return "", fmt.Errorf("enter a positive number")
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case YesNo:
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return "yes", nil
+		case "n", "no":
+			return "no", nil
+		}
+		return "", fmt.Errorf("answer yes or no")
+	case Choice:
+		for _, c := range q.Choices {
+			if strings.EqualFold(answer, c) {
+				return c, nil
+			}
+		}
+		return "", fmt.Errorf("choose one of %s", strings.Join(q.Choices, ", "))
+	case Multi:
+		if answer == "" || strings.EqualFold(answer, "none") {
+			return "none", nil
+		}
+		var picked []string
+		for _, part := range strings.Split(answer, ",") {
+			part = strings.ToLower(strings.TrimSpace(part))
+			if !contains(q.Choices, part) {
+				return "", fmt.Errorf("unknown choice %q; use %s or none", part, strings.Join(q.Choices, ", "))
+			}
+			if !contains(picked, part) {
+				picked = append(picked, part)
+			}
+		}
+		return strings.Join(picked, ","), nil
+	}
+	if answer == "" {
+		return "", fmt.Errorf("an answer is required")
+	}
+	return answer, nil
+}
+
+// Yes reports whether a yes/no question was answered yes
+func (a Answers) Yes(id string) bool {
+	return a[id] == "yes"
+}
+
+// Has reports whether a choice or multi answer contains value
+func (a Answers) Has(id, value string) bool {
+	return contains(strings.Split(a[id], ","), value)
+}
+
+// Number returns a numeric answer, or 0
+func (a Answers) Number(id string) float64 {
+	v, _ := strconv.ParseFloat(a[id], 64)
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/wizard"
+)
+
+// wizardSession asks wizard questions on the terminal
+var wizardSession = wizard.Session{Ask: readLine, Out: os.Stdout}
+
+// wizardHint is shown before the first question of every wizard
+const wizardHint = "Press Enter for the default in brackets, ? for help, < to go back."
+
+// wizardFlag removes --wizard from the arguments of a generator and reports
+// whether it was given
+func wizardFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	wiz := false
+	for _, a := range args {
+		if a == "--wizard" {
+			wiz = true
+			continue
+		}
+		rest = append(rest, a)
+	}
+	return rest, wiz
+}
+
+// wizardOptions runs a generator wizard. Options given on the command line
+// answer the question with the same name; the others are asked. It returns
+// the answers as options, followed by the options no question covers, for
+// the generator to parse as if they had been typed.
+func wizardOptions(title string, questions []wizard.Question, args []string) ([]string, error) {
+	answers := wizard.Answers{}
+	var rest []string
+	for i := 0; i+1 < len(args); i += 2 {
+		id := strings.TrimPrefix(args[i], "--")
+		if _, ok := findQuestion(questions, id); ok && id != args[i] {
+			answers[id] = args[i+1]
+			continue
+		}
+		rest = append(rest, args[i], args[i+1])
+	}
+	if len(args)%2 == 1 {
+		rest = append(rest, args[len(args)-1])
+	}
+
+	fmt.Println(title)
+	fmt.Println(wizardHint)
+	if err := wizardSession.Run(questions, answers); err != nil {
+		return nil, err
+	}
+	var options []string
+	for _, q := range questions {
+		if v, ok := answers[q.ID]; ok {
+			options = append(options, "--"+q.ID, v)
+		}
+	}
+	return append(options, rest...), nil
+}
+
+func findQuestion(questions []wizard.Question, id string) (wizard.Question, bool) {
+	for _, q := range questions {
+		if q.ID == id {
+			return q, true
+		}
+	}
+	return wizard.Question{}, false
+}
+
+// identifier validates a wizard answer used as a RAPID name
+func identifier(answer string) error {
+	if !identifierRe.MatchString(answer) {
+		return fmt.Errorf("%q is not a valid RAPID name", answer)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/abb"
+	"github.com/polyfant/automation-helper-cli/calc"
+)
+
+const workspaceUsage = `Usage: calc workspace --robot <model> [--pedestal 400] [--mounting floor]
+       [--heights 0,500,1000] [--output dims.csv]
+Reports the horizontal reach at heights above the floor for a robot on a
+pedestal (mm), and warns about floor contact and mounting constraints. A
+family such as irb2600 compares all its variants. For ceiling mounting the
+pedestal is the height of the base above the floor. --output exports the
+key dimensions as CSV for layout drawings.
+
+The envelope is a sphere of the rated reach around axis 2; the product
+manual drawing is authoritative near the base and behind the robot.
+
+Examples:
+  calc workspace --robot irb2600 --pedestal 400
+  calc workspace --robot irb4600-60 --mounting ceiling --pedestal 3200 --output irb4600_dims.csv`
+
+// calcWorkspace reports the working envelope of one robot or a family
+func calcWorkspace(args []string) (string, error) {
+	var robot, output, heightList string
+	mounting := "floor"
+	pedestal := 0.0
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return workspaceUsage, nil
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--robot":
+			robot = value
+		case "--pedestal":
+			v, err := strconv.ParseFloat(strings.TrimSuffix(value, "mm"), 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid pedestal height %q", value)
+			}
+			pedestal = v
+		case "--mounting":
+			mounting = strings.ToLower(value)
+		case "--heights":
+			heightList = value
+		case "--output":
+			output = value
+		default:
+			return workspaceUsage, nil
+		}
+		i++
+	}
+	if robot == "" {
+		robot = projectRobot()
+	}
+	if robot == "" {
+		return workspaceUsage, nil
+	}
+
+	keys := abb.RobotVariants(robot)
+	if r, ok := abb.RobotModel(robot); ok && len(keys) != 1 {
+		keys = nil
+		for _, key := range abb.RobotKeys() {
+			if candidate, _ := abb.RobotModel(key); candidate.Model == r.Model {
+				keys = []string{key}
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("unknown robot %q. Available: %s", robot, strings.Join(abb.RobotKeys(), ", "))
+	}
+	var envelopes []calc.Envelope
+	for _, key := range keys {
+		r, _ := abb.RobotModel(key)
+		e, err := calc.Workspace(r, mounting, pedestal)
+		if err != nil {
+			return "", err
+		}
+		envelopes = append(envelopes, e)
+	}
+
+	heights, err := workspaceHeights(heightList, envelopes)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Working envelope, %s mounting, pedestal %.0f mm:\n\n", mounting, pedestal)
+	fmt.Fprintf(&result, "  %-22s", "")
+	for _, e := range envelopes {
+		fmt.Fprintf(&result, " %18s", e.Robot.Model)
+	}
+	result.WriteString("\n")
+	row := func(label string, value func(calc.Envelope) string) {
+		fmt.Fprintf(&result, "  %-22s", label)
+		for _, e := range envelopes {
+			fmt.Fprintf(&result, " %18s", value(e))
+		}
+		result.WriteString("\n")
+	}
+	mm := func(v float64) string { return fmt.Sprintf("%.0f mm", v) }
+	row("Base footprint", func(e calc.Envelope) string { return e.Robot.Footprint })
+	row("Axis 2 height", func(e calc.Envelope) string { return mm(e.Center) })
+	row("Max reach", func(e calc.Envelope) string { return mm(e.Reach) })
+	row("Envelope top", func(e calc.Envelope) string { return mm(e.Top) })
+	row("Envelope bottom", func(e calc.Envelope) string { return mm(e.Bottom) })
+	result.WriteString("\n  Reach at height:\n")
+	for _, h := range heights {
+		row(fmt.Sprintf("  %.0f mm", h), func(e calc.Envelope) string {
+			if r := e.ReachAt(h); r > 0 {
+				return mm(r)
+			}
+			return "-"
+		})
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range envelopes {
+		for _, w := range e.Warnings {
+			if len(envelopes) > 1 && !strings.HasPrefix(w, e.Robot.Model) {
+				w = e.Robot.Model + ": " + w
+			}
+			if !seen[w] {
+				seen[w] = true
+				result.WriteString("\nWarning: " + w)
+			}
+		}
+	}
+
+	if output != "" {
+		if err := writeWorkspaceCSV(output, envelopes, heights); err != nil {
+			return "", err
+		}
+		result.WriteString("\n\nWrote " + output)
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+// workspaceHeights parses --heights or steps from the floor to the highest
+// envelope top, 250 mm apart
+func workspaceHeights(list string, envelopes []calc.Envelope) ([]float64, error) {
+	var heights []float64
+	if list != "" {
+		for _, field := range strings.Split(list, ",") {
+			h, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid height %q", field)
+			}
+			heights = append(heights, h)
+		}
+		sort.Float64s(heights)
+		return heights, nil
+	}
+	top := 0.0
+	for _, e := range envelopes {
+		if e.Top > top {
+			top = e.Top
+		}
+	}
+	for h := 0.0; h < top; h += 250 {
+		heights = append(heights, h)
+	}
+	return heights, nil
+}
+
+// writeWorkspaceCSV exports the key dimensions, one column per robot
+func writeWorkspaceCSV(path string, envelopes []calc.Envelope, heights []float64) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"dimension_mm"}
+	for _, e := range envelopes {
+		header = append(header, e.Robot.Model)
+	}
+	records := [][]string{header}
+	add := func(name string, value func(calc.Envelope) float64) {
+		rec := []string{name}
+		for _, e := range envelopes {
+			rec = append(rec, strconv.FormatFloat(value(e), 'f', 0, 64))
+		}
+		records = append(records, rec)
+	}
+	add("pedestal", func(e calc.Envelope) float64 { return e.Pedestal })
+	add("axis2_height", func(e calc.Envelope) float64 { return e.Center })
+	add("max_reach", func(e calc.Envelope) float64 { return e.Reach })
+	add("envelope_top", func(e calc.Envelope) float64 { return e.Top })
+	add("envelope_bottom", func(e calc.Envelope) float64 { return e.Bottom })
+	for _, h := range heights {
+		add(fmt.Sprintf("reach_at_%.0f", h), func(e calc.Envelope) float64 { return e.ReachAt(h) })
+	}
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/polyfant/automation-helper-cli/generate"
+)
+
+const zoneInterlockUsage = `Usage: generate zone-interlock --robots <n> --zones <n> [--method signals|worldzones]
+       [--timeout 30] [--output dir]
+Generates matched code for shared zones in a multi-robot cell: a RAPID
+module per robot with EnterZone/ExitZone (request, wait for permit, occupy)
+and the PLC function block FB_ZoneArbiter granting each zone to one robot at
+a time. With worldzones the in-zone outputs are set by world zones from the
+TCP position instead of by the program. --output writes ZoneInterlock_R<n>.mod,
+FB_ZoneArbiter.st and zone_signals.txt. 'scenario sim' runs the function
+block against scripted requests.
+
+World zones watch the TCP only; use SafeMove zones where a collision would
+injure people or damage the cell.
+
+Example:
+  generate zone-interlock --robots 2 --zones 3 --method worldzones`
+
+// generateZoneInterlock writes the robot and PLC side of a zone interlock
+func generateZoneInterlock(args []string) (string, error) {
+	opts := generate.ZoneOptions{Robots: 2, Method: generate.ZoneSignals, Timeout: 30}
+	output := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return zoneInterlockUsage, nil
+		}
+		value := args[i+1]
+		var err error
+		switch args[i] {
+		case "--robots":
+			opts.Robots, err = strconv.Atoi(value)
+		case "--zones":
+			opts.Zones, err = strconv.Atoi(value)
+		case "--method":
+			opts.Method = strings.ToLower(value)
+		case "--timeout":
+			opts.Timeout, err = strconv.ParseFloat(value, 64)
+		case "--output":
+			output = value
+		default:
+			return zoneInterlockUsage, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s", value, args[i])
+		}
+		i++
+	}
+	if opts.Zones == 0 {
+		return zoneInterlockUsage, nil
+	}
+
+	z, err := generate.ZoneInterlockCode(opts)
+	if err != nil {
+		return "", err
+	}
+	if output == "" {
+		var result strings.Builder
+		for i, module := range z.Rapid {
+			fmt.Fprintf(&result, "--- ZoneInterlock_R%d.mod ---\n%s\n", i+1, module)
+		}
+		result.WriteString("--- FB_ZoneArbiter.st ---\n" + z.ST + "\n")
+		result.WriteString("--- I/O ---\n" + z.Signals)
+		return strings.TrimRight(result.String(), "\n"), nil
+	}
+
+	if err := makeDir(output); err != nil {
+		return "", err
+	}
+	var files []struct{ name, content string }
+	for i, module := range z.Rapid {
+		files = append(files, struct{ name, content string }{fmt.Sprintf("ZoneInterlock_R%d.mod", i+1), module})
+	}
+	files = append(files,
+		struct{ name, content string }{"FB_ZoneArbiter.st", z.ST},
+		struct{ name, content string }{"zone_signals.txt", z.Signals})
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(output, f.name)
+		if err := writeFile(path, []byte(f.content), 0644); err != nil {
+			return "", err
+		}
+		written = append(written, path)
+	}
+	return "Wrote " + strings.Join(written, ", "), nil
+}